@@ -6,8 +6,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/idgen"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
@@ -21,14 +21,6 @@ type User struct {
 	Email string `json:"email"`
 }
 
-// BeforeCreate is a GORM hook that generates a UUID for the UID field if it's empty
-func (u *User) BeforeCreate(tx *gorm.DB) error {
-	if u.UID == "" {
-		u.UID = uuid.New().String()
-	}
-	return nil
-}
-
 // SetID sets the UID field - implements interface for ID-aware models
 func (u *User) SetID(id interface{}) {
 	if idStr, ok := id.(string); ok {
@@ -44,14 +36,6 @@ type Product struct {
 	Price       float64 `json:"price"`
 }
 
-// BeforeCreate is a GORM hook that generates a UUID for the GUID field if it's empty
-func (p *Product) BeforeCreate(tx *gorm.DB) error {
-	if p.GUID == "" {
-		p.GUID = uuid.New().String()
-	}
-	return nil
-}
-
 // SetID sets the GUID field - implements interface for ID-aware models
 func (p *Product) SetID(id interface{}) {
 	if idStr, ok := id.(string); ok {
@@ -85,7 +69,8 @@ func main() {
 	userResource := resource.NewResource(resource.ResourceConfig{
 		Name:        "users",
 		Model:       User{},
-		IDFieldName: "UID", // Specify custom ID field name
+		IDFieldName: "UID",             // Specify custom ID field name
+		IDGenerator: idgen.NewUUIDv4(), // Generate a UUID for created users without a UID
 		Operations: []resource.Operation{
 			resource.OperationList,
 			resource.OperationCreate,
@@ -105,7 +90,8 @@ func main() {
 	productResource := resource.NewResource(resource.ResourceConfig{
 		Name:        "products",
 		Model:       Product{},
-		IDFieldName: "GUID", // Specify custom ID field name
+		IDFieldName: "GUID",            // Specify custom ID field name
+		IDGenerator: idgen.NewUUIDv7(), // Time-ordered UUIDs keep products in creation order
 		Operations: []resource.Operation{
 			resource.OperationList,
 			resource.OperationCreate,
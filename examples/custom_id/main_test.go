@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/idgen"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
@@ -34,6 +35,7 @@ func TestCustomIDIntegration(t *testing.T) {
 		Name:        "users",
 		Model:       &User{},
 		IDFieldName: "UID",
+		IDGenerator: idgen.NewUUIDv4(),
 		Operations: []resource.Operation{
 			resource.OperationList,
 			resource.OperationCreate,
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/auth"
 	"github.com/suranig/refine-gin/pkg/handler"
 	"github.com/suranig/refine-gin/pkg/middleware"
 	"github.com/suranig/refine-gin/pkg/repository"
@@ -41,22 +43,30 @@ type Note struct {
 	CreatedAt time.Time `json:"created_at" refine:"filterable;sortable"`
 }
 
-// JWT middleware to extract and validate tokens
-func jwtMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// In a real application, you would validate the token here
-		// For the sake of this example, we'll simulate a valid token
+// demoUsers authenticates the seeded users. Every user has the password "password";
+// a real provider would check a password hash.
+type demoUsers struct {
+	db *gorm.DB
+}
 
-		// Create a mock JWT claims with user ID
-		claims := jwt.MapClaims{
-			"sub": "user-1", // A user ID for the owner
-			"exp": time.Now().Add(time.Hour * 24).Unix(),
-		}
+func (u demoUsers) Authenticate(ctx context.Context, email, password string) (*auth.User, error) {
+	var user User
+	if err := u.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil || password != "password" {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return toAuthUser(user), nil
+}
 
-		// Store claims in context for later extraction
-		c.Set("claims", claims)
-		c.Next()
+func (u demoUsers) GetUser(ctx context.Context, id string) (*auth.User, error) {
+	var user User
+	if err := u.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
+		return nil, auth.ErrUserNotFound
 	}
+	return toAuthUser(user), nil
+}
+
+func toAuthUser(user User) *auth.User {
+	return &auth.User{ID: user.ID, Username: user.Email, Roles: []string{user.Role}, Data: user}
 }
 
 func main() {
@@ -191,9 +201,16 @@ func main() {
 	handler.RegisterResource(api, userResource, userRepo)
 	handler.RegisterResource(api, taskResource, taskRepo)
 
+	// Issue tokens with POST /api/auth/login {"email": "john@example.com", "password": "password"}
+	jwtConfig := auth.DefaultJWTConfig()
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		jwtConfig.Secret = secret
+	}
+	auth.RegisterRoutes(api, auth.RoutesConfig{JWT: jwtConfig, Users: demoUsers{db: db}})
+
 	// Register owner resource with JWT middleware
 	securedApi := api.Group("")
-	securedApi.Use(jwtMiddleware())
+	securedApi.Use(middleware.JWTAuth(jwtConfig))
 	securedApi.Use(middleware.OwnerContext(middleware.ExtractOwnerIDFromJWT("sub")))
 	handler.RegisterOwnerResource(securedApi, ownerNoteResource, noteRepo)
 
@@ -10,6 +10,7 @@ import (
 	"github.com/suranig/refine-gin/pkg/handler"
 	"github.com/suranig/refine-gin/pkg/query"
 	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -78,6 +79,16 @@ type Tag struct {
 	Posts     []Post    `json:"posts" gorm:"many2many:post_tags;" relation:"resource=posts;type=many-to-many;field=posts"`
 }
 
+// requestIncludes returns the relations requested with ?include=, or none when the
+// repository is called outside of an HTTP request
+func requestIncludes(ctx context.Context, res resource.Resource) []string {
+	c, ok := requestctx.Gin.Get(ctx)
+	if !ok || res == nil {
+		return nil
+	}
+	return resource.IncludeRelations(c, res)
+}
+
 // UserRepository implements repository for User
 type UserRepository struct {
 	db *gorm.DB
@@ -90,7 +101,7 @@ func (r *UserRepository) List(ctx context.Context, options query.QueryOptions) (
 	q := r.db.Model(&User{})
 
 	// Apply includes from options
-	includes := resource.IncludeRelations(ctx.(*gin.Context), options.Resource)
+	includes := requestIncludes(ctx, options.Resource)
 	for _, include := range includes {
 		q = q.Preload(include)
 	}
@@ -107,7 +118,8 @@ func (r *UserRepository) Get(ctx context.Context, id interface{}) (interface{},
 	var user User
 
 	// Get includes from context
-	includes := resource.IncludeRelations(ctx.(*gin.Context), ctx.Value("resource").(resource.Resource))
+	res, _ := requestctx.Resource.Get(ctx)
+	includes := requestIncludes(ctx, res)
 
 	// Apply includes
 	q := r.db
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// DefaultPath is the path the admin UI is mounted at
+const DefaultPath = "/_admin"
+
+// DefaultTitle is the title of the admin UI
+const DefaultTitle = "Refine-Gin Admin"
+
+//go:embed ui
+var files embed.FS
+
+// index is the page of the single-page admin, loaded once
+var index = template.Must(template.ParseFS(files, "ui/index.html"))
+
+// Config contains configuration for the embedded admin UI
+type Config struct {
+	// Path is the path of the UI, relative to the router (DefaultPath if empty)
+	Path string
+
+	// APIPath is the URL path of the resource routes, which serve the metadata
+	// endpoint GET <APIPath>/config (see handler.RegisterAPIConfigEndpoint)
+	APIPath string
+
+	// Title of the page (DefaultTitle if empty)
+	Title string
+
+	// Production serves the UI in every gin mode. By default it only answers in
+	// gin's debug mode and returns 404 otherwise; protect it with Middleware when
+	// enabling it.
+	Production bool
+
+	// Middleware run before the UI handlers, e.g. authentication
+	Middleware []gin.HandlerFunc
+}
+
+// RegisterAdmin registers a lightweight admin UI for inspecting the registered
+// resources before a Refine frontend exists:
+//
+//	GET <path>/           the single-page UI
+//	GET <path>/assets/*   its scripts and styles
+//
+// The UI reads the resources from the metadata endpoint and lists, shows, creates,
+// edits and deletes records through the resource routes, as far as their operations
+// allow. Requests carry the bearer token entered in the UI.
+func RegisterAdmin(router *gin.RouterGroup, cfg Config) {
+	if cfg.Path == "" {
+		cfg.Path = DefaultPath
+	}
+	if cfg.Title == "" {
+		cfg.Title = DefaultTitle
+	}
+	cfg.APIPath = strings.TrimSuffix(cfg.APIPath, "/")
+
+	assets, err := fs.Sub(files, "ui/assets")
+	if err != nil {
+		panic(err)
+	}
+
+	handlers := append([]gin.HandlerFunc{adminMiddleware(cfg)}, cfg.Middleware...)
+	group := router.Group(cfg.Path, handlers...)
+	group.GET("/", generateIndexHandler(cfg, group.BasePath()))
+	group.StaticFS("/assets", http.FS(assets))
+}
+
+// adminMiddleware hides the UI outside gin's debug mode, unless it is enabled for
+// production
+func adminMiddleware(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Production && !gin.IsDebugging() {
+			apierror.AbortMessage(c, http.StatusNotFound, "Not found")
+			return
+		}
+		utils.DisableCaching(c.Writer)
+		c.Next()
+	}
+}
+
+// generateIndexHandler serves the page of the UI
+func generateIndexHandler(cfg Config, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		err := index.Execute(c.Writer, gin.H{
+			"Title":    cfg.Title,
+			"APIPath":  cfg.APIPath,
+			"BasePath": strings.TrimSuffix(basePath, "/"),
+		})
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRegisterAdmin(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	RegisterAdmin(&router.RouterGroup, Config{APIPath: "/api/", Title: "Shop <Admin>"})
+
+	w := serve(router, "/_admin/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `data-api="/api"`)
+	assert.Contains(t, w.Body.String(), `<title>Shop &lt;Admin&gt;</title>`)
+	assert.Contains(t, w.Body.String(), `src="/_admin/assets/admin.js"`)
+	assert.Contains(t, w.Header().Get("Cache-Control"), "no-store")
+
+	w = serve(router, "/_admin/assets/admin.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `request("GET", "/config")`)
+
+	w = serve(router, "/_admin/assets/admin.css")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, "/_admin")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestRegisterAdminPathAndMiddleware(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	api := router.Group("/api")
+	RegisterAdmin(api, Config{
+		Path:    "/console",
+		APIPath: "/api",
+		Middleware: []gin.HandlerFunc{func(c *gin.Context) {
+			if c.GetHeader("Authorization") == "" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		}},
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, serve(router, "/api/console/").Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/console/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="/api/console/assets/admin.css"`)
+	assert.Contains(t, w.Body.String(), DefaultTitle)
+}
+
+func TestRegisterAdminReleaseMode(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	RegisterAdmin(&router.RouterGroup, Config{APIPath: "/api"})
+	assert.Equal(t, http.StatusNotFound, serve(router, "/_admin/").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "/_admin/assets/admin.js").Code)
+
+	router = gin.New()
+	RegisterAdmin(&router.RouterGroup, Config{APIPath: "/api", Production: true})
+	assert.Equal(t, http.StatusOK, serve(router, "/_admin/").Code)
+}
@@ -0,0 +1,396 @@
+// Refine-Gin admin: a small single-page UI over the metadata endpoint and the
+// resource routes. Routes live in the URL hash:
+//
+//   #/<resource>?page=1&sort=id&order=desc   list
+//   #/<resource>/new                         create
+//   #/<resource>/<id>                        show
+//   #/<resource>/<id>/edit                   edit
+(function () {
+  "use strict";
+
+  var api = document.body.dataset.api || "";
+  var pageSize = 20;
+  var resources = {};
+  var content = document.getElementById("content");
+  var nav = document.getElementById("resources");
+
+  // --- HTTP ---------------------------------------------------------------
+
+  function request(method, path, body) {
+    var headers = { Accept: "application/json" };
+    var token = localStorage.getItem("refineGinAdminToken");
+    if (token) headers.Authorization = "Bearer " + token;
+    if (body !== undefined) headers["Content-Type"] = "application/json";
+
+    return fetch(api + path, {
+      method: method,
+      headers: headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    }).then(function (response) {
+      return response.text().then(function (text) {
+        var data = null;
+        try { data = text ? JSON.parse(text) : null; } catch (e) { data = text; }
+        if (!response.ok) {
+          var message = data && data.error ? data.error : response.status + " " + response.statusText;
+          if (data && data.details) message += "\n" + JSON.stringify(data.details, null, 2);
+          throw new Error(message);
+        }
+        return data;
+      });
+    });
+  }
+
+  // --- DOM helpers --------------------------------------------------------
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) {
+      if (key === "text") node.textContent = attrs[key];
+      else if (key.indexOf("on") === 0) node.addEventListener(key.slice(2), attrs[key]);
+      else node.setAttribute(key, attrs[key]);
+    });
+    (children || []).forEach(function (child) {
+      if (child) node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  }
+
+  function render() {
+    content.textContent = "";
+    Array.prototype.forEach.call(arguments, function (node) {
+      if (node) content.appendChild(node);
+    });
+  }
+
+  function showError(err) {
+    content.insertBefore(el("div", { class: "error", text: err.message }), content.firstChild);
+  }
+
+  function link(hash, text) {
+    return el("a", { href: "#" + hash, class: "button", text: text });
+  }
+
+  // --- Metadata -----------------------------------------------------------
+
+  function can(res, operation) {
+    return (res.operations || []).indexOf(operation) >= 0;
+  }
+
+  function idField(res) {
+    return res.idFieldName || "id";
+  }
+
+  function fieldsOf(res, names) {
+    var visible = (res.fields || []).filter(function (field) { return !field.hidden; });
+    if (!names || !names.length) return visible;
+    return names.map(function (name) {
+      return visible.filter(function (field) { return field.name === name; })[0];
+    }).filter(Boolean);
+  }
+
+  function options(field) {
+    var props = field.antDesign && field.antDesign.props;
+    return props && Array.isArray(props.options) && props.mode !== "multiple" ? props.options : null;
+  }
+
+  function kind(field) {
+    var type = (field.type || "").toLowerCase();
+    if (field.flags && field.flags.length) return "flags";
+    if (options(field)) return "select";
+    if (type === "json" || type.indexOf("map[") === 0 || type.indexOf("[]") === 0) return "json";
+    if (type.indexOf("bool") >= 0) return "boolean";
+    if (type.indexOf("time") >= 0 || type === "date" || type === "datetime") return "datetime";
+    if (/int|float|number|decimal|double/.test(type)) return "number";
+    return "string";
+  }
+
+  function format(value) {
+    if (value === null || value === undefined) return "";
+    if (typeof value === "object") return JSON.stringify(value);
+    return String(value);
+  }
+
+  // --- Navigation ---------------------------------------------------------
+
+  function renderNav(active) {
+    nav.textContent = "";
+    Object.keys(resources).sort().forEach(function (name) {
+      var res = resources[name];
+      nav.appendChild(el("a", {
+        href: "#/" + encodeURIComponent(name),
+        class: name === active ? "active" : "",
+        text: res.label || name,
+      }));
+    });
+  }
+
+  function route() {
+    var hash = location.hash.replace(/^#\/?/, "");
+    var query = "";
+    var index = hash.indexOf("?");
+    if (index >= 0) {
+      query = hash.slice(index + 1);
+      hash = hash.slice(0, index);
+    }
+    var parts = hash.split("/").filter(Boolean).map(decodeURIComponent);
+    var res = resources[parts[0]];
+
+    renderNav(parts[0]);
+    if (!res) {
+      render(el("p", { class: "muted", text: Object.keys(resources).length ? "Select a resource." : "No resources are registered." }));
+      return;
+    }
+
+    var view;
+    if (parts.length === 1) view = listView(res, new URLSearchParams(query));
+    else if (parts[1] === "new") view = formView(res, null);
+    else if (parts[2] === "edit") view = formView(res, parts[1]);
+    else view = showView(res, parts[1]);
+    view.catch(showError);
+  }
+
+  // --- Views --------------------------------------------------------------
+
+  function listView(res, params) {
+    var page = parseInt(params.get("page"), 10) || 1;
+    var sort = params.get("sort") || (res.defaultSort ? res.defaultSort.field : "");
+    var order = params.get("order") || (res.defaultSort ? res.defaultSort.order : "asc");
+    var fields = fieldsOf(res, res.tableFields);
+    var base = "/" + encodeURIComponent(res.name);
+
+    var toolbar = el("div", { class: "toolbar" }, [
+      can(res, "create") ? link(base + "/new", "Create") : null,
+    ]);
+    render(el("h2", { text: res.label || res.name }), toolbar);
+
+    if (!can(res, "list")) {
+      content.appendChild(el("p", { class: "muted", text: "This resource cannot be listed." }));
+      return Promise.resolve();
+    }
+
+    var query = "?current=" + page + "&pageSize=" + pageSize;
+    if (sort) query += "&sort=" + encodeURIComponent(sort) + "&order=" + encodeURIComponent(order);
+
+    return request("GET", base + query).then(function (result) {
+      var sortable = res.sortableFields || [];
+      var head = el("tr", {}, fields.map(function (field) {
+        var canSort = sortable.indexOf(field.name) >= 0;
+        var label = (field.label || field.name) + (field.name === sort ? (order === "desc" ? " ▼" : " ▲") : "");
+        return el("th", {
+          class: canSort ? "sortable" : "",
+          text: label,
+          onclick: canSort ? function () {
+            var next = field.name === sort && order === "asc" ? "desc" : "asc";
+            location.hash = base + "?page=1&sort=" + encodeURIComponent(field.name) + "&order=" + next;
+          } : null,
+        });
+      }).concat([el("th", { text: "" })]));
+
+      var rows = (result.data || []).map(function (record) {
+        var id = encodeURIComponent(format(record[idField(res)]));
+        return el("tr", {}, fields.map(function (field) {
+          var text = format(record[field.name]);
+          return el("td", { title: text, text: text });
+        }).concat([el("td", {}, [
+          can(res, "read") ? link(base + "/" + id, "Show") : null,
+          can(res, "update") ? link(base + "/" + id + "/edit", "Edit") : null,
+          can(res, "delete") ? deleteButton(res, record[idField(res)]) : null,
+        ])]));
+      });
+
+      var total = result.total || 0;
+      var pages = Math.max(1, Math.ceil(total / pageSize));
+      var pageLink = function (target, text) {
+        var hash = base + "?page=" + target + (sort ? "&sort=" + encodeURIComponent(sort) + "&order=" + order : "");
+        return target >= 1 && target <= pages ? link(hash, text) : null;
+      };
+
+      content.appendChild(el("table", {}, [el("thead", {}, [head]), el("tbody", {}, rows)]));
+      content.appendChild(el("div", { class: "toolbar" }, [
+        pageLink(page - 1, "Previous"),
+        el("span", { class: "muted", text: "Page " + page + " of " + pages + " (" + total + " records)" }),
+        pageLink(page + 1, "Next"),
+      ]));
+    });
+  }
+
+  function showView(res, id) {
+    var base = "/" + encodeURIComponent(res.name);
+    return request("GET", base + "/" + encodeURIComponent(id)).then(function (result) {
+      var record = result.data || {};
+      var items = [];
+      fieldsOf(res).forEach(function (field) {
+        var value = record[field.name];
+        items.push(el("dt", { text: field.label || field.name }));
+        items.push(el("dd", { text: typeof value === "object" && value !== null ? JSON.stringify(value, null, 2) : format(value) }));
+      });
+      render(
+        el("h2", { text: (res.label || res.name) + " " + id }),
+        el("div", { class: "toolbar" }, [
+          link(base, "Back"),
+          can(res, "update") ? link(base + "/" + encodeURIComponent(id) + "/edit", "Edit") : null,
+          can(res, "delete") ? deleteButton(res, id) : null,
+        ]),
+        el("dl", {}, items)
+      );
+    });
+  }
+
+  function formView(res, id) {
+    var base = "/" + encodeURIComponent(res.name);
+    var load = id === null ? Promise.resolve({ data: {} }) : request("GET", base + "/" + encodeURIComponent(id));
+
+    return load.then(function (result) {
+      var record = result.data || {};
+      var fields = fieldsOf(res, res.formFields).filter(function (field) {
+        return !field.readOnly && !field.computed && field.name !== idField(res);
+      });
+      var form = el("form", { class: "record" }, fields.map(function (field) {
+        return el("label", {}, [el("span", { text: (field.label || field.name) + (field.required ? " *" : "") }), input(field, record[field.name])]);
+      }).concat([el("button", { type: "submit", text: id === null ? "Create" : "Save" })]));
+
+      form.addEventListener("submit", function (event) {
+        event.preventDefault();
+        var body;
+        try {
+          body = collect(form, fields);
+        } catch (err) {
+          showError(err);
+          return;
+        }
+        var saved = id === null
+          ? request("POST", base, body)
+          : request("PATCH", base + "/" + encodeURIComponent(id), body);
+        saved.then(function (response) {
+          var created = response && response.data ? response.data[idField(res)] : id;
+          location.hash = base + (created !== undefined && created !== null ? "/" + encodeURIComponent(created) : "");
+        }).catch(showError);
+      });
+
+      render(
+        el("h2", { text: id === null ? "Create " + (res.label || res.name) : "Edit " + (res.label || res.name) + " " + id }),
+        el("div", { class: "toolbar" }, [link(base, "Back")]),
+        form
+      );
+    });
+  }
+
+  function deleteButton(res, id) {
+    return el("button", {
+      class: "danger",
+      type: "button",
+      text: "Delete",
+      onclick: function () {
+        if (!confirm("Delete " + (res.label || res.name) + " " + id + "?")) return;
+        request("DELETE", "/" + encodeURIComponent(res.name) + "/" + encodeURIComponent(id))
+          .then(function () {
+            if (location.hash === "#/" + encodeURIComponent(res.name)) route();
+            else location.hash = "/" + encodeURIComponent(res.name);
+          })
+          .catch(showError);
+      },
+    });
+  }
+
+  // --- Form fields --------------------------------------------------------
+
+  function input(field, value) {
+    var name = field.name;
+    switch (kind(field)) {
+      case "boolean":
+        var checkbox = el("input", { type: "checkbox", name: name });
+        checkbox.checked = !!value;
+        return checkbox;
+      case "number":
+        return el("input", { type: "number", step: "any", name: name, value: format(value) });
+      case "datetime":
+        return el("input", { type: "datetime-local", step: "1", name: name, value: value ? localTime(value) : "" });
+      case "json":
+        return el("textarea", { name: name }, [value === undefined || value === null ? "" : JSON.stringify(value, null, 2)]);
+      case "select":
+        var select = el("select", { name: name }, [el("option", { value: "", text: "" })].concat(options(field).map(function (option) {
+          return el("option", { value: format(option.value), text: option.label || format(option.value) });
+        })));
+        select.value = format(value);
+        return select;
+      case "flags":
+        var set = Array.isArray(value) ? value : [];
+        return el("div", { "data-flags": name }, field.flags.map(function (flag) {
+          var box = el("input", { type: "checkbox", value: flag.name });
+          box.checked = set.indexOf(flag.name) >= 0;
+          return el("label", {}, [box, " " + (flag.label || flag.name)]);
+        }));
+      default:
+        return el("input", { type: "text", name: name, value: format(value) });
+    }
+  }
+
+  function collect(form, fields) {
+    var body = {};
+    fields.forEach(function (field) {
+      if (kind(field) === "flags") {
+        var boxes = form.querySelectorAll('[data-flags="' + CSS.escape(field.name) + '"] input:checked');
+        body[field.name] = Array.prototype.map.call(boxes, function (box) { return box.value; });
+        return;
+      }
+      var control = form.elements[field.name];
+      if (!control) return;
+      switch (kind(field)) {
+        case "boolean":
+          body[field.name] = control.checked;
+          break;
+        case "number":
+          if (control.value !== "") body[field.name] = Number(control.value);
+          break;
+        case "datetime":
+          if (control.value !== "") body[field.name] = new Date(control.value).toISOString();
+          break;
+        case "json":
+          if (control.value.trim() !== "") {
+            try {
+              body[field.name] = JSON.parse(control.value);
+            } catch (e) {
+              throw new Error((field.label || field.name) + ": invalid JSON");
+            }
+          }
+          break;
+        default:
+          if (control.value !== "" || field.required) body[field.name] = control.value;
+      }
+    });
+    return body;
+  }
+
+  function localTime(value) {
+    var date = new Date(value);
+    if (isNaN(date)) return "";
+    var offset = date.getTimezoneOffset() * 60000;
+    return new Date(date.getTime() - offset).toISOString().slice(0, 19);
+  }
+
+  // --- Startup ------------------------------------------------------------
+
+  var tokenForm = document.getElementById("token");
+  tokenForm.elements.token.value = localStorage.getItem("refineGinAdminToken") || "";
+  tokenForm.addEventListener("submit", function (event) {
+    event.preventDefault();
+    var token = tokenForm.elements.token.value.trim();
+    if (token) localStorage.setItem("refineGinAdminToken", token);
+    else localStorage.removeItem("refineGinAdminToken");
+    load();
+  });
+
+  function load() {
+    request("GET", "/config").then(function (config) {
+      resources = (config && config.resources) || {};
+      route();
+    }).catch(function (err) {
+      renderNav();
+      render();
+      showError(new Error("Could not load " + api + "/config: " + err.message));
+    });
+  }
+
+  window.addEventListener("hashchange", route);
+  load();
+})();
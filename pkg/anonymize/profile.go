@@ -0,0 +1,90 @@
+package anonymize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Built-in anonymization strategies
+const (
+	// StrategyFakeEmail replaces values with a deterministic fake e-mail address
+	StrategyFakeEmail = "fake-email"
+
+	// StrategyFakeName replaces values with a deterministic fake person name
+	StrategyFakeName = "fake-name"
+
+	// StrategyFakePhone replaces values with a deterministic fake phone number
+	StrategyFakePhone = "fake-phone"
+
+	// StrategyHash replaces values with a salted SHA-256 hash
+	StrategyHash = "hash"
+
+	// StrategyMask keeps the first and last character and masks the rest
+	StrategyMask = "mask"
+
+	// StrategyShuffle permutes the values of the column between records
+	StrategyShuffle = "shuffle"
+
+	// StrategyNull clears the value
+	StrategyNull = "null"
+
+	// StrategyFixed replaces values with Rule.Value
+	StrategyFixed = "fixed"
+
+	// StrategyCustom replaces values using Rule.Func
+	StrategyCustom = "custom"
+)
+
+// Rule describes how a single field is anonymized
+type Rule struct {
+	Strategy string      `json:"strategy"`
+	Value    interface{} `json:"value,omitempty"`
+
+	// Func computes the replacement for StrategyCustom; it receives the original
+	// value and the record ID
+	Func func(value interface{}, id interface{}) interface{} `json:"-"`
+}
+
+// UnmarshalJSON accepts either a strategy name or a rule object
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var strategy string
+	if err := json.Unmarshal(data, &strategy); err == nil {
+		r.Strategy = strategy
+		return nil
+	}
+	type rule Rule
+	return json.Unmarshal(data, (*rule)(r))
+}
+
+// Profile maps resource names to field rules
+type Profile map[string]map[string]Rule
+
+// LoadProfile reads a JSON profile such as
+// {"users": {"email": "fake-email", "name": "fake-name", "notes": {"strategy": "fixed", "value": ""}}}
+func LoadProfile(r io.Reader) (Profile, error) {
+	var profile Profile
+	if err := json.NewDecoder(r).Decode(&profile); err != nil {
+		return nil, err
+	}
+	return profile, profile.Validate()
+}
+
+// Validate checks that all rules use a known strategy
+func (p Profile) Validate() error {
+	for resourceName, fields := range p {
+		for field, rule := range fields {
+			switch rule.Strategy {
+			case StrategyFakeEmail, StrategyFakeName, StrategyFakePhone, StrategyHash,
+				StrategyMask, StrategyShuffle, StrategyNull, StrategyFixed:
+			case StrategyCustom:
+				if rule.Func == nil {
+					return fmt.Errorf("%s.%s: custom strategy requires Func", resourceName, field)
+				}
+			default:
+				return fmt.Errorf("%s.%s: unknown strategy %q", resourceName, field, rule.Strategy)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package anonymize
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingSalt is returned when a runner is started without a salt. Without a
+// secret salt hashed and fake values could be reversed by hashing known inputs.
+var ErrMissingSalt = errors.New("anonymization salt is required")
+
+// ResourceReport summarizes the anonymization of one resource
+type ResourceReport struct {
+	Resource string   `json:"resource"`
+	Fields   []string `json:"fields"`
+	Rows     int      `json:"rows"`
+}
+
+// Report is the result of an anonymization run
+type Report struct {
+	StartedAt time.Time        `json:"startedAt"`
+	Duration  time.Duration    `json:"duration"`
+	Resources []ResourceReport `json:"resources"`
+}
+
+// Config contains configuration for the anonymization runner
+type Config struct {
+	// Database copy to rewrite
+	DB *gorm.DB
+
+	// Anonymization rules per resource and field
+	Profile Profile
+
+	// Resources referenced by the profile (defaults to the global registry)
+	Resources []resource.Resource
+
+	// Secret mixed into hashed and fake values. The same input produces the same
+	// output within a run, so values shared between tables stay consistent.
+	Salt string
+
+	// Number of records rewritten per transaction (default 500)
+	BatchSize int
+
+	// Guard is called before anything is changed; return an error to abort, e.g.
+	// when DB points at a production database
+	Guard func(db *gorm.DB) error
+
+	// OnProgress is called after each batch with the number of rows processed so far
+	OnProgress func(resource string, rows int)
+}
+
+// Runner rewrites a database copy according to an anonymization profile
+type Runner struct {
+	config Config
+}
+
+// NewRunner creates a new anonymization runner
+func NewRunner(config Config) *Runner {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	return &Runner{config: config}
+}
+
+// plan is a resolved profile entry for one resource
+type plan struct {
+	res      resource.Resource
+	table    string
+	idColumn string
+	fields   []string
+	columns  map[string]string
+}
+
+// Run anonymizes all resources of the profile in name order
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	if r.config.Salt == "" {
+		return nil, ErrMissingSalt
+	}
+	if err := r.config.Profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	plans, err := r.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if r.config.Guard != nil {
+		if err := r.config.Guard(r.config.DB); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{StartedAt: time.Now(), Resources: []ResourceReport{}}
+	for _, p := range plans {
+		rows, err := r.anonymize(ctx, p)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", p.res.GetName(), err)
+		}
+		report.Resources = append(report.Resources, ResourceReport{Resource: p.res.GetName(), Fields: p.fields, Rows: rows})
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// resolve maps profile entries to tables and columns before any data is touched
+func (r *Runner) resolve() ([]plan, error) {
+	names := make([]string, 0, len(r.config.Profile))
+	for name := range r.config.Profile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plans := make([]plan, 0, len(names))
+	for _, name := range names {
+		res := r.lookup(name)
+		if res == nil {
+			return nil, fmt.Errorf("resource %q not found", name)
+		}
+
+		stmt := &gorm.Statement{DB: r.config.DB}
+		if err := stmt.Parse(res.GetModel()); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		p := plan{
+			res:      res,
+			table:    stmt.Schema.Table,
+			idColumn: columnFor(stmt.Schema, res.GetIDFieldName()),
+			columns:  map[string]string{},
+		}
+		if p.idColumn == "" {
+			return nil, fmt.Errorf("%s: ID field %q not found", name, res.GetIDFieldName())
+		}
+
+		for field := range r.config.Profile[name] {
+			column := columnFor(stmt.Schema, field)
+			if column == "" {
+				return nil, fmt.Errorf("%s: field %q has no column", name, field)
+			}
+			if column == p.idColumn {
+				return nil, fmt.Errorf("%s: the ID field cannot be anonymized", name)
+			}
+			p.columns[field] = column
+			p.fields = append(p.fields, field)
+		}
+		sort.Strings(p.fields)
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+func (r *Runner) lookup(name string) resource.Resource {
+	if r.config.Resources == nil {
+		res, ok := resource.GlobalResourceRegistry.GetByName(name)
+		if !ok {
+			return nil
+		}
+		return res
+	}
+	for _, res := range r.config.Resources {
+		if res.GetName() == name {
+			return res
+		}
+	}
+	return nil
+}
+
+// anonymize rewrites one resource batch by batch, including soft-deleted records
+func (r *Runner) anonymize(ctx context.Context, p plan) (int, error) {
+	rules := r.config.Profile[p.res.GetName()]
+	shuffled, err := r.shuffle(ctx, p, rules)
+	if err != nil {
+		return 0, err
+	}
+
+	selectColumns := []string{p.idColumn}
+	for _, field := range p.fields {
+		selectColumns = append(selectColumns, p.columns[field])
+	}
+
+	processed := 0
+	for offset := 0; ; offset += r.config.BatchSize {
+		var rows []map[string]interface{}
+		err := r.config.DB.WithContext(ctx).Table(p.table).
+			Select(selectColumns).Order(p.idColumn).
+			Limit(r.config.BatchSize).Offset(offset).
+			Find(&rows).Error
+		if err != nil {
+			return processed, err
+		}
+
+		err = r.config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				id := row[p.idColumn]
+				updates := make(map[string]interface{}, len(p.fields))
+				for _, field := range p.fields {
+					column := p.columns[field]
+					if values, ok := shuffled[column]; ok {
+						updates[column] = values[fmt.Sprint(id)]
+						continue
+					}
+					updates[column] = replace(rules[field], r.config.Salt, row[column], id)
+				}
+				if err := tx.Table(p.table).Where(p.idColumn+" = ?", id).UpdateColumns(updates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return processed, err
+		}
+
+		processed += len(rows)
+		if r.config.OnProgress != nil {
+			r.config.OnProgress(p.res.GetName(), processed)
+		}
+		if len(rows) < r.config.BatchSize {
+			return processed, nil
+		}
+	}
+}
+
+// shuffle loads the columns using StrategyShuffle and returns the new value of each
+// record by column and record ID. Values are permuted across the whole table, so the
+// columns are held in memory for the duration of the run.
+func (r *Runner) shuffle(ctx context.Context, p plan, rules map[string]Rule) (map[string]map[string]interface{}, error) {
+	shuffled := map[string]map[string]interface{}{}
+	for _, field := range p.fields {
+		if rules[field].Strategy != StrategyShuffle {
+			continue
+		}
+		column := p.columns[field]
+
+		var rows []map[string]interface{}
+		for offset := 0; ; offset += r.config.BatchSize {
+			var batch []map[string]interface{}
+			err := r.config.DB.WithContext(ctx).Table(p.table).
+				Select(p.idColumn, column).Order(p.idColumn).
+				Limit(r.config.BatchSize).Offset(offset).
+				Find(&batch).Error
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, batch...)
+			if len(batch) < r.config.BatchSize {
+				break
+			}
+		}
+
+		sum := digest(r.config.Salt, p.table+"."+column)
+		rng := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+		order := rng.Perm(len(rows))
+
+		values := make(map[string]interface{}, len(rows))
+		for i, row := range rows {
+			values[fmt.Sprint(row[p.idColumn])] = rows[order[i]][column]
+		}
+		shuffled[column] = values
+	}
+	return shuffled, nil
+}
+
+// columnFor resolves a field name (JSON name, Go name or column name) to its column
+func columnFor(s *schema.Schema, name string) string {
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(jsonName, name) || strings.EqualFold(field.Name, name) || field.DBName == name {
+			return field.DBName
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package anonymize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Customer struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Email     string         `json:"email" gorm:"uniqueIndex"`
+	FullName  string         `json:"full_name"`
+	Phone     *string        `json:"phone"`
+	City      string         `json:"city"`
+	Notes     string         `json:"notes"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type Invoice struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Email string `json:"email"`
+}
+
+func setupRunnerTest(t *testing.T) (*gorm.DB, []resource.Resource) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Customer{}, &Invoice{}))
+
+	phone := "+48 600 700 800"
+	cities := []string{"Warsaw", "Berlin", "Paris", "Rome", "Madrid"}
+	for i, city := range cities {
+		customer := Customer{
+			Email:    strings.ToLower(city) + "@real.com",
+			FullName: "Real Person " + city,
+			City:     city,
+			Notes:    "secret",
+		}
+		if i == 0 {
+			customer.Phone = &phone
+		}
+		require.NoError(t, db.Create(&customer).Error)
+		require.NoError(t, db.Create(&Invoice{Email: customer.Email}).Error)
+	}
+	require.NoError(t, db.Delete(&Customer{}, 5).Error)
+
+	return db, []resource.Resource{
+		resource.NewResource(resource.ResourceConfig{Name: "customers", Model: Customer{}}),
+		resource.NewResource(resource.ResourceConfig{Name: "invoices", Model: Invoice{}}),
+	}
+}
+
+func TestRunner(t *testing.T) {
+	db, resources := setupRunnerTest(t)
+
+	var progress []int
+	runner := NewRunner(Config{
+		DB:        db,
+		Resources: resources,
+		Salt:      "s3cret",
+		BatchSize: 2,
+		Profile: Profile{
+			"customers": {
+				"email":     {Strategy: StrategyFakeEmail},
+				"full_name": {Strategy: StrategyFakeName},
+				"phone":     {Strategy: StrategyFakePhone},
+				"city":      {Strategy: StrategyShuffle},
+				"notes":     {Strategy: StrategyFixed, Value: "redacted"},
+			},
+			"invoices": {
+				"email": {Strategy: StrategyFakeEmail},
+			},
+		},
+		OnProgress: func(res string, rows int) {
+			if res == "customers" {
+				progress = append(progress, rows)
+			}
+		},
+	})
+
+	report, err := runner.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Resources, 2)
+	assert.Equal(t, "customers", report.Resources[0].Resource)
+	assert.Equal(t, 5, report.Resources[0].Rows)
+	assert.Equal(t, []string{"city", "email", "full_name", "notes", "phone"}, report.Resources[0].Fields)
+	assert.Equal(t, []int{2, 4, 5}, progress)
+
+	var customers []Customer
+	require.NoError(t, db.Unscoped().Order("id").Find(&customers).Error)
+	require.Len(t, customers, 5)
+
+	var invoices []Invoice
+	require.NoError(t, db.Order("id").Find(&invoices).Error)
+
+	var cities []string
+	for i, customer := range customers {
+		assert.True(t, strings.HasSuffix(customer.Email, "@example.com"), customer.Email)
+		assert.NotContains(t, customer.FullName, "Real")
+		assert.Equal(t, "redacted", customer.Notes)
+		// The same input produces the same fake value across tables
+		assert.Equal(t, customer.Email, invoices[i].Email)
+		cities = append(cities, customer.City)
+	}
+	require.NotNil(t, customers[0].Phone)
+	assert.True(t, strings.HasPrefix(*customers[0].Phone, "+1555"))
+	assert.Nil(t, customers[1].Phone)
+
+	sort.Strings(cities)
+	assert.Equal(t, []string{"Berlin", "Madrid", "Paris", "Rome", "Warsaw"}, cities)
+}
+
+func TestRunnerErrors(t *testing.T) {
+	db, resources := setupRunnerTest(t)
+	profile := Profile{"customers": {"email": {Strategy: StrategyHash}}}
+
+	_, err := NewRunner(Config{DB: db, Resources: resources, Profile: profile}).Run(context.Background())
+	assert.ErrorIs(t, err, ErrMissingSalt)
+
+	errProduction := errors.New("production database")
+	_, err = NewRunner(Config{
+		DB:        db,
+		Resources: resources,
+		Profile:   profile,
+		Salt:      "s",
+		Guard:     func(*gorm.DB) error { return errProduction },
+	}).Run(context.Background())
+	assert.ErrorIs(t, err, errProduction)
+
+	var customer Customer
+	require.NoError(t, db.First(&customer, 1).Error)
+	assert.Equal(t, "warsaw@real.com", customer.Email)
+
+	for _, p := range []Profile{
+		{"unknown": {"email": {Strategy: StrategyHash}}},
+		{"customers": {"missing": {Strategy: StrategyHash}}},
+		{"customers": {"id": {Strategy: StrategyHash}}},
+		{"customers": {"email": {Strategy: "scramble"}}},
+	} {
+		_, err := NewRunner(Config{DB: db, Resources: resources, Profile: p, Salt: "s"}).Run(context.Background())
+		assert.Error(t, err)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	profile, err := LoadProfile(strings.NewReader(`{"users": {"email": "fake-email", "notes": {"strategy": "fixed", "value": ""}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, StrategyFakeEmail, profile["users"]["email"].Strategy)
+	assert.Equal(t, Rule{Strategy: StrategyFixed, Value: ""}, profile["users"]["notes"])
+
+	_, err = LoadProfile(strings.NewReader(`{"users": {"email": "custom"}}`))
+	assert.Error(t, err)
+}
+
+func TestReplace(t *testing.T) {
+	assert.Equal(t, "j******e", replace(Rule{Strategy: StrategyMask}, "s", "jane doe", 1))
+	assert.Equal(t, "**", mask("ab"))
+	assert.Len(t, replace(Rule{Strategy: StrategyHash}, "s", "value", 1), 32)
+	assert.NotEqual(t, replace(Rule{Strategy: StrategyHash}, "a", "value", 1), replace(Rule{Strategy: StrategyHash}, "b", "value", 1))
+	assert.Nil(t, replace(Rule{Strategy: StrategyNull}, "s", "value", 1))
+	assert.Equal(t, "id-7", replace(Rule{Strategy: StrategyCustom, Func: func(v, id interface{}) interface{} {
+		return fmt.Sprintf("id-%v", id)
+	}}, "s", "value", 7))
+}
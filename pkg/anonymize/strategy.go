@@ -0,0 +1,67 @@
+package anonymize
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+var firstNames = []string{
+	"Alex", "Blake", "Casey", "Dana", "Eli", "Frankie", "Gray", "Harper", "Indy", "Jordan",
+	"Kai", "Logan", "Morgan", "Noel", "Oakley", "Parker", "Quinn", "Riley", "Sage", "Taylor",
+}
+
+var lastNames = []string{
+	"Anderson", "Brooks", "Carter", "Dawson", "Ellis", "Fischer", "Garcia", "Hughes", "Ivanov", "Jensen",
+	"Kowalski", "Larsen", "Moreau", "Novak", "Olsen", "Petrov", "Rossi", "Schmidt", "Tanaka", "Weber",
+}
+
+// digest returns the salted SHA-256 digest of a value
+func digest(salt string, value interface{}) []byte {
+	sum := sha256.Sum256([]byte(salt + "\x00" + fmt.Sprint(value)))
+	return sum[:]
+}
+
+// replace computes the replacement of a non-nil value for per-value strategies
+func replace(rule Rule, salt string, value, id interface{}) interface{} {
+	switch rule.Strategy {
+	case StrategyNull:
+		return nil
+	case StrategyFixed:
+		return rule.Value
+	case StrategyCustom:
+		return rule.Func(value, id)
+	}
+
+	if value == nil {
+		return nil
+	}
+	sum := digest(salt, value)
+
+	switch rule.Strategy {
+	case StrategyFakeEmail:
+		return "user-" + hex.EncodeToString(sum[:6]) + "@example.com"
+	case StrategyFakeName:
+		n := binary.BigEndian.Uint32(sum[:4])
+		m := binary.BigEndian.Uint32(sum[4:8])
+		return firstNames[n%uint32(len(firstNames))] + " " + lastNames[m%uint32(len(lastNames))]
+	case StrategyFakePhone:
+		return fmt.Sprintf("+1555%07d", binary.BigEndian.Uint32(sum[:4])%10000000)
+	case StrategyHash:
+		return hex.EncodeToString(sum[:16])
+	case StrategyMask:
+		return mask(fmt.Sprint(value))
+	}
+	return value
+}
+
+// mask keeps the first and last character of a string
+func mask(s string) string {
+	runes := []rune(s)
+	if len(runes) <= 2 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1])
+}
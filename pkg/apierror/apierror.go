@@ -0,0 +1,140 @@
+// Package apierror writes error responses in the format of refine's HttpError, so
+// the handlers, middlewares and plugins of refine-gin answer errors alike: data
+// providers show Message in notifications and forms show the messages of Errors under
+// their fields.
+//
+//	{"error": "Resource not found", "message": "Resource not found", "statusCode": 404, "code": "not_found"}
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes of error responses, stable identifiers clients can branch on
+const (
+	CodeBadRequest      = "bad_request"
+	CodeValidation      = "validation_failed"
+	CodeInvalidJSON     = "invalid_json"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeNotSupported    = "not_supported"
+	CodeConflict        = "conflict"
+	CodePayloadTooLarge = "payload_too_large"
+	CodeUnprocessable   = "unprocessable"
+	CodeUnavailable     = "unavailable"
+	CodeInternal        = "internal_error"
+	CodeUnsupportedMIME = "unsupported_media_type"
+)
+
+// Response is the body of error responses. Error repeats Message for clients reading
+// the error key.
+type Response struct {
+	Error      string              `json:"error"`
+	Message    string              `json:"message"`
+	StatusCode int                 `json:"statusCode"`
+	Code       string              `json:"code"`
+	Errors     map[string][]string `json:"errors,omitempty"`
+}
+
+// Translator customizes error responses before they are written, e.g. to translate
+// their messages to the locale of the request (requestctx.Locale). err is the error
+// the response was built from, nil for plain messages.
+type Translator func(c *gin.Context, response *Response, err error)
+
+// Translate is called with every error response, if set
+var Translate Translator
+
+// New returns the response of a status with a message
+func New(status int, message string) *Response {
+	return &Response{Error: message, Message: message, StatusCode: status, Code: CodeFor(status)}
+}
+
+// Prepare passes a response to Translate and returns it. Responses with more members
+// embed the Response and are prepared before they are written.
+func Prepare(c *gin.Context, response *Response, err error) *Response {
+	if Translate != nil {
+		Translate(c, response, err)
+		response.Error = response.Message
+	}
+	return response
+}
+
+// Write prepares a response and writes it
+func Write(c *gin.Context, response *Response, err error) {
+	c.JSON(response.StatusCode, Prepare(c, response, err))
+}
+
+// WriteWith prepares a response and writes it with more members, e.g. the record a
+// request conflicts with
+func WriteWith(c *gin.Context, response *Response, err error, members gin.H) {
+	Prepare(c, response, err)
+	body := gin.H{
+		"error":      response.Error,
+		"message":    response.Message,
+		"statusCode": response.StatusCode,
+		"code":       response.Code,
+	}
+	if len(response.Errors) > 0 {
+		body["errors"] = response.Errors
+	}
+	for name, value := range members {
+		body[name] = value
+	}
+	c.JSON(response.StatusCode, body)
+}
+
+// Respond writes the response of an error with a status
+func Respond(c *gin.Context, status int, err error) {
+	Write(c, New(status, err.Error()), err)
+}
+
+// RespondMessage writes the response of a status with a message
+func RespondMessage(c *gin.Context, status int, message string) {
+	Write(c, New(status, message), nil)
+}
+
+// Abort writes the response of an error with a status and stops the handler chain
+func Abort(c *gin.Context, status int, err error) {
+	c.Abort()
+	Respond(c, status, err)
+}
+
+// AbortMessage writes the response of a status with a message and stops the handler
+// chain
+func AbortMessage(c *gin.Context, status int, message string) {
+	c.Abort()
+	RespondMessage(c, status, message)
+}
+
+// CodeFor returns the code of responses with a status
+func CodeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return CodeNotSupported
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMIME
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
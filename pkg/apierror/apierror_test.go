@@ -0,0 +1,71 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/missing", func(c *gin.Context) {
+		RespondMessage(c, http.StatusNotFound, "Resource not found")
+	})
+	r.GET("/locked", func(c *gin.Context) {
+		AbortMessage(c, http.StatusLocked, "locked")
+	})
+	r.GET("/conflict", func(c *gin.Context) {
+		WriteWith(c, New(http.StatusConflict, "duplicate file"), nil, gin.H{"duplicates": []int{1}})
+	})
+	r.GET("/failed", func(c *gin.Context) {
+		Respond(c, http.StatusInternalServerError, errors.New("boom"))
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": "Resource not found", "message": "Resource not found", "statusCode": 404, "code": "not_found"}`, w.Body.String())
+
+	w = get("/locked")
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.JSONEq(t, `{"error": "locked", "message": "locked", "statusCode": 423, "code": "bad_request"}`, w.Body.String())
+
+	w = get("/conflict")
+	assert.JSONEq(t, `{"error": "duplicate file", "message": "duplicate file", "statusCode": 409, "code": "conflict",
+		"duplicates": [1]}`, w.Body.String())
+
+	w = get("/failed")
+	assert.JSONEq(t, `{"error": "boom", "message": "boom", "statusCode": 500, "code": "internal_error"}`, w.Body.String())
+}
+
+func TestTranslate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Translate = func(c *gin.Context, response *Response, err error) {
+		if c.GetHeader("Accept-Language") == "pl" && response.Code == CodeConflict {
+			response.Message = "Duplikat pliku"
+		}
+	}
+	defer func() { Translate = nil }()
+
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		WriteWith(c, New(http.StatusConflict, "duplicate file"), nil, gin.H{"duplicates": []int{1}})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "pl")
+	r.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"error": "Duplikat pliku", "message": "Duplikat pliku", "statusCode": 409, "code": "conflict",
+		"duplicates": [1]}`, w.Body.String())
+}
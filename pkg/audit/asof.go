@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// AsOfParam is the query parameter selecting the point in time of a time-travel read
+const AsOfParam = "asOf"
+
+// AsOfConfig contains configuration for time-travel reads
+type AsOfConfig struct {
+	// Store holding the audit entries of the resource
+	Store *GormStore
+
+	// Lists enables ?asOf on the list route. The whole audit history of the resource
+	// is read, so only enable it for small resources.
+	Lists bool
+
+	// Name of the record ID route parameter (default "id")
+	IDParam string
+}
+
+// StateAt reconstructs a record at a point in time from its audit entries. It returns
+// nil if the record did not exist (or was not audited yet) at that time.
+func (s *GormStore) StateAt(ctx context.Context, resourceName string, recordID interface{}, at time.Time) (json.RawMessage, error) {
+	entries, err := s.History(ctx, resourceName, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	var state json.RawMessage
+	for _, entry := range entries {
+		if entry.CreatedAt.After(at) {
+			break
+		}
+		state = entry.After
+	}
+	return state, nil
+}
+
+// ListAt reconstructs all records of a resource at a point in time, ordered by record ID
+func (s *GormStore) ListAt(ctx context.Context, resourceName string, at time.Time) ([]json.RawMessage, error) {
+	var entries []Entry
+	err := s.DB.WithContext(ctx).
+		Where("resource = ? AND created_at <= ?", resourceName, at).
+		Order("created_at, id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]json.RawMessage)
+	for _, entry := range entries {
+		if len(entry.After) == 0 {
+			delete(states, entry.RecordID)
+		} else {
+			states[entry.RecordID] = entry.After
+		}
+	}
+
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+
+	records := make([]json.RawMessage, len(ids))
+	for i, id := range ids {
+		records[i] = states[id]
+	}
+	return records, nil
+}
+
+// AsOf is a middleware answering reads of a resource with ?asOf=<RFC 3339 time> from
+// its audit history instead of the current data: GET /<resource>/:id returns the
+// record as it was at that time and, with AsOfConfig.Lists, GET /<resource> returns
+// all records that existed then. Resource and field read permissions are applied.
+// Requests without ?asOf are passed through.
+func AsOf(res resource.Resource, config AsOfConfig) gin.HandlerFunc {
+	if config.IDParam == "" {
+		config.IDParam = "id"
+	}
+	collection := "/" + res.GetName()
+	item := collection + "/:" + config.IDParam
+
+	return func(c *gin.Context) {
+		raw, ok := c.GetQuery(AsOfParam)
+		path := c.FullPath()
+		isItem := strings.HasSuffix(path, item)
+		isList := config.Lists && strings.HasSuffix(path, collection)
+		if !ok || c.Request.Method != http.MethodGet || (!isItem && !isList) {
+			c.Next()
+			return
+		}
+
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			apierror.AbortMessage(c, http.StatusBadRequest, "asOf must be an RFC 3339 time")
+			return
+		}
+
+		op := resource.OperationList
+		if isItem {
+			op = resource.OperationRead
+		}
+		roles := auth.UserRoles(c)
+		if !auth.CanPerform(res, op, roles) {
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: insufficient permissions for this resource")
+			return
+		}
+
+		if isItem {
+			state, err := config.Store.StateAt(c.Request.Context(), res.GetName(), c.Param(config.IDParam), at)
+			if err != nil {
+				apierror.Abort(c, http.StatusInternalServerError, err)
+				return
+			}
+			if state == nil {
+				apierror.AbortMessage(c, http.StatusNotFound, "Resource not found at the given time")
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusOK, gin.H{"data": readableState(res, state, roles)})
+			return
+		}
+
+		states, err := config.Store.ListAt(c.Request.Context(), res.GetName(), at)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, err)
+			return
+		}
+		records := make([]map[string]interface{}, len(states))
+		for i, state := range states {
+			records[i] = readableState(res, state, roles)
+		}
+		c.AbortWithStatusJSON(http.StatusOK, gin.H{"data": records, "total": len(records)})
+	}
+}
+
+// readableState decodes a stored record without the fields the roles may not read
+func readableState(res resource.Resource, state json.RawMessage, roles []string) map[string]interface{} {
+	var record map[string]interface{}
+	_ = json.Unmarshal(state, &record)
+	for _, field := range res.GetFields() {
+		if !auth.CanAccessField(field, "read", roles) {
+			delete(record, field.Name)
+			delete(record, field.APIName())
+		}
+	}
+	return record
+}
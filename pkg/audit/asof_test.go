@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var asOfBase = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func setupAsOf(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	t.Cleanup(func() { resource.GlobalResourceRegistry = registry })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+	store := NewGormStore(db)
+	require.NoError(t, store.AutoMigrate())
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "documents",
+		Model: Document{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "pages", Type: "int", Permissions: map[string][]string{"read": {"editor"}}},
+		},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+	})
+	repo := NewRepository(repository.NewGenericRepositoryWithResource(db, res), res, Config{Store: store})
+
+	// A and B are created on Jan 2, A is renamed on Jan 3 and B is deleted on Jan 4
+	ctx := context.Background()
+	_, err = repo.Create(ctx, &Document{Title: "A", Pages: 1})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, &Document{Title: "B", Pages: 2})
+	require.NoError(t, err)
+	_, err = repo.Update(ctx, 1, &Document{ID: 1, Title: "A2", Pages: 1})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, 2))
+	for id, day := range map[int]int{1: 1, 2: 1, 3: 2, 4: 3} {
+		require.NoError(t, db.Model(&Entry{}).Where("id = ?", id).Update("created_at", asOfBase.AddDate(0, 0, day)).Error)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if role := c.GetHeader("X-Roles"); role != "" {
+			requestctx.Roles.Set(c, []string{role})
+		}
+	})
+	api := r.Group("/api", AsOf(res, AsOfConfig{Store: store, Lists: true}))
+	handler.RegisterResource(api, res, repo)
+	return r
+}
+
+func asOfRequest(r *gin.Engine, path, role string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Roles", role)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAsOfRecord(t *testing.T) {
+	r := setupAsOf(t)
+
+	w := asOfRequest(r, "/api/documents/1?asOf=2024-01-02T12:00:00Z", "editor")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"data":{"id":1,"title":"A","pages":1}}`, w.Body.String())
+
+	w = asOfRequest(r, "/api/documents/1?asOf=2024-01-05T00:00:00Z", "editor")
+	assert.JSONEq(t, `{"data":{"id":1,"title":"A2","pages":1}}`, w.Body.String())
+
+	// Deleted records can still be read before their deletion
+	w = asOfRequest(r, "/api/documents/2?asOf=2024-01-02T00:00:00Z", "editor")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, asOfRequest(r, "/api/documents/2?asOf=2024-01-04T00:00:00Z", "editor").Code)
+	assert.Equal(t, http.StatusNotFound, asOfRequest(r, "/api/documents/1?asOf=2023-12-31T00:00:00Z", "editor").Code)
+
+	// Without asOf the current record is returned
+	w = asOfRequest(r, "/api/documents/1", "editor")
+	assert.Contains(t, w.Body.String(), `"title":"A2"`)
+
+	assert.Equal(t, http.StatusBadRequest, asOfRequest(r, "/api/documents/1?asOf=yesterday", "editor").Code)
+}
+
+func TestAsOfList(t *testing.T) {
+	r := setupAsOf(t)
+
+	var resp struct {
+		Data  []map[string]interface{} `json:"data"`
+		Total int                      `json:"total"`
+	}
+	w := asOfRequest(r, "/api/documents?asOf=2024-01-02T12:00:00Z", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Total)
+	assert.Equal(t, "A", resp.Data[0]["title"])
+	assert.Equal(t, "B", resp.Data[1]["title"])
+
+	// Unreadable fields are removed
+	assert.NotContains(t, resp.Data[0], "pages")
+
+	w = asOfRequest(r, "/api/documents?asOf=2024-01-04T12:00:00Z", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Total)
+	assert.Equal(t, "A2", resp.Data[0]["title"])
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// ResourceName is the name of the read-only resource exposing the audit log
+const ResourceName = "audit-logs"
+
+// Entry records a single change of a record
+type Entry struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	Resource  string          `json:"resource" gorm:"size:191;index:idx_audit_logs_record"`
+	RecordID  string          `json:"recordId" gorm:"size:191;index:idx_audit_logs_record"`
+	Action    string          `json:"action" gorm:"size:32;index"`
+	Actor     string          `json:"actor,omitempty" gorm:"size:191;index"`
+	RequestID string          `json:"requestId,omitempty" gorm:"size:128"`
+	Before    json.RawMessage `json:"before,omitempty" gorm:"type:text"`
+	After     json.RawMessage `json:"after,omitempty" gorm:"type:text"`
+	Changes   json.RawMessage `json:"changes,omitempty" gorm:"type:text"`
+	CreatedAt time.Time       `json:"createdAt" gorm:"index"`
+}
+
+// TableName returns the table used to store audit entries
+func (Entry) TableName() string {
+	return "refine_audit_logs"
+}
+
+// Change is the old and new value of a changed field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Store persists audit entries
+type Store interface {
+	Record(ctx context.Context, entries []*Entry) error
+}
+
+// GormStore stores audit entries in a database table
+type GormStore struct {
+	DB *gorm.DB
+}
+
+// NewGormStore creates a new database audit store
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{DB: db}
+}
+
+// AutoMigrate creates or updates the audit log table
+func (s *GormStore) AutoMigrate() error {
+	return s.DB.AutoMigrate(&Entry{})
+}
+
+// Record stores the entries
+func (s *GormStore) Record(ctx context.Context, entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).Create(entries).Error
+}
+
+// History returns the entries of a record, oldest first
+func (s *GormStore) History(ctx context.Context, resourceName string, recordID interface{}) ([]Entry, error) {
+	var entries []Entry
+	err := s.DB.WithContext(ctx).
+		Where("resource = ? AND record_id = ?", resourceName, fmt.Sprintf("%v", recordID)).
+		Order("created_at, id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// Resource returns the read-only resource exposing the audit log
+func Resource() resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  ResourceName,
+		Label: "Audit Logs",
+		Model: Entry{},
+		Operations: []resource.Operation{
+			resource.OperationList,
+			resource.OperationRead,
+			resource.OperationCount,
+		},
+		DefaultSort: &resource.Sort{
+			Field: "id",
+			Order: "desc",
+		},
+	})
+}
+
+// RegisterRoutes registers the read-only audit-logs resource backed by the store
+func RegisterRoutes(router *gin.RouterGroup, store *GormStore) {
+	handler.RegisterResource(router, Resource(), repository.NewGenericRepository(store.DB, &Entry{}))
+}
+
+// DefaultActor uses the owner ID from the request context as actor
+func DefaultActor(ctx context.Context) string {
+	if ownerID, ok := requestctx.OwnerID.Get(ctx); ok && ownerID != nil {
+		return fmt.Sprintf("%v", ownerID)
+	}
+	return ""
+}
+
+// snapshot serializes a record, returning nil for missing records
+func snapshot(record interface{}) (json.RawMessage, map[string]interface{}) {
+	if record == nil {
+		return nil, nil
+	}
+	if value := reflect.ValueOf(record); value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data, nil
+	}
+	return data, fields
+}
+
+// diff returns the fields whose serialized values differ between two snapshots
+func diff(before, after map[string]interface{}) json.RawMessage {
+	changes := make(map[string]Change)
+	for key, value := range before {
+		if !reflect.DeepEqual(value, after[key]) {
+			changes[key] = Change{From: value, To: after[key]}
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = Change{To: value}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(changes)
+	return data
+}
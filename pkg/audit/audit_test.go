@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Document struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Pages int    `json:"pages"`
+}
+
+type failingStore struct{}
+
+func (failingStore) Record(ctx context.Context, entries []*Entry) error {
+	return errors.New("store unavailable")
+}
+
+func setupAudit(t *testing.T) (*gin.Engine, *GormStore, *Repository) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	t.Cleanup(func() { resource.GlobalResourceRegistry = registry })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+	store := NewGormStore(db)
+	require.NoError(t, store.AutoMigrate())
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "documents",
+		Model: Document{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate,
+			resource.OperationUpdate, resource.OperationDelete,
+		},
+	})
+	repo := NewRepository(repository.NewGenericRepositoryWithResource(db, res), res, Config{Store: store})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		requestctx.OwnerID.Set(c, c.GetHeader("X-User"))
+	})
+	api := r.Group("/api")
+	handler.RegisterResource(api, res, repo)
+	RegisterRoutes(api, store)
+	return r, store, repo
+}
+
+func auditRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", "ann")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuditRecordsMutations(t *testing.T) {
+	r, store, _ := setupAudit(t)
+
+	w := auditRequest(r, http.MethodPost, "/api/documents", `{"title":"Draft","pages":1}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = auditRequest(r, http.MethodPut, "/api/documents/1", `{"title":"Final","pages":1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = auditRequest(r, http.MethodDelete, "/api/documents/1", "")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	entries, err := store.History(context.Background(), "documents", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "create", entries[0].Action)
+	assert.Equal(t, "1", entries[0].RecordID)
+	assert.Equal(t, "ann", entries[0].Actor)
+	assert.Empty(t, entries[0].Before)
+	assert.JSONEq(t, `{"id":1,"title":"Draft","pages":1}`, string(entries[0].After))
+
+	assert.Equal(t, "update", entries[1].Action)
+	assert.JSONEq(t, `{"title":{"from":"Draft","to":"Final"}}`, string(entries[1].Changes))
+
+	assert.Equal(t, "delete", entries[2].Action)
+	assert.JSONEq(t, `{"id":1,"title":"Final","pages":1}`, string(entries[2].Before))
+	assert.Empty(t, entries[2].After)
+}
+
+func TestAuditBulkOperations(t *testing.T) {
+	_, store, repo := setupAudit(t)
+	ctx := context.Background()
+
+	docs := []Document{{Title: "A", Pages: 1}, {Title: "B", Pages: 2}}
+	_, err := repo.CreateMany(ctx, &docs)
+	require.NoError(t, err)
+	_, err = repo.UpdateMany(ctx, []interface{}{1, 2}, map[string]interface{}{"pages": 5})
+	require.NoError(t, err)
+	_, err = repo.DeleteMany(ctx, []interface{}{2})
+	require.NoError(t, err)
+
+	first, err := store.History(ctx, "documents", 1)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, "createMany", first[0].Action)
+	assert.Equal(t, "updateMany", first[1].Action)
+	assert.JSONEq(t, `{"pages":{"from":1,"to":5}}`, string(first[1].Changes))
+
+	second, err := store.History(ctx, "documents", 2)
+	require.NoError(t, err)
+	require.Len(t, second, 3)
+	assert.Equal(t, "deleteMany", second[2].Action)
+}
+
+func TestAuditLogResource(t *testing.T) {
+	r, _, _ := setupAudit(t)
+
+	auditRequest(r, http.MethodPost, "/api/documents", `{"title":"Draft"}`)
+	auditRequest(r, http.MethodPost, "/api/documents", `{"title":"Other"}`)
+
+	w := auditRequest(r, http.MethodGet, "/api/audit-logs?resource=documents", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data []Entry `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, "2", resp.Data[0].RecordID)
+
+	// The audit log is read-only
+	w = auditRequest(r, http.MethodPost, "/api/audit-logs", `{"action":"create"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = auditRequest(r, http.MethodDelete, "/api/audit-logs/1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAuditStoreFailure(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+	res := resource.NewResource(resource.ResourceConfig{Name: "documents", Model: Document{}})
+
+	var failed []*Entry
+	repo := NewRepository(repository.NewGenericRepositoryWithResource(db, res), res, Config{
+		Store:   failingStore{},
+		OnError: func(entries []*Entry, err error) { failed = append(failed, entries...) },
+	})
+
+	// The change is kept even if it cannot be recorded
+	_, err = repo.Create(context.Background(), &Document{Title: "A"})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "1", failed[0].RecordID)
+}
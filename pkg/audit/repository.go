@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// Config contains configuration for audited repositories
+type Config struct {
+	// Store persisting the entries
+	Store Store
+
+	// Actor identifies the user making a change (default DefaultActor)
+	Actor func(ctx context.Context) string
+
+	// OnError is called when entries cannot be recorded. The change itself is not
+	// undone. By default the error is logged.
+	OnError func(entries []*Entry, err error)
+}
+
+func (config Config) withDefaults() Config {
+	if config.Actor == nil {
+		config.Actor = DefaultActor
+	}
+	if config.OnError == nil {
+		config.OnError = func(entries []*Entry, err error) {
+			log.Printf("audit: failed to record %d entries: %v", len(entries), err)
+		}
+	}
+	return config
+}
+
+// Repository decorates a repository, recording every create, update and delete
+// (including bulk operations) of a resource with the state of the record before and
+// after the change. Bulk operations record one entry per record.
+type Repository struct {
+	repository.Repository
+
+	Resource resource.Resource
+	Config   Config
+}
+
+// NewRepository creates an audited repository
+func NewRepository(repo repository.Repository, res resource.Resource, config Config) *Repository {
+	return &Repository{Repository: repo, Resource: res, Config: config.withDefaults()}
+}
+
+// Create creates a resource and records it
+func (r *Repository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.Create(ctx, data)
+	if err != nil {
+		return created, err
+	}
+	r.record(ctx, r.entry(ctx, string(resource.OperationCreate), r.recordID(created), nil, created))
+	return created, nil
+}
+
+// Update updates a resource and records its state before and after the change
+func (r *Repository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	before := r.current(ctx, id)
+	updated, err := r.Repository.Update(ctx, id, data)
+	if err != nil {
+		return updated, err
+	}
+	r.record(ctx, r.entry(ctx, string(resource.OperationUpdate), id, before, updated))
+	return updated, nil
+}
+
+// Delete deletes a resource and records its last state
+func (r *Repository) Delete(ctx context.Context, id interface{}) error {
+	before := r.current(ctx, id)
+	if err := r.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.record(ctx, r.entry(ctx, string(resource.OperationDelete), id, before, nil))
+	return nil
+}
+
+// CreateMany creates resources and records each of them
+func (r *Repository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.CreateMany(ctx, data)
+	if err != nil {
+		return created, err
+	}
+
+	var entries []*Entry
+	items := reflect.Indirect(reflect.ValueOf(created))
+	if items.Kind() == reflect.Slice {
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i).Interface()
+			entries = append(entries, r.entry(ctx, string(resource.OperationCreateMany), r.recordID(item), nil, item))
+		}
+	}
+	r.record(ctx, entries...)
+	return created, nil
+}
+
+// CreateManyPartial creates the items if the decorated repository supports partial
+// bulk creates, and records the created ones
+func (r *Repository) CreateManyPartial(ctx context.Context, items []interface{}) ([]repository.BulkItemResult, error) {
+	creator, ok := r.Repository.(repository.PartialBulkCreator)
+	if !ok {
+		return nil, repository.ErrNotSupported
+	}
+	results, err := creator.CreateManyPartial(ctx, items)
+	if err != nil {
+		return results, err
+	}
+
+	var entries []*Entry
+	for _, result := range results {
+		if !result.Failed() {
+			entries = append(entries, r.entry(ctx, string(resource.OperationCreateMany), result.ID, nil, result.Data))
+		}
+	}
+	r.record(ctx, entries...)
+	return results, nil
+}
+
+// UpdateMany updates resources and records each of them
+func (r *Repository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	before := make([]interface{}, len(ids))
+	for i, id := range ids {
+		before[i] = r.current(ctx, id)
+	}
+
+	affected, err := r.Repository.UpdateMany(ctx, ids, data)
+	if err != nil {
+		return affected, err
+	}
+
+	var entries []*Entry
+	for i, id := range ids {
+		if before[i] != nil {
+			entries = append(entries, r.entry(ctx, string(resource.OperationUpdateMany), id, before[i], r.current(ctx, id)))
+		}
+	}
+	r.record(ctx, entries...)
+	return affected, nil
+}
+
+// DeleteMany deletes resources and records the last state of each of them
+func (r *Repository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	before := make([]interface{}, len(ids))
+	for i, id := range ids {
+		before[i] = r.current(ctx, id)
+	}
+
+	affected, err := r.Repository.DeleteMany(ctx, ids)
+	if err != nil {
+		return affected, err
+	}
+
+	var entries []*Entry
+	for i, id := range ids {
+		if before[i] != nil {
+			entries = append(entries, r.entry(ctx, string(resource.OperationDeleteMany), id, before[i], nil))
+		}
+	}
+	r.record(ctx, entries...)
+	return affected, nil
+}
+
+// WithRelations returns an audited repository that preloads relations
+func (r *Repository) WithRelations(relations ...string) repository.Repository {
+	return &Repository{Repository: r.Repository.WithRelations(relations...), Resource: r.Resource, Config: r.Config}
+}
+
+// WithTransaction runs fn in a transaction with an audited transactional repository
+func (r *Repository) WithTransaction(fn func(repository.Repository) error) error {
+	return r.Repository.WithTransaction(func(tx repository.Repository) error {
+		return fn(&Repository{Repository: tx, Resource: r.Resource, Config: r.Config})
+	})
+}
+
+// current returns the current state of a record, or nil if it cannot be loaded
+func (r *Repository) current(ctx context.Context, id interface{}) interface{} {
+	record, err := r.Repository.Get(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// recordID returns the ID of a record
+func (r *Repository) recordID(record interface{}) interface{} {
+	id, err := utils.GetFieldValue(record, r.Resource.GetIDFieldName())
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+// entry builds the entry of a change
+func (r *Repository) entry(ctx context.Context, action string, id, before, after interface{}) *Entry {
+	beforeJSON, beforeFields := snapshot(before)
+	afterJSON, afterFields := snapshot(after)
+
+	entry := &Entry{
+		Resource:  r.Resource.GetName(),
+		Action:    action,
+		Actor:     r.Config.Actor(ctx),
+		Before:    beforeJSON,
+		After:     afterJSON,
+		Changes:   diff(beforeFields, afterFields),
+		CreatedAt: time.Now(),
+	}
+	if id != nil {
+		entry.RecordID = fmt.Sprintf("%v", id)
+	}
+	if requestID, ok := requestctx.RequestID.Get(ctx); ok {
+		entry.RequestID = requestID
+	}
+	return entry
+}
+
+// record stores entries, reporting failures to OnError
+func (r *Repository) record(ctx context.Context, entries ...*Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := r.Config.Store.Record(context.WithoutCancel(ctx), entries); err != nil {
+		r.Config.OnError(entries, err)
+	}
+}
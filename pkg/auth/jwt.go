@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
 )
 
 // JWTConfig contains configuration for JWT authentication
@@ -19,6 +21,18 @@ type JWTConfig struct {
 	// Token expiration time
 	ExpirationTime time.Duration
 
+	// Refresh token expiration time, used by the auth routes
+	RefreshExpirationTime time.Duration
+
+	// Signing algorithm, "HS256" (default, signed with Secret) or "RS256"
+	SigningMethod string
+
+	// RSA key used to sign RS256 tokens
+	PrivateKey *rsa.PrivateKey
+
+	// RSA key used to verify RS256 tokens; derived from PrivateKey when nil
+	PublicKey *rsa.PublicKey
+
 	// Token issuer
 	Issuer string
 
@@ -32,10 +46,12 @@ type JWTConfig struct {
 // DefaultJWTConfig returns a default JWT configuration
 func DefaultJWTConfig() JWTConfig {
 	return JWTConfig{
-		Secret:         "secret", // Should be overridden in production
-		ExpirationTime: time.Hour * 24,
-		Issuer:         "refine-gin",
-		Audience:       "refine-gin-api",
+		Secret:                "secret", // Should be overridden in production
+		ExpirationTime:        time.Hour * 24,
+		RefreshExpirationTime: time.Hour * 24 * 7,
+		SigningMethod:         jwt.SigningMethodHS256.Alg(),
+		Issuer:                "refine-gin",
+		Audience:              "refine-gin-api",
 		ClaimsExtractor: func(token *jwt.Token) (interface{}, error) {
 			return token.Claims, nil
 		},
@@ -48,14 +64,14 @@ func JWTMiddleware(config JWTConfig) gin.HandlerFunc {
 		// Get token from header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			apierror.AbortMessage(c, http.StatusUnauthorized, "Authorization header is required")
 			return
 		}
 
 		// Check if the header has the correct format
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header must be in the format 'Bearer {token}'"})
+			apierror.AbortMessage(c, http.StatusUnauthorized, "Authorization header must be in the format 'Bearer {token}'")
 			return
 		}
 
@@ -72,20 +88,20 @@ func JWTMiddleware(config JWTConfig) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			apierror.Abort(c, http.StatusUnauthorized, err)
 			return
 		}
 
 		// Check if token is valid
 		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			apierror.AbortMessage(c, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
 		// Extract claims
 		claims, err := config.ClaimsExtractor(token)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			apierror.Abort(c, http.StatusUnauthorized, err)
 			return
 		}
 
@@ -98,10 +114,13 @@ func JWTMiddleware(config JWTConfig) gin.HandlerFunc {
 
 // GenerateJWT generates a JWT token
 func GenerateJWT(config JWTConfig, claims jwt.Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	method, key, err := config.signingKey()
+	if err != nil {
+		return "", err
+	}
 
-	// Sign token with secret
-	tokenString, err := token.SignedString([]byte(config.Secret))
+	// Sign token with the configured key
+	tokenString, err := jwt.NewWithClaims(method, claims).SignedString(key)
 	if err != nil {
 		return "", err
 	}
@@ -127,16 +146,7 @@ func GenerateJWTWithStandardClaims(config JWTConfig, subject string, customClaim
 		claims[key] = value
 	}
 
-	// Utwórz token z claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Podpisz token kluczem tajnym
-	tokenString, err := token.SignedString([]byte(config.Secret))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	return GenerateJWT(config, claims)
 }
 
 // ExtractSubjectFromToken extracts the subject from a JWT token
@@ -201,3 +211,89 @@ func ExtractClaimsFromToken(tokenString string, secret string) (jwt.MapClaims, e
 
 	return claims, nil
 }
+
+// ParseToken validates a token signed with the configured method and key, checking
+// its expiration and, when configured, its issuer and audience
+func ParseToken(config JWTConfig, tokenString string) (jwt.MapClaims, error) {
+	method, err := config.signingMethod()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{method.Alg()}), jwt.WithExpirationRequired()}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return config.verificationKey()
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// BearerToken returns the token of a "Bearer {token}" Authorization header
+func BearerToken(c *gin.Context) (string, error) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return "", errors.New("Authorization header is required")
+	}
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", errors.New("Authorization header must be in the format 'Bearer {token}'")
+	}
+	return parts[1], nil
+}
+
+// signingMethod returns the configured signing method, HS256 by default
+func (config JWTConfig) signingMethod() (jwt.SigningMethod, error) {
+	switch config.SigningMethod {
+	case "", jwt.SigningMethodHS256.Alg():
+		return jwt.SigningMethodHS256, nil
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, fmt.Errorf("unsupported signing method: %s", config.SigningMethod)
+}
+
+// signingKey returns the signing method and the key tokens are signed with
+func (config JWTConfig) signingKey() (jwt.SigningMethod, interface{}, error) {
+	method, err := config.signingMethod()
+	if err != nil {
+		return nil, nil, err
+	}
+	if method == jwt.SigningMethodRS256 {
+		if config.PrivateKey == nil {
+			return nil, nil, errors.New("RS256 signing requires a private key")
+		}
+		return method, config.PrivateKey, nil
+	}
+	return method, []byte(config.Secret), nil
+}
+
+// verificationKey returns the key tokens are verified with
+func (config JWTConfig) verificationKey() (interface{}, error) {
+	method, err := config.signingMethod()
+	if err != nil {
+		return nil, err
+	}
+	if method != jwt.SigningMethodRS256 {
+		return []byte(config.Secret), nil
+	}
+	if config.PublicKey != nil {
+		return config.PublicKey, nil
+	}
+	if config.PrivateKey != nil {
+		return &config.PrivateKey.PublicKey, nil
+	}
+	return nil, errors.New("RS256 verification requires a public key")
+}
@@ -2,10 +2,13 @@ package auth
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 )
 
@@ -156,21 +159,21 @@ func (p *JWTAuthorizationProvider) AddRule(resourceName string, op resource.Oper
 func AuthorizationMiddleware(provider AuthorizationProvider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get resource and operation from context
-		res, ok := c.Get("resource")
+		res, ok := requestctx.Resource.Get(c)
 		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden")
 			return
 		}
 
 		op, ok := c.Get("operation")
 		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden")
 			return
 		}
 
 		// Check if access is allowed
-		if !provider.CanAccess(c, res.(resource.Resource), op.(resource.Operation)) {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden"})
+		if !provider.CanAccess(c, res, op.(resource.Operation)) {
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden")
 			return
 		}
 
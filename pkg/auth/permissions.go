@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 )
 
@@ -10,35 +14,35 @@ import (
 func PermissionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get resource and operation from context
-		res, ok := c.Get("resource")
+		res, ok := requestctx.Resource.Get(c)
 		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: resource not found in context"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: resource not found in context")
 			return
 		}
 
 		op, ok := c.Get("operation")
 		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: operation not found in context"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: operation not found in context")
 			return
 		}
 
 		// Get user role from JWT claims
 		claimsValue, exists := c.Get("claims")
 		if !exists {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: no authentication claims found"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: no authentication claims found")
 			return
 		}
 
 		claims, ok := claimsValue.(jwt.MapClaims)
 		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: invalid authentication claims"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: invalid authentication claims")
 			return
 		}
 
 		// Extract user role(s)
 		userRoles := extractUserRoles(claims)
 		if len(userRoles) == 0 {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: no roles found"})
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: no roles found")
 			return
 		}
 
@@ -60,13 +64,13 @@ func PermissionMiddleware() gin.HandlerFunc {
 		}
 
 		// Check resource-level permissions
-		if !hasResourcePermission(res.(resource.Resource), opStr, userRoles) {
-			c.AbortWithStatusJSON(403, gin.H{"error": "Forbidden: insufficient permissions for this resource"})
+		if !hasResourcePermission(res, opStr, userRoles) {
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden: insufficient permissions for this resource")
 			return
 		}
 
 		// Store user roles in context for field-level permission filtering
-		c.Set("userRoles", userRoles)
+		requestctx.Roles.Set(c, userRoles)
 
 		c.Next()
 	}
@@ -114,15 +118,7 @@ func hasResourcePermission(res resource.Resource, operation string, userRoles []
 	}
 
 	// Check if any user role is in the allowed roles
-	for _, userRole := range userRoles {
-		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
-				return true
-			}
-		}
-	}
-
-	return false
+	return hasAnyRole(userRoles, allowedRoles)
 }
 
 // FilterFieldsByPermission filters fields based on field-level permissions
@@ -170,3 +166,63 @@ func FilterFieldsByPermission(fields []resource.FieldMetadata, operation string,
 
 	return result
 }
+
+// UserRoles returns the roles of the current user: the roles stored in the request
+// context, or the "roles" claim of the JWT claims stored by the JWT middleware
+func UserRoles(c *gin.Context) []string {
+	if roles, ok := requestctx.Roles.Get(c); ok {
+		return roles
+	}
+	if claims, ok := c.Get("claims"); ok {
+		if mapClaims, ok := claims.(jwt.MapClaims); ok {
+			return extractUserRoles(mapClaims)
+		}
+	}
+	return nil
+}
+
+// CanPerform reports whether any of the roles may perform an operation on a resource.
+// Batch operations also require the permission of their single-record operation, and
+// counts, facets, aggregates and exports the permission to list.
+func CanPerform(res resource.Resource, op resource.Operation, roles []string) bool {
+	switch op {
+	case resource.OperationCreateMany:
+		if !hasResourcePermission(res, string(resource.OperationCreate), roles) {
+			return false
+		}
+	case resource.OperationUpdateMany:
+		if !hasResourcePermission(res, string(resource.OperationUpdate), roles) {
+			return false
+		}
+	case resource.OperationDeleteMany:
+		if !hasResourcePermission(res, string(resource.OperationDelete), roles) {
+			return false
+		}
+	case resource.OperationCount, resource.OperationFacets, resource.OperationAggregate, resource.OperationExport:
+		// Derived from the records a list returns
+		if !hasResourcePermission(res, string(resource.OperationList), roles) {
+			return false
+		}
+	}
+	return hasResourcePermission(res, string(op), roles)
+}
+
+// CanAccessField reports whether any of the roles may use a field for an operation
+// ("read", "create" or "update"). Fields without permissions for the operation are
+// accessible to everyone.
+func CanAccessField(field resource.Field, operation string, roles []string) bool {
+	allowedRoles := field.Permissions[operation]
+	return len(allowedRoles) == 0 || hasAnyRole(roles, allowedRoles)
+}
+
+// hasAnyRole reports whether any of the roles is allowed
+func hasAnyRole(roles, allowedRoles []string) bool {
+	for _, role := range roles {
+		for _, allowedRole := range allowedRoles {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -287,3 +287,56 @@ func TestHasResourcePermission(t *testing.T) {
 	emptyResource.On("GetPermissions").Return(nil)
 	assert.True(t, hasResourcePermission(emptyResource, "read", []string{"user"}))
 }
+
+func TestUserRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Nil(t, UserRoles(c))
+
+	c.Set("claims", jwt.MapClaims{"roles": []interface{}{"editor"}})
+	assert.Equal(t, []string{"editor"}, UserRoles(c))
+
+	// Roles stored in the request context take precedence
+	c.Set("userRoles", []string{"admin"})
+	assert.Equal(t, []string{"admin"}, UserRoles(c))
+}
+
+func TestCanPerform(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{ ID uint }{},
+		Permissions: map[string][]string{
+			"delete":     {"admin", "editor"},
+			"deleteMany": {"admin", "reviewer"},
+		},
+	})
+
+	assert.True(t, CanPerform(res, resource.OperationList, nil))
+	assert.True(t, CanPerform(res, resource.OperationDelete, []string{"editor"}))
+	assert.False(t, CanPerform(res, resource.OperationDelete, []string{"viewer"}))
+	assert.True(t, CanPerform(res, resource.OperationDeleteMany, []string{"admin"}))
+	assert.False(t, CanPerform(res, resource.OperationDeleteMany, []string{"editor"}))
+	assert.False(t, CanPerform(res, resource.OperationDeleteMany, []string{"reviewer"}))
+
+	// Counts, facets, aggregates and exports return what lists return
+	res = resource.NewResource(resource.ResourceConfig{
+		Name:        "salaries",
+		Model:       struct{ ID uint }{},
+		Permissions: map[string][]string{"list": {"admin"}},
+	})
+	for _, op := range []resource.Operation{resource.OperationCount, resource.OperationFacets, resource.OperationAggregate, resource.OperationExport} {
+		assert.True(t, CanPerform(res, op, []string{"admin"}), op)
+		assert.False(t, CanPerform(res, op, []string{"viewer"}), op)
+	}
+}
+
+func TestCanAccessField(t *testing.T) {
+	field := resource.Field{Name: "salary", Permissions: map[string][]string{"read": {"hr"}}}
+
+	assert.True(t, CanAccessField(field, "read", []string{"hr"}))
+	assert.False(t, CanAccessField(field, "read", []string{"editor"}))
+	assert.False(t, CanAccessField(field, "read", nil))
+	assert.True(t, CanAccessField(field, "update", nil))
+	assert.True(t, CanAccessField(resource.Field{Name: "name"}, "read", nil))
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
+)
+
+const (
+	// TokenTypeClaim is the claim distinguishing access tokens from refresh tokens
+	TokenTypeClaim = "token_type"
+
+	// TokenTypeAccess marks access tokens
+	TokenTypeAccess = "access"
+
+	// TokenTypeRefresh marks refresh tokens, which are only accepted by /auth/refresh
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidCredentials is returned by UserProvider.Authenticate for a wrong login or password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrUserNotFound is returned by UserProvider.GetUser for unknown (or disabled) users
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// User is an authenticated user
+type User struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+
+	// Claims are added to the access tokens of the user
+	Claims map[string]interface{} `json:"-"`
+
+	// Data is returned by /auth/me besides the fields above (profile, avatar, ...)
+	Data interface{} `json:"data,omitempty"`
+}
+
+// UserProvider looks up users for the auth routes
+type UserProvider interface {
+	// Authenticate returns the user for valid credentials, or ErrInvalidCredentials
+	Authenticate(ctx context.Context, username, password string) (*User, error)
+
+	// GetUser returns a user by ID, or ErrUserNotFound. It is called on refresh and
+	// by /auth/me, so disabled users lose access when their tokens are used.
+	GetUser(ctx context.Context, id string) (*User, error)
+}
+
+// RoutesConfig contains configuration for the auth routes
+type RoutesConfig struct {
+	// JWT signs issued tokens and validates presented ones
+	JWT JWTConfig
+
+	// Users authenticates credentials and looks up users
+	Users UserProvider
+}
+
+// TokenPair is the response of /auth/login and /auth/refresh
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+// loginRequest is the body of /auth/login; "email" is accepted instead of "username"
+type loginRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// refreshRequest is the body of /auth/refresh
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// RegisterRoutes registers POST /auth/login, POST /auth/refresh and GET /auth/me
+func RegisterRoutes(router *gin.RouterGroup, config RoutesConfig) {
+	group := router.Group("/auth")
+	group.POST("/login", LoginHandler(config))
+	group.POST("/refresh", RefreshHandler(config))
+	group.GET("/me", MeHandler(config))
+}
+
+// LoginHandler exchanges credentials for an access and a refresh token
+func LoginHandler(config RoutesConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		var req loginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		username := req.Username
+		if username == "" {
+			username = req.Email
+		}
+
+		user, err := config.Users.Authenticate(c.Request.Context(), username, req.Password)
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrUserNotFound) {
+			apierror.RespondMessage(c, http.StatusUnauthorized, "Invalid credentials")
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		respondWithTokens(c, config.JWT, user)
+	}
+}
+
+// RefreshHandler exchanges a refresh token for a new token pair
+func RefreshHandler(config RoutesConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		var req refreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		claims, err := ParseToken(config.JWT, req.RefreshToken)
+		if err != nil || claims[TokenTypeClaim] != TokenTypeRefresh {
+			apierror.RespondMessage(c, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+
+		user, ok := lookupUser(c, config, claims)
+		if !ok {
+			return
+		}
+		respondWithTokens(c, config.JWT, user)
+	}
+}
+
+// MeHandler returns the user of the bearer access token
+func MeHandler(config RoutesConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := BearerToken(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+		claims, err := ParseToken(config.JWT, tokenString)
+		if err != nil || claims[TokenTypeClaim] == TokenTypeRefresh {
+			apierror.RespondMessage(c, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		user, ok := lookupUser(c, config, claims)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": user})
+	}
+}
+
+// IssueTokens creates an access and a refresh token for a user
+func IssueTokens(config JWTConfig, user *User) (TokenPair, error) {
+	now := time.Now()
+	access := jwt.MapClaims{}
+	for key, value := range user.Claims {
+		access[key] = value
+	}
+	for key, value := range tokenClaims(config, user.ID, TokenTypeAccess, now, config.ExpirationTime) {
+		access[key] = value
+	}
+	if len(user.Roles) > 0 {
+		access["roles"] = user.Roles
+	}
+
+	accessToken, err := GenerateJWT(config, access)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := GenerateJWT(config, tokenClaims(config, user.ID, TokenTypeRefresh, now, config.RefreshExpirationTime))
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(config.ExpirationTime / time.Second),
+	}, nil
+}
+
+// tokenClaims returns the registered claims of an issued token
+func tokenClaims(config JWTConfig, subject, tokenType string, now time.Time, lifetime time.Duration) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":          subject,
+		"iat":          now.Unix(),
+		"exp":          now.Add(lifetime).Unix(),
+		TokenTypeClaim: tokenType,
+	}
+	if config.Issuer != "" {
+		claims["iss"] = config.Issuer
+	}
+	if config.Audience != "" {
+		claims["aud"] = config.Audience
+	}
+	return claims
+}
+
+// lookupUser loads the subject of a token, responding with 401 for unknown users
+func lookupUser(c *gin.Context, config RoutesConfig, claims jwt.MapClaims) (*User, bool) {
+	subject, _ := claims.GetSubject()
+	user, err := config.Users.GetUser(c.Request.Context(), subject)
+	if errors.Is(err, ErrUserNotFound) {
+		apierror.RespondMessage(c, http.StatusUnauthorized, "User not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// respondWithTokens issues a token pair for a user
+func respondWithTokens(c *gin.Context, config JWTConfig, user *User) {
+	tokens, err := IssueTokens(config, user)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
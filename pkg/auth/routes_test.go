@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUsers struct {
+	disabled bool
+}
+
+func (u *testUsers) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	if username != "ada@example.com" || password != "secret" {
+		return nil, ErrInvalidCredentials
+	}
+	return u.GetUser(ctx, "1")
+}
+
+func (u *testUsers) GetUser(ctx context.Context, id string) (*User, error) {
+	if id != "1" || u.disabled {
+		return nil, ErrUserNotFound
+	}
+	return &User{ID: "1", Username: "ada", Roles: []string{"admin"}, Claims: map[string]interface{}{"tenant": "acme"}}, nil
+}
+
+func TestAuthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := DefaultJWTConfig()
+	config.Secret = "test-secret"
+	users := &testUsers{}
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api"), RoutesConfig{JWT: config, Users: users})
+
+	request := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	tokens := func(w *httptest.ResponseRecorder) TokenPair {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Data TokenPair `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/auth/login", `{"email":"ada@example.com","password":"wrong"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/auth/login", `{"email":"ada@example.com"}`, "").Code)
+
+	pair := tokens(request(http.MethodPost, "/api/auth/login", `{"email":"ada@example.com","password":"secret"}`, ""))
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, int64(86400), pair.ExpiresIn)
+
+	claims, err := ParseToken(config, pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, TokenTypeAccess, claims[TokenTypeClaim])
+	assert.Equal(t, "acme", claims["tenant"])
+	assert.Equal(t, []interface{}{"admin"}, claims["roles"])
+
+	w := request(http.MethodGet, "/api/auth/me", "", pair.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"id":"1","username":"ada","roles":["admin"]}}`, w.Body.String())
+
+	// Refresh tokens are only accepted by /auth/refresh
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/auth/me", "", pair.RefreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/auth/refresh", `{"refreshToken":"`+pair.AccessToken+`"}`, "").Code)
+	refreshed := tokens(request(http.MethodPost, "/api/auth/refresh", `{"refreshToken":"`+pair.RefreshToken+`"}`, ""))
+	assert.NotEmpty(t, refreshed.AccessToken)
+
+	// Disabled users cannot refresh
+	users.disabled = true
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodPost, "/api/auth/refresh", `{"refreshToken":"`+pair.RefreshToken+`"}`, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/auth/me", "", pair.AccessToken).Code)
+}
@@ -0,0 +1,191 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/locking"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Reasons reported for denied checks
+const (
+	ReasonUnknownResource = "unknown resource"
+	ReasonUnsupported     = "action not supported"
+	ReasonForbidden       = "insufficient permissions"
+	ReasonNotFound        = "record not found"
+	ReasonNotOwner        = "record belongs to another user"
+	ReasonLocked          = "record is locked"
+)
+
+// DefaultMaxChecks limits the number of checks in one request
+const DefaultMaxChecks = 100
+
+// Check asks whether the current user may perform an action on a resource, or on one
+// of its records when ID is set. Actions are operations ("list", "read", "create",
+// "update", "delete", ...) or custom action names.
+type Check struct {
+	Resource string      `json:"resource" binding:"required"`
+	Action   string      `json:"action" binding:"required"`
+	ID       interface{} `json:"id,omitempty"`
+}
+
+// Decision is the outcome of a check. Reason explains why the action is not allowed.
+type Decision struct {
+	Check
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Rule is an application-specific check, e.g. whether a state-machine transition is
+// possible from the record's current state. Record is the loaded record for checks
+// with an ID (nil without one or without a repository). Rules return the reason the
+// action is denied, or "" to allow it.
+type Rule func(c *gin.Context, res resource.Resource, check Check, record interface{}) string
+
+// Config contains configuration for the authorization check endpoint
+type Config struct {
+	// Registry resolves resource names (default resource.GlobalResourceRegistry)
+	Registry *resource.ResourceRegistry
+
+	// Repositories load the records of checks with an ID, by resource name. Missing
+	// records, and records of other users with owner repositories, are denied.
+	Repositories map[string]repository.Repository
+
+	// Locks denies updates and deletes of records locked by another user
+	Locks *locking.Config
+
+	// Rules are evaluated after the built-in checks, in order
+	Rules []Rule
+
+	// MaxChecks limits the number of checks in one request (default DefaultMaxChecks)
+	MaxChecks int
+}
+
+func (config Config) withDefaults() Config {
+	if config.Registry == nil {
+		config.Registry = resource.GlobalResourceRegistry
+	}
+	if config.MaxChecks <= 0 {
+		config.MaxChecks = DefaultMaxChecks
+	}
+	return config
+}
+
+// checkRequest is the body of /authz/check
+type checkRequest struct {
+	Checks []Check `json:"checks" binding:"required,dive"`
+}
+
+// RegisterRoutes registers POST /authz/check
+func RegisterRoutes(router *gin.RouterGroup, config Config) {
+	router.POST("/authz/check", GenerateCheckHandler(config))
+}
+
+// GenerateCheckHandler creates a handler evaluating a batch of checks for the current
+// user, so clients can enable or disable actions for many records in one request. The
+// decisions are returned in the order of the checks.
+func GenerateCheckHandler(config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		var req checkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if len(req.Checks) > config.MaxChecks {
+			apierror.RespondMessage(c, http.StatusBadRequest, fmt.Sprintf("at most %d checks are allowed", config.MaxChecks))
+			return
+		}
+
+		roles := auth.UserRoles(c)
+		decisions := make([]Decision, len(req.Checks))
+		for i, check := range req.Checks {
+			reason, err := evaluate(c, config, check, roles)
+			if err != nil {
+				apierror.Respond(c, http.StatusInternalServerError, err)
+				return
+			}
+			decisions[i] = Decision{Check: check, Allowed: reason == "", Reason: reason}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": decisions})
+	}
+}
+
+// evaluate returns the reason a check is denied, or "" if it is allowed
+func evaluate(c *gin.Context, config Config, check Check, roles []string) (string, error) {
+	res, ok := config.Registry.GetByName(check.Resource)
+	if !ok {
+		return ReasonUnknownResource, nil
+	}
+
+	op := resource.Operation(check.Action)
+	if isOperation(op) && !res.HasOperation(op) {
+		return ReasonUnsupported, nil
+	}
+	if !auth.CanPerform(res, op, roles) {
+		return ReasonForbidden, nil
+	}
+
+	var record interface{}
+	if check.ID != nil {
+		if repo, ok := config.Repositories[res.GetName()]; ok {
+			var err error
+			record, err = repo.Get(c.Request.Context(), check.ID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ReasonNotFound, nil
+			}
+			if errors.Is(err, repository.ErrOwnerMismatch) || errors.Is(err, repository.ErrOwnerIDNotFound) {
+				return ReasonNotOwner, nil
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if config.Locks != nil && (op == resource.OperationUpdate || op == resource.OperationDelete) {
+			holder := locking.DefaultHolder
+			if config.Locks.Holder != nil {
+				holder = config.Locks.Holder
+			}
+			current, _ := holder(c)
+			_, err := config.Locks.Store.Check(c.Request.Context(), res.GetName(), check.ID, current)
+			if errors.Is(err, locking.ErrLocked) {
+				return ReasonLocked, nil
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, rule := range config.Rules {
+		if reason := rule(c, res, check, record); reason != "" {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// isOperation reports whether an action is a built-in operation rather than a custom
+// action name
+func isOperation(op resource.Operation) bool {
+	switch op {
+	case resource.OperationList, resource.OperationCreate, resource.OperationRead,
+		resource.OperationUpdate, resource.OperationDelete, resource.OperationCount,
+		resource.OperationFacets, resource.OperationAggregate, resource.OperationImport, resource.OperationExport,
+		resource.OperationCreateMany, resource.OperationUpdateMany, resource.OperationDeleteMany:
+		return true
+	}
+	return false
+}
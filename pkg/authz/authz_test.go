@@ -0,0 +1,152 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/locking"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Invoice struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Status string `json:"status"`
+}
+
+func setupAuthz(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Invoice{}))
+	require.NoError(t, db.Create(&[]Invoice{{Status: "draft"}, {Status: "paid"}}).Error)
+
+	store := locking.NewStore(db)
+	require.NoError(t, store.AutoMigrate())
+	_, err = store.Acquire(context.Background(), "invoices", 2, "bob", time.Minute)
+	require.NoError(t, err)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "invoices",
+		Model:      Invoice{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationUpdate, resource.OperationDelete},
+		Permissions: map[string][]string{
+			"delete":  {"admin"},
+			"approve": {"manager"},
+		},
+	})
+	registry := resource.NewResourceRegistry()
+	registry.Register(res)
+
+	holder := func(c *gin.Context) (string, error) { return c.GetHeader("X-User"), nil }
+	config := Config{
+		Registry:     registry,
+		Repositories: map[string]repository.Repository{"invoices": repository.NewGenericRepositoryWithResource(db, res)},
+		Locks:        &locking.Config{Store: store, Holder: holder},
+		Rules: []Rule{func(c *gin.Context, res resource.Resource, check Check, record interface{}) string {
+			// Only drafts can be approved
+			if invoice, ok := record.(*Invoice); ok && check.Action == "approve" && invoice.Status != "draft" {
+				return "invoice is " + invoice.Status
+			}
+			return ""
+		}},
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if role := c.GetHeader("X-Roles"); role != "" {
+			requestctx.Roles.Set(c, []string{role})
+		}
+	})
+	RegisterRoutes(r.Group("/api"), config)
+	return r
+}
+
+func check(t *testing.T, r *gin.Engine, user, role, body string) []Decision {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/authz/check", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", user)
+	req.Header.Set("X-Roles", role)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Data []Decision `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+func TestCheckHandler(t *testing.T) {
+	r := setupAuthz(t)
+
+	decisions := check(t, r, "ann", "manager", `{"checks":[
+		{"resource":"invoices","action":"list"},
+		{"resource":"invoices","action":"update","id":1},
+		{"resource":"invoices","action":"update","id":2},
+		{"resource":"invoices","action":"delete","id":1},
+		{"resource":"invoices","action":"read","id":99},
+		{"resource":"invoices","action":"create"},
+		{"resource":"orders","action":"list"},
+		{"resource":"invoices","action":"approve","id":1},
+		{"resource":"invoices","action":"approve","id":2}
+	]}`)
+	require.Len(t, decisions, 9)
+
+	reasons := make([]string, len(decisions))
+	for i, decision := range decisions {
+		assert.Equal(t, decision.Reason == "", decision.Allowed)
+		reasons[i] = decision.Reason
+	}
+	assert.Equal(t, []string{
+		"",
+		"",
+		ReasonLocked,
+		ReasonForbidden,
+		ReasonNotFound,
+		ReasonUnsupported,
+		ReasonUnknownResource,
+		"",
+		"invoice is paid",
+	}, reasons)
+	assert.Equal(t, "invoices", decisions[1].Resource)
+	assert.Equal(t, float64(1), decisions[1].ID)
+}
+
+func TestCheckHandlerLockHolderAndRoles(t *testing.T) {
+	r := setupAuthz(t)
+
+	decisions := check(t, r, "bob", "admin", `{"checks":[
+		{"resource":"invoices","action":"update","id":2},
+		{"resource":"invoices","action":"delete","id":2},
+		{"resource":"invoices","action":"approve","id":1}
+	]}`)
+	assert.True(t, decisions[0].Allowed)
+	assert.True(t, decisions[1].Allowed)
+	assert.Equal(t, ReasonForbidden, decisions[2].Reason)
+}
+
+func TestCheckHandlerValidation(t *testing.T) {
+	r := setupAuthz(t)
+
+	for _, body := range []string{`{}`, `{"checks":[{"resource":"invoices"}]}`} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/authz/check", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/expr"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// ExpressionRule returns a rule allowing an action while a sandboxed expression (see
+// package expr) is true, e.g. `action != "approve" || record.status == "draft"`.
+//
+// Expressions see action, resource (the resource name), id, roles, owner (the owner ID
+// of the request) and record, which is null for checks without an ID. Only the given
+// fields of the record are accessible, or all of them when none are given. Actions
+// are denied with reason when the expression is false or fails.
+func ExpressionRule(source, reason string, fields ...string) (Rule, error) {
+	var recordFields []string
+	if len(fields) > 0 {
+		recordFields = fields
+	}
+	program, err := expr.Compile(source, expr.Env{Variables: map[string][]string{
+		"action":   {},
+		"resource": {},
+		"id":       {},
+		"roles":    {},
+		"owner":    {},
+		"record":   recordFields,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context, res resource.Resource, check Check, record interface{}) string {
+		owner, _ := middleware.GetOwnerID(c.Request.Context())
+		allowed, err := program.EvalBool(c.Request.Context(), map[string]interface{}{
+			"action":   check.Action,
+			"resource": res.GetName(),
+			"id":       check.ID,
+			"roles":    auth.UserRoles(c),
+			"owner":    owner,
+			"record":   record,
+		})
+		if err != nil || !allowed {
+			return reason
+		}
+		return ""
+	}, nil
+}
@@ -0,0 +1,40 @@
+package authz
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestExpressionRule(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "invoices", Model: Invoice{}})
+
+	rule, err := ExpressionRule(`action != "approve" || (record != null && record.status == "draft") || "admin" in roles`, "only drafts can be approved", "status")
+	require.NoError(t, err)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/authz/check", nil)
+	requestctx.Roles.Set(c, []string{"manager"})
+
+	assert.Equal(t, "", rule(c, res, Check{Resource: "invoices", Action: "update", ID: 2}, &Invoice{ID: 2, Status: "paid"}))
+	assert.Equal(t, "", rule(c, res, Check{Resource: "invoices", Action: "approve", ID: 1}, &Invoice{ID: 1, Status: "draft"}))
+	assert.Equal(t, "only drafts can be approved", rule(c, res, Check{Resource: "invoices", Action: "approve", ID: 2}, &Invoice{ID: 2, Status: "paid"}))
+	assert.Equal(t, "only drafts can be approved", rule(c, res, Check{Resource: "invoices", Action: "approve"}, nil))
+
+	requestctx.Roles.Set(c, []string{"admin"})
+	assert.Equal(t, "", rule(c, res, Check{Resource: "invoices", Action: "approve", ID: 2}, &Invoice{ID: 2, Status: "paid"}))
+
+	// Failing expressions deny the action
+	failing, err := ExpressionRule(`record.status == "draft"`, "denied")
+	require.NoError(t, err)
+	assert.Equal(t, "denied", failing(c, res, Check{Resource: "invoices", Action: "approve"}, nil))
+
+	_, err = ExpressionRule(`record.id > 1`, "denied", "status")
+	assert.ErrorContains(t, err, `field "id" of "record" is not accessible`)
+}
@@ -0,0 +1,31 @@
+// Package cache provides the storage backends of the repository result cache: an
+// in-memory LRU and a Redis client.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// Cache stores byte values under string keys
+type Cache interface {
+	// Get returns the value of a key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores a value; a zero ttl keeps it until it is evicted or deleted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes keys, ignoring missing ones
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// ETag returns a strong ETag for the parts, e.g. a resource, its cache version and the
+// query. Handlers use it for ETags changing whenever the cached data changes.
+func ETag(parts ...string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(parts, "\x00")))
+	return fmt.Sprintf("\"%x\"", h.Sum64())
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory(2)
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, m.Set(ctx, "b", []byte("2"), 0))
+
+	value, found, err := m.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("1"), value)
+
+	// "b" is the least recently used entry
+	require.NoError(t, m.Set(ctx, "c", []byte("3"), 0))
+	_, found, _ = m.Get(ctx, "b")
+	assert.False(t, found)
+	assert.Equal(t, 2, m.Len())
+
+	now = now.Add(time.Minute)
+	_, found, _ = m.Get(ctx, "a")
+	assert.False(t, found, "expired")
+
+	require.NoError(t, m.Delete(ctx, "c", "missing"))
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestETag(t *testing.T) {
+	assert.Equal(t, ETag("posts", "v1", "page=1"), ETag("posts", "v1", "page=1"))
+	assert.NotEqual(t, ETag("posts", "v1", "page=1"), ETag("posts", "v2", "page=1"))
+	assert.NotEqual(t, ETag("ab", "c"), ETag("a", "bc"))
+	assert.Regexp(t, `^"[0-9a-f]+"$`, ETag("posts"))
+}
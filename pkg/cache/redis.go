@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisConfig contains the connection settings of a Redis cache
+type RedisConfig struct {
+	// Server address (default "localhost:6379")
+	Addr string
+
+	// Password sent with AUTH (optional)
+	Password string
+
+	// Database selected with SELECT (default 0)
+	DB int
+
+	// Prefix prepended to every key, e.g. "refine:" (optional)
+	Prefix string
+
+	// Maximum number of idle connections kept open (default 10)
+	PoolSize int
+
+	// Timeout for dialing and for commands without a context deadline (default 5s)
+	Timeout time.Duration
+}
+
+// RedisError is an error reply of the Redis server
+type RedisError string
+
+func (e RedisError) Error() string { return "redis: " + string(e) }
+
+// Redis is a cache stored in a Redis server, shared by all instances of the application
+type Redis struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis creates a Redis cache. Connections are opened on first use.
+func NewRedis(config RedisConfig) *Redis {
+	if config.Addr == "" {
+		config.Addr = "localhost:6379"
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = 10
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	return &Redis{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+// Get returns the value of a key
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", r.config.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores a value with SET, expiring it after ttl if positive
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", r.config.Prefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Delete removes keys with DEL
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, r.config.Prefix+key)
+	}
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+// Ping checks the connection to the server
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a command and reads its reply. Connections are returned to the pool
+// unless the command failed on the network.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(ctx, r.config.Timeout, args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+	return reply, err
+}
+
+// conn takes an idle connection or dials a new one
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.config.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if r.config.Password != "" {
+		if _, err := conn.command(ctx, r.config.Timeout, "AUTH", r.config.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if r.config.DB != 0 {
+		if _, err := conn.command(ctx, r.config.Timeout, "SELECT", strconv.Itoa(r.config.DB)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// command writes a command as a RESP array of bulk strings and reads the reply
+func (c *redisConn) command(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// readReply reads a RESP2 reply: simple strings are returned as string, integers as
+// int64, bulk strings as []byte (nil when missing) and arrays as []interface{}
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", payload)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, size)
+		for i := range values {
+			if values[i], err = readReply(reader); err != nil {
+				var redisErr RedisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				values[i] = redisErr
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
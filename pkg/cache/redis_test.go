@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis serves the commands used by the Redis cache from a map
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{values: map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, listener.Addr().String()
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		args := make([]string, 0)
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		var response string
+		switch args[0] {
+		case "AUTH":
+			if args[1] == "secret" {
+				response = "+OK\r\n"
+			} else {
+				response = "-WRONGPASS invalid password\r\n"
+			}
+		case "SELECT", "SET":
+			if args[0] == "SET" {
+				s.values[args[1]] = args[2]
+			}
+			response = "+OK\r\n"
+		case "GET":
+			if value, ok := s.values[args[1]]; ok {
+				response = "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			} else {
+				response = "$-1\r\n"
+			}
+		case "DEL":
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := s.values[key]; ok {
+					delete(s.values, key)
+					deleted++
+				}
+			}
+			response = ":" + strconv.Itoa(deleted) + "\r\n"
+		case "PING":
+			response = "+PONG\r\n"
+		default:
+			response = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+
+		if _, err := conn.Write([]byte(response)); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedis(t *testing.T) {
+	server, addr := startFakeRedis(t)
+	ctx := context.Background()
+	r := NewRedis(RedisConfig{Addr: addr, Password: "secret", DB: 2, Prefix: "app:"})
+	defer r.Close()
+
+	require.NoError(t, r.Ping(ctx))
+	require.NoError(t, r.Set(ctx, "posts", []byte("a\r\nb"), 1500*time.Millisecond))
+
+	value, found, err := r.Get(ctx, "posts")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("a\r\nb"), value)
+
+	require.NoError(t, r.Delete(ctx, "posts"))
+	_, found, err = r.Get(ctx, "posts")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, []string{
+		"AUTH secret",
+		"SELECT 2",
+		"PING",
+		"SET app:posts a\r\nb PX 1500",
+		"GET app:posts",
+		"DEL app:posts",
+		"GET app:posts",
+	}, server.commands, "a single pooled connection")
+}
+
+func TestRedisErrors(t *testing.T) {
+	_, addr := startFakeRedis(t)
+	ctx := context.Background()
+
+	r := NewRedis(RedisConfig{Addr: addr, Password: "wrong"})
+	err := r.Ping(ctx)
+	assert.EqualError(t, err, "redis: WRONGPASS invalid password")
+
+	r = NewRedis(RedisConfig{Addr: addr})
+	_, err = r.do(ctx, "FLUSHALL")
+	assert.Equal(t, RedisError("ERR unknown command"), err)
+	// Error replies keep the connection usable
+	assert.NoError(t, r.Ping(ctx))
+	assert.Len(t, r.idle, 1)
+}
@@ -0,0 +1,42 @@
+package codegen
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// TypeScriptPath is the path of the TypeScript download below the API group
+const TypeScriptPath = "/_codegen/typescript"
+
+// TypeScriptFilename is the name the TypeScript module is downloaded as
+const TypeScriptFilename = "refine-resources.ts"
+
+// RegisterRoutes registers GET /_codegen/typescript, serving the TypeScript module of
+// the resources in registry (resource.GlobalResourceRegistry if nil)
+func RegisterRoutes(router *gin.RouterGroup, registry *resource.ResourceRegistry) {
+	router.GET(TypeScriptPath, GenerateTypeScriptHandler(registry))
+}
+
+// GenerateTypeScriptHandler generates a handler downloading the TypeScript module of
+// the resources in registry (resource.GlobalResourceRegistry if nil). The module is
+// generated per request, so it includes resources registered after the route.
+func GenerateTypeScriptHandler(registry *resource.ResourceRegistry) gin.HandlerFunc {
+	if registry == nil {
+		registry = resource.GlobalResourceRegistry
+	}
+	return func(c *gin.Context) {
+		source := TypeScript(registry.GetAll())
+
+		etag := utils.GenerateETag(source)
+		c.Header("ETag", etag)
+		if utils.IsETagMatch(etag, c.GetHeader("If-None-Match")) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+TypeScriptFilename+`"`)
+		c.Data(http.StatusOK, "application/typescript; charset=utf-8", []byte(source))
+	}
+}
@@ -0,0 +1,312 @@
+// Package codegen generates frontend code from registered resources, so frontends stay
+// in sync with the Go models.
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/naming"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/swagger"
+)
+
+// header starts every generated file
+const header = "// Code generated by refine-gin. DO NOT EDIT.\n"
+
+// identifierPattern matches property names usable without quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScript returns a TypeScript module describing resources:
+//
+//   - an interface per resource model and per nested struct, with the validation of
+//     fields in doc comments
+//   - ResourceTypes, mapping resource names to their record interfaces
+//   - resources, a refine resource map with the label, ID field, operations and
+//     relations of each resource in meta
+func TypeScript(resources []resource.Resource) string {
+	resources = append([]resource.Resource(nil), resources...)
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].GetName() < resources[j].GetName()
+	})
+
+	components := make(map[string]swagger.Schema)
+	interfaces := make(map[string]swagger.Schema)
+	typeNames := make([]string, len(resources))
+	for i, res := range resources {
+		typeNames[i] = interfaceName(res)
+		interfaces[typeNames[i]] = swagger.ResourceSchema(res, components)
+	}
+	for name, schema := range components {
+		// Resource schemas carry aliases and validation the plain struct lacks
+		if _, exists := interfaces[name]; !exists {
+			interfaces[name] = schema
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+
+	names := make([]string, 0, len(interfaces))
+	for name := range interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\n")
+		writeInterface(&b, name, interfaces[name])
+	}
+
+	b.WriteString("\nexport interface ResourceTypes {\n")
+	for i, res := range resources {
+		fmt.Fprintf(&b, "  %s: %s;\n", propertyName(res.GetName()), typeNames[i])
+	}
+	b.WriteString("}\n\nexport type ResourceName = keyof ResourceTypes;\n")
+
+	b.WriteString("\nexport const resources = [\n")
+	for _, res := range resources {
+		writeResource(&b, res)
+	}
+	b.WriteString("] as const;\n")
+
+	return b.String()
+}
+
+// WriteTypeScript writes the TypeScript module of resources to w
+func WriteTypeScript(w io.Writer, resources []resource.Resource) error {
+	_, err := io.WriteString(w, TypeScript(resources))
+	return err
+}
+
+// WriteTypeScriptFile writes the TypeScript module of resources to a file, e.g. from a
+// go:generate command
+func WriteTypeScriptFile(path string, resources []resource.Resource) error {
+	return os.WriteFile(path, []byte(TypeScript(resources)), 0o644)
+}
+
+// interfaceName returns the interface name of the records of a resource: the name of
+// its model type, or its name in PascalCase
+func interfaceName(res resource.Resource) string {
+	if model := res.GetModel(); model != nil {
+		t := reflect.TypeOf(model)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Name() != "" {
+			return t.Name()
+		}
+	}
+	return naming.ToPascalCase(res.GetName())
+}
+
+// writeInterface writes an object schema as an exported interface
+func writeInterface(b *strings.Builder, name string, schema swagger.Schema) {
+	if schema.Title != "" || schema.Description != "" {
+		writeDoc(b, "", []string{schema.Title, schema.Description})
+	}
+	fmt.Fprintf(b, "export interface %s {\n", name)
+	writeProperties(b, "  ", schema)
+	b.WriteString("}\n")
+}
+
+// writeProperties writes the properties of an object schema, sorted by name
+func writeProperties(b *strings.Builder, indent string, schema swagger.Schema) {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := schema.Properties[name]
+		if doc := propertyDoc(name, property); len(doc) > 0 {
+			writeDoc(b, indent, doc)
+		}
+		b.WriteString(indent)
+		if property.ReadOnly {
+			b.WriteString("readonly ")
+		}
+		b.WriteString(propertyName(name))
+		if !required[name] {
+			b.WriteString("?")
+		}
+		fmt.Fprintf(b, ": %s;\n", typeOf(property, indent))
+	}
+}
+
+// typeOf returns the TypeScript type of a schema
+func typeOf(schema swagger.Schema, indent string) string {
+	switch {
+	case schema.Ref != "":
+		return schema.Ref[strings.LastIndex(schema.Ref, "/")+1:]
+	case len(schema.Enum) > 0:
+		values := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			values[i] = literal(value)
+		}
+		return strings.Join(values, " | ")
+	case len(schema.OneOf) > 0:
+		types := make([]string, len(schema.OneOf))
+		for i, option := range schema.OneOf {
+			types[i] = typeOf(option, indent)
+		}
+		return strings.Join(types, " | ")
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		if schema.Items == nil {
+			return "unknown[]"
+		}
+		items := typeOf(*schema.Items, indent)
+		if strings.Contains(items, " | ") {
+			items = "(" + items + ")"
+		}
+		return items + "[]"
+	case "object":
+		if len(schema.Properties) > 0 {
+			var b strings.Builder
+			b.WriteString("{\n")
+			writeProperties(&b, indent+"  ", schema)
+			b.WriteString(indent + "}")
+			return b.String()
+		}
+		if values, ok := schema.AdditionalProperties.(swagger.Schema); ok {
+			return "Record<string, " + typeOf(values, indent) + ">"
+		}
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+// propertyDoc returns the doc comment lines of a property: its title, unless it just
+// repeats the name, and its constraints
+func propertyDoc(name string, schema swagger.Schema) []string {
+	var doc []string
+	if schema.Title != "" && !strings.EqualFold(schema.Title, name) {
+		doc = append(doc, schema.Title)
+	}
+	if schema.Description != "" {
+		doc = append(doc, schema.Description)
+	}
+	if schema.Format != "" && schema.Type == "string" {
+		doc = append(doc, "@format "+schema.Format)
+	}
+	for _, bound := range []struct {
+		tag   string
+		value *float64
+	}{
+		{"@minimum", schema.Minimum},
+		{"@maximum", schema.Maximum},
+		{"@exclusiveMinimum", schema.ExclusiveMinimum},
+		{"@exclusiveMaximum", schema.ExclusiveMaximum},
+	} {
+		if bound.value != nil {
+			doc = append(doc, fmt.Sprintf("%s %v", bound.tag, *bound.value))
+		}
+	}
+	for _, bound := range []struct {
+		tag   string
+		value *int
+	}{
+		{"@minLength", schema.MinLength},
+		{"@maxLength", schema.MaxLength},
+		{"@minItems", schema.MinItems},
+		{"@maxItems", schema.MaxItems},
+	} {
+		if bound.value != nil {
+			doc = append(doc, fmt.Sprintf("%s %d", bound.tag, *bound.value))
+		}
+	}
+	if schema.Pattern != "" {
+		doc = append(doc, "@pattern "+schema.Pattern)
+	}
+	return doc
+}
+
+// writeDoc writes a doc comment, skipping empty lines
+func writeDoc(b *strings.Builder, indent string, lines []string) {
+	b.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		if line != "" {
+			// A closing sequence in a pattern would end the comment
+			fmt.Fprintf(b, "%s * %s\n", indent, strings.ReplaceAll(line, "*/", "*\\/"))
+		}
+	}
+	b.WriteString(indent + " */\n")
+}
+
+// writeResource writes the refine resource map entry of a resource
+func writeResource(b *strings.Builder, res resource.Resource) {
+	operations := make([]string, 0, len(res.GetOperations()))
+	for _, op := range res.GetOperations() {
+		operations = append(operations, literal(string(op)))
+	}
+
+	b.WriteString("  {\n")
+	fmt.Fprintf(b, "    name: %s,\n", literal(res.GetName()))
+	b.WriteString("    meta: {\n")
+	if label := res.GetLabel(); label != "" {
+		fmt.Fprintf(b, "      label: %s,\n", literal(label))
+	}
+	if icon := res.GetIcon(); icon != "" {
+		fmt.Fprintf(b, "      icon: %s,\n", literal(icon))
+	}
+	fmt.Fprintf(b, "      idField: %s,\n", literal(idFieldName(res)))
+	fmt.Fprintf(b, "      operations: [%s],\n", strings.Join(operations, ", "))
+	fmt.Fprintf(b, "      canDelete: %t,\n", res.HasOperation(resource.OperationDelete))
+	if relations := res.GetRelations(); len(relations) > 0 {
+		b.WriteString("      relations: [\n")
+		for _, relation := range relations {
+			fmt.Fprintf(b, "        { name: %s, type: %s, resource: %s },\n",
+				literal(relation.Name), literal(string(relation.Type)), literal(relation.Resource))
+		}
+		b.WriteString("      ],\n")
+	}
+	b.WriteString("    },\n")
+	b.WriteString("  },\n")
+}
+
+// idFieldName returns the API name of the ID field of a resource
+func idFieldName(res resource.Resource) string {
+	name := res.GetIDFieldName()
+	for _, field := range res.GetFields() {
+		if strings.EqualFold(field.Name, name) {
+			return field.APIName()
+		}
+	}
+	return name
+}
+
+// propertyName returns a property name, quoted if it is not an identifier
+func propertyName(name string) string {
+	if identifierPattern.MatchString(name) {
+		return name
+	}
+	return literal(name)
+}
+
+// literal returns a value as a TypeScript literal
+func literal(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+	return string(encoded)
+}
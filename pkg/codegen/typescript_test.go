@@ -0,0 +1,110 @@
+package codegen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type Author struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type Post struct {
+	ID       uint              `json:"id"`
+	Title    string            `json:"title" binding:"required,min=3,max=100"`
+	Status   string            `json:"status"`
+	Tags     []string          `json:"tags"`
+	Meta     map[string]string `json:"meta"`
+	AuthorID uint              `json:"authorId"`
+	Author   *Author           `json:"author,omitempty" relation:"resource=authors;type=many-to-one;field=AuthorID;reference=ID"`
+}
+
+func testResources() []resource.Resource {
+	posts := resource.NewResource(resource.ResourceConfig{
+		Name:       "posts",
+		Label:      "Posts",
+		Model:      Post{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationDelete},
+		Fields: []resource.Field{
+			{Name: "id", Type: "uint"},
+			{Name: "title", Type: "string", Label: "Post title", Validation: &resource.Validation{Required: true}},
+			{Name: "status", Type: "string", Options: []resource.Option{{Value: "draft"}, {Value: "published"}}},
+			{Name: "tags"},
+			{Name: "meta"},
+			{Name: "authorId", Alias: "writerId"},
+			{Name: "author"},
+		},
+	})
+	authors := resource.NewResource(resource.ResourceConfig{
+		Name:       "authors",
+		Model:      Author{},
+		Operations: []resource.Operation{resource.OperationList},
+	})
+	return []resource.Resource{posts, authors}
+}
+
+func TestTypeScript(t *testing.T) {
+	source := TypeScript(testResources())
+
+	assert.Contains(t, source, "// Code generated by refine-gin. DO NOT EDIT.")
+	assert.Contains(t, source, "export interface Author {\n  id?: number;\n  name?: string;\n}")
+
+	// Field types, options, aliases and relations
+	assert.Contains(t, source, "  status?: \"draft\" | \"published\";\n")
+	assert.Contains(t, source, "  tags?: string[];\n")
+	assert.Contains(t, source, "  meta?: Record<string, string>;\n")
+	assert.Contains(t, source, "  writerId?: number;\n")
+	assert.Contains(t, source, "  author?: Author;\n")
+
+	// Validation is documented, required fields are not optional
+	assert.Contains(t, source, "  /**\n   * Post title\n   * @minLength 3\n   * @maxLength 100\n   */\n  title: string;\n")
+
+	assert.Contains(t, source, "export interface ResourceTypes {\n  authors: Author;\n  posts: Post;\n}")
+	assert.Contains(t, source, `operations: ["list", "read", "delete"],`)
+	assert.Contains(t, source, `canDelete: true,`)
+	assert.Contains(t, source, `{ name: "Author", type: "many-to-one", resource: "authors" },`)
+
+	// The output is stable
+	assert.Equal(t, source, TypeScript(testResources()))
+}
+
+func TestWriteTypeScriptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resources.ts")
+	require.NoError(t, WriteTypeScriptFile(path, testResources()))
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, TypeScript(testResources()), string(written))
+}
+
+func TestTypeScriptHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.NewResourceRegistry()
+	for _, res := range testResources() {
+		registry.Register(res)
+	}
+	r := gin.New()
+	RegisterRoutes(r.Group("/api"), registry)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/_codegen/typescript", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/typescript; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="refine-resources.ts"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, TypeScript(registry.GetAll()), w.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/_codegen/typescript", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
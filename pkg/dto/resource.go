@@ -0,0 +1,302 @@
+package dto
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// ForResource returns the DTO provider of a resource: a ResourceDTOProvider if the
+// resource declares DTOs (see resource.ResourceConfig.CreateDTO), a DefaultDTOProvider
+// using the model otherwise
+func ForResource(res resource.Resource) DTOProvider {
+	if declared, ok := res.(resource.DTOResource); ok && !declared.GetDTOConfig().IsZero() {
+		return NewResourceDTOProvider(res)
+	}
+	return &DefaultDTOProvider{Model: res.GetModel()}
+}
+
+// ResourceDTOProvider binds requests to the create and update DTOs of a resource and
+// maps responses to its response DTO. DTOs that are not declared are generated from
+// the model if the resource enables AutoDTO, the model is used otherwise.
+//
+// Update DTOs whose fields are pointers (like generated ones) describe partial updates:
+// they are mapped to a map of the fields present in the request, so omitted fields
+// keep their stored values.
+type ResourceDTOProvider struct {
+	Model interface{}
+
+	modelType    reflect.Type
+	createType   reflect.Type
+	updateType   reflect.Type
+	responseType reflect.Type
+}
+
+// NewResourceDTOProvider creates a DTO provider from the DTO configuration of a resource
+func NewResourceDTOProvider(res resource.Resource) *ResourceDTOProvider {
+	var config resource.DTOConfig
+	if declared, ok := res.(resource.DTOResource); ok {
+		config = declared.GetDTOConfig()
+	}
+
+	p := &ResourceDTOProvider{Model: res.GetModel(), modelType: structType(res.GetModel())}
+	p.createType = structType(config.CreateDTO)
+	p.updateType = structType(config.UpdateDTO)
+	p.responseType = structType(config.ResponseDTO)
+
+	if config.AutoDTO {
+		if p.createType == nil {
+			p.createType = generateDTO(p.modelType, requestFields(res, p.modelType), false)
+		}
+		if p.updateType == nil {
+			p.updateType = generateDTO(p.modelType, requestFields(res, p.modelType), true)
+		}
+		if p.responseType == nil {
+			p.responseType = generateDTO(p.modelType, responseFields(res, p.modelType), false)
+		}
+	}
+	if p.createType == nil {
+		p.createType = p.modelType
+	}
+	if p.updateType == nil {
+		p.updateType = p.modelType
+	}
+	return p
+}
+
+func (p *ResourceDTOProvider) GetCreateDTO() interface{} {
+	return reflect.New(p.createType).Interface()
+}
+
+func (p *ResourceDTOProvider) GetUpdateDTO() interface{} {
+	return reflect.New(p.updateType).Interface()
+}
+
+func (p *ResourceDTOProvider) GetResponseDTO() interface{} {
+	if p.responseType == nil {
+		return reflect.New(p.modelType).Interface()
+	}
+	return reflect.New(p.responseType).Interface()
+}
+
+// TransformToModel validates a create or update DTO and maps it to the model, or to a
+// map of the fields present in a partial update
+func (p *ResourceDTOProvider) TransformToModel(dto interface{}) (interface{}, error) {
+	value := reflect.ValueOf(dto)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct || value.Elem().Type() == p.modelType {
+		return dto, nil
+	}
+	if err := validate.Struct(dto); err != nil {
+		return nil, err
+	}
+
+	source := value.Elem()
+	if source.Type() == p.updateType && p.updateType != p.createType {
+		return p.updateMap(source), nil
+	}
+
+	model := reflect.New(p.modelType)
+	copyFields(model.Elem(), source)
+	return model.Interface(), nil
+}
+
+// TransformFromModel maps a model, or a slice of models, to the response DTO
+func (p *ResourceDTOProvider) TransformFromModel(model interface{}) (interface{}, error) {
+	if p.responseType == nil || model == nil {
+		return model, nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+	switch {
+	case value.Kind() == reflect.Slice:
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			item, err := p.TransformFromModel(value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case value.Kind() != reflect.Struct || value.Type() == p.responseType:
+		return model, nil
+	}
+
+	response := reflect.New(p.responseType)
+	copyFields(response.Elem(), value)
+	return response.Interface(), nil
+}
+
+// updateMap returns the fields of a partial update DTO present in the request, keyed
+// by model field name. Nil pointers, slices and maps are left out.
+func (p *ResourceDTOProvider) updateMap(source reflect.Value) map[string]interface{} {
+	updates := make(map[string]interface{})
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Type().Field(i)
+		modelField, ok := p.modelType.FieldByName(field.Name)
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		value := source.Field(i)
+		switch value.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			if value.IsNil() {
+				continue
+			}
+		}
+		if value.Kind() == reflect.Ptr && modelField.Type.Kind() != reflect.Ptr {
+			value = value.Elem()
+		}
+		updates[field.Name] = value.Interface()
+	}
+	return updates
+}
+
+// structType returns the struct type of a value or pointer, or nil
+func structType(value interface{}) reflect.Type {
+	if value == nil {
+		return nil
+	}
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// modelFields returns the exported fields of a model, flattening embedded structs
+func modelFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, modelFields(field.Type)...)
+			continue
+		}
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// resourceField returns the resource field of a model field, matched by Go or JSON name
+func resourceField(res resource.Resource, field reflect.StructField) *resource.Field {
+	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+	for _, f := range res.GetFields() {
+		if strings.EqualFold(f.Name, field.Name) || (jsonName != "" && f.Name == jsonName) {
+			return &f
+		}
+	}
+	return nil
+}
+
+// requestFields returns the model fields accepted in requests: all but the ID and the
+// read-only and hidden fields
+func requestFields(res resource.Resource, t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, field := range modelFields(t) {
+		if strings.EqualFold(field.Name, res.GetIDFieldName()) {
+			continue
+		}
+		if f := resourceField(res, field); f != nil && (f.ReadOnly || f.Hidden) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// responseFields returns the model fields returned in responses: all but the hidden
+// fields
+func responseFields(res resource.Resource, t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, field := range modelFields(t) {
+		if f := resourceField(res, field); f != nil && f.Hidden {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// generateDTO builds a struct type with the given model fields. Partial DTOs use
+// pointers for all fields that cannot be nil and drop "required" rules, so omitted
+// fields can be told apart from zero values.
+func generateDTO(model reflect.Type, fields []reflect.StructField, partial bool) reflect.Type {
+	generated := make([]reflect.StructField, 0, len(fields))
+	for _, field := range fields {
+		fieldType := field.Type
+		tag := `json:"` + field.Tag.Get("json") + `"`
+		for _, key := range []string{"binding", "validate"} {
+			rules := field.Tag.Get(key)
+			if partial {
+				rules = partialRules(rules)
+			}
+			if rules != "" {
+				tag += ` ` + key + `:"` + rules + `"`
+			}
+		}
+		if partial {
+			switch fieldType.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			default:
+				fieldType = reflect.PtrTo(fieldType)
+			}
+		}
+		generated = append(generated, reflect.StructField{
+			Name: field.Name,
+			Type: fieldType,
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	return reflect.StructOf(generated)
+}
+
+// partialRules removes "required" from validation rules and skips the remaining ones
+// for omitted fields
+func partialRules(rules string) string {
+	var kept []string
+	for _, rule := range strings.Split(rules, ",") {
+		if rule != "" && rule != "required" && rule != "omitempty" {
+			kept = append(kept, rule)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return "omitempty," + strings.Join(kept, ",")
+}
+
+// copyFields copies the fields of src to the fields of dst with the same name,
+// dereferencing and allocating pointers as needed. Nil pointers are not copied.
+func copyFields(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := src.FieldByName(field.Name)
+		if !value.IsValid() {
+			continue
+		}
+		target := dst.Field(i)
+		switch {
+		case value.Type().AssignableTo(target.Type()):
+			target.Set(value)
+		case value.Kind() == reflect.Ptr && value.Type().Elem().AssignableTo(target.Type()):
+			if !value.IsNil() {
+				target.Set(value.Elem())
+			}
+		case target.Kind() == reflect.Ptr && value.Type().AssignableTo(target.Type().Elem()):
+			pointer := reflect.New(value.Type())
+			pointer.Elem().Set(value)
+			target.Set(pointer)
+		}
+	}
+}
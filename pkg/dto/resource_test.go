@@ -0,0 +1,103 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type DTOAccount struct {
+	ID           uint   `json:"id"`
+	Email        string `json:"email" validate:"required,email"`
+	Name         string `json:"name" validate:"required,min=2"`
+	Role         string `json:"role"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+type DTOAccountCreate struct {
+	Email string `json:"email" validate:"required,email"`
+	Name  string `json:"name"`
+}
+
+func accountResource(config resource.ResourceConfig) resource.Resource {
+	config.Name = "accounts"
+	config.Model = DTOAccount{}
+	config.Fields = []resource.Field{
+		{Name: "id", Type: "int"},
+		{Name: "email", Type: "string"},
+		{Name: "name", Type: "string"},
+		{Name: "role", Type: "string", ReadOnly: true},
+		{Name: "passwordHash", Type: "string", Hidden: true},
+	}
+	return resource.NewResource(config)
+}
+
+func TestForResource(t *testing.T) {
+	assert.IsType(t, &DefaultDTOProvider{}, ForResource(accountResource(resource.ResourceConfig{})))
+	assert.IsType(t, &ResourceDTOProvider{}, ForResource(accountResource(resource.ResourceConfig{AutoDTO: true})))
+
+	// Owner resources use the DTOs of the wrapped resource
+	owned := resource.NewOwnerResource(accountResource(resource.ResourceConfig{CreateDTO: DTOAccountCreate{}}), resource.OwnerConfig{})
+	provider := ForResource(owned)
+	require.IsType(t, &ResourceDTOProvider{}, provider)
+	assert.IsType(t, &DTOAccountCreate{}, provider.GetCreateDTO())
+}
+
+func TestResourceDTOProviderDeclared(t *testing.T) {
+	provider := NewResourceDTOProvider(accountResource(resource.ResourceConfig{CreateDTO: DTOAccountCreate{}}))
+
+	create := provider.GetCreateDTO()
+	require.IsType(t, &DTOAccountCreate{}, create)
+
+	_, err := provider.TransformToModel(&DTOAccountCreate{Email: "not-an-email"})
+	assert.Error(t, err)
+
+	model, err := provider.TransformToModel(&DTOAccountCreate{Email: "ada@example.com", Name: "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, &DTOAccount{Email: "ada@example.com", Name: "Ada"}, model)
+
+	// Without a response DTO models are returned as they are
+	account := &DTOAccount{ID: 1}
+	response, err := provider.TransformFromModel(account)
+	require.NoError(t, err)
+	assert.Same(t, account, response)
+}
+
+func TestResourceDTOProviderAuto(t *testing.T) {
+	provider := NewResourceDTOProvider(accountResource(resource.ResourceConfig{AutoDTO: true}))
+
+	// Create DTOs leave out the ID, read-only and hidden fields
+	create := provider.GetCreateDTO()
+	require.NoError(t, json.Unmarshal([]byte(`{"id":7,"email":"ada@example.com","name":"Ada","role":"admin","passwordHash":"x"}`), create))
+	model, err := provider.TransformToModel(create)
+	require.NoError(t, err)
+	assert.Equal(t, &DTOAccount{Email: "ada@example.com", Name: "Ada"}, model)
+
+	// Validation rules of the model apply
+	invalid := provider.GetCreateDTO()
+	require.NoError(t, json.Unmarshal([]byte(`{"email":"ada@example.com","name":"A"}`), invalid))
+	_, err = provider.TransformToModel(invalid)
+	assert.Error(t, err)
+
+	// Update DTOs only carry the fields present in the request
+	update := provider.GetUpdateDTO()
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"Grace","role":"admin"}`), update))
+	updates, err := provider.TransformToModel(update)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"Name": "Grace"}, updates)
+
+	invalidUpdate := provider.GetUpdateDTO()
+	require.NoError(t, json.Unmarshal([]byte(`{"name":"G"}`), invalidUpdate))
+	_, err = provider.TransformToModel(invalidUpdate)
+	assert.Error(t, err)
+
+	// Response DTOs leave out hidden fields
+	response, err := provider.TransformFromModel([]DTOAccount{{ID: 1, Email: "ada@example.com", Role: "admin", PasswordHash: "secret"}})
+	require.NoError(t, err)
+	encoded, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"email":"ada@example.com","name":"","role":"admin"}]`, string(encoded))
+}
@@ -0,0 +1,52 @@
+package encryption
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// EncryptedString is a string column stored encrypted with DefaultKeyring.
+// Values are decrypted on read and always encrypted with the current key on write,
+// so saving a record lazily moves its value to the newest key version.
+type EncryptedString string
+
+// Value implements driver.Valuer
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	return DefaultKeyring.Encrypt(string(s))
+}
+
+// Scan implements sql.Scanner
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into EncryptedString", value)
+	}
+
+	if raw == "" {
+		*s = ""
+		return nil
+	}
+
+	plaintext, err := DefaultKeyring.Decrypt(raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// GormDataType returns the column type used by GORM migrations
+func (EncryptedString) GormDataType() string {
+	return "text"
+}
@@ -0,0 +1,60 @@
+package encryption
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// RegisterRotationEndpoints registers the key rotation status and trigger endpoints
+func RegisterRotationEndpoints(router *gin.RouterGroup, rotator *Rotator) {
+	router.GET("/encryption/status", GenerateRotationStatusHandler(rotator))
+	router.POST("/encryption/rotate", GenerateRotationStartHandler(rotator))
+}
+
+// GenerateRotationStatusHandler creates a handler reporting how many rows remain on old keys
+func GenerateRotationStatusHandler(rotator *Rotator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := rotator.Status(c.Request.Context())
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		var remaining int64
+		for _, s := range status {
+			remaining += s.Remaining
+		}
+
+		utils.DisableCaching(c.Writer)
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"currentVersion": rotator.Keyring.CurrentVersion(),
+				"running":        rotator.IsRunning(),
+				"remaining":      remaining,
+				"columns":        status,
+			},
+		})
+	}
+}
+
+// GenerateRotationStartHandler creates a handler starting the background re-encryption job
+func GenerateRotationStartHandler(rotator *Rotator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The job outlives the request, so it must not use the request context
+		if !rotator.Start(context.Background()) {
+			apierror.RespondMessage(c, http.StatusConflict, "rotation already running")
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		c.JSON(http.StatusAccepted, gin.H{
+			"data": gin.H{
+				"running": true,
+			},
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Error constants for encryption
+var (
+	ErrNoCurrentKey      = errors.New("no current encryption key configured")
+	ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext format")
+	ErrInvalidKeySize    = errors.New("encryption key must be 16, 24 or 32 bytes")
+)
+
+// versionPrefix starts the key version marker stored in front of every ciphertext ("v2:...")
+const versionPrefix = "v"
+
+// Keyring holds multiple versions of AES keys. New values are always encrypted with the
+// current version, while older versions are kept so existing values can still be read.
+type Keyring struct {
+	keys    map[int][]byte
+	current int
+	mutex   sync.RWMutex
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: make(map[int][]byte),
+	}
+}
+
+// DefaultKeyring is used by EncryptedString values
+var DefaultKeyring = NewKeyring()
+
+// AddKey registers a key under the given version. The first key added becomes current.
+func (k *Keyring) AddKey(version int, key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return ErrInvalidKeySize
+	}
+	if version <= 0 {
+		return fmt.Errorf("key version must be positive, got %d", version)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.keys[version] = key
+	if k.current == 0 {
+		k.current = version
+	}
+	return nil
+}
+
+// SetCurrent selects the key version used for new encryptions
+func (k *Keyring) SetCurrent(version int) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if _, ok := k.keys[version]; !ok {
+		return ErrUnknownKeyVersion
+	}
+	k.current = version
+	return nil
+}
+
+// CurrentVersion returns the key version used for new encryptions
+func (k *Keyring) CurrentVersion() int {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.current
+}
+
+// Versions returns all registered key versions
+func (k *Keyring) Versions() []int {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	versions := make([]int, 0, len(k.keys))
+	for v := range k.keys {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// CurrentPrefix returns the prefix of values encrypted with the current key (e.g. "v2:")
+func (k *Keyring) CurrentPrefix() string {
+	return versionPrefix + strconv.Itoa(k.CurrentVersion()) + ":"
+}
+
+// Encrypt encrypts plaintext with the current key and prefixes the key version
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	k.mutex.RLock()
+	version := k.current
+	key := k.keys[version]
+	k.mutex.RUnlock()
+
+	if version == 0 {
+		return "", ErrNoCurrentKey
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return versionPrefix + strconv.Itoa(version) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt using the key version stored in it
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	version, payload, err := splitCiphertext(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	k.mutex.RLock()
+	key, ok := k.keys[version]
+	k.mutex.RUnlock()
+	if !ok {
+		return "", ErrUnknownKeyVersion
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// KeyVersion returns the key version a ciphertext was encrypted with
+func (k *Keyring) KeyVersion(ciphertext string) (int, error) {
+	version, _, err := splitCiphertext(ciphertext)
+	return version, err
+}
+
+// NeedsRotation checks if a ciphertext was encrypted with a key other than the current one
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	version, err := k.KeyVersion(ciphertext)
+	return err == nil && version != k.CurrentVersion()
+}
+
+// Reencrypt decrypts a value and encrypts it again with the current key
+func (k *Keyring) Reencrypt(ciphertext string) (string, error) {
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext)
+}
+
+// splitCiphertext splits "v<version>:<payload>" into its parts
+func splitCiphertext(ciphertext string) (int, string, error) {
+	if !strings.HasPrefix(ciphertext, versionPrefix) {
+		return 0, "", ErrInvalidCiphertext
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(ciphertext, versionPrefix), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", ErrInvalidCiphertext
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", ErrInvalidCiphertext
+	}
+	return version, parts[1], nil
+}
+
+// newGCM creates an AES-GCM cipher for the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
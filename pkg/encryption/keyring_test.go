@@ -0,0 +1,77 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKeyV1 = []byte("0123456789abcdef0123456789abcdef")
+	testKeyV2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestKeyring(t *testing.T) {
+	t.Run("no key", func(t *testing.T) {
+		_, err := NewKeyring().Encrypt("secret")
+		assert.ErrorIs(t, err, ErrNoCurrentKey)
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		assert.ErrorIs(t, NewKeyring().AddKey(1, []byte("short")), ErrInvalidKeySize)
+		assert.Error(t, NewKeyring().AddKey(0, testKeyV1))
+	})
+
+	t.Run("encrypt and decrypt", func(t *testing.T) {
+		k := NewKeyring()
+		require.NoError(t, k.AddKey(1, testKeyV1))
+
+		ciphertext, err := k.Encrypt("secret")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(ciphertext, "v1:"))
+		assert.NotContains(t, ciphertext, "secret")
+
+		plaintext, err := k.Decrypt(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+	})
+
+	t.Run("rotation keeps old keys readable", func(t *testing.T) {
+		k := NewKeyring()
+		require.NoError(t, k.AddKey(1, testKeyV1))
+		old, err := k.Encrypt("secret")
+		require.NoError(t, err)
+
+		require.NoError(t, k.AddKey(2, testKeyV2))
+		assert.Equal(t, 1, k.CurrentVersion())
+		require.NoError(t, k.SetCurrent(2))
+		assert.ElementsMatch(t, []int{1, 2}, k.Versions())
+
+		assert.True(t, k.NeedsRotation(old))
+		plaintext, err := k.Decrypt(old)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", plaintext)
+
+		rotated, err := k.Reencrypt(old)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(rotated, "v2:"))
+		assert.False(t, k.NeedsRotation(rotated))
+	})
+
+	t.Run("invalid ciphertext", func(t *testing.T) {
+		k := NewKeyring()
+		require.NoError(t, k.AddKey(1, testKeyV1))
+
+		_, err := k.Decrypt("plain")
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+		_, err = k.Decrypt("vx:abc")
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+		_, err = k.Decrypt("v9:abc")
+		assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+		_, err = k.Decrypt("v1:!!!")
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+		assert.ErrorIs(t, k.SetCurrent(5), ErrUnknownKeyVersion)
+	})
+}
@@ -0,0 +1,266 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRotationBatchSize is the number of rows re-encrypted per batch
+const DefaultRotationBatchSize = 100
+
+// RotationTarget identifies an encrypted column
+type RotationTarget struct {
+	// Table name
+	Table string
+
+	// Encrypted column name
+	Column string
+
+	// Primary key column name (defaults to "id")
+	IDColumn string
+}
+
+// key returns a unique identifier for the target
+func (t RotationTarget) key() string {
+	return t.Table + "." + t.Column
+}
+
+// idColumn returns the primary key column, defaulting to "id"
+func (t RotationTarget) idColumn() string {
+	if t.IDColumn == "" {
+		return "id"
+	}
+	return t.IDColumn
+}
+
+// RotationStatus reports the re-encryption progress of a column
+type RotationStatus struct {
+	Table          string     `json:"table"`
+	Column         string     `json:"column"`
+	CurrentVersion int        `json:"currentVersion"`
+	Remaining      int64      `json:"remaining"`
+	Processed      int64      `json:"processed"`
+	Failed         int64      `json:"failed"`
+	Skipped        int64      `json:"skipped"`
+	Running        bool       `json:"running"`
+	LastError      string     `json:"lastError,omitempty"`
+	StartedAt      *time.Time `json:"startedAt,omitempty"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Rotator re-encrypts columns still using old key versions
+type Rotator struct {
+	DB        *gorm.DB
+	Keyring   *Keyring
+	Targets   []RotationTarget
+	BatchSize int
+
+	mutex    sync.Mutex
+	progress map[string]*RotationStatus
+	running  bool
+}
+
+// NewRotator creates a new rotator for the given encrypted columns
+func NewRotator(db *gorm.DB, keyring *Keyring, targets ...RotationTarget) *Rotator {
+	if keyring == nil {
+		keyring = DefaultKeyring
+	}
+	return &Rotator{
+		DB:        db,
+		Keyring:   keyring,
+		Targets:   targets,
+		BatchSize: DefaultRotationBatchSize,
+		progress:  make(map[string]*RotationStatus),
+	}
+}
+
+// Remaining counts rows of a target that are not encrypted with the current key
+func (r *Rotator) Remaining(ctx context.Context, target RotationTarget) (int64, error) {
+	var count int64
+	err := r.staleRows(ctx, target).Count(&count).Error
+	return count, err
+}
+
+// Status returns the rotation status of every target
+func (r *Rotator) Status(ctx context.Context) ([]RotationStatus, error) {
+	result := make([]RotationStatus, 0, len(r.Targets))
+
+	for _, target := range r.Targets {
+		remaining, err := r.Remaining(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mutex.Lock()
+		status := RotationStatus{Table: target.Table, Column: target.Column}
+		if progress, ok := r.progress[target.key()]; ok {
+			status = *progress
+		}
+		r.mutex.Unlock()
+
+		status.CurrentVersion = r.Keyring.CurrentVersion()
+		status.Remaining = remaining
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// IsRunning reports whether a rotation job is in progress
+func (r *Rotator) IsRunning() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.running
+}
+
+// Start runs the rotation job in the background. It returns false if a job is already running.
+func (r *Rotator) Start(ctx context.Context) bool {
+	r.mutex.Lock()
+	if r.running {
+		r.mutex.Unlock()
+		return false
+	}
+	r.running = true
+	r.mutex.Unlock()
+
+	go func() {
+		_ = r.run(ctx)
+	}()
+	return true
+}
+
+// Run re-encrypts all targets synchronously
+func (r *Rotator) Run(ctx context.Context) error {
+	r.mutex.Lock()
+	if r.running {
+		r.mutex.Unlock()
+		return fmt.Errorf("rotation already running")
+	}
+	r.running = true
+	r.mutex.Unlock()
+
+	return r.run(ctx)
+}
+
+// run processes every target; the caller must have set r.running
+func (r *Rotator) run(ctx context.Context) error {
+	defer func() {
+		r.mutex.Lock()
+		r.running = false
+		r.mutex.Unlock()
+	}()
+
+	for _, target := range r.Targets {
+		if err := r.rotateTarget(ctx, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateTarget re-encrypts one column in batches ordered by primary key
+func (r *Rotator) rotateTarget(ctx context.Context, target RotationTarget) error {
+	now := time.Now()
+	status := &RotationStatus{Table: target.Table, Column: target.Column, Running: true, StartedAt: &now}
+
+	r.mutex.Lock()
+	r.progress[target.key()] = status
+	r.mutex.Unlock()
+
+	finish := func(err error) error {
+		finished := time.Now()
+		r.mutex.Lock()
+		status.Running = false
+		status.FinishedAt = &finished
+		if err != nil {
+			status.LastError = err.Error()
+		}
+		r.mutex.Unlock()
+		return err
+	}
+
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultRotationBatchSize
+	}
+
+	idColumn := target.idColumn()
+	var lastID interface{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return finish(err)
+		}
+
+		query := r.staleRows(ctx, target).Select(idColumn, target.Column).Order(idColumn).Limit(batchSize)
+		if lastID != nil {
+			query = query.Where(idColumn+" > ?", lastID)
+		}
+
+		rows, err := query.Rows()
+		if err != nil {
+			return finish(err)
+		}
+
+		type staleRow struct {
+			id    interface{}
+			value string
+		}
+		var batch []staleRow
+		for rows.Next() {
+			var id interface{}
+			var value sql.NullString
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return finish(err)
+			}
+			batch = append(batch, staleRow{id: id, value: value.String})
+		}
+		rows.Close()
+
+		if len(batch) == 0 {
+			return finish(nil)
+		}
+
+		for _, row := range batch {
+			lastID = row.id
+
+			// The update only applies to the ciphertext read, so values written or
+			// deleted concurrently (always under the current key) are skipped, not lost
+			var updated int64
+			reencrypted, err := r.Keyring.Reencrypt(row.value)
+			if err == nil {
+				result := r.DB.WithContext(ctx).Table(target.Table).
+					Where(idColumn+" = ?", row.id).
+					Where(target.Column+" = ?", row.value).
+					Update(target.Column, reencrypted)
+				err, updated = result.Error, result.RowsAffected
+			}
+
+			r.mutex.Lock()
+			switch {
+			case err != nil:
+				status.Failed++
+				status.LastError = err.Error()
+			case updated == 0:
+				status.Skipped++
+			default:
+				status.Processed++
+			}
+			r.mutex.Unlock()
+		}
+	}
+}
+
+// staleRows builds a query selecting rows not encrypted with the current key
+func (r *Rotator) staleRows(ctx context.Context, target RotationTarget) *gorm.DB {
+	return r.DB.WithContext(ctx).Table(target.Table).
+		Where(target.Column+" IS NOT NULL").
+		Where(target.Column+" <> ''").
+		Where(target.Column+" NOT LIKE ?", r.Keyring.CurrentPrefix()+"%")
+}
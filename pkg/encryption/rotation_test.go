@@ -0,0 +1,183 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SecretRecord struct {
+	ID     uint            `gorm:"primaryKey"`
+	Token  EncryptedString `json:"token"`
+	Public string          `json:"public"`
+}
+
+func setupRotationTest(t *testing.T) *gorm.DB {
+	original := DefaultKeyring
+	DefaultKeyring = NewKeyring()
+	t.Cleanup(func() { DefaultKeyring = original })
+	require.NoError(t, DefaultKeyring.AddKey(1, testKeyV1))
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SecretRecord{}))
+	return db
+}
+
+func rawToken(t *testing.T, db *gorm.DB, id uint) string {
+	var raw string
+	require.NoError(t, db.Table("secret_records").Select("token").Where("id = ?", id).Scan(&raw).Error)
+	return raw
+}
+
+func TestEncryptedStringLazyReencryption(t *testing.T) {
+	db := setupRotationTest(t)
+
+	record := SecretRecord{Token: "secret"}
+	require.NoError(t, db.Create(&record).Error)
+	assert.True(t, strings.HasPrefix(rawToken(t, db, record.ID), "v1:"))
+
+	require.NoError(t, DefaultKeyring.AddKey(2, testKeyV2))
+	require.NoError(t, DefaultKeyring.SetCurrent(2))
+
+	// Reading still works with the old key
+	var loaded SecretRecord
+	require.NoError(t, db.First(&loaded, record.ID).Error)
+	assert.Equal(t, EncryptedString("secret"), loaded.Token)
+
+	// Writing moves the value to the current key
+	require.NoError(t, db.Save(&loaded).Error)
+	assert.True(t, strings.HasPrefix(rawToken(t, db, record.ID), "v2:"))
+}
+
+func TestRotator(t *testing.T) {
+	db := setupRotationTest(t)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(&SecretRecord{Token: "secret"}).Error)
+	}
+	require.NoError(t, db.Create(&SecretRecord{Public: "no token"}).Error)
+
+	require.NoError(t, DefaultKeyring.AddKey(2, testKeyV2))
+	require.NoError(t, DefaultKeyring.SetCurrent(2))
+
+	rotator := NewRotator(db, nil, RotationTarget{Table: "secret_records", Column: "token"})
+	rotator.BatchSize = 2
+
+	remaining, err := rotator.Remaining(context.Background(), rotator.Targets[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), remaining)
+
+	require.NoError(t, rotator.Run(context.Background()))
+
+	status, err := rotator.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	assert.Equal(t, int64(0), status[0].Remaining)
+	assert.Equal(t, int64(5), status[0].Processed)
+	assert.Equal(t, 2, status[0].CurrentVersion)
+	assert.False(t, status[0].Running)
+
+	var records []SecretRecord
+	require.NoError(t, db.Where("token <> ''").Find(&records).Error)
+	for _, r := range records {
+		assert.Equal(t, EncryptedString("secret"), r.Token)
+	}
+}
+
+func TestRotatorCountsFailures(t *testing.T) {
+	db := setupRotationTest(t)
+	require.NoError(t, db.Create(&SecretRecord{Token: "secret"}).Error)
+	require.NoError(t, db.Table("secret_records").Create(map[string]interface{}{"token": "v7:garbage"}).Error)
+
+	require.NoError(t, DefaultKeyring.AddKey(2, testKeyV2))
+	require.NoError(t, DefaultKeyring.SetCurrent(2))
+
+	rotator := NewRotator(db, DefaultKeyring, RotationTarget{Table: "secret_records", Column: "token"})
+	require.NoError(t, rotator.Run(context.Background()))
+
+	status, err := rotator.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status[0].Processed)
+	assert.Equal(t, int64(1), status[0].Failed)
+	assert.Equal(t, int64(1), status[0].Remaining)
+	assert.NotEmpty(t, status[0].LastError)
+}
+
+func TestRotatorSkipsConcurrentWrites(t *testing.T) {
+	db := setupRotationTest(t)
+	require.NoError(t, db.Create(&SecretRecord{Token: "secret"}).Error)
+	require.NoError(t, db.Create(&SecretRecord{Token: "secret"}).Error)
+
+	require.NoError(t, DefaultKeyring.AddKey(2, testKeyV2))
+	require.NoError(t, DefaultKeyring.SetCurrent(2))
+
+	// The first record is saved with a new value while the rotator re-encrypts it
+	written := false
+	require.NoError(t, db.Callback().Update().Before("gorm:update").Register("test:concurrent_write", func(tx *gorm.DB) {
+		if written {
+			return
+		}
+		written = true
+		fresh, err := DefaultKeyring.Encrypt("fresh")
+		require.NoError(t, err)
+		_, err = tx.Statement.ConnPool.ExecContext(tx.Statement.Context, "UPDATE secret_records SET token = ? WHERE id = ?", fresh, 1)
+		require.NoError(t, err)
+	}))
+
+	rotator := NewRotator(db, DefaultKeyring, RotationTarget{Table: "secret_records", Column: "token"})
+	require.NoError(t, rotator.Run(context.Background()))
+
+	status, err := rotator.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status[0].Processed)
+	assert.Equal(t, int64(1), status[0].Skipped)
+	assert.Equal(t, int64(0), status[0].Remaining)
+
+	var first SecretRecord
+	require.NoError(t, db.First(&first, 1).Error)
+	assert.Equal(t, EncryptedString("fresh"), first.Token)
+}
+
+func TestRotationEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupRotationTest(t)
+	require.NoError(t, db.Create(&SecretRecord{Token: "secret"}).Error)
+	require.NoError(t, DefaultKeyring.AddKey(2, testKeyV2))
+	require.NoError(t, DefaultKeyring.SetCurrent(2))
+
+	rotator := NewRotator(db, nil, RotationTarget{Table: "secret_records", Column: "token"})
+	r := gin.New()
+	RegisterRotationEndpoints(r.Group("/admin"), rotator)
+
+	getStatus := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/admin/encryption/status", nil)
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["data"]
+	}
+
+	assert.Equal(t, float64(1), getStatus()["remaining"])
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/encryption/rotate", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	assert.Eventually(t, func() bool {
+		return !rotator.IsRunning() && getStatus()["remaining"] == float64(0)
+	}, time.Second, 10*time.Millisecond)
+}
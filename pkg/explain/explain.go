@@ -0,0 +1,201 @@
+// Package explain adds an explain mode to the generic handlers for debugging: in gin
+// debug mode, responses to requests with ?_explain=true include the SQL statements the
+// request ran with their bound parameters, the filters and sorts applied after
+// validation, and a timing breakdown.
+//
+// Statements are captured by a GORM plugin, which must be installed on the database:
+//
+//	db.Use(explain.Plugin{})
+package explain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
+)
+
+// Statement is a SQL statement run by a request
+type Statement struct {
+	// SQL is the statement with placeholders, Vars the parameters bound to them
+	SQL  string        `json:"sql"`
+	Vars []interface{} `json:"vars"`
+
+	// Query is the statement with the parameters interpolated by the dialect, to run
+	// it in a database console
+	Query string `json:"query"`
+
+	Rows     int64  `json:"rows"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Timing is the time spent by a request, in total, running statements and in the
+// application
+type Timing struct {
+	Total       string `json:"total"`
+	Database    string `json:"database"`
+	Application string `json:"application"`
+}
+
+// Report describes how a request was answered. Filters, Sort, Search and pagination
+// are set for list requests.
+type Report struct {
+	Statements []Statement        `json:"statements"`
+	Filters    []query.Filter     `json:"filters,omitempty"`
+	Sort       []query.SortOption `json:"sort,omitempty"`
+	Search     string             `json:"search,omitempty"`
+	Page       int                `json:"page,omitempty"`
+	PerPage    int                `json:"perPage,omitempty"`
+	Timing     Timing             `json:"timing"`
+}
+
+// recorder collects the report of a request
+type recorder struct {
+	mu         sync.Mutex
+	started    time.Time
+	database   time.Duration
+	statements []Statement
+	options    *query.QueryOptions
+}
+
+// recorderKey is the context key of the recorder of a request
+type recorderKey struct{}
+
+// fromContext returns the recorder of a request in explain mode, or nil
+func fromContext(ctx context.Context) *recorder {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(recorderKey{}).(*recorder)
+	return r
+}
+
+// Enabled reports whether the request of ctx is in explain mode
+func Enabled(ctx context.Context) bool {
+	return fromContext(ctx) != nil
+}
+
+// RecordOptions records the query options a list request ran with. It does nothing
+// outside explain mode.
+func RecordOptions(ctx context.Context, options query.QueryOptions) {
+	if r := fromContext(ctx); r != nil {
+		r.mu.Lock()
+		r.options = &options
+		r.mu.Unlock()
+	}
+}
+
+// addStatement records a statement that took elapsed
+func (r *recorder) addStatement(statement Statement, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, statement)
+	r.database += elapsed
+}
+
+// report returns the report of the request so far
+func (r *recorder) report() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := time.Since(r.started)
+	report := Report{
+		Statements: append([]Statement{}, r.statements...),
+		Timing: Timing{
+			Total:       total.String(),
+			Database:    r.database.String(),
+			Application: (total - r.database).String(),
+		},
+	}
+	if r.options != nil {
+		if r.options.Resource != nil {
+			report.Filters = r.options.AppliedFilters()
+		}
+		report.Sort = r.options.SortFields()
+		report.Search = r.options.Search
+		if !r.options.DisablePagination {
+			report.Page = r.options.Page
+			report.PerPage = r.options.PerPage
+		}
+	}
+	return report
+}
+
+// Middleware puts requests with ?_explain=true in explain mode and adds the report
+// under "explain" to their JSON object responses. It only does so in gin debug mode,
+// reports are never produced in release mode. Explained responses are not cached.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Not c.Query, which would cache the parameters before later middleware rewrites them
+		if requested, _ := strconv.ParseBool(c.Request.URL.Query().Get(query.ExplainParam)); !requested || !gin.IsDebugging() {
+			c.Next()
+			return
+		}
+
+		r := &recorder{started: time.Now()}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), recorderKey{}, r))
+		// Run the queries even if the client has the response cached
+		c.Request.Header.Del("If-None-Match")
+
+		w := &reportWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(r.report())
+	}
+}
+
+// reportWriter buffers JSON responses to add the report to them
+type reportWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON bodies and passes everything else through
+func (w *reportWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString buffers JSON bodies and passes everything else through
+func (w *reportWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flush writes the buffered body with the report added if it is a JSON object
+func (w *reportWriter) flush(report Report) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope != nil {
+		if encoded, err := json.Marshal(report); err == nil {
+			envelope["explain"] = encoded
+			if rewritten, err := json.Marshal(envelope); err == nil {
+				body = rewritten
+			}
+		}
+	}
+
+	header := w.ResponseWriter.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Del("ETag")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	if w.ResponseWriter.Status() == http.StatusNotModified {
+		return
+	}
+	w.ResponseWriter.Write(body)
+}
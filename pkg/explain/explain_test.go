@@ -0,0 +1,127 @@
+package explain_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/explain"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ExplainedTask struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+type explainResponse struct {
+	Data    json.RawMessage `json:"data"`
+	Explain *explain.Report `json:"explain"`
+}
+
+func setupExplain(t *testing.T) *gin.Engine {
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	t.Cleanup(func() { resource.GlobalResourceRegistry = registry })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(explain.Plugin{}))
+	require.NoError(t, db.AutoMigrate(&ExplainedTask{}))
+	require.NoError(t, db.Create(&[]ExplainedTask{{Title: "a", Status: "open"}, {Title: "b", Status: "done"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "tasks",
+		Model:      ExplainedTask{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+	})
+	r := gin.New()
+	handler.RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+	return r
+}
+
+func get(r *gin.Engine, path string) (*httptest.ResponseRecorder, explainResponse) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var response explainResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestExplainList(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+	r := setupExplain(t)
+
+	w, response := get(r, "/api/tasks?_explain=true&status=open&filter[title][ne]=x&filter[unknown][eq]=1&sort=title&order=desc")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	require.NotNil(t, response.Explain)
+	report := response.Explain
+
+	// The count and the page queries, with their parameters
+	require.Len(t, report.Statements, 2)
+	assert.True(t, strings.HasPrefix(report.Statements[0].SQL, "SELECT count(*)"), report.Statements[0].SQL)
+	assert.Contains(t, report.Statements[1].SQL, "ORDER BY title desc")
+	assert.Equal(t, []interface{}{"open", "x"}, report.Statements[1].Vars[:2])
+	assert.Contains(t, report.Statements[1].Query, `"open"`)
+	assert.Equal(t, int64(1), report.Statements[1].Rows)
+
+	// Filters on unknown fields are left out, as they are not applied
+	require.Len(t, report.Filters, 2)
+	assert.Equal(t, "status", report.Filters[0].Field)
+	assert.Equal(t, "title", report.Filters[1].Field)
+	assert.Equal(t, "ne", report.Filters[1].Operator)
+	require.Len(t, report.Sort, 1)
+	assert.Equal(t, "desc", report.Sort[0].Order)
+	assert.Equal(t, 1, report.Page)
+	assert.NotEmpty(t, report.Timing.Total)
+	assert.NotEmpty(t, report.Timing.Database)
+}
+
+func TestExplainGet(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+	r := setupExplain(t)
+
+	w, response := get(r, "/api/tasks/2?_explain=1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, response.Explain)
+	require.Len(t, response.Explain.Statements, 1)
+	assert.Contains(t, response.Explain.Statements[0].Query, `id = "2"`)
+	assert.Empty(t, response.Explain.Filters)
+
+	// Errors are explained too
+	w, response = get(r, "/api/tasks/9?_explain=true")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	require.NotNil(t, response.Explain)
+	assert.Equal(t, "record not found", response.Explain.Statements[0].Error)
+}
+
+func TestExplainDisabled(t *testing.T) {
+	r := setupExplain(t)
+
+	// Only in debug mode
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(gin.TestMode)
+	w, response := get(r, "/api/tasks?_explain=true")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, response.Explain)
+
+	// Only when requested
+	gin.SetMode(gin.DebugMode)
+	w, response = get(r, "/api/tasks")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, response.Explain)
+	assert.NotEmpty(t, response.Data)
+}
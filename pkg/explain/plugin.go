@@ -0,0 +1,72 @@
+package explain
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startKey is the statement instance key of the time a statement started
+const startKey = "explain:start"
+
+// Plugin is a GORM plugin recording the statements run by requests in explain mode.
+// Statements run outside explain mode are not affected.
+type Plugin struct{}
+
+// Name returns the name of the plugin
+func (Plugin) Name() string {
+	return "refine-gin:explain"
+}
+
+// Initialize registers the callbacks recording statements
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("explain:before_create", start),
+		callbacks.Create().After("gorm:create").Register("explain:after_create", record),
+		callbacks.Query().Before("gorm:query").Register("explain:before_query", start),
+		callbacks.Query().After("gorm:query").Register("explain:after_query", record),
+		callbacks.Update().Before("gorm:update").Register("explain:before_update", start),
+		callbacks.Update().After("gorm:update").Register("explain:after_update", record),
+		callbacks.Delete().Before("gorm:delete").Register("explain:before_delete", start),
+		callbacks.Delete().After("gorm:delete").Register("explain:after_delete", record),
+		callbacks.Row().Before("gorm:row").Register("explain:before_row", start),
+		callbacks.Row().After("gorm:row").Register("explain:after_row", record),
+		callbacks.Raw().Before("gorm:raw").Register("explain:before_raw", start),
+		callbacks.Raw().After("gorm:raw").Register("explain:after_raw", record),
+	)
+}
+
+// start records the time a statement of a request in explain mode starts
+func start(db *gorm.DB) {
+	if fromContext(db.Statement.Context) != nil {
+		db.InstanceSet(startKey, time.Now())
+	}
+}
+
+// record records a statement of a request in explain mode
+func record(db *gorm.DB) {
+	r := fromContext(db.Statement.Context)
+	if r == nil {
+		return
+	}
+
+	var elapsed time.Duration
+	if started, ok := db.InstanceGet(startKey); ok {
+		elapsed = time.Since(started.(time.Time))
+	}
+	sql := db.Statement.SQL.String()
+	vars := append([]interface{}{}, db.Statement.Vars...)
+	statement := Statement{
+		SQL:      sql,
+		Vars:     vars,
+		Query:    db.Dialector.Explain(sql, vars...),
+		Rows:     db.RowsAffected,
+		Duration: elapsed.String(),
+	}
+	if db.Error != nil {
+		statement.Error = db.Error.Error()
+	}
+	r.addStatement(statement, elapsed)
+}
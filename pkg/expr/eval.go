@@ -0,0 +1,685 @@
+package expr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// timeCheckInterval is the number of steps between timeout checks
+const timeCheckInterval = 32
+
+// evaluator evaluates a syntax tree within the limits
+type evaluator struct {
+	ctx       context.Context
+	limits    Limits
+	functions map[string]Function
+	vars      map[string]interface{}
+	cost      int
+	checked   int
+}
+
+// step charges the cost of an operation
+func (e *evaluator) step(n node, cost int) error {
+	e.cost += cost
+	if e.cost > e.limits.MaxCost {
+		return &Error{Position: n.pos(), Message: ErrCostLimit.Error(), Err: ErrCostLimit}
+	}
+	if e.cost-e.checked >= timeCheckInterval {
+		e.checked = e.cost
+		if e.ctx.Err() != nil {
+			return &Error{Position: n.pos(), Message: ErrTimeout.Error(), Err: ErrTimeout}
+		}
+	}
+	return nil
+}
+
+// checkSize fails when a built string or list exceeds the size limit
+func (e *evaluator) checkSize(n node, size int) error {
+	if size > e.limits.MaxSize {
+		return &Error{Position: n.pos(), Message: fmt.Sprintf("%s (%d > %d)", ErrSizeLimit, size, e.limits.MaxSize), Err: ErrSizeLimit}
+	}
+	return e.step(n, size/64)
+}
+
+func (e *evaluator) fail(n node, format string, args ...interface{}) error {
+	return &Error{Position: n.pos(), Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *evaluator) eval(n node) (interface{}, error) {
+	if err := e.step(n, 1); err != nil {
+		return nil, err
+	}
+
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+
+	case *identNode:
+		return e.vars[n.name], nil
+
+	case *memberNode:
+		target, err := e.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		value, err := member(target, n.name)
+		if err != nil {
+			return nil, e.fail(n, "%v", err)
+		}
+		return value, nil
+
+	case *indexNode:
+		target, err := e.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		index, err := e.eval(n.index)
+		if err != nil {
+			return nil, err
+		}
+		value, err := indexValue(target, index)
+		if err != nil {
+			return nil, e.fail(n, "%v", err)
+		}
+		return value, nil
+
+	case *listNode:
+		if err := e.checkSize(n, len(n.items)); err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			value, err := e.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+
+	case *unaryNode:
+		operand, err := e.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		return e.unary(n, operand)
+
+	case *binaryNode:
+		return e.binary(n)
+
+	case *conditionalNode:
+		condition, err := e.eval(n.condition)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := condition.(bool)
+		if !ok {
+			return nil, e.fail(n, "condition is %s, not bool", typeName(condition))
+		}
+		if value {
+			return e.eval(n.then)
+		}
+		return e.eval(n.other)
+
+	case *callNode:
+		args := make([]interface{}, len(n.args))
+		for i, arg := range n.args {
+			value, err := e.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		if builtin, ok := builtins[n.name]; ok {
+			result, err := builtin.fn(e, n, args)
+			if err != nil {
+				var exprErr *Error
+				if errors.As(err, &exprErr) {
+					return nil, err
+				}
+				return nil, e.fail(n, "%s: %v", n.name, err)
+			}
+			return result, nil
+		}
+		result, err := e.functions[n.name](args...)
+		if err != nil {
+			return nil, e.fail(n, "%s: %v", n.name, err)
+		}
+		return normalize(result), nil
+	}
+	return nil, e.fail(n, "unsupported expression")
+}
+
+func (e *evaluator) unary(n *unaryNode, operand interface{}) (interface{}, error) {
+	switch n.op {
+	case "!":
+		if value, ok := operand.(bool); ok {
+			return !value, nil
+		}
+	case "-":
+		switch value := operand.(type) {
+		case int64:
+			if value == math.MinInt64 {
+				return nil, e.fail(n, "integer overflow")
+			}
+			return -value, nil
+		case float64:
+			return -value, nil
+		case time.Duration:
+			return -value, nil
+		}
+	}
+	return nil, e.fail(n, "invalid operand %s for %s", typeName(operand), n.op)
+}
+
+func (e *evaluator) binary(n *binaryNode) (interface{}, error) {
+	left, err := e.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit
+	if n.op == "&&" || n.op == "||" {
+		value, ok := left.(bool)
+		if !ok {
+			return nil, e.fail(n, "invalid operand %s for %s", typeName(left), n.op)
+		}
+		if value == (n.op == "||") {
+			return value, nil
+		}
+		right, err := e.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := right.(bool); ok {
+			return value, nil
+		}
+		return nil, e.fail(n, "invalid operand %s for %s", typeName(right), n.op)
+	}
+
+	right, err := e.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		equal, err := e.equal(n, left, right)
+		if err != nil {
+			return nil, err
+		}
+		return equal == (n.op == "=="), nil
+
+	case "<", "<=", ">", ">=":
+		order, ok := compare(left, right)
+		if !ok {
+			return nil, e.fail(n, "cannot compare %s and %s", typeName(left), typeName(right))
+		}
+		switch n.op {
+		case "<":
+			return order < 0, nil
+		case "<=":
+			return order <= 0, nil
+		case ">":
+			return order > 0, nil
+		}
+		return order >= 0, nil
+
+	case "in":
+		return e.contains(n, right, left)
+	}
+	return e.arithmetic(n, left, right)
+}
+
+func (e *evaluator) arithmetic(n *binaryNode, left, right interface{}) (interface{}, error) {
+	if a, ok := left.(int64); ok {
+		if b, ok := right.(int64); ok {
+			return e.integer(n, a, b)
+		}
+	}
+	if a, ok := toFloat(left); ok {
+		if b, ok := toFloat(right); ok {
+			switch n.op {
+			case "+":
+				return a + b, nil
+			case "-":
+				return a - b, nil
+			case "*":
+				return a * b, nil
+			case "/":
+				return a / b, nil
+			}
+		}
+	}
+
+	switch a := left.(type) {
+	case string:
+		if b, ok := right.(string); ok && n.op == "+" {
+			if err := e.checkSize(n, len(a)+len(b)); err != nil {
+				return nil, err
+			}
+			return a + b, nil
+		}
+	case time.Time:
+		switch b := right.(type) {
+		case time.Duration:
+			if n.op == "+" {
+				return a.Add(b), nil
+			}
+			if n.op == "-" {
+				return a.Add(-b), nil
+			}
+		case time.Time:
+			if n.op == "-" {
+				return a.Sub(b), nil
+			}
+		}
+	case time.Duration:
+		switch b := right.(type) {
+		case time.Duration:
+			if n.op == "+" {
+				return a + b, nil
+			}
+			if n.op == "-" {
+				return a - b, nil
+			}
+		case time.Time:
+			if n.op == "+" {
+				return b.Add(a), nil
+			}
+		}
+	}
+
+	if n.op == "+" {
+		if a, ok := listValues(left); ok {
+			if b, ok := listValues(right); ok {
+				if err := e.checkSize(n, len(a)+len(b)); err != nil {
+					return nil, err
+				}
+				return append(append(make([]interface{}, 0, len(a)+len(b)), a...), b...), nil
+			}
+		}
+	}
+	return nil, e.fail(n, "invalid operands %s and %s for %s", typeName(left), typeName(right), n.op)
+}
+
+// integer applies an arithmetic operator to integers, failing on overflow
+func (e *evaluator) integer(n *binaryNode, a, b int64) (interface{}, error) {
+	switch n.op {
+	case "+":
+		r := a + b
+		if (a^r)&(b^r) < 0 {
+			return nil, e.fail(n, "integer overflow")
+		}
+		return r, nil
+	case "-":
+		r := a - b
+		if (a^b)&(a^r) < 0 {
+			return nil, e.fail(n, "integer overflow")
+		}
+		return r, nil
+	case "*":
+		r := a * b
+		if a != 0 && (r/a != b || (a == -1 && b == math.MinInt64)) {
+			return nil, e.fail(n, "integer overflow")
+		}
+		return r, nil
+	case "/", "%":
+		if b == 0 {
+			return nil, e.fail(n, "division by zero")
+		}
+		if a == math.MinInt64 && b == -1 {
+			return nil, e.fail(n, "integer overflow")
+		}
+		if n.op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	}
+	return nil, e.fail(n, "invalid operator %s", n.op)
+}
+
+// equal compares values; numbers are equal across int and float
+func (e *evaluator) equal(n node, a, b interface{}) (bool, error) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			if i, ok := a.(int64); ok {
+				if j, ok := b.(int64); ok {
+					return i == j, nil
+				}
+			}
+			return x == y, nil
+		}
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return b == nil, nil
+	case bool, string, time.Duration:
+		return a == b, nil
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y), nil
+	}
+
+	if x, ok := listValues(a); ok {
+		y, ok := listValues(b)
+		if !ok || len(x) != len(y) {
+			return false, nil
+		}
+		for i := range x {
+			if err := e.step(n, 1); err != nil {
+				return false, err
+			}
+			equal, err := e.equal(n, normalize(x[i]), normalize(y[i]))
+			if err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if b == nil {
+		return false, nil
+	}
+	if err := e.step(n, 16); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(a, b), nil
+}
+
+// contains implements "in" for lists and map keys
+func (e *evaluator) contains(n node, container, value interface{}) (bool, error) {
+	if items, ok := listValues(container); ok {
+		for _, item := range items {
+			if err := e.step(n, 1); err != nil {
+				return false, err
+			}
+			equal, err := e.equal(n, value, normalize(item))
+			if err != nil {
+				return false, err
+			}
+			if equal {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	key, ok := value.(string)
+	if !ok {
+		return false, e.fail(n, "invalid operand %s for in", typeName(value))
+	}
+	switch m := container.(type) {
+	case map[string]interface{}:
+		_, found := m[key]
+		return found, nil
+	}
+	rv := reflect.ValueOf(container)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		return rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).IsValid(), nil
+	}
+	return false, e.fail(n, "invalid operand %s for in", typeName(container))
+}
+
+// compare orders numbers, strings, times and durations
+func compare(a, b interface{}) (int, bool) {
+	if i, ok := a.(int64); ok {
+		if j, ok := b.(int64); ok {
+			return order(i < j, i > j), true
+		}
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return order(x < y, x > y), true
+		}
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+	case time.Duration:
+		if y, ok := b.(time.Duration); ok {
+			return order(x < y, x > y), true
+		}
+	}
+	return 0, false
+}
+
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// toFloat converts numbers to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// normalize converts Go values to the values of the language: integers become int64,
+// floats float64, pointers are dereferenced and named scalar types are unwrapped.
+// Lists, maps and structs are kept and read with reflection.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int64, float64, string, time.Time, time.Duration, []interface{}, map[string]interface{}:
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []byte:
+		return string(v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Type() == reflect.TypeOf(time.Duration(0)) {
+			return time.Duration(rv.Int())
+		}
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	return value
+}
+
+// listValues returns the items of lists, slices and arrays
+func listValues(value interface{}) ([]interface{}, bool) {
+	if items, ok := value.([]interface{}); ok {
+		return items, true
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// member reads a field of a map or struct
+func member(value interface{}, name string) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("cannot read field %q of null", name)
+	case map[string]interface{}:
+		return normalize(v[name]), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			item := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !item.IsValid() {
+				return nil, nil
+			}
+			return normalize(item.Interface()), nil
+		}
+	case reflect.Struct:
+		if _, isTime := value.(time.Time); !isTime {
+			if field, ok := structField(rv, name); ok {
+				return normalize(field.Interface()), nil
+			}
+			return nil, fmt.Errorf("no field %q", name)
+		}
+	}
+	return nil, fmt.Errorf("cannot read field %q of %s", name, typeName(value))
+}
+
+// structField finds an exported field, or a field of an embedded struct, by JSON or Go name
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == name || field.Name == name {
+			return rv.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+		embedded := rv.Field(i)
+		if embedded.Kind() == reflect.Ptr {
+			if embedded.IsNil() {
+				continue
+			}
+			embedded = embedded.Elem()
+		}
+		if embedded.Kind() == reflect.Struct {
+			if value, ok := structField(embedded, name); ok {
+				return value, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// indexValue reads an item of a list or map, or a field of a struct
+func indexValue(value, index interface{}) (interface{}, error) {
+	if name, ok := index.(string); ok {
+		return member(value, name)
+	}
+	i, ok := index.(int64)
+	if !ok {
+		return nil, fmt.Errorf("invalid index %s", typeName(index))
+	}
+	items, ok := listValues(value)
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s with an integer", typeName(value))
+	}
+	if i < 0 || i >= int64(len(items)) {
+		return nil, fmt.Errorf("index %d out of range", i)
+	}
+	return normalize(items[i]), nil
+}
+
+// project restricts a struct or map variable to its whitelisted fields
+func project(value interface{}, fields []string) (interface{}, error) {
+	value = normalize(value)
+	if fields == nil || value == nil {
+		return value, nil
+	}
+	kind := reflect.TypeOf(value).Kind()
+	if _, isTime := value.(time.Time); isTime || (kind != reflect.Struct && kind != reflect.Map) {
+		return value, nil
+	}
+
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		fieldValue, err := member(value, field)
+		if err != nil {
+			return nil, err
+		}
+		projected[field] = fieldValue
+	}
+	return projected, nil
+}
+
+// typeName names the type of a value in error messages
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case time.Time:
+		return "timestamp"
+	case time.Duration:
+		return "duration"
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,189 @@
+// Package expr evaluates user-provided expressions in a sandbox. It is shared by the
+// subsystems running expressions (computed fields, conditional validations, policies)
+// so they all accept the same language and enforce the same limits.
+//
+// The language is a small subset of CEL:
+//
+//	record.status == "open" && record.total >= 100
+//	"admin" in roles || record.ownerId == user.id
+//	size(record.tags) > 0 ? lower(record.tags[0]) : "none"
+//	timestamp(record.dueAt) < timestamp("2025-01-01T00:00:00Z") + duration("24h")
+//
+// Expressions only see the declared variables, and only the whitelisted fields of
+// them. Every function is deterministic: there is no clock, randomness or I/O, so the
+// same input always gives the same result. Evaluation is bounded by a step budget, a
+// timeout and a maximum size of the strings and lists it builds.
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors wrapped by evaluation errors when a limit is exceeded
+var (
+	ErrCostLimit = errors.New("evaluation cost limit exceeded")
+	ErrTimeout   = errors.New("evaluation timed out")
+	ErrSizeLimit = errors.New("value size limit exceeded")
+)
+
+// Default limits
+const (
+	DefaultMaxLength = 4096
+	DefaultMaxDepth  = 50
+	DefaultMaxCost   = 10000
+	DefaultMaxSize   = 64 * 1024
+	DefaultTimeout   = 50 * time.Millisecond
+)
+
+// Function is an application-provided function. It must be deterministic: its result
+// may only depend on its arguments.
+type Function func(args ...interface{}) (interface{}, error)
+
+// Limits bound the resources used by an expression
+type Limits struct {
+	// Maximum length of the source in bytes (default DefaultMaxLength)
+	MaxLength int
+
+	// Maximum nesting of the expression (default DefaultMaxDepth)
+	MaxDepth int
+
+	// Maximum number of evaluation steps (default DefaultMaxCost)
+	MaxCost int
+
+	// Maximum length of strings and lists built during evaluation (default DefaultMaxSize)
+	MaxSize int
+
+	// Maximum evaluation time (default DefaultTimeout)
+	Timeout time.Duration
+}
+
+// Env declares what expressions may use
+type Env struct {
+	// Variables maps the names of the variables to the fields expressions may read
+	// from them. Nil allows every field; an empty list allows none, e.g. for scalars.
+	// Fields are matched by JSON name or Go name.
+	Variables map[string][]string
+
+	// Functions are added to the built-in functions
+	Functions map[string]Function
+
+	Limits Limits
+}
+
+// Error is a compilation or evaluation error
+type Error struct {
+	// Position of the error in the source (0-based byte offset)
+	Position int
+	Message  string
+
+	// Err is the limit error, if a limit was exceeded
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("expr: %s (at position %d)", e.Message, e.Position)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Program is a compiled expression
+type Program struct {
+	source string
+	root   node
+	env    Env
+}
+
+// Compile parses an expression and checks it against the environment
+func Compile(source string, env Env) (*Program, error) {
+	env.Limits = env.Limits.withDefaults()
+	if len(source) > env.Limits.MaxLength {
+		return nil, &Error{Message: fmt.Sprintf("expression longer than %d bytes", env.Limits.MaxLength)}
+	}
+	for name := range env.Functions {
+		if _, ok := builtins[name]; ok {
+			return nil, &Error{Message: fmt.Sprintf("function %q redeclares a built-in function", name)}
+		}
+	}
+
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, maxDepth: env.Limits.MaxDepth}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if err := check(root, env); err != nil {
+		return nil, err
+	}
+	return &Program{source: source, root: root, env: env}, nil
+}
+
+// MustCompile is like Compile but panics on errors
+func MustCompile(source string, env Env) *Program {
+	program, err := Compile(source, env)
+	if err != nil {
+		panic(err)
+	}
+	return program
+}
+
+// Source returns the expression
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Eval evaluates the expression. Variables missing from vars are null.
+func (p *Program) Eval(ctx context.Context, vars map[string]interface{}) (interface{}, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.env.Limits.Timeout)
+	defer cancel()
+
+	e := &evaluator{ctx: ctx, limits: p.env.Limits, functions: p.env.Functions, vars: make(map[string]interface{}, len(p.env.Variables))}
+	for name, fields := range p.env.Variables {
+		value, err := project(vars[name], fields)
+		if err != nil {
+			return nil, &Error{Message: fmt.Sprintf("variable %q: %v", name, err)}
+		}
+		e.vars[name] = value
+	}
+	return e.eval(p.root)
+}
+
+// EvalBool evaluates an expression that must return a boolean
+func (p *Program) EvalBool(ctx context.Context, vars map[string]interface{}) (bool, error) {
+	result, err := p.Eval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+	value, ok := result.(bool)
+	if !ok {
+		return false, &Error{Position: p.root.pos(), Message: fmt.Sprintf("expression returned %s, not bool", typeName(result))}
+	}
+	return value, nil
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxLength <= 0 {
+		l.MaxLength = DefaultMaxLength
+	}
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	if l.MaxCost <= 0 {
+		l.MaxCost = DefaultMaxCost
+	}
+	if l.MaxSize <= 0 {
+		l.MaxSize = DefaultMaxSize
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultTimeout
+	}
+	return l
+}
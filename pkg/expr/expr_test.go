@@ -0,0 +1,173 @@
+package expr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOrder struct {
+	ID       uint       `json:"id"`
+	Status   string     `json:"status"`
+	Total    float64    `json:"total"`
+	Items    []testItem `json:"items"`
+	Tags     []string   `json:"tags"`
+	DueAt    *time.Time `json:"dueAt"`
+	Secret   string     `json:"-"`
+	Internal string     `json:"internal"`
+}
+
+type testItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+func TestEval(t *testing.T) {
+	due := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	vars := map[string]interface{}{
+		"record": &testOrder{
+			ID: 7, Status: "open", Total: 120.5, Tags: []string{"VIP", "b2b"}, DueAt: &due,
+			Items: []testItem{{Name: "pen", Quantity: 3}, {Name: "ink", Quantity: 1}},
+		},
+		"roles": []string{"editor"},
+		"limit": 100,
+	}
+	env := Env{Variables: map[string][]string{"record": nil, "roles": {}, "limit": {}}}
+
+	tests := map[string]interface{}{
+		`record.status == "open" && record.total >= limit`:                             true,
+		`record.total > 200 || "admin" in roles`:                                       false,
+		`"editor" in roles`:                                                            true,
+		`record.items[0].quantity * 2 + record.items[1].quantity`:                      int64(7),
+		`size(record.items) == 2 ? lower(record.tags[0]) : "none"`:                     "vip",
+		`record["status"].startsWith("op")`:                                            true,
+		`record.id == 7.0 && 7 / 2 == 3 && 7 % 2 == 1 && 7.0 / 2 == 3.5`:               true,
+		`timestamp(record.dueAt) < timestamp("2024-05-02T00:00:00Z")`:                  true,
+		`record.dueAt + duration("12h") == timestamp("2024-05-02T00:00:00Z")`:          true,
+		`string(record.dueAt - timestamp("2024-05-01T00:00:00Z"))`:                     "12h0m0s",
+		`max(record.total, 10, int("200")) - min([3, 1, 2])`:                           int64(199),
+		`matches(record.tags[1], "^b[0-9]b$") && !contains("abc", "d")`:                true,
+		`[1, "a"] + [true] == [1, "a", true]`:                                          true,
+		`has(record, "status") && !has(record, "missing")`:                             true,
+		`-abs(-2) + round(2.5) + floor(-1.5) + ceil(1.2)`:                              float64(1),
+		`"a\té" + 'b' == "a\tébb".trim().upper().lower().endsWith("b") ? "yes" : "no"`: "no",
+		`!has(record.items[0], "missing") && record.items[0]["name"] == "pen"`:         true,
+	}
+	for source, want := range tests {
+		program, err := Compile(source, env)
+		require.NoError(t, err, source)
+		got, err := program.Eval(context.Background(), vars)
+		require.NoError(t, err, source)
+		assert.Equal(t, want, got, source)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	env := Env{
+		Variables: map[string][]string{"record": {"status", "total"}, "user": nil},
+		Functions: map[string]Function{"discount": func(args ...interface{}) (interface{}, error) { return 0.1, nil }},
+	}
+
+	tests := map[string]string{
+		`record.status ==`:       "unexpected end of expression",
+		`record.status = "open"`: `unexpected character '='`,
+		`unknown > 1`:            `undeclared variable "unknown"`,
+		`record.internal == ""`:  `field "internal" of "record" is not accessible`,
+		`record["internal"]`:     `field "internal" of "record" is not accessible`,
+		`record[user.field]`:     `fields of "record" must be accessed by name`,
+		`now() > 1`:              `undeclared function "now"`,
+		`size(1, 2)`:             "wrong number of arguments to size",
+		`"unterminated`:          "unterminated string",
+		`99999999999999999999`:   "invalid number",
+		`(1 + 2`:                 `expected ")", found end of expression`,
+		`record.status in`:       "unexpected end of expression",
+		strings.Repeat("(", 60) + "1" + strings.Repeat(")", 60): "nested deeper than 50 levels",
+	}
+	for source, want := range tests {
+		_, err := Compile(source, env)
+		require.Error(t, err, source)
+		assert.Contains(t, err.Error(), want, source)
+	}
+
+	_, err := Compile(strings.Repeat("1+", 3000)+"1", env)
+	assert.ErrorContains(t, err, "longer than 4096 bytes")
+
+	_, err = Compile(`size(x)`, Env{Functions: map[string]Function{"size": nil}})
+	assert.ErrorContains(t, err, `function "size" redeclares a built-in function`)
+
+	program, err := Compile(`user.anything == null && record.total * discount() > 10`, env)
+	require.NoError(t, err)
+	assert.Equal(t, `user.anything == null && record.total * discount() > 10`, program.Source())
+}
+
+func TestWhitelistedFieldsAreProjected(t *testing.T) {
+	env := Env{Variables: map[string][]string{"record": {"status", "total"}}}
+	record := testOrder{Status: "open", Total: 10, Internal: "hidden"}
+
+	// Whole values only expose whitelisted fields
+	program := MustCompile(`size(record) == 2 && !has(record, "internal") && record.total == 10`, env)
+	ok, err := program.EvalBool(context.Background(), map[string]interface{}{"record": record})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = MustCompile(`record.secretField`, Env{Variables: map[string][]string{"record": {"secretField"}}}).
+		Eval(context.Background(), map[string]interface{}{"record": record})
+	assert.ErrorContains(t, err, `variable "record": no field "secretField"`)
+}
+
+func TestEvalErrors(t *testing.T) {
+	env := Env{Variables: map[string][]string{"record": nil, "n": {}}}
+	vars := map[string]interface{}{"record": map[string]interface{}{"name": "a", "list": []int{1}}, "n": int64(9223372036854775807)}
+
+	tests := map[string]string{
+		`record.name > 1`:        "cannot compare string and int",
+		`record.name && true`:    "invalid operand string for &&",
+		`1 / 0`:                  "division by zero",
+		`n + 1`:                  "integer overflow",
+		`record.list[3]`:         "index 3 out of range",
+		`record.missing.field`:   `cannot read field "field" of null`,
+		`"x" - 1`:                "invalid operands string and int for -",
+		`int("abc")`:             `int: strconv.ParseInt: parsing "abc"`,
+		`timestamp("yesterday")`: "timestamp: parsing time",
+		`record.name ? 1 : 2`:    "condition is string, not bool",
+		`matches("a", "(")`:      "matches: error parsing regexp",
+	}
+	for source, want := range tests {
+		program, err := Compile(source, env)
+		require.NoError(t, err, source)
+		_, err = program.Eval(context.Background(), vars)
+		require.Error(t, err, source)
+		assert.Contains(t, err.Error(), want, source)
+	}
+
+	_, err := MustCompile(`1 + 1`, env).EvalBool(context.Background(), nil)
+	assert.ErrorContains(t, err, "expression returned int, not bool")
+}
+
+func TestLimits(t *testing.T) {
+	env := Env{Variables: map[string][]string{"s": {}, "list": {}}, Limits: Limits{MaxCost: 50, MaxSize: 1000}}
+	vars := map[string]interface{}{"s": strings.Repeat("x", 600), "list": make([]int, 100)}
+
+	_, err := MustCompile(`s + s`, env).Eval(context.Background(), vars)
+	assert.True(t, errors.Is(err, ErrSizeLimit), err)
+
+	_, err = MustCompile(`-1 in list`, env).Eval(context.Background(), vars)
+	assert.True(t, errors.Is(err, ErrCostLimit), err)
+
+	slow := Env{
+		Variables: map[string][]string{"list": {}},
+		Functions: map[string]Function{"wait": func(args ...interface{}) (interface{}, error) {
+			time.Sleep(2 * time.Millisecond)
+			return true, nil
+		}},
+		Limits: Limits{Timeout: time.Millisecond},
+	}
+	source := strings.Repeat("wait() && ", 40) + "true"
+	_, err = MustCompile(source, slow).Eval(context.Background(), nil)
+	assert.True(t, errors.Is(err, ErrTimeout), err)
+}
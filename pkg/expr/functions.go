@@ -0,0 +1,262 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// builtin is a built-in function; maxArgs -1 allows any number of arguments
+type builtin struct {
+	minArgs, maxArgs int
+	fn               func(e *evaluator, n node, args []interface{}) (interface{}, error)
+}
+
+// builtins are the built-in functions. They are all deterministic.
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"size":       {1, 1, size},
+		"contains":   {2, 2, stringFunction(strings.Contains)},
+		"startsWith": {2, 2, stringFunction(strings.HasPrefix)},
+		"endsWith":   {2, 2, stringFunction(strings.HasSuffix)},
+		"matches":    {2, 2, matches},
+		"lower":      {1, 1, stringTransform(strings.ToLower)},
+		"upper":      {1, 1, stringTransform(strings.ToUpper)},
+		"trim":       {1, 1, stringTransform(strings.TrimSpace)},
+		"abs":        {1, 1, floatFunction(math.Abs)},
+		"ceil":       {1, 1, floatFunction(math.Ceil)},
+		"floor":      {1, 1, floatFunction(math.Floor)},
+		"round":      {1, 1, floatFunction(math.Round)},
+		"min":        {1, -1, extremum(-1)},
+		"max":        {1, -1, extremum(1)},
+		"int":        {1, 1, toInt},
+		"float":      {1, 1, toFloatFunction},
+		"string":     {1, 1, toString},
+		"timestamp":  {1, 1, timestamp},
+		"duration":   {1, 1, duration},
+		"has":        {2, 2, has},
+	}
+}
+
+func size(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	if s, ok := args[0].(string); ok {
+		return int64(utf8.RuneCountInString(s)), nil
+	}
+	if m, ok := args[0].(map[string]interface{}); ok {
+		return int64(len(m)), nil
+	}
+	rv := reflect.ValueOf(args[0])
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return int64(rv.Len()), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func stringFunction(fn func(s, substr string) bool) func(*evaluator, node, []interface{}) (interface{}, error) {
+	return func(e *evaluator, n node, args []interface{}) (interface{}, error) {
+		s, ok1 := args[0].(string)
+		substr, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("invalid arguments %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		if err := e.step(n, len(s)/64); err != nil {
+			return nil, err
+		}
+		return fn(s, substr), nil
+	}
+}
+
+func stringTransform(fn func(string) string) func(*evaluator, node, []interface{}) (interface{}, error) {
+	return func(e *evaluator, n node, args []interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+		}
+		result := fn(s)
+		if err := e.checkSize(n, len(result)); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// matches reports whether a string matches a regular expression. Go regular
+// expressions run in linear time, so patterns cannot backtrack catastrophically.
+func matches(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	s, ok1 := args[0].(string)
+	pattern, ok2 := args[1].(string)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("invalid arguments %s and %s", typeName(args[0]), typeName(args[1]))
+	}
+	if err := e.step(n, (len(s)+len(pattern)*8)/16); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(s), nil
+}
+
+func floatFunction(fn func(float64) float64) func(*evaluator, node, []interface{}) (interface{}, error) {
+	return func(e *evaluator, n node, args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case int64:
+			result := fn(float64(v))
+			if result >= math.MaxInt64 || result < math.MinInt64 {
+				return nil, errors.New("integer overflow")
+			}
+			return int64(result), nil
+		case float64:
+			return fn(v), nil
+		}
+		return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+	}
+}
+
+// extremum returns the smallest (want -1) or largest (want 1) argument, or item of a
+// single list argument
+func extremum(want int) func(*evaluator, node, []interface{}) (interface{}, error) {
+	return func(e *evaluator, n node, args []interface{}) (interface{}, error) {
+		values := args
+		if len(args) == 1 {
+			items, ok := listValues(args[0])
+			if !ok {
+				return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+			}
+			if len(items) == 0 {
+				return nil, errors.New("empty list")
+			}
+			values = items
+		}
+
+		result := normalize(values[0])
+		for _, value := range values[1:] {
+			if err := e.step(n, 1); err != nil {
+				return nil, err
+			}
+			value = normalize(value)
+			order, ok := compare(value, result)
+			if !ok {
+				return nil, fmt.Errorf("cannot compare %s and %s", typeName(value), typeName(result))
+			}
+			if order == want {
+				result = value
+			}
+		}
+		if _, ok := compare(result, result); !ok {
+			return nil, fmt.Errorf("invalid argument %s", typeName(result))
+		}
+		return result, nil
+	}
+}
+
+func toInt(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+			return nil, errors.New("integer overflow")
+		}
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	case time.Duration:
+		return int64(v), nil
+	case time.Time:
+		return v.Unix(), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func toFloatFunction(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+func toString(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return v.String(), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+// timestamp parses an RFC 3339 time
+func timestamp(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+// duration parses a duration such as "1h30m"
+func duration(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
+
+// has reports whether a map has a key or a struct a field
+func has(e *evaluator, n node, args []interface{}) (interface{}, error) {
+	name, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid field name %s", typeName(args[1]))
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return false, nil
+	case map[string]interface{}:
+		_, found := v[name]
+		return found, nil
+	}
+	rv := reflect.ValueOf(args[0])
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())).IsValid(), nil
+		}
+	case reflect.Struct:
+		_, found := structField(rv, name)
+		return found, nil
+	}
+	return nil, fmt.Errorf("invalid argument %s", typeName(args[0]))
+}
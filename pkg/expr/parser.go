@@ -0,0 +1,570 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// operators are the operator tokens, two-character ones first
+var operators = []string{
+	"<=", ">=", "==", "!=", "&&", "||",
+	"(", ")", "[", "]", ",", ".", "?", ":", "!", "-", "+", "*", "/", "%", "<", ">",
+}
+
+// lex splits the source into tokens
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c >= '0' && c <= '9':
+			start := i
+			isFloat := false
+			for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+				i++
+			}
+			if i+1 < len(source) && source[i] == '.' && source[i+1] >= '0' && source[i+1] <= '9' {
+				isFloat = true
+				for i++; i < len(source) && source[i] >= '0' && source[i] <= '9'; i++ {
+				}
+			}
+			if i < len(source) && (source[i] == 'e' || source[i] == 'E') {
+				isFloat = true
+				i++
+				if i < len(source) && (source[i] == '+' || source[i] == '-') {
+					i++
+				}
+				for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+					i++
+				}
+			}
+			text := source[start:i]
+			var value interface{}
+			var err error
+			if isFloat {
+				value, err = strconv.ParseFloat(text, 64)
+			} else {
+				value, err = strconv.ParseInt(text, 10, 64)
+			}
+			if err != nil {
+				return nil, &Error{Position: start, Message: fmt.Sprintf("invalid number %q", text)}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+
+		case c == '"' || c == '\'':
+			value, end, err := lexString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[i:end], value: value, pos: i})
+			i = end
+
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(source) && (source[i] == '_' || (source[i] >= 'a' && source[i] <= 'z') || (source[i] >= 'A' && source[i] <= 'Z') || (source[i] >= '0' && source[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				r, _ := utf8.DecodeRuneInString(source[i:])
+				return nil, &Error{Position: i, Message: fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// lexString reads a quoted string starting at start and returns its value and end
+func lexString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var b strings.Builder
+	for i := start + 1; i < len(source); {
+		c := source[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\':
+			if i+1 >= len(source) {
+				break
+			}
+			switch escape := source[i+1]; escape {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '"', '\'':
+				b.WriteByte(escape)
+			case 'u':
+				if i+6 > len(source) {
+					return "", 0, &Error{Position: i, Message: "invalid unicode escape"}
+				}
+				code, err := strconv.ParseUint(source[i+2:i+6], 16, 32)
+				if err != nil {
+					return "", 0, &Error{Position: i, Message: "invalid unicode escape"}
+				}
+				b.WriteRune(rune(code))
+				i += 6
+				continue
+			default:
+				return "", 0, &Error{Position: i, Message: fmt.Sprintf("invalid escape \\%c", escape)}
+			}
+			i += 2
+			continue
+		case c == '\n':
+			return "", 0, &Error{Position: i, Message: "newline in string"}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return "", 0, &Error{Position: start, Message: "unterminated string"}
+}
+
+// node is a node of the syntax tree
+type node interface {
+	pos() int
+}
+
+type literalNode struct {
+	p     int
+	value interface{}
+}
+
+type identNode struct {
+	p    int
+	name string
+}
+
+type memberNode struct {
+	p      int
+	target node
+	name   string
+}
+
+type indexNode struct {
+	p             int
+	target, index node
+}
+
+// callNode is a function call; receiver calls x.f(a) are calls f(x, a)
+type callNode struct {
+	p    int
+	name string
+	args []node
+}
+
+type unaryNode struct {
+	p       int
+	op      string
+	operand node
+}
+
+type binaryNode struct {
+	p           int
+	op          string
+	left, right node
+}
+
+type conditionalNode struct {
+	p                      int
+	condition, then, other node
+}
+
+type listNode struct {
+	p     int
+	items []node
+}
+
+func (n *literalNode) pos() int     { return n.p }
+func (n *identNode) pos() int       { return n.p }
+func (n *memberNode) pos() int      { return n.p }
+func (n *indexNode) pos() int       { return n.p }
+func (n *callNode) pos() int        { return n.p }
+func (n *unaryNode) pos() int       { return n.p }
+func (n *binaryNode) pos() int      { return n.p }
+func (n *conditionalNode) pos() int { return n.p }
+func (n *listNode) pos() int        { return n.p }
+
+// binaryLevels are the binary operators by increasing precedence
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parser is a recursive descent parser
+type parser struct {
+	tokens   []token
+	current  int
+	depth    int
+	maxDepth int
+}
+
+func (p *parser) parse() (node, error) {
+	root, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, &Error{Position: next.pos, Message: fmt.Sprintf("unexpected %q", next.text)}
+	}
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEOF {
+		p.current++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == text {
+		p.current++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return &Error{Position: t.pos, Message: fmt.Sprintf("expected %q, found end of expression", text)}
+		}
+		return &Error{Position: t.pos, Message: fmt.Sprintf("expected %q, found %q", text, t.text)}
+	}
+	return nil
+}
+
+// enter tracks the nesting depth
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > p.maxDepth {
+		return &Error{Position: p.peek().pos, Message: fmt.Sprintf("expression nested deeper than %d levels", p.maxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) expression() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	condition, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	start := p.peek().pos
+	if !p.accept("?") {
+		return condition, nil
+	}
+	then, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	other, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{p: start, condition: condition, then: then, other: other}, nil
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		for _, candidate := range binaryLevels[level] {
+			if (t.kind == tokenOperator || t.kind == tokenIdent) && t.text == candidate {
+				op = candidate
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{p: t.pos, op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	t := p.peek()
+	if t.kind == tokenOperator && (t.text == "!" || t.text == "-") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{p: t.pos, op: t.text, operand: operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	target, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, &Error{Position: name.pos, Message: "expected field name after \".\""}
+			}
+			if p.peek().kind == tokenOperator && p.peek().text == "(" {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				target = &callNode{p: name.pos, name: name.text, args: append([]node{target}, args...)}
+			} else {
+				target = &memberNode{p: t.pos, target: target, name: name.text}
+			}
+		case p.accept("["):
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			target = &indexNode{p: t.pos, target: target, index: index}
+		default:
+			return target, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{p: t.pos, value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{p: t.pos, value: true}, nil
+		case "false":
+			return &literalNode{p: t.pos, value: false}, nil
+		case "null":
+			return &literalNode{p: t.pos, value: nil}, nil
+		case "in":
+			return nil, &Error{Position: t.pos, Message: "unexpected \"in\""}
+		}
+		if p.peek().kind == tokenOperator && p.peek().text == "(" {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{p: t.pos, name: t.text, args: args}, nil
+		}
+		return &identNode{p: t.pos, name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer func() { p.depth-- }()
+			list := &listNode{p: t.pos}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case tokenEOF:
+		return nil, &Error{Position: t.pos, Message: "unexpected end of expression"}
+	}
+	return nil, &Error{Position: t.pos, Message: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// arguments parses a parenthesized argument list
+func (p *parser) arguments() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// check resolves the variables, fields and functions of the tree against the environment
+func check(n node, env Env) error {
+	switch n := n.(type) {
+	case *identNode:
+		if _, ok := env.Variables[n.name]; !ok {
+			return &Error{Position: n.p, Message: fmt.Sprintf("undeclared variable %q", n.name)}
+		}
+	case *memberNode:
+		if ident, ok := n.target.(*identNode); ok {
+			if err := checkField(env, ident, n.name, n.p); err != nil {
+				return err
+			}
+		}
+		return check(n.target, env)
+	case *indexNode:
+		if ident, ok := n.target.(*identNode); ok {
+			if fields := env.Variables[ident.name]; fields != nil {
+				var name string
+				isString := false
+				if key, ok := n.index.(*literalNode); ok {
+					name, isString = key.value.(string)
+				}
+				if !isString {
+					return &Error{Position: n.p, Message: fmt.Sprintf("fields of %q must be accessed by name", ident.name)}
+				}
+				if err := checkField(env, ident, name, n.p); err != nil {
+					return err
+				}
+			}
+		}
+		if err := check(n.target, env); err != nil {
+			return err
+		}
+		return check(n.index, env)
+	case *callNode:
+		if builtin, ok := builtins[n.name]; ok {
+			if len(n.args) < builtin.minArgs || (builtin.maxArgs >= 0 && len(n.args) > builtin.maxArgs) {
+				return &Error{Position: n.p, Message: fmt.Sprintf("wrong number of arguments to %s", n.name)}
+			}
+		} else if _, ok := env.Functions[n.name]; !ok {
+			return &Error{Position: n.p, Message: fmt.Sprintf("undeclared function %q", n.name)}
+		}
+		for _, arg := range n.args {
+			if err := check(arg, env); err != nil {
+				return err
+			}
+		}
+	case *unaryNode:
+		return check(n.operand, env)
+	case *binaryNode:
+		if err := check(n.left, env); err != nil {
+			return err
+		}
+		return check(n.right, env)
+	case *conditionalNode:
+		for _, child := range []node{n.condition, n.then, n.other} {
+			if err := check(child, env); err != nil {
+				return err
+			}
+		}
+	case *listNode:
+		for _, item := range n.items {
+			if err := check(item, env); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkField checks that a field of a variable is whitelisted
+func checkField(env Env, variable *identNode, field string, position int) error {
+	fields, declared := env.Variables[variable.name]
+	if !declared || fields == nil {
+		return nil
+	}
+	for _, allowed := range fields {
+		if allowed == field {
+			return nil
+		}
+	}
+	return &Error{Position: position, Message: fmt.Sprintf("field %q of %q is not accessible", field, variable.name)}
+}
@@ -0,0 +1,488 @@
+// Package grpcgen serves registered resources over gRPC, so internal services can
+// read and write them without going through HTTP. The generic ResourceService has the
+// List, Get, Create, Update and Delete methods of every resource; their payloads are
+// google.protobuf.Struct messages holding the JSON of the HTTP API.
+package grpcgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/uow"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gorm.io/gorm"
+)
+
+// ServiceName is the full name of the generated service
+const ServiceName = "refinegin.ResourceService"
+
+// ProtoFile is the definition of the service, for clients generating their stubs:
+//
+//	List:   {"resource", "filters", "sorters", "pagination", "fields", "include", "q"} -> {"data": [...], "total"}
+//	Get:    {"resource", "id"} -> {"data"}
+//	Create: {"resource", "data"} -> {"data"}
+//	Update: {"resource", "id", "data"} -> {"data"}
+//	Delete: {"resource", "id"} -> {}
+//
+// List requests hold the parameters of POST list queries (handler.ListQueryRequest).
+const ProtoFile = `syntax = "proto3";
+
+package refinegin;
+
+import "google/protobuf/struct.proto";
+
+service ResourceService {
+  rpc List(google.protobuf.Struct) returns (google.protobuf.Struct);
+  rpc Get(google.protobuf.Struct) returns (google.protobuf.Struct);
+  rpc Create(google.protobuf.Struct) returns (google.protobuf.Struct);
+  rpc Update(google.protobuf.Struct) returns (google.protobuf.Struct);
+  rpc Delete(google.protobuf.Struct) returns (google.protobuf.Struct);
+}
+`
+
+// ResourceServiceServer is the server API of the ResourceService
+type ResourceServiceServer interface {
+	List(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Get(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Create(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Update(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Delete(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+// ServiceDesc describes the ResourceService for grpc.Server.RegisterService
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*ResourceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "List", Handler: unaryHandler("List", ResourceServiceServer.List)},
+		{MethodName: "Get", Handler: unaryHandler("Get", ResourceServiceServer.Get)},
+		{MethodName: "Create", Handler: unaryHandler("Create", ResourceServiceServer.Create)},
+		{MethodName: "Update", Handler: unaryHandler("Update", ResourceServiceServer.Update)},
+		{MethodName: "Delete", Handler: unaryHandler("Delete", ResourceServiceServer.Delete)},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "refinegin/resource_service.proto",
+}
+
+type method func(srv ResourceServiceServer, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+
+// unaryHandler decodes the request of a method and runs it through the interceptors
+func unaryHandler(name string, call method) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	fullMethod := "/" + ServiceName + "/" + name
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(structpb.Struct)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(ResourceServiceServer), ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(ResourceServiceServer), ctx, req.(*structpb.Struct))
+		})
+	}
+}
+
+// Config contains configuration for the ResourceService
+type Config struct {
+	// Registry the resources are looked up in (the global registry if nil)
+	Registry *resource.ResourceRegistry
+
+	// Repositories by resource name, e.g. those the resources are registered with on
+	// the router, so both APIs share their caches and decorators
+	Repositories map[string]repository.Repository
+
+	// DB the repositories of resources missing from Repositories are created on
+	DB *gorm.DB
+
+	// Factory creates the repositories of resources missing from Repositories
+	// (uow.DefaultRepositoryFactory if nil)
+	Factory uow.RepositoryFactory
+}
+
+// Service implements the ResourceService with the repositories of the resources. The
+// operations of resources, their DTOs and their lifecycle hooks apply as over HTTP;
+// authentication is left to interceptors.
+type Service struct {
+	config Config
+	mu     sync.Mutex
+	repos  map[string]repository.Repository
+}
+
+// NewService creates the ResourceService of the resources of a registry
+func NewService(config Config) *Service {
+	if config.Registry == nil {
+		config.Registry = resource.GlobalResourceRegistry
+	}
+	if config.Factory == nil {
+		config.Factory = uow.DefaultRepositoryFactory
+	}
+	repos := make(map[string]repository.Repository, len(config.Repositories))
+	for name, repo := range config.Repositories {
+		repos[name] = repo
+	}
+	return &Service{config: config, repos: repos}
+}
+
+// Register registers a ResourceService serving config on a gRPC server
+func Register(server grpc.ServiceRegistrar, config Config) *Service {
+	service := NewService(config)
+	server.RegisterService(&ServiceDesc, service)
+	return service
+}
+
+// request is the decoded payload of a request
+type request struct {
+	Resource string          `json:"resource"`
+	ID       interface{}     `json:"id"`
+	Data     json.RawMessage `json:"data"`
+	handler.ListQueryRequest
+}
+
+// List returns a page of the records of a resource
+func (s *Service) List(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	req, res, repo, err := s.prepare(in, resource.OperationList)
+	if err != nil {
+		return nil, err
+	}
+
+	options := query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Order: "asc"}
+	if defaultSort := res.GetDefaultSort(); defaultSort != nil {
+		options.Sort = defaultSort.Field
+		options.Order = defaultSort.Order
+	}
+	req.Apply(res, &options)
+	if err := options.Validate(); err != nil {
+		return nil, statusError(http.StatusBadRequest, err)
+	}
+	if hook := hooks(res).BeforeList; hook != nil {
+		if err := hook(ctx, res, &options); err != nil {
+			return nil, statusError(http.StatusUnprocessableEntity, err)
+		}
+	}
+
+	data, total, err := repo.List(ctx, options)
+	if err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+
+	// Transform the records (pointers to structs) to their response DTOs
+	provider := dto.ForResource(res)
+	records := make([]interface{}, 0)
+	if v := reflect.Indirect(reflect.ValueOf(data)); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			record, err := provider.TransformFromModel(item.Interface())
+			if err != nil {
+				return nil, statusError(http.StatusInternalServerError, err)
+			}
+			records = append(records, record)
+		}
+	}
+	return respond(map[string]interface{}{"data": records, "total": total})
+}
+
+// Get returns a record of a resource
+func (s *Service) Get(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	req, res, repo, err := s.prepare(in, resource.OperationRead)
+	if err != nil {
+		return nil, err
+	}
+	id, err := recordID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := repo.Get(ctx, id)
+	if err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+	return respondRecord(res, record)
+}
+
+// Create creates a record of a resource
+func (s *Service) Create(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	req, res, repo, err := s.prepare(in, resource.OperationCreate)
+	if err != nil {
+		return nil, err
+	}
+	provider := dto.ForResource(res)
+	model, err := decodeModel(req, provider.GetCreateDTO(), provider)
+	if err != nil {
+		return nil, err
+	}
+	if db := repo.Query(ctx); db != nil && len(res.GetRelations()) > 0 {
+		if err := resource.ValidateRelations(db, model); err != nil {
+			return nil, statusError(http.StatusBadRequest, err)
+		}
+	}
+
+	h := hooks(res)
+	if h.BeforeCreate != nil {
+		if err := h.BeforeCreate(ctx, res, model); err != nil {
+			return nil, statusError(http.StatusUnprocessableEntity, err)
+		}
+	}
+	created, err := repo.Create(ctx, model)
+	if err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+	if h.AfterCreate != nil {
+		if err := h.AfterCreate(ctx, res, created); err != nil {
+			return nil, statusError(http.StatusInternalServerError, err)
+		}
+	}
+	return respondRecord(res, created)
+}
+
+// Update updates a record of a resource
+func (s *Service) Update(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	req, res, repo, err := s.prepare(in, resource.OperationUpdate)
+	if err != nil {
+		return nil, err
+	}
+	id, err := recordID(req)
+	if err != nil {
+		return nil, err
+	}
+	provider := dto.ForResource(res)
+	model, err := decodeModel(req, provider.GetUpdateDTO(), provider)
+	if err != nil {
+		return nil, err
+	}
+	model = resource.FilterOutReadOnlyFields(model, res)
+	if db := repo.Query(ctx); db != nil && len(res.GetRelations()) > 0 {
+		if err := resource.ValidateRelations(db, model); err != nil {
+			return nil, statusError(http.StatusBadRequest, err)
+		}
+	}
+
+	ctx = resource.WithRecordID(ctx, id)
+	h := hooks(res)
+	if h.BeforeUpdate != nil {
+		if err := h.BeforeUpdate(ctx, res, model); err != nil {
+			return nil, statusError(http.StatusUnprocessableEntity, err)
+		}
+	}
+	updated, err := repo.Update(ctx, id, model)
+	if err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+	if h.AfterUpdate != nil {
+		if err := h.AfterUpdate(ctx, res, updated); err != nil {
+			return nil, statusError(http.StatusInternalServerError, err)
+		}
+	}
+	return respondRecord(res, updated)
+}
+
+// Delete deletes a record of a resource
+func (s *Service) Delete(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	req, res, repo, err := s.prepare(in, resource.OperationDelete)
+	if err != nil {
+		return nil, err
+	}
+	id, err := recordID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hooks(res)
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, res, id); err != nil {
+			return nil, statusError(http.StatusUnprocessableEntity, err)
+		}
+	}
+	if err := repo.Delete(ctx, id); err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+	if h.AfterDelete != nil {
+		if err := h.AfterDelete(ctx, res, id); err != nil {
+			return nil, statusError(http.StatusInternalServerError, err)
+		}
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{}}, nil
+}
+
+// prepare decodes a request and returns its resource, which must allow op, and the
+// repository of the resource
+func (s *Service) prepare(in *structpb.Struct, op resource.Operation) (*request, resource.Resource, repository.Repository, error) {
+	data, err := protojson.Marshal(in)
+	if err != nil {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, nil, nil, statusError(http.StatusBadRequest, err)
+	}
+	if req.Resource == "" {
+		return nil, nil, nil, status.Error(codes.InvalidArgument, "resource is required")
+	}
+	res, ok := s.config.Registry.GetByName(req.Resource)
+	if !ok {
+		return nil, nil, nil, status.Errorf(codes.NotFound, "unknown resource %q", req.Resource)
+	}
+	if !res.HasOperation(op) {
+		return nil, nil, nil, status.Errorf(codes.Unimplemented, "resource %q does not support %s", req.Resource, op)
+	}
+	repo, err := s.repository(res)
+	if err != nil {
+		return nil, nil, nil, status.Error(codes.Internal, err.Error())
+	}
+	return &req, res, repo, nil
+}
+
+// repository returns the repository of a resource, created on first use if it was not
+// configured
+func (s *Service) repository(res resource.Resource) (repository.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if repo, ok := s.repos[res.GetName()]; ok {
+		return repo, nil
+	}
+	if s.config.DB == nil {
+		return nil, fmt.Errorf("no repository for resource %q", res.GetName())
+	}
+	repo, err := s.config.Factory(s.config.DB, res)
+	if err != nil {
+		return nil, err
+	}
+	s.repos[res.GetName()] = repo
+	return repo, nil
+}
+
+func hooks(res resource.Resource) *resource.LifecycleHooks {
+	if hooked, ok := res.(resource.LifecycleHookResource); ok && hooked.GetHooks() != nil {
+		return hooked.GetHooks()
+	}
+	return &resource.LifecycleHooks{}
+}
+
+// recordID returns the ID of a request as a string, the type of IDs of HTTP routes
+func recordID(req *request) (string, error) {
+	switch id := req.ID.(type) {
+	case string:
+		if id != "" {
+			return id, nil
+		}
+	case float64:
+		if id == math.Trunc(id) {
+			return strconv.FormatInt(int64(id), 10), nil
+		}
+		return strconv.FormatFloat(id, 'f', -1, 64), nil
+	}
+	return "", status.Error(codes.InvalidArgument, "id is required")
+}
+
+// decodeModel decodes the data of a request into a DTO and transforms it to a model
+func decodeModel(req *request, dtoInstance interface{}, provider dto.DTOProvider) (interface{}, error) {
+	if len(req.Data) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "data is required")
+	}
+	if err := json.Unmarshal(req.Data, dtoInstance); err != nil {
+		return nil, statusError(http.StatusBadRequest, err)
+	}
+	model, err := provider.TransformToModel(dtoInstance)
+	if err != nil {
+		return nil, statusError(http.StatusBadRequest, err)
+	}
+	return model, nil
+}
+
+// respondRecord returns the response DTO of a record as {"data": record}
+func respondRecord(res resource.Resource, record interface{}) (*structpb.Struct, error) {
+	data, err := dto.ForResource(res).TransformFromModel(record)
+	if err != nil {
+		return nil, statusError(http.StatusInternalServerError, err)
+	}
+	return respond(map[string]interface{}{"data": data})
+}
+
+// respond converts a response to a Struct through its JSON
+func respond(response interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := new(structpb.Struct)
+	if err := protojson.Unmarshal(data, out); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// statusError converts an error to a gRPC status with the code of the HTTP status the
+// handlers would respond with (see handler.NewErrorResponse). Rejected fields are
+// attached as a BadRequest detail.
+func statusError(httpStatus int, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	response := handler.NewErrorResponse(httpStatus, err)
+	st := status.New(grpcCode(response.StatusCode), response.Message)
+	if len(response.Errors) == 0 {
+		return st.Err()
+	}
+	details := &errdetails.BadRequest{}
+	for field, messages := range response.Errors {
+		for _, message := range messages {
+			details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field,
+				Description: message,
+			})
+		}
+	}
+	if withDetails, detailsErr := st.WithDetails(details); detailsErr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// grpcCode maps an HTTP status to a gRPC code
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if httpStatus >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,168 @@
+package grpcgen
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type GrpcTask struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+func newClient(t *testing.T, config Config) ResourceServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	Register(server, config)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return NewResourceServiceClient(conn)
+}
+
+func payload(t *testing.T, fields map[string]interface{}) *structpb.Struct {
+	s, err := structpb.NewStruct(fields)
+	require.NoError(t, err)
+	return s
+}
+
+func TestResourceService(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&GrpcTask{}))
+
+	registry := resource.NewResourceRegistry()
+	registry.Register(resource.NewResource(resource.ResourceConfig{
+		Name:  "grpc_tasks",
+		Model: GrpcTask{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate,
+			resource.OperationUpdate, resource.OperationDelete,
+		},
+		Hooks: &resource.LifecycleHooks{
+			BeforeCreate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				if data.(*GrpcTask).Title == "" {
+					return resource.NewValidationError("title is required", map[string][]string{"title": {"is required"}})
+				}
+				return nil
+			},
+		},
+	}))
+	registry.Register(resource.NewResource(resource.ResourceConfig{
+		Name:       "grpc_readonly",
+		Model:      GrpcTask{},
+		Operations: []resource.Operation{resource.OperationList},
+	}))
+	client := newClient(t, Config{Registry: registry, DB: db})
+	ctx := context.Background()
+
+	for _, title := range []string{"Write docs", "Ship release"} {
+		out, err := client.Create(ctx, payload(t, map[string]interface{}{
+			"resource": "grpc_tasks",
+			"data":     map[string]interface{}{"title": title},
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, title, out.Fields["data"].GetStructValue().Fields["title"].GetStringValue())
+	}
+
+	out, err := client.Update(ctx, payload(t, map[string]interface{}{
+		"resource": "grpc_tasks",
+		"id":       2,
+		"data":     map[string]interface{}{"title": "Ship release", "done": true},
+	}))
+	require.NoError(t, err)
+	assert.True(t, out.Fields["data"].GetStructValue().Fields["done"].GetBoolValue())
+
+	out, err = client.Get(ctx, payload(t, map[string]interface{}{"resource": "grpc_tasks", "id": "1"}))
+	require.NoError(t, err)
+	assert.Equal(t, "Write docs", out.Fields["data"].GetStructValue().Fields["title"].GetStringValue())
+
+	// Lists take the parameters of POST list queries
+	out, err = client.List(ctx, payload(t, map[string]interface{}{
+		"resource":   "grpc_tasks",
+		"filters":    []interface{}{map[string]interface{}{"field": "done", "operator": "eq", "value": true}},
+		"pagination": map[string]interface{}{"current": 1, "pageSize": 5},
+	}))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, out.Fields["total"].GetNumberValue())
+	records := out.Fields["data"].GetListValue().Values
+	require.Len(t, records, 1)
+	assert.Equal(t, "Ship release", records[0].GetStructValue().Fields["title"].GetStringValue())
+
+	_, err = client.Delete(ctx, payload(t, map[string]interface{}{"resource": "grpc_tasks", "id": 1}))
+	require.NoError(t, err)
+	_, err = client.Get(ctx, payload(t, map[string]interface{}{"resource": "grpc_tasks", "id": 1}))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Rejected fields are attached to the status
+	_, err = client.Create(ctx, payload(t, map[string]interface{}{"resource": "grpc_tasks", "data": map[string]interface{}{}}))
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	violations := st.Details()[0].(*errdetails.BadRequest).FieldViolations
+	require.Len(t, violations, 1)
+	assert.Equal(t, "title", violations[0].Field)
+
+	_, err = client.Delete(ctx, payload(t, map[string]interface{}{"resource": "grpc_readonly", "id": 2}))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = client.List(ctx, payload(t, map[string]interface{}{"resource": "unknown"}))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.Get(ctx, payload(t, map[string]interface{}{"resource": "grpc_tasks"}))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestResourceServiceValidatesQueries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&GrpcTask{}))
+
+	registry := resource.NewResourceRegistry()
+	registry.Register(resource.NewResource(resource.ResourceConfig{
+		Name:            "grpc_tasks",
+		Model:           GrpcTask{},
+		Operations:      []resource.Operation{resource.OperationList},
+		SortableFields:  []string{"title"},
+		QueryValidation: resource.QueryValidationStrict,
+	}))
+	client := newClient(t, Config{Registry: registry, DB: db})
+	ctx := context.Background()
+
+	_, err = client.List(ctx, payload(t, map[string]interface{}{
+		"resource": "grpc_tasks",
+		"sorters":  []interface{}{map[string]interface{}{"field": "done", "order": "desc"}},
+	}))
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	violations := st.Details()[0].(*errdetails.BadRequest).FieldViolations
+	require.Len(t, violations, 1)
+	assert.Equal(t, "done", violations[0].Field)
+
+	_, err = client.List(ctx, payload(t, map[string]interface{}{
+		"resource": "grpc_tasks",
+		"sorters":  []interface{}{map[string]interface{}{"field": "title", "order": "desc"}},
+	}))
+	assert.NoError(t, err)
+}
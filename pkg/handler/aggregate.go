@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// GenerateAggregateHandler generates a handler returning metrics of the records under
+// the current filter set, grouped by filterable fields, e.g.
+// GET /resources/aggregate?groupBy=category&metrics=sum:price,count. Fields must be
+// filterable, visible and readable by the user.
+func GenerateAggregateHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := repo.(repository.AggregateProvider)
+		if !ok {
+			respondErrorMessage(c, http.StatusNotImplemented, "Aggregations are not supported by this repository")
+			return
+		}
+
+		spec, err := repository.ParseAggregateSpec(c.Query("groupBy"), c.Query("metrics"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		fields := append([]string(nil), spec.GroupBy...)
+		for _, metric := range spec.Metrics {
+			if metric.Field != "" {
+				fields = append(fields, metric.Field)
+			}
+		}
+		roles := auth.UserRoles(c)
+		for _, field := range fields {
+			if !aggregatableField(res, field, roles) {
+				respondErrorMessage(c, http.StatusBadRequest, "Field is not filterable: "+field)
+				return
+			}
+		}
+
+		// Create query options (without pagination)
+		options := query.NewQueryOptions(c, res)
+		options.DisablePagination = true
+		if !validateListOptions(c, &options) {
+			return
+		}
+
+		rows, err := provider.Aggregate(c.Request.Context(), options, spec)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": rows})
+	}
+}
+
+// aggregatableField reports whether records may be grouped by a field or its values
+// aggregated: it must be filterable, not hidden and readable by the roles, since
+// metrics like min and max return its values under their own names
+func aggregatableField(res resource.Resource, name string, roles []string) bool {
+	if !isFilterableField(res, name) {
+		return false
+	}
+	field := res.GetField(name)
+	return field != nil && !field.Hidden && auth.CanAccessField(*field, "read", roles) &&
+		!unserializedField(reflect.TypeOf(res.GetModel()), name)
+}
+
+// unserializedField reports whether the model field of the name (Go or JSON name)
+// is left out of JSON with json:"-"
+func unserializedField(t reflect.Type, name string) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	field, ok := t.FieldByNameFunc(func(fieldName string) bool { return strings.EqualFold(fieldName, name) })
+	if !ok {
+		return false
+	}
+	return strings.Split(field.Tag.Get("json"), ",")[0] == "-"
+}
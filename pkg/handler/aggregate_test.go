@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AggregateOrder struct {
+	ID       uint    `json:"id" gorm:"primaryKey"`
+	Category string  `json:"category"`
+	Status   string  `json:"status"`
+	Price    float64 `json:"price"`
+	Margin   float64 `json:"-"`
+}
+
+func TestGenerateAggregateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&AggregateOrder{}))
+	require.NoError(t, db.Create(&[]AggregateOrder{
+		{Category: "books", Status: "paid", Price: 10},
+		{Category: "books", Status: "open", Price: 30},
+		{Category: "games", Status: "paid", Price: 50},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:             "orders",
+		Model:            AggregateOrder{},
+		FilterableFields: []string{"category", "status", "price"},
+		Operations:       []resource.Operation{resource.OperationList, resource.OperationAggregate},
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group(""), res, repository.NewGenericRepository(db, res))
+
+	request := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("groups metrics", func(t *testing.T) {
+		w := request("/orders/aggregate?groupBy=category&metrics=sum:price,avg:price,max:price,count")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 2)
+		assert.Equal(t, map[string]interface{}{"category": "books", "sum_price": float64(40), "avg_price": float64(20), "max_price": float64(30), "count": float64(2)}, response.Data[0])
+		assert.Equal(t, "games", response.Data[1]["category"])
+	})
+
+	t.Run("applies current filters", func(t *testing.T) {
+		w := request("/orders/aggregate?metrics=sum:price,count&status=paid")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"data":[{"sum_price":60,"count":2}]}`, w.Body.String())
+	})
+
+	t.Run("invalid specs", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request("/orders/aggregate").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/orders/aggregate?metrics=median:price").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/orders/aggregate?metrics=sum").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/orders/aggregate?metrics=sum:cost").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/orders/aggregate?groupBy=id&metrics=count").Code)
+	})
+
+	t.Run("unreadable fields", func(t *testing.T) {
+		restricted := resource.NewResource(resource.ResourceConfig{
+			Name:  "restricted_orders",
+			Model: AggregateOrder{},
+			Fields: []resource.Field{
+				{Name: "category", Type: "string", Hidden: true},
+				{Name: "status", Type: "string"},
+				{Name: "price", Type: "float64", Permissions: map[string][]string{"read": {"admin"}}},
+				{Name: "margin", Type: "float64"},
+			},
+			FilterableFields: []string{"category", "status", "price", "margin"},
+			Operations:       []resource.Operation{resource.OperationList, resource.OperationAggregate},
+		})
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if role := c.GetHeader("X-Role"); role != "" {
+				requestctx.Roles.Set(c, []string{role})
+			}
+		})
+		r.GET("/aggregate", GenerateAggregateHandler(restricted, repository.NewGenericRepository(db, restricted)))
+		request := func(url, role string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			req.Header.Set("X-Role", role)
+			r.ServeHTTP(w, req)
+			return w
+		}
+
+		assert.Equal(t, http.StatusOK, request("/aggregate?groupBy=status&metrics=count", "").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/aggregate?groupBy=category&metrics=count", "").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/aggregate?metrics=max:category", "").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/aggregate?metrics=max:margin", "").Code)
+		assert.Equal(t, http.StatusBadRequest, request("/aggregate?metrics=max:price", "user").Code)
+
+		w := request("/aggregate?metrics=max:price", "admin")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.JSONEq(t, `{"data":[{"max_price":50}]}`, w.Body.String())
+	})
+
+	t.Run("repository without aggregation support", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/orders/aggregate", GenerateAggregateHandler(res, new(MockRepository)))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/orders/aggregate?metrics=count", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+var (
+	// aliasFilterParamPattern matches filter[field][operator], filters[field] and operators[field]
+	// capturing the field name
+	aliasFilterParamPattern = regexp.MustCompile(`^(filter\[|filters\[|operators\[)([^\]]+)(\].*)$`)
+
+	// aliasSortParamPattern matches the query parameters whose values are field names
+	aliasSortParamPattern = regexp.MustCompile(`^(?:sort|sort\[field\]|sort\[\d+\]\[field\]|fields|columns)$`)
+)
+
+// FieldAliasMiddleware exposes fields under their aliases (Field.Alias). Aliases are
+// mapped to field names in filters, sorts, sparse fieldsets and JSON bodies, and field
+// names are mapped back to aliases in the records of JSON responses. Resources without
+// aliases are passed through untouched.
+//
+// The fields of value objects may also be sent in JSON bodies under their nested names
+// ("address.city"), which are moved into the value object ({"address": {"city": ...}})
+// the model binds.
+func FieldAliasMiddleware(res resource.Resource) gin.HandlerFunc {
+	aliases := resource.FieldAliases(res.GetFields())
+	valueObjects := resource.ValueObjectParents(res.GetFields())
+
+	// Requests may use any naming convention, so names are matched normalized
+	toField := make(map[string]string, len(aliases))
+	toAlias := make(map[string]string, len(aliases))
+	for name, alias := range aliases {
+		toField[normalizeBodyKey(alias)] = name
+		toAlias[normalizeBodyKey(name)] = alias
+	}
+
+	return func(c *gin.Context) {
+		if len(aliases) == 0 && len(valueObjects) == 0 {
+			c.Next()
+			return
+		}
+
+		rewriteAliasedQuery(c.Request, toField)
+		err := rewriteBodyRecords(c, func(records interface{}) bool {
+			renamed := renameRecordKeys(records, toField)
+			nested := nestValueObjectKeys(records, valueObjects)
+			return renamed || nested
+		})
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		if len(aliases) == 0 {
+			c.Next()
+			return
+		}
+
+		w := &recordWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(func(records interface{}) bool {
+			return renameRecordKeys(records, toAlias)
+		})
+	}
+}
+
+// rewriteAliasedQuery replaces aliases with field names in query parameter names and
+// in the values of sort and fields parameters
+func rewriteAliasedQuery(req *http.Request, toField map[string]string) {
+	values := req.URL.Query()
+	if len(values) == 0 {
+		return
+	}
+
+	rewritten := make(url.Values, len(values))
+	for key, vals := range values {
+		if name, ok := toField[normalizeBodyKey(key)]; ok {
+			key = name
+		} else if match := aliasFilterParamPattern.FindStringSubmatch(key); match != nil {
+			if name, ok := toField[normalizeBodyKey(match[2])]; ok {
+				key = match[1] + name + match[3]
+			}
+		}
+
+		if aliasSortParamPattern.MatchString(key) {
+			mapped := make([]string, len(vals))
+			for i, value := range vals {
+				mapped[i] = aliasedFieldList(value, toField)
+			}
+			vals = mapped
+		}
+		rewritten[key] = append(rewritten[key], vals...)
+	}
+
+	req.URL.RawQuery = rewritten.Encode()
+}
+
+// aliasedFieldList maps a comma-separated list of field names, keeping "-" prefixes
+// used for descending sorts
+func aliasedFieldList(value string, toField map[string]string) string {
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		prefix := ""
+		if strings.HasPrefix(trimmed, "-") {
+			prefix, trimmed = "-", trimmed[1:]
+		}
+		if name, ok := toField[normalizeBodyKey(trimmed)]; ok {
+			parts[i] = prefix + name
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// rewriteBodyRecords rewrites the records of a JSON body in place with rewrite, which
+// reports whether it changed anything. Batch envelopes ({"ids": ..., "values": ...})
+// are rewritten by their values.
+func rewriteBodyRecords(c *gin.Context, rewrite func(records interface{}) bool) error {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") || isJSONPatchBody(c) {
+		return nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	payload, err := decodeJSON(body)
+	if err != nil {
+		// Malformed bodies are reported by the handler
+		return nil
+	}
+	records := payload
+	if envelope, ok := payload.(map[string]interface{}); ok && isBatchEnvelope(envelope) {
+		records = envelope["values"]
+	}
+	if !rewrite(records) {
+		return nil
+	}
+
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Request.ContentLength = int64(len(rewritten))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+	return nil
+}
+
+// renameRecordKeys renames the keys of a record or a list of records in place,
+// reporting whether any key was renamed
+func renameRecordKeys(records interface{}, names map[string]string) bool {
+	switch value := records.(type) {
+	case map[string]interface{}:
+		renamed := false
+		for key, v := range value {
+			name, ok := names[normalizeBodyKey(key)]
+			if !ok || name == key {
+				continue
+			}
+			delete(value, key)
+			value[name] = v
+			renamed = true
+		}
+		return renamed
+	case []interface{}:
+		renamed := false
+		for _, item := range value {
+			if renameRecordKeys(item, names) {
+				renamed = true
+			}
+		}
+		return renamed
+	}
+	return false
+}
+
+// nestValueObjectKeys moves the "parent.key" keys of a record or a list of records
+// into the value objects under parent, reporting whether any key was moved
+func nestValueObjectKeys(records interface{}, valueObjects map[string][]string) bool {
+	switch value := records.(type) {
+	case map[string]interface{}:
+		nested := false
+		for parent, keys := range valueObjects {
+			for _, key := range keys {
+				v, ok := value[parent+"."+key]
+				if !ok {
+					continue
+				}
+				object, ok := value[parent].(map[string]interface{})
+				if !ok {
+					object = make(map[string]interface{})
+					value[parent] = object
+				}
+				object[key] = v
+				delete(value, parent+"."+key)
+				nested = true
+			}
+		}
+		return nested
+	case []interface{}:
+		nested := false
+		for _, item := range value {
+			if nestValueObjectKeys(item, valueObjects) {
+				nested = true
+			}
+		}
+		return nested
+	}
+	return false
+}
+
+// decodeJSON decodes a JSON document keeping numbers exact
+func decodeJSON(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// recordWriter buffers JSON responses so the records under "data" can be rewritten
+type recordWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON bodies and passes everything else through
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString buffers JSON bodies and passes everything else through
+func (w *recordWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flush writes the buffered body with the records under "data" rewritten by rewrite,
+// which reports whether it changed anything
+func (w *recordWriter) flush(rewrite func(records interface{}) bool) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	if payload, err := decodeJSON(body); err == nil {
+		if envelope, ok := payload.(map[string]interface{}); ok && rewrite(envelope["data"]) {
+			if rewritten, err := json.Marshal(envelope); err == nil {
+				body = rewritten
+			}
+		}
+	}
+
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.Write(body)
+}
+
+// aliasRecord returns a copy of a record with aliased field names replaced by their aliases
+func aliasRecord(record map[string]interface{}, fields []resource.Field) map[string]interface{} {
+	aliases := resource.FieldAliases(fields)
+	if record == nil || len(aliases) == 0 {
+		return record
+	}
+	result := make(map[string]interface{}, len(record))
+	for key, value := range record {
+		if alias, ok := aliases[key]; ok {
+			key = alias
+		}
+		result[key] = value
+	}
+	return result
+}
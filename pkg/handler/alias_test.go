@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type AliasedOrder struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	LegacyRef string `json:"legacy_ref"`
+	Total     int    `json:"total_amount"`
+}
+
+func TestFieldAliasMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Keep the resource out of the global registry used by other tests
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&AliasedOrder{}))
+	require.NoError(t, db.Create(&[]AliasedOrder{{LegacyRef: "A-1", Total: 10}, {LegacyRef: "B-2", Total: 20}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "aliased_orders",
+		Model: AliasedOrder{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "legacy_ref", Alias: "externalId", Type: "string"},
+			{Name: "total_amount", Type: "int"},
+		},
+		FilterableFields: []string{"legacy_ref", "total_amount"},
+		SortableFields:   []string{"legacy_ref"},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate,
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/aliased_orders?sort=externalId&order=desc", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "B-2", list.Data[0]["externalId"])
+	assert.NotContains(t, list.Data[0], "legacy_ref")
+	assert.Equal(t, float64(20), list.Data[0]["total_amount"])
+
+	w = request(http.MethodGet, "/api/aliased_orders?filter[externalId][eq]=A-1", "")
+	assert.Contains(t, w.Body.String(), `"total":1`)
+	assert.Contains(t, w.Body.String(), `"externalId":"A-1"`)
+	assert.Contains(t, request(http.MethodGet, "/api/aliased_orders?externalId=B-2", "").Body.String(), `"externalId":"B-2"`)
+
+	w = request(http.MethodPost, "/api/aliased_orders", `{"externalId":"C-3","total_amount":30}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"externalId":"C-3"`)
+	var stored AliasedOrder
+	require.NoError(t, db.Where("legacy_ref = ?", "C-3").First(&stored).Error)
+	assert.Equal(t, 30, stored.Total)
+
+	assert.Contains(t, request(http.MethodGet, "/api/aliased_orders/1", "").Body.String(), `"externalId":"A-1"`)
+
+	// Metadata exposes the alias only
+	w = request(http.MethodOptions, "/api/aliased_orders", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"externalId"`)
+	assert.Contains(t, w.Body.String(), `"filterable":["externalId","total_amount"]`)
+	assert.NotContains(t, w.Body.String(), "legacy_ref")
+}
+
+func TestFieldAliasMiddlewareRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:   "aliased_orders",
+		Model:  AliasedOrder{},
+		Fields: []resource.Field{{Name: "legacy_ref", Alias: "externalId", Type: "string"}},
+	})
+
+	r := gin.New()
+	r.PUT("/aliased_orders/batch", FieldAliasMiddleware(res), func(c *gin.Context) {
+		var body map[string]interface{}
+		require.NoError(t, c.ShouldBindJSON(&body))
+		c.JSON(http.StatusOK, gin.H{"query": c.Request.URL.Query(), "body": body})
+	})
+
+	req := httptest.NewRequest(http.MethodPut,
+		"/aliased_orders/batch?sort=-external_id,id&filters[externalId]=a&operators[externalId]=eq&fields=id,externalId",
+		strings.NewReader(`{"ids":[1,2],"values":{"externalId":"x"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Query map[string][]string    `json:"query"`
+		Body  map[string]interface{} `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"-legacy_ref,id"}, response.Query["sort"])
+	assert.Equal(t, []string{"a"}, response.Query["filters[legacy_ref]"])
+	assert.Equal(t, []string{"eq"}, response.Query["operators[legacy_ref]"])
+	assert.Equal(t, []string{"id,legacy_ref"}, response.Query["fields"])
+	assert.Equal(t, map[string]interface{}{"legacy_ref": "x"}, response.Body["values"])
+}
+
+func TestFieldAliasMiddlewareWithoutAliases(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:   "plain_orders",
+		Model:  AliasedOrder{},
+		Fields: []resource.Field{{Name: "legacy_ref", Type: "string"}},
+	})
+
+	r := gin.New()
+	r.GET("/plain_orders", FieldAliasMiddleware(res), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.URL.RawQuery)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain_orders?externalId=1&sort=b", nil))
+	assert.Equal(t, "externalId=1&sort=b", w.Body.String())
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"github.com/suranig/refine-gin/pkg/utils"
 )
@@ -37,6 +38,7 @@ func GenerateAPIConfigHandler() gin.HandlerFunc {
 		for _, res := range allResources {
 			// Generate metadata for this resource
 			metadata := resource.GenerateResourceMetadata(res)
+			metadata.QueryCapabilities = query.Capabilities(res)
 			resources[res.GetName()] = metadata
 		}
 
@@ -44,7 +46,8 @@ func GenerateAPIConfigHandler() gin.HandlerFunc {
 		response := APIConfigResponse{
 			Resources: resources,
 			Config: map[string]interface{}{
-				"version": "1.0.0", // Add library version
+				"version":      "1.0.0", // Add library version
+				"filterMacros": query.GetFilterMacrosMetadata(),
 			},
 		}
 
@@ -71,6 +71,9 @@ func TestGenerateAPIConfigHandler(t *testing.T) {
 	assert.Contains(t, resourceNames, "resource1")
 	assert.Contains(t, resourceNames, "resource2")
 
+	// Verify query capabilities are described
+	assert.NotNil(t, response.Resources["resource1"].QueryCapabilities)
+
 	// Verify ETag header is set
 	assert.NotEmpty(t, w.Header().Get("ETag"))
 
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/cache"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type CachedNote struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Text string `json:"text"`
+}
+
+func TestCachedRepositoryETags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CachedNote{}))
+	require.NoError(t, db.Create(&CachedNote{Text: "a"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "notes",
+		Model:      CachedNote{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationUpdate},
+	})
+	repo := repository.WithCache(repository.NewGenericRepositoryWithResource(db, res), cache.NewMemory(0), time.Minute)
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repo)
+
+	request := func(method, path, body, etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/api/notes/1", "/api/notes?page=1"} {
+		w := request(http.MethodGet, path, "", "")
+		require.Equal(t, http.StatusOK, w.Code, path)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		assert.Equal(t, http.StatusNotModified, request(http.MethodGet, path, "", etag).Code, path)
+
+		w = request(http.MethodPut, "/api/notes/1", `{"text":"`+path+`"}`, "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		// The update changed the cache version and the ETag with it
+		w = request(http.MethodGet, path, "", etag)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), path)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/repository"
+)
+
+// CircuitBreakerMiddleware responds with a fast 503 while the circuit breaker is open,
+// so requests do not pile up waiting for an unavailable database
+func CircuitBreakerMiddleware(breaker *repository.CircuitBreaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if breaker.State() == repository.CircuitOpen {
+			retryAfter := int(math.Ceil(breaker.RetryAfter().Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			abortWithError(c, http.StatusServiceUnavailable, repository.ErrCircuitOpen)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/repository"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	breaker := repository.NewCircuitBreaker(repository.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+
+	r := gin.New()
+	r.Use(CircuitBreakerMiddleware(breaker))
+	r.GET("/tasks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": []string{}})
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+
+	breaker.Allow()
+	breaker.Record(driver.ErrBadConn)
+
+	w := request()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "circuit breaker is open")
+}
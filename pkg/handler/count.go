@@ -9,17 +9,45 @@ import (
 	"github.com/suranig/refine-gin/pkg/resource"
 )
 
-// GenerateCountHandler generates a handler for COUNT operations
+// CountRequest is the body of POST count requests, for filter sets too long for a URL
+type CountRequest struct {
+	// Filters is a filter tree in the format of refine's CrudFilters, with "and" and
+	// "or" groups. Its filters must all match, along with the filters of the URL.
+	Filters []query.FilterNode `json:"filters"`
+
+	// Search replaces the q parameter
+	Search string `json:"q,omitempty"`
+}
+
+// GenerateCountHandler generates a handler for COUNT operations. Filters are parsed
+// like those of lists, and the BeforeList hook runs, so a count matches the total of
+// the same list. POST requests add the filter tree of a CountRequest body.
 func GenerateCountHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Create query options (without pagination)
-		options := query.NewQueryOptions(c, res)
+		options := query.ParseQueryOptions(c, res)
 		options.DisablePagination = true
 
+		if c.Request.Method == http.MethodPost {
+			var req CountRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, err)
+				return
+			}
+			options.FilterTree = append(options.FilterTree, req.Filters...)
+			if req.Search != "" {
+				options.Search = req.Search
+			}
+		}
+
+		if !prepareListOptions(c, res, &options) {
+			return
+		}
+
 		// Call repository count method
 		count, err := repo.Count(c.Request.Context(), options)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -29,3 +57,10 @@ func GenerateCountHandler(res resource.Resource, repo repository.Repository) gin
 		})
 	}
 }
+
+// registerCountRoutes registers the count endpoint under the resource router, for GET
+// requests and POST requests with filters in the body
+func registerCountRoutes(router routeRegistrar, path string, handler gin.HandlerFunc) {
+	router.GET(path, handler)
+	router.POST(path, handler)
+}
@@ -6,13 +6,18 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestGenerateCountHandler(t *testing.T) {
@@ -321,3 +326,70 @@ func TestCountWithConstraints(t *testing.T) {
 func (m *MockResource) GetFormLayout() *resource.FormLayout {
 	return nil
 }
+
+type CountedTicket struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Status   string `json:"status"`
+	Priority int    `json:"priority"`
+	Archived bool   `json:"archived"`
+}
+
+func TestCountHandler_FilterTree(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CountedTicket{}))
+	require.NoError(t, db.Create([]*CountedTicket{
+		{Status: "open", Priority: 1},
+		{Status: "open", Priority: 5},
+		{Status: "closed", Priority: 5},
+		{Status: "open", Priority: 5, Archived: true},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "tickets",
+		Model:      CountedTicket{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationCount},
+		Hooks: &resource.LifecycleHooks{
+			// Lists and counts leave archived tickets out
+			BeforeList: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				options := data.(*query.QueryOptions)
+				options.AdvancedFilters = append(options.AdvancedFilters, query.Filter{Field: "archived", Operator: "eq", Value: false})
+				return nil
+			},
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	count := func(method, target, body string) (int, float64) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		count, _ := response["count"].(float64)
+		return w.Code, count
+	}
+
+	code, n := count(http.MethodGet, "/api/tickets/count?filter[status]=open", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), n)
+
+	code, n = count(http.MethodPost, "/api/tickets/count", `{"filters": [
+		{"operator": "or", "value": [
+			{"field": "status", "operator": "eq", "value": "closed"},
+			{"field": "priority", "operator": "lt", "value": 2}
+		]}
+	]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), n)
+
+	// Filters of the URL apply too
+	_, n = count(http.MethodPost, "/api/tickets/count?filter[status]=open", `{"filters": [{"field": "priority", "operator": "gte", "value": 5}]}`)
+	assert.Equal(t, float64(1), n)
+
+	code, _ = count(http.MethodPost, "/api/tickets/count", `{"filters": [{"operator": "eq"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
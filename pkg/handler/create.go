@@ -17,14 +17,14 @@ func GenerateCreateHandler(res resource.Resource, repo repository.Repository, dt
 
 		// Parse request data into DTO
 		if err := c.ShouldBindJSON(dtoInstance); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Transform DTO to model
 		model, err := dtoProvider.TransformToModel(dtoInstance)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -35,22 +35,31 @@ func GenerateCreateHandler(res resource.Resource, repo repository.Repository, dt
 		if db != nil && len(res.GetRelations()) > 0 {
 			// Validate relations
 			if err := resource.ValidateRelations(db, model); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 		}
 
+		hooks := lifecycleHooks(res)
+		if !runBeforeHook(c, res, hooks.BeforeCreate, model) {
+			return
+		}
+
 		// Call repository
 		createdModel, err := repo.Create(c.Request.Context(), model)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterCreate, createdModel) {
 			return
 		}
 
 		// Transform model to response DTO
 		responseDTO, err := dtoProvider.TransformFromModel(createdModel)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
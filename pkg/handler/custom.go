@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/jobs"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"github.com/suranig/refine-gin/pkg/utils"
@@ -29,6 +33,20 @@ type CustomAction struct {
 
 	// Whether the action is for bulk operations
 	IsBulk bool
+
+	// Summary of the action in the OpenAPI documentation (optional)
+	Summary string
+
+	// Request and Response are example values of the request body and of the data
+	// returned by the action; their types describe the action in the OpenAPI
+	// documentation (optional)
+	Request  interface{}
+	Response interface{}
+
+	// Jobs runs the action in the background when set: the request gets 202 Accepted
+	// with the job and its status URL, and the result of the handler becomes the
+	// result of the job. Handlers report progress with jobs.ProgressFromContext.
+	Jobs *jobs.Manager
 }
 
 // CustomActionResponse is the standard response for custom actions
@@ -39,12 +57,15 @@ type CustomActionResponse struct {
 // GenerateCustomActionHandler creates a handler for a custom action
 func GenerateCustomActionHandler(res resource.Resource, repo repository.Repository, action CustomAction) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if action.Jobs != nil {
+			startActionJob(c, res, repo, action)
+			return
+		}
+
 		// Execute the custom action
 		result, err := action.Handler(c, res, repo)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			apierror.Respond(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -60,6 +81,27 @@ func GenerateCustomActionHandler(res resource.Resource, repo repository.Reposito
 	}
 }
 
+// startActionJob runs a custom action as a job. The handler gets a copy of the
+// context with the request body buffered, as the request is over before it runs.
+func startActionJob(c *gin.Context, res resource.Resource, repo repository.Repository, action CustomAction) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	copied := c.Copy()
+	request := c.Request
+	jobs.Accepted(c, action.Jobs, res.GetName()+"/"+action.Name, func(ctx context.Context, progress *jobs.Progress) (interface{}, error) {
+		copied.Request = request.Clone(jobs.WithProgress(ctx, progress))
+		copied.Request.Body = io.NopCloser(bytes.NewReader(body))
+		return action.Handler(copied, res, repo)
+	})
+}
+
 // RegisterCustomActions registers custom actions for a resource
 func RegisterCustomActions(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, actions []CustomAction) {
 	for _, action := range actions {
@@ -325,6 +325,8 @@ func TestRegisterResourceForRefineWithRelations(t *testing.T) {
 	mockResource.On("GetFilters").Return([]resource.Filter{})
 	mockResource.On("GetMiddlewares").Return([]interface{}{})
 	mockResource.On("GetSearchable").Return([]string{})
+	mockResource.On("GetFilterableFields").Return([]string{})
+	mockResource.On("GetPermissions").Return(map[string][]string(nil))
 
 	// Match any operation name with this catch-all mock
 	mockResource.On("HasOperation", mock.AnythingOfType("resource.Operation")).Return(true)
@@ -467,10 +469,11 @@ func TestGenerateCustomActionHandler(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 
-		var resp map[string]string
+		var resp map[string]interface{}
 		err := json.Unmarshal(w.Body.Bytes(), &resp)
 		require.NoError(t, err)
 		assert.Equal(t, "boom", resp["error"])
+		assert.Equal(t, "internal_error", resp["code"])
 	})
 }
 
@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/jobs"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
 )
@@ -59,3 +64,52 @@ func TestRegisterCustomActions(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomActionAsJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	manager := jobs.NewManager(jobs.Config{Path: "/api/jobs"})
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "reports", Model: struct{ ID uint }{}})
+	action := CustomAction{
+		Name:   "rebuild",
+		Method: http.MethodPost,
+		Jobs:   manager,
+		Handler: func(c *gin.Context, r resource.Resource, repo repository.Repository) (interface{}, error) {
+			var body struct {
+				Year int `json:"year"`
+			}
+			if err := c.ShouldBindJSON(&body); err != nil {
+				return nil, err
+			}
+			progress := jobs.ProgressFromContext(c.Request.Context())
+			progress.SetTotal(12)
+			progress.Add(12)
+			return gin.H{"year": body.Year}, nil
+		},
+	}
+	RegisterCustomActions(router.Group("/api"), res, nil, []CustomAction{action})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/reports/actions/rebuild", strings.NewReader(`{"year":2024}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var response struct {
+		Data jobs.Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "/api/jobs/"+response.Data.ID, w.Header().Get("Location"))
+	assert.Equal(t, "reports/rebuild", response.Data.Name)
+
+	var job jobs.Job
+	require.Eventually(t, func() bool {
+		job, _ = manager.Job(response.Data.ID)
+		return job.Status.Finished()
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, jobs.StatusSucceeded, job.Status, job.Error)
+	assert.Equal(t, gin.H{"year": 2024}, job.Result)
+	assert.Equal(t, int64(12), job.Done)
+}
@@ -14,10 +14,20 @@ func GenerateDeleteHandler(res resource.Resource, repo repository.Repository) gi
 		// Get ID from URL parameters
 		id := c.Param("id")
 
+		hooks := lifecycleHooks(res)
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, hooks.BeforeDelete, id) {
+			return
+		}
+
 		// Call repository
 		err := repo.Delete(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterDelete, id) {
 			return
 		}
 
@@ -32,10 +42,20 @@ func GenerateDeleteHandlerWithParam(res resource.Resource, repo repository.Repos
 		// Get ID from URL parameters using custom parameter name
 		id := c.Param(idParamName)
 
+		hooks := lifecycleHooks(res)
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, hooks.BeforeDelete, id) {
+			return
+		}
+
 		// Call repository
 		err := repo.Delete(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterDelete, id) {
 			return
 		}
 
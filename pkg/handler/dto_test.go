@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type DTOMember struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Name         string `json:"name" binding:"required"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+func TestResourceDTOs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DTOMember{}))
+	require.NoError(t, db.Create(&DTOMember{Name: "Ada", Email: "ada@example.com", Role: "admin", PasswordHash: "hash"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "members",
+		Model: DTOMember{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int", ReadOnly: true},
+			{Name: "name", Type: "string"},
+			{Name: "email", Type: "string"},
+			{Name: "role", Type: "string", ReadOnly: true},
+			{Name: "passwordHash", Type: "string", Hidden: true},
+		},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationCreate, resource.OperationUpdate},
+		AutoDTO:    true,
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepository(db, res))
+
+	send := func(method, path, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// Creates ignore read-only and hidden fields, responses leave out hidden fields
+	status, response := send(http.MethodPost, "/api/members", `{"name":"Grace","role":"admin","passwordHash":"x"}`)
+	require.Equal(t, http.StatusCreated, status)
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, "Grace", data["name"])
+	assert.Equal(t, "", data["role"])
+	assert.NotContains(t, data, "passwordHash")
+
+	status, _ = send(http.MethodPost, "/api/members", `{"email":"nobody@example.com"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Updates only change the fields present in the request
+	status, response = send(http.MethodPut, "/api/members/1", `{"email":"ada@example.org"}`)
+	require.Equal(t, http.StatusOK, status)
+	data = response["data"].(map[string]interface{})
+	assert.Equal(t, "Ada", data["name"])
+	assert.Equal(t, "ada@example.org", data["email"])
+	assert.Equal(t, "admin", data["role"])
+
+	var stored DTOMember
+	require.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "hash", stored.PasswordHash)
+
+	status, response = send(http.MethodGet, "/api/members", "")
+	require.Equal(t, http.StatusOK, status)
+	for _, item := range response["data"].([]interface{}) {
+		assert.NotContains(t, item, "passwordHash")
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/naming"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// Codes of error responses, stable identifiers clients can branch on
+const (
+	ErrorCodeBadRequest      = apierror.CodeBadRequest
+	ErrorCodeValidation      = apierror.CodeValidation
+	ErrorCodeInvalidJSON     = apierror.CodeInvalidJSON
+	ErrorCodeUnauthorized    = apierror.CodeUnauthorized
+	ErrorCodeForbidden       = apierror.CodeForbidden
+	ErrorCodeNotFound        = apierror.CodeNotFound
+	ErrorCodeNotSupported    = apierror.CodeNotSupported
+	ErrorCodeConflict        = apierror.CodeConflict
+	ErrorCodeUnprocessable   = apierror.CodeUnprocessable
+	ErrorCodeUnavailable     = apierror.CodeUnavailable
+	ErrorCodeInternal        = apierror.CodeInternal
+	ErrorCodeUnsupportedMIME = apierror.CodeUnsupportedMIME
+)
+
+// ErrorResponse is the body of the error responses of the handlers (see
+// apierror.Response). apierror.Translate customizes them before they are written.
+type ErrorResponse = apierror.Response
+
+// ValidationErrorResponse is the body of rejected values, an ErrorResponse with Errors
+type ValidationErrorResponse = ErrorResponse
+
+// NewErrorResponse builds the response of an error. Known errors set their own status:
+// gorm.ErrRecordNotFound 404, repository.ErrOwnerMismatch 403, a missing owner 401,
+// version conflicts 409, repository.ErrNotSupported 405, an open circuit breaker 503
+// and *resource.HookError its status. Binding and hook validation failures and filters
+// rejected by custom operators (*query.FilterError) and sorts and filters on fields
+// that are not sortable or filterable (*query.FieldError) list the messages of their
+// fields in Errors, keyed by JSON name; malformed JSON is a 400 Bad Request. Other
+// errors get status with their message.
+func NewErrorResponse(status int, err error) *ErrorResponse {
+	response := &ErrorResponse{StatusCode: status, Message: err.Error()}
+
+	var hookErr *resource.HookError
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var filterErr *query.FilterError
+	var fieldErr *query.FieldError
+	switch {
+	case errors.As(err, &hookErr):
+		response.StatusCode = hookErr.StatusCode()
+		if len(hookErr.Fields) > 0 {
+			response.Code = ErrorCodeValidation
+			response.Errors = hookErr.Fields
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.StatusCode = http.StatusNotFound
+		response.Message = "Resource not found"
+	case errors.Is(err, repository.ErrOwnerMismatch):
+		response.StatusCode = http.StatusForbidden
+		response.Message = "You don't have permission to access this resource"
+	case errors.Is(err, repository.ErrOwnerIDNotFound), errors.Is(err, middleware.ErrOwnerIDNotFound):
+		response.StatusCode = http.StatusUnauthorized
+	case errors.Is(err, repository.ErrVersionConflict):
+		response.StatusCode = http.StatusConflict
+	case errors.Is(err, repository.ErrNotSupported):
+		response.StatusCode = http.StatusMethodNotAllowed
+		response.Code = ErrorCodeNotSupported
+	case errors.Is(err, repository.ErrCircuitOpen):
+		response.StatusCode = http.StatusServiceUnavailable
+	case errors.As(err, &validationErrs):
+		response.StatusCode = clientErrorStatus(status)
+		response.Code = ErrorCodeValidation
+		response.Errors = make(map[string][]string, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			name := fieldErrorKey(fieldErr.Field())
+			response.Errors[name] = append(response.Errors[name], validationMessage(fieldErr))
+		}
+		response.Message = fieldErrorKey(validationErrs[0].Field()) + " " + validationMessage(validationErrs[0])
+	case errors.As(err, &filterErr):
+		response.StatusCode = http.StatusBadRequest
+		response.Code = ErrorCodeValidation
+		response.Message = filterErr.Error()
+		response.Errors = map[string][]string{filterErr.Field: {filterErr.Err.Error()}}
+	case errors.As(err, &fieldErr):
+		response.StatusCode = http.StatusBadRequest
+		response.Code = ErrorCodeValidation
+		response.Errors = make(map[string][]string, len(fieldErr.Fields))
+		for _, field := range fieldErr.Fields {
+			message := field.Reason
+			if field.Suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", field.Suggestion)
+			}
+			response.Errors[field.Name] = append(response.Errors[field.Name], message)
+		}
+	case errors.As(err, &typeErr):
+		response.StatusCode = clientErrorStatus(status)
+		response.Code = ErrorCodeValidation
+		if typeErr.Field != "" {
+			response.Errors = map[string][]string{
+				typeErr.Field: {typeMessage(typeErr.Type)},
+			}
+		}
+	case errors.As(err, &syntaxErr):
+		response.StatusCode = clientErrorStatus(status)
+		response.Code = ErrorCodeInvalidJSON
+	}
+
+	if response.Code == "" {
+		response.Code = apierror.CodeFor(response.StatusCode)
+	}
+	response.Error = response.Message
+	return response
+}
+
+// respondError writes the error response of err (see NewErrorResponse), status being
+// the status of errors that don't set their own
+func respondError(c *gin.Context, status int, err error) {
+	apierror.Write(c, NewErrorResponse(status, err), err)
+}
+
+// respondErrorMessage writes an error response with a message
+func respondErrorMessage(c *gin.Context, status int, message string) {
+	apierror.RespondMessage(c, status, message)
+}
+
+// abortWithError writes the error response of err and stops the handler chain
+func abortWithError(c *gin.Context, status int, err error) {
+	c.Abort()
+	respondError(c, status, err)
+}
+
+// clientErrorStatus returns status if it is a client error, 400 Bad Request otherwise
+func clientErrorStatus(status int) int {
+	if status >= 400 && status < 500 {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// fieldErrorKey returns the JSON name of a struct field (e.g. ownerId for OwnerID),
+// the name refine forms know the field by
+func fieldErrorKey(name string) string {
+	return naming.ToCamelCase(naming.ToSnakeCase(name))
+}
+
+// typeMessage returns the message of a JSON value of the wrong type
+func typeMessage(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "must be a string"
+	case reflect.Bool:
+		return "must be a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "must be an integer"
+	case reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.Slice, reflect.Array:
+		return "must be an array"
+	case reflect.Map, reflect.Struct:
+		return "must be an object"
+	}
+	return "has an invalid type"
+}
+
+// validationMessage returns the message of a failed validation rule
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "min", "gte":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max", "lte":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fieldErr.Param())
+	case "len":
+		return fmt.Sprintf("must have a length of %s", fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	}
+	return fmt.Sprintf("failed the %q rule", fieldErr.Tag())
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type signupRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	Name    string `json:"name" binding:"required,min=2"`
+	OwnerID int    `json:"ownerId" binding:"gte=1"`
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		err     error
+		want    int
+		code    string
+		message string
+	}{
+		{"plain", http.StatusInternalServerError, errors.New("boom"), 500, ErrorCodeInternal, "boom"},
+		{"not found", http.StatusInternalServerError, fmt.Errorf("load: %w", gorm.ErrRecordNotFound), 404, ErrorCodeNotFound, "Resource not found"},
+		{"owner mismatch", http.StatusInternalServerError, repository.ErrOwnerMismatch, 403, ErrorCodeForbidden, "You don't have permission to access this resource"},
+		{"missing owner", http.StatusInternalServerError, repository.ErrOwnerIDNotFound, 401, ErrorCodeUnauthorized, repository.ErrOwnerIDNotFound.Error()},
+		{"version conflict", http.StatusInternalServerError, repository.ErrVersionConflict, 409, ErrorCodeConflict, repository.ErrVersionConflict.Error()},
+		{"not supported", http.StatusInternalServerError, repository.ErrNotSupported, 405, ErrorCodeNotSupported, repository.ErrNotSupported.Error()},
+		{"circuit open", http.StatusInternalServerError, repository.ErrCircuitOpen, 503, ErrorCodeUnavailable, repository.ErrCircuitOpen.Error()},
+		{"hook", http.StatusUnprocessableEntity, resource.NewHookError(http.StatusPaymentRequired, "upgrade"), 402, ErrorCodeBadRequest, "upgrade"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := NewErrorResponse(tt.status, tt.err)
+			assert.Equal(t, tt.want, response.StatusCode)
+			assert.Equal(t, tt.code, response.Code)
+			assert.Equal(t, tt.message, response.Message)
+			assert.Equal(t, tt.message, response.Error)
+			assert.Empty(t, response.Errors)
+		})
+	}
+
+	// Hooks reject fields
+	response := NewErrorResponse(http.StatusUnprocessableEntity, resource.NewValidationError("Invalid order",
+		map[string][]string{"quantity": {"exceeds the stock"}}))
+	assert.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	assert.Equal(t, ErrorCodeValidation, response.Code)
+	assert.Equal(t, map[string][]string{"quantity": {"exceeds the stock"}}, response.Errors)
+}
+
+type OperatorNote struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Views int    `json:"views"`
+}
+
+func TestCustomOperatorErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	query.RegisterOperator("prefix", func(field resource.Field, column string, value interface{}) (clause.Expression, error) {
+		if field.Type != "string" {
+			return nil, errors.New("only applies to text")
+		}
+		return clause.Like{Column: clause.Expr{SQL: column}, Value: fmt.Sprint(value) + "%"}, nil
+	})
+	defer query.UnregisterOperator("prefix")
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OperatorNote{}))
+	require.NoError(t, db.Create(&[]OperatorNote{{Title: "alpha"}, {Title: "beta"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "operator-notes",
+		Model:      OperatorNote{},
+		Operations: []resource.Operation{resource.OperationList},
+	})
+	r := gin.New()
+	RegisterResource(r.Group(""), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/operator-notes?filter[title][prefix]=al", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"alpha"`)
+	assert.NotContains(t, w.Body.String(), `"beta"`)
+
+	// Filters the operator rejects are a validation error of their field
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/operator-notes?filter[views][prefix]=1", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, ErrorCodeValidation, response.Code)
+	assert.Equal(t, map[string][]string{"views": {"only applies to text"}}, response.Errors)
+}
+
+func TestQueryValidationErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OperatorNote{}))
+	require.NoError(t, db.Create(&[]OperatorNote{{Title: "alpha", Views: 2}, {Title: "beta", Views: 1}}).Error)
+
+	r := gin.New()
+	for _, validation := range []resource.QueryValidation{resource.QueryValidationLenient, resource.QueryValidationStrict} {
+		res := resource.NewResource(resource.ResourceConfig{
+			Name:             "notes-" + string(validation),
+			Model:            OperatorNote{},
+			Operations:       []resource.Operation{resource.OperationList},
+			FilterableFields: []string{"title"},
+			SortableFields:   []string{"title"},
+			QueryValidation:  validation,
+		})
+		RegisterResource(r.Group(""), res, repository.NewGenericRepositoryWithResource(db, res))
+	}
+
+	// Sorts and filters on other fields are dropped
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes-lenient?views=2&sort=views&order=desc", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"alpha"`)
+	assert.Contains(t, w.Body.String(), `"beta"`)
+
+	// or rejected, naming the fields
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes-strict?filter[titel][eq]=a&sort=views", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, ErrorCodeValidation, response.Code)
+	assert.Equal(t, map[string][]string{
+		"titel": {`unknown filter field, did you mean "title"?`},
+		"views": {"field is not sortable"},
+	}, response.Errors)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notes-strict?title=alpha&sort=title", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), `"beta"`)
+}
+
+func TestBindingErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/signup", func(c *gin.Context) {
+		var req signupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	post := func(body string) (int, ErrorResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body)))
+		var response ErrorResponse
+		if w.Code != http.StatusNoContent {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w.Code, response
+	}
+
+	// Failed rules are listed by field
+	status, response := post(`{"email": "nope", "name": "A"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, ErrorCodeValidation, response.Code)
+	assert.Equal(t, "email must be a valid email address", response.Message)
+	assert.Equal(t, map[string][]string{
+		"email":   {"must be a valid email address"},
+		"name":    {"must be at least 2 characters long"},
+		"ownerId": {"must be at least 1"},
+	}, response.Errors)
+
+	// Wrong types are reported under the field of the body
+	status, response = post(`{"email": "ann@example.com", "name": "Ann", "ownerId": "one"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, ErrorCodeValidation, response.Code)
+	assert.Equal(t, map[string][]string{"ownerId": {"must be an integer"}}, response.Errors)
+
+	status, response = post(`{"email": `)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, ErrorCodeBadRequest, response.Code)
+
+	status, _ = post(`{"email": "ann@example.com", "name": "Ann", "ownerId": 1}`)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestTranslateError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apierror.Translate = func(c *gin.Context, response *ErrorResponse, err error) {
+		locale, _ := requestctx.Locale.Get(c)
+		if locale == "pl" && response.Code == ErrorCodeNotFound {
+			response.Message = "Nie znaleziono zasobu"
+		}
+	}
+	defer func() { apierror.Translate = nil }()
+
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		requestctx.Locale.Set(c, c.GetHeader("Accept-Language"))
+		respondError(c, http.StatusInternalServerError, gorm.ErrRecordNotFound)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "pl")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": "Nie znaleziono zasobu", "message": "Nie znaleziono zasobu",
+		"statusCode": 404, "code": "not_found"}`, w.Body.String())
+}
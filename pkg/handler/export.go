@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// exportBatchSize is the number of records fetched per query while exporting
+const exportBatchSize = 500
+
+// exportFormats maps the supported export formats to their content types
+var exportFormats = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exportWriter writes exported records in one format
+type exportWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// GenerateExportHandler generates a handler streaming all records matching the list
+// filters and sort as CSV, XLSX or JSON (?format=csv|xlsx|json, default csv), ignoring
+// pagination. Columns default to the table fields of the resource and can be selected
+// with ?columns=a,b.
+func GenerateExportHandler(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := strings.ToLower(c.DefaultQuery("format", "csv"))
+		contentType, ok := exportFormats[format]
+		if !ok {
+			respondErrorMessage(c, http.StatusBadRequest, fmt.Sprintf("unsupported export format %q (use csv, xlsx or json)", format))
+			return
+		}
+
+		columns, err := ExportColumns(res, c.Query("columns"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		if roles, ok := requestctx.Roles.Get(c); ok {
+			columns = readableColumns(columns, roles)
+			if len(columns) == 0 {
+				respondErrorMessage(c, http.StatusForbidden, "Forbidden: no readable export columns")
+				return
+			}
+		}
+
+		options := query.ParseQueryOptions(c, res)
+		options.PerPage = exportBatchSize
+		options.Fields = nil // columns select the exported fields
+		if !validateListOptions(c, &options) {
+			return
+		}
+
+		// Fetch the first batch before writing so query errors still get a JSON response
+		batch, err := exportBatch(c, repo, dtoProvider, options, 1)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, res.GetName(), format))
+		c.Status(http.StatusOK)
+
+		writer, err := newExportWriter(c, format, res.GetName(), columns)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		for page := 1; ; page++ {
+			if page > 1 {
+				if batch, err = exportBatch(c, repo, dtoProvider, options, page); err != nil {
+					// Headers are sent; the truncated file is the only signal left
+					c.Error(err)
+					return
+				}
+			}
+			for _, record := range batch {
+				values := make([]interface{}, len(columns))
+				for i, field := range columns {
+					values[i] = record[field.Name]
+				}
+				if err := writer.WriteRow(values); err != nil {
+					c.Error(err)
+					return
+				}
+			}
+			c.Writer.Flush()
+			if len(batch) < exportBatchSize || c.Request.Context().Err() != nil {
+				break
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// ExportColumns returns the fields exported for a comma-separated list of field names
+// (or aliases). An empty list selects the table fields, or all visible fields when the
+// resource defines no table fields.
+func ExportColumns(res resource.Resource, names string) ([]resource.Field, error) {
+	fields := res.GetFields()
+	byName := make(map[string]resource.Field, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+		byName[field.APIName()] = field
+	}
+
+	var selected []string
+	if names != "" {
+		selected = strings.Split(names, ",")
+	} else {
+		selected = res.GetTableFields()
+	}
+
+	if len(selected) == 0 {
+		columns := make([]resource.Field, 0, len(fields))
+		for _, field := range fields {
+			if !field.Hidden {
+				columns = append(columns, field)
+			}
+		}
+		return columns, nil
+	}
+
+	columns := make([]resource.Field, 0, len(selected))
+	for _, name := range selected {
+		field, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown export column %q", strings.TrimSpace(name))
+		}
+		columns = append(columns, field)
+	}
+	return columns, nil
+}
+
+// readableColumns returns the columns the roles may read
+func readableColumns(columns []resource.Field, roles []string) []resource.Field {
+	readable := make([]resource.Field, 0, len(columns))
+	for _, field := range columns {
+		if auth.CanAccessField(field, "read", roles) {
+			readable = append(readable, field)
+		}
+	}
+	return readable
+}
+
+// exportBatch fetches one page of records as JSON objects keyed by field name
+func exportBatch(c *gin.Context, repo repository.Repository, dtoProvider dto.DTOProvider, options query.QueryOptions, page int) ([]map[string]interface{}, error) {
+	options.Page = page
+	data, _, err := repo.List(c.Request.Context(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, nil
+	}
+
+	records := make([]map[string]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item, err := dtoProvider.TransformFromModel(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// newExportWriter creates the writer for a format and writes its header
+func newExportWriter(c *gin.Context, format, name string, columns []resource.Field) (exportWriter, error) {
+	header := make([]interface{}, len(columns))
+	names := make([]string, len(columns))
+	for i, field := range columns {
+		header[i] = field.APIName()
+		names[i] = field.APIName()
+	}
+
+	switch format {
+	case "xlsx":
+		writer, err := utils.NewXLSXWriter(c.Writer, name)
+		if err != nil {
+			return nil, err
+		}
+		return writer, writer.WriteRow(header)
+	case "json":
+		return &jsonExportWriter{c: c, names: names}, nil
+	default:
+		writer := &csvExportWriter{csv: csv.NewWriter(c.Writer)}
+		return writer, writer.WriteRow(header)
+	}
+}
+
+// csvExportWriter writes records as CSV rows
+type csvExportWriter struct {
+	csv *csv.Writer
+}
+
+func (w *csvExportWriter) WriteRow(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, value := range values {
+		row[i] = exportText(value)
+	}
+	return w.csv.Write(row)
+}
+
+func (w *csvExportWriter) Close() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// jsonExportWriter writes records as a JSON array of objects
+type jsonExportWriter struct {
+	c     *gin.Context
+	names []string
+	rows  int
+}
+
+func (w *jsonExportWriter) WriteRow(values []interface{}) error {
+	var b bytes.Buffer
+	if w.rows == 0 {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte(',')
+	}
+	b.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(w.names[i])
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(encoded)
+	}
+	b.WriteByte('}')
+	w.rows++
+	_, err := w.c.Writer.Write(b.Bytes())
+	return err
+}
+
+func (w *jsonExportWriter) Close() error {
+	closing := "]"
+	if w.rows == 0 {
+		closing = "[]"
+	}
+	_, err := w.c.Writer.WriteString(closing)
+	return err
+}
+
+// exportText formats a value as CSV text; objects and arrays are written as JSON
+func exportText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
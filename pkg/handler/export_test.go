@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ExportProduct struct {
+	ID    uint    `json:"id" gorm:"primaryKey"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	Notes string  `json:"notes"`
+}
+
+func setupExport(t *testing.T, count int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ExportProduct{}))
+	products := make([]ExportProduct, count)
+	for i := range products {
+		products[i] = ExportProduct{Name: "product", Price: float64(i + 1), Notes: "a, \"quoted\" note"}
+	}
+	products[0].Name = "first"
+	require.NoError(t, db.CreateInBatches(&products, 200).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "export_products",
+		Model: ExportProduct{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string", Label: "Name"},
+			{Name: "price", Type: "float"},
+			{Name: "notes", Type: "string"},
+		},
+		TableFields:      []string{"id", "name", "price"},
+		FilterableFields: []string{"name", "price"},
+		SortableFields:   []string{"price"},
+		Operations:       []resource.Operation{resource.OperationList, resource.OperationExport},
+	})
+
+	r := gin.New()
+	repo := repository.NewGenericRepositoryWithResource(db, res)
+	r.GET("/export_products/export", GenerateExportHandler(res, repo, &dto.DefaultDTOProvider{Model: res.GetModel()}))
+	return r
+}
+
+func exportRequest(r *gin.Engine, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export_products/export"+query, nil))
+	return w
+}
+
+func TestExportCSV(t *testing.T) {
+	r := setupExport(t, 1203)
+
+	w := exportRequest(r, "?filter[price][gt]=0&sort=price&order=desc")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="export_products.csv"`, w.Header().Get("Content-Disposition"))
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 1204, "all records are exported, ignoring pagination")
+	assert.Equal(t, []string{"id", "name", "price"}, rows[0])
+	assert.Equal(t, []string{"1203", "product", "1203"}, rows[1])
+	assert.Equal(t, []string{"1", "first", "1"}, rows[1203])
+
+	// Filters and column selection
+	w = exportRequest(r, "?name=first&columns=name,notes")
+	rows, err = csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"name", "notes"}, {"first", `a, "quoted" note`}}, rows)
+}
+
+func TestExportJSON(t *testing.T) {
+	r := setupExport(t, 3)
+
+	w := exportRequest(r, "?format=json&columns=id,price&sort=price&order=desc")
+	require.Equal(t, http.StatusOK, w.Code)
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+	assert.Equal(t, []map[string]interface{}{
+		{"id": float64(3), "price": float64(3)},
+		{"id": float64(2), "price": float64(2)},
+		{"id": float64(1), "price": float64(1)},
+	}, records)
+
+	w = exportRequest(r, "?format=json&name=none")
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestExportXLSX(t *testing.T) {
+	r := setupExport(t, 2)
+
+	w := exportRequest(r, "?format=xlsx")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="export_products.xlsx"`, w.Header().Get("Content-Disposition"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			f, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(f)
+			require.NoError(t, err)
+			sheet = string(content)
+		}
+	}
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">first</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="C3"><v>2</v></c>`)
+}
+
+func TestExportErrors(t *testing.T) {
+	r := setupExport(t, 1)
+
+	w := exportRequest(r, "?format=pdf")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "unsupported export format"))
+
+	w = exportRequest(r, "?columns=name,secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown export column \"secret\"`)
+}
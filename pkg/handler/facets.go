@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// GenerateFacetsHandler generates a handler returning value counts for filterable fields
+// under the current filter set, e.g. GET /resources/facets?fields=category,status
+func GenerateFacetsHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, ok := repo.(repository.FacetProvider)
+		if !ok {
+			respondErrorMessage(c, http.StatusNotImplemented, "Facets are not supported by this repository")
+			return
+		}
+
+		var fields []string
+		for _, field := range strings.Split(c.Query("fields"), ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			respondErrorMessage(c, http.StatusBadRequest, "Missing fields parameter")
+			return
+		}
+
+		for _, field := range fields {
+			if !isFilterableField(res, field) {
+				respondErrorMessage(c, http.StatusBadRequest, "Field is not filterable: "+field)
+				return
+			}
+		}
+
+		// Create query options (without pagination)
+		options := query.NewQueryOptions(c, res)
+		options.DisablePagination = true
+		options.Fields = nil // fields names the faceted fields here
+		if !validateListOptions(c, &options) {
+			return
+		}
+
+		facets, err := provider.Facets(c.Request.Context(), options, fields)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": facets})
+	}
+}
+
+// isFilterableField checks (case-insensitively) if a field is filterable for the resource
+func isFilterableField(res resource.Resource, field string) bool {
+	for _, filterable := range res.GetFilterableFields() {
+		if strings.EqualFold(filterable, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type FacetTask struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+}
+
+func TestGenerateFacetsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&FacetTask{}))
+	require.NoError(t, db.Create(&[]FacetTask{
+		{Title: "a", Status: "open", Priority: "high"},
+		{Title: "b", Status: "open", Priority: "low"},
+		{Title: "c", Status: "done", Priority: "high"},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:             "tasks",
+		Model:            FacetTask{},
+		FilterableFields: []string{"status", "priority"},
+		Operations:       []resource.Operation{resource.OperationList, resource.OperationFacets},
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group(""), res, repository.NewGenericRepository(db, res))
+
+	request := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("returns counts", func(t *testing.T) {
+		w := request("/tasks/facets?fields=status,priority")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data map[string]map[string]int64 `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]int64{"open": 2, "done": 1}, response.Data["status"])
+		assert.Equal(t, map[string]int64{"high": 2, "low": 1}, response.Data["priority"])
+	})
+
+	t.Run("applies current filters", func(t *testing.T) {
+		w := request("/tasks/facets?fields=status&priority=high")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"status":{"open":1,"done":1}}}`, w.Body.String())
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request("/tasks/facets").Code)
+	})
+
+	t.Run("field not filterable", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, request("/tasks/facets?fields=title").Code)
+	})
+
+	t.Run("repository without facet support", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/tasks/facets", GenerateFacetsHandler(res, new(MockRepository)))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/tasks/facets?fields=status", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
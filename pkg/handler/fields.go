@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// selectRecordFields returns the records in data (a record or a slice of records)
+// with only the keys of the selected fields (a sparse fieldset). Data is returned
+// unchanged when no fields are selected.
+func selectRecordFields(res resource.Resource, data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range fields {
+		selected[normalizeBodyKey(name)] = true
+		if field := res.GetField(name); field != nil {
+			selected[normalizeBodyKey(field.APIName())] = true
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var records interface{}
+	if err := decoder.Decode(&records); err != nil {
+		return nil, err
+	}
+
+	keep := func(record interface{}) {
+		if object, ok := record.(map[string]interface{}); ok {
+			for key := range object {
+				if !selected[normalizeBodyKey(key)] {
+					delete(object, key)
+				}
+			}
+		}
+	}
+	if items, ok := records.([]interface{}); ok {
+		for _, item := range items {
+			keep(item)
+		}
+	} else {
+		keep(records)
+	}
+	return records, nil
+}
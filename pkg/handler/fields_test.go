@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SparseProduct struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	Name        string  `json:"name"`
+	Price       float64 `json:"price"`
+	Description string  `json:"description"`
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SparseProduct{}))
+	require.NoError(t, db.Create(&SparseProduct{Name: "Lamp", Price: 19.5, Description: "A very long description"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: SparseProduct{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "price", Type: "float"},
+			{Name: "description", Type: "string"},
+		},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group(""), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("list", func(t *testing.T) {
+		w := request("/products?fields=name,price")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 1)
+		assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "Lamp", "price": 19.5}, response.Data[0])
+	})
+
+	t.Run("get", func(t *testing.T) {
+		w := request("/products/1?fields=description")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]interface{}{"id": float64(1), "description": "A very long description"}, response.Data)
+
+		// The selection is part of the ETag
+		full := request("/products/1")
+		assert.NotEqual(t, full.Header().Get("ETag"), w.Header().Get("ETag"))
+		assert.Contains(t, full.Body.String(), `"price":19.5`)
+	})
+}
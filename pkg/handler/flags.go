@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// FlagsMiddleware translates bit flag fields (resource.FlagsConfig) between the integer
+// columns of the model and arrays of flag names: arrays in JSON bodies are converted
+// to their masks before binding, and masks in the records of JSON responses to arrays.
+// Unknown flags are rejected with 422. Resources without flag fields are passed
+// through untouched.
+func FlagsMiddleware(res resource.Resource) gin.HandlerFunc {
+	flags := make(map[string]*resource.FlagsConfig)
+	for _, field := range res.GetFields() {
+		if field.Flags != nil {
+			flags[normalizeBodyKey(field.Name)] = field.Flags
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(flags) == 0 {
+			c.Next()
+			return
+		}
+
+		var flagErr error
+		err := rewriteBodyRecords(c, func(records interface{}) bool {
+			changed, err := convertRecordFlags(records, flags, true)
+			flagErr = err
+			return changed
+		})
+		if err == nil {
+			err = flagErr
+		}
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		w := &recordWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(func(records interface{}) bool {
+			changed, _ := convertRecordFlags(records, flags, false)
+			return changed
+		})
+	}
+}
+
+// convertRecordFlags converts the flag fields of a record or a list of records in
+// place, to masks or to arrays of names, reporting whether any value was converted
+func convertRecordFlags(records interface{}, flags map[string]*resource.FlagsConfig, toMask bool) (bool, error) {
+	switch value := records.(type) {
+	case map[string]interface{}:
+		changed := false
+		for key, v := range value {
+			config, ok := flags[normalizeBodyKey(key)]
+			if !ok {
+				continue
+			}
+			if toMask {
+				names, ok := flagNames(v)
+				if !ok {
+					continue
+				}
+				mask, err := config.Mask(names)
+				if err != nil {
+					return changed, resource.NewValidationError(
+						fmt.Sprintf("%s: %v", key, err), map[string][]string{key: {err.Error()}})
+				}
+				value[key] = mask
+			} else {
+				mask, ok := flagMask(v)
+				if !ok {
+					continue
+				}
+				value[key] = config.Names(mask)
+			}
+			changed = true
+		}
+		return changed, nil
+	case []interface{}:
+		changed := false
+		for _, item := range value {
+			itemChanged, err := convertRecordFlags(item, flags, toMask)
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || itemChanged
+		}
+		return changed, nil
+	}
+	return false, nil
+}
+
+// flagNames returns the flag names of a body value: an array of names. Other values,
+// such as masks, are left to the binding.
+func flagNames(value interface{}) ([]string, bool) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = fmt.Sprint(item)
+	}
+	return names, true
+}
+
+// flagMask returns the mask of a response value
+func flagMask(value interface{}) (int64, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	mask, err := strconv.ParseInt(number.String(), 10, 64)
+	return mask, err == nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type FlaggedAccount struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Name         string `json:"name"`
+	Capabilities int    `json:"capabilities" refine:"flags=read|write|admin"`
+}
+
+func TestFlagsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&FlaggedAccount{}))
+	require.NoError(t, db.Create(&[]FlaggedAccount{{Name: "viewer", Capabilities: 1}, {Name: "root", Capabilities: 7}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "accounts",
+		Model: FlaggedAccount{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate, resource.OperationUpdate,
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Responses carry flag names
+	w := request(http.MethodGet, "/api/accounts/2", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"capabilities":["read","write","admin"]`)
+
+	// Bodies are converted to masks
+	w = request(http.MethodPost, "/api/accounts", `{"name":"editor","capabilities":["read","write"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"capabilities":["read","write"]`)
+	var stored FlaggedAccount
+	require.NoError(t, db.Where("name = ?", "editor").First(&stored).Error)
+	assert.Equal(t, 3, stored.Capabilities)
+
+	w = request(http.MethodPost, "/api/accounts", `{"name":"bad","capabilities":["read","fly"]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var errResponse ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResponse))
+	assert.Equal(t, []string{`unknown flag "fly"`}, errResponse.Errors["capabilities"])
+
+	// has_flag keeps the records with all the flags set
+	list := func(query string) (int, []string) {
+		w := request(http.MethodGet, "/api/accounts?"+query, "")
+		var body struct {
+			Data []struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		names := []string{}
+		for _, account := range body.Data {
+			names = append(names, account.Name)
+		}
+		return w.Code, names
+	}
+	code, names := list("filter[capabilities][has_flag]=write&sort=id")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"root", "editor"}, names)
+	_, names = list("filter[capabilities][has_flag]=write,admin")
+	assert.Equal(t, []string{"root"}, names)
+	code, _ = list("filter[capabilities][has_flag]=fly")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Metadata lists the flags
+	w = request(http.MethodOptions, "/api/accounts", "")
+	assert.Contains(t, w.Body.String(), `"flags":[{"name":"read","bit":0},{"name":"write","bit":1},{"name":"admin","bit":2}]`)
+}
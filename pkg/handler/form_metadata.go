@@ -49,7 +49,7 @@ func GenerateFormMetadataHandler(res resource.Resource) gin.HandlerFunc {
 		}
 
 		// Extract default values from model
-		metadata.DefaultValues = extractDefaultValues(res.GetModel())
+		metadata.DefaultValues = aliasRecord(extractDefaultValues(res.GetModel()), res.GetFields())
 
 		// Extract field dependencies
 		metadata.Dependencies = extractFieldDependencies(res.GetFields())
@@ -244,7 +244,7 @@ func RegisterResourceFormEndpoints(router *gin.RouterGroup, res resource.Resourc
 		}
 
 		// Use the processed item as default values
-		metadata.DefaultValues = defaultValues
+		metadata.DefaultValues = aliasRecord(defaultValues, res.GetFields())
 
 		// Extract field dependencies
 		metadata.Dependencies = extractFieldDependencies(res.GetFields())
@@ -1,33 +1,33 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/query"
 	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"github.com/suranig/refine-gin/pkg/utils"
 )
 
 // GenerateGetHandler generates a handler for READ operations
 func GenerateGetHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
-	// Use default DTO provider if none is specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// Use the DTO provider of the resource
+	dtoProvider := dto.ForResource(res)
 
 	return generateGetHandlerWithDTO("id", res, repo, dtoProvider)
 }
 
 // GenerateGetHandlerWithParam generates a handler for READ operations with custom ID parameter name
 func GenerateGetHandlerWithParam(res resource.Resource, repo repository.Repository, idParamName string) gin.HandlerFunc {
-	// Use default DTO provider if none is specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// Use the DTO provider of the resource
+	dtoProvider := dto.ForResource(res)
 
 	return generateGetHandlerWithDTO(idParamName, res, repo, dtoProvider)
 }
@@ -48,8 +48,28 @@ func generateGetHandlerWithDTO(idParamName string, res resource.Resource, repo r
 		// Get ID from URL parameters using custom parameter name
 		id := c.Param(idParamName)
 
+		// Selected fields (sparse fieldset) are passed to the repository
+		fields := query.ParseFields(c, res)
+		if len(fields) > 0 {
+			requestctx.Fields.Set(c, fields)
+		}
+
+		// Included relations are loaded by the repository
+		includes := query.ParseIncludes(c, res)
+		if len(includes) > 0 {
+			requestctx.Includes.Set(c, includes)
+		}
+
 		// Generate ETag for cache validation
 		etag := utils.GenerateResourceETag(res.GetName(), id)
+		if provider, ok := repo.(repository.ETagProvider); ok {
+			if versioned, err := provider.ETag(c.Request.Context(), id); err == nil {
+				etag = versioned
+			}
+		}
+		if len(fields) > 0 || len(includes) > 0 {
+			etag = utils.GenerateETag(etag + "|" + strings.Join(fields, ",") + "|" + c.Query(query.IncludeParam))
+		}
 		ifNoneMatch := c.GetHeader("If-None-Match")
 
 		// Check if client's cached version is still valid
@@ -61,7 +81,7 @@ func generateGetHandlerWithDTO(idParamName string, res resource.Resource, repo r
 		// Call repository
 		data, err := repo.Get(c.Request.Context(), id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 			return
 		}
 
@@ -69,11 +89,12 @@ func generateGetHandlerWithDTO(idParamName string, res resource.Resource, repo r
 		if dtoProvider != nil {
 			dtoData, err := dtoProvider.TransformFromModel(data)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error transforming data: " + err.Error()})
+				respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
 				return
 			}
 			data = dtoData
 		}
+		data = transformResponseItem(c, res, data)
 
 		// Set cache headers
 		// Try to get last modified time from the data
@@ -84,6 +105,12 @@ func generateGetHandlerWithDTO(idParamName string, res resource.Resource, repo r
 
 		utils.SetCacheHeaders(c.Writer, 120, etag, lastModified, []string{"Accept", "Accept-Encoding", "Authorization"})
 
+		// Keep the selected fields only
+		if data, err = selectRecordFields(res, data, fields); err != nil {
+			respondError(c, http.StatusInternalServerError, fmt.Errorf("Error selecting fields: %w", err))
+			return
+		}
+
 		// Return result
 		c.JSON(http.StatusOK, gin.H{
 			"data": data,
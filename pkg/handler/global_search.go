@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// DefaultGlobalSearchLimit is the number of results returned per resource when a
+// search source has no limit
+const DefaultGlobalSearchLimit = 5
+
+// SearchSource is a resource searched by the global search endpoint
+type SearchSource struct {
+	Resource   resource.Resource
+	Repository repository.Repository
+
+	// Limit of results returned for the resource (DefaultGlobalSearchLimit if zero)
+	Limit int
+}
+
+// GlobalSearchItem is a record found by the global search
+type GlobalSearchItem struct {
+	ID    interface{} `json:"id"`
+	Label interface{} `json:"label,omitempty"`
+}
+
+// GlobalSearchGroup holds the records of a resource found by the global search
+type GlobalSearchGroup struct {
+	Resource string             `json:"resource"`
+	Label    string             `json:"label"`
+	Total    int64              `json:"total"`
+	Items    []GlobalSearchItem `json:"items"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// RegisterGlobalSearch registers GET /search on the router, searching the searchable
+// fields of all sources (see GenerateGlobalSearchHandler)
+func RegisterGlobalSearch(router *gin.RouterGroup, sources ...SearchSource) {
+	router.GET("/search", GenerateGlobalSearchHandler(sources))
+}
+
+// GenerateGlobalSearchHandler generates a handler searching the searchable fields of
+// several resources at once, e.g. GET /search?q=ada&resources=users,posts. The
+// resources are queried in parallel, each limited to the limit of its source. Results
+// are grouped by resource, in the order of the sources, and items carry the record ID
+// and the value of the first searchable field as label. Resources without searchable
+// fields, without the list operation, or which the roles of the user may not list are
+// skipped. A failing resource reports its error in its group instead of failing the
+// whole search.
+func GenerateGlobalSearchHandler(sources []SearchSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		term := strings.TrimSpace(c.Query("q"))
+		if term == "" {
+			respondErrorMessage(c, http.StatusBadRequest, "Missing q parameter")
+			return
+		}
+
+		var only map[string]bool
+		if names := c.Query("resources"); names != "" {
+			only = make(map[string]bool)
+			for _, name := range strings.Split(names, ",") {
+				only[strings.TrimSpace(name)] = true
+			}
+		}
+
+		roles := auth.UserRoles(c)
+		var selected []SearchSource
+		for _, source := range sources {
+			res := source.Resource
+			if only != nil && !only[res.GetName()] {
+				continue
+			}
+			if len(res.GetSearchable()) == 0 || !res.HasOperation(resource.OperationList) {
+				continue
+			}
+			if len(res.GetPermissions()) > 0 && !auth.CanPerform(res, resource.OperationList, roles) {
+				continue
+			}
+			selected = append(selected, source)
+		}
+
+		groups := make([]GlobalSearchGroup, len(selected))
+		var wg sync.WaitGroup
+		for i, source := range selected {
+			wg.Add(1)
+			go func(i int, source SearchSource) {
+				defer wg.Done()
+				groups[i] = searchSource(c, source, term)
+			}(i, source)
+		}
+		wg.Wait()
+
+		c.JSON(http.StatusOK, gin.H{"data": groups})
+	}
+}
+
+// searchSource searches the records of a single source
+func searchSource(c *gin.Context, source SearchSource, term string) GlobalSearchGroup {
+	res := source.Resource
+	group := GlobalSearchGroup{
+		Resource: res.GetName(),
+		Label:    res.GetLabel(),
+		Items:    []GlobalSearchItem{},
+	}
+
+	limit := source.Limit
+	if limit <= 0 {
+		limit = DefaultGlobalSearchLimit
+	}
+	options := query.QueryOptions{
+		Resource: res,
+		Page:     1,
+		PerPage:  limit,
+		Search:   term,
+		Order:    "asc",
+	}
+	if defaultSort := res.GetDefaultSort(); defaultSort != nil {
+		options.Sort = defaultSort.Field
+		options.Order = defaultSort.Order
+	}
+	if err := options.Validate(); err != nil {
+		group.Error = err.Error()
+		return group
+	}
+
+	data, total, err := source.Repository.List(c.Request.Context(), options)
+	if err != nil {
+		group.Error = err.Error()
+		return group
+	}
+	group.Total = total
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		group.Error = err.Error()
+		return group
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(encoded, &records); err != nil {
+		group.Error = err.Error()
+		return group
+	}
+
+	idKey := normalizeBodyKey(res.GetIDFieldName())
+	labelKey := normalizeBodyKey(res.GetSearchable()[0])
+	for _, record := range records {
+		var item GlobalSearchItem
+		for key, value := range record {
+			switch normalizeBodyKey(key) {
+			case idKey:
+				item.ID = value
+			case labelKey:
+				item.Label = value
+			}
+		}
+		group.Items = append(group.Items, item)
+	}
+	return group
+}
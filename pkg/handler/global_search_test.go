@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SearchCustomer struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type SearchInvoice struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Number string `json:"number"`
+	Note   string `json:"note"`
+}
+
+func TestGlobalSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	// Resources are searched in parallel, and every connection to an in-memory
+	// database opens a new database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&SearchCustomer{}, &SearchInvoice{}))
+	require.NoError(t, db.Create(&[]SearchCustomer{
+		{Name: "Ada Lovelace", Email: "ada@example.com"},
+		{Name: "Adam Smith", Email: "adam@example.com"},
+		{Name: "Grace Hopper", Email: "grace@example.com"},
+	}).Error)
+	require.NoError(t, db.Create(&[]SearchInvoice{
+		{Number: "INV-1", Note: "for Ada"},
+		{Number: "INV-2", Note: "for Grace"},
+	}).Error)
+
+	customers := resource.NewResource(resource.ResourceConfig{
+		Name:             "customers",
+		Label:            "Customers",
+		Model:            SearchCustomer{},
+		SearchableFields: []string{"name", "email"},
+		Operations:       []resource.Operation{resource.OperationList},
+	})
+	invoices := resource.NewResource(resource.ResourceConfig{
+		Name:             "invoices",
+		Label:            "Invoices",
+		Model:            SearchInvoice{},
+		SearchableFields: []string{"number", "note"},
+		Operations:       []resource.Operation{resource.OperationList},
+		Permissions:      map[string][]string{"list": {"accounting"}},
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if roles := c.GetHeader("X-Roles"); roles != "" {
+			c.Set("claims", jwt.MapClaims{"roles": []interface{}{roles}})
+		}
+	})
+	RegisterGlobalSearch(r.Group("/api"),
+		SearchSource{Resource: customers, Repository: repository.NewGenericRepositoryWithResource(db, customers), Limit: 1},
+		SearchSource{Resource: invoices, Repository: repository.NewGenericRepositoryWithResource(db, invoices)},
+	)
+
+	search := func(url, role string) (int, []GlobalSearchGroup) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if role != "" {
+			req.Header.Set("X-Roles", role)
+		}
+		r.ServeHTTP(w, req)
+		var response struct {
+			Data []GlobalSearchGroup `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data
+	}
+
+	t.Run("groups results by resource", func(t *testing.T) {
+		code, groups := search("/api/search?q=ada", "accounting")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, groups, 2)
+
+		assert.Equal(t, "customers", groups[0].Resource)
+		assert.Equal(t, "Customers", groups[0].Label)
+		assert.Equal(t, int64(2), groups[0].Total)
+		require.Len(t, groups[0].Items, 1)
+		assert.Equal(t, float64(1), groups[0].Items[0].ID)
+		assert.Equal(t, "Ada Lovelace", groups[0].Items[0].Label)
+
+		assert.Equal(t, "invoices", groups[1].Resource)
+		require.Len(t, groups[1].Items, 1)
+		assert.Equal(t, "INV-1", groups[1].Items[0].Label)
+	})
+
+	t.Run("skips resources the roles may not list", func(t *testing.T) {
+		_, groups := search("/api/search?q=ada", "")
+		require.Len(t, groups, 1)
+		assert.Equal(t, "customers", groups[0].Resource)
+	})
+
+	t.Run("restricts the searched resources", func(t *testing.T) {
+		_, groups := search("/api/search?q=grace&resources=invoices", "accounting")
+		require.Len(t, groups, 1)
+		assert.Equal(t, "invoices", groups[0].Resource)
+		assert.Equal(t, int64(1), groups[0].Total)
+	})
+
+	t.Run("requires a search term", func(t *testing.T) {
+		code, _ := search("/api/search?q=", "")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
@@ -351,6 +351,7 @@ func setupTest() (*gin.Engine, *MockRepository, *MockResource, *MockDTOProvider)
 	mockResource.On("GetIDFieldName").Return("ID")
 	mockResource.On("GetField", mock.Anything).Return(nil)
 	mockResource.On("GetSearchable").Return([]string{})
+	mockResource.On("GetFilterableFields").Return([]string{})
 
 	return r, mockRepo, mockResource, mockDTOProvider
 }
@@ -525,6 +526,11 @@ func TestRegisterResource(t *testing.T) {
 	mockResource.On("HasOperation", resource.OperationUpdate).Return(true)
 	mockResource.On("HasOperation", resource.OperationDelete).Return(true)
 	mockResource.On("HasOperation", resource.OperationCount).Return(true)
+	mockResource.On("HasOperation", resource.OperationFacets).Return(false)
+	mockResource.On("HasOperation", resource.OperationAggregate).Return(false)
+	mockResource.On("HasOperation", resource.OperationImport).Return(false)
+	mockResource.On("HasOperation", resource.OperationExport).Return(false)
+	mockResource.On("GetPermissions").Return(map[string][]string(nil))
 
 	// Register resource
 	api := r.Group("/api")
@@ -655,6 +661,11 @@ func TestRegisterResourceWithOptions(t *testing.T) {
 	mockResource.On("HasOperation", resource.OperationUpdate).Return(true)
 	mockResource.On("HasOperation", resource.OperationDelete).Return(true)
 	mockResource.On("HasOperation", resource.OperationCount).Return(true)
+	mockResource.On("HasOperation", resource.OperationFacets).Return(false)
+	mockResource.On("HasOperation", resource.OperationAggregate).Return(false)
+	mockResource.On("HasOperation", resource.OperationImport).Return(false)
+	mockResource.On("HasOperation", resource.OperationExport).Return(false)
+	mockResource.On("GetPermissions").Return(map[string][]string(nil))
 
 	// Register resource with custom ID parameter name
 	api := r.Group("/api")
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// errNoVersion is returned for repositories without a query builder
+var errNoVersion = errors.New("no version")
+
+// HTTPCacheMiddleware returns the middleware.HTTPCache of an operation (OperationList
+// or OperationRead) configured by the HTTP cache options of a resource, or nil if they
+// are disabled. Lists are versioned by their count and the latest value of the version
+// field of their records, and records by their value of the field, so unchanged data
+// is answered 304 Not Modified without being loaded. The BeforeList hook doesn't run
+// for versions, which may only be broader than the lists.
+func HTTPCacheMiddleware(res resource.Resource, repo repository.Repository, options resource.HTTPCacheOptions, op resource.Operation, idParamName string) gin.HandlerFunc {
+	if !options.Enabled {
+		return nil
+	}
+	config := middleware.HTTPCacheConfig{MaxAge: options.MaxAge[op], Public: options.Public}
+
+	versionField := versionColumn(repo, res, options.VersionField)
+	if versionField != nil {
+		switch op {
+		case resource.OperationList:
+			config.Version = func(c *gin.Context) (string, error) {
+				return listVersion(c, res, repo, versionField.DBName)
+			}
+		case resource.OperationRead:
+			idField := idColumn(repo, res)
+			if idField != nil {
+				config.Version = func(c *gin.Context) (string, error) {
+					return recordVersion(c, repo, versionField.DBName, idField.DBName, c.Param(idParamName))
+				}
+			}
+		}
+	}
+	return middleware.HTTPCache(config)
+}
+
+// listVersion returns the count of the records of a list and their latest version
+func listVersion(c *gin.Context, res resource.Resource, repo repository.Repository, column string) (string, error) {
+	db := repo.Query(c.Request.Context())
+	if db == nil {
+		return "", errNoVersion
+	}
+	options := query.ParseQueryOptions(c, res)
+	if err := options.Validate(); err != nil {
+		return "", err
+	}
+	options.Sort = ""
+
+	var count int64
+	var latest interface{}
+	row := options.Apply(db).Select(fmt.Sprintf("COUNT(*), MAX(%s)", db.Statement.Quote(column))).Row()
+	if err := row.Scan(&count, &latest); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d|%v", count, latest), nil
+}
+
+// recordVersion returns the version of a record
+func recordVersion(c *gin.Context, repo repository.Repository, column, idColumn, id string) (string, error) {
+	db := repo.Query(c.Request.Context())
+	if db == nil {
+		return "", errNoVersion
+	}
+	var version interface{}
+	row := db.Select(db.Statement.Quote(column)).Where(fmt.Sprintf("%s = ?", db.Statement.Quote(idColumn)), id).Limit(1).Row()
+	if err := row.Scan(&version); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", version), nil
+}
+
+// versionColumn returns the version field of a resource, or nil if its model has none
+func versionColumn(repo repository.Repository, res resource.Resource, name string) *schema.Field {
+	modelSchema := parseModelSchema(repo, res)
+	if modelSchema == nil {
+		return nil
+	}
+	if name == "" {
+		return modelSchema.LookUpField("updated_at")
+	}
+	return modelSchema.LookUpField(name)
+}
+
+// idColumn returns the ID field of a resource
+func idColumn(repo repository.Repository, res resource.Resource) *schema.Field {
+	modelSchema := parseModelSchema(repo, res)
+	if modelSchema == nil {
+		return nil
+	}
+	if field := modelSchema.LookUpField(res.GetIDFieldName()); field != nil {
+		return field
+	}
+	return modelSchema.PrioritizedPrimaryField
+}
+
+// parseModelSchema parses the model of a resource with the database of its repository
+func parseModelSchema(repo repository.Repository, res resource.Resource) *schema.Schema {
+	if repo == nil || res.GetModel() == nil {
+		return nil
+	}
+	db := repo.Query(context.Background())
+	if db == nil {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return nil
+	}
+	return stmt.Schema
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type HTTPCachedNote struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func TestHTTPCacheMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&HTTPCachedNote{}))
+	require.NoError(t, db.Create(&HTTPCachedNote{Title: "First"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "http_cached_notes",
+		Model:      HTTPCachedNote{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+	})
+	queries := 0
+	db.Callback().Query().Before("gorm:query").Register("count_queries", func(tx *gorm.DB) {
+		if tx.Statement.Dest != nil {
+			queries++
+		}
+	})
+	r := gin.New()
+	opts := resource.DefaultOptions().WithHTTPCache(resource.HTTPCacheOptions{
+		Enabled: true,
+		MaxAge:  map[resource.Operation]int{resource.OperationRead: 30},
+	})
+	RegisterResourceWithOptions(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res), opts)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/api/http_cached_notes?q=", "/api/http_cached_notes/1"} {
+		w := get(path, "")
+		require.Equal(t, http.StatusOK, w.Code, path)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		// Unchanged data is answered 304 without loading the records
+		queries = 0
+		w = get(path, etag)
+		assert.Equal(t, http.StatusNotModified, w.Code, path)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		assert.Zero(t, queries, path)
+
+		// Changes replace the ETag
+		require.NoError(t, db.Model(&HTTPCachedNote{}).Where("id = 1").Update("title", "Changed "+path).Error)
+		w = get(path, etag)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	}
+	assert.Equal(t, "private, no-cache", get("/api/http_cached_notes", "").Header().Get("Cache-Control"))
+	assert.Equal(t, "private, max-age=30", get("/api/http_cached_notes/1", "").Header().Get("Cache-Control"))
+
+	// New records change the ETag of lists
+	etag := get("/api/http_cached_notes", "").Header().Get("ETag")
+	require.NoError(t, db.Create(&HTTPCachedNote{Title: "Second"}).Error)
+	assert.Equal(t, http.StatusOK, get("/api/http_cached_notes", etag).Code)
+
+	// Missing records aren't cached
+	w := get("/api/http_cached_notes/42", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
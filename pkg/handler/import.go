@@ -0,0 +1,405 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+const (
+	// importSampleSize is the number of sample rows returned by the inspect endpoint
+	importSampleSize = 5
+
+	// importProgressEvery is the number of rows between progress events
+	importProgressEvery = 100
+
+	// maxImportErrors limits the number of row errors reported in the summary
+	maxImportErrors = 100
+)
+
+// ImportRowError describes a CSV row that could not be imported
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ImportResult summarizes an import run
+type ImportResult struct {
+	DryRun    bool             `json:"dryRun"`
+	Processed int              `json:"processed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// GenerateImportInspectHandler generates a handler returning the columns, sample rows and
+// suggested column-to-field mapping of an uploaded CSV file (multipart field "file")
+func GenerateImportInspectHandler(res resource.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reader, closeFile, err := openImportCSV(c)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		defer closeFile()
+
+		columns, err := reader.Read()
+		if err != nil {
+			respondErrorMessage(c, http.StatusBadRequest, "CSV file has no header row")
+			return
+		}
+
+		sample := [][]string{}
+		for len(sample) < importSampleSize {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				respondError(c, http.StatusBadRequest, err)
+				return
+			}
+			sample = append(sample, row)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"columns":   columns,
+			"sample":    sample,
+			"mapping":   SuggestImportMapping(res, columns),
+			"delimiter": string(reader.Comma),
+		}})
+	}
+}
+
+// GenerateImportRunHandler generates a handler importing an uploaded CSV file (multipart
+// field "file") using the column-to-field "mapping" (JSON object). With "dryRun" set to
+// true rows are only validated. Clients accepting text/event-stream receive "progress",
+// "rowError" and "complete" events; other clients receive the summary as JSON.
+func GenerateImportRunHandler(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		var mapping map[string]string
+		if err := json.Unmarshal([]byte(c.PostForm("mapping")), &mapping); err != nil || len(mapping) == 0 {
+			respondErrorMessage(c, http.StatusBadRequest, "mapping must be a JSON object of CSV columns to fields")
+			return
+		}
+		importable := importableFields(res)
+		for column, field := range mapping {
+			if field != "" && !importable[field] {
+				respondErrorMessage(c, http.StatusBadRequest, fmt.Sprintf("column %q is mapped to unknown or read-only field %q", column, field))
+				return
+			}
+		}
+		dryRun, _ := strconv.ParseBool(c.PostForm("dryRun"))
+
+		reader, closeFile, err := openImportCSV(c)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		defer closeFile()
+
+		header, err := reader.Read()
+		if err != nil {
+			respondErrorMessage(c, http.StatusBadRequest, "CSV file has no header row")
+			return
+		}
+
+		stream := strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+		emit := func(event string, data interface{}) {
+			if stream {
+				c.SSEvent(event, data)
+				c.Writer.Flush()
+			}
+		}
+		if stream {
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("X-Accel-Buffering", "no")
+			c.Status(http.StatusOK)
+		}
+
+		result := ImportResult{DryRun: dryRun, Errors: []ImportRowError{}}
+		fail := func(rowErr ImportRowError) {
+			result.Failed++
+			if len(result.Errors) < maxImportErrors {
+				result.Errors = append(result.Errors, rowErr)
+			}
+			emit("rowError", rowErr)
+		}
+
+		for row := 2; ; row++ {
+			if err := c.Request.Context().Err(); err != nil {
+				return
+			}
+
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			result.Processed++
+			if err != nil {
+				fail(ImportRowError{Row: row, Error: err.Error()})
+				continue
+			}
+
+			if rowErr := importRow(c, repo, dtoProvider, header, record, mapping, dryRun); rowErr != nil {
+				rowErr.Row = row
+				fail(*rowErr)
+			} else {
+				result.Succeeded++
+			}
+
+			if result.Processed%importProgressEvery == 0 {
+				emit("progress", gin.H{"processed": result.Processed, "succeeded": result.Succeeded, "failed": result.Failed})
+			}
+		}
+
+		if stream {
+			emit("complete", result)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": result})
+	}
+}
+
+// SuggestImportMapping maps CSV columns to importable fields by comparing normalized
+// column names with field names and labels
+func SuggestImportMapping(res resource.Resource, columns []string) map[string]string {
+	importable := importableFields(res)
+	mapping := make(map[string]string)
+	used := make(map[string]bool)
+
+	for _, column := range columns {
+		normalized := normalizeImportName(column)
+		for _, field := range res.GetFields() {
+			if !importable[field.Name] || used[field.Name] {
+				continue
+			}
+			if normalized == normalizeImportName(field.Name) || (field.Label != "" && normalized == normalizeImportName(field.Label)) {
+				mapping[column] = field.Name
+				used[field.Name] = true
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// importRow converts, validates and (unless dryRun) creates a single CSV row
+func importRow(c *gin.Context, repo repository.Repository, dtoProvider dto.DTOProvider, header, record []string, mapping map[string]string, dryRun bool) *ImportRowError {
+	dtoInstance := dtoProvider.GetCreateDTO()
+	dtoType := reflect.TypeOf(dtoInstance)
+	for dtoType.Kind() == reflect.Ptr {
+		dtoType = dtoType.Elem()
+	}
+
+	values := make(map[string]interface{})
+	for i, column := range header {
+		field := mapping[column]
+		if field == "" || i >= len(record) {
+			continue
+		}
+		raw := strings.TrimSpace(record[i])
+		if raw == "" {
+			continue
+		}
+		value, err := convertImportValue(jsonFieldType(dtoType, field), raw)
+		if err != nil {
+			return &ImportRowError{Column: column, Error: err.Error()}
+		}
+		values[field] = value
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return &ImportRowError{Error: err.Error()}
+	}
+	if err := json.Unmarshal(data, dtoInstance); err != nil {
+		return &ImportRowError{Error: err.Error()}
+	}
+	if err := binding.Validator.ValidateStruct(dtoInstance); err != nil {
+		return &ImportRowError{Error: err.Error()}
+	}
+
+	model, err := dtoProvider.TransformToModel(dtoInstance)
+	if err != nil {
+		return &ImportRowError{Error: err.Error()}
+	}
+	if dryRun {
+		return nil
+	}
+	if _, err := repo.Create(c.Request.Context(), model); err != nil {
+		return &ImportRowError{Error: err.Error()}
+	}
+	return nil
+}
+
+// openImportCSV opens the uploaded CSV file and detects its delimiter
+// (",", ";" or tab; override with the "delimiter" form value)
+func openImportCSV(c *gin.Context) (*csv.Reader, func(), error) {
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, nil, errors.New("CSV file is required in the \"file\" field")
+	}
+
+	buffered := bufio.NewReader(file)
+	delimiter := c.PostForm("delimiter")
+	if delimiter == "" {
+		firstLine, _ := buffered.Peek(4096)
+		delimiter = detectDelimiter(string(firstLine))
+	}
+
+	// Skip a UTF-8 byte order mark written by spreadsheet applications
+	if bom, _ := buffered.Peek(3); string(bom) == "\xef\xbb\xbf" {
+		_, _ = buffered.Discard(3)
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = []rune(delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = false
+	return reader, func() { file.Close() }, nil
+}
+
+// detectDelimiter picks the most frequent candidate delimiter in the header line
+func detectDelimiter(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+	best, bestCount := ",", 0
+	for _, candidate := range []string{",", ";", "\t"} {
+		if count := strings.Count(text, candidate); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
+
+// importableFields returns the fields that can be set by an import
+func importableFields(res resource.Resource) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range res.GetFields() {
+		if field.ReadOnly || field.Computed != nil || strings.EqualFold(field.Name, res.GetIDFieldName()) {
+			continue
+		}
+		fields[field.Name] = true
+	}
+	return fields
+}
+
+// normalizeImportName lowercases a name and strips everything but letters and digits
+func normalizeImportName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// jsonFieldType returns the type of the struct field with the given JSON name
+func jsonFieldType(structType reflect.Type, name string) reflect.Type {
+	if structType.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous {
+			if t := jsonFieldType(field.Type, name); t != nil {
+				return t
+			}
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if strings.EqualFold(jsonName, name) {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// importTimeLayouts are the date formats accepted for time fields
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// convertImportValue converts a CSV cell to a JSON value matching the target type
+func convertImportValue(t reflect.Type, raw string) (interface{}, error) {
+	if t == nil {
+		return raw, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", raw)
+		}
+		return v, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return v, nil
+	case reflect.Bool:
+		switch strings.ToLower(raw) {
+		case "yes", "y":
+			return true, nil
+		case "no", "n":
+			return false, nil
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", raw)
+		}
+		return v, nil
+	case reflect.String:
+		return raw, nil
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		for _, layout := range importTimeLayouts {
+			if v, err := time.Parse(layout, raw); err == nil {
+				return v.Format(time.RFC3339Nano), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date %q", raw)
+	}
+
+	// Structured values (JSON columns) may be given as JSON
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err == nil {
+		return v, nil
+	}
+	return raw, nil
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ImportProduct struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" binding:"required"`
+	Price     float64   `json:"price"`
+	InStock   bool      `json:"in_stock"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func setupImportTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ImportProduct{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "products",
+		Model:      ImportProduct{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationImport},
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group(""), res, repository.NewGenericRepository(db, res))
+	return r, db
+}
+
+func importRequest(t *testing.T, url, csvContent string, fields map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, v := range fields {
+		require.NoError(t, writer.WriteField(k, v))
+	}
+	part, err := writer.CreateFormFile("file", "products.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(csvContent))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+const importCSV = "\xef\xbb\xbfProduct Name;Price;In Stock;Qty;Notes\n" +
+	"Apple;1,5;yes;10;fresh\n" +
+	"Pear;abc;no;5;\n" +
+	";2;no;1;missing name\n" +
+	"Plum;3;true;7;\n"
+
+func TestImportInspect(t *testing.T) {
+	r, _ := setupImportTest(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, importRequest(t, "/products/import/inspect", importCSV, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data struct {
+			Columns   []string          `json:"columns"`
+			Sample    [][]string        `json:"sample"`
+			Mapping   map[string]string `json:"mapping"`
+			Delimiter string            `json:"delimiter"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"Product Name", "Price", "In Stock", "Qty", "Notes"}, response.Data.Columns)
+	assert.Len(t, response.Data.Sample, 4)
+	assert.Equal(t, ";", response.Data.Delimiter)
+	assert.Equal(t, map[string]string{"Price": "price", "In Stock": "in_stock"}, response.Data.Mapping)
+}
+
+func TestImportRun(t *testing.T) {
+	mapping := `{"Product Name":"name","Price":"price","In Stock":"in_stock","Qty":"quantity"}`
+
+	t.Run("dry run validates without creating", func(t *testing.T) {
+		r, db := setupImportTest(t)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, importRequest(t, "/products/import/run", importCSV, map[string]string{"mapping": mapping, "dryRun": "true"}))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			Data ImportResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Data.DryRun)
+		assert.Equal(t, 4, response.Data.Processed)
+		assert.Equal(t, 2, response.Data.Succeeded)
+		assert.Equal(t, 2, response.Data.Failed)
+		require.Len(t, response.Data.Errors, 2)
+		assert.Equal(t, ImportRowError{Row: 3, Column: "Price", Error: `invalid number "abc"`}, response.Data.Errors[0])
+		assert.Equal(t, 4, response.Data.Errors[1].Row)
+
+		var count int64
+		db.Model(&ImportProduct{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("creates records", func(t *testing.T) {
+		r, db := setupImportTest(t)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, importRequest(t, "/products/import/run", importCSV, map[string]string{"mapping": mapping}))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var products []ImportProduct
+		require.NoError(t, db.Order("id").Find(&products).Error)
+		require.Len(t, products, 2)
+		assert.Equal(t, "Apple", products[0].Name)
+		assert.Equal(t, 1.5, products[0].Price)
+		assert.True(t, products[0].InStock)
+		assert.Equal(t, 10, products[0].Quantity)
+		assert.Equal(t, "Plum", products[1].Name)
+	})
+
+	t.Run("streams progress events", func(t *testing.T) {
+		r, _ := setupImportTest(t)
+
+		req := importRequest(t, "/products/import/run", importCSV, map[string]string{"mapping": mapping, "dryRun": "true"})
+		req.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+		body := w.Body.String()
+		assert.Equal(t, 2, strings.Count(body, "event:rowError"))
+		assert.Contains(t, body, "event:complete")
+		assert.Contains(t, body, `"succeeded":2`)
+	})
+
+	t.Run("rejects invalid mapping", func(t *testing.T) {
+		r, _ := setupImportTest(t)
+
+		for _, m := range []string{"", `{"Price":"unknown"}`, `{"ID":"id"}`} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, importRequest(t, "/products/import/run", importCSV, map[string]string{"mapping": m}))
+			assert.Equal(t, http.StatusBadRequest, w.Code, m)
+		}
+	})
+}
+
+func TestConvertImportValue(t *testing.T) {
+	v, err := convertImportValue(jsonFieldType(reflect.TypeOf(ImportProduct{}), "created_at"), "2024-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T00:00:00Z", v)
+
+	_, err = convertImportValue(jsonFieldType(reflect.TypeOf(ImportProduct{}), "quantity"), "1.5")
+	assert.Error(t, err)
+
+	v, err = convertImportValue(nil, "text")
+	require.NoError(t, err)
+	assert.Equal(t, "text", v)
+
+	assert.Equal(t, "\t", detectDelimiter("a\tb\tc\n1,2\t3"))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type IncludeInvoice struct {
+	ID    uint                 `json:"id" gorm:"primaryKey"`
+	Lines []IncludeInvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID" relation:"resource=lines;type=one-to-many"`
+}
+
+type IncludeInvoiceLine struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	InvoiceID uint   `json:"invoiceId"`
+	Product   string `json:"product"`
+	Amount    int    `json:"amount"`
+}
+
+func TestIncludeModifiers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&IncludeInvoice{}, &IncludeInvoiceLine{}))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, db.Create(&IncludeInvoice{Lines: []IncludeInvoiceLine{
+			{Product: "pen", Amount: 5}, {Product: "ink", Amount: 20}, {Product: "paper", Amount: 10},
+		}}).Error)
+	}
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "invoices",
+		Model:      IncludeInvoice{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	get := func(path string) []IncludeInvoice {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var invoices []IncludeInvoice
+		if response.Data[0] != '[' {
+			var invoice IncludeInvoice
+			require.NoError(t, json.Unmarshal(response.Data, &invoice))
+			return append(invoices, invoice)
+		}
+		require.NoError(t, json.Unmarshal(response.Data, &invoices))
+		return invoices
+	}
+	include := "?include=" + url.QueryEscape("lines(sort=amount desc,limit=2)")
+
+	// Each invoice of the list gets its two largest lines
+	invoices := get("/api/invoices" + include)
+	require.Len(t, invoices, 2)
+	for _, invoice := range invoices {
+		require.Len(t, invoice.Lines, 2)
+		assert.Equal(t, "ink", invoice.Lines[0].Product)
+		assert.Equal(t, "paper", invoice.Lines[1].Product)
+	}
+
+	invoices = get("/api/invoices/1" + include)
+	require.Len(t, invoices[0].Lines, 2)
+	assert.Equal(t, "ink", invoices[0].Lines[0].Product)
+
+	// Relations are only loaded when included
+	assert.Empty(t, get("/api/invoices/1")[0].Lines)
+}
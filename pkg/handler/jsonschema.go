@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/jsonschema"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// JSONSchemaMiddleware validates the records in create and update request bodies
+// against the JSON Schemas of a resource: its body schema (resource.BodySchemaResource)
+// and the JSONSchema of its json fields. Invalid requests are rejected with a 400
+// listing every error with the JSON Pointer of the invalid value. Bulk updates only
+// carry the changed fields, so they are checked against the field schemas only.
+//
+// Schemas describe bodies as clients send them, before naming conventions and field
+// aliases are applied.
+func JSONSchemaMiddleware(res resource.Resource) gin.HandlerFunc {
+	var bodySchema *jsonschema.Schema
+	fieldSchemas := make(map[string]*jsonschema.Schema)
+	var compileErr error
+
+	if schemaRes, ok := res.(resource.BodySchemaResource); ok && schemaRes.GetBodySchema() != nil {
+		bodySchema, compileErr = jsonschema.Compile(schemaRes.GetBodySchema())
+	}
+	for _, field := range res.GetFields() {
+		if compileErr != nil {
+			break
+		}
+		if field.Json == nil || field.Json.JSONSchema == nil {
+			continue
+		}
+		var schema *jsonschema.Schema
+		if schema, compileErr = jsonschema.Compile(field.Json.JSONSchema); compileErr == nil {
+			fieldSchemas[normalizeBodyKey(field.Name)] = schema
+			fieldSchemas[normalizeBodyKey(field.APIName())] = schema
+		}
+	}
+
+	return func(c *gin.Context) {
+		if bodySchema == nil && len(fieldSchemas) == 0 && compileErr == nil {
+			c.Next()
+			return
+		}
+		rest := resourceBasePath(c.FullPath(), res.GetName())
+		if !hasRecordBody(c, rest) {
+			c.Next()
+			return
+		}
+		if compileErr != nil {
+			abortWithError(c, http.StatusInternalServerError, fmt.Errorf("Invalid JSON Schema: %w", compileErr))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var payload interface{}
+		if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &payload) != nil {
+			// Empty and malformed bodies are reported by the handler
+			c.Next()
+			return
+		}
+
+		var errs []jsonschema.Error
+		switch {
+		case rest == "batch" && c.Request.Method == http.MethodPost:
+			envelope, _ := payload.(map[string]interface{})
+			items, _ := envelope["values"].([]interface{})
+			for i, item := range items {
+				errs = append(errs, validateRecordSchemas(item, "/values/"+strconv.Itoa(i), bodySchema, fieldSchemas)...)
+			}
+		case rest == "batch":
+			envelope, _ := payload.(map[string]interface{})
+			errs = validateRecordSchemas(envelope["values"], "/values", nil, fieldSchemas)
+		case c.Request.Method == http.MethodPatch:
+			// Merge patches carry some of the fields only, so the body schema does not apply
+			errs = validateRecordSchemas(payload, "", nil, fieldSchemas)
+		default:
+			errs = validateRecordSchemas(body, "", bodySchema, fieldSchemas)
+		}
+
+		if len(errs) > 0 {
+			c.Abort()
+			apierror.WriteWith(c, apierror.New(http.StatusBadRequest, "JSON Schema validation failed: "+errs[0].Error()), errs[0],
+				gin.H{"schemaErrors": errs})
+			return
+		}
+		c.Next()
+	}
+}
+
+// validateRecordSchemas validates a record against the body schema and its json field
+// values against the field schemas. Instance paths are prefixed with the location of
+// the record in the body.
+func validateRecordSchemas(record interface{}, location string, bodySchema *jsonschema.Schema, fieldSchemas map[string]*jsonschema.Schema) []jsonschema.Error {
+	var errs []jsonschema.Error
+	if bodySchema != nil {
+		for _, err := range bodySchema.Validate(record) {
+			err.InstancePath = location + err.InstancePath
+			errs = append(errs, err)
+		}
+	}
+
+	var object map[string]interface{}
+	switch record := record.(type) {
+	case []byte:
+		_ = json.Unmarshal(record, &object)
+	case map[string]interface{}:
+		object = record
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		schema, ok := fieldSchemas[normalizeBodyKey(key)]
+		if !ok || object[key] == nil {
+			continue
+		}
+		for _, err := range schema.Validate(object[key]) {
+			err.InstancePath = location + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1") + err.InstancePath
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
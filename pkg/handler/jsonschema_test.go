@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SchemaService struct {
+	ID       uint            `json:"id" gorm:"primaryKey"`
+	Name     string          `json:"name"`
+	Settings json.RawMessage `json:"settings" gorm:"type:text"`
+}
+
+func TestJSONSchemaMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SchemaService{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "services",
+		Model: SchemaService{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "settings", Type: "json", Json: &resource.JsonConfig{JSONSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"port"},
+				"properties": map[string]interface{}{
+					"port": map[string]interface{}{"type": "integer", "maximum": 65535},
+				},
+			}}},
+		},
+		Operations: []resource.Operation{
+			resource.OperationCreate, resource.OperationUpdate, resource.OperationCreateMany, resource.OperationUpdateMany,
+		},
+		BodySchema: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"name"},
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{"type": "string", "minLength": 3},
+			},
+		},
+	})
+	r := gin.New()
+	RegisterResourceForRefine(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res), "id")
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	schemaErrors := func(w *httptest.ResponseRecorder) []string {
+		var response struct {
+			SchemaErrors []struct {
+				InstancePath string `json:"instancePath"`
+				Keyword      string `json:"keyword"`
+			} `json:"schemaErrors"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result := make([]string, len(response.SchemaErrors))
+		for i, err := range response.SchemaErrors {
+			result[i] = err.InstancePath + " " + err.Keyword
+		}
+		return result
+	}
+
+	w := request(http.MethodPost, "/api/services", `{"name":"api","settings":{"port":8080}}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = request(http.MethodPost, "/api/services", `{"name":"x","settings":{"port":70000}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `JSON Schema validation failed: /name: must be at least 3 characters long`)
+	assert.Equal(t, []string{"/name minLength", "/settings/port maximum"}, schemaErrors(w))
+
+	w = request(http.MethodPut, "/api/services/1", `{"settings":{}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{" required", "/settings required"}, schemaErrors(w))
+
+	w = request(http.MethodPost, "/api/services/batch", `{"values":[{"name":"web"},{"name":"db","settings":{"port":"x"}}]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"/values/1/name minLength", "/values/1/settings/port type"}, schemaErrors(w))
+
+	// Bulk updates are partial, so only field schemas apply
+	w = request(http.MethodPut, "/api/services/batch", `{"ids":[1],"values":{"settings":{"port":443}}}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = request(http.MethodPut, "/api/services/batch", `{"ids":[1],"values":{"settings":{"port":-1.5}}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"/values/settings/port type"}, schemaErrors(w))
+}
+
+func TestJSONSchemaMiddlewareInvalidSchema(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "services",
+		Model:      SchemaService{},
+		Operations: []resource.Operation{resource.OperationCreate},
+		BodySchema: map[string]interface{}{"$ref": "#/$defs/missing"},
+	})
+	r := gin.New()
+	r.POST("/services", JSONSchemaMiddleware(res), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/services", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid JSON Schema")
+}
+
+type OwnedSchemaService struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	OwnerID string `json:"ownerId"`
+	Name    string `json:"name"`
+}
+
+func TestJSONSchemaMiddlewareOwnerResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OwnedSchemaService{}))
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:       "services",
+		Model:      OwnedSchemaService{},
+		Operations: []resource.Operation{resource.OperationCreate},
+		BodySchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string", "minLength": 3}},
+		},
+	}), resource.DefaultOwnerConfig())
+	repo, err := repository.NewOwnerRepository(db, res)
+	require.NoError(t, err)
+	r := gin.New()
+	RegisterOwnerResource(r.Group("/api", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID"))), res, repo)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/services", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Owner-ID", "ann")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := create(`{"name":"x"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "JSON Schema validation failed")
+	assert.Equal(t, http.StatusCreated, create(`{"name":"api"}`).Code)
+}
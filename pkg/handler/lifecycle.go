@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// lifecycleHooks returns the lifecycle hooks of a resource, or empty hooks
+func lifecycleHooks(res resource.Resource) *resource.LifecycleHooks {
+	if hooked, ok := res.(resource.LifecycleHookResource); ok && hooked.GetHooks() != nil {
+		return hooked.GetHooks()
+	}
+	return &resource.LifecycleHooks{}
+}
+
+// withRecordID stores the ID of the record the request operates on for the lifecycle
+// hooks (see resource.RecordIDFromContext)
+func withRecordID(c *gin.Context, id interface{}) {
+	c.Request = c.Request.WithContext(resource.WithRecordID(c.Request.Context(), id))
+}
+
+// runBeforeHook runs a Before lifecycle hook, if set. Rejections are written to the
+// response with the status of a *resource.HookError or 422, and false is returned.
+func runBeforeHook(c *gin.Context, res resource.Resource, hook resource.LifecycleHook, data interface{}) bool {
+	if hook == nil {
+		return true
+	}
+	if err := hook(c.Request.Context(), res, data); err != nil {
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	return true
+}
+
+// runAfterHook runs an After lifecycle hook, if set. Errors are written to the
+// response as 500 Internal Server Error and false is returned.
+func runAfterHook(c *gin.Context, res resource.Resource, hook resource.LifecycleHook, data interface{}) bool {
+	if hook == nil {
+		return true
+	}
+	if err := hook(c.Request.Context(), res, data); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+// runHookForEach calls run for each record of a slice (pointers to struct elements, so
+// hooks can modify them) or for data itself if it is not a slice, stopping at the
+// first failure
+func runHookForEach(data interface{}, run func(item interface{}) bool) bool {
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Slice {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Slice {
+		return run(data)
+	}
+	for i := 0; i < value.Len(); i++ {
+		item := value.Index(i)
+		if item.Kind() == reflect.Struct && item.CanAddr() {
+			item = item.Addr()
+		}
+		if !run(item.Interface()) {
+			return false
+		}
+	}
+	return true
+}
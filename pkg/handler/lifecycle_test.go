@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type HookedNote struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&HookedNote{}))
+
+	var events []string
+	var updatedID, deletedID interface{}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "notes",
+		Model: HookedNote{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationCreate, resource.OperationUpdate, resource.OperationDelete,
+		},
+		Hooks: &resource.LifecycleHooks{
+			BeforeCreate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				note := data.(*HookedNote)
+				if note.Title == "" {
+					return resource.NewHookError(http.StatusBadRequest, "title is required")
+				}
+				note.Status = "draft"
+				events = append(events, "before create")
+				return nil
+			},
+			AfterCreate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				events = append(events, "after create "+data.(*HookedNote).Status)
+				return nil
+			},
+			BeforeUpdate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				updatedID, _ = resource.RecordIDFromContext(ctx)
+				if data.(map[string]interface{})["Status"] == "published" {
+					return assert.AnError
+				}
+				return nil
+			},
+			AfterUpdate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				events = append(events, "after update")
+				return nil
+			},
+			BeforeDelete: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				deletedID = data
+				return nil
+			},
+			AfterDelete: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				events = append(events, "after delete")
+				return nil
+			},
+			BeforeList: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				options := data.(*query.QueryOptions)
+				options.Filters = map[string]interface{}{"status": "draft"}
+				return nil
+			},
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Before hooks modify the record or reject the request
+	w := send(http.MethodPost, "/api/notes", `{"title":"first"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = send(http.MethodPost, "/api/notes", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "title is required")
+	require.NoError(t, db.Create(&HookedNote{Title: "archived", Status: "archived"}).Error)
+
+	var stored HookedNote
+	require.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "draft", stored.Status)
+
+	w = send(http.MethodPut, "/api/notes/1", `{"title":"renamed","status":"draft"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "1", updatedID)
+
+	// Plain errors reject requests with a 422
+	w = send(http.MethodPut, "/api/notes/1", `{"status":"published"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// BeforeList changes the query options
+	w = send(http.MethodGet, "/api/notes", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data  []HookedNote `json:"data"`
+		Total int64        `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "renamed", response.Data[0].Title)
+
+	w = send(http.MethodDelete, "/api/notes/1", "")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Equal(t, "1", deletedID)
+
+	assert.Equal(t, []string{"before create", "after create draft", "after update", "after delete"}, events)
+}
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
 
 	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/explain"
 	"github.com/suranig/refine-gin/pkg/query"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
@@ -14,10 +16,8 @@ import (
 
 // GenerateListHandler generates a handler for LIST operations
 func GenerateListHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
-	// Use default DTO provider if none is specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// Use the DTO provider of the resource
+	dtoProvider := dto.ForResource(res)
 
 	return generateListHandlerWithDTO(res, repo, dtoProvider)
 }
@@ -33,8 +33,18 @@ func generateListHandlerWithDTO(res resource.Resource, repo repository.Repositor
 		// Create query options
 		options := query.ParseQueryOptions(c, res)
 
+		// Run the BeforeList hook first, it may change the options
+		if !prepareListOptions(c, res, &options) {
+			return
+		}
+
 		// Generate ETag based on query parameters for cache validation
 		etag := utils.GenerateQueryETag(c.Request.URL.RawQuery)
+		if provider, ok := repo.(repository.ETagProvider); ok {
+			if versioned, err := provider.ETag(c.Request.Context(), c.Request.URL.RawQuery); err == nil {
+				etag = versioned
+			}
+		}
 		ifNoneMatch := c.GetHeader("If-None-Match")
 
 		// Check if client's cached version is still valid
@@ -43,40 +53,81 @@ func generateListHandlerWithDTO(res resource.Resource, repo repository.Repositor
 			return
 		}
 
-		// Call repository
-		data, total, err := repo.List(c.Request.Context(), options)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+		writeList(c, res, repo, dtoProvider, options, etag)
+	}
+}
+
+// writeList lists the records of the options and answers with them in refine's
+// format, their total and the page. A non-empty ETag sets cache headers.
+func writeList(c *gin.Context, res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider, options query.QueryOptions, etag string) {
+	// Call repository
+	data, total, err := repo.List(c.Request.Context(), options)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
 
-		// Transform models to DTOs if we have an array
-		if reflect.TypeOf(data).Kind() == reflect.Slice {
-			v := reflect.ValueOf(data)
-			dtoItems := make([]interface{}, 0, v.Len())
-			for i := 0; i < v.Len(); i++ {
-				item := v.Index(i).Interface()
-				dtoItem, err := dtoProvider.TransformFromModel(item)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Error transforming data: " + err.Error()})
-					return
-				}
-				dtoItems = append(dtoItems, dtoItem)
+	// Transform models to DTOs if we have an array (or a pointer to one, whose
+	// items are transformed as pointers)
+	if v := reflect.Indirect(reflect.ValueOf(data)); v.Kind() == reflect.Slice {
+		isPointer := reflect.TypeOf(data).Kind() == reflect.Ptr
+		dtoItems := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if isPointer && item.Kind() == reflect.Struct {
+				item = item.Addr()
 			}
-			data = dtoItems
+			dtoItem, err := dtoProvider.TransformFromModel(item.Interface())
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
+				return
+			}
+			dtoItems = append(dtoItems, dtoItem)
 		}
+		data = transformResponseItems(c, res, dtoItems)
+	}
+
+	// Keep the selected fields only (sparse fieldset)
+	if data, err = selectRecordFields(res, data, options.Fields); err != nil {
+		respondError(c, http.StatusInternalServerError, fmt.Errorf("Error selecting fields: %w", err))
+		return
+	}
 
-		// Set cache headers
+	// Set cache headers
+	if etag != "" {
 		utils.SetCacheHeaders(c.Writer, 60, etag, nil, []string{"Accept", "Accept-Encoding", "Authorization"})
+	}
+
+	// Return results in Refine.dev compatible format
+	c.JSON(http.StatusOK, gin.H{
+		"data":         data,
+		"total":        total,
+		"meta":         options.PaginationMeta(total),
+		"appliedQuery": options.AppliedQuery(c.Request.URL.Query()),
+	})
+}
+
+// prepareListOptions validates the sorts and filters of list and count requests, runs
+// the BeforeList hook, which may change their options, and records the options for
+// query explanations. false is returned once the request was rejected.
+func prepareListOptions(c *gin.Context, res resource.Resource, options *query.QueryOptions) bool {
+	if !validateListOptions(c, options) {
+		return false
+	}
+	if !runBeforeHook(c, res, lifecycleHooks(res).BeforeList, options) {
+		return false
+	}
+	explain.RecordOptions(c.Request.Context(), *options)
+	return true
+}
 
-		// Return results in Refine.dev compatible format
-		c.JSON(http.StatusOK, gin.H{
-			"data":  data,
-			"total": total,
-			"meta": gin.H{
-				"page":     options.Page,
-				"pageSize": options.PerPage,
-			},
-		})
+// validateListOptions checks the sorts and filters of a request against the sortable
+// and filterable fields (see query.QueryOptions.Validate), answering 400 when they are
+// rejected. false is returned once the request was rejected.
+func validateListOptions(c *gin.Context, options *query.QueryOptions) bool {
+	if err := options.Validate(); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return false
 	}
+	return true
 }
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// ListQueryRequest is the body of POST list queries (/:resource/query), for queries
+// too long for a URL, e.g. filters on hundreds of IDs. It holds the parameters of
+// refine's getList; omitted parameters keep the values of the URL and the defaults.
+type ListQueryRequest struct {
+	// Filters is a filter tree in the format of refine's CrudFilters, with "and" and
+	// "or" groups. Its filters must all match, along with the filters of the URL.
+	Filters []query.FilterNode `json:"filters,omitempty"`
+
+	// Sorters replace the sort of the URL; sorts by unknown fields are ignored
+	Sorters []query.SortOption `json:"sorters,omitempty"`
+
+	// Pagination selects the page
+	Pagination *ListQueryPagination `json:"pagination,omitempty"`
+
+	// Fields selects the returned fields (a sparse fieldset), like the fields parameter
+	Fields []string `json:"fields,omitempty"`
+
+	// Include lists the loaded relations, with the modifiers of the include parameter
+	// (e.g. "comments(sort:-created_at;limit:5)")
+	Include []string `json:"include,omitempty"`
+
+	// Search replaces the q parameter
+	Search string `json:"q,omitempty"`
+}
+
+// ListQueryPagination is the pagination of a ListQueryRequest
+type ListQueryPagination struct {
+	Current  int `json:"current"`
+	PageSize int `json:"pageSize"`
+}
+
+// UnmarshalJSON decodes the pagination with keys in any naming convention (pageSize,
+// page_size), as the naming convention middleware may convert the keys of bodies
+func (p *ListQueryPagination) UnmarshalJSON(data []byte) error {
+	var raw map[string]int
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = ListQueryPagination{}
+	for key, value := range raw {
+		switch normalizeBodyKey(key) {
+		case "current":
+			p.Current = value
+		case "pagesize":
+			p.PageSize = value
+		}
+	}
+	return nil
+}
+
+// GenerateListQueryHandler generates a handler for LIST operations whose parameters
+// are in a ListQueryRequest body. The records, the BeforeList hook and the response
+// are those of GET lists; responses are not cached.
+func GenerateListQueryHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
+	return GenerateListQueryHandlerWithDTO(res, repo, dto.ForResource(res))
+}
+
+// GenerateListQueryHandlerWithDTO generates a handler for LIST operations with a
+// ListQueryRequest body and DTO transformation
+func GenerateListQueryHandlerWithDTO(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ListQueryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		options := query.ParseQueryOptions(c, res)
+		req.Apply(res, &options)
+
+		if !prepareListOptions(c, res, &options) {
+			return
+		}
+		writeList(c, res, repo, dtoProvider, options, "")
+	}
+}
+
+// Apply sets the parameters of the request on list options
+func (req ListQueryRequest) Apply(res resource.Resource, options *query.QueryOptions) {
+	options.FilterTree = append(options.FilterTree, req.Filters...)
+	options.SetSorts(req.Sorters)
+	if req.Pagination != nil {
+		if req.Pagination.Current > 0 {
+			options.Page = req.Pagination.Current
+		}
+		if req.Pagination.PageSize > 0 {
+			options.PerPage = req.Pagination.PageSize
+			options.LimitPageSize()
+		}
+	}
+	if len(req.Fields) > 0 {
+		options.Fields = query.SelectFields(res, req.Fields)
+	}
+	if len(req.Include) > 0 {
+		options.Includes = query.ParseIncludeList(res, strings.Join(req.Include, ","))
+	}
+	if req.Search != "" {
+		options.Search = req.Search
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type QueriedTicket struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Priority int    `json:"priority"`
+}
+
+func TestListQueryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&QueriedTicket{}))
+	require.NoError(t, db.Create([]*QueriedTicket{
+		{Title: "a", Status: "open", Priority: 1},
+		{Title: "b", Status: "open", Priority: 5},
+		{Title: "c", Status: "closed", Priority: 5},
+		{Title: "d", Status: "open", Priority: 3},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "tickets",
+		Model:      QueriedTicket{},
+		Operations: []resource.Operation{resource.OperationList},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	list := func(target, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+	titles := func(response map[string]interface{}) []string {
+		var titles []string
+		data, _ := response["data"].([]interface{})
+		for _, item := range data {
+			titles = append(titles, item.(map[string]interface{})["title"].(string))
+		}
+		return titles
+	}
+
+	code, response := list("/api/tickets/query", `{
+		"filters": [{"operator": "or", "value": [
+			{"field": "status", "operator": "eq", "value": "closed"},
+			{"field": "priority", "operator": "in", "value": [1, 3]}
+		]}],
+		"sorters": [{"field": "priority", "order": "desc"}, {"field": "title", "order": "asc"}],
+		"pagination": {"current": 1, "pageSize": 2}
+	}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"c", "d"}, titles(response))
+	assert.Equal(t, float64(3), response["total"])
+	assert.Equal(t, map[string]interface{}{"page": float64(1), "pageSize": float64(2), "hasNextPage": true}, response["meta"])
+
+	// The response echoes the query the list ran with
+	applied := response["appliedQuery"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "priority", "order": "desc"},
+		map[string]interface{}{"field": "title", "order": "asc"},
+	}, applied["sorters"])
+	assert.Equal(t, map[string]interface{}{"current": float64(1), "pageSize": float64(2)}, applied["pagination"])
+	assert.Len(t, applied["filterTree"], 1)
+	assert.Nil(t, applied["ignored"])
+
+	_, response = list("/api/tickets/query?filter[stauts]=open&tz=UTC&limit=5", `{}`)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "limit", "reason": "unknown parameter"},
+		map[string]interface{}{"name": "stauts", "reason": "unknown filter field", "suggestion": "status"},
+	}, response["appliedQuery"].(map[string]interface{})["ignored"])
+
+	// Sparse fieldsets, and sorts by unknown fields are ignored
+	code, response = list("/api/tickets/query", `{"fields": ["title"], "sorters": [{"field": "title; DROP TABLE queried_tickets", "order": "desc"}]}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, titles(response))
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "title": "a"}, response["data"].([]interface{})[0])
+
+	// Parameters of the URL apply too
+	_, response = list("/api/tickets/query?filter[status]=open", `{"filters": [{"field": "priority", "operator": "gte", "value": 3}]}`)
+	assert.Equal(t, []string{"b", "d"}, titles(response))
+
+	code, _ = list("/api/tickets/query", `{"filters": [{"operator": "eq"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
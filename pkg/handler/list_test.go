@@ -41,6 +41,8 @@ func TestGenerateListHandler(t *testing.T) {
 	mockResource.On("GetModel").Return(TestItem{}).Maybe()
 	mockResource.On("GetIDFieldName").Return("ID").Maybe()
 	mockResource.On("GetSearchable").Return([]string{"Name"}).Maybe()
+	mockResource.On("GetFilterableFields").Return([]string{}).Maybe()
+	mockResource.On("GetField", mock.Anything).Return(nil).Maybe()
 	mockResource.On("GetDefaultSort").Return(nil).Maybe()
 	mockResource.On("GetFilters").Return([]resource.Filter{}).Maybe()
 	mockResource.On("GetFields").Return([]resource.Field{
@@ -237,6 +239,8 @@ func TestGenerateListHandlerWithDTO(t *testing.T) {
 	mockResource.On("GetModel").Return(TestItem{}).Maybe()
 	mockResource.On("GetIDFieldName").Return("ID").Maybe()
 	mockResource.On("GetSearchable").Return([]string{"Name"}).Maybe()
+	mockResource.On("GetFilterableFields").Return([]string{}).Maybe()
+	mockResource.On("GetField", mock.Anything).Return(nil).Maybe()
 	mockResource.On("GetDefaultSort").Return(nil).Maybe()
 	mockResource.On("GetFilters").Return([]resource.Filter{}).Maybe()
 	mockResource.On("GetFields").Return([]resource.Field{
@@ -337,3 +341,53 @@ func TestGenerateListHandlerWithDTO(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestGenerateListHandlerWithoutCount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "events", Model: TestItem{}, CountMode: resource.CountNone})
+
+	// Without a count, the repository returns a lower bound of the total
+	repo := new(MockRepository)
+	repo.On("List", mock.Anything, mock.MatchedBy(func(options query.QueryOptions) bool {
+		return options.CountMode == resource.CountNone
+	})).Return([]TestItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, int64(3), nil).Once()
+	repo.On("List", mock.Anything, mock.MatchedBy(func(options query.QueryOptions) bool {
+		return options.CountMode == resource.CountExact
+	})).Return([]TestItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, int64(2), nil).Once()
+
+	router := gin.New()
+	router.GET("/events", GenerateListHandler(res, repo))
+
+	list := func(url string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	meta := list("/events?pageSize=2")["meta"].(map[string]interface{})
+	assert.Equal(t, true, meta["hasNextPage"])
+	assert.Equal(t, "none", meta["countMode"])
+
+	meta = list("/events?pageSize=2&withCount=true")["meta"].(map[string]interface{})
+	assert.Equal(t, false, meta["hasNextPage"])
+	assert.NotContains(t, meta, "countMode")
+	repo.AssertExpectations(t)
+}
+
+func TestGenerateListHandlerInvalidTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "events", Model: TestItem{}})
+	repo := new(MockRepository)
+
+	router := gin.New()
+	router.GET("/events", GenerateListHandler(res, repo))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events?tz=Mars/Olympus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid timezone")
+	repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
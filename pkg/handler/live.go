@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// Live event types, as expected by refine's liveProvider
+const (
+	LiveEventCreated = "created"
+	LiveEventUpdated = "updated"
+	LiveEventDeleted = "deleted"
+)
+
+// DefaultLiveKeepAlive is the default interval of the keep-alive comments sent on idle
+// live streams
+const DefaultLiveKeepAlive = 30 * time.Second
+
+// LivePayload identifies the records changed by a live event
+type LivePayload struct {
+	IDs []interface{} `json:"ids"`
+}
+
+// LiveEvent is a change of one or more records of a resource. Only the IDs are sent,
+// clients refetch the records they display.
+type LiveEvent struct {
+	Channel string      `json:"channel"`
+	Type    string      `json:"type"`
+	Payload LivePayload `json:"payload"`
+	Date    time.Time   `json:"date"`
+}
+
+// LiveChannel returns the channel of a resource, "resources/<name>" like refine's
+// default channel names
+func LiveChannel(resourceName string) string {
+	return "resources/" + resourceName
+}
+
+// LiveBroker fans out live events to the subscribers of each channel
+type LiveBroker struct {
+	// KeepAlive is the interval of keep-alive comments on idle streams
+	// (default DefaultLiveKeepAlive)
+	KeepAlive time.Duration
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan LiveEvent]struct{}
+}
+
+// NewLiveBroker creates a new live event broker
+func NewLiveBroker() *LiveBroker {
+	return &LiveBroker{
+		KeepAlive:   DefaultLiveKeepAlive,
+		subscribers: make(map[string]map[chan LiveEvent]struct{}),
+	}
+}
+
+// Subscribe returns the events of a channel until the returned function is called
+func (b *LiveBroker) Subscribe(channel string) (<-chan LiveEvent, func()) {
+	events := make(chan LiveEvent, 16)
+
+	b.mu.Lock()
+	if b.subscribers[channel] == nil {
+		b.subscribers[channel] = make(map[chan LiveEvent]struct{})
+	}
+	b.subscribers[channel][events] = struct{}{}
+	b.mu.Unlock()
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[channel], events)
+		if len(b.subscribers[channel]) == 0 {
+			delete(b.subscribers, channel)
+		}
+	}
+}
+
+// Publish sends an event to the subscribers of its channel. Subscribers too slow to
+// keep up miss the event rather than blocking the mutation.
+func (b *LiveBroker) Publish(event LiveEvent) {
+	if event.Date.IsZero() {
+		event.Date = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers[event.Channel] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// RegisterLiveProvider registers GET /<resource>/live, a server-sent events stream of
+// the created, updated and deleted events of the resource. Events are published by
+// repositories wrapped with NewLiveRepository. Browsers' EventSource can subscribe
+// directly; WebSocket is not supported.
+func RegisterLiveProvider(router *gin.RouterGroup, res resource.Resource, broker *LiveBroker) {
+	router.GET("/"+res.GetName()+"/live", GenerateLiveHandler(res, broker))
+}
+
+// GenerateLiveHandler creates a handler streaming the live events of a resource. Each
+// event is sent with its type as event name and the LiveEvent as data.
+func GenerateLiveHandler(res resource.Resource, broker *LiveBroker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.CanPerform(res, resource.OperationList, auth.UserRoles(c)) {
+			respondErrorMessage(c, http.StatusForbidden, "Forbidden: insufficient permissions for this resource")
+			return
+		}
+
+		events, unsubscribe := broker.Subscribe(LiveChannel(res.GetName()))
+		defer unsubscribe()
+
+		utils.DisableCaching(c.Writer)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		keepAlive := broker.KeepAlive
+		if keepAlive <= 0 {
+			keepAlive = DefaultLiveKeepAlive
+		}
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-ticker.C:
+				if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			case event := <-events:
+				c.SSEvent(event.Type, event)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// LiveRepository decorates a repository, publishing a live event after every
+// successful create, update and delete (including bulk operations) of a resource.
+// Changes made in a transaction are published once it commits.
+type LiveRepository struct {
+	repository.Repository
+
+	Resource resource.Resource
+	Broker   *LiveBroker
+
+	// publish replaces Broker.Publish inside transactions
+	publish func(event LiveEvent)
+}
+
+// NewLiveRepository creates a repository publishing live events
+func NewLiveRepository(repo repository.Repository, res resource.Resource, broker *LiveBroker) *LiveRepository {
+	return &LiveRepository{Repository: repo, Resource: res, Broker: broker}
+}
+
+// Create creates a resource and publishes a created event
+func (r *LiveRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.Create(ctx, data)
+	if err != nil {
+		return created, err
+	}
+	r.notify(LiveEventCreated, r.recordID(created))
+	return created, nil
+}
+
+// Update updates a resource and publishes an updated event
+func (r *LiveRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	updated, err := r.Repository.Update(ctx, id, data)
+	if err != nil {
+		return updated, err
+	}
+	r.notify(LiveEventUpdated, id)
+	return updated, nil
+}
+
+// Delete deletes a resource and publishes a deleted event
+func (r *LiveRepository) Delete(ctx context.Context, id interface{}) error {
+	if err := r.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.notify(LiveEventDeleted, id)
+	return nil
+}
+
+// CreateMany creates resources and publishes one created event with all their IDs
+func (r *LiveRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.CreateMany(ctx, data)
+	if err != nil {
+		return created, err
+	}
+
+	var ids []interface{}
+	items := reflect.Indirect(reflect.ValueOf(created))
+	if items.Kind() == reflect.Slice {
+		for i := 0; i < items.Len(); i++ {
+			ids = append(ids, r.recordID(items.Index(i).Interface()))
+		}
+	}
+	r.notify(LiveEventCreated, ids...)
+	return created, nil
+}
+
+// CreateManyPartial creates the items if the decorated repository supports partial
+// bulk creates, and publishes a created event with the IDs of the created ones
+func (r *LiveRepository) CreateManyPartial(ctx context.Context, items []interface{}) ([]repository.BulkItemResult, error) {
+	creator, ok := r.Repository.(repository.PartialBulkCreator)
+	if !ok {
+		return nil, repository.ErrNotSupported
+	}
+	results, err := creator.CreateManyPartial(ctx, items)
+	if err != nil {
+		return results, err
+	}
+
+	var ids []interface{}
+	for _, result := range results {
+		if !result.Failed() {
+			ids = append(ids, result.ID)
+		}
+	}
+	r.notify(LiveEventCreated, ids...)
+	return results, nil
+}
+
+// UpdateMany updates resources and publishes one updated event with their IDs
+func (r *LiveRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	affected, err := r.Repository.UpdateMany(ctx, ids, data)
+	if err != nil {
+		return affected, err
+	}
+	r.notify(LiveEventUpdated, ids...)
+	return affected, nil
+}
+
+// DeleteMany deletes resources and publishes one deleted event with their IDs
+func (r *LiveRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	affected, err := r.Repository.DeleteMany(ctx, ids)
+	if err != nil {
+		return affected, err
+	}
+	r.notify(LiveEventDeleted, ids...)
+	return affected, nil
+}
+
+// WithRelations returns a live repository that preloads relations
+func (r *LiveRepository) WithRelations(relations ...string) repository.Repository {
+	return &LiveRepository{Repository: r.Repository.WithRelations(relations...), Resource: r.Resource, Broker: r.Broker, publish: r.publish}
+}
+
+// WithTransaction runs fn in a transaction with a live transactional repository. Its
+// events are held back until the transaction commits and dropped on rollback.
+func (r *LiveRepository) WithTransaction(fn func(repository.Repository) error) error {
+	var pending []LiveEvent
+	err := r.Repository.WithTransaction(func(tx repository.Repository) error {
+		return fn(&LiveRepository{
+			Repository: tx,
+			Resource:   r.Resource,
+			Broker:     r.Broker,
+			publish:    func(event LiveEvent) { pending = append(pending, event) },
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range pending {
+		r.emit(event)
+	}
+	return nil
+}
+
+// recordID returns the ID of a record
+func (r *LiveRepository) recordID(record interface{}) interface{} {
+	id, err := utils.GetFieldValue(record, r.Resource.GetIDFieldName())
+	if err != nil {
+		return nil
+	}
+	return id
+}
+
+// notify publishes an event for the changed records
+func (r *LiveRepository) notify(eventType string, ids ...interface{}) {
+	if len(ids) == 0 {
+		return
+	}
+	r.emit(LiveEvent{
+		Channel: LiveChannel(r.Resource.GetName()),
+		Type:    eventType,
+		Payload: LivePayload{IDs: ids},
+		Date:    time.Now(),
+	})
+}
+
+// emit publishes an event, or holds it back inside a transaction
+func (r *LiveRepository) emit(event LiveEvent) {
+	if r.publish != nil {
+		r.publish(event)
+		return
+	}
+	r.Broker.Publish(event)
+}
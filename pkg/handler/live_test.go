@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type LiveNote struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Text string `json:"text"`
+}
+
+func newLiveNotes(t *testing.T) (resource.Resource, repository.Repository) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&LiveNote{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "notes",
+		Model: LiveNote{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationCreate, resource.OperationUpdate, resource.OperationDelete,
+		},
+	})
+	return res, repository.NewGenericRepositoryWithResource(db, res)
+}
+
+func receiveLiveEvent(t *testing.T, events <-chan LiveEvent) LiveEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no live event received")
+		return LiveEvent{}
+	}
+}
+
+func TestLiveBroker(t *testing.T) {
+	broker := NewLiveBroker()
+	events, unsubscribe := broker.Subscribe(LiveChannel("notes"))
+	other, unsubscribeOther := broker.Subscribe(LiveChannel("tags"))
+	defer unsubscribeOther()
+
+	broker.Publish(LiveEvent{Channel: LiveChannel("notes"), Type: LiveEventCreated, Payload: LivePayload{IDs: []interface{}{1}}})
+	event := receiveLiveEvent(t, events)
+	assert.Equal(t, "resources/notes", event.Channel)
+	assert.False(t, event.Date.IsZero())
+	assert.Empty(t, other)
+
+	unsubscribe()
+	broker.Publish(LiveEvent{Channel: LiveChannel("notes"), Type: LiveEventDeleted})
+	assert.Empty(t, events)
+}
+
+func TestLiveRepository(t *testing.T) {
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	res, repo := newLiveNotes(t)
+	broker := NewLiveBroker()
+	live := NewLiveRepository(repo, res, broker)
+	events, unsubscribe := broker.Subscribe(LiveChannel("notes"))
+	defer unsubscribe()
+	ctx := context.Background()
+
+	_, err := live.Create(ctx, &LiveNote{Text: "first"})
+	require.NoError(t, err)
+	event := receiveLiveEvent(t, events)
+	assert.Equal(t, LiveEventCreated, event.Type)
+	assert.Equal(t, []interface{}{uint(1)}, event.Payload.IDs)
+
+	_, err = live.Update(ctx, uint(1), &LiveNote{ID: 1, Text: "changed"})
+	require.NoError(t, err)
+	assert.Equal(t, LiveEventUpdated, receiveLiveEvent(t, events).Type)
+
+	_, err = live.CreateMany(ctx, []LiveNote{{Text: "a"}, {Text: "b"}})
+	require.NoError(t, err)
+	event = receiveLiveEvent(t, events)
+	assert.Equal(t, LiveEventCreated, event.Type)
+	assert.Len(t, event.Payload.IDs, 2)
+
+	// Failed mutations publish nothing
+	_, err = live.Update(ctx, uint(99), &LiveNote{ID: 99, Text: "missing"})
+	require.Error(t, err)
+	assert.Empty(t, events)
+
+	// Rolled back transactions publish nothing, committed ones publish after commit
+	err = live.WithTransaction(func(tx repository.Repository) error {
+		_, err := tx.Create(ctx, &LiveNote{Text: "rolled back"})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, events)
+
+	err = live.WithTransaction(func(tx repository.Repository) error {
+		return tx.Delete(ctx, uint(1))
+	})
+	require.NoError(t, err)
+	event = receiveLiveEvent(t, events)
+	assert.Equal(t, LiveEventDeleted, event.Type)
+	assert.Equal(t, []interface{}{uint(1)}, event.Payload.IDs)
+}
+
+func TestLiveProviderStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	res, repo := newLiveNotes(t)
+	broker := NewLiveBroker()
+	r := gin.New()
+	api := r.Group("/api")
+	RegisterResource(api, res, NewLiveRepository(repo, res, broker))
+	RegisterLiveProvider(api, res, broker)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/notes/live", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	created, err := http.Post(server.URL+"/api/notes", "application/json", strings.NewReader(`{"text":"hello"}`))
+	require.NoError(t, err)
+	created.Body.Close()
+	require.Equal(t, http.StatusCreated, created.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, "event:created", lines[0])
+	assert.Contains(t, lines[1], `"channel":"resources/notes"`)
+	assert.Contains(t, lines[1], `"ids":[1]`)
+}
+
+func TestLiveProviderForbidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:        "notes",
+		Model:       LiveNote{},
+		Operations:  []resource.Operation{resource.OperationList},
+		Permissions: map[string][]string{string(resource.OperationList): {"admin"}},
+	})
+	r := gin.New()
+	RegisterLiveProvider(r.Group("/api"), res, NewLiveBroker())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/notes/live", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -2,31 +2,47 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/suranig/refine-gin/pkg/dto"
 	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
 )
 
 // BulkCreateRequest is the request structure for creating multiple resources
 type BulkCreateRequest struct {
 	Values interface{} `json:"values"`
+
+	// Partial creates the valid items even if others fail (also enabled with
+	// ?partial=true) and responds with per-item results
+	Partial bool `json:"partial"`
 }
 
 // BulkUpdateRequest is the request structure for updating multiple resources
 type BulkUpdateRequest struct {
 	IDs    interface{} `json:"ids"`
 	Values interface{} `json:"values"`
+
+	// Partial updates each record on its own (also enabled with ?partial=true) and
+	// responds with per-item results
+	Partial bool `json:"partial"`
 }
 
 // BulkDeleteRequest is the request structure for deleting multiple resources
 type BulkDeleteRequest struct {
 	IDs interface{} `json:"ids"`
+
+	// Partial deletes each record on its own (also enabled with ?partial=true) and
+	// responds with per-item results
+	Partial bool `json:"partial"`
 }
 
 // BulkResponse is the common response structure for bulk operations
@@ -34,19 +50,95 @@ type BulkResponse struct {
 	Data interface{} `json:"data"`
 }
 
-// GenerateCreateManyHandler generates a handler for bulk create operations
+// BulkCreateSummary counts the outcomes of a partial bulk create
+type BulkCreateSummary struct {
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// BulkCreateResponse is the response of a partial bulk create: one result per
+// request item, in order
+type BulkCreateResponse struct {
+	Data []repository.BulkItemResult `json:"data"`
+	Meta BulkCreateSummary           `json:"meta"`
+}
+
+// BulkUpdateSummary counts the outcomes of a partial bulk update
+type BulkUpdateSummary struct {
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+// BulkUpdateResponse is the response of a partial bulk update: one result per ID, in
+// order
+type BulkUpdateResponse struct {
+	Data []repository.BulkItemResult `json:"data"`
+	Meta BulkUpdateSummary           `json:"meta"`
+}
+
+// BulkDeleteSummary counts the outcomes of a partial bulk delete
+type BulkDeleteSummary struct {
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+}
+
+// BulkDeleteResponse is the response of a partial bulk delete: one result per ID, in
+// order
+type BulkDeleteResponse struct {
+	Data []repository.BulkItemResult `json:"data"`
+	Meta BulkDeleteSummary           `json:"meta"`
+}
+
+// partialMode reports whether a bulk request runs in partial mode, requested in the
+// body or with ?partial=true
+func partialMode(c *gin.Context, requested bool) bool {
+	partial, _ := strconv.ParseBool(c.Query("partial"))
+	return partial || requested
+}
+
+// multiStatus returns the status of a partial bulk response: success if every item
+// succeeded, 207 if some failed and 422 if all failed
+func multiStatus(success, succeeded, failed int) int {
+	switch {
+	case failed == 0:
+		return success
+	case succeeded == 0:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// runItemHook runs a lifecycle hook, if set, for one item of a partial bulk operation.
+// Unlike runBeforeHook and runAfterHook it returns the error, which fails the item
+// instead of the request.
+func runItemHook(c *gin.Context, res resource.Resource, hook resource.LifecycleHook, data interface{}) error {
+	if hook == nil {
+		return nil
+	}
+	return hook(c.Request.Context(), res, data)
+}
+
+// GenerateCreateManyHandler generates a handler for bulk create operations. By default
+// the items are created together and one failing item fails the whole request; in
+// partial mode each item succeeds or fails on its own (see createManyPartial).
 func GenerateCreateManyHandler(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse request
 		var req BulkCreateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Check if values is a slice
 		if !resource.IsSlice(req.Values) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "values must be an array"})
+			respondErrorMessage(c, http.StatusBadRequest, "values must be an array")
+			return
+		}
+
+		if partialMode(c, req.Partial) {
+			createManyPartial(c, res, repo, dtoProvider, req.Values)
 			return
 		}
 
@@ -56,7 +148,7 @@ func GenerateCreateManyHandler(res resource.Resource, repo repository.Repository
 		if dtoProvider != nil {
 			modelData, err = dtoProvider.TransformToModel(req.Values)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 		} else {
@@ -80,16 +172,29 @@ func GenerateCreateManyHandler(res resource.Resource, repo repository.Repository
 
 				// Validate relations before saving
 				if err := resource.ValidateRelations(db, item); err != nil {
-					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Item %d: %s", i, err.Error())})
+					respondErrorMessage(c, http.StatusBadRequest, fmt.Sprintf("Item %d: %s", i, err.Error()))
 					return
 				}
 			}
 		}
 
+		hooks := lifecycleHooks(res)
+		if !runHookForEach(modelData, func(item interface{}) bool {
+			return runBeforeHook(c, res, hooks.BeforeCreate, item)
+		}) {
+			return
+		}
+
 		// Call repository method
 		result, err := repo.CreateMany(c, modelData)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runHookForEach(result, func(item interface{}) bool {
+			return runAfterHook(c, res, hooks.AfterCreate, item)
+		}) {
 			return
 		}
 
@@ -98,7 +203,7 @@ func GenerateCreateManyHandler(res resource.Resource, repo repository.Repository
 		if dtoProvider != nil {
 			responseData, err = dtoProvider.TransformFromModel(result)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				respondError(c, http.StatusInternalServerError, err)
 				return
 			}
 		} else {
@@ -113,13 +218,123 @@ func GenerateCreateManyHandler(res resource.Resource, repo repository.Repository
 	}
 }
 
-// GenerateUpdateManyHandler generates a handler for bulk update operations
+// createManyPartial decodes, validates and creates each item on its own and responds
+// with per-item results: 201 if all items were created, 207 if some failed and 422 if
+// all failed. Repositories implementing repository.PartialBulkCreator create the
+// valid items in one transaction with a savepoint per item; others create them one
+// by one.
+func createManyPartial(c *gin.Context, res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider, values interface{}) {
+	ctx := c.Request.Context()
+	if dtoProvider == nil {
+		dtoProvider = dto.ForResource(res)
+	}
+	db := repo.Query(ctx)
+
+	items := reflect.ValueOf(values)
+	results := make([]repository.BulkItemResult, items.Len())
+	var models []interface{}
+	var indexes []int
+	for i := 0; i < items.Len(); i++ {
+		results[i] = repository.BulkItemResult{Index: i}
+		model, err := bulkItemModel(res, dtoProvider, db, items.Index(i).Interface())
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		models = append(models, model)
+		indexes = append(indexes, i)
+	}
+
+	created, err := createBulkItems(c, res, repo, models)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	summary := BulkCreateSummary{}
+	for i, result := range created {
+		result.Index = indexes[i]
+		if !result.Failed() {
+			if result.Data, err = dtoProvider.TransformFromModel(result.Data); err != nil {
+				respondError(c, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		results[result.Index] = result
+	}
+	for _, result := range results {
+		if result.Failed() {
+			summary.Failed++
+		} else {
+			summary.Created++
+		}
+	}
+
+	utils.DisableCaching(c.Writer)
+	c.JSON(multiStatus(http.StatusCreated, summary.Created, summary.Failed), BulkCreateResponse{Data: results, Meta: summary})
+}
+
+// bulkItemModel converts a request item into a validated model through the create DTO
+func bulkItemModel(res resource.Resource, dtoProvider dto.DTOProvider, db *gorm.DB, item interface{}) (interface{}, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	dtoInstance := dtoProvider.GetCreateDTO()
+	if err := json.Unmarshal(data, dtoInstance); err != nil {
+		return nil, err
+	}
+	if err := binding.Validator.ValidateStruct(dtoInstance); err != nil {
+		return nil, err
+	}
+
+	model, err := dtoProvider.TransformToModel(dtoInstance)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil && len(res.GetRelations()) > 0 {
+		if err := resource.ValidateRelations(db, model); err != nil {
+			return nil, err
+		}
+	}
+	return model, nil
+}
+
+// createBulkItems creates the models with the repository's partial bulk create, or
+// one by one if the repository does not support it
+func createBulkItems(c *gin.Context, res resource.Resource, repo repository.Repository, models []interface{}) ([]repository.BulkItemResult, error) {
+	if len(models) == 0 {
+		return nil, nil
+	}
+	if creator, ok := repo.(repository.PartialBulkCreator); ok {
+		results, err := creator.CreateManyPartial(c.Request.Context(), models)
+		if !errors.Is(err, repository.ErrNotSupported) {
+			return results, err
+		}
+	}
+
+	results := make([]repository.BulkItemResult, len(models))
+	for i, model := range models {
+		created, err := repo.Create(c.Request.Context(), model)
+		if err != nil {
+			results[i] = repository.BulkItemResult{Index: i, Error: err.Error()}
+			continue
+		}
+		id, _ := utils.GetFieldValue(created, res.GetIDFieldName())
+		results[i] = repository.BulkItemResult{Index: i, ID: id, Data: created}
+	}
+	return results, nil
+}
+
+// GenerateUpdateManyHandler generates a handler for bulk update operations. By default
+// the records are updated with one statement, all or none; in partial mode each record
+// is updated on its own (see updateManyPartial).
 func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse request
 		var req BulkUpdateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -133,7 +348,7 @@ func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository
 			// Convert to JSON and back to ensure it's a slice
 			jsonData, err := json.Marshal(v)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IDs format"})
+				respondErrorMessage(c, http.StatusBadRequest, "Invalid IDs format")
 				return
 			}
 
@@ -142,7 +357,12 @@ func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository
 				ids = []interface{}{v}
 			}
 		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "IDs must be an array or a single value"})
+			respondErrorMessage(c, http.StatusBadRequest, "IDs must be an array or a single value")
+			return
+		}
+
+		if partialMode(c, req.Partial) {
+			updateManyPartial(c, res, repo, dtoProvider, ids, req.Values)
 			return
 		}
 
@@ -156,19 +376,19 @@ func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository
 			// Convert values to DTO type
 			jsonData, err := json.Marshal(req.Values)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 
 			if err := json.Unmarshal(jsonData, &updateDTO); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 
 			// Transform to model
 			modelData, err = dtoProvider.TransformToModel(updateDTO)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 		} else {
@@ -186,15 +406,25 @@ func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository
 		if db != nil && len(res.GetRelations()) > 0 {
 			// Validate relations before save
 			if err := resource.ValidateRelations(db, modelData); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				respondError(c, http.StatusBadRequest, err)
 				return
 			}
 		}
 
+		hooks := lifecycleHooks(res)
+		withRecordID(c, ids)
+		if !runBeforeHook(c, res, hooks.BeforeUpdate, modelData) {
+			return
+		}
+
 		// Call repository method
 		count, err := repo.UpdateMany(c, ids, modelData)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterUpdate, modelData) {
 			return
 		}
 
@@ -210,13 +440,15 @@ func GenerateUpdateManyHandler(res resource.Resource, repo repository.Repository
 	}
 }
 
-// GenerateDeleteManyHandler generates a handler for bulk delete operations
+// GenerateDeleteManyHandler generates a handler for bulk delete operations. By default
+// the records are deleted with one statement, all or none; in partial mode each record
+// is deleted on its own (see deleteManyPartial).
 func GenerateDeleteManyHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse request
 		var req BulkDeleteRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -229,7 +461,7 @@ func GenerateDeleteManyHandler(res resource.Resource, repo repository.Repository
 			// Convert to JSON and back to ensure it's a slice
 			jsonData, err := json.Marshal(v)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IDs format"})
+				respondErrorMessage(c, http.StatusBadRequest, "Invalid IDs format")
 				return
 			}
 
@@ -238,14 +470,29 @@ func GenerateDeleteManyHandler(res resource.Resource, repo repository.Repository
 				ids = []interface{}{v}
 			}
 		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "IDs must be an array or a single value"})
+			respondErrorMessage(c, http.StatusBadRequest, "IDs must be an array or a single value")
+			return
+		}
+
+		if partialMode(c, req.Partial) {
+			deleteManyPartial(c, res, repo, ids)
+			return
+		}
+
+		hooks := lifecycleHooks(res)
+		withRecordID(c, ids)
+		if !runBeforeHook(c, res, hooks.BeforeDelete, ids) {
 			return
 		}
 
 		// Call repository method
 		count, err := repo.DeleteMany(c, ids)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterDelete, ids) {
 			return
 		}
 
@@ -260,3 +507,124 @@ func GenerateDeleteManyHandler(res resource.Resource, repo repository.Repository
 		})
 	}
 }
+
+// updateManyPartial applies the same values to each record on its own and responds
+// with per-item results: 200 if all records were updated, 207 if some failed and 422
+// if all failed. The values are validated once for the whole request; missing records,
+// version conflicts and hook rejections fail their item only.
+func updateManyPartial(c *gin.Context, res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider, ids []interface{}, values interface{}) {
+	model, err := bulkUpdateModel(res, dtoProvider, values)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if db := repo.Query(c.Request.Context()); db != nil && len(res.GetRelations()) > 0 {
+		if err := resource.ValidateRelations(db, model); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	hooks := lifecycleHooks(res)
+	results := make([]repository.BulkItemResult, len(ids))
+	summary := BulkUpdateSummary{}
+	for i, id := range ids {
+		results[i] = repository.BulkItemResult{Index: i, ID: id}
+		if i > 0 {
+			// Repositories may set the ID on the model, so every record gets its own
+			if model, err = bulkUpdateModel(res, dtoProvider, values); err != nil {
+				respondError(c, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		withRecordID(c, id)
+		if err := runItemHook(c, res, hooks.BeforeUpdate, model); err != nil {
+			results[i].Error = err.Error()
+			summary.Failed++
+			continue
+		}
+		updated, err := repo.Update(c.Request.Context(), id, model)
+		if err == nil {
+			err = runItemHook(c, res, hooks.AfterUpdate, updated)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			summary.Failed++
+			continue
+		}
+
+		results[i].Data = updated
+		if dtoProvider != nil {
+			if results[i].Data, err = dtoProvider.TransformFromModel(updated); err != nil {
+				respondError(c, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		summary.Updated++
+	}
+
+	utils.DisableCaching(c.Writer)
+	c.JSON(multiStatus(http.StatusOK, summary.Updated, summary.Failed), BulkUpdateResponse{Data: results, Meta: summary})
+}
+
+// bulkUpdateModel converts the values of a bulk update into a model through the update
+// DTO, or into a map of the updated fields without a DTO provider, like the default
+// mode. Read-only fields are removed.
+func bulkUpdateModel(res resource.Resource, dtoProvider dto.DTOProvider, values interface{}) (interface{}, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	if dtoProvider == nil {
+		var updates map[string]interface{}
+		if err := json.Unmarshal(data, &updates); err != nil {
+			return nil, err
+		}
+		return resource.FilterOutReadOnlyFields(updates, res), nil
+	}
+
+	dtoInstance := dtoProvider.GetUpdateDTO()
+	if err := json.Unmarshal(data, dtoInstance); err != nil {
+		return nil, err
+	}
+	model, err := dtoProvider.TransformToModel(dtoInstance)
+	if err != nil {
+		return nil, err
+	}
+	return resource.FilterOutReadOnlyFields(model, res), nil
+}
+
+// deleteManyPartial deletes each record on its own and responds with per-item results:
+// 200 if all records were deleted, 207 if some failed and 422 if all failed. Records
+// the repository does not return, e.g. of other owners, fail with "record not found".
+func deleteManyPartial(c *gin.Context, res resource.Resource, repo repository.Repository, ids []interface{}) {
+	hooks := lifecycleHooks(res)
+	results := make([]repository.BulkItemResult, len(ids))
+	summary := BulkDeleteSummary{}
+	for i, id := range ids {
+		results[i] = repository.BulkItemResult{Index: i, ID: id}
+
+		withRecordID(c, id)
+		// Deletes of missing records succeed, so they are looked up first
+		_, err := repo.Get(c.Request.Context(), id)
+		if err == nil {
+			err = runItemHook(c, res, hooks.BeforeDelete, id)
+		}
+		if err == nil {
+			err = repo.Delete(c.Request.Context(), id)
+		}
+		if err == nil {
+			err = runItemHook(c, res, hooks.AfterDelete, id)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			summary.Failed++
+			continue
+		}
+		summary.Deleted++
+	}
+
+	utils.DisableCaching(c.Writer)
+	c.JSON(multiStatus(http.StatusOK, summary.Deleted, summary.Failed), BulkDeleteResponse{Data: results, Meta: summary})
+}
@@ -12,7 +12,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/repository"
 	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -205,7 +209,7 @@ func TestCreateManyHandler_Error(t *testing.T) {
 	// Check response indicates error
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Contains(t, jsonResp["error"], "transform error")
@@ -243,7 +247,7 @@ func TestUpdateManyHandler_Error(t *testing.T) {
 	// Check response indicates error
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Contains(t, jsonResp["error"], "transform error")
@@ -283,7 +287,7 @@ func TestUpdateManyHandler_RepositoryError(t *testing.T) {
 	// Check response
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Equal(t, "database error", jsonResp["error"])
@@ -392,7 +396,7 @@ func TestDeleteManyHandler_Error(t *testing.T) {
 	// Check response indicates error
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Equal(t, "delete error", jsonResp["error"])
@@ -516,7 +520,7 @@ func TestCreateManyHandler_NonArrayValues(t *testing.T) {
 	// Check response indicates error
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Equal(t, "values must be an array", jsonResp["error"])
@@ -558,7 +562,7 @@ func TestCreateManyHandler_RepositoryError(t *testing.T) {
 	// Check response
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Equal(t, "database error", jsonResp["error"])
@@ -601,7 +605,7 @@ func TestCreateManyHandler_DTOResponseError(t *testing.T) {
 	// Check response
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 
-	var jsonResp map[string]string
+	var jsonResp map[string]interface{}
 	err := json.Unmarshal(resp.Body.Bytes(), &jsonResp)
 	assert.NoError(t, err)
 	assert.Equal(t, "transform response error", jsonResp["error"])
@@ -683,3 +687,145 @@ func (m *MockRepository) DeleteMany(ctx context.Context, ids []interface{}) (int
 	args := m.Called(ctx, ids)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+type PartialBulkContact struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" gorm:"uniqueIndex"`
+}
+
+func setupPartialCreateMany(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&PartialBulkContact{}))
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "contacts", Model: PartialBulkContact{}})
+	repo := repository.NewGenericRepositoryWithResource(db, res)
+
+	r := gin.New()
+	r.POST("/contacts/batch", GenerateCreateManyHandler(res, repo, &dto.DefaultDTOProvider{Model: PartialBulkContact{}}))
+	return r, db
+}
+
+func postBatch(r *gin.Engine, path, body string) (*httptest.ResponseRecorder, BulkCreateResponse) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp BulkCreateResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestCreateManyHandler_Partial(t *testing.T) {
+	r, db := setupPartialCreateMany(t)
+
+	w, resp := postBatch(r, "/contacts/batch", `{"partial":true,"values":[
+		{"name":"Ann","email":"ann@example.com"},
+		{"email":"nameless@example.com"},
+		{"name":"Ann again","email":"ann@example.com"},
+		{"name":"Bob","email":"bob@example.com"}
+	]}`)
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+	assert.Equal(t, BulkCreateSummary{Created: 2, Failed: 2}, resp.Meta)
+	require.Len(t, resp.Data, 4)
+
+	assert.Equal(t, 0, resp.Data[0].Index)
+	assert.Equal(t, float64(1), resp.Data[0].ID)
+	assert.Equal(t, "Ann", resp.Data[0].Data.(map[string]interface{})["name"])
+
+	assert.Equal(t, 1, resp.Data[1].Index)
+	assert.Contains(t, resp.Data[1].Error, "required")
+	assert.Equal(t, 2, resp.Data[2].Index)
+	assert.Contains(t, resp.Data[2].Error, "UNIQUE")
+	assert.Equal(t, float64(2), resp.Data[3].ID)
+
+	var count int64
+	require.NoError(t, db.Model(&PartialBulkContact{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestCreateManyHandler_PartialStatus(t *testing.T) {
+	r, _ := setupPartialCreateMany(t)
+
+	w, resp := postBatch(r, "/contacts/batch?partial=true", `{"values":[{"name":"Ann","email":"ann@example.com"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, BulkCreateSummary{Created: 1}, resp.Meta)
+
+	w, resp = postBatch(r, "/contacts/batch?partial=true", `{"values":[{"name":"Ann","email":"ann@example.com"},{"email":"x@example.com"}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, BulkCreateSummary{Failed: 2}, resp.Meta)
+}
+
+func setupPartialBulk(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&PartialBulkContact{}))
+	require.NoError(t, db.Create(&[]PartialBulkContact{
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "contacts", Model: PartialBulkContact{}})
+	repo := repository.NewGenericRepositoryWithResource(db, res)
+
+	r := gin.New()
+	r.PUT("/contacts/batch", GenerateUpdateManyHandler(res, repo, nil))
+	r.DELETE("/contacts/batch", GenerateDeleteManyHandler(res, repo))
+	return r, db
+}
+
+func sendBatch(r *gin.Engine, method, path, body string, resp interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	_ = json.Unmarshal(w.Body.Bytes(), resp)
+	return w
+}
+
+func TestUpdateManyHandler_Partial(t *testing.T) {
+	r, db := setupPartialBulk(t)
+
+	var resp BulkUpdateResponse
+	w := sendBatch(r, http.MethodPut, "/contacts/batch?partial=true", `{"ids":[1,99,2],"values":{"email":"shared@example.com"}}`, &resp)
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+	assert.Equal(t, BulkUpdateSummary{Updated: 1, Failed: 2}, resp.Meta)
+	require.Len(t, resp.Data, 3)
+
+	assert.Equal(t, float64(1), resp.Data[0].ID)
+	assert.Equal(t, "shared@example.com", resp.Data[0].Data.(map[string]interface{})["email"])
+	assert.Contains(t, resp.Data[1].Error, "not found")
+	assert.Contains(t, resp.Data[2].Error, "UNIQUE")
+
+	var bob PartialBulkContact
+	require.NoError(t, db.First(&bob, 2).Error)
+	assert.Equal(t, "bob@example.com", bob.Email)
+
+	resp = BulkUpdateResponse{}
+	w = sendBatch(r, http.MethodPut, "/contacts/batch", `{"partial":true,"ids":[1,2],"values":{"name":"Renamed"}}`, &resp)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, BulkUpdateSummary{Updated: 2}, resp.Meta)
+}
+
+func TestDeleteManyHandler_Partial(t *testing.T) {
+	r, db := setupPartialBulk(t)
+
+	var resp BulkDeleteResponse
+	w := sendBatch(r, http.MethodDelete, "/contacts/batch?partial=true", `{"ids":[1,99]}`, &resp)
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+	assert.Equal(t, BulkDeleteSummary{Deleted: 1, Failed: 1}, resp.Meta)
+	assert.Empty(t, resp.Data[0].Error)
+	assert.Contains(t, resp.Data[1].Error, "not found")
+
+	resp = BulkDeleteResponse{}
+	w = sendBatch(r, http.MethodDelete, "/contacts/batch?partial=true", `{"ids":[1]}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var count int64
+	require.NoError(t, db.Model(&PartialBulkContact{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
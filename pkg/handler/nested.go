@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/explain"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+const (
+	// NestedParentParam is the route parameter of the parent ID in nested routes
+	NestedParentParam = "id"
+
+	// NestedChildParam is the route parameter of the child ID in nested routes
+	NestedChildParam = "childId"
+)
+
+// RouteParams names the ID route parameters of routes under the records of a resource:
+// the parent and child IDs of nested routes, or the record and related record IDs of
+// relation routes. Empty names get the defaults of the routes.
+type RouteParams struct {
+	Parent string
+	Child  string
+}
+
+// withDefaults returns the params with default names for the empty ones
+func (p RouteParams) withDefaults(parent, child string) RouteParams {
+	if p.Parent == "" {
+		p.Parent = parent
+	}
+	if p.Child == "" {
+		p.Child = child
+	}
+	return p
+}
+
+// RegisterNestedResource registers the CRUD endpoints of a child resource under the
+// records of its parent, e.g. for orders and their items:
+//
+//	GET    /orders/:id/items           list the items of the order
+//	POST   /orders/:id/items/query     list them with query parameters in the body
+//	GET    /orders/:id/items/count     count them
+//	POST   /orders/:id/items/count     count them with filters in the body
+//	POST   /orders/:id/items           create an item in the order
+//	GET    /orders/:id/items/:childId  read an item of the order
+//	PUT    /orders/:id/items/:childId  update it
+//	PATCH  /orders/:id/items/:childId  patch it
+//	DELETE /orders/:id/items/:childId  delete it
+//
+// foreignKey is the child field referencing the parent (e.g. "order_id"). Reads are
+// scoped to the parent with repository.WithScope, children of other parents are not
+// found, and the foreign key of created and updated records is set from the route.
+func RegisterNestedResource(router *gin.RouterGroup, parent, child resource.Resource, repo repository.Repository, foreignKey string) {
+	RegisterNestedResourceWithParams(router, parent, child, repo, foreignKey, RouteParams{})
+}
+
+// RegisterNestedResourceWithParams registers a nested resource like
+// RegisterNestedResource, with the route parameters of params, e.g.
+// /orders/:orderCode/items/:itemId. The parent parameter must match the one of the
+// parent routes (see RegisterResourceForRefine), since gin rejects two names for the
+// same path segment.
+func RegisterNestedResourceWithParams(router *gin.RouterGroup, parent, child resource.Resource, repo repository.Repository, foreignKey string, params RouteParams) {
+	resource.RegisterToRegistry(child)
+
+	dtoProvider := dto.ForResource(child)
+	params = params.withDefaults(NestedParentParam, NestedChildParam)
+	childParam := params.Child
+
+	nestedRouter := router.Group("/"+parent.GetName()+"/:"+params.Parent+"/"+child.GetName(),
+		explain.Middleware(),
+		requestctx.Middleware(child),
+		NestedScopeMiddlewareWithParams(child, repo, foreignKey, params),
+		JSONSchemaMiddleware(child),
+		FieldAliasMiddleware(child),
+		FlagsMiddleware(child),
+		VersionMiddleware(child),
+		RBACMiddleware(child),
+	)
+
+	if child.HasOperation(resource.OperationList) {
+		nestedRouter.GET("", GenerateListHandlerWithDTO(child, repo, dtoProvider))
+		nestedRouter.POST("/query", middleware.NoCacheMiddleware(), GenerateListQueryHandlerWithDTO(child, repo, dtoProvider))
+	}
+	if child.HasOperation(resource.OperationCount) {
+		registerCountRoutes(nestedRouter, "/count", GenerateCountHandler(child, repo))
+	}
+	if child.HasOperation(resource.OperationCreate) {
+		nestedRouter.POST("", middleware.NoCacheMiddleware(), GenerateCreateHandler(child, repo, dtoProvider))
+	}
+	if child.HasOperation(resource.OperationRead) {
+		nestedRouter.GET("/:"+childParam, GenerateGetHandlerWithParamAndDTO(child, repo, childParam, dtoProvider))
+	}
+	if child.HasOperation(resource.OperationUpdate) {
+		nestedRouter.PUT("/:"+childParam, middleware.NoCacheMiddleware(), GenerateUpdateHandlerWithParam(child, repo, dtoProvider, childParam))
+		nestedRouter.PATCH("/:"+childParam, middleware.NoCacheMiddleware(), GeneratePatchHandlerWithParam(child, repo, dtoProvider, childParam))
+	}
+	if child.HasOperation(resource.OperationDelete) {
+		nestedRouter.DELETE("/:"+childParam, middleware.NoCacheMiddleware(), GenerateDeleteHandlerWithParam(child, repo, childParam))
+	}
+}
+
+// NestedScopeMiddleware scopes the requests of nested routes to the parent record: the
+// repository only sees children with the parent ID in foreignKey, requests for children
+// of other parents get a 404, and the foreign key is set in request bodies.
+func NestedScopeMiddleware(child resource.Resource, repo repository.Repository, foreignKey string) gin.HandlerFunc {
+	return NestedScopeMiddlewareWithParams(child, repo, foreignKey, RouteParams{})
+}
+
+// NestedScopeMiddlewareWithParams is NestedScopeMiddleware for nested routes with the
+// route parameters of params
+func NestedScopeMiddlewareWithParams(child resource.Resource, repo repository.Repository, foreignKey string, params RouteParams) gin.HandlerFunc {
+	jsonKey, numeric := foreignKeyField(child.GetModel(), foreignKey)
+	params = params.withDefaults(NestedParentParam, NestedChildParam)
+
+	return func(c *gin.Context) {
+		parentID := c.Param(params.Parent)
+		var scopeValue interface{} = parentID
+		if numeric {
+			if number, err := strconv.ParseInt(parentID, 10, 64); err == nil {
+				scopeValue = number
+			} else if number, err := strconv.ParseFloat(parentID, 64); err == nil {
+				scopeValue = number
+			} else {
+				abortWithError(c, http.StatusNotFound, errors.New("Resource not found"))
+				return
+			}
+		}
+		c.Request = c.Request.WithContext(repository.WithScope(c.Request.Context(), foreignKey, scopeValue))
+
+		if childID := c.Param(params.Child); childID != "" && c.Request.Method != http.MethodGet {
+			if _, err := repo.Get(c.Request.Context(), childID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "not found") {
+					abortWithError(c, http.StatusNotFound, errors.New("Resource not found"))
+					return
+				}
+				abortWithError(c, http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		if c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut || c.Request.Method == http.MethodPatch {
+			err := rewriteBodyRecords(c, func(records interface{}) bool {
+				record, ok := records.(map[string]interface{})
+				if !ok {
+					return false
+				}
+				for key := range record {
+					if normalizeBodyKey(key) == normalizeBodyKey(jsonKey) {
+						delete(record, key)
+					}
+				}
+				record[jsonKey] = scopeValue
+				return true
+			})
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, err)
+				return
+			}
+			// JSON Patches get a last operation setting the foreign key back
+			if isJSONPatchBody(c) {
+				if err := appendJSONPatchOperation(c, "add", jsonKey, scopeValue); err != nil {
+					abortWithError(c, http.StatusBadRequest, err)
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// foreignKeyField returns the JSON name of the model field matching a foreign key (by
+// JSON, Go or snake_case name) and whether it holds a number
+func foreignKeyField(model interface{}, foreignKey string) (string, bool) {
+	modelType := reflect.TypeOf(model)
+	for modelType != nil && modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType == nil || modelType.Kind() != reflect.Struct {
+		return foreignKey, false
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if normalizeBodyKey(jsonName) != normalizeBodyKey(foreignKey) && normalizeBodyKey(field.Name) != normalizeBodyKey(foreignKey) {
+			continue
+		}
+		if jsonName == "" || jsonName == "-" {
+			jsonName = field.Name
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return jsonName, true
+		}
+		return jsonName, false
+	}
+	return foreignKey, false
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type NestedOrder struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Customer string `json:"customer"`
+}
+
+type NestedItem struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	OrderID uint   `json:"order_id"`
+	Product string `json:"product"`
+}
+
+func TestRegisterNestedResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&NestedOrder{}, &NestedItem{}))
+	require.NoError(t, db.Create(&[]NestedOrder{{Customer: "ann"}, {Customer: "bob"}}).Error)
+	require.NoError(t, db.Create(&NestedItem{OrderID: 2, Product: "other"}).Error)
+
+	allOperations := []resource.Operation{
+		resource.OperationList, resource.OperationCount, resource.OperationCreate,
+		resource.OperationRead, resource.OperationUpdate, resource.OperationDelete,
+	}
+	orders := resource.NewResource(resource.ResourceConfig{Name: "orders", Model: NestedOrder{}, Operations: allOperations})
+	items := resource.NewResource(resource.ResourceConfig{Name: "items", Model: NestedItem{}, Operations: allOperations})
+
+	r := gin.New()
+	api := r.Group("/api")
+	RegisterResource(api, orders, repository.NewGenericRepositoryWithResource(db, orders))
+	RegisterNestedResource(api, orders, items, repository.NewGenericRepositoryWithResource(db, items), "order_id")
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The foreign key is taken from the route, not from the body
+	w := request(http.MethodPost, "/api/orders/1/items", `{"product":"pen","order_id":2}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"order_id":1`)
+
+	w = request(http.MethodGet, "/api/orders/1/items", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"product":"pen"`)
+	assert.NotContains(t, w.Body.String(), `"product":"other"`)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	w = request(http.MethodGet, "/api/orders/1/items/count", "")
+	assert.Contains(t, w.Body.String(), `1`)
+
+	// Items of other orders are not found
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/orders/1/items/2", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/orders/1/items/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPut, "/api/orders/1/items/1", `{"product":"x"}`).Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/api/orders/1/items/1", "").Code)
+
+	w = request(http.MethodPut, "/api/orders/1/items/2", `{"product":"pencil","order_id":2}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"order_id":1`)
+	assert.Contains(t, w.Body.String(), `"product":"pencil"`)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/orders/1/items/2", "").Code)
+	var remaining int64
+	db.Model(&NestedItem{}).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+
+	// The parent routes still work
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/orders/1", "").Code)
+}
+
+func TestRegisterNestedResourceWithParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&NestedOrder{}, &NestedItem{}))
+	require.NoError(t, db.Create(&[]NestedOrder{{Customer: "ann"}, {Customer: "bob"}}).Error)
+	require.NoError(t, db.Create(&[]NestedItem{{OrderID: 1, Product: "pen"}, {OrderID: 2, Product: "other"}}).Error)
+
+	operations := []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationUpdate, resource.OperationDelete}
+	orders := resource.NewResource(resource.ResourceConfig{Name: "orders", Model: NestedOrder{}, Operations: operations})
+	items := resource.NewResource(resource.ResourceConfig{Name: "items", Model: NestedItem{}, Operations: operations})
+
+	// The parent parameter matches the one of the parent routes, which gin requires
+	r := gin.New()
+	api := r.Group("/api")
+	RegisterResourceForRefine(api, orders, repository.NewGenericRepositoryWithResource(db, orders), "orderCode")
+	RegisterNestedResourceWithParams(api, orders, items, repository.NewGenericRepositoryWithResource(db, items), "order_id",
+		RouteParams{Parent: "orderCode", Child: "itemId"})
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/orders/1/items", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"product":"pen"`)
+	assert.NotContains(t, w.Body.String(), `"product":"other"`)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/orders/1/items/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/orders/1/items/2", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPut, "/api/orders/1/items/2", `{"product":"x"}`).Code)
+
+	w = request(http.MethodPatch, "/api/orders/1/items/1", `{"product":"pencil"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"product":"pencil"`)
+	assert.Contains(t, w.Body.String(), `"order_id":1`)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/orders/1/items/1", "").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/orders/2", "").Code)
+}
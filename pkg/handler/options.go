@@ -2,15 +2,22 @@ package handler
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"github.com/suranig/refine-gin/pkg/utils"
 )
 
 // GenerateOptionsHandler creates a handler for the OPTIONS method
-// This handler returns detailed metadata about the resource
+// This handler returns detailed metadata about the resource, generated on the first
+// request (see WarmCaches)
 func GenerateOptionsHandler(res resource.Resource) gin.HandlerFunc {
+	var once sync.Once
+	var generated resource.ResourceMetadata
+
 	return func(c *gin.Context) {
 		// Generate ETag based on resource name for cache validation
 		etag := utils.GenerateResourceETag(res.GetName(), "options")
@@ -22,17 +29,15 @@ func GenerateOptionsHandler(res resource.Resource) gin.HandlerFunc {
 			return
 		}
 
-		// Generate full metadata for the resource
-		metadata := resource.GenerateResourceMetadata(res)
+		// Generate full metadata for the resource, a copy of which is filtered below
+		once.Do(func() {
+			generated = resource.GenerateResourceMetadata(res)
+			generated.QueryCapabilities = query.Capabilities(res)
+		})
+		metadata := generated
 
 		// Get user roles from context if available
-		var userRoles []string
-		userRolesValue, exists := c.Get("userRoles")
-		if exists {
-			if roles, ok := userRolesValue.([]string); ok {
-				userRoles = roles
-			}
-		}
+		userRoles, _ := requestctx.Roles.Get(c)
 
 		// If user roles are available, filter fields based on permissions
 		if len(userRoles) > 0 {
@@ -74,16 +79,18 @@ func GenerateOptionsHandler(res resource.Resource) gin.HandlerFunc {
 
 		// Format metadata as gin.H for response
 		responseMetadata := gin.H{
-			"name":        metadata.Name,
-			"label":       metadata.Label,
-			"icon":        metadata.Icon,
-			"operations":  metadata.Operations,
-			"fields":      metadata.Fields,
-			"defaultSort": metadata.DefaultSort,
-			"relations":   metadata.Relations,
-			"filters":     metadata.Filters,
-			"idField":     metadata.IDFieldName,
-			"permissions": metadata.Permissions,
+			"name":              metadata.Name,
+			"label":             metadata.Label,
+			"icon":              metadata.Icon,
+			"operations":        metadata.Operations,
+			"fields":            metadata.Fields,
+			"defaultSort":       metadata.DefaultSort,
+			"relations":         metadata.Relations,
+			"filters":           metadata.Filters,
+			"filterMacros":      query.GetFilterMacrosMetadata(),
+			"queryCapabilities": metadata.QueryCapabilities,
+			"idField":           metadata.IDFieldName,
+			"permissions":       metadata.Permissions,
 			"lists": gin.H{
 				"filterable": metadata.FilterableFields,
 				"searchable": metadata.Searchable,
@@ -94,6 +101,11 @@ func GenerateOptionsHandler(res resource.Resource) gin.HandlerFunc {
 			},
 		}
 
+		// Link the saved views of the resource, if registered
+		if savedViews := savedViewsMetadata(res); savedViews != nil {
+			responseMetadata["savedViews"] = savedViews
+		}
+
 		// Set cache headers
 		utils.SetCacheHeaders(c.Writer, 300, etag, nil, []string{"Accept", "Accept-Encoding", "Authorization"})
 
@@ -10,13 +10,14 @@ import (
 // RegisterOwnerResource registers all the owner-specific resource handlers for a given resource
 func RegisterOwnerResource(group *gin.RouterGroup, res resource.OwnerResource, repo repository.Repository) {
 	// Create DTO provider
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	dtoProvider := dto.ForResource(res)
 
 	// Resource name and base path
 	resourceName := res.GetName()
 
+	// Owner routes run through the middlewares of the other resource routes, RBAC included
+	group = group.Group("", resourceMiddlewares(res, resourceChain{})...)
+
 	// Register list handler
 	if res.HasOperation(resource.OperationList) {
 		group.GET("/"+resourceName, GenerateOwnerListHandler(res, repo, dtoProvider))
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"reflect"
 
@@ -17,6 +19,9 @@ func GenerateOwnerListHandler(res resource.Resource, repo repository.Repository,
 	return func(c *gin.Context) {
 		// Parse query options from the request
 		options := query.ParseQueryOptions(c, res)
+		if !prepareListOptions(c, res, &options) {
+			return
+		}
 
 		// Generate ETag for cache validation
 		etag := utils.GenerateQueryETag(c.Request.URL.RawQuery)
@@ -31,7 +36,7 @@ func GenerateOwnerListHandler(res resource.Resource, repo repository.Repository,
 		// Get data from repository (owner filtering is handled in repository)
 		data, total, err := repo.List(c.Request.Context(), options)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -43,12 +48,18 @@ func GenerateOwnerListHandler(res resource.Resource, repo repository.Repository,
 				item := v.Index(i).Interface()
 				dtoItem, err := dtoProvider.TransformFromModel(item)
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Error transforming data: " + err.Error()})
+					respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
 					return
 				}
 				dtoItems = append(dtoItems, dtoItem)
 			}
-			data = dtoItems
+			data = transformResponseItems(c, res, dtoItems)
+		}
+
+		// Keep the selected fields only (sparse fieldset)
+		if data, err = selectRecordFields(res, data, options.Fields); err != nil {
+			respondError(c, http.StatusInternalServerError, fmt.Errorf("Error selecting fields: %w", err))
+			return
 		}
 
 		// Set cache headers
@@ -56,12 +67,10 @@ func GenerateOwnerListHandler(res resource.Resource, repo repository.Repository,
 
 		// Return results in Refine.dev compatible format
 		c.JSON(http.StatusOK, gin.H{
-			"data":  data,
-			"total": total,
-			"meta": gin.H{
-				"page":     options.Page,
-				"pageSize": options.PerPage,
-			},
+			"data":         data,
+			"total":        total,
+			"meta":         options.PaginationMeta(total),
+			"appliedQuery": options.AppliedQuery(c.Request.URL.Query()),
 		})
 	}
 }
@@ -71,6 +80,10 @@ func GenerateOwnerCountHandler(res resource.Resource, repo repository.Repository
 	return func(c *gin.Context) {
 		// Parse query options from the request
 		options := query.ParseQueryOptions(c, res)
+		options.DisablePagination = true
+		if !prepareListOptions(c, res, &options) {
+			return
+		}
 
 		// Generate ETag for cache validation
 		etag := utils.GenerateQueryETag(c.Request.URL.RawQuery)
@@ -85,7 +98,7 @@ func GenerateOwnerCountHandler(res resource.Resource, repo repository.Repository
 		// Get count from repository (owner filtering is handled in repository)
 		count, err := repo.Count(c.Request.Context(), options)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -105,27 +118,36 @@ func GenerateOwnerCreateHandler(res resource.Resource, repo repository.Repositor
 		// Create a new instance of the model
 		model := utils.CreateNewModelInstance(res.GetModel())
 		if model == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model instance"})
+			respondErrorMessage(c, http.StatusInternalServerError, "Failed to create model instance")
 			return
 		}
 
 		// Bind request to model
 		if err := c.ShouldBindJSON(model); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		hooks := lifecycleHooks(res)
+		if !runBeforeHook(c, res, hooks.BeforeCreate, model) {
 			return
 		}
 
 		// Create in repository (owner field will be set automatically)
 		created, err := repo.Create(c.Request.Context(), model)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterCreate, created) {
 			return
 		}
 
 		// Transform to DTO
 		dtoData, err := dtoProvider.TransformFromModel(created)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -142,7 +164,7 @@ func GenerateOwnerGetHandler(res resource.Resource, repo repository.Repository,
 		// Get ID from URL
 		id := c.Param(idParamName)
 		if id == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing resource ID"})
+			respondErrorMessage(c, http.StatusBadRequest, "Missing resource ID")
 			return
 		}
 
@@ -161,23 +183,23 @@ func GenerateOwnerGetHandler(res resource.Resource, repo repository.Repository,
 		if err != nil {
 			// Handle specific errors
 			if err == repository.ErrOwnerMismatch {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to access this resource"})
+				respondErrorMessage(c, http.StatusForbidden, "You don't have permission to access this resource")
 				return
 			}
 			// Check for not found error
 			if err.Error() == "record not found" {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
 		// Transform to DTO
 		dtoData, err := dtoProvider.TransformFromModel(data)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -186,7 +208,7 @@ func GenerateOwnerGetHandler(res resource.Resource, repo repository.Repository,
 
 		// Return the resource
 		c.JSON(http.StatusOK, gin.H{
-			"data": dtoData,
+			"data": transformResponseItem(c, res, dtoData),
 		})
 	}
 }
@@ -197,48 +219,62 @@ func GenerateOwnerUpdateHandler(res resource.Resource, repo repository.Repositor
 		// Get ID from URL
 		id := c.Param(idParamName)
 		if id == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing resource ID"})
+			respondErrorMessage(c, http.StatusBadRequest, "Missing resource ID")
 			return
 		}
 
 		// Create a new instance of the model
 		model := utils.CreateNewModelInstance(res.GetModel())
 		if model == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model instance"})
+			respondErrorMessage(c, http.StatusInternalServerError, "Failed to create model instance")
 			return
 		}
 
 		// Bind request to model
 		if err := c.ShouldBindJSON(model); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Filter out read-only fields
 		model = resource.FilterOutReadOnlyFields(model, res)
 
+		hooks := lifecycleHooks(res)
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, hooks.BeforeUpdate, model) {
+			return
+		}
+
 		// Update in repository (ownership verification happens in repository)
 		updated, err := repo.Update(c.Request.Context(), id, model)
 		if err != nil {
 			// Handle specific errors
 			if err == repository.ErrOwnerMismatch {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this resource"})
+				respondErrorMessage(c, http.StatusForbidden, "You don't have permission to update this resource")
 				return
 			}
 			// Check for not found error
 			if err.Error() == "record not found" {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+				return
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
 				return
 			}
 			// Other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterUpdate, updated) {
 			return
 		}
 
 		// Transform to DTO
 		dtoData, err := dtoProvider.TransformFromModel(updated)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -255,7 +291,13 @@ func GenerateOwnerDeleteHandler(res resource.Resource, repo repository.Repositor
 		// Get ID from URL
 		id := c.Param(idParamName)
 		if id == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing resource ID"})
+			respondErrorMessage(c, http.StatusBadRequest, "Missing resource ID")
+			return
+		}
+
+		hooks := lifecycleHooks(res)
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, hooks.BeforeDelete, id) {
 			return
 		}
 
@@ -264,16 +306,20 @@ func GenerateOwnerDeleteHandler(res resource.Resource, repo repository.Repositor
 		if err != nil {
 			// Handle specific errors
 			if err == repository.ErrOwnerMismatch {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this resource"})
+				respondErrorMessage(c, http.StatusForbidden, "You don't have permission to delete this resource")
 				return
 			}
 			// Check for not found error
 			if err.Error() == "record not found" {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, hooks.AfterDelete, id) {
 			return
 		}
 
@@ -290,27 +336,27 @@ func GenerateOwnerCreateManyHandler(res resource.Resource, repo repository.Repos
 		// Create a new slice of the model type
 		slice := utils.CreateNewSliceOfModel(res.GetModel())
 		if slice == nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create model slice"})
+			respondErrorMessage(c, http.StatusInternalServerError, "Failed to create model slice")
 			return
 		}
 
 		// Bind request to slice
 		if err := c.ShouldBindJSON(slice); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Create many in repository (owner field will be set automatically)
 		created, err := repo.CreateMany(c.Request.Context(), slice)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
 		// Transform to DTOs
 		dtoData, err := dtoProvider.TransformFromModel(created)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -332,7 +378,7 @@ func GenerateOwnerUpdateManyHandler(res resource.Resource, repo repository.Repos
 
 		// Bind request
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -344,11 +390,11 @@ func GenerateOwnerUpdateManyHandler(res resource.Resource, repo repository.Repos
 		if err != nil {
 			// Handle specific errors
 			if err == repository.ErrOwnerMismatch {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update some resources"})
+				respondErrorMessage(c, http.StatusForbidden, "You don't have permission to update some resources")
 				return
 			}
 			// Other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -369,7 +415,7 @@ func GenerateOwnerDeleteManyHandler(res resource.Resource, repo repository.Repos
 
 		// Bind request
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -378,11 +424,11 @@ func GenerateOwnerDeleteManyHandler(res resource.Resource, repo repository.Repos
 		if err != nil {
 			// Handle specific errors
 			if err == repository.ErrOwnerMismatch {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete some resources"})
+				respondErrorMessage(c, http.StatusForbidden, "You don't have permission to delete some resources")
 				return
 			}
 			// Other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -0,0 +1,588 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// Media types of PATCH bodies
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Patch errors
+var (
+	// ErrInvalidPatch is returned for malformed patch documents (400 Bad Request)
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrPatchNotApplicable is returned for JSON Patch operations that cannot be applied
+	// to the record, e.g. removing a missing member (422 Unprocessable Entity)
+	ErrPatchNotApplicable = errors.New("patch cannot be applied")
+
+	// ErrPatchTestFailed is returned when a JSON Patch "test" operation fails
+	// (409 Conflict)
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// GeneratePatchHandler generates a handler for PATCH requests. The body is applied to
+// the stored record as a JSON Merge Patch (RFC 7386, application/merge-patch+json or
+// application/json) or a JSON Patch (RFC 6902, application/json-patch+json), and the
+// patched record is saved like a PUT of the whole record.
+func GeneratePatchHandler(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return GeneratePatchHandlerWithParam(res, repo, dtoProvider, "id")
+}
+
+// GeneratePatchHandlerWithParam generates a handler for PATCH requests with a custom ID
+// parameter name
+func GeneratePatchHandlerWithParam(res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider, idParamName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param(idParamName)
+
+		var apply func(document, patch []byte) ([]byte, error)
+		switch c.ContentType() {
+		case MergePatchContentType, "application/json", "":
+			apply = ApplyMergePatch
+		case JSONPatchContentType:
+			apply = ApplyJSONPatch
+		default:
+			respondErrorMessage(c, http.StatusUnsupportedMediaType, "Unsupported patch media type: "+c.ContentType())
+			return
+		}
+
+		patch, err := c.GetRawData()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		// Fetch the record the patch applies to
+		existing, err := repo.Get(c.Request.Context(), id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		document, err := json.Marshal(existing)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		patched, err := apply(document, patch)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, ErrPatchTestFailed):
+				status = http.StatusConflict
+			case errors.Is(err, ErrPatchNotApplicable):
+				status = http.StatusUnprocessableEntity
+			}
+			respondError(c, status, err)
+			return
+		}
+
+		// JSON Patch bodies are not stripped by RBACMiddleware, so changes to fields the
+		// roles may not update are rejected here
+		if roles, ok := requestctx.Roles.Get(c); ok {
+			if changed := changedFields(document, patched, deniedFields(res.GetFields(), "update", roles)); len(changed) > 0 {
+				respondErrorMessage(c, http.StatusForbidden, "Forbidden: insufficient permissions to update "+strings.Join(changed, ", "))
+				return
+			}
+		}
+
+		// Bind the patched record like the body of a PUT
+		dtoInstance := dtoProvider.GetUpdateDTO()
+		if err := json.Unmarshal(patched, dtoInstance); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := binding.Validator.ValidateStruct(dtoInstance); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		model, err := dtoProvider.TransformToModel(dtoInstance)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		// Filter out read-only fields from the model
+		model = resource.FilterOutReadOnlyFields(model, res)
+
+		// Validate nested JSON fields if present
+		if err := validateNestedJsonFields(res, model); err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Errorf("JSON validation failed: %w", err))
+			return
+		}
+
+		// Validate relations (if any) - only perform if repository has DB access
+		if db := repo.Query(c.Request.Context()); db != nil && len(res.GetRelations()) > 0 {
+			if err := resource.ValidateRelations(db, model); err != nil {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("Relation validation failed: %w", err))
+				return
+			}
+		}
+
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, model) {
+			return
+		}
+
+		updatedModel, err := repo.Update(c.Request.Context(), id, model)
+		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
+				return
+			}
+			// Check if it's a "not found" error
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updatedModel) {
+			return
+		}
+
+		// Transform model to response DTO
+		responseDTO, err := dtoProvider.TransformFromModel(updatedModel)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": responseDTO})
+	}
+}
+
+// isJSONPatchBody reports whether the body of a request is a JSON Patch, i.e. a list
+// of operations rather than a record
+func isJSONPatchBody(c *gin.Context) bool {
+	return c.ContentType() == JSONPatchContentType
+}
+
+// appendJSONPatchOperation appends an operation on a top level member to the JSON
+// Patch body of a request. Malformed bodies are left for the handler to report.
+func appendJSONPatchOperation(c *gin.Context, op, member string, value interface{}) error {
+	if c.Request.Body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var operations []interface{}
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return nil
+	}
+	path := "/" + strings.ReplaceAll(strings.ReplaceAll(member, "~", "~0"), "/", "~1")
+	operations = append(operations, map[string]interface{}{"op": op, "path": path, "value": value})
+
+	rewritten, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Request.ContentLength = int64(len(rewritten))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+	return nil
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7386) to a JSON document: members
+// of patch objects replace the members of the document, null removes them and
+// non-object patches replace the document
+func ApplyMergePatch(document, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	merge, err := decodePatchJSON(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergePatch(target, merge))
+}
+
+// mergePatch merges a decoded merge patch into a decoded document
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// ApplyJSONPatch applies a JSON Patch (RFC 6902) to a JSON document. The operations
+// add, remove, replace, move, copy and test are applied in order; the document is
+// left unchanged if one of them fails.
+func ApplyJSONPatch(document, patch []byte) ([]byte, error) {
+	target, err := decodePatchJSON(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: expected a list of operations: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		if target, err = applyPatchOperation(target, operation); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// applyPatchOperation applies a JSON Patch operation to a decoded document
+func applyPatchOperation(document interface{}, operation map[string]json.RawMessage) (interface{}, error) {
+	var op string
+	if err := json.Unmarshal(operation["op"], &op); err != nil {
+		return nil, fmt.Errorf("%w: missing or invalid op", ErrInvalidPatch)
+	}
+	path, err := patchPointer(operation, "path")
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		raw, ok := operation["value"]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, op)
+		}
+		decoded, err := decodePatchJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return decoded, nil
+	}
+
+	switch op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, path, v)
+	case "remove":
+		document, _, err := pointerRemove(document, path)
+		return document, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if document, _, err = pointerRemove(document, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, path, v)
+	case "move":
+		from, err := patchPointer(operation, "from")
+		if err != nil {
+			return nil, err
+		}
+		if len(from) < len(path) && strings.Join(path[:len(from)], "/") == strings.Join(from, "/") {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+		}
+		document, moved, err := pointerRemove(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, path, moved)
+	case "copy":
+		from, err := patchPointer(operation, "from")
+		if err != nil {
+			return nil, err
+		}
+		copied, err := pointerGet(document, from)
+		if err != nil {
+			return nil, err
+		}
+		// Decode a copy so the values do not share maps and slices
+		encoded, err := json.Marshal(copied)
+		if err != nil {
+			return nil, err
+		}
+		if copied, err = decodePatchJSON(encoded); err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, path, copied)
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, expected) {
+			return nil, fmt.Errorf("%w: value at %q differs", ErrPatchTestFailed, "/"+strings.Join(path, "/"))
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op)
+}
+
+// patchPointer parses a JSON Pointer (RFC 6901) member of an operation into its
+// unescaped reference tokens
+func patchPointer(operation map[string]json.RawMessage, member string) ([]string, error) {
+	var pointer string
+	if err := json.Unmarshal(operation[member], &pointer); err != nil {
+		return nil, fmt.Errorf("%w: missing or invalid %s", ErrInvalidPatch, member)
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid pointer %q", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerGet returns the value a pointer refers to
+func pointerGet(document interface{}, path []string) (interface{}, error) {
+	current := document
+	for _, token := range path {
+		switch container := current.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrPatchNotApplicable, token)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = container[index]
+		default:
+			return nil, fmt.Errorf("%w: %q is not in a container", ErrPatchNotApplicable, token)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd adds a value at a pointer, replacing object members and inserting into
+// arrays ("-" appends), and returns the document
+func pointerAdd(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last] = value
+		return document, nil
+	case []interface{}:
+		index := len(container)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(container)); err != nil {
+				return nil, err
+			}
+		}
+		container = append(container, nil)
+		copy(container[index+1:], container[index:])
+		container[index] = value
+		return replaceAt(document, path[:len(path)-1], container)
+	}
+	return nil, fmt.Errorf("%w: %q is not in a container", ErrPatchNotApplicable, last)
+}
+
+// pointerRemove removes the value at a pointer and returns the document and the
+// removed value
+func pointerRemove(document interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, document, nil
+	}
+	parent, err := pointerGet(document, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		removed, ok := container[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: member %q not found", ErrPatchNotApplicable, last)
+		}
+		delete(container, last)
+		return document, removed, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(container)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := container[index]
+		container = append(container[:index:index], container[index+1:]...)
+		document, err = replaceAt(document, path[:len(path)-1], container)
+		return document, removed, err
+	}
+	return nil, nil, fmt.Errorf("%w: %q is not in a container", ErrPatchNotApplicable, last)
+}
+
+// replaceAt replaces the value at an existing pointer and returns the document
+func replaceAt(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = value
+	}
+	return document, nil
+}
+
+// arrayIndex parses an array index token, which must be at most max
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPatchNotApplicable, token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("%w: array index %d out of bounds", ErrPatchNotApplicable, index)
+	}
+	return index, nil
+}
+
+// decodePatchJSON decodes JSON keeping numbers as json.Number, so large IDs survive
+// the round trip
+func decodePatchJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// changedFields returns the sorted top level members of a patched document whose
+// normalized names are in fields and that differ from the original document
+func changedFields(document, patched []byte, fields map[string]bool) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	original, errA := decodePatchJSON(document)
+	result, errB := decodePatchJSON(patched)
+	if errA != nil || errB != nil {
+		return nil
+	}
+	before, _ := original.(map[string]interface{})
+	after, _ := result.(map[string]interface{})
+
+	changed := make(map[string]bool)
+	compare := func(from, to map[string]interface{}) {
+		for key, value := range from {
+			if !fields[normalizeBodyKey(key)] {
+				continue
+			}
+			if other, ok := to[key]; !ok || !jsonEqual(value, other) {
+				changed[key] = true
+			}
+		}
+	}
+	compare(before, after)
+	compare(after, before)
+
+	names := make([]string, 0, len(changed))
+	for key := range changed {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jsonEqual compares decoded JSON values, numbers by value
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, errA := a.Float64()
+		bf, errB := bn.Float64()
+		return errA == nil && errB == nil && af == bf
+	case map[string]interface{}:
+		bm, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := bm[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bs, ok := b.([]interface{})
+		if !ok || len(a) != len(bs) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type PatchedArticle struct {
+	ID     uint     `json:"id" gorm:"primaryKey"`
+	Title  string   `json:"title"`
+	Status string   `json:"status"`
+	Views  int      `json:"views"`
+	Tags   []string `json:"tags" gorm:"serializer:json"`
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	// Examples of RFC 7386, appendix A
+	tests := []struct {
+		document, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"id":9007199254740993}`, `{"a":1}`, `{"a":1,"id":9007199254740993}`},
+	}
+	for _, test := range tests {
+		patched, err := ApplyMergePatch([]byte(test.document), []byte(test.patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, test.expected, string(patched), "%s + %s", test.document, test.patch)
+	}
+
+	_, err := ApplyMergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := `{"title":"draft","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`
+	tests := []struct {
+		name, patch, expected string
+	}{
+		{"add member", `[{"op":"add","path":"/status","value":"new"}]`, `{"title":"draft","status":"new","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"insert into array", `[{"op":"add","path":"/tags/1","value":"x"}]`, `{"title":"draft","tags":["a","x","b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"append to array", `[{"op":"add","path":"/tags/-","value":"c"}]`, `{"title":"draft","tags":["a","b","c"],"meta":{"a/b":1,"m~n":2}}`},
+		{"remove escaped members", `[{"op":"remove","path":"/meta/a~1b"},{"op":"remove","path":"/meta/m~0n"}]`, `{"title":"draft","tags":["a","b"],"meta":{}}`},
+		{"remove from array", `[{"op":"remove","path":"/tags/0"}]`, `{"title":"draft","tags":["b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"replace", `[{"op":"replace","path":"/tags/0","value":"z"},{"op":"replace","path":"/title","value":null}]`, `{"title":null,"tags":["z","b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"move", `[{"op":"move","from":"/title","path":"/name"}]`, `{"name":"draft","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"copy", `[{"op":"copy","from":"/tags","path":"/labels"},{"op":"add","path":"/labels/-","value":"c"}]`, `{"title":"draft","tags":["a","b"],"labels":["a","b","c"],"meta":{"a/b":1,"m~n":2}}`},
+		{"test", `[{"op":"test","path":"/meta","value":{"m~n":2.0,"a/b":1}},{"op":"replace","path":"/title","value":"final"}]`, `{"title":"final","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`},
+		{"replace document", `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := ApplyJSONPatch([]byte(document), []byte(test.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(patched))
+		})
+	}
+
+	failures := []struct {
+		patch    string
+		expected error
+	}{
+		{`{"op":"add"}`, ErrInvalidPatch},
+		{`[{"op":"increment","path":"/views"}]`, ErrInvalidPatch},
+		{`[{"op":"add","path":"title","value":1}]`, ErrInvalidPatch},
+		{`[{"op":"add","path":"/title"}]`, ErrInvalidPatch},
+		{`[{"op":"move","from":"/meta","path":"/meta/child"}]`, ErrInvalidPatch},
+		{`[{"op":"remove","path":"/missing"}]`, ErrPatchNotApplicable},
+		{`[{"op":"replace","path":"/missing","value":1}]`, ErrPatchNotApplicable},
+		{`[{"op":"add","path":"/tags/3","value":"x"}]`, ErrPatchNotApplicable},
+		{`[{"op":"add","path":"/tags/01","value":"x"}]`, ErrPatchNotApplicable},
+		{`[{"op":"add","path":"/missing/child","value":1}]`, ErrPatchNotApplicable},
+		{`[{"op":"test","path":"/title","value":"final"}]`, ErrPatchTestFailed},
+	}
+	for _, failure := range failures {
+		_, err := ApplyJSONPatch([]byte(document), []byte(failure.patch))
+		assert.ErrorIs(t, err, failure.expected, failure.patch)
+	}
+}
+
+func TestPatchHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&PatchedArticle{}))
+	require.NoError(t, db.Create(&PatchedArticle{Title: "draft", Status: "new", Views: 3, Tags: []string{"go"}}).Error)
+
+	var updatedID interface{}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "articles",
+		Model:      PatchedArticle{},
+		Operations: []resource.Operation{resource.OperationRead, resource.OperationUpdate},
+		Fields: []resource.Field{
+			{Name: "id", Type: "uint", ReadOnly: true},
+			{Name: "title", Type: "string"},
+			{Name: "status", Type: "string"},
+			{Name: "views", Type: "int", ReadOnly: true},
+			{Name: "tags", Type: "array"},
+		},
+		Hooks: &resource.LifecycleHooks{
+			AfterUpdate: func(ctx context.Context, res resource.Resource, data interface{}) error {
+				updatedID, _ = resource.RecordIDFromContext(ctx)
+				return nil
+			},
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	send := func(contentType, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	stored := func() PatchedArticle {
+		var article PatchedArticle
+		require.NoError(t, db.First(&article, 1).Error)
+		return article
+	}
+
+	// Merge patches change the members present only, read-only fields are ignored
+	w := send(MergePatchContentType, "/api/articles/1", `{"title":"published","views":100}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Data PatchedArticle `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, PatchedArticle{ID: 1, Title: "published", Status: "new", Views: 3, Tags: []string{"go"}}, response.Data)
+	assert.Equal(t, "1", updatedID)
+
+	// Plain JSON bodies are merge patches too
+	w = send("application/json", "/api/articles/1", `{"status":"live"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "live", stored().Status)
+	assert.Equal(t, "published", stored().Title)
+
+	// JSON Patches apply their operations to the stored record
+	w = send(JSONPatchContentType, "/api/articles/1", `[
+		{"op":"test","path":"/status","value":"live"},
+		{"op":"add","path":"/tags/-","value":"gin"},
+		{"op":"replace","path":"/title","value":"patched"}
+	]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, PatchedArticle{ID: 1, Title: "patched", Status: "live", Views: 3, Tags: []string{"go", "gin"}}, stored())
+
+	// Failed tests leave the record unchanged
+	w = send(JSONPatchContentType, "/api/articles/1", `[
+		{"op":"replace","path":"/title","value":"lost"},
+		{"op":"test","path":"/status","value":"new"}
+	]`)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	w = send(JSONPatchContentType, "/api/articles/1", `[{"op":"remove","path":"/missing"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	w = send(JSONPatchContentType, "/api/articles/1", `{"op":"remove"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Equal(t, "patched", stored().Title)
+
+	w = send("text/plain", "/api/articles/1", `title=x`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w = send(MergePatchContentType, "/api/articles/2", `{"title":"x"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPatchHandlerGetErrors(t *testing.T) {
+	r, mockRepo, mockResource, mockDTOProvider := setupTest()
+	mockRepo.On("Get", mock.Anything, "1").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Get", mock.Anything, "2").Return(nil, errors.New("database is locked"))
+	r.PATCH("/tests/:id", GeneratePatchHandler(mockResource, mockRepo, mockDTOProvider))
+
+	for id, status := range map[string]int{"1": http.StatusNotFound, "2": http.StatusInternalServerError} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/tests/"+id, bytes.NewBufferString(`{"name":"x"}`))
+		req.Header.Set("Content-Type", MergePatchContentType)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, w.Body.String())
+	}
+}
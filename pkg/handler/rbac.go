@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// RBACMiddleware enforces the resource permissions (ResourceConfig.Permissions) and the
+// field permissions (Field.Permissions) with the roles of the current user, read from
+// requestctx.Roles or the JWT claims. Requests for operations the roles may not
+// perform get 403. Fields the roles may not create or update are removed from request
+// bodies (JSON Patch requests changing them get 403 from the PATCH handler), and fields they may not read are removed from responses and exports.
+// Resources without permissions are passed through untouched. The operation of each
+// request is stored in requestctx.Operation.
+func RBACMiddleware(res resource.Resource) gin.HandlerFunc {
+	fields := res.GetFields()
+	enforced := len(res.GetPermissions()) > 0
+	for _, field := range fields {
+		if len(field.Permissions) > 0 {
+			enforced = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		op, ok := requestOperation(c, res)
+		if ok {
+			requestctx.Operation.Set(c, op)
+		}
+		if !enforced || !ok {
+			c.Next()
+			return
+		}
+
+		roles := auth.UserRoles(c)
+		if !auth.CanPerform(res, op, roles) {
+			abortWithError(c, http.StatusForbidden, errors.New("Forbidden: insufficient permissions for this resource"))
+			return
+		}
+		requestctx.Roles.Set(c, roles)
+
+		if writeOp := fieldWriteOperation(op); writeOp != "" {
+			if denied := deniedFields(fields, writeOp, roles); len(denied) > 0 {
+				err := rewriteBodyRecords(c, func(records interface{}) bool {
+					return removeRecordKeys(records, denied)
+				})
+				if err != nil {
+					abortWithError(c, http.StatusBadRequest, err)
+					return
+				}
+			}
+		}
+
+		denied := deniedFields(fields, "read", roles)
+		if len(denied) == 0 {
+			c.Next()
+			return
+		}
+
+		w := &recordWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(func(records interface{}) bool {
+			return removeRecordKeys(records, denied)
+		})
+	}
+}
+
+// requestOperation returns the operation of the matched resource route. OPTIONS
+// (metadata) requests and requests without a matched route have no operation.
+func requestOperation(c *gin.Context, res resource.Resource) (resource.Operation, bool) {
+	fullPath := c.FullPath()
+	prefix := "/" + res.GetName()
+	index := strings.LastIndex(fullPath, prefix)
+	if c.Request.Method == http.MethodOptions || index < 0 {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(fullPath[index+len(prefix):], "/"), "/")
+
+	method := c.Request.Method
+	switch {
+	case segments[0] == "":
+		switch method {
+		case http.MethodGet, http.MethodHead:
+			return resource.OperationList, true
+		case http.MethodPost:
+			return resource.OperationCreate, true
+		}
+	case segments[0] == "batch":
+		switch method {
+		case http.MethodPost:
+			return resource.OperationCreateMany, true
+		case http.MethodPut, http.MethodPatch:
+			return resource.OperationUpdateMany, true
+		case http.MethodDelete:
+			return resource.OperationDeleteMany, true
+		}
+	case segments[0] == "query":
+		return resource.OperationList, true
+	case segments[0] == "count":
+		return resource.OperationCount, true
+	case segments[0] == "facets":
+		return resource.OperationFacets, true
+	case segments[0] == "aggregate":
+		return resource.OperationAggregate, true
+	case segments[0] == "export":
+		return resource.OperationExport, true
+	case segments[0] == "import":
+		return resource.OperationImport, true
+	case segments[0] == "trash":
+		return resource.OperationList, true
+	case segments[0] == "slug":
+		return resource.OperationRead, true
+	case segments[0] == "validate":
+		// Validation reveals whether values are stored, like reading records
+		return resource.OperationRead, true
+	case segments[0] == "live":
+		return resource.OperationList, true
+	case strings.HasPrefix(segments[0], ":") && len(segments) == 1:
+		switch method {
+		case http.MethodGet, http.MethodHead:
+			return resource.OperationRead, true
+		case http.MethodPut, http.MethodPatch:
+			return resource.OperationUpdate, true
+		case http.MethodDelete:
+			return resource.OperationDelete, true
+		}
+	case strings.HasPrefix(segments[0], ":") && len(segments) == 2:
+		switch segments[1] {
+		case "restore":
+			return resource.OperationUpdate, true
+		case "force":
+			return resource.OperationDelete, true
+		}
+	case strings.HasPrefix(segments[0], ":") && len(segments) == 3 && segments[1] == "slug":
+		return resource.OperationUpdate, true
+	}
+	if strings.HasPrefix(segments[0], ":") && len(segments) > 1 {
+		switch segments[1] {
+		case "actions":
+			return resource.OperationCustom, true
+		case "presence":
+			// Announcing presence only needs the record to be readable
+			return resource.OperationRead, true
+		}
+		// Other routes of a record (files, uploads, locks, linked records) read it or
+		// change it
+		switch method {
+		case http.MethodGet, http.MethodHead:
+			return resource.OperationRead, true
+		default:
+			return resource.OperationUpdate, true
+		}
+	}
+	return resource.OperationCustom, true
+}
+
+// fieldWriteOperation returns the field permission checked for the body of an
+// operation, or "" for operations without a record body
+func fieldWriteOperation(op resource.Operation) string {
+	switch op {
+	case resource.OperationCreate, resource.OperationCreateMany, resource.OperationImport:
+		return "create"
+	case resource.OperationUpdate, resource.OperationUpdateMany:
+		return "update"
+	}
+	return ""
+}
+
+// deniedFields returns the normalized names (and aliases) of the fields the roles may
+// not use for a field operation
+func deniedFields(fields []resource.Field, operation string, roles []string) map[string]bool {
+	denied := make(map[string]bool)
+	for _, field := range fields {
+		if !auth.CanAccessField(field, operation, roles) {
+			denied[normalizeBodyKey(field.Name)] = true
+			denied[normalizeBodyKey(field.APIName())] = true
+		}
+	}
+	return denied
+}
+
+// removeRecordKeys removes the denied keys from a record or a list of records in
+// place, reporting whether any key was removed
+func removeRecordKeys(records interface{}, denied map[string]bool) bool {
+	switch value := records.(type) {
+	case map[string]interface{}:
+		removed := false
+		for key := range value {
+			if denied[normalizeBodyKey(key)] {
+				delete(value, key)
+				removed = true
+			}
+		}
+		return removed
+	case []interface{}:
+		removed := false
+		for _, item := range value {
+			if removeRecordKeys(item, denied) {
+				removed = true
+			}
+		}
+		return removed
+	}
+	return false
+}
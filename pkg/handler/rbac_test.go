@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type RBACEmployee struct {
+	ID     uint    `json:"id" gorm:"primaryKey"`
+	Name   string  `json:"name"`
+	Salary float64 `json:"salary"`
+}
+
+func setupRBAC(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	t.Cleanup(func() { resource.GlobalResourceRegistry = registry })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RBACEmployee{}))
+	require.NoError(t, db.Create(&RBACEmployee{Name: "Ann", Salary: 5000}).Error)
+
+	hrOnly := map[string][]string{"read": {"hr"}, "create": {"hr"}, "update": {"hr"}}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "employees",
+		Model: RBACEmployee{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "salary", Type: "float", Permissions: hrOnly},
+		},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate,
+			resource.OperationUpdate, resource.OperationDelete, resource.OperationExport,
+		},
+		Permissions: map[string][]string{
+			"create": {"admin", "hr"},
+			"delete": {"admin"},
+		},
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		// Stands in for the JWT middleware
+		if roles := c.GetHeader("X-Roles"); roles != "" {
+			c.Set("claims", jwt.MapClaims{"roles": []interface{}{roles}})
+		}
+	})
+	RegisterResource(r.Group(""), res, repository.NewGenericRepositoryWithResource(db, res))
+	return r, db
+}
+
+func rbacRequest(r *gin.Engine, method, path, role, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("X-Roles", role)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRBACResourcePermissions(t *testing.T) {
+	r, _ := setupRBAC(t)
+
+	// Operations without permissions are open to everyone
+	assert.Equal(t, http.StatusOK, rbacRequest(r, http.MethodGet, "/employees", "", "").Code)
+	assert.Equal(t, http.StatusOK, rbacRequest(r, http.MethodOptions, "/employees", "", "").Code)
+
+	w := rbacRequest(r, http.MethodPost, "/employees", "editor", `{"name":"Bob"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient permissions")
+
+	assert.Equal(t, http.StatusForbidden, rbacRequest(r, http.MethodDelete, "/employees/1", "", "").Code)
+	assert.Equal(t, http.StatusForbidden, rbacRequest(r, http.MethodDelete, "/employees/1", "hr", "").Code)
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodDelete, "/employees/1", "admin", "").Code)
+}
+
+func TestRBACStripsUnreadableFields(t *testing.T) {
+	r, _ := setupRBAC(t)
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	w := rbacRequest(r, http.MethodGet, "/employees", "editor", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "Ann", response.Data[0]["name"])
+	assert.NotContains(t, response.Data[0], "salary")
+
+	w = rbacRequest(r, http.MethodGet, "/employees/1", "hr", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"salary":5000`)
+}
+
+func TestRBACStripsUnwritableFields(t *testing.T) {
+	r, db := setupRBAC(t)
+
+	w := rbacRequest(r, http.MethodPost, "/employees", "admin", `{"name":"Bob","salary":9000}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "salary")
+
+	w = rbacRequest(r, http.MethodPost, "/employees", "hr", `{"name":"Cid","salary":7000}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var employees []RBACEmployee
+	require.NoError(t, db.Order("id").Find(&employees).Error)
+	require.Len(t, employees, 3)
+	assert.Equal(t, float64(0), employees[1].Salary)
+	assert.Equal(t, float64(7000), employees[2].Salary)
+}
+
+func TestRBACJSONPatchFields(t *testing.T) {
+	r, db := setupRBAC(t)
+
+	patch := func(role, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/employees/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", JSONPatchContentType)
+		req.Header.Set("X-Roles", role)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("editor", `[{"op":"replace","path":"/salary","value":1}]`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "salary")
+	w = patch("editor", `[{"op":"move","from":"/salary","path":"/name"}]`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var employee RBACEmployee
+	require.NoError(t, db.First(&employee, 1).Error)
+	assert.Equal(t, float64(5000), employee.Salary)
+	assert.Equal(t, "Ann", employee.Name)
+
+	w = patch("editor", `[{"op":"replace","path":"/name","value":"Bea"}]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = patch("hr", `[{"op":"replace","path":"/salary","value":6000}]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.NoError(t, db.First(&employee, 1).Error)
+	assert.Equal(t, float64(6000), employee.Salary)
+	assert.Equal(t, "Bea", employee.Name)
+}
+
+func TestRBACExportColumns(t *testing.T) {
+	r, _ := setupRBAC(t)
+
+	w := rbacRequest(r, http.MethodGet, "/employees/export?columns=name,salary", "editor", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "name\nAnn\n", w.Body.String())
+
+	w = rbacRequest(r, http.MethodGet, "/employees/export?columns=salary", "editor", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = rbacRequest(r, http.MethodGet, "/employees/export?columns=name,salary", "hr", "")
+	assert.Equal(t, "name,salary\nAnn,5000\n", w.Body.String())
+}
+
+func TestRequestOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: RBACEmployee{}})
+
+	tests := []struct {
+		method, route, path string
+		expected            resource.Operation
+	}{
+		{http.MethodGet, "/api/posts", "/api/posts", resource.OperationList},
+		{http.MethodPost, "/api/posts", "/api/posts", resource.OperationCreate},
+		{http.MethodGet, "/api/posts/:id", "/api/posts/1", resource.OperationRead},
+		{http.MethodPatch, "/api/posts/:id", "/api/posts/1", resource.OperationUpdate},
+		{http.MethodDelete, "/api/posts/:id", "/api/posts/1", resource.OperationDelete},
+		{http.MethodPost, "/api/posts/batch", "/api/posts/batch", resource.OperationCreateMany},
+		{http.MethodDelete, "/api/posts/batch", "/api/posts/batch", resource.OperationDeleteMany},
+		{http.MethodGet, "/api/posts/count", "/api/posts/count", resource.OperationCount},
+		{http.MethodPost, "/api/posts/import/run", "/api/posts/import/run", resource.OperationImport},
+		{http.MethodPost, "/api/posts/:id/restore", "/api/posts/1/restore", resource.OperationUpdate},
+		{http.MethodGet, "/api/posts/slug/:slug", "/api/posts/slug/hello", resource.OperationRead},
+		{http.MethodPost, "/api/posts/:id/slug/regenerate", "/api/posts/1/slug/regenerate", resource.OperationUpdate},
+		{http.MethodPost, "/api/posts/:id/actions/publish", "/api/posts/1/actions/publish", resource.OperationCustom},
+		{http.MethodPost, "/api/posts/:id/files/:field", "/api/posts/1/files/cover", resource.OperationUpdate},
+		{http.MethodGet, "/api/posts/:id/files/:field", "/api/posts/1/files/cover", resource.OperationRead},
+		{http.MethodPost, "/api/posts/:id/uploads/confirm", "/api/posts/1/uploads/confirm", resource.OperationUpdate},
+		{http.MethodPost, "/api/posts/:id/lock", "/api/posts/1/lock", resource.OperationUpdate},
+		{http.MethodPost, "/api/posts/:id/presence", "/api/posts/1/presence", resource.OperationRead},
+		{http.MethodGet, "/api/posts/export", "/api/posts/export", resource.OperationExport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.route, func(t *testing.T) {
+			var op resource.Operation
+			r := gin.New()
+			r.Handle(tt.method, tt.route, func(c *gin.Context) {
+				op, _ = requestOperation(c, res)
+			})
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, strings.NewReader("")))
+			assert.Equal(t, tt.expected, op)
+		})
+	}
+
+	c := requestctx.TestContext()
+	c.Request.Method = http.MethodOptions
+	_, ok := requestOperation(c, res)
+	assert.False(t, ok)
+}
+
+type RBACExpense struct {
+	ID      uint    `json:"id" gorm:"primaryKey"`
+	OwnerID string  `json:"ownerId"`
+	Title   string  `json:"title"`
+	Amount  float64 `json:"amount"`
+}
+
+func TestRBACOwnerResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RBACExpense{}))
+	require.NoError(t, db.Create(&RBACExpense{OwnerID: "ann", Title: "Train", Amount: 40}).Error)
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:  "expenses",
+		Model: RBACExpense{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "ownerId", Type: "string"},
+			{Name: "title", Type: "string"},
+			{Name: "amount", Type: "float", Permissions: map[string][]string{"read": {"accountant"}}},
+		},
+		Operations:  []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationDelete},
+		Permissions: map[string][]string{"delete": {"admin"}},
+	}), resource.OwnerConfig{OwnerField: "OwnerID", EnforceOwnership: true})
+	repo, err := repository.NewOwnerRepository(db, res)
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if roles := c.GetHeader("X-Roles"); roles != "" {
+			c.Set("claims", jwt.MapClaims{"roles": []interface{}{roles}})
+		}
+	})
+	RegisterOwnerResource(r.Group("", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID"))), res, repo)
+	ownerRequest := func(method, path, role string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Owner-ID", "ann")
+		req.Header.Set("X-Roles", role)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The owner is denied operations their role may not perform
+	w := ownerRequest(http.MethodDelete, "/expenses/1", "editor")
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	var count int64
+	require.NoError(t, db.Model(&RBACExpense{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// and fields their role may not read
+	w = ownerRequest(http.MethodGet, "/expenses/1", "editor")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"Train"`)
+	assert.NotContains(t, w.Body.String(), "amount")
+
+	w = ownerRequest(http.MethodGet, "/expenses/1", "accountant")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"amount":40`)
+
+	assert.Equal(t, http.StatusOK, ownerRequest(http.MethodDelete, "/expenses/1", "admin").Code)
+}
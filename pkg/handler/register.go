@@ -1,66 +1,117 @@
 package handler
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/explain"
 	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/naming"
 	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 )
 
 // RegisterResource registers resource handlers in the Gin router
 func RegisterResource(router *gin.RouterGroup, res resource.Resource, repo repository.Repository) {
+	MountResource(router, res, repo)
+}
+
+// MountResource registers a resource like RegisterResource and returns the manifest
+// of the registered routes, e.g. to check them in tests
+func MountResource(router *gin.RouterGroup, res resource.Resource, repo repository.Repository) RouteManifest {
 	// Register resource to registry
 	resource.RegisterToRegistry(res)
 
-	// Create default DTO provider if not specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// DTO provider of the resource (the model unless it declares DTOs)
+	dtoProvider := dto.ForResource(res)
 
 	// Określ nazwę parametru URL dla identyfikatora (domyślnie "id")
 	idParamName := "id"
 
+	// Map field aliases and bit flags in requests and back in responses
+	group := router.Group("", resourceMiddlewares(res, resourceChain{})...)
+	resourceRouter := newRouteRecorder(group, res, strings.TrimSuffix(group.BasePath(), "/")+"/"+res.GetName())
+
 	// Register OPTIONS handler for metadata
-	router.OPTIONS("/"+res.GetName(), GenerateOptionsHandler(res))
+	resourceRouter.OPTIONS("/"+res.GetName(), GenerateOptionsHandler(res))
 
 	// Register handlers for allowed operations
 	if res.HasOperation(resource.OperationList) {
-		router.GET("/"+res.GetName(), GenerateListHandlerWithDTO(res, repo, dtoProvider))
+		resourceRouter.GET("/"+res.GetName(), GenerateListHandlerWithDTO(res, repo, dtoProvider))
+		resourceRouter.POST("/"+res.GetName()+"/query", GenerateListQueryHandlerWithDTO(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationRead) {
-		router.GET("/"+res.GetName()+"/:"+idParamName, GenerateGetHandlerWithParamAndDTO(res, repo, idParamName, dtoProvider))
+		resourceRouter.GET("/"+res.GetName()+"/:"+idParamName, GenerateGetHandlerWithParamAndDTO(res, repo, idParamName, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationCreate) {
-		router.POST("/"+res.GetName(), GenerateCreateHandler(res, repo, dtoProvider))
+		resourceRouter.POST("/"+res.GetName(), GenerateCreateHandler(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationUpdate) {
-		router.PUT("/"+res.GetName()+"/:"+idParamName, GenerateUpdateHandler(res, repo, dtoProvider))
+		resourceRouter.PUT("/"+res.GetName()+"/:"+idParamName, GenerateUpdateHandler(res, repo, dtoProvider))
+		resourceRouter.PATCH("/"+res.GetName()+"/:"+idParamName, GeneratePatchHandler(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationDelete) {
-		router.DELETE("/"+res.GetName()+"/:"+idParamName, GenerateDeleteHandler(res, repo))
+		resourceRouter.DELETE("/"+res.GetName()+"/:"+idParamName, GenerateDeleteHandler(res, repo))
 	}
 
+	// Register trash, restore and force delete handlers for soft-deleted models
+	registerSoftDeleteRoutes(resourceRouter, "/"+res.GetName(), res, repo, idParamName, dtoProvider)
+
+	// Register lookup-by-slug and slug regeneration handlers for slug fields
+	registerSlugRoutes(resourceRouter, "/"+res.GetName(), res, repo, idParamName, dtoProvider)
+
+	// Register the field validation endpoint backing AsyncValidator URLs
+	registerValidateRoutes(resourceRouter, "/"+res.GetName(), res, repo)
+
 	// Register count handler if the operation is allowed
 	if res.HasOperation(resource.OperationCount) {
-		router.GET("/"+res.GetName()+"/count", GenerateCountHandler(res, repo))
+		registerCountRoutes(resourceRouter, "/"+res.GetName()+"/count", GenerateCountHandler(res, repo))
+	}
+
+	// Register facets handler if the operation is allowed
+	if res.HasOperation(resource.OperationFacets) {
+		resourceRouter.GET("/"+res.GetName()+"/facets", GenerateFacetsHandler(res, repo))
+	}
+
+	// Register aggregate handler if the operation is allowed
+	if res.HasOperation(resource.OperationAggregate) {
+		resourceRouter.GET("/"+res.GetName()+"/aggregate", GenerateAggregateHandler(res, repo))
 	}
+
+	// Register CSV import handlers if the operation is allowed
+	if res.HasOperation(resource.OperationImport) {
+		resourceRouter.POST("/"+res.GetName()+"/import/inspect", GenerateImportInspectHandler(res))
+		resourceRouter.POST("/"+res.GetName()+"/import/run", GenerateImportRunHandler(res, repo, dtoProvider))
+	}
+
+	// Register export handler if the operation is allowed
+	if res.HasOperation(resource.OperationExport) {
+		resourceRouter.GET("/"+res.GetName()+"/export", GenerateExportHandler(res, repo, dtoProvider))
+	}
+
+	return resourceRouter.done()
 }
 
 // RegisterResourceWithDTO registers resource handlers with custom DTO provider
 func RegisterResourceWithDTO(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) {
+	MountResourceWithDTO(router, res, repo, dtoProvider)
+}
+
+// MountResourceWithDTO registers a resource like RegisterResourceWithDTO and returns
+// the manifest of the registered routes, e.g. to check them in tests
+func MountResourceWithDTO(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, dtoProvider dto.DTOProvider) RouteManifest {
 	// Register resource to registry
 	resource.RegisterToRegistry(res)
 
-	// Use default options with DTO provider
-	opts := resource.DefaultOptions()
-
-	// Create resource router with naming convention middleware
-	resourceRouter := router.Group("/"+res.GetName(), middleware.NamingConventionMiddleware(opts.NamingConvention))
+	// Create resource router with the naming convention of the default options
+	chain := resourceChain{NamingConvention: resource.DefaultOptions().NamingConvention}
+	resourceRouter := newRouteRecorder(router.Group("/"+res.GetName(), resourceMiddlewares(res, chain)...), res, "")
 
 	// Register OPTIONS handler for metadata
 	resourceRouter.OPTIONS("", GenerateOptionsHandler(res))
@@ -68,6 +119,7 @@ func RegisterResourceWithDTO(router *gin.RouterGroup, res resource.Resource, rep
 	// Register handlers for allowed operations
 	if res.HasOperation(resource.OperationList) {
 		resourceRouter.GET("", GenerateListHandlerWithDTO(res, repo, dtoProvider))
+		resourceRouter.POST("/query", GenerateListQueryHandlerWithDTO(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationCreate) {
@@ -80,19 +132,55 @@ func RegisterResourceWithDTO(router *gin.RouterGroup, res resource.Resource, rep
 
 	if res.HasOperation(resource.OperationUpdate) {
 		resourceRouter.PUT("/:id", GenerateUpdateHandler(res, repo, dtoProvider))
+		resourceRouter.PATCH("/:id", GeneratePatchHandler(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationDelete) {
 		resourceRouter.DELETE("/:id", GenerateDeleteHandler(res, repo))
 	}
 
+	registerSoftDeleteRoutes(resourceRouter, "", res, repo, "id", dtoProvider)
+
+	// Register lookup-by-slug and slug regeneration handlers for slug fields
+	registerSlugRoutes(resourceRouter, "", res, repo, "id", dtoProvider)
+
+	// Register the field validation endpoint backing AsyncValidator URLs
+	registerValidateRoutes(resourceRouter, "", res, repo)
+
 	if res.HasOperation(resource.OperationCount) {
-		resourceRouter.GET("/count", GenerateCountHandler(res, repo))
+		registerCountRoutes(resourceRouter, "/count", GenerateCountHandler(res, repo))
+	}
+
+	if res.HasOperation(resource.OperationFacets) {
+		resourceRouter.GET("/facets", GenerateFacetsHandler(res, repo))
+	}
+
+	if res.HasOperation(resource.OperationAggregate) {
+		resourceRouter.GET("/aggregate", GenerateAggregateHandler(res, repo))
 	}
+
+	// Register CSV import handlers if the operation is allowed
+	if res.HasOperation(resource.OperationImport) {
+		resourceRouter.POST("/import/inspect", GenerateImportInspectHandler(res))
+		resourceRouter.POST("/import/run", GenerateImportRunHandler(res, repo, dtoProvider))
+	}
+
+	// Register export handler if the operation is allowed
+	if res.HasOperation(resource.OperationExport) {
+		resourceRouter.GET("/export", GenerateExportHandler(res, repo, dtoProvider))
+	}
+
+	return resourceRouter.done()
 }
 
 // RegisterResourceWithOptions registers a resource with customizable options
 func RegisterResourceWithOptions(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, opts resource.Options) {
+	MountResourceWithOptions(router, res, repo, opts)
+}
+
+// MountResourceWithOptions registers a resource like RegisterResourceWithOptions and
+// returns the manifest of the registered routes, e.g. to check them in tests
+func MountResourceWithOptions(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, opts resource.Options) RouteManifest {
 	// Register resource to registry
 	resource.RegisterToRegistry(res)
 
@@ -102,28 +190,26 @@ func RegisterResourceWithOptions(router *gin.RouterGroup, res resource.Resource,
 		idParamName = paramName
 	}
 
-	// Create default DTO provider if not specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// DTO provider of the resource (the model unless it declares DTOs)
+	dtoProvider := dto.ForResource(res)
 
 	// Create resource router with naming convention middleware
-	resourceRouter := router.Group("/"+res.GetName(),
-		middleware.NamingConventionMiddleware(opts.NamingConvention),
-	)
+	chain := resourceChain{NamingConvention: opts.NamingConvention, StableJSON: opts.StableJSON, Strict: opts.Strict}
+	resourceRouter := newRouteRecorder(router.Group("/"+res.GetName(), resourceMiddlewares(res, chain)...), res, "")
 
 	// Register handlers for allowed operations
 	if res.HasOperation(resource.OperationList) {
-		resourceRouter.GET("", GenerateListHandler(res, repo))
+		resourceRouter.GET("", withMiddleware(HTTPCacheMiddleware(res, repo, opts.HTTPCache, resource.OperationList, idParamName), operationHandler(opts, resource.OperationList, GenerateListHandler(res, repo)))...)
+		resourceRouter.POST("/query", middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationList, GenerateListQueryHandler(res, repo)))
 	}
 
 	if res.HasOperation(resource.OperationCreate) {
 		// Dla operacji modyfikujących dane, wyłącz cache
-		resourceRouter.POST("", middleware.NoCacheMiddleware(), GenerateCreateHandler(res, repo, dtoProvider))
+		resourceRouter.POST("", middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationCreate, GenerateCreateHandler(res, repo, dtoProvider)))
 	}
 
 	if res.HasOperation(resource.OperationRead) {
-		resourceRouter.GET("/:"+idParamName, GenerateGetHandlerWithParam(res, repo, idParamName))
+		resourceRouter.GET("/:"+idParamName, withMiddleware(HTTPCacheMiddleware(res, repo, opts.HTTPCache, resource.OperationRead, idParamName), operationHandler(opts, resource.OperationRead, GenerateGetHandlerWithParam(res, repo, idParamName)))...)
 	}
 
 	if res.HasOperation(resource.OperationUpdate) {
@@ -131,33 +217,67 @@ func RegisterResourceWithOptions(router *gin.RouterGroup, res resource.Resource,
 		hasCustomID := res.GetIDFieldName() != "ID" && res.GetIDFieldName() != "id"
 		if hasCustomID {
 			// Use custom update handler for resources with non-standard ID fields
-			resourceRouter.PUT("/:"+idParamName, middleware.NoCacheMiddleware(), GenerateCustomUpdateHandler(res, repo, idParamName))
+			resourceRouter.PUT("/:"+idParamName, middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationUpdate, GenerateCustomUpdateHandler(res, repo, idParamName)))
 		} else {
 			// Use standard update handler
-			resourceRouter.PUT("/:"+idParamName, middleware.NoCacheMiddleware(), GenerateUpdateHandlerWithParam(res, repo, dtoProvider, idParamName))
+			resourceRouter.PUT("/:"+idParamName, middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationUpdate, GenerateUpdateHandlerWithParam(res, repo, dtoProvider, idParamName)))
 		}
+		resourceRouter.PATCH("/:"+idParamName, middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationUpdate, GeneratePatchHandlerWithParam(res, repo, dtoProvider, idParamName)))
 	}
 
 	if res.HasOperation(resource.OperationDelete) {
-		resourceRouter.DELETE("/:"+idParamName, middleware.NoCacheMiddleware(), GenerateDeleteHandlerWithParam(res, repo, idParamName))
+		resourceRouter.DELETE("/:"+idParamName, middleware.NoCacheMiddleware(), operationHandler(opts, resource.OperationDelete, GenerateDeleteHandlerWithParam(res, repo, idParamName)))
 	}
 
+	registerSoftDeleteRoutes(resourceRouter, "", res, repo, idParamName, dtoProvider)
+
+	// Register lookup-by-slug and slug regeneration handlers for slug fields
+	registerSlugRoutes(resourceRouter, "", res, repo, idParamName, dtoProvider)
+
+	// Register the field validation endpoint backing AsyncValidator URLs
+	registerValidateRoutes(resourceRouter, "", res, repo)
+
 	if res.HasOperation(resource.OperationCount) {
-		resourceRouter.GET("/count", GenerateCountHandler(res, repo))
+		registerCountRoutes(resourceRouter, "/count", operationHandler(opts, resource.OperationCount, GenerateCountHandler(res, repo)))
 	}
+
+	if res.HasOperation(resource.OperationFacets) {
+		resourceRouter.GET("/facets", operationHandler(opts, resource.OperationFacets, GenerateFacetsHandler(res, repo)))
+	}
+
+	if res.HasOperation(resource.OperationAggregate) {
+		resourceRouter.GET("/aggregate", operationHandler(opts, resource.OperationAggregate, GenerateAggregateHandler(res, repo)))
+	}
+
+	// Register CSV import handlers if the operation is allowed
+	if res.HasOperation(resource.OperationImport) {
+		resourceRouter.POST("/import/inspect", operationHandler(opts, resource.OperationImport, GenerateImportInspectHandler(res)))
+		resourceRouter.POST("/import/run", operationHandler(opts, resource.OperationImport, GenerateImportRunHandler(res, repo, dtoProvider)))
+	}
+
+	// Register export handler if the operation is allowed
+	if res.HasOperation(resource.OperationExport) {
+		resourceRouter.GET("/export", operationHandler(opts, resource.OperationExport, GenerateExportHandler(res, repo, dtoProvider)))
+	}
+
+	return resourceRouter.done()
 }
 
 // RegisterResourceForRefine registers resource handlers optimized for Refine.dev
 // The idParamName parameter allows specifying a custom ID parameter name for the resource
 // This is useful for resources that use a non-standard ID field (not 'id')
 func RegisterResourceForRefine(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, idParamName string) {
+	MountResourceForRefine(router, res, repo, idParamName)
+}
+
+// MountResourceForRefine registers a resource like RegisterResourceForRefine and
+// returns the manifest of the registered routes, e.g. to check them in tests
+func MountResourceForRefine(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, idParamName string) RouteManifest {
 	// Register resource to registry
 	resource.RegisterToRegistry(res)
 
-	// Create default DTO provider if not specified
-	dtoProvider := &dto.DefaultDTOProvider{
-		Model: res.GetModel(),
-	}
+	// DTO provider of the resource (the model unless it declares DTOs)
+	dtoProvider := dto.ForResource(res)
 
 	// If idParamName is empty, use default "id"
 	if idParamName == "" {
@@ -169,10 +289,11 @@ func RegisterResourceForRefine(router *gin.RouterGroup, res resource.Resource, r
 	cacheConfig.Methods = append(cacheConfig.Methods, "OPTIONS")
 
 	// Create resource router with naming convention middleware - default to camelCase for Refine.dev
-	resourceRouter := router.Group("/"+res.GetName(),
-		middleware.NamingConventionMiddleware(resource.DefaultOptions().NamingConvention),
-		middleware.CacheByResource(res.GetName(), cacheConfig), // Dodaj middleware cache dla całego zasobu
-	)
+	chain := resourceChain{
+		NamingConvention: resource.DefaultOptions().NamingConvention,
+		Cache:            middleware.CacheByResource(res.GetName(), cacheConfig), // Dodaj middleware cache dla całego zasobu
+	}
+	resourceRouter := newRouteRecorder(router.Group("/"+res.GetName(), resourceMiddlewares(res, chain)...), res, "")
 
 	// Register OPTIONS handler for resource metadata
 	resourceRouter.OPTIONS("", GenerateOptionsHandler(res))
@@ -180,6 +301,7 @@ func RegisterResourceForRefine(router *gin.RouterGroup, res resource.Resource, r
 	// Register handlers for allowed operations
 	if res.HasOperation(resource.OperationList) {
 		resourceRouter.GET("", GenerateListHandlerWithDTO(res, repo, dtoProvider))
+		resourceRouter.POST("/query", middleware.NoCacheMiddleware(), GenerateListQueryHandlerWithDTO(res, repo, dtoProvider))
 	}
 
 	if res.HasOperation(resource.OperationCreate) {
@@ -201,14 +323,42 @@ func RegisterResourceForRefine(router *gin.RouterGroup, res resource.Resource, r
 			// Use standard update handler
 			resourceRouter.PUT("/:"+idParamName, middleware.NoCacheMiddleware(), GenerateUpdateHandlerWithParam(res, repo, dtoProvider, idParamName))
 		}
+		resourceRouter.PATCH("/:"+idParamName, middleware.NoCacheMiddleware(), GeneratePatchHandlerWithParam(res, repo, dtoProvider, idParamName))
 	}
 
 	if res.HasOperation(resource.OperationDelete) {
 		resourceRouter.DELETE("/:"+idParamName, middleware.NoCacheMiddleware(), GenerateDeleteHandlerWithParam(res, repo, idParamName))
 	}
 
+	registerSoftDeleteRoutes(resourceRouter, "", res, repo, idParamName, dtoProvider)
+
+	// Register lookup-by-slug and slug regeneration handlers for slug fields
+	registerSlugRoutes(resourceRouter, "", res, repo, idParamName, dtoProvider)
+
+	// Register the field validation endpoint backing AsyncValidator URLs
+	registerValidateRoutes(resourceRouter, "", res, repo)
+
 	if res.HasOperation(resource.OperationCount) {
-		resourceRouter.GET("/count", GenerateCountHandler(res, repo))
+		registerCountRoutes(resourceRouter, "/count", GenerateCountHandler(res, repo))
+	}
+
+	if res.HasOperation(resource.OperationFacets) {
+		resourceRouter.GET("/facets", GenerateFacetsHandler(res, repo))
+	}
+
+	if res.HasOperation(resource.OperationAggregate) {
+		resourceRouter.GET("/aggregate", GenerateAggregateHandler(res, repo))
+	}
+
+	// Register CSV import handlers if the operation is allowed
+	if res.HasOperation(resource.OperationImport) {
+		resourceRouter.POST("/import/inspect", GenerateImportInspectHandler(res))
+		resourceRouter.POST("/import/run", GenerateImportRunHandler(res, repo, dtoProvider))
+	}
+
+	// Register export handler if the operation is allowed
+	if res.HasOperation(resource.OperationExport) {
+		resourceRouter.GET("/export", GenerateExportHandler(res, repo, dtoProvider))
 	}
 
 	// Register handlers for bulk operations
@@ -226,6 +376,64 @@ func RegisterResourceForRefine(router *gin.RouterGroup, res resource.Resource, r
 		// DELETE /resources/batch for deleting multiple resources
 		resourceRouter.DELETE("/batch", middleware.NoCacheMiddleware(), GenerateDeleteManyHandler(res, repo))
 	}
+
+	return resourceRouter.done()
+}
+
+// resourceChain selects the optional middlewares of the routes of a resource (see
+// resourceMiddlewares)
+type resourceChain struct {
+	// NamingConvention converts the keys of bodies, none if empty
+	NamingConvention naming.NamingConvention
+
+	// StableJSON orders the keys of response bodies like the fields of the resource
+	StableJSON bool
+
+	// Cache caches the responses of the resource, none if nil
+	Cache gin.HandlerFunc
+
+	// Strict rejects unknown query parameters and body fields (see StrictMode)
+	Strict bool
+}
+
+// resourceMiddlewares returns the middlewares all routes of a resource run through,
+// whichever function mounts them: query explanations, the request context, JSON
+// schemas, field aliases, bit flags, versions and RBAC, with the optional ones of chain
+func resourceMiddlewares(res resource.Resource, chain resourceChain) []gin.HandlerFunc {
+	middlewares := []gin.HandlerFunc{explain.Middleware()}
+	if chain.StableJSON {
+		// Registered first so it reorders the body after naming conversion
+		middlewares = append(middlewares, middleware.StableJSON(FieldOrder(res)))
+	}
+	middlewares = append(middlewares, requestctx.Middleware(res), JSONSchemaMiddleware(res))
+	if chain.NamingConvention != "" {
+		middlewares = append(middlewares, middleware.NamingConventionMiddleware(chain.NamingConvention))
+	}
+	if chain.Cache != nil {
+		middlewares = append(middlewares, chain.Cache)
+	}
+	middlewares = append(middlewares, FieldAliasMiddleware(res), FlagsMiddleware(res), VersionMiddleware(res), RBACMiddleware(res))
+	if chain.Strict {
+		middlewares = append(middlewares, StrictMode(res, StrictConfig{}))
+	}
+	return middlewares
+}
+
+// operationHandler returns the handler override of an operation (see
+// resource.Options.WithHandlerOverride), or the generated handler
+func operationHandler(opts resource.Options, op resource.Operation, generated gin.HandlerFunc) gin.HandlerFunc {
+	if override := opts.GetHandlerOverride(op); override != nil {
+		return override
+	}
+	return generated
+}
+
+// withMiddleware returns the handlers of a route: an optional middleware and the handler
+func withMiddleware(mw gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	if mw == nil {
+		return []gin.HandlerFunc{handler}
+	}
+	return []gin.HandlerFunc{mw, handler}
 }
 
 // RegisterOptions zawiera opcje rejestracji zasobu
@@ -245,3 +453,14 @@ func RegisterOptionsToResourceOptions(regOpts RegisterOptions) resource.Options
 
 	return opts
 }
+
+// FieldOrder returns the field names of a resource in metadata order, for use with
+// middleware.StableJSON
+func FieldOrder(res resource.Resource) []string {
+	fields := res.GetFields()
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.APIName())
+	}
+	return names
+}
@@ -3,12 +3,16 @@ package handler
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/naming"
 	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -84,3 +88,104 @@ func TestRegisterResourceEndpoints(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterResourceWithHandlerOverride(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RegisterTestEntity{}))
+	require.NoError(t, db.Create(&RegisterTestEntity{ID: 1, Name: "Generated"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "entities",
+		Model: &RegisterTestEntity{},
+		Operations: []resource.Operation{
+			resource.OperationList,
+			resource.OperationRead,
+			resource.OperationImport,
+		},
+	})
+	repo := repository.NewGenericRepository(db, &RegisterTestEntity{})
+
+	opts := resource.DefaultOptions().
+		WithHandlerOverride(resource.OperationList, func(c *gin.Context) {
+			current, _ := requestctx.Resource.Get(c)
+			c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"name": "Overridden"}}, "resource": current.GetName()})
+		}).
+		WithHandlerOverride(resource.OperationImport, func(c *gin.Context) {
+			c.JSON(http.StatusAccepted, gin.H{"imported": false})
+		})
+	RegisterResourceWithOptions(router.Group("/api"), res, repo, opts)
+
+	// The override keeps the middleware of the resource
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entities", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[{"name":"Overridden"}],"resource":"entities"}`, w.Body.String())
+
+	// Other operations keep their generated handlers
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/entities/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Generated")
+
+	// Import routes are overridden too
+	for _, path := range []string{"/api/entities/import/inspect", "/api/entities/import/run"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, http.StatusAccepted, w.Code, path)
+	}
+}
+
+// LegacyNamingEntity is a legacy model with snake_case JSON names
+type LegacyNamingEntity struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	DisplayName string `json:"display_name"`
+}
+
+// CamelNamingEntity is a model with camelCase JSON names
+type CamelNamingEntity struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	DisplayName string `json:"displayName"`
+}
+
+func TestRegisterResourceWithOptionsNamingConvention(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&LegacyNamingEntity{}, &CamelNamingEntity{}))
+
+	operations := []resource.Operation{resource.OperationCreate}
+	legacy := resource.NewResource(resource.ResourceConfig{Name: "legacy", Model: &LegacyNamingEntity{}, Operations: operations})
+	modern := resource.NewResource(resource.ResourceConfig{Name: "modern", Model: &CamelNamingEntity{}, Operations: operations})
+
+	// The API uses camelCase, the legacy resource keeps snake_case
+	router := gin.New()
+	api := router.Group("/api", middleware.NamingConventionMiddleware(naming.CamelCase))
+	RegisterResourceWithOptions(api, legacy, repository.NewGenericRepositoryWithResource(db, legacy),
+		resource.DefaultOptions().WithNamingConvention(naming.SnakeCase))
+	RegisterResourceWithOptions(api, modern, repository.NewGenericRepositoryWithResource(db, modern),
+		resource.DefaultOptions().WithNamingConvention(naming.CamelCase))
+
+	create := func(path string) string {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"display_name":"A","displayName":"A"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	assert.Contains(t, create("/api/legacy"), `"display_name":"A"`)
+	assert.Contains(t, create("/api/modern"), `"displayName":"A"`)
+
+	var stored LegacyNamingEntity
+	require.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, "A", stored.DisplayName)
+}
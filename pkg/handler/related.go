@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/explain"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/naming"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// RelatedParam is the route parameter of the related record ID in relation routes
+const RelatedParam = "relatedId"
+
+// RegisterRelationRoutes registers endpoints linking records of a resource to related
+// records through one-to-many and many-to-many relations, e.g. for posts and tags:
+//
+//	POST   /posts/:id/tags             attach the tags of the body ({"ids": [1, 2]})
+//	PUT    /posts/:id/tags             link exactly the tags of the body
+//	DELETE /posts/:id/tags/:relatedId  detach a tag
+//
+// The related records are neither created nor deleted. relations names the relations
+// in any naming convention, all to-many relations of the resource if empty; their
+// routes use snake_case names. The resource needs the update operation, which RBAC
+// checks, and repo must implement repository.RelationRepository.
+func RegisterRelationRoutes(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, relations ...string) {
+	RegisterRelationRoutesWithParams(router, res, repo, RouteParams{}, relations...)
+}
+
+// RegisterRelationRoutesWithParams registers relation routes like
+// RegisterRelationRoutes, with the record ID in the Parent route parameter of params
+// (default "id") and the related record ID in the Child one (default RelatedParam),
+// e.g. /posts/:postId/tags/:tagId.
+func RegisterRelationRoutesWithParams(router *gin.RouterGroup, res resource.Resource, repo repository.Repository, params RouteParams, relations ...string) {
+	relationRepo, ok := repo.(repository.RelationRepository)
+	if !ok {
+		panic(fmt.Sprintf("relation routes of %s need a repository.RelationRepository", res.GetName()))
+	}
+	if !res.HasOperation(resource.OperationUpdate) {
+		return
+	}
+
+	var managed []resource.Relation
+	if len(relations) == 0 {
+		for _, relation := range res.GetRelations() {
+			if isManagedRelation(relation) {
+				managed = append(managed, relation)
+			}
+		}
+	}
+	for _, name := range relations {
+		relation, ok := managedRelation(res, name)
+		if !ok {
+			panic(fmt.Sprintf("%s has no one-to-many or many-to-many relation %q", res.GetName(), name))
+		}
+		managed = append(managed, relation)
+	}
+
+	params = params.withDefaults("id", RelatedParam)
+	group := router.Group("/"+res.GetName(), explain.Middleware(), requestctx.Middleware(res), RBACMiddleware(res), middleware.NoCacheMiddleware())
+	for _, relation := range managed {
+		path := "/:" + params.Parent + "/" + naming.ToSnakeCase(relation.Name)
+		group.POST(path, GenerateAttachRelatedHandlerWithParam(res, relationRepo, relation.Name, params.Parent))
+		group.PUT(path, GenerateReplaceRelatedHandlerWithParam(res, relationRepo, relation.Name, params.Parent))
+		group.DELETE(path+"/:"+params.Child, GenerateDetachRelatedHandlerWithParams(res, relationRepo, relation.Name, params))
+	}
+}
+
+// GenerateAttachRelatedHandler generates a handler linking the related records of a
+// RelationRequest body to a record
+func GenerateAttachRelatedHandler(res resource.Resource, repo repository.RelationRepository, relation string) gin.HandlerFunc {
+	return GenerateAttachRelatedHandlerWithParam(res, repo, relation, "id")
+}
+
+// GenerateAttachRelatedHandlerWithParam generates an attach handler for routes with
+// the record ID in the idParamName parameter
+func GenerateAttachRelatedHandlerWithParam(res resource.Resource, repo repository.RelationRepository, relation string, idParamName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RelationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		if len(req.IDs) == 0 {
+			respondErrorMessage(c, http.StatusBadRequest, "no IDs provided")
+			return
+		}
+		writeRelatedResult(c, repo.AttachRelated(c.Request.Context(), c.Param(idParamName), relation, req.IDs))
+	}
+}
+
+// GenerateReplaceRelatedHandler generates a handler linking exactly the related
+// records of a RelationRequest body to a record. An empty list unlinks them all.
+func GenerateReplaceRelatedHandler(res resource.Resource, repo repository.RelationRepository, relation string) gin.HandlerFunc {
+	return GenerateReplaceRelatedHandlerWithParam(res, repo, relation, "id")
+}
+
+// GenerateReplaceRelatedHandlerWithParam generates a replace handler for routes with
+// the record ID in the idParamName parameter
+func GenerateReplaceRelatedHandlerWithParam(res resource.Resource, repo repository.RelationRepository, relation string, idParamName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RelationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		writeRelatedResult(c, repo.ReplaceRelated(c.Request.Context(), c.Param(idParamName), relation, req.IDs))
+	}
+}
+
+// GenerateDetachRelatedHandler generates a handler unlinking the related record of the
+// route from a record
+func GenerateDetachRelatedHandler(res resource.Resource, repo repository.RelationRepository, relation string) gin.HandlerFunc {
+	return GenerateDetachRelatedHandlerWithParams(res, repo, relation, RouteParams{})
+}
+
+// GenerateDetachRelatedHandlerWithParams generates a detach handler for routes with the
+// record and related record IDs in the route parameters of params
+func GenerateDetachRelatedHandlerWithParams(res resource.Resource, repo repository.RelationRepository, relation string, params RouteParams) gin.HandlerFunc {
+	params = params.withDefaults("id", RelatedParam)
+	return func(c *gin.Context) {
+		ids := []interface{}{c.Param(params.Child)}
+		writeRelatedResult(c, repo.DetachRelated(c.Request.Context(), c.Param(params.Parent), relation, ids))
+	}
+}
+
+// writeRelatedResult answers a relation change with 204 No Content, or its error
+func writeRelatedResult(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.Status(http.StatusNoContent)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+	case errors.Is(err, repository.ErrRelatedNotFound):
+		respondError(c, http.StatusUnprocessableEntity, err)
+	default:
+		respondError(c, http.StatusInternalServerError, err)
+	}
+}
+
+// managedRelation returns the one-to-many or many-to-many relation of a resource with
+// the name in any naming convention
+func managedRelation(res resource.Resource, name string) (resource.Relation, bool) {
+	for _, relation := range res.GetRelations() {
+		if isManagedRelation(relation) && normalizeBodyKey(relation.Name) == normalizeBodyKey(name) {
+			return relation, true
+		}
+	}
+	return resource.Relation{}, false
+}
+
+// isManagedRelation reports whether relation routes can manage a relation
+func isManagedRelation(relation resource.Relation) bool {
+	return relation.Type == resource.RelationTypeOneToMany || relation.Type == resource.RelationTypeManyToMany
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TaggedArticle struct {
+	ID       uint             `json:"id" gorm:"primaryKey"`
+	Title    string           `json:"title"`
+	Tags     []ArticleTag     `json:"tags" gorm:"many2many:tagged_article_tags" relation:"resource=tags;type=many-to-many"`
+	Comments []ArticleComment `json:"comments" gorm:"foreignKey:ArticleID" relation:"resource=comments;type=one-to-many"`
+	Author   *ArticleTag      `json:"author" relation:"resource=authors;type=many-to-one"`
+	AuthorID *uint            `json:"author_id"`
+}
+
+type ArticleTag struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type ArticleComment struct {
+	ID        uint  `json:"id" gorm:"primaryKey"`
+	ArticleID *uint `json:"article_id"`
+}
+
+func TestRelationRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ArticleTag{}, &TaggedArticle{}, &ArticleComment{}))
+	require.NoError(t, db.Create(&[]ArticleTag{{Name: "go"}, {Name: "sql"}, {Name: "web"}}).Error)
+	require.NoError(t, db.Create(&ArticleComment{}).Error)
+	require.NoError(t, db.Create(&TaggedArticle{Title: "Hello"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:        "articles",
+		Model:       TaggedArticle{},
+		Operations:  []resource.Operation{resource.OperationRead, resource.OperationUpdate},
+		Permissions: map[string][]string{"update": {"editor"}},
+	})
+	repo := repository.NewGenericRepositoryWithResource(db, res)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"roles": []interface{}{c.GetHeader("X-Roles")}})
+	})
+	RegisterResource(r.Group(""), res, repo)
+	RegisterRelationRoutes(r.Group(""), res, repo)
+
+	tagIDs := func() []uint {
+		var article TaggedArticle
+		require.NoError(t, db.Preload("Tags").First(&article, 1).Error)
+		ids := []uint{}
+		for _, tag := range article.Tags {
+			ids = append(ids, tag.ID)
+		}
+		return ids
+	}
+
+	w := rbacRequest(r, http.MethodPost, "/articles/1/tags", "editor", `{"ids":[1,2]}`)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []uint{1, 2}, tagIDs())
+
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodDelete, "/articles/1/tags/1", "editor", "").Code)
+	assert.ElementsMatch(t, []uint{2}, tagIDs())
+
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodPut, "/articles/1/tags", "editor", `{"ids":[3]}`).Code)
+	assert.ElementsMatch(t, []uint{3}, tagIDs())
+
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodPost, "/articles/1/comments", "editor", `{"ids":[1]}`).Code)
+	var comment ArticleComment
+	require.NoError(t, db.First(&comment, 1).Error)
+	require.NotNil(t, comment.ArticleID)
+	assert.Equal(t, uint(1), *comment.ArticleID)
+
+	// Linking related records needs the update permission
+	assert.Equal(t, http.StatusForbidden, rbacRequest(r, http.MethodPut, "/articles/1/tags", "viewer", `{"ids":[]}`).Code)
+	assert.ElementsMatch(t, []uint{3}, tagIDs())
+
+	assert.Equal(t, http.StatusBadRequest, rbacRequest(r, http.MethodPost, "/articles/1/tags", "editor", `{"ids":[]}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, rbacRequest(r, http.MethodPost, "/articles/1/tags", "editor", `{"ids":[7]}`).Code)
+	assert.Equal(t, http.StatusNotFound, rbacRequest(r, http.MethodPost, "/articles/9/tags", "editor", `{"ids":[1]}`).Code)
+
+	// Only to-many relations get routes
+	assert.Equal(t, http.StatusNotFound, rbacRequest(r, http.MethodPost, "/articles/1/author", "editor", `{"ids":[1]}`).Code)
+	assert.Panics(t, func() { RegisterRelationRoutes(gin.New().Group(""), res, repo, "author") })
+}
+
+func TestRelationRoutesWithParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ArticleTag{}, &TaggedArticle{}, &ArticleComment{}))
+	require.NoError(t, db.Create(&[]ArticleTag{{Name: "go"}, {Name: "sql"}}).Error)
+	require.NoError(t, db.Create(&TaggedArticle{Title: "Hello"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "articles",
+		Model:      TaggedArticle{},
+		Operations: []resource.Operation{resource.OperationRead, resource.OperationUpdate},
+	})
+	repo := repository.NewGenericRepositoryWithResource(db, res)
+
+	r := gin.New()
+	RegisterResourceForRefine(r.Group(""), res, repo, "articleId")
+	RegisterRelationRoutesWithParams(r.Group(""), res, repo, RouteParams{Parent: "articleId", Child: "tagId"}, "tags")
+
+	tagIDs := func() []uint {
+		var article TaggedArticle
+		require.NoError(t, db.Preload("Tags").First(&article, 1).Error)
+		ids := []uint{}
+		for _, tag := range article.Tags {
+			ids = append(ids, tag.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodPost, "/articles/1/tags", "", `{"ids":[1,2]}`).Code)
+	assert.ElementsMatch(t, []uint{1, 2}, tagIDs())
+	assert.Equal(t, http.StatusNoContent, rbacRequest(r, http.MethodDelete, "/articles/1/tags/2", "", "").Code)
+	assert.ElementsMatch(t, []uint{1}, tagIDs())
+	assert.Equal(t, http.StatusNotFound, rbacRequest(r, http.MethodPut, "/articles/9/tags", "", `{"ids":[]}`).Code)
+}
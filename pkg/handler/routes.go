@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// RoutesPath is the path of the routes endpoint (see RegisterRoutesEndpoint)
+const RoutesPath = "/_routes"
+
+// RouteInfo describes a route registered for a resource
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// Operation served by the route, empty for metadata and helper routes (OPTIONS,
+	// trash, slugs, field validation)
+	Operation resource.Operation `json:"operation,omitempty"`
+
+	// Middleware are the names of the handlers running before the route handler, the
+	// group middleware first
+	Middleware []string `json:"middleware"`
+
+	// Handler is the name of the route handler
+	Handler string `json:"handler"`
+}
+
+// RouteManifest lists the routes registered for a resource, in registration order
+type RouteManifest struct {
+	Resource string      `json:"resource"`
+	BasePath string      `json:"basePath"`
+	Routes   []RouteInfo `json:"routes"`
+}
+
+// Route returns the route of a method and path
+func (m RouteManifest) Route(method, path string) (RouteInfo, bool) {
+	for _, route := range m.Routes {
+		if route.Method == method && route.Path == path {
+			return route, true
+		}
+	}
+	return RouteInfo{}, false
+}
+
+// Operations returns the routes serving an operation
+func (m RouteManifest) Operations(op resource.Operation) []RouteInfo {
+	var routes []RouteInfo
+	for _, route := range m.Routes {
+		if route.Operation == op {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// routeRegistrar registers routes, on a router group or through a routeRecorder
+type routeRegistrar interface {
+	GET(string, ...gin.HandlerFunc) gin.IRoutes
+	POST(string, ...gin.HandlerFunc) gin.IRoutes
+	PUT(string, ...gin.HandlerFunc) gin.IRoutes
+	PATCH(string, ...gin.HandlerFunc) gin.IRoutes
+	DELETE(string, ...gin.HandlerFunc) gin.IRoutes
+	OPTIONS(string, ...gin.HandlerFunc) gin.IRoutes
+}
+
+// routeRecorder registers routes on a router group and records them in the manifest
+// of a resource
+type routeRecorder struct {
+	group    *gin.RouterGroup
+	manifest *RouteManifest
+}
+
+// newRouteRecorder creates the recorder of the routes of a resource served under
+// basePath (the path of group if empty), registered on group
+func newRouteRecorder(group *gin.RouterGroup, res resource.Resource, basePath string) *routeRecorder {
+	if basePath == "" {
+		basePath = group.BasePath()
+	}
+	return &routeRecorder{group: group, manifest: &RouteManifest{Resource: res.GetName(), BasePath: basePath}}
+}
+
+func (r *routeRecorder) handle(method, relativePath string, handlers []gin.HandlerFunc) gin.IRoutes {
+	path := r.group.BasePath()
+	if relativePath != "" {
+		path = strings.TrimSuffix(path, "/") + relativePath
+	}
+	info := RouteInfo{
+		Method:     method,
+		Path:       path,
+		Operation:  routeOperation(method, strings.TrimPrefix(path, r.manifest.BasePath)),
+		Middleware: []string{},
+	}
+	chain := append(append([]gin.HandlerFunc{}, r.group.Handlers...), handlers...)
+	for i, h := range chain {
+		if i == len(chain)-1 {
+			info.Handler = handlerName(h)
+		} else {
+			info.Middleware = append(info.Middleware, handlerName(h))
+		}
+	}
+	r.manifest.Routes = append(r.manifest.Routes, info)
+	return r.group.Handle(method, relativePath, handlers...)
+}
+
+// GET registers and records a GET route
+func (r *routeRecorder) GET(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodGet, path, handlers)
+}
+
+// POST registers and records a POST route
+func (r *routeRecorder) POST(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPost, path, handlers)
+}
+
+// PUT registers and records a PUT route
+func (r *routeRecorder) PUT(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPut, path, handlers)
+}
+
+// PATCH registers and records a PATCH route
+func (r *routeRecorder) PATCH(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodPatch, path, handlers)
+}
+
+// DELETE registers and records a DELETE route
+func (r *routeRecorder) DELETE(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodDelete, path, handlers)
+}
+
+// OPTIONS registers and records an OPTIONS route
+func (r *routeRecorder) OPTIONS(path string, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return r.handle(http.MethodOptions, path, handlers)
+}
+
+// done stores the manifest for the routes endpoint and returns it
+func (r *routeRecorder) done() RouteManifest {
+	manifests.Store(r.manifest.BasePath, *r.manifest)
+	return *r.manifest
+}
+
+// manifests are the route manifests of the registered resources by base path
+var manifests sync.Map
+
+// RouteManifests returns the manifests of the resources registered so far, sorted by
+// base path. Resources registered again under the same path replace their manifest.
+func RouteManifests() []RouteManifest {
+	var list []RouteManifest
+	manifests.Range(func(_, value interface{}) bool {
+		list = append(list, value.(RouteManifest))
+		return true
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].BasePath < list[j].BasePath
+	})
+	return list
+}
+
+// RegisterRoutesEndpoint registers GET /_routes, listing the routes of the registered
+// resources (see RouteManifests). It only answers in gin's debug mode, as route lists
+// help attackers; other modes get 404.
+func RegisterRoutesEndpoint(router *gin.RouterGroup) {
+	router.GET(RoutesPath, func(c *gin.Context) {
+		if !gin.IsDebugging() {
+			respondErrorMessage(c, http.StatusNotFound, "Not found")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": RouteManifests()})
+	})
+}
+
+// routeOperations maps the method and path (below the resource path, IDs replaced by
+// ":id") of the routes of operations to the operations
+var routeOperations = map[string]resource.Operation{
+	"GET ":                 resource.OperationList,
+	"POST /query":          resource.OperationList,
+	"POST ":                resource.OperationCreate,
+	"GET /:id":             resource.OperationRead,
+	"PUT /:id":             resource.OperationUpdate,
+	"PATCH /:id":           resource.OperationUpdate,
+	"DELETE /:id":          resource.OperationDelete,
+	"GET /count":           resource.OperationCount,
+	"POST /count":          resource.OperationCount,
+	"GET /facets":          resource.OperationFacets,
+	"GET /aggregate":       resource.OperationAggregate,
+	"POST /import/inspect": resource.OperationImport,
+	"POST /import/run":     resource.OperationImport,
+	"GET /export":          resource.OperationExport,
+	"POST /batch":          resource.OperationCreateMany,
+	"PUT /batch":           resource.OperationUpdateMany,
+	"DELETE /batch":        resource.OperationDeleteMany,
+}
+
+var pathParam = regexp.MustCompile(`^:[^/]+$`)
+
+// routeOperation returns the operation of a route of a resource
+func routeOperation(method, subPath string) resource.Operation {
+	segments := strings.Split(subPath, "/")
+	if len(segments) == 2 && pathParam.MatchString(segments[1]) {
+		subPath = "/:id"
+	}
+	return routeOperations[method+" "+subPath]
+}
+
+var funcSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// handlerName returns the short name of a handler, e.g. "handler.RBACMiddleware"
+func handlerName(h gin.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	name = funcSuffix.ReplaceAllString(name, "")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
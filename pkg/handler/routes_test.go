@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type RoutedNote struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+}
+
+func TestMountResourceManifest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RoutedNote{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "routed-notes",
+		Model: RoutedNote{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+		},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationCreate},
+	})
+	r := gin.New()
+	manifest := MountResourceForRefine(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res), "noteId")
+
+	assert.Equal(t, "routed-notes", manifest.Resource)
+	assert.Equal(t, "/api/routed-notes", manifest.BasePath)
+
+	list, ok := manifest.Route(http.MethodGet, "/api/routed-notes")
+	require.True(t, ok)
+	assert.Equal(t, resource.OperationList, list.Operation)
+	assert.Contains(t, list.Middleware, "handler.RBACMiddleware")
+	assert.NotEmpty(t, list.Handler)
+
+	read, ok := manifest.Route(http.MethodGet, "/api/routed-notes/:noteId")
+	require.True(t, ok)
+	assert.Equal(t, resource.OperationRead, read.Operation)
+
+	assert.Len(t, manifest.Operations(resource.OperationCreate), 1)
+	assert.Empty(t, manifest.Operations(resource.OperationDelete))
+	_, ok = manifest.Route(http.MethodDelete, "/api/routed-notes/:noteId")
+	assert.False(t, ok)
+
+	// The manifest describes the routes actually served
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/routed-notes", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestRoutesEndpoint(t *testing.T) {
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RoutedNote{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "listed-notes",
+		Model:      RoutedNote{},
+		Fields:     []resource.Field{{Name: "id", Type: "int"}, {Name: "title", Type: "string"}},
+		Operations: []resource.Operation{resource.OperationList},
+	})
+	r := gin.New()
+	api := r.Group("/api")
+	MountResourceWithOptions(api, res, repository.NewGenericRepositoryWithResource(db, res), resource.DefaultOptions())
+	RegisterRoutesEndpoint(api)
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api"+RoutesPath, nil))
+		return w
+	}
+
+	// Routes are only listed in debug mode
+	gin.SetMode(gin.TestMode)
+	assert.Equal(t, http.StatusNotFound, request().Code)
+
+	gin.SetMode(gin.DebugMode)
+	defer gin.SetMode(gin.TestMode)
+	w := request()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		Data []RouteManifest `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	var listed *RouteManifest
+	for i := range body.Data {
+		if body.Data[i].BasePath == "/api/listed-notes" {
+			listed = &body.Data[i]
+		}
+	}
+	require.NotNil(t, listed)
+	route, ok := listed.Route(http.MethodGet, "/api/listed-notes")
+	require.True(t, ok)
+	assert.Equal(t, resource.OperationList, route.Operation)
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// SavedViewsResourceName is the name of the saved views resource
+const SavedViewsResourceName = "saved_views"
+
+// SavedView is a named list configuration of a resource saved by a user: the filters,
+// sorters, visible columns and page size of a table, in the format of refine's
+// useTable
+type SavedView struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	OwnerID  string `json:"ownerId" gorm:"size:191;not null;index:idx_refine_saved_views_owner_resource"`
+	Resource string `json:"resource" gorm:"size:191;not null;index:idx_refine_saved_views_owner_resource"`
+	Name     string `json:"name" gorm:"size:191;not null"`
+
+	Filters  []query.FilterNode `json:"filters,omitempty" gorm:"serializer:json"`
+	Sorters  []query.SortOption `json:"sorters,omitempty" gorm:"serializer:json"`
+	Columns  []string           `json:"columns,omitempty" gorm:"serializer:json"`
+	PageSize int                `json:"pageSize,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName returns the table storing saved views
+func (SavedView) TableName() string {
+	return "refine_saved_views"
+}
+
+// RegisterSavedViews registers the saved_views owner resource, storing the saved views
+// of each user in the refine_saved_views table (migrate it with
+// db.AutoMigrate(&handler.SavedView{})):
+//
+//	GET    /saved_views?resource=posts  list the views of the user for posts
+//	POST   /saved_views                 save a view
+//	GET    /saved_views/:id             read a view
+//	PUT    /saved_views/:id             update it
+//	DELETE /saved_views/:id             delete it
+//
+// Views are scoped to the owner of the request, so the group must use
+// middleware.OwnerContext. A view must name a registered resource, and its sorters and
+// columns fields of that resource. Once registered, the metadata of resources (OPTIONS)
+// links to their views.
+func RegisterSavedViews(router *gin.RouterGroup, db *gorm.DB) resource.OwnerResource {
+	hooks := &resource.LifecycleHooks{
+		BeforeCreate: validateSavedView,
+		BeforeUpdate: validateSavedView,
+	}
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:  SavedViewsResourceName,
+		Label: "Saved views",
+		Model: SavedView{},
+		Operations: []resource.Operation{
+			resource.OperationList,
+			resource.OperationRead,
+			resource.OperationCreate,
+			resource.OperationUpdate,
+			resource.OperationDelete,
+			resource.OperationCount,
+		},
+		DefaultSort: &resource.Sort{Field: "name", Order: "asc"},
+		Hooks:       hooks,
+	}), resource.OwnerConfig{OwnerField: "OwnerID", EnforceOwnership: true})
+
+	repo, err := repository.NewOwnerRepository(db, res)
+	if err != nil {
+		panic(fmt.Sprintf("saved views: %v", err))
+	}
+	resource.RegisterToRegistry(res)
+	RegisterOwnerResource(router, res, repo)
+	return res
+}
+
+// savedViewsMetadata links the metadata of a resource to its saved views, as the
+// resource and filters of a refine list, or returns nil unless saved views are
+// registered
+func savedViewsMetadata(res resource.Resource) gin.H {
+	if res.GetName() == SavedViewsResourceName {
+		return nil
+	}
+	if _, ok := resource.GlobalResourceRegistry.GetByName(SavedViewsResourceName); !ok {
+		return nil
+	}
+	return gin.H{
+		"resource": SavedViewsResourceName,
+		"filters":  []query.Filter{{Field: "resource", Operator: "eq", Value: res.GetName()}},
+	}
+}
+
+// validateSavedView checks a saved view against the resource it configures
+func validateSavedView(_ context.Context, _ resource.Resource, data interface{}) error {
+	view, ok := data.(*SavedView)
+	if !ok {
+		return nil
+	}
+	if strings.TrimSpace(view.Name) == "" {
+		return resource.NewHookError(http.StatusBadRequest, "name is required")
+	}
+	res, ok := resource.GlobalResourceRegistry.GetByName(view.Resource)
+	if !ok || view.Resource == SavedViewsResourceName {
+		return resource.NewHookError(http.StatusBadRequest, fmt.Sprintf("unknown resource '%s'", view.Resource))
+	}
+	if view.PageSize < 0 {
+		return resource.NewHookError(http.StatusBadRequest, "pageSize must be positive")
+	}
+
+	for _, sorter := range view.Sorters {
+		if res.GetField(sorter.Field) == nil {
+			return resource.NewHookError(http.StatusBadRequest, fmt.Sprintf("unknown sort field '%s'", sorter.Field))
+		}
+		if sorter.Order != "" && sorter.Order != "asc" && sorter.Order != "desc" {
+			return resource.NewHookError(http.StatusBadRequest, "order must be 'asc' or 'desc'")
+		}
+	}
+	for _, column := range view.Columns {
+		if res.GetField(column) == nil && res.GetRelation(column) == nil {
+			return resource.NewHookError(http.StatusBadRequest, fmt.Sprintf("unknown column '%s'", column))
+		}
+	}
+	return nil
+}
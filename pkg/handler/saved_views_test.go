@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ViewedArticle struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+func TestSavedViews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ViewedArticle{}, &SavedView{}))
+
+	articles := resource.NewResource(resource.ResourceConfig{
+		Name:       "viewed_articles",
+		Model:      ViewedArticle{},
+		Operations: []resource.Operation{resource.OperationList},
+	})
+	r := gin.New()
+	api := r.Group("/api", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID")))
+	RegisterResource(api, articles, repository.NewGenericRepositoryWithResource(db, articles))
+	RegisterSavedViews(api, db)
+
+	request := func(method, target, owner, body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Owner-ID", owner)
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	code, response := request(http.MethodPost, "/api/saved_views", "alice", `{
+		"resource": "viewed_articles",
+		"name": "Open articles",
+		"filters": [{"operator": "or", "value": [{"field": "status", "operator": "eq", "value": "open"}]}],
+		"sorters": [{"field": "title", "order": "asc"}],
+		"columns": ["title", "status"],
+		"pageSize": 50
+	}`)
+	require.Equal(t, http.StatusCreated, code, response)
+	view := response["data"].(map[string]interface{})
+	assert.Equal(t, "alice", view["ownerId"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"field": "title", "order": "asc"}}, view["sorters"])
+	assert.Len(t, view["filters"], 1)
+
+	// Views are scoped to their owner
+	_, response = request(http.MethodGet, "/api/saved_views?resource=viewed_articles", "alice", "")
+	assert.Equal(t, float64(1), response["total"])
+	_, response = request(http.MethodGet, "/api/saved_views?resource=viewed_articles", "bob", "")
+	assert.Equal(t, float64(0), response["total"])
+
+	// Views must configure fields of a registered resource
+	code, _ = request(http.MethodPost, "/api/saved_views", "alice", `{"resource": "viewed_articles", "name": "Bad", "sorters": [{"field": "missing"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPost, "/api/saved_views", "alice", `{"resource": "viewed_articles", "name": "Bad", "columns": ["missing"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPost, "/api/saved_views", "alice", `{"resource": "unknown", "name": "Bad"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(http.MethodPost, "/api/saved_views", "alice", `{"resource": "viewed_articles"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// The metadata of resources links to their views
+	_, response = request(http.MethodOptions, "/api/viewed_articles", "alice", "")
+	assert.Equal(t, map[string]interface{}{
+		"resource": "saved_views",
+		"filters":  []interface{}{map[string]interface{}{"field": "resource", "operator": "eq", "value": "viewed_articles"}},
+	}, response["savedViews"])
+}
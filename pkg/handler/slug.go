@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// registerSlugRoutes registers the lookup-by-slug and slug regeneration endpoints
+// under the resource router when the resource has slug fields
+func registerSlugRoutes(router routeRegistrar, prefix string, res resource.Resource, repo repository.Repository, idParamName string, dtoProvider dto.DTOProvider) {
+	slugRepo, ok := repo.(repository.SlugRepository)
+	if !ok || len(resource.SlugFields(res)) == 0 {
+		return
+	}
+
+	if res.HasOperation(resource.OperationRead) {
+		router.GET(prefix+"/slug/:slug", GenerateGetBySlugHandler(res, slugRepo, dtoProvider))
+	}
+	if res.HasOperation(resource.OperationUpdate) {
+		router.POST(prefix+"/:"+idParamName+"/slug/regenerate", middleware.NoCacheMiddleware(), GenerateRegenerateSlugHandler(res, slugRepo, idParamName, dtoProvider))
+	}
+}
+
+// slugField returns the slug field selected with ?field=, or the first slug field
+func slugField(c *gin.Context, res resource.Resource) (resource.Field, bool) {
+	fields := resource.SlugFields(res)
+	name := c.Query("field")
+	for _, field := range fields {
+		if name == "" || field.Name == name || field.APIName() == name {
+			return field, true
+		}
+	}
+	return resource.Field{}, false
+}
+
+// GenerateGetBySlugHandler generates a handler returning a record by its slug. Slugs
+// unique within a scope need the scope fields as query parameters
+// (GET /posts/slug/hello-world?categoryId=3).
+func GenerateGetBySlugHandler(res resource.Resource, repo repository.SlugRepository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field, ok := slugField(c, res)
+		if !ok {
+			respondErrorMessage(c, http.StatusBadRequest, "Unknown slug field")
+			return
+		}
+
+		scope := make(map[string]interface{})
+		for _, name := range field.Slug.Scope {
+			if value, ok := c.GetQuery(name); ok {
+				scope[name] = value
+			}
+		}
+
+		data, err := repo.GetBySlug(c.Request.Context(), field.Name, c.Param("slug"), scope)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if dtoProvider != nil {
+			data, err = dtoProvider.TransformFromModel(data)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
+				return
+			}
+		}
+		data = transformResponseItem(c, res, data)
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": data,
+		})
+	}
+}
+
+// GenerateRegenerateSlugHandler generates a handler replacing the slug of a record with
+// one generated from the current value of its source field
+func GenerateRegenerateSlugHandler(res resource.Resource, repo repository.SlugRepository, idParamName string, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		field, ok := slugField(c, res)
+		if !ok {
+			respondErrorMessage(c, http.StatusBadRequest, "Unknown slug field")
+			return
+		}
+
+		data, err := repo.RegenerateSlug(c.Request.Context(), c.Param(idParamName), field.Name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if dtoProvider != nil {
+			data, err = dtoProvider.TransformFromModel(data)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": data,
+		})
+	}
+}
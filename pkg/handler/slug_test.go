@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SlugArticle struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+func TestSlugRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SlugArticle{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "articles",
+		Model: SlugArticle{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "slug", Type: "string", Slug: &resource.SlugConfig{Source: "title"}},
+		},
+		Operations: []resource.Operation{resource.OperationRead, resource.OperationCreate, resource.OperationUpdate},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/articles", `{"title":"Hello World"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"slug":"hello-world"`)
+
+	w = request(http.MethodGet, "/api/articles/slug/hello-world", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"id":1`)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/articles/slug/missing", "").Code)
+
+	// The slug is kept on update until it is regenerated
+	w = request(http.MethodPut, "/api/articles/1", `{"title":"Goodbye"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"slug":"hello-world"`)
+
+	w = request(http.MethodPost, "/api/articles/1/slug/regenerate", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"slug":"goodbye"`)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/articles/1/slug/regenerate?field=title", "").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/api/articles/9/slug/regenerate", "").Code)
+}
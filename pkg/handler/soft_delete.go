@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/dto"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// softDeleteRepository returns the repository as a SoftDeleteRepository when the
+// resource supports soft delete
+func softDeleteRepository(res resource.Resource, repo repository.Repository) (repository.SoftDeleteRepository, bool) {
+	if !res.HasOperation(resource.OperationDelete) {
+		return nil, false
+	}
+	softRepo, ok := repo.(repository.SoftDeleteRepository)
+	if !ok || !softRepo.SoftDeletes() {
+		return nil, false
+	}
+	return softRepo, true
+}
+
+// registerSoftDeleteRoutes registers the trash, restore and force delete endpoints
+// under the resource router when the repository soft-deletes records
+func registerSoftDeleteRoutes(router routeRegistrar, prefix string, res resource.Resource, repo repository.Repository, idParamName string, dtoProvider dto.DTOProvider) {
+	softRepo, ok := softDeleteRepository(res, repo)
+	if !ok {
+		return
+	}
+
+	router.GET(prefix+"/trash", GenerateTrashHandler(res, softRepo, dtoProvider))
+	router.POST(prefix+"/:"+idParamName+"/restore", middleware.NoCacheMiddleware(), GenerateRestoreHandler(res, softRepo, idParamName, dtoProvider))
+	router.DELETE(prefix+"/:"+idParamName+"/force", middleware.NoCacheMiddleware(), GenerateForceDeleteHandler(res, softRepo, idParamName))
+}
+
+// GenerateTrashHandler generates a handler listing soft-deleted records
+func GenerateTrashHandler(res resource.Resource, repo repository.SoftDeleteRepository, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+		options := query.ParseQueryOptions(c, res)
+		if !validateListOptions(c, &options) {
+			return
+		}
+
+		data, total, err := repo.ListTrashed(c.Request.Context(), options)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		data, err = transformList(data, dtoProvider)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":  data,
+			"total": total,
+			"meta": gin.H{
+				"page":     options.Page,
+				"pageSize": options.PerPage,
+			},
+		})
+	}
+}
+
+// GenerateRestoreHandler generates a handler restoring a soft-deleted record
+func GenerateRestoreHandler(res resource.Resource, repo repository.SoftDeleteRepository, idParamName string, dtoProvider dto.DTOProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := repo.Restore(c.Request.Context(), c.Param(idParamName))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found in trash")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if dtoProvider != nil {
+			data, err = dtoProvider.TransformFromModel(data)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, fmt.Errorf("Error transforming data: %w", err))
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": data,
+		})
+	}
+}
+
+// GenerateForceDeleteHandler generates a handler permanently deleting a record
+func GenerateForceDeleteHandler(res resource.Resource, repo repository.SoftDeleteRepository, idParamName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := repo.ForceDelete(c.Request.Context(), c.Param(idParamName))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// transformList transforms the items of a list result with the DTO provider
+func transformList(data interface{}, dtoProvider dto.DTOProvider) (interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if dtoProvider == nil || v.Kind() != reflect.Slice {
+		return data, nil
+	}
+
+	items := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item, err := dtoProvider.TransformFromModel(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
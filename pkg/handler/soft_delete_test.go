@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TrashNote struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Title     string         `json:"title"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt"`
+}
+
+func TestSoftDeleteRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TrashNote{}))
+	require.NoError(t, db.Create(&[]TrashNote{{Title: "a"}, {Title: "b"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "notes",
+		Model: TrashNote{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationDelete,
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/notes/1").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/notes/1").Code)
+	assert.Contains(t, request(http.MethodGet, "/api/notes").Body.String(), `"total":1`)
+
+	w := request(http.MethodGet, "/api/notes/trash")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"title":"a"`)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	w = request(http.MethodPost, "/api/notes/1/restore")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"title":"a"`)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/api/notes/1/restore").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/notes/1").Code)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/api/notes/2/force").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/api/notes/2/force").Code)
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&TrashNote{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Models without a soft delete field get no trash endpoints
+	plain := resource.NewResource(resource.ResourceConfig{
+		Name:       "tasks",
+		Model:      FacetTask{},
+		Operations: []resource.Operation{resource.OperationDelete},
+	})
+	RegisterResource(r.Group("/api"), plain, repository.NewGenericRepositoryWithResource(db, plain))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/api/tasks/trash").Code)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// StrictConfig contains configuration for strict mode
+type StrictConfig struct {
+	// AllowedParams are extra query parameters accepted besides the standard ones
+	// and the resource fields
+	AllowedParams []string
+
+	// AllowedFields are extra body keys accepted besides the model fields
+	AllowedFields []string
+
+	// SkipBody disables the check of JSON body keys
+	SkipBody bool
+}
+
+// StrictMode rejects requests with unknown query parameters or unknown JSON body keys
+// with a descriptive 400, instead of silently ignoring typos like perPgae=50.
+//
+// With a nil resource the middleware applies to every resource of the global registry,
+// matched by the route path, so it can be used on a whole router group.
+func StrictMode(res resource.Resource, config StrictConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, base := res, ""
+		if target == nil {
+			target, base = registeredResource(c.FullPath())
+			if target == nil {
+				c.Next()
+				return
+			}
+		} else {
+			base = resourceBasePath(c.FullPath(), target.GetName())
+		}
+
+		if unknown := query.UnknownParams(c.Request.URL.Query(), target, config.AllowedParams...); len(unknown) > 0 {
+			names := make([]string, len(unknown))
+			for i, param := range unknown {
+				names[i] = describeUnknown(param.Name, param.Suggestion)
+			}
+			c.Abort()
+			apierror.WriteWith(c, apierror.New(http.StatusBadRequest, "Unknown query parameters: "+strings.Join(names, ", ")), nil,
+				gin.H{"unknownParams": unknown})
+			return
+		}
+
+		if !config.SkipBody && hasRecordBody(c, base) {
+			unknown, err := unknownBodyFields(c, target, config.AllowedFields)
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, err)
+				return
+			}
+			if len(unknown) > 0 {
+				names := make([]string, len(unknown))
+				for i, field := range unknown {
+					names[i] = describeUnknown(field.Name, field.Suggestion)
+				}
+				c.Abort()
+				apierror.WriteWith(c, apierror.New(http.StatusBadRequest, "Unknown fields: "+strings.Join(names, ", ")), nil,
+					gin.H{"unknownFields": unknown})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// describeUnknown formats an unknown name with its suggestion
+func describeUnknown(name, suggestion string) string {
+	if suggestion == "" {
+		return fmt.Sprintf("%q", name)
+	}
+	return fmt.Sprintf("%q (did you mean %q?)", name, suggestion)
+}
+
+// registeredResource finds the registered resource a route belongs to, returning it
+// with the part of the route following the resource name
+func registeredResource(fullPath string) (resource.Resource, string) {
+	segments := strings.Split(strings.Trim(fullPath, "/"), "/")
+	for i, segment := range segments {
+		if res, ok := resource.GlobalResourceRegistry.GetByName(segment); ok {
+			return res, strings.Join(segments[i+1:], "/")
+		}
+	}
+	return nil, ""
+}
+
+// resourceBasePath returns the part of the route following the resource name
+func resourceBasePath(fullPath, name string) string {
+	segments := strings.Split(strings.Trim(fullPath, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == name {
+			return strings.Join(segments[i+1:], "/")
+		}
+	}
+	return ""
+}
+
+// hasRecordBody reports whether the request carries records in a JSON body: creating
+// on the collection, updating a single record or a batch operation
+func hasRecordBody(c *gin.Context, rest string) bool {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") || isJSONPatchBody(c) {
+		return false
+	}
+	switch c.Request.Method {
+	case http.MethodPost:
+		return rest == "" || rest == "batch"
+	case http.MethodPut, http.MethodPatch:
+		return rest == "batch" || (strings.HasPrefix(rest, ":") && !strings.Contains(rest, "/"))
+	}
+	return false
+}
+
+// unknownBodyFields returns the keys of the records in the body that are not model
+// fields. Batch envelopes ({"ids": ..., "values": ...}) are checked by their values.
+func unknownBodyFields(c *gin.Context, res resource.Resource, allowed []string) ([]query.UnknownParam, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Malformed bodies are reported by the handler
+		return nil, nil
+	}
+	if envelope, ok := payload.(map[string]interface{}); ok && isBatchEnvelope(envelope) {
+		payload = envelope["values"]
+	}
+
+	known := bodyFieldNames(res)
+	for _, name := range allowed {
+		known[name] = true
+	}
+	normalized := make(map[string]bool, len(known))
+	for name := range known {
+		normalized[normalizeBodyKey(name)] = true
+	}
+	candidates := make([]string, 0, len(known))
+	for name := range known {
+		candidates = append(candidates, name)
+	}
+
+	records := []interface{}{payload}
+	if items, ok := payload.([]interface{}); ok {
+		records = items
+	}
+	seen := map[string]bool{}
+	var unknown []query.UnknownParam
+	for _, record := range records {
+		object, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range object {
+			if normalized[normalizeBodyKey(key)] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			unknown = append(unknown, query.UnknownParam{Name: key, Suggestion: query.Suggest(key, candidates)})
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Name < unknown[j].Name })
+	return unknown, nil
+}
+
+// isBatchEnvelope reports whether an object is a bulk request with "ids" and "values"
+func isBatchEnvelope(object map[string]interface{}) bool {
+	if _, ok := object["values"]; !ok {
+		return false
+	}
+	for key := range object {
+		if key != "ids" && key != "values" {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeBodyKey makes body keys comparable across naming conventions
+func normalizeBodyKey(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// bodyFieldNames returns the names accepted in record bodies: resource fields,
+// relations and the JSON names of the model fields
+func bodyFieldNames(res resource.Resource) map[string]bool {
+	names := make(map[string]bool)
+	for _, field := range res.GetFields() {
+		names[field.Name] = true
+	}
+	for _, relation := range res.GetRelations() {
+		names[relation.Name] = true
+	}
+	collectJSONNames(reflect.TypeOf(res.GetModel()), names)
+	return names
+}
+
+// collectJSONNames adds the JSON names of a struct type, flattening embedded structs
+func collectJSONNames(t reflect.Type, names map[string]bool) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" {
+			collectJSONNames(field.Type, names)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		names[tag] = true
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type StrictPost struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	AuthorID uint   `json:"author_id"`
+}
+
+func TestStrictMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "strict_posts",
+		Model: StrictPost{},
+	})
+
+	r := gin.New()
+	group := r.Group("/api/strict_posts", StrictMode(res, StrictConfig{AllowedParams: []string{"debug"}}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.GET("", ok)
+	group.POST("", ok)
+	group.PUT("/:id", ok)
+	group.PUT("/batch", ok)
+	group.POST("/:id/publish", ok)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/strict_posts?page=2&per_page=5&title=a&debug=1", "").Code)
+
+	w := request(http.MethodGet, "/api/strict_posts?perPgae=50", "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, `Unknown query parameters: "perPgae" (did you mean "per_page"?)`, response["error"])
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/strict_posts", `{"title":"a","authorId":1}`).Code)
+	w = request(http.MethodPost, "/api/strict_posts", `{"titel":"a","extra":true}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `Unknown fields: \"extra\", \"titel\" (did you mean \"title\"?)`)
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/api/strict_posts/1", `{"name":"a"}`).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "/api/strict_posts/batch", `{"ids":[1],"values":{"title":"b"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/api/strict_posts/batch", `{"ids":[1],"values":{"tilte":"b"}}`).Code)
+
+	// Bodies of custom actions are not records
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/api/strict_posts/1/publish", `{"notify":true}`).Code)
+}
+
+func TestStrictModeForRegisteredResources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "strict_global_posts",
+		Model: StrictPost{},
+	})
+	resource.RegisterToRegistry(res)
+
+	r := gin.New()
+	api := r.Group("/api", StrictMode(nil, StrictConfig{}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/strict_global_posts", ok)
+	api.GET("/health", ok)
+
+	request := func(path string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, request("/api/strict_global_posts?title=a"))
+	assert.Equal(t, http.StatusBadRequest, request("/api/strict_global_posts?titel=a"))
+	assert.Equal(t, http.StatusOK, request("/api/health?anything=1"))
+}
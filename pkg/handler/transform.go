@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// transformResponseItem applies the response transformer of a resource, if any, to a
+// record of a get response
+func transformResponseItem(c *gin.Context, res resource.Resource, item interface{}) interface{} {
+	transformed, ok := res.(resource.ResponseTransformerResource)
+	if !ok || transformed.GetResponseTransformer() == nil {
+		return item
+	}
+	return transformed.GetResponseTransformer()(c.Request.Context(), item)
+}
+
+// transformResponseItems applies the response transformer of a resource, if any, to
+// each record of a list response, then its list transformer
+func transformResponseItems(c *gin.Context, res resource.Resource, items []interface{}) []interface{} {
+	transformed, ok := res.(resource.ResponseTransformerResource)
+	if !ok {
+		return items
+	}
+	if transform := transformed.GetResponseTransformer(); transform != nil {
+		for i, item := range items {
+			items[i] = transform(c.Request.Context(), item)
+		}
+	}
+	if transform := transformed.GetResponseListTransformer(); transform != nil {
+		items = transform(c.Request.Context(), items)
+	}
+	return items
+}
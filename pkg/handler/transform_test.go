@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TransformedCustomer struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+func TestResponseTransformers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TransformedCustomer{}))
+	require.NoError(t, db.Create(&[]TransformedCustomer{
+		{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"},
+		{FirstName: "Alan", LastName: "Turing", Email: "alan@example.com"},
+	}).Error)
+
+	type contextKey struct{}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "customers",
+		Model:      TransformedCustomer{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationRead},
+		ResponseTransformer: func(ctx context.Context, item interface{}) interface{} {
+			customer := item.(*TransformedCustomer)
+			masked := *customer
+			masked.Email = masked.Email[:1] + "***" + masked.Email[strings.Index(masked.Email, "@"):]
+			return gin.H{
+				"customer":    masked,
+				"displayName": customer.FirstName + " " + customer.LastName,
+				"viewer":      ctx.Value(contextKey{}),
+			}
+		},
+		ResponseListTransformer: func(ctx context.Context, items []interface{}) []interface{} {
+			for i, item := range items {
+				item.(gin.H)["position"] = i + 1
+			}
+			return items
+		},
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, "admin"))
+	})
+	RegisterResource(&router.RouterGroup, res, repository.NewGenericRepositoryWithResource(db, res))
+
+	get := func(path string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	// List responses run the record transformer, then the list transformer
+	items := get("/customers?sort=id")["data"].([]interface{})
+	require.Len(t, items, 2)
+	first := items[0].(map[string]interface{})
+	assert.Equal(t, "Ada Lovelace", first["displayName"])
+	assert.Equal(t, "a***@example.com", first["customer"].(map[string]interface{})["email"])
+	assert.Equal(t, "admin", first["viewer"])
+	assert.Equal(t, float64(1), first["position"])
+	assert.Equal(t, float64(2), items[1].(map[string]interface{})["position"])
+
+	// Get responses run the record transformer only
+	record := get("/customers/2")["data"].(map[string]interface{})
+	assert.Equal(t, "Alan Turing", record["displayName"])
+	assert.Equal(t, "a***@example.com", record["customer"].(map[string]interface{})["email"])
+	assert.NotContains(t, record, "position")
+
+	// Stored records are unchanged
+	var stored TransformedCustomer
+	require.NoError(t, db.First(&stored, 2).Error)
+	assert.Equal(t, "alan@example.com", stored.Email)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -73,14 +74,14 @@ func GenerateUpdateHandler(res resource.Resource, repo repository.Repository, dt
 
 		// Parse request data into DTO
 		if err := c.ShouldBindJSON(dtoInstance); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Transform DTO to model
 		model, err := dtoProvider.TransformToModel(dtoInstance)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -89,7 +90,7 @@ func GenerateUpdateHandler(res resource.Resource, repo repository.Repository, dt
 
 		// Validate nested JSON fields if present
 		if err := validateNestedJsonFields(res, model); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "JSON validation failed: " + err.Error()})
+			respondError(c, http.StatusBadRequest, fmt.Errorf("JSON validation failed: %w", err))
 			return
 		}
 
@@ -100,28 +101,41 @@ func GenerateUpdateHandler(res resource.Resource, repo repository.Repository, dt
 		if db != nil && len(res.GetRelations()) > 0 {
 			// Validate relations
 			if err := resource.ValidateRelations(db, model); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Relation validation failed: " + err.Error()})
+				respondError(c, http.StatusBadRequest, fmt.Errorf("Relation validation failed: %w", err))
 				return
 			}
 		}
 
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, model) {
+			return
+		}
+
 		// Call repository
 		updatedModel, err := repo.Update(c.Request.Context(), id, model)
 		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
+				return
+			}
 			// Check if it's a "not found" error
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Handle other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updatedModel) {
 			return
 		}
 
 		// Transform model to response DTO
 		responseDTO, err := dtoProvider.TransformFromModel(updatedModel)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -143,14 +157,14 @@ func GenerateUpdateHandlerWithParam(res resource.Resource, repo repository.Repos
 
 		// Parse request data into DTO
 		if err := c.ShouldBindJSON(dtoInstance); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
 		// Transform DTO to model
 		model, err := dtoProvider.TransformToModel(dtoInstance)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -159,7 +173,7 @@ func GenerateUpdateHandlerWithParam(res resource.Resource, repo repository.Repos
 
 		// Validate nested JSON fields if present
 		if err := validateNestedJsonFields(res, model); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "JSON validation failed: " + err.Error()})
+			respondError(c, http.StatusBadRequest, fmt.Errorf("JSON validation failed: %w", err))
 			return
 		}
 
@@ -170,28 +184,41 @@ func GenerateUpdateHandlerWithParam(res resource.Resource, repo repository.Repos
 		if db != nil && len(res.GetRelations()) > 0 {
 			// Validate relations
 			if err := resource.ValidateRelations(db, model); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Relation validation failed: " + err.Error()})
+				respondError(c, http.StatusBadRequest, fmt.Errorf("Relation validation failed: %w", err))
 				return
 			}
 		}
 
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, model) {
+			return
+		}
+
 		// Call repository
 		updatedModel, err := repo.Update(c.Request.Context(), id, model)
 		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
+				return
+			}
 			// Check if it's a "not found" error
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Handle other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updatedModel) {
 			return
 		}
 
 		// Transform model to response DTO
 		responseDTO, err := dtoProvider.TransformFromModel(updatedModel)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -210,22 +237,35 @@ func UpdateHandler(c *gin.Context, res resource.Resource, repo repository.Reposi
 	// Parse request body
 	var requestBody map[string]interface{}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	// Check if request follows Refine structure with a "data" field
 	if data, ok := requestBody["data"]; ok {
 		// Update the resource directly with the data
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, data) {
+			return
+		}
+
 		updated, err := repo.Update(c.Request.Context(), id, data)
 		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
+				return
+			}
 			// Check if it's a "not found" error
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Handle other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updated) {
 			return
 		}
 
@@ -236,15 +276,28 @@ func UpdateHandler(c *gin.Context, res resource.Resource, repo repository.Reposi
 
 	// If we get here, the request doesn't have a "data" field,
 	// so we'll update the resource directly with the request body
+	withRecordID(c, id)
+	if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, requestBody) {
+		return
+	}
+
 	updated, err := repo.Update(c.Request.Context(), id, requestBody)
 	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			respondVersionConflict(c, err)
+			return
+		}
 		// Check if it's a "not found" error
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+			respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 			return
 		}
 		// Handle other errors
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updated) {
 		return
 	}
 
@@ -261,7 +314,7 @@ func GenerateCustomUpdateHandler(res resource.Resource, repo repository.Reposito
 		// Parse request body
 		var requestBody map[string]interface{}
 		if err := c.ShouldBindJSON(&requestBody); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			respondError(c, http.StatusBadRequest, err)
 			return
 		}
 
@@ -286,12 +339,12 @@ func GenerateCustomUpdateHandler(res resource.Resource, repo repository.Reposito
 			// Convert to JSON and back to the model type
 			jsonData, err := json.Marshal(dataMap)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to marshal data: " + err.Error()})
+				respondError(c, http.StatusBadRequest, fmt.Errorf("Failed to marshal data: %w", err))
 				return
 			}
 
 			if err := json.Unmarshal(jsonData, model); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to unmarshal data to model: " + err.Error()})
+				respondError(c, http.StatusBadRequest, fmt.Errorf("Failed to unmarshal data to model: %w", err))
 				return
 			}
 
@@ -299,15 +352,28 @@ func GenerateCustomUpdateHandler(res resource.Resource, repo repository.Reposito
 			repository.TrySetID(model, id)
 
 			// Update with the structured model
+			withRecordID(c, id)
+			if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, model) {
+				return
+			}
+
 			updated, err := repo.Update(c.Request.Context(), id, model)
 			if err != nil {
+				if errors.Is(err, repository.ErrVersionConflict) {
+					respondVersionConflict(c, err)
+					return
+				}
 				// Check if it's a "not found" error
 				if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-					c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+					respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 					return
 				}
 				// Handle other errors
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				respondError(c, http.StatusInternalServerError, err)
+				return
+			}
+
+			if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updated) {
 				return
 			}
 
@@ -317,15 +383,28 @@ func GenerateCustomUpdateHandler(res resource.Resource, repo repository.Reposito
 		}
 
 		// If we couldn't convert to a struct, try updating with raw data
+		withRecordID(c, id)
+		if !runBeforeHook(c, res, lifecycleHooks(res).BeforeUpdate, dataToUpdate) {
+			return
+		}
+
 		updated, err := repo.Update(c.Request.Context(), id, dataToUpdate)
 		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				respondVersionConflict(c, err)
+				return
+			}
 			// Check if it's a "not found" error
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no rows") {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+				respondErrorMessage(c, http.StatusNotFound, "Resource not found")
 				return
 			}
 			// Handle other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !runAfterHook(c, res, lifecycleHooks(res).AfterUpdate, updated) {
 			return
 		}
 
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ValidateFieldRequest is the body of the field validation endpoint
+type ValidateFieldRequest struct {
+	// Value is the value to validate
+	Value interface{} `json:"value"`
+
+	// ID identifies the record being edited, which uniqueness checks ignore
+	ID interface{} `json:"id,omitempty"`
+
+	// Values are the other values of the form, passed to field checks
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// registerValidateRoutes registers the field validation endpoint under the resource
+// router when the resource has forms, i.e. it can be created or updated
+func registerValidateRoutes(router routeRegistrar, prefix string, res resource.Resource, repo repository.Repository) {
+	if res.HasOperation(resource.OperationCreate) || res.HasOperation(resource.OperationUpdate) {
+		router.POST(prefix+"/validate/:field", GenerateValidateFieldHandler(res, repo))
+	}
+}
+
+// GenerateValidateFieldHandler generates the handler backing the AsyncValidator URLs of
+// fields (POST /<resource>/validate/:field). The value is checked against the field
+// validation and validators, then against the stored records if the field is unique
+// (ResourceConfig.UniqueFields, or a unique column of the model), then by the field
+// check of ResourceConfig.FieldChecks. Valid values get 200, rejected ones 422 with
+// the messages in ValidationErrorResponse.
+func GenerateValidateFieldHandler(res resource.Resource, repo repository.Repository) gin.HandlerFunc {
+	var checks map[string]resource.FieldCheck
+	if checked, ok := res.(resource.FieldCheckResource); ok {
+		checks = checked.GetFieldChecks()
+	}
+
+	return func(c *gin.Context) {
+		field, ok := validatedField(res, c.Param("field"))
+		if !ok {
+			respondErrorMessage(c, http.StatusNotFound, fmt.Sprintf("Unknown field %q", c.Param("field")))
+			return
+		}
+
+		var req ValidateFieldRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		messages := validateFieldValue(field, req.Value)
+		if len(messages) == 0 && !isEmptyValue(req.Value) {
+			taken, err := valueTaken(c.Request.Context(), res, repo, field, req.Value, req.ID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, err)
+				return
+			}
+			if taken {
+				messages = append(messages, fmt.Sprintf("%s is already taken", fieldLabel(field)))
+			}
+		}
+		if check := checks[field.Name]; len(messages) == 0 && check != nil {
+			if err := check(c.Request.Context(), req.Value, req.Values); err != nil {
+				messages = append(messages, err.Error())
+			}
+		}
+
+		if len(messages) > 0 {
+			// Errors are keyed by the field name of the URL, the name used by the form
+			response := apierror.New(http.StatusUnprocessableEntity, messages[0])
+			response.Code = ErrorCodeValidation
+			response.Errors = map[string][]string{c.Param("field"): messages}
+			apierror.Write(c, response, nil)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"valid": true}})
+	}
+}
+
+// validatedField returns the field of a resource by name or alias in any naming
+// convention
+func validatedField(res resource.Resource, name string) (resource.Field, bool) {
+	key := normalizeBodyKey(name)
+	for _, field := range res.GetFields() {
+		if normalizeBodyKey(field.Name) == key || normalizeBodyKey(field.APIName()) == key {
+			return field, true
+		}
+	}
+	return resource.Field{}, false
+}
+
+// validateFieldValue returns the messages of the field validation and validators the
+// value fails. Empty values only fail required fields.
+func validateFieldValue(field resource.Field, value interface{}) []string {
+	validation := field.Validation
+	if validation == nil {
+		validation = &resource.Validation{}
+	}
+	message := func(err error) string {
+		if validation.Message != "" {
+			return validation.Message
+		}
+		return fmt.Sprintf("%s: %s", fieldLabel(field), err.Error())
+	}
+
+	if isEmptyValue(value) {
+		if validation.Required {
+			return []string{message(errors.New("is required"))}
+		}
+		return nil
+	}
+
+	var messages []string
+	switch value.(type) {
+	case string:
+		validator := resource.StringValidator{MinLength: validation.MinLength, MaxLength: validation.MaxLength, Pattern: validation.Pattern}
+		if err := validator.Validate(value); err != nil {
+			messages = append(messages, message(err))
+		}
+	case float64:
+		validator := resource.NumberValidator{Min: validation.Min, Max: validation.Max}
+		if err := validator.Validate(value); err != nil {
+			messages = append(messages, message(err))
+		}
+	}
+	for _, validator := range field.Validators {
+		if err := validator.Validate(value); err != nil {
+			messages = append(messages, message(err))
+		}
+	}
+	return messages
+}
+
+// valueTaken reports whether another stored record has the value in a unique field.
+// Fields that are not unique, and repositories without a database, are never taken.
+func valueTaken(ctx context.Context, res resource.Resource, repo repository.Repository, field resource.Field, value, id interface{}) (bool, error) {
+	db := repo.Query(ctx)
+	if db == nil {
+		return false, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return false, err
+	}
+	column := stmt.Schema.LookUpField(field.Name)
+	if column == nil || column.DBName == "" || !isUniqueField(res, stmt.Schema, column) {
+		return false, nil
+	}
+
+	tx := db.Where(clause.Eq{Column: clause.Column{Name: column.DBName}, Value: value})
+	if primaryKey := stmt.Schema.PrioritizedPrimaryField; id != nil && primaryKey != nil {
+		tx = tx.Where(clause.Neq{Column: clause.Column{Name: primaryKey.DBName}, Value: id})
+	}
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// isUniqueField reports whether a field is declared unique by the resource, or has a
+// unique constraint or single column unique index in the model
+func isUniqueField(res resource.Resource, modelSchema *schema.Schema, field *schema.Field) bool {
+	if declared, ok := res.(resource.UniqueFieldsResource); ok {
+		for _, name := range declared.GetUniqueFields() {
+			if normalizeBodyKey(name) == normalizeBodyKey(field.Name) || normalizeBodyKey(name) == normalizeBodyKey(field.DBName) {
+				return true
+			}
+		}
+	}
+	if field.Unique {
+		return true
+	}
+	for _, index := range modelSchema.ParseIndexes() {
+		if index.Class == "UNIQUE" && len(index.Fields) == 1 && index.Fields[0].Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldLabel returns the label of a field, or its API name
+func fieldLabel(field resource.Field) string {
+	if field.Label != "" {
+		return field.Label
+	}
+	return field.APIName()
+}
+
+// isEmptyValue reports whether a form value is missing: null, an empty string or an
+// empty array
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if s, ok := value.(string); ok {
+		return s == ""
+	}
+	return reflect.ValueOf(value).Kind() == reflect.Slice && reflect.ValueOf(value).Len() == 0
+}
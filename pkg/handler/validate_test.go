@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ValidatedAccount struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Email    string `json:"email" gorm:"uniqueIndex"`
+	Username string `json:"username"`
+	Handle   string `json:"handle"`
+}
+
+func setupValidateField(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ValidatedAccount{}))
+	require.NoError(t, db.Create(&ValidatedAccount{Email: "ann@example.com", Username: "ann", Handle: "@ann"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "accounts",
+		Model: ValidatedAccount{},
+		Fields: []resource.Field{
+			{Name: "ID", Type: "uint"},
+			{Name: "Email", Type: "string", Label: "Email"},
+			{Name: "Username", Type: "string", Label: "Username", Validation: &resource.Validation{Required: true, MinLength: 3}},
+			{Name: "Handle", Type: "string", Label: "Handle"},
+		},
+		Operations:   []resource.Operation{resource.OperationCreate, resource.OperationUpdate},
+		UniqueFields: []string{"handle"},
+		FieldChecks: map[string]resource.FieldCheck{
+			"Username": func(ctx context.Context, value interface{}, values map[string]interface{}) error {
+				if value == "admin" {
+					return errors.New("Username is reserved")
+				}
+				return nil
+			},
+		},
+	})
+
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+	return r
+}
+
+func validateField(r *gin.Engine, field, body string) (*httptest.ResponseRecorder, ValidationErrorResponse) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/accounts/validate/"+field, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp ValidationErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestValidateFieldHandler(t *testing.T) {
+	r := setupValidateField(t)
+
+	// Unique index of the model
+	w, resp := validateField(r, "email", `{"value":"ann@example.com"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, map[string][]string{"email": {"Email is already taken"}}, resp.Errors)
+
+	// The record being edited keeps its value
+	w, _ = validateField(r, "email", `{"value":"ann@example.com","id":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = validateField(r, "email", `{"value":"bob@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Unique field declared by the resource
+	w, _ = validateField(r, "handle", `{"value":"@ann"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Field validation, then the field check
+	w, resp = validateField(r, "username", `{"value":""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "Username: is required", resp.Message)
+	_, resp = validateField(r, "username", `{"value":"al"}`)
+	assert.Contains(t, resp.Message, "at least 3")
+	_, resp = validateField(r, "username", `{"value":"admin","values":{"email":"x@example.com"}}`)
+	assert.Equal(t, "Username is reserved", resp.Message)
+	w, _ = validateField(r, "username", `{"value":"ann"}`)
+	assert.Equal(t, http.StatusOK, w.Code, "usernames are not unique")
+
+	w, _ = validateField(r, "missing", `{"value":"x"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestValidateFieldHandlerOwnerResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ValidatedAccount{}))
+	require.NoError(t, db.Create(&ValidatedAccount{Email: "ann@example.com", Username: "ann", Handle: "@ann"}).Error)
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:  "accounts",
+		Model: ValidatedAccount{},
+		Fields: []resource.Field{
+			{Name: "ID", Type: "uint"},
+			{Name: "Username", Type: "string", Label: "Username"},
+			{Name: "Handle", Type: "string", Label: "Handle"},
+		},
+		Operations:   []resource.Operation{resource.OperationCreate},
+		UniqueFields: []string{"handle"},
+		FieldChecks: map[string]resource.FieldCheck{
+			"Username": func(ctx context.Context, value interface{}, values map[string]interface{}) error {
+				if value == "admin" {
+					return errors.New("Username is reserved")
+				}
+				return nil
+			},
+		},
+	}), resource.OwnerConfig{})
+	r := gin.New()
+	r.POST("/api/accounts/validate/:field", GenerateValidateFieldHandler(res, repository.NewGenericRepositoryWithResource(db, res)))
+
+	// Unique fields declared by the wrapped resource
+	w, _ := validateField(r, "handle", `{"value":"@ann"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+
+	// and its field checks
+	w, resp := validateField(r, "username", `{"value":"admin"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Equal(t, "Username is reserved", resp.Message)
+}
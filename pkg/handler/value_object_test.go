@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type CustomerAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type AddressedCustomer struct {
+	ID      uint            `json:"id" gorm:"primaryKey"`
+	Name    string          `json:"name"`
+	Address CustomerAddress `json:"address" gorm:"embedded;embeddedPrefix:address_"`
+}
+
+func TestValueObjectFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&AddressedCustomer{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "customers",
+		Model: AddressedCustomer{},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationCreate,
+		},
+	})
+	r := gin.New()
+	RegisterResource(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res))
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Value objects are bound from nested objects and from their nested field names
+	w := request(http.MethodPost, "/api/customers", `{"name":"Ann","address":{"street":"Main","city":"Warsaw"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = request(http.MethodPost, "/api/customers", `{"name":"Bob","address.street":"High","address.city":"Berlin"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"address":{"street":"High","city":"Berlin"}`)
+
+	var stored AddressedCustomer
+	require.NoError(t, db.Where("address_city = ?", "Berlin").First(&stored).Error)
+	assert.Equal(t, "Bob", stored.Name)
+
+	list := func(query string) []string {
+		w := request(http.MethodGet, "/api/customers?"+query, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body struct {
+			Data []AddressedCustomer `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		names := []string{}
+		for _, customer := range body.Data {
+			names = append(names, customer.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"Bob"}, list("filter[address.city][eq]=Berlin"))
+	assert.Equal(t, []string{"Ann"}, list("filter[address.city][contains]=saw"))
+	assert.Equal(t, []string{"Bob", "Ann"}, list("sort=address.city&order=asc"))
+	assert.Equal(t, []string{"Ann", "Bob"}, list("sort=address.city&order=desc"))
+
+	// Metadata describes the fields of the value object
+	w = request(http.MethodOptions, "/api/customers", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"address.city"`)
+}
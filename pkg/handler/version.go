@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// VersionMiddleware enables optimistic locking on the updates of single records of
+// resources with a version field (resource.VersionedResource). The version the client
+// read is taken from the If-Match header or, without it, from the version field of the
+// body, and passed to the repository with repository.WithExpectedVersion. Updates of
+// records modified in the meantime fail with repository.ErrVersionConflict and get a
+// 409 Conflict. Requests without a version are not checked.
+func VersionMiddleware(res resource.Resource) gin.HandlerFunc {
+	versionField := ""
+	if versioned, ok := res.(resource.VersionedResource); ok {
+		versionField = versioned.GetVersionField()
+	}
+
+	return func(c *gin.Context) {
+		if versionField == "" || (c.Request.Method != http.MethodPut && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		rest := resourceBasePath(c.FullPath(), res.GetName())
+		if !strings.HasPrefix(rest, ":") || strings.Contains(rest, "/") {
+			c.Next()
+			return
+		}
+
+		var expected interface{}
+		if ifMatch := strings.TrimSpace(c.GetHeader("If-Match")); ifMatch != "" {
+			if ifMatch != "*" {
+				expected = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+			}
+		} else {
+			err := rewriteBodyRecords(c, func(records interface{}) bool {
+				record, ok := records.(map[string]interface{})
+				if !ok {
+					return false
+				}
+				if data, ok := record["data"].(map[string]interface{}); ok {
+					record = data
+				}
+				for key, value := range record {
+					if normalizeBodyKey(key) == normalizeBodyKey(versionField) {
+						expected = value
+					}
+				}
+				return false
+			})
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		if expected != nil {
+			c.Request = c.Request.WithContext(repository.WithExpectedVersion(c.Request.Context(), expected))
+		}
+		c.Next()
+	}
+}
+
+// respondVersionConflict writes a 409 Conflict with the current version of the record
+func respondVersionConflict(c *gin.Context, err error) {
+	response := struct {
+		*ErrorResponse
+		Version interface{} `json:"version,omitempty"`
+	}{ErrorResponse: NewErrorResponse(http.StatusConflict, err)}
+	var conflict *repository.VersionConflictError
+	if errors.As(err, &conflict) {
+		response.Version = conflict.Current
+	}
+	apierror.Prepare(c, response.ErrorResponse, err)
+	c.JSON(response.StatusCode, response)
+}
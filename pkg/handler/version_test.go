@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type VersionedArticle struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+func TestVersionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&VersionedArticle{}))
+	require.NoError(t, db.Create(&VersionedArticle{Title: "draft", Version: 1}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:         "articles",
+		Model:        VersionedArticle{},
+		Operations:   []resource.Operation{resource.OperationRead, resource.OperationUpdate},
+		VersionField: "version",
+	})
+	r := gin.New()
+	RegisterResourceForRefine(r.Group("/api"), res, repository.NewGenericRepositoryWithResource(db, res), "id")
+
+	update := func(body, ifMatch string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/articles/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	w, response := update(`{"title":"first","version":1}`, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(2), response["data"].(map[string]interface{})["version"])
+
+	// Another client still holding version 1 gets a conflict with the current version
+	w, response = update(`{"title":"stale","version":1}`, "")
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, float64(2), response["version"])
+
+	// If-Match takes precedence over the body
+	w, _ = update(`{"title":"second","version":1}`, `W/"2"`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w, _ = update(`{"title":"stale"}`, `"2"`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Requests without a version are not checked
+	w, response = update(`{"title":"third"}`, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(4), response["data"].(map[string]interface{})["version"])
+
+	var stored VersionedArticle
+	require.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "third", stored.Title)
+}
+
+type OwnedVersionedArticle struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	OwnerID string `json:"ownerId"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+func TestVersionMiddlewareOwnerResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OwnedVersionedArticle{}))
+	require.NoError(t, db.Create(&OwnedVersionedArticle{OwnerID: "ann", Title: "draft", Version: 1}).Error)
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:         "articles",
+		Model:        OwnedVersionedArticle{},
+		Operations:   []resource.Operation{resource.OperationRead, resource.OperationUpdate},
+		VersionField: "version",
+	}), resource.DefaultOwnerConfig())
+	repo, err := repository.NewOwnerRepository(db, res)
+	require.NoError(t, err)
+	r := gin.New()
+	RegisterOwnerResource(r.Group("/api", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID"))), res, repo)
+
+	update := func(body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/articles/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Owner-ID", "ann")
+		req.Header.Set("If-Match", ifMatch)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := update(`{"title":"first"}`, `"1"`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"version":2`)
+
+	w = update(`{"title":"stale"}`, `"1"`)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var article OwnedVersionedArticle
+	require.NoError(t, db.First(&article, 1).Error)
+	assert.Equal(t, "first", article.Title)
+	assert.Equal(t, "ann", article.OwnerID)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// WarmConfig configures WarmCaches
+type WarmConfig struct {
+	// Prefix of the resource routes, e.g. "/api"
+	Prefix string
+
+	// Resources to warm by name (default: the registered resources with a list
+	// operation)
+	Resources []string
+
+	// Query of the warmed first page, e.g. "current=1&pageSize=10" to match the
+	// requests of the admin exactly (default: the default page of the list handler)
+	Query string
+
+	// Options also warms the first page of the resources related to the warmed ones,
+	// which the select inputs of their forms list
+	Options bool
+
+	// Header is sent with the warming requests, e.g. the Authorization of a service
+	// account so auth middleware admits them. Owner and tenant scoped caches are
+	// warmed for the owner and tenant of the requests.
+	Header http.Header
+
+	// Concurrency is the number of requests run at once (default 4)
+	Concurrency int
+
+	// Registry of the resources (default: the global registry)
+	Registry *resource.ResourceRegistry
+}
+
+// WarmResult is the outcome of a warming request
+type WarmResult struct {
+	Resource string        `json:"resource"`
+	Method   string        `json:"method"`
+	Path     string        `json:"path"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"`
+}
+
+// OK reports whether the request succeeded
+func (r WarmResult) OK() bool {
+	return r.Status >= 200 && r.Status < 400
+}
+
+// WarmCaches prefetches what the first admin page load after a deploy reads, so it
+// isn't slowed down by cold caches and counts: the first page of the configured
+// resources (filling the result cache of cached repositories, with the total) and
+// their metadata (OPTIONS), plus the first page of related resources if
+// config.Options is set. Requests are served by router, the engine the resources are
+// registered on, so they go through the same middleware and are cached under the same
+// keys as the requests of clients. Call it once the routes are registered, before or
+// while serving traffic. Failed requests are reported in the results; ctx cancels the
+// requests not yet run.
+func WarmCaches(ctx context.Context, router http.Handler, config WarmConfig) []WarmResult {
+	registry := config.Registry
+	if registry == nil {
+		registry = resource.GlobalResourceRegistry
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	type warmRequest struct {
+		resource string
+		method   string
+		path     string
+	}
+	var requests []warmRequest
+	warmed := make(map[string]bool)
+	addList := func(name string) {
+		res, ok := registry.GetByName(name)
+		if !ok || warmed[name] || !res.HasOperation(resource.OperationList) {
+			return
+		}
+		warmed[name] = true
+		path := strings.TrimRight(config.Prefix, "/") + "/" + name
+		list := path
+		if config.Query != "" {
+			list += "?" + config.Query
+		}
+		requests = append(requests,
+			warmRequest{resource: name, method: http.MethodGet, path: list},
+			warmRequest{resource: name, method: http.MethodOptions, path: path})
+	}
+
+	names := config.Resources
+	if len(names) == 0 {
+		for _, res := range registry.GetAll() {
+			names = append(names, res.GetName())
+		}
+	}
+	for _, name := range names {
+		addList(name)
+	}
+	if config.Options {
+		for _, name := range names {
+			if res, ok := registry.GetByName(name); ok {
+				for _, relation := range res.GetRelations() {
+					addList(relation.Resource)
+				}
+			}
+		}
+	}
+
+	results := make([]WarmResult, len(requests))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	started := 0
+	for i, request := range requests {
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(i int, request warmRequest) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			req := httptest.NewRequest(request.method, request.path, nil).WithContext(ctx)
+			for name, values := range config.Header {
+				req.Header[name] = values
+			}
+			recorder := httptest.NewRecorder()
+			start := time.Now()
+			router.ServeHTTP(recorder, req)
+			results[i] = WarmResult{
+				Resource: request.resource,
+				Method:   request.method,
+				Path:     request.path,
+				Status:   recorder.Code,
+				Duration: time.Since(start),
+			}
+		}(i, request)
+	}
+	wg.Wait()
+	return results[:started]
+}
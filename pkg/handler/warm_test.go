@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/cache"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type WarmAuthor struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type WarmPost struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Title    string `json:"title"`
+	AuthorID uint   `json:"authorId"`
+}
+
+func TestWarmCaches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	defer func() { resource.GlobalResourceRegistry = registry }()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&WarmAuthor{}, &WarmPost{}))
+	require.NoError(t, db.Create(&WarmAuthor{Name: "Ann"}).Error)
+	require.NoError(t, db.Create(&WarmPost{Title: "Hello", AuthorID: 1}).Error)
+
+	operations := []resource.Operation{resource.OperationList, resource.OperationRead}
+	authors := resource.NewResource(resource.ResourceConfig{Name: "warm_authors", Model: WarmAuthor{}, Operations: operations})
+	posts := resource.NewResource(resource.ResourceConfig{
+		Name:       "warm_posts",
+		Model:      WarmPost{},
+		Operations: operations,
+		Relations: []resource.Relation{
+			{Name: "Author", Type: resource.RelationTypeManyToOne, Resource: "warm_authors", Field: "AuthorID"},
+		},
+	})
+	store := cache.NewMemory(0)
+	r := gin.New()
+	for _, res := range []resource.Resource{authors, posts} {
+		repo := repository.WithCache(repository.NewGenericRepositoryWithResource(db, res), store, time.Minute)
+		RegisterResource(r.Group("/api"), res, repo)
+	}
+
+	results := WarmCaches(context.Background(), r, WarmConfig{
+		Prefix:    "/api",
+		Resources: []string{"warm_posts"},
+		Options:   true,
+	})
+	var requests []string
+	for _, result := range results {
+		assert.True(t, result.OK(), result.Path)
+		requests = append(requests, result.Method+" "+result.Path)
+	}
+	assert.Equal(t, []string{
+		"GET /api/warm_posts",
+		"OPTIONS /api/warm_posts",
+		"GET /api/warm_authors",
+		"OPTIONS /api/warm_authors",
+	}, requests)
+
+	// The first pages are served from the cache, even once the rows are gone
+	require.NoError(t, db.Exec("DELETE FROM warm_posts").Error)
+	require.NoError(t, db.Exec("DELETE FROM warm_authors").Error)
+	for _, path := range []string{"/api/warm_posts", "/api/warm_authors"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"total":1`, path)
+	}
+
+	// Cancelled warming runs no request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, WarmCaches(ctx, r, WarmConfig{Prefix: "/api"}))
+}
@@ -0,0 +1,273 @@
+// Package idgen generates record IDs for deployments with several writers. The IDs
+// never collide across instances and regions, and sort roughly by creation time, so
+// keyset pagination over them follows insertion order.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ID generation strategies
+const (
+	StrategyUUIDv4    = "uuidv4"
+	StrategyUUIDv7    = "uuidv7"
+	StrategyULID      = "ulid"
+	StrategyKSUID     = "ksuid"
+	StrategySnowflake = "snowflake"
+	StrategySequence  = "sequence"
+)
+
+// Generator generates record IDs
+type Generator interface {
+	// Strategy names the generator in resource metadata (e.g. "ulid")
+	Strategy() string
+
+	// NewID returns a new ID: a string for UUIDs, ULIDs and KSUIDs, an int64 for
+	// snowflakes and sequences
+	NewID() (interface{}, error)
+}
+
+// New returns the generator of a strategy. node identifies the instance for
+// snowflake IDs and is ignored by the other strategies. Sequences need the name of
+// the database sequence and are created with NewSequence.
+func New(strategy string, node int64) (Generator, error) {
+	switch strategy {
+	case StrategyUUIDv4, "uuid":
+		return NewUUIDv4(), nil
+	case StrategyUUIDv7:
+		return NewUUIDv7(), nil
+	case StrategyULID:
+		return NewULID(), nil
+	case StrategyKSUID:
+		return NewKSUID(), nil
+	case StrategySnowflake:
+		return NewSnowflake(node)
+	case StrategySequence:
+		return nil, errors.New("sequence IDs need a sequence name, use NewSequence")
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q", strategy)
+	}
+}
+
+// UUIDv4 generates random UUIDs (RFC 9562 version 4). They do not sort by creation
+// time; prefer UUIDv7 for new tables.
+type UUIDv4 struct{}
+
+// NewUUIDv4 creates a UUIDv4 generator
+func NewUUIDv4() *UUIDv4 {
+	return &UUIDv4{}
+}
+
+// Strategy returns StrategyUUIDv4
+func (g *UUIDv4) Strategy() string {
+	return StrategyUUIDv4
+}
+
+// NewID returns a new UUID string
+func (g *UUIDv4) NewID() (interface{}, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	return id.String(), nil
+}
+
+// UUIDv7 generates time-ordered UUIDs (RFC 9562 version 7): a millisecond timestamp
+// followed by random bits, in the standard UUID format
+type UUIDv7 struct{}
+
+// NewUUIDv7 creates a UUIDv7 generator
+func NewUUIDv7() *UUIDv7 {
+	return &UUIDv7{}
+}
+
+// Strategy returns StrategyUUIDv7
+func (g *UUIDv7) Strategy() string {
+	return StrategyUUIDv7
+}
+
+// NewID returns a new UUID string
+func (g *UUIDv7) NewID() (interface{}, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return id.String(), nil
+}
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates ULIDs: 26 character strings of a millisecond timestamp and 80 random
+// bits. IDs generated by one generator within the same millisecond increment the
+// random bits, so they stay in order.
+type ULID struct {
+	mu      sync.Mutex
+	last    uint64
+	entropy [10]byte
+}
+
+// NewULID creates a ULID generator
+func NewULID() *ULID {
+	return &ULID{}
+}
+
+// Strategy returns StrategyULID
+func (g *ULID) Strategy() string {
+	return StrategyULID
+}
+
+// NewID returns a new ULID
+func (g *ULID) NewID() (interface{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.last {
+		// Same millisecond, or the clock went back: keep the order of earlier IDs
+		ms = g.last
+		if !increment(g.entropy[:]) {
+			ms++
+			if _, err := rand.Read(g.entropy[:]); err != nil {
+				return nil, err
+			}
+		}
+	} else if _, err := rand.Read(g.entropy[:]); err != nil {
+		return nil, err
+	}
+	g.last = ms
+
+	hi := ms<<16 | uint64(binary.BigEndian.Uint16(g.entropy[:2]))
+	lo := binary.BigEndian.Uint64(g.entropy[2:])
+	id := make([]byte, 26)
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id), nil
+}
+
+// increment adds one to a big-endian number, reporting false when it overflows
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ksuidEpoch is the start of KSUID timestamps (2014-05-13T16:53:20Z)
+const ksuidEpoch = 1400000000
+
+// base62 is the alphabet of KSUIDs, in ASCII order so KSUIDs sort as strings
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// KSUID generates KSUIDs: 27 character strings of a second timestamp and 128 random
+// bits
+type KSUID struct{}
+
+// NewKSUID creates a KSUID generator
+func NewKSUID() *KSUID {
+	return &KSUID{}
+}
+
+// Strategy returns StrategyKSUID
+func (g *KSUID) Strategy() string {
+	return StrategyKSUID
+}
+
+// NewID returns a new KSUID
+func (g *KSUID) NewID() (interface{}, error) {
+	raw := make([]byte, 20)
+	binary.BigEndian.PutUint32(raw, uint32(time.Now().Unix()-ksuidEpoch))
+	if _, err := rand.Read(raw[4:]); err != nil {
+		return nil, err
+	}
+
+	n := new(big.Int).SetBytes(raw)
+	base := big.NewInt(int64(len(base62)))
+	digit := new(big.Int)
+	id := make([]byte, 27)
+	for i := len(id) - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		id[i] = base62[digit.Int64()]
+	}
+	return string(id), nil
+}
+
+// Snowflake layout: 41 bits of milliseconds since SnowflakeEpoch, 10 bits of node ID
+// and 12 bits of sequence
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+
+	// MaxSnowflakeNode is the highest snowflake node ID
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+)
+
+// SnowflakeEpoch is the start of snowflake timestamps
+var SnowflakeEpoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrInvalidNode is returned for snowflake node IDs out of range
+var ErrInvalidNode = errors.New("snowflake node ID must be between 0 and 1023")
+
+// Snowflake generates 63 bit integer IDs from a millisecond timestamp, the node ID and
+// a sequence. Every instance writing to the same table needs its own node ID.
+type Snowflake struct {
+	node int64
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake creates a snowflake generator for a node
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, ErrInvalidNode
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// Strategy returns StrategySnowflake
+func (g *Snowflake) Strategy() string {
+	return StrategySnowflake
+}
+
+// Node returns the node ID of the generator
+func (g *Snowflake) Node() int64 {
+	return g.node
+}
+
+// NewID returns a new snowflake ID
+func (g *Snowflake) NewID() (interface{}, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Since(SnowflakeEpoch).Milliseconds()
+	if ms <= g.last {
+		// Same millisecond, or the clock went back: keep the order of earlier IDs
+		ms = g.last
+		g.sequence = (g.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if g.sequence == 0 {
+			// The sequence of this millisecond is exhausted
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = ms
+
+	return ms<<(snowflakeNodeBits+snowflakeSequenceBits) | g.node<<snowflakeSequenceBits | g.sequence, nil
+}
@@ -0,0 +1,126 @@
+package idgen
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func generate(t *testing.T, generator Generator, n int) []interface{} {
+	t.Helper()
+	ids := make([]interface{}, n)
+	for i := range ids {
+		id, err := generator.NewID()
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestULID(t *testing.T) {
+	ids := generate(t, NewULID(), 1000)
+	strings := make([]string, len(ids))
+	for i, id := range ids {
+		strings[i] = id.(string)
+		assert.Len(t, strings[i], 26)
+	}
+	// IDs of the same millisecond stay in order
+	assert.True(t, sort.StringsAreSorted(strings))
+	assert.Equal(t, StrategyULID, NewULID().Strategy())
+}
+
+func TestKSUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, id := range generate(t, NewKSUID(), 1000) {
+		assert.Len(t, id.(string), 27)
+		seen[id.(string)] = true
+	}
+	assert.Len(t, seen, 1000)
+}
+
+func TestSnowflake(t *testing.T) {
+	_, err := NewSnowflake(MaxSnowflakeNode + 1)
+	assert.ErrorIs(t, err, ErrInvalidNode)
+
+	// Nodes generating concurrently never collide
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[int64]bool)
+	for node := int64(0); node < 4; node++ {
+		generator, err := NewSnowflake(node)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			previous := int64(0)
+			for i := 0; i < 5000; i++ {
+				id, _ := generator.NewID()
+				assert.Greater(t, id.(int64), previous)
+				assert.Equal(t, node, id.(int64)>>snowflakeSequenceBits&MaxSnowflakeNode)
+				previous = id.(int64)
+				mu.Lock()
+				seen[previous] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seen, 20000)
+}
+
+func TestUUID(t *testing.T) {
+	for _, id := range generate(t, NewUUIDv4(), 10) {
+		parsed, err := uuid.Parse(id.(string))
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(4), parsed.Version())
+	}
+
+	ids := generate(t, NewUUIDv7(), 1000)
+	strings := make([]string, len(ids))
+	for i, id := range ids {
+		parsed, err := uuid.Parse(id.(string))
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), parsed.Version())
+		strings[i] = id.(string)
+	}
+	assert.True(t, sort.StringsAreSorted(strings))
+}
+
+func TestSequence(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	_, err = NewSequence("order_ids").NewIDFromDB(context.Background(), db)
+	assert.Error(t, err, "sqlite has no sequences")
+
+	generator := &Sequence{Name: "order_ids", Query: "SELECT 41 + 1"}
+	id, err := generator.NewIDFromDB(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	_, err = generator.NewID()
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	for _, strategy := range []string{StrategyUUIDv4, StrategyUUIDv7, StrategyULID, StrategyKSUID, StrategySnowflake} {
+		generator, err := New(strategy, 1)
+		require.NoError(t, err)
+		assert.Equal(t, strategy, generator.Strategy())
+	}
+	generator, err := New("uuid", 0)
+	require.NoError(t, err)
+	assert.Equal(t, StrategyUUIDv4, generator.Strategy())
+
+	_, err = New(StrategySequence, 0)
+	assert.Error(t, err)
+	_, err = New("uuidv1", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package idgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// DBGenerator is implemented by generators that read IDs from the database of the
+// repository, which calls NewIDFromDB instead of NewID
+type DBGenerator interface {
+	Generator
+
+	// NewIDFromDB returns a new ID read with db
+	NewIDFromDB(ctx context.Context, db *gorm.DB) (interface{}, error)
+}
+
+// Sequence reads IDs from a database sequence, for tables whose IDs are shared with
+// other writers of the database or must stay dense. Each ID costs a round trip.
+type Sequence struct {
+	// Name is the name of the sequence
+	Name string
+
+	// Query overrides the statement returning the next value, for databases without
+	// a built-in one (e.g. "SELECT next_id FROM ids" behind a trigger)
+	Query string
+}
+
+// NewSequence creates a generator reading the next values of the named sequence
+func NewSequence(name string) *Sequence {
+	return &Sequence{Name: name}
+}
+
+// Strategy returns StrategySequence
+func (g *Sequence) Strategy() string {
+	return StrategySequence
+}
+
+// NewID fails: sequences are read from the database, see NewIDFromDB
+func (g *Sequence) NewID() (interface{}, error) {
+	return nil, errors.New("sequence IDs need a database")
+}
+
+// NewIDFromDB returns the next value of the sequence. PostgreSQL, SQL Server and
+// MariaDB sequences are supported; other databases need Query.
+func (g *Sequence) NewIDFromDB(ctx context.Context, db *gorm.DB) (interface{}, error) {
+	tx := db.WithContext(ctx)
+	var id int64
+	var err error
+	switch {
+	case g.Query != "":
+		err = tx.Raw(g.Query).Scan(&id).Error
+	case g.Name == "":
+		return nil, errors.New("sequence name is empty")
+	default:
+		switch dialect := tx.Dialector.Name(); dialect {
+		case "postgres":
+			err = tx.Raw("SELECT nextval(?)", g.Name).Scan(&id).Error
+		case "sqlserver":
+			err = tx.Raw("SELECT NEXT VALUE FOR " + tx.Statement.Quote(g.Name)).Scan(&id).Error
+		case "mysql":
+			err = tx.Raw("SELECT NEXTVAL(" + tx.Statement.Quote(g.Name) + ")").Scan(&id).Error
+		default:
+			return nil, fmt.Errorf("sequences are not supported by %s, set Sequence.Query", dialect)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sequence %s: %w", g.Name, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// Job event types sent by the events endpoint: progress while the job runs, then its
+// final status
+const (
+	EventProgress  = "progress"
+	EventSucceeded = string(StatusSucceeded)
+	EventFailed    = string(StatusFailed)
+)
+
+// RegisterJobRoutes registers the job endpoints below path (e.g. "/jobs", which should
+// match Config.Path):
+//
+//	GET <path>/:id         status of a job, with a Retry-After header while it runs
+//	GET <path>/:id/events  progress of a job as server-sent events
+func RegisterJobRoutes(router *gin.RouterGroup, path string, manager *Manager) {
+	router.GET(path+"/:id", middleware.NoCacheMiddleware(), GenerateStatusHandler(manager))
+	router.GET(path+"/:id/events", GenerateEventsHandler(manager))
+}
+
+// Accepted starts fn as a job and responds with 202 Accepted, the job, its status URL
+// in the Location header and the suggested poll interval in Retry-After
+func Accepted(c *gin.Context, manager *Manager, name string, fn Func) {
+	job, err := manager.Start(c.Request.Context(), name, fn)
+	if err != nil {
+		apierror.Respond(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	c.Header("Location", manager.Path(job.ID))
+	setRetryAfter(c, job)
+	c.JSON(http.StatusAccepted, gin.H{"data": job})
+}
+
+// GenerateStatusHandler generates a handler returning the status of a job. Jobs of
+// owners are only visible to them.
+func GenerateStatusHandler(manager *Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := visibleJob(c, manager)
+		if !ok {
+			apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+		setRetryAfter(c, job)
+		c.JSON(http.StatusOK, gin.H{"data": job})
+	}
+}
+
+// GenerateEventsHandler generates a handler streaming the progress of a job as
+// server-sent events until it finishes or the client disconnects
+func GenerateEventsHandler(manager *Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, ok := visibleJob(c, manager)
+		if !ok {
+			apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+		events, unsubscribe, ok := manager.Subscribe(job.ID)
+		if !ok {
+			apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+		defer unsubscribe()
+
+		utils.DisableCaching(c.Writer)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		if job.RetryAfter > 0 {
+			// Clients reconnect after the suggested poll interval
+			_, _ = c.Writer.WriteString(fmt.Sprintf("retry: %d\n\n", job.RetryAfter*1000))
+		}
+		c.SSEvent(eventType(job), job)
+		c.Writer.Flush()
+		if job.Status.Finished() {
+			return
+		}
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case job, open := <-events:
+				if !open {
+					return
+				}
+				c.SSEvent(eventType(job), job)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// visibleJob returns the job of the id parameter, if the requester may see it
+func visibleJob(c *gin.Context, manager *Manager) (Job, bool) {
+	job, ok := manager.Job(c.Param("id"))
+	if !ok {
+		return Job{}, false
+	}
+	if job.ownerID != "" {
+		ownerID, err := middleware.GetOwnerID(c.Request.Context())
+		if err != nil || fmt.Sprint(ownerID) != job.ownerID {
+			return Job{}, false
+		}
+	}
+	return job, true
+}
+
+// setRetryAfter sets the Retry-After header of unfinished jobs
+func setRetryAfter(c *gin.Context, job Job) {
+	if job.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(job.RetryAfter))
+	}
+}
+
+// eventType returns the server-sent event type of a job update
+func eventType(job Job) string {
+	if job.Status.Finished() {
+		return string(job.Status)
+	}
+	return EventProgress
+}
@@ -0,0 +1,410 @@
+// Package jobs runs long custom actions and bulk operations in the background and
+// reports their progress. Job statuses carry polling hints (a suggested poll interval
+// sent as Retry-After, throughput and ETA) and progress is streamed as server-sent
+// events, so clients don't have to poll aggressively.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/worker"
+)
+
+// ErrJobNotFound is returned for unknown or expired jobs
+var ErrJobNotFound = errors.New("job not found")
+
+// Default settings
+const (
+	DefaultMinPollInterval = time.Second
+	DefaultMaxPollInterval = 30 * time.Second
+	DefaultEventInterval   = 250 * time.Millisecond
+	DefaultRetention       = time.Hour
+)
+
+// Status of a job
+type Status string
+
+// Job statuses
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Finished reports whether the job is done, successfully or not
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// Func is the work of a job. It reports its progress through progress and returns
+// the result of the job.
+type Func func(ctx context.Context, progress *Progress) (interface{}, error)
+
+// Job is the status of a background job. Percent, Throughput, ETA and RetryAfter are
+// computed when the job is read.
+type Job struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Status      Status      `json:"status"`
+	Total       int64       `json:"total,omitempty"`
+	Done        int64       `json:"done"`
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	StartedAt   *time.Time  `json:"startedAt,omitempty"`
+	CompletedAt *time.Time  `json:"completedAt,omitempty"`
+
+	// Percent of the total done, once the total is known
+	Percent *float64 `json:"percent,omitempty"`
+
+	// Throughput is the number of items done per second
+	Throughput float64 `json:"throughput,omitempty"`
+
+	// ETA is the estimated completion time, once the total is known and items are done
+	ETA *time.Time `json:"eta,omitempty"`
+
+	// RetryAfter is the suggested number of seconds before polling the job again,
+	// zero once it is finished
+	RetryAfter int `json:"retryAfter,omitempty"`
+
+	ownerID string
+}
+
+// Config contains configuration for a job manager
+type Config struct {
+	// Path the job routes are registered under (e.g. "/api/jobs"), used in the
+	// Location header of accepted requests
+	Path string
+
+	// Pool the jobs run on (optional, a goroutine per job without it)
+	Pool *worker.Pool
+
+	// MinPollInterval and MaxPollInterval bound the suggested poll interval
+	// (DefaultMinPollInterval and DefaultMaxPollInterval if zero)
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+
+	// EventInterval is the minimum interval between progress events of a job
+	// (DefaultEventInterval if zero)
+	EventInterval time.Duration
+
+	// Retention is how long finished jobs are kept (DefaultRetention if zero)
+	Retention time.Duration
+}
+
+// Manager runs jobs and keeps their status. It implements refinegin.Drainer, so
+// running jobs can be drained on shutdown.
+type Manager struct {
+	config Config
+	wg     sync.WaitGroup
+
+	mu          sync.Mutex
+	jobs        map[string]*Job
+	published   map[string]time.Time
+	subscribers map[string]map[chan Job]struct{}
+}
+
+// NewManager creates a job manager
+func NewManager(config Config) *Manager {
+	if config.MinPollInterval <= 0 {
+		config.MinPollInterval = DefaultMinPollInterval
+	}
+	if config.MaxPollInterval <= 0 {
+		config.MaxPollInterval = DefaultMaxPollInterval
+	}
+	if config.MaxPollInterval < config.MinPollInterval {
+		config.MaxPollInterval = config.MinPollInterval
+	}
+	if config.EventInterval <= 0 {
+		config.EventInterval = DefaultEventInterval
+	}
+	if config.Retention <= 0 {
+		config.Retention = DefaultRetention
+	}
+	return &Manager{
+		config:      config,
+		jobs:        make(map[string]*Job),
+		published:   make(map[string]time.Time),
+		subscribers: make(map[string]map[chan Job]struct{}),
+	}
+}
+
+// Path returns the path of the status endpoint of a job
+func (m *Manager) Path(id string) string {
+	return m.config.Path + "/" + id
+}
+
+// Start runs fn in the background. The job belongs to the owner of ctx, if any (see
+// requestctx.OwnerID), and fn receives ctx without its cancellation, so jobs
+// started by requests outlive them.
+func (m *Manager) Start(ctx context.Context, name string, fn Func) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := &Job{ID: id, Name: name, Status: StatusPending, CreatedAt: time.Now()}
+	if ownerID, ok := requestctx.OwnerID.Get(ctx); ok && ownerID != nil {
+		job.ownerID = fmt.Sprint(ownerID)
+	}
+
+	m.mu.Lock()
+	m.cleanup(job.CreatedAt)
+	m.jobs[id] = job
+	snapshot := m.snapshot(job, job.CreatedAt)
+	m.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	m.wg.Add(1)
+	if m.config.Pool != nil {
+		// Jobs run once and keep the values of ctx, the context of the pool is
+		// ignored
+		if err := m.config.Pool.Submit("job:"+name, func(context.Context) error {
+			defer m.wg.Done()
+			m.run(ctx, id, fn)
+			return nil
+		}); err != nil {
+			m.wg.Done()
+			m.mu.Lock()
+			delete(m.jobs, id)
+			m.mu.Unlock()
+			return Job{}, err
+		}
+		return snapshot, nil
+	}
+	go func() {
+		defer m.wg.Done()
+		m.run(ctx, id, fn)
+	}()
+	return snapshot, nil
+}
+
+// Job returns the status of a job
+func (m *Manager) Job(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return m.snapshot(job, time.Now()), true
+}
+
+// Subscribe returns the progress of a job until the returned function is called.
+// The channel is closed after the job finishes.
+func (m *Manager) Subscribe(id string) (<-chan Job, func(), bool) {
+	events := make(chan Job, 16)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, false
+	}
+	if job.Status.Finished() {
+		events <- m.snapshot(job, time.Now())
+		close(events)
+		return events, func() {}, true
+	}
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = make(map[chan Job]struct{})
+	}
+	m.subscribers[id][events] = struct{}{}
+
+	return events, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subscribers[id][events]; ok {
+			delete(m.subscribers[id], events)
+			close(events)
+		}
+	}, true
+}
+
+// Drain waits for the running jobs to finish or ctx to be done
+func (m *Manager) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run runs the work of a job and records its outcome
+func (m *Manager) run(ctx context.Context, id string, fn Func) {
+	m.update(id, func(job *Job, now time.Time) {
+		job.Status = StatusRunning
+		job.StartedAt = &now
+	})
+
+	result, err := func() (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return fn(ctx, &Progress{manager: m, id: id})
+	}()
+
+	m.update(id, func(job *Job, now time.Time) {
+		job.CompletedAt = &now
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = StatusSucceeded
+		job.Result = result
+		if job.Total > 0 {
+			job.Done = job.Total
+		}
+	})
+}
+
+// update changes a job and publishes its progress to subscribers. Progress of running
+// jobs is published at most once per EventInterval.
+func (m *Manager) update(id string, change func(job *Job, now time.Time)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	change(job, now)
+
+	finished := job.Status.Finished()
+	if !finished && job.Status == StatusRunning && now.Sub(m.published[id]) < m.config.EventInterval {
+		return
+	}
+	m.published[id] = now
+
+	snapshot := m.snapshot(job, now)
+	for events := range m.subscribers[id] {
+		select {
+		case events <- snapshot:
+		default:
+			// Slow subscribers miss intermediate progress
+		}
+		if finished {
+			close(events)
+		}
+	}
+	if finished {
+		delete(m.subscribers, id)
+		delete(m.published, id)
+	}
+}
+
+// snapshot copies a job with its polling hints
+func (m *Manager) snapshot(job *Job, now time.Time) Job {
+	snapshot := *job
+	if job.Total > 0 {
+		percent := math.Min(100, float64(job.Done)*100/float64(job.Total))
+		snapshot.Percent = &percent
+	}
+	if job.Status.Finished() {
+		return snapshot
+	}
+
+	interval := m.config.MinPollInterval
+	if job.StartedAt != nil {
+		elapsed := now.Sub(*job.StartedAt)
+		// Without an estimate, poll less often the longer the job runs
+		interval = elapsed / 10
+		if job.Done > 0 && elapsed > 0 {
+			snapshot.Throughput = float64(job.Done) / elapsed.Seconds()
+			if job.Total > job.Done {
+				remaining := time.Duration(float64(job.Total-job.Done) / snapshot.Throughput * float64(time.Second))
+				eta := now.Add(remaining)
+				snapshot.ETA = &eta
+				interval = remaining / 10
+			}
+		}
+	}
+	if interval < m.config.MinPollInterval {
+		interval = m.config.MinPollInterval
+	}
+	if interval > m.config.MaxPollInterval {
+		interval = m.config.MaxPollInterval
+	}
+	snapshot.RetryAfter = int(math.Ceil(interval.Seconds()))
+	return snapshot
+}
+
+// cleanup removes jobs finished longer than the retention ago
+func (m *Manager) cleanup(now time.Time) {
+	for id, job := range m.jobs {
+		if job.CompletedAt != nil && now.Sub(*job.CompletedAt) > m.config.Retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Progress reports the progress of a running job
+type Progress struct {
+	manager *Manager
+	id      string
+}
+
+// SetTotal sets the number of items the job processes
+func (p *Progress) SetTotal(total int64) {
+	p.manager.update(p.id, func(job *Job, _ time.Time) {
+		job.Total = total
+	})
+}
+
+// Add marks n more items as done
+func (p *Progress) Add(n int64) {
+	p.manager.update(p.id, func(job *Job, _ time.Time) {
+		job.Done += n
+	})
+}
+
+// Set sets the number of items done
+func (p *Progress) Set(done int64) {
+	p.manager.update(p.id, func(job *Job, _ time.Time) {
+		job.Done = done
+	})
+}
+
+// progressKey is the context key of the progress of a job
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx carrying the progress of a job
+func WithProgress(ctx context.Context, progress *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// ProgressFromContext returns the progress of the job running with ctx. Outside
+// jobs it returns a progress reporting nowhere, so code can report progress
+// whether it runs in a job or not.
+func ProgressFromContext(ctx context.Context) *Progress {
+	if progress, ok := ctx.Value(progressKey{}).(*Progress); ok {
+		return progress
+	}
+	return &Progress{manager: NewManager(Config{})}
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
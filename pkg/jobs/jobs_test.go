@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+func waitFinished(t *testing.T, manager *Manager, id string) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		job, _ = manager.Job(id)
+		return job.Status.Finished()
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestManagerRunsJobs(t *testing.T) {
+	manager := NewManager(Config{})
+
+	job, err := manager.Start(context.Background(), "import", func(ctx context.Context, progress *Progress) (interface{}, error) {
+		progress.SetTotal(3)
+		progress.Add(2)
+		return "imported", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, job.Status)
+	assert.Equal(t, DefaultMinPollInterval, time.Duration(job.RetryAfter)*time.Second)
+
+	job = waitFinished(t, manager, job.ID)
+	assert.Equal(t, StatusSucceeded, job.Status)
+	assert.Equal(t, "imported", job.Result)
+	assert.Equal(t, int64(3), job.Done)
+	assert.Equal(t, 100.0, *job.Percent)
+	assert.Zero(t, job.RetryAfter)
+
+	failed, err := manager.Start(context.Background(), "broken", func(ctx context.Context, progress *Progress) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	require.NoError(t, err)
+	failed = waitFinished(t, manager, failed.ID)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Equal(t, "boom", failed.Error)
+
+	require.NoError(t, manager.Drain(context.Background()))
+}
+
+func TestManagerPollingHints(t *testing.T) {
+	manager := NewManager(Config{MinPollInterval: time.Second, MaxPollInterval: time.Minute})
+	now := time.Now()
+	started := now.Add(-100 * time.Second)
+
+	// 100 of 400 items in 100s leaves 300s, polled every 30s
+	job := &Job{Status: StatusRunning, StartedAt: &started, Total: 400, Done: 100}
+	snapshot := manager.snapshot(job, now)
+	assert.Equal(t, 25.0, *snapshot.Percent)
+	assert.InDelta(t, 1.0, snapshot.Throughput, 0.001)
+	assert.WithinDuration(t, now.Add(300*time.Second), *snapshot.ETA, time.Second)
+	assert.Equal(t, 30, snapshot.RetryAfter)
+
+	// Without a total, the interval grows with the running time up to the maximum
+	job = &Job{Status: StatusRunning, StartedAt: &started}
+	assert.Equal(t, 10, manager.snapshot(job, now).RetryAfter)
+	longRunning := now.Add(-time.Hour)
+	job.StartedAt = &longRunning
+	assert.Equal(t, 60, manager.snapshot(job, now).RetryAfter)
+}
+
+func TestJobRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := NewManager(Config{Path: "/jobs", EventInterval: time.Millisecond})
+
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if owner := c.GetHeader("X-Owner"); owner != "" {
+			requestctx.OwnerID.Set(c, owner)
+		}
+	})
+	r.POST("/reports", func(c *gin.Context) {
+		Accepted(c, manager, "report", func(ctx context.Context, progress *Progress) (interface{}, error) {
+			progress.SetTotal(2)
+			progress.Add(1)
+			<-release
+			progress.Add(1)
+			return gin.H{"rows": 2}, nil
+		})
+	})
+	RegisterJobRoutes(r.Group(""), "/jobs", manager)
+
+	request := func(method, path, owner string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		if owner != "" {
+			req.Header.Set("X-Owner", owner)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/reports", "ann")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	var accepted struct {
+		Data Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	assert.Equal(t, "/jobs/"+accepted.Data.ID, w.Header().Get("Location"))
+
+	// Jobs of owners are only visible to them
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/jobs/"+accepted.Data.ID, "bob").Code)
+	w = request(http.MethodGet, "/jobs/"+accepted.Data.ID, "ann")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// The event stream ends with the final status
+	server := httptest.NewServer(r)
+	defer server.Close()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/jobs/"+accepted.Data.ID+"/events", nil)
+	req.Header.Set("X-Owner", "ann")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	close(release)
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if event, ok := strings.CutPrefix(scanner.Text(), "event:"); ok {
+			events = append(events, event)
+		}
+	}
+	require.NotEmpty(t, events)
+	assert.Equal(t, EventProgress, events[0])
+	assert.Equal(t, EventSucceeded, events[len(events)-1])
+
+	w = request(http.MethodGet, "/jobs/"+accepted.Data.ID, "ann")
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"rows":2`)
+}
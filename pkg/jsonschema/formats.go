@@ -0,0 +1,78 @@
+package jsonschema
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Formats are the format checks asserted by the format keyword, by format name. Add
+// entries to assert custom formats; unknown formats are ignored.
+var Formats = map[string]func(value string) bool{
+	"date-time": isDateTime,
+	"date":      isDate,
+	"time":      isTime,
+	"email":     isEmail,
+	"hostname":  isHostname,
+	"ipv4":      isIPv4,
+	"ipv6":      isIPv6,
+	"uri":       isURI,
+	"uuid":      isUUID,
+	"regex":     isRegex,
+}
+
+var (
+	hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+	uuidPattern     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+func isDateTime(value string) bool {
+	_, err := time.Parse(time.RFC3339Nano, strings.ToUpper(value))
+	return err == nil
+}
+
+func isDate(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+func isTime(value string) bool {
+	_, err := time.Parse("15:04:05.999999999Z07:00", strings.ToUpper(value))
+	return err == nil
+}
+
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+func isHostname(value string) bool {
+	return len(value) <= 253 && hostnamePattern.MatchString(value)
+}
+
+func isIPv4(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+}
+
+func isIPv6(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && strings.Contains(value, ":")
+}
+
+func isURI(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != ""
+}
+
+func isUUID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+func isRegex(value string) bool {
+	_, err := regexp.Compile(value)
+	return err == nil
+}
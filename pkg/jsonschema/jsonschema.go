@@ -0,0 +1,302 @@
+// Package jsonschema validates JSON documents against JSON Schema draft 2020-12.
+//
+// Schemas are self-contained: $ref resolves JSON Pointers and $anchor names within the
+// same document, remote references are rejected when compiling. All vocabularies of
+// draft 2020-12 are applied, including unevaluatedProperties and unevaluatedItems.
+// The format keyword is asserted for the formats listed in Formats and ignored for
+// others.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Draft is the $schema URI of the supported draft
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Error is a failed validation, located by JSON Pointers into the instance and the
+// schema
+type Error struct {
+	// InstancePath points to the invalid value, "" for the whole document
+	InstancePath string `json:"instancePath"`
+
+	// SchemaPath points to the failed keyword in the schema
+	SchemaPath string `json:"schemaPath"`
+
+	// Keyword is the failed keyword
+	Keyword string `json:"keyword"`
+
+	// Message describes the failure
+	Message string `json:"message"`
+}
+
+// Error formats the error with its instance path
+func (e Error) Error() string {
+	path := e.InstancePath
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message
+}
+
+// Schema is a compiled schema document
+type Schema struct {
+	root     interface{}
+	anchors  map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Compile prepares a schema document for validation. The document may be a map, a
+// boolean, raw JSON or any value marshaling to a JSON object.
+func Compile(document interface{}) (*Schema, error) {
+	root, err := normalize(document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if _, ok := root.(map[string]interface{}); !ok {
+		if _, ok := root.(bool); !ok {
+			return nil, fmt.Errorf("invalid schema: must be an object or a boolean")
+		}
+	}
+	if object, ok := root.(map[string]interface{}); ok {
+		if draft, ok := object["$schema"].(string); ok && strings.TrimSuffix(draft, "#") != Draft {
+			return nil, fmt.Errorf("unsupported $schema %q, only %s is supported", draft, Draft)
+		}
+	}
+
+	s := &Schema{
+		root:     root,
+		anchors:  make(map[string]interface{}),
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	if err := s.prepare(root, ""); err != nil {
+		return nil, err
+	}
+	if err := s.checkRefs(root, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustCompile is like Compile but panics on invalid schemas
+func MustCompile(document interface{}) *Schema {
+	s, err := Compile(document)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate validates an instance. The instance may be a decoded JSON value, raw JSON
+// or any value marshaling to JSON. It returns nil when the instance is valid.
+func (s *Schema) Validate(instance interface{}) []Error {
+	value, err := normalize(instance)
+	if err != nil {
+		return []Error{{Keyword: "type", Message: "invalid JSON: " + err.Error()}}
+	}
+	v := &validator{schema: s}
+	return v.validate(s.root, value, "", "").errors
+}
+
+// prepare collects the anchors and compiles the patterns of a schema and its subschemas
+func (s *Schema) prepare(node interface{}, path string) error {
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		if _, ok := node.(bool); ok {
+			return nil
+		}
+		return fmt.Errorf("invalid schema at %q: must be an object or a boolean", pointerOrRoot(path))
+	}
+
+	if anchor, ok := object["$anchor"].(string); ok {
+		s.anchors[anchor] = object
+	}
+	if anchor, ok := object["$dynamicAnchor"].(string); ok {
+		if _, taken := s.anchors[anchor]; !taken {
+			s.anchors[anchor] = object
+		}
+	}
+	if pattern, ok := object["pattern"].(string); ok {
+		if err := s.compilePattern(pattern, path+"/pattern"); err != nil {
+			return err
+		}
+	}
+	if properties, ok := object["patternProperties"].(map[string]interface{}); ok {
+		for pattern := range properties {
+			if err := s.compilePattern(pattern, path+"/patternProperties"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, keyword := range sortedKeys(object) {
+		value := object[keyword]
+		switch keyword {
+		case "items", "contains", "additionalProperties", "propertyNames", "not", "if", "then", "else",
+			"unevaluatedItems", "unevaluatedProperties", "contentSchema":
+			if err := s.prepare(value, path+"/"+keyword); err != nil {
+				return err
+			}
+		case "allOf", "anyOf", "oneOf", "prefixItems":
+			items, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid schema at %q: must be an array", path+"/"+keyword)
+			}
+			for i, item := range items {
+				if err := s.prepare(item, fmt.Sprintf("%s/%s/%d", path, keyword, i)); err != nil {
+					return err
+				}
+			}
+		case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas":
+			schemas, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid schema at %q: must be an object", path+"/"+keyword)
+			}
+			for _, name := range sortedKeys(schemas) {
+				if err := s.prepare(schemas[name], path+"/"+keyword+"/"+escapePointer(name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRefs verifies that every reference of the document resolves
+func (s *Schema) checkRefs(node interface{}, path string) error {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for _, keyword := range []string{"$ref", "$dynamicRef"} {
+			if ref, ok := node[keyword].(string); ok {
+				if _, err := s.resolve(ref); err != nil {
+					return fmt.Errorf("invalid schema at %q: %w", path+"/"+keyword, err)
+				}
+			}
+		}
+		for _, key := range sortedKeys(node) {
+			if key == "const" || key == "enum" || key == "default" || key == "examples" {
+				continue
+			}
+			if err := s.checkRefs(node[key], path+"/"+escapePointer(key)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range node {
+			if err := s.checkRefs(item, path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compilePattern compiles an ECMA-262 pattern, as far as Go's RE2 syntax allows
+func (s *Schema) compilePattern(pattern, path string) error {
+	if _, ok := s.patterns[pattern]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid schema at %q: invalid pattern %q: %w", path, pattern, err)
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+// resolve returns the subschema a reference points to
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("remote reference %q is not supported", ref)
+	}
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %q", ref)
+	}
+	if fragment != "" && !strings.HasPrefix(fragment, "/") {
+		if target, ok := s.anchors[fragment]; ok {
+			return target, nil
+		}
+		return nil, fmt.Errorf("unknown anchor in reference %q", ref)
+	}
+
+	node := s.root
+	if fragment == "" {
+		return node, nil
+	}
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch current := node.(type) {
+		case map[string]interface{}:
+			next, ok := current[token]
+			if !ok {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(current) {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			node = current[i]
+		default:
+			return nil, fmt.Errorf("reference %q not found", ref)
+		}
+	}
+	return node, nil
+}
+
+// normalize converts a value to the generic form of decoded JSON, with json.Number
+// for numbers
+func normalize(value interface{}) (interface{}, error) {
+	var data []byte
+	switch v := value.(type) {
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	default:
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result interface{}
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// escapePointer escapes a JSON Pointer token
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// pointerOrRoot returns a pointer, or "/" for the root
+func pointerOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// sortedKeys returns the keys of an object in order, so errors are deterministic
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paths(errs []Error) []string {
+	result := make([]string, len(errs))
+	for i, err := range errs {
+		result[i] = err.InstancePath + " " + err.Keyword
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	schema := MustCompile(json.RawMessage(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["name", "port"],
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+			"ratio": {"type": "number", "multipleOf": 0.1},
+			"mode": {"enum": ["fast", "safe"]},
+			"email": {"type": "string", "format": "email"},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+			"owner": {"$ref": "#/$defs/user"}
+		},
+		"additionalProperties": false,
+		"$defs": {
+			"user": {"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}}}
+		}
+	}`))
+
+	assert.Empty(t, schema.Validate(map[string]interface{}{
+		"name": "api", "port": 8080, "ratio": 0.3, "mode": "safe", "email": "a@example.com",
+		"tags": []string{"a", "b"}, "owner": map[string]interface{}{"id": 1},
+	}))
+
+	errs := schema.Validate(json.RawMessage(`{
+		"name": "A", "port": 70000.5, "ratio": 0.25, "mode": "slow", "email": "nope",
+		"tags": ["a", "a", 3, "d"], "owner": {}, "extra": true
+	}`))
+	assert.ElementsMatch(t, []string{
+		"/name minLength",
+		"/name pattern",
+		"/port type",
+		"/port maximum",
+		"/ratio multipleOf",
+		"/mode enum",
+		"/email format",
+		"/tags maxItems",
+		"/tags uniqueItems",
+		"/tags/2 type",
+		"/owner required",
+		"/extra additionalProperties",
+	}, paths(errs))
+
+	errs = schema.Validate(map[string]interface{}{})
+	assert.Equal(t, []string{" required", " required"}, paths(errs))
+	assert.Equal(t, "/required", errs[0].SchemaPath)
+	assert.Equal(t, `/: missing required property "name"`, errs[0].Error())
+}
+
+func TestValidateCombinators(t *testing.T) {
+	schema := MustCompile(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"kind": map[string]interface{}{"type": "string"},
+		},
+		"if": map[string]interface{}{
+			"properties": map[string]interface{}{"kind": map[string]interface{}{"const": "card"}},
+		},
+		"then": map[string]interface{}{
+			"required":   []string{"number"},
+			"properties": map[string]interface{}{"number": map[string]interface{}{"type": "string"}},
+		},
+		"else": map[string]interface{}{
+			"required":   []string{"iban"},
+			"properties": map[string]interface{}{"iban": map[string]interface{}{"type": "string"}},
+		},
+		"unevaluatedProperties": false,
+	})
+
+	assert.Empty(t, schema.Validate(map[string]interface{}{"kind": "card", "number": "4111"}))
+	assert.Empty(t, schema.Validate(map[string]interface{}{"kind": "wire", "iban": "PL61"}))
+	assert.Equal(t, []string{" required"}, paths(schema.Validate(map[string]interface{}{"kind": "card"})))
+	assert.Equal(t, []string{"/iban unevaluatedProperties"}, paths(schema.Validate(map[string]interface{}{"kind": "card", "number": "1", "iban": "x"})))
+
+	oneOf := MustCompile(map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "integer"},
+			map[string]interface{}{"minimum": 2},
+		},
+	})
+	assert.Empty(t, oneOf.Validate(1))
+	assert.Empty(t, oneOf.Validate(2.5))
+	assert.Equal(t, []string{" oneOf"}, paths(oneOf.Validate(3)))
+
+	anyOf := MustCompile(map[string]interface{}{
+		"anyOf": []interface{}{map[string]interface{}{"type": "string"}, map[string]interface{}{"type": "null"}},
+		"not":   map[string]interface{}{"const": "forbidden"},
+	})
+	assert.Empty(t, anyOf.Validate(nil))
+	assert.Equal(t, []string{" anyOf", " type", " type"}, paths(anyOf.Validate(1)))
+	assert.Equal(t, []string{" not"}, paths(anyOf.Validate("forbidden")))
+}
+
+func TestValidateArrays(t *testing.T) {
+	schema := MustCompile(map[string]interface{}{
+		"type":             "array",
+		"prefixItems":      []interface{}{map[string]interface{}{"type": "string"}, map[string]interface{}{"type": "integer"}},
+		"contains":         map[string]interface{}{"const": true},
+		"maxContains":      1,
+		"unevaluatedItems": false,
+	})
+
+	assert.Empty(t, schema.Validate([]interface{}{"a", 1, true}))
+	assert.Equal(t, []string{"/1 type"}, paths(schema.Validate([]interface{}{"a", "b", true})))
+	assert.Equal(t, []string{" contains", "/2 unevaluatedItems"}, paths(schema.Validate([]interface{}{"a", 1, false})))
+	assert.Equal(t, []string{" maxContains"}, paths(schema.Validate([]interface{}{"a", 1, true, true})))
+}
+
+func TestValidateRecursiveRef(t *testing.T) {
+	schema := MustCompile(map[string]interface{}{
+		"$defs": map[string]interface{}{
+			"node": map[string]interface{}{
+				"$anchor": "node",
+				"type":    "object",
+				"properties": map[string]interface{}{
+					"value":    map[string]interface{}{"type": "integer"},
+					"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#node"}},
+				},
+			},
+		},
+		"$ref": "#/$defs/node",
+	})
+
+	errs := schema.Validate(json.RawMessage(`{"value": 1, "children": [{"value": 2, "children": [{"value": "x"}]}]}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/children/0/children/0/value", errs[0].InstancePath)
+	assert.Equal(t, "/$ref/properties/children/items/$ref/properties/children/items/$ref/properties/value/type", errs[0].SchemaPath)
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := Compile(map[string]interface{}{"$ref": "https://example.com/schema.json"})
+	assert.ErrorContains(t, err, "remote reference")
+
+	_, err = Compile(map[string]interface{}{"$ref": "#/$defs/missing"})
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = Compile(map[string]interface{}{"properties": map[string]interface{}{"name": map[string]interface{}{"pattern": "("}}})
+	assert.ErrorContains(t, err, "/properties/name/pattern")
+
+	_, err = Compile(map[string]interface{}{"$schema": "http://json-schema.org/draft-07/schema#"})
+	assert.ErrorContains(t, err, "unsupported $schema")
+
+	_, err = Compile("string")
+	assert.Error(t, err)
+}
+
+func TestFormats(t *testing.T) {
+	valid := map[string]string{
+		"date-time": "2024-01-02T10:00:00Z",
+		"date":      "2024-02-29",
+		"time":      "10:00:00+02:00",
+		"email":     "user@example.com",
+		"hostname":  "api.example.com",
+		"ipv4":      "192.168.0.1",
+		"ipv6":      "::1",
+		"uri":       "https://example.com/a?b=c",
+		"uuid":      "123e4567-e89b-12d3-a456-426614174000",
+	}
+	for format, value := range valid {
+		assert.True(t, Formats[format](value), format)
+	}
+	assert.False(t, Formats["date"]("2023-02-29"))
+	assert.False(t, Formats["ipv4"]("::1"))
+	assert.False(t, Formats["uri"]("/relative"))
+
+	// Unknown formats are not asserted
+	assert.Empty(t, MustCompile(map[string]interface{}{"format": "custom"}).Validate("anything"))
+}
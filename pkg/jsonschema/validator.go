@@ -0,0 +1,603 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDepth bounds reference chains, so recursive schemas cannot loop forever
+const maxDepth = 256
+
+// validator validates one instance
+type validator struct {
+	schema *Schema
+	depth  int
+}
+
+// result holds the errors of a subschema and the properties and items it evaluated,
+// which unevaluatedProperties and unevaluatedItems depend on
+type result struct {
+	errors   []Error
+	props    map[string]bool
+	items    map[int]bool
+	allItems bool
+}
+
+func (r *result) valid() bool {
+	return len(r.errors) == 0
+}
+
+func (r *result) fail(instancePath, schemaPath, keyword, format string, args ...interface{}) {
+	r.errors = append(r.errors, Error{
+		InstancePath: instancePath,
+		SchemaPath:   schemaPath + "/" + keyword,
+		Keyword:      keyword,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// merge adds the annotations of a successful subschema
+func (r *result) merge(other *result) {
+	for name := range other.props {
+		r.evaluateProp(name)
+	}
+	for i := range other.items {
+		r.evaluateItem(i)
+	}
+	r.allItems = r.allItems || other.allItems
+}
+
+func (r *result) evaluateProp(name string) {
+	if r.props == nil {
+		r.props = make(map[string]bool)
+	}
+	r.props[name] = true
+}
+
+func (r *result) evaluateItem(i int) {
+	if r.items == nil {
+		r.items = make(map[int]bool)
+	}
+	r.items[i] = true
+}
+
+// validate applies a schema to an instance
+func (v *validator) validate(schema interface{}, instance interface{}, instancePath, schemaPath string) *result {
+	r := &result{}
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			r.errors = append(r.errors, Error{InstancePath: instancePath, SchemaPath: schemaPath, Keyword: "false", Message: "no value is allowed"})
+		}
+		return r
+	case map[string]interface{}:
+		v.applyReferences(schema, instance, instancePath, schemaPath, r)
+		v.applyGeneric(schema, instance, instancePath, schemaPath, r)
+		v.applyCombinators(schema, instance, instancePath, schemaPath, r)
+		switch value := instance.(type) {
+		case json.Number:
+			v.applyNumber(schema, value, instancePath, schemaPath, r)
+		case string:
+			v.applyString(schema, value, instancePath, schemaPath, r)
+		case []interface{}:
+			v.applyArray(schema, value, instancePath, schemaPath, r)
+		case map[string]interface{}:
+			v.applyObject(schema, value, instancePath, schemaPath, r)
+		}
+	}
+	return r
+}
+
+// applyReferences follows $ref and $dynamicRef
+func (v *validator) applyReferences(schema map[string]interface{}, instance interface{}, instancePath, schemaPath string, r *result) {
+	for _, keyword := range []string{"$ref", "$dynamicRef"} {
+		ref, ok := schema[keyword].(string)
+		if !ok {
+			continue
+		}
+		target, err := v.schema.resolve(ref)
+		if err != nil {
+			r.fail(instancePath, schemaPath, keyword, "%s", err.Error())
+			continue
+		}
+		if v.depth >= maxDepth {
+			r.fail(instancePath, schemaPath, keyword, "reference %q nested too deeply", ref)
+			continue
+		}
+		v.depth++
+		sub := v.validate(target, instance, instancePath, schemaPath+"/"+keyword)
+		v.depth--
+		r.errors = append(r.errors, sub.errors...)
+		if sub.valid() {
+			r.merge(sub)
+		}
+	}
+}
+
+// applyGeneric applies the keywords valid for any type
+func (v *validator) applyGeneric(schema map[string]interface{}, instance interface{}, instancePath, schemaPath string, r *result) {
+	if types, ok := schema["type"]; ok {
+		names := []string{}
+		switch types := types.(type) {
+		case string:
+			names = append(names, types)
+		case []interface{}:
+			for _, name := range types {
+				if name, ok := name.(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+		matched := false
+		for _, name := range names {
+			if hasType(instance, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r.fail(instancePath, schemaPath, "type", "must be %s, got %s", strings.Join(names, " or "), typeOf(instance))
+		}
+	}
+
+	if expected, ok := schema["const"]; ok && !equal(instance, expected) {
+		r.fail(instancePath, schemaPath, "const", "must be %s", describe(expected))
+	}
+
+	if values, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, value := range values {
+			if equal(instance, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, len(values))
+			for i, value := range values {
+				allowed[i] = describe(value)
+			}
+			r.fail(instancePath, schemaPath, "enum", "must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+}
+
+// applyCombinators applies allOf, anyOf, oneOf, not, if/then/else
+func (v *validator) applyCombinators(schema map[string]interface{}, instance interface{}, instancePath, schemaPath string, r *result) {
+	if schemas, ok := schema["allOf"].([]interface{}); ok {
+		for i, sub := range schemas {
+			res := v.validate(sub, instance, instancePath, fmt.Sprintf("%s/allOf/%d", schemaPath, i))
+			r.errors = append(r.errors, res.errors...)
+			if res.valid() {
+				r.merge(res)
+			}
+		}
+	}
+
+	if schemas, ok := schema["anyOf"].([]interface{}); ok {
+		var failures []Error
+		matched := false
+		for i, sub := range schemas {
+			res := v.validate(sub, instance, instancePath, fmt.Sprintf("%s/anyOf/%d", schemaPath, i))
+			if res.valid() {
+				matched = true
+				r.merge(res)
+			} else {
+				failures = append(failures, res.errors...)
+			}
+		}
+		if !matched {
+			r.fail(instancePath, schemaPath, "anyOf", "must match at least one schema of anyOf")
+			r.errors = append(r.errors, failures...)
+		}
+	}
+
+	if schemas, ok := schema["oneOf"].([]interface{}); ok {
+		var failures []Error
+		var matches []int
+		var match *result
+		for i, sub := range schemas {
+			res := v.validate(sub, instance, instancePath, fmt.Sprintf("%s/oneOf/%d", schemaPath, i))
+			if res.valid() {
+				matches = append(matches, i)
+				match = res
+			} else {
+				failures = append(failures, res.errors...)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			r.fail(instancePath, schemaPath, "oneOf", "must match exactly one schema of oneOf")
+			r.errors = append(r.errors, failures...)
+		case 1:
+			r.merge(match)
+		default:
+			r.fail(instancePath, schemaPath, "oneOf", "must match exactly one schema of oneOf, matched %d (%s)", len(matches), joinInts(matches))
+		}
+	}
+
+	if sub, ok := schema["not"]; ok {
+		if v.validate(sub, instance, instancePath, schemaPath+"/not").valid() {
+			r.fail(instancePath, schemaPath, "not", "must not match the schema of not")
+		}
+	}
+
+	if condition, ok := schema["if"]; ok {
+		res := v.validate(condition, instance, instancePath, schemaPath+"/if")
+		branch := "else"
+		if res.valid() {
+			r.merge(res)
+			branch = "then"
+		}
+		if sub, ok := schema[branch]; ok {
+			res := v.validate(sub, instance, instancePath, schemaPath+"/"+branch)
+			r.errors = append(r.errors, res.errors...)
+			if res.valid() {
+				r.merge(res)
+			}
+		}
+	}
+}
+
+// applyNumber applies the numeric keywords
+func (v *validator) applyNumber(schema map[string]interface{}, value json.Number, instancePath, schemaPath string, r *result) {
+	number, ok := toRat(value)
+	if !ok {
+		return
+	}
+	bound := func(keyword string, failed func(cmp int) bool, relation string) {
+		limit, ok := toRat(schema[keyword])
+		if ok && failed(number.Cmp(limit)) {
+			r.fail(instancePath, schemaPath, keyword, "must be %s %s", relation, schema[keyword])
+		}
+	}
+	bound("minimum", func(cmp int) bool { return cmp < 0 }, ">=")
+	bound("maximum", func(cmp int) bool { return cmp > 0 }, "<=")
+	bound("exclusiveMinimum", func(cmp int) bool { return cmp <= 0 }, ">")
+	bound("exclusiveMaximum", func(cmp int) bool { return cmp >= 0 }, "<")
+
+	if divisor, ok := toRat(schema["multipleOf"]); ok && divisor.Sign() > 0 {
+		quotient := new(big.Rat).Quo(number, divisor)
+		if !quotient.IsInt() {
+			r.fail(instancePath, schemaPath, "multipleOf", "must be a multiple of %s", schema["multipleOf"])
+		}
+	}
+}
+
+// applyString applies the string keywords
+func (v *validator) applyString(schema map[string]interface{}, value, instancePath, schemaPath string, r *result) {
+	length := utf8.RuneCountInString(value)
+	if limit, ok := toInt(schema["minLength"]); ok && length < limit {
+		r.fail(instancePath, schemaPath, "minLength", "must be at least %d characters long", limit)
+	}
+	if limit, ok := toInt(schema["maxLength"]); ok && length > limit {
+		r.fail(instancePath, schemaPath, "maxLength", "must be at most %d characters long", limit)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re := v.schema.patterns[pattern]; re != nil && !re.MatchString(value) {
+			r.fail(instancePath, schemaPath, "pattern", "must match pattern %q", pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok {
+		if check, known := Formats[format]; known && !check(value) {
+			r.fail(instancePath, schemaPath, "format", "must be a valid %s", format)
+		}
+	}
+}
+
+// applyArray applies the array keywords
+func (v *validator) applyArray(schema map[string]interface{}, items []interface{}, instancePath, schemaPath string, r *result) {
+	if limit, ok := toInt(schema["minItems"]); ok && len(items) < limit {
+		r.fail(instancePath, schemaPath, "minItems", "must have at least %d items", limit)
+	}
+	if limit, ok := toInt(schema["maxItems"]); ok && len(items) > limit {
+		r.fail(instancePath, schemaPath, "maxItems", "must have at most %d items", limit)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+	duplicates:
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if equal(items[i], items[j]) {
+					r.fail(instancePath, schemaPath, "uniqueItems", "must not contain duplicate items (%d and %d are equal)", i, j)
+					break duplicates
+				}
+			}
+		}
+	}
+
+	prefix := 0
+	if schemas, ok := schema["prefixItems"].([]interface{}); ok {
+		for i, sub := range schemas {
+			if i >= len(items) {
+				break
+			}
+			res := v.validate(sub, items[i], instancePath+"/"+strconv.Itoa(i), fmt.Sprintf("%s/prefixItems/%d", schemaPath, i))
+			r.errors = append(r.errors, res.errors...)
+			r.evaluateItem(i)
+			prefix = i + 1
+		}
+	}
+	if sub, ok := schema["items"]; ok {
+		for i := prefix; i < len(items); i++ {
+			if sub == false {
+				r.fail(instancePath+"/"+strconv.Itoa(i), schemaPath, "items", "item %d is not allowed", i)
+				continue
+			}
+			res := v.validate(sub, items[i], instancePath+"/"+strconv.Itoa(i), schemaPath+"/items")
+			r.errors = append(r.errors, res.errors...)
+		}
+		r.allItems = true
+	}
+
+	if sub, ok := schema["contains"]; ok {
+		matches := 0
+		for i, item := range items {
+			if v.validate(sub, item, instancePath+"/"+strconv.Itoa(i), schemaPath+"/contains").valid() {
+				matches++
+				r.evaluateItem(i)
+			}
+		}
+		minimum := 1
+		if limit, ok := toInt(schema["minContains"]); ok {
+			minimum = limit
+		}
+		if matches < minimum {
+			r.fail(instancePath, schemaPath, "contains", "must contain at least %d matching items, found %d", minimum, matches)
+		}
+		if limit, ok := toInt(schema["maxContains"]); ok && matches > limit {
+			r.fail(instancePath, schemaPath, "maxContains", "must contain at most %d matching items, found %d", limit, matches)
+		}
+	}
+
+	if sub, ok := schema["unevaluatedItems"]; ok && !r.allItems {
+		for i := range items {
+			if r.items[i] {
+				continue
+			}
+			if sub == false {
+				r.fail(instancePath+"/"+strconv.Itoa(i), schemaPath, "unevaluatedItems", "item %d is not allowed", i)
+				continue
+			}
+			res := v.validate(sub, items[i], instancePath+"/"+strconv.Itoa(i), schemaPath+"/unevaluatedItems")
+			r.errors = append(r.errors, res.errors...)
+		}
+		r.allItems = true
+	}
+}
+
+// applyObject applies the object keywords
+func (v *validator) applyObject(schema map[string]interface{}, object map[string]interface{}, instancePath, schemaPath string, r *result) {
+	if limit, ok := toInt(schema["minProperties"]); ok && len(object) < limit {
+		r.fail(instancePath, schemaPath, "minProperties", "must have at least %d properties", limit)
+	}
+	if limit, ok := toInt(schema["maxProperties"]); ok && len(object) > limit {
+		r.fail(instancePath, schemaPath, "maxProperties", "must have at most %d properties", limit)
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := object[name]; !present {
+					r.fail(instancePath, schemaPath, "required", "missing required property %q", name)
+				}
+			}
+		}
+	}
+	if dependencies, ok := schema["dependentRequired"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependencies) {
+			if _, present := object[name]; !present {
+				continue
+			}
+			required, _ := dependencies[name].([]interface{})
+			for _, other := range required {
+				if other, ok := other.(string); ok {
+					if _, present := object[other]; !present {
+						r.fail(instancePath, schemaPath, "dependentRequired", "property %q is required when %q is present", other, name)
+					}
+				}
+			}
+		}
+	}
+
+	if sub, ok := schema["propertyNames"]; ok {
+		for _, name := range sortedKeys(object) {
+			res := v.validate(sub, name, instancePath+"/"+escapePointer(name), schemaPath+"/propertyNames")
+			r.errors = append(r.errors, res.errors...)
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	patterns, _ := schema["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+	for _, name := range sortedKeys(object) {
+		value := object[name]
+		location := instancePath + "/" + escapePointer(name)
+		matched := false
+
+		if sub, ok := properties[name]; ok {
+			matched = true
+			res := v.validate(sub, value, location, schemaPath+"/properties/"+escapePointer(name))
+			r.errors = append(r.errors, res.errors...)
+		}
+		for _, pattern := range sortedKeys(patterns) {
+			if re := v.schema.patterns[pattern]; re != nil && re.MatchString(name) {
+				matched = true
+				res := v.validate(patterns[pattern], value, location, schemaPath+"/patternProperties/"+escapePointer(pattern))
+				r.errors = append(r.errors, res.errors...)
+			}
+		}
+		if !matched && hasAdditional {
+			matched = true
+			if additional == false {
+				r.fail(location, schemaPath, "additionalProperties", "property %q is not allowed", name)
+			} else {
+				res := v.validate(additional, value, location, schemaPath+"/additionalProperties")
+				r.errors = append(r.errors, res.errors...)
+			}
+		}
+		if matched {
+			r.evaluateProp(name)
+		}
+	}
+
+	if dependencies, ok := schema["dependentSchemas"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependencies) {
+			if _, present := object[name]; !present {
+				continue
+			}
+			res := v.validate(dependencies[name], object, instancePath, schemaPath+"/dependentSchemas/"+escapePointer(name))
+			r.errors = append(r.errors, res.errors...)
+			if res.valid() {
+				r.merge(res)
+			}
+		}
+	}
+
+	if sub, ok := schema["unevaluatedProperties"]; ok {
+		for _, name := range sortedKeys(object) {
+			if r.props[name] {
+				continue
+			}
+			location := instancePath + "/" + escapePointer(name)
+			if sub == false {
+				r.fail(location, schemaPath, "unevaluatedProperties", "property %q is not allowed", name)
+			} else {
+				res := v.validate(sub, object[name], location, schemaPath+"/unevaluatedProperties")
+				r.errors = append(r.errors, res.errors...)
+			}
+			r.evaluateProp(name)
+		}
+	}
+}
+
+// hasType reports whether an instance has a JSON Schema type
+func hasType(instance interface{}, name string) bool {
+	switch name {
+	case "null":
+		return instance == nil
+	case "boolean":
+		_, ok := instance.(bool)
+		return ok
+	case "string":
+		_, ok := instance.(string)
+		return ok
+	case "number":
+		_, ok := instance.(json.Number)
+		return ok
+	case "integer":
+		number, ok := instance.(json.Number)
+		if !ok {
+			return false
+		}
+		rat, ok := toRat(number)
+		return ok && rat.IsInt()
+	case "array":
+		_, ok := instance.([]interface{})
+		return ok
+	case "object":
+		_, ok := instance.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type of an instance
+func typeOf(instance interface{}) string {
+	switch instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if hasType(instance, "integer") {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", instance)
+}
+
+// equal compares JSON values, numbers by value
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okA := toRat(a)
+		y, okB := toRat(b)
+		return okA && okB && x.Cmp(y) == 0
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// describe formats a schema value for messages
+func describe(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// toRat converts a schema or instance number to an exact rational
+func toRat(value interface{}) (*big.Rat, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	rat, ok := new(big.Rat).SetString(string(number))
+	return rat, ok
+}
+
+// toInt converts a non-negative integer keyword value
+func toInt(value interface{}) (int, bool) {
+	rat, ok := toRat(value)
+	if !ok || !rat.IsInt() || rat.Sign() < 0 || !rat.Num().IsInt64() || rat.Num().Int64() > math.MaxInt32 {
+		return 0, false
+	}
+	return int(rat.Num().Int64()), true
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,272 @@
+package locking
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// LockField is the key added to locked records in list responses
+const LockField = "_lock"
+
+// Config contains configuration for record locking
+type Config struct {
+	// Store persisting the locks
+	Store *Store
+
+	// Lock duration; clients keep a lock alive with heartbeats (default 5m)
+	TTL time.Duration
+
+	// Holder identifies the current user (default the owner ID set by middleware.OwnerContext)
+	Holder func(c *gin.Context) (string, error)
+
+	// Name of the record ID route parameter (default "id")
+	IDParam string
+}
+
+func (config Config) withDefaults() Config {
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.Holder == nil {
+		config.Holder = DefaultHolder
+	}
+	if config.IDParam == "" {
+		config.IDParam = "id"
+	}
+	return config
+}
+
+// DefaultHolder uses the owner ID from the context as lock holder
+func DefaultHolder(c *gin.Context) (string, error) {
+	ownerID, err := middleware.GetOwnerID(c)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", ownerID), nil
+}
+
+// LockInfo describes a lock in list responses
+type LockInfo struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// RegisterLockRoutes registers POST /<resource>/:id/lock, /unlock and /heartbeat endpoints
+func RegisterLockRoutes(router *gin.RouterGroup, res resource.Resource, config Config) {
+	config = config.withDefaults()
+	base := "/" + res.GetName() + "/:" + config.IDParam
+	router.POST(base+"/lock", GenerateLockHandler(res, config))
+	router.POST(base+"/unlock", GenerateUnlockHandler(res, config))
+	router.POST(base+"/heartbeat", GenerateHeartbeatHandler(res, config))
+}
+
+// GenerateLockHandler creates a handler acquiring an edit lock on a record
+func GenerateLockHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		holder, err := config.Holder(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		lock, err := config.Store.Acquire(c.Request.Context(), res.GetName(), c.Param(config.IDParam), holder, config.TTL)
+		if errors.Is(err, ErrLocked) {
+			apierror.WriteWith(c, apierror.New(http.StatusLocked, err.Error()), err, gin.H{"lock": lock})
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": lock})
+	}
+}
+
+// GenerateHeartbeatHandler creates a handler extending the current user's lock
+func GenerateHeartbeatHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		holder, err := config.Holder(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		lock, err := config.Store.Heartbeat(c.Request.Context(), res.GetName(), c.Param(config.IDParam), holder, config.TTL)
+		if errors.Is(err, ErrLockNotHeld) {
+			apierror.Respond(c, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": lock})
+	}
+}
+
+// GenerateUnlockHandler creates a handler releasing the current user's lock
+func GenerateUnlockHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		holder, err := config.Holder(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		err = config.Store.Release(c.Request.Context(), res.GetName(), c.Param(config.IDParam), holder)
+		if errors.Is(err, ErrLockNotHeld) {
+			apierror.Respond(c, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// EnforceLocks is a middleware rejecting updates and deletes of records locked by another
+// user with 423 Locked, and adding LockField to locked records in list responses.
+// Records without a lock can be changed by anyone.
+func EnforceLocks(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	collection := "/" + res.GetName()
+	item := collection + "/:" + config.IDParam
+
+	return func(c *gin.Context) {
+		path := c.FullPath()
+
+		switch {
+		case strings.HasSuffix(path, item) && isMutation(c.Request.Method):
+			holder, _ := config.Holder(c)
+			lock, err := config.Store.Check(c.Request.Context(), res.GetName(), c.Param(config.IDParam), holder)
+			if errors.Is(err, ErrLocked) {
+				c.Abort()
+				apierror.WriteWith(c, apierror.New(http.StatusLocked, err.Error()), err, gin.H{"lock": lock})
+				return
+			}
+			if err != nil {
+				apierror.Abort(c, http.StatusInternalServerError, err)
+				return
+			}
+			c.Next()
+
+		case strings.HasSuffix(path, collection) && c.Request.Method == http.MethodGet:
+			// Lock state changes independently of the query, so cached lists would be stale
+			c.Request.Header.Del("If-None-Match")
+			w := &listWriter{ResponseWriter: c.Writer}
+			c.Writer = w
+			c.Next()
+			c.Writer = w.ResponseWriter
+			w.flush(c, res, config.Store)
+
+		default:
+			c.Next()
+		}
+	}
+}
+
+func isMutation(method string) bool {
+	return method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// listWriter buffers successful JSON list responses so lock info can be added to them
+type listWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers successful JSON bodies and passes everything else through
+func (w *listWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && w.Status() == http.StatusOK &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString buffers successful JSON bodies and passes everything else through
+func (w *listWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flush writes the buffered list with LockField set on locked records
+func (w *listWriter) flush(c *gin.Context, res resource.Resource, store *Store) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	if annotated, err := annotate(c, res, store, body); err == nil {
+		body = annotated
+	}
+
+	w.Header().Del("ETag")
+	utils.DisableCaching(w.ResponseWriter)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.Write(body)
+}
+
+// annotate adds lock info to the locked records of a {"data": [...]} payload
+func annotate(c *gin.Context, res resource.Resource, store *Store, body []byte) ([]byte, error) {
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+	items, ok := payload["data"].([]interface{})
+	if !ok {
+		return body, nil
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		if record, ok := item.(map[string]interface{}); ok {
+			ids[i] = recordID(record, res.GetIDFieldName())
+		}
+	}
+
+	locks, err := store.Active(c.Request.Context(), res.GetName(), ids)
+	if err != nil || len(locks) == 0 {
+		return body, err
+	}
+	for i, item := range items {
+		if lock, ok := locks[ids[i]]; ok && ids[i] != "" {
+			item.(map[string]interface{})[LockField] = LockInfo{Holder: lock.Holder, ExpiresAt: lock.ExpiresAt}
+		}
+	}
+	return json.Marshal(payload)
+}
+
+// recordID returns the ID of a serialized record
+func recordID(record map[string]interface{}, idField string) string {
+	for key, value := range record {
+		if strings.EqualFold(key, idField) && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package locking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLocked is returned when a record is locked by another holder
+	ErrLocked = errors.New("record is locked by another user")
+
+	// ErrLockNotHeld is returned when a holder extends or releases a lock it does not hold
+	ErrLockNotHeld = errors.New("lock is not held")
+)
+
+// Lock is an edit lock held on a record
+type Lock struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	Resource   string    `json:"resource" gorm:"size:191;not null;uniqueIndex:idx_refine_record_locks_record"`
+	RecordID   string    `json:"recordId" gorm:"size:191;not null;uniqueIndex:idx_refine_record_locks_record"`
+	Holder     string    `json:"holder" gorm:"size:191;not null"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt" gorm:"index"`
+}
+
+// TableName returns the table used to store locks
+func (Lock) TableName() string {
+	return "refine_record_locks"
+}
+
+// Store persists record locks. Expired locks are ignored and replaced on the next acquire.
+type Store struct {
+	DB  *gorm.DB
+	now func() time.Time
+}
+
+// NewStore creates a new lock store
+func NewStore(db *gorm.DB) *Store {
+	return &Store{DB: db, now: time.Now}
+}
+
+// AutoMigrate creates or updates the locks table
+func (s *Store) AutoMigrate() error {
+	return s.DB.AutoMigrate(&Lock{})
+}
+
+// Acquire locks a record for a holder or extends the holder's lock. When another holder
+// has an active lock, it is returned together with ErrLocked.
+func (s *Store) Acquire(ctx context.Context, resourceName string, recordID interface{}, holder string, ttl time.Duration) (*Lock, error) {
+	id := fmt.Sprintf("%v", recordID)
+	now := s.now()
+
+	var lock Lock
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("resource = ? AND record_id = ? AND expires_at <= ?", resourceName, id, now).
+			Delete(&Lock{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Lock{
+			Resource:   resourceName,
+			RecordID:   id,
+			Holder:     holder,
+			AcquiredAt: now,
+			ExpiresAt:  now.Add(ttl),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("resource = ? AND record_id = ?", resourceName, id).First(&lock).Error; err != nil {
+			return err
+		}
+		if lock.Holder != holder {
+			return ErrLocked
+		}
+
+		lock.ExpiresAt = now.Add(ttl)
+		return tx.Model(&lock).Update("expires_at", lock.ExpiresAt).Error
+	})
+	if errors.Is(err, ErrLocked) {
+		return &lock, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// Heartbeat extends an active lock held by holder
+func (s *Store) Heartbeat(ctx context.Context, resourceName string, recordID interface{}, holder string, ttl time.Duration) (*Lock, error) {
+	id := fmt.Sprintf("%v", recordID)
+	now := s.now()
+
+	result := s.DB.WithContext(ctx).Model(&Lock{}).
+		Where("resource = ? AND record_id = ? AND holder = ? AND expires_at > ?", resourceName, id, holder, now).
+		Update("expires_at", now.Add(ttl))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrLockNotHeld
+	}
+	return s.Get(ctx, resourceName, id)
+}
+
+// Release removes the lock held by holder
+func (s *Store) Release(ctx context.Context, resourceName string, recordID interface{}, holder string) error {
+	result := s.DB.WithContext(ctx).
+		Where("resource = ? AND record_id = ? AND holder = ?", resourceName, fmt.Sprintf("%v", recordID), holder).
+		Delete(&Lock{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Get returns the active lock of a record, or nil when the record is not locked
+func (s *Store) Get(ctx context.Context, resourceName string, recordID interface{}) (*Lock, error) {
+	var lock Lock
+	err := s.DB.WithContext(ctx).
+		Where("resource = ? AND record_id = ? AND expires_at > ?", resourceName, fmt.Sprintf("%v", recordID), s.now()).
+		First(&lock).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// Check returns the lock together with ErrLocked when another holder has locked the record
+func (s *Store) Check(ctx context.Context, resourceName string, recordID interface{}, holder string) (*Lock, error) {
+	lock, err := s.Get(ctx, resourceName, recordID)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil && lock.Holder != holder {
+		return lock, ErrLocked
+	}
+	return lock, nil
+}
+
+// Active returns the active locks of the given records keyed by record ID
+func (s *Store) Active(ctx context.Context, resourceName string, recordIDs []string) (map[string]Lock, error) {
+	locks := make(map[string]Lock)
+	if len(recordIDs) == 0 {
+		return locks, nil
+	}
+
+	var rows []Lock
+	err := s.DB.WithContext(ctx).
+		Where("resource = ? AND record_id IN ? AND expires_at > ?", resourceName, recordIDs, s.now()).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, lock := range rows {
+		locks[lock.RecordID] = lock
+	}
+	return locks, nil
+}
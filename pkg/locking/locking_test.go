@@ -0,0 +1,165 @@
+package locking
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Article struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+}
+
+func setupStore(t *testing.T) (*Store, *gorm.DB, *time.Time) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewStore(db)
+	require.NoError(t, store.AutoMigrate())
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, db, &now
+}
+
+func TestStore(t *testing.T) {
+	store, _, now := setupStore(t)
+	ctx := context.Background()
+
+	lock, err := store.Acquire(ctx, "articles", 1, "alice", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", lock.Holder)
+	assert.Equal(t, "1", lock.RecordID)
+
+	// Another holder is rejected and gets the current lock
+	lock, err = store.Acquire(ctx, "articles", "1", "bob", time.Minute)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Equal(t, "alice", lock.Holder)
+
+	_, err = store.Check(ctx, "articles", 1, "bob")
+	assert.ErrorIs(t, err, ErrLocked)
+	_, err = store.Check(ctx, "articles", 1, "alice")
+	assert.NoError(t, err)
+
+	// Heartbeats extend the lock of the holder only
+	*now = now.Add(50 * time.Second)
+	lock, err = store.Heartbeat(ctx, "articles", 1, "alice", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), lock.ExpiresAt.UTC())
+	_, err = store.Heartbeat(ctx, "articles", 1, "bob", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotHeld)
+
+	// Expired locks can be taken over
+	*now = now.Add(2 * time.Minute)
+	_, err = store.Heartbeat(ctx, "articles", 1, "alice", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotHeld)
+	lock, err = store.Acquire(ctx, "articles", 1, "bob", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", lock.Holder)
+
+	active, err := store.Active(ctx, "articles", []string{"1", "2"})
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+	assert.Equal(t, "bob", active["1"].Holder)
+
+	assert.ErrorIs(t, store.Release(ctx, "articles", 1, "alice"), ErrLockNotHeld)
+	require.NoError(t, store.Release(ctx, "articles", 1, "bob"))
+	lock, err = store.Get(ctx, "articles", 1)
+	require.NoError(t, err)
+	assert.Nil(t, lock)
+}
+
+func setupRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	store, db, _ := setupStore(t)
+	require.NoError(t, db.AutoMigrate(&Article{}))
+	require.NoError(t, db.Create(&[]Article{{Title: "First"}, {Title: "Second"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "articles",
+		Model:      Article{},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationUpdate, resource.OperationDelete},
+	})
+	config := Config{
+		Store:  store,
+		Holder: func(c *gin.Context) (string, error) { return c.GetHeader("X-User"), nil },
+	}
+
+	r := gin.New()
+	api := r.Group("", EnforceLocks(res, config))
+	handler.RegisterResource(api, res, repository.NewGenericRepository(db, res))
+	RegisterLockRoutes(api, res, config)
+	return r
+}
+
+func request(r *gin.Engine, method, url, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLockRoutes(t *testing.T) {
+	r := setupRouter(t)
+
+	w := request(r, http.MethodPost, "/articles/1/lock", "alice", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = request(r, http.MethodPost, "/articles/1/lock", "bob", "")
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.Contains(t, w.Body.String(), `"holder":"alice"`)
+
+	// Updates from non-holders are rejected
+	w = request(r, http.MethodPut, "/articles/1", "bob", `{"title":"Changed"}`)
+	assert.Equal(t, http.StatusLocked, w.Code)
+	w = request(r, http.MethodDelete, "/articles/1", "bob", "")
+	assert.Equal(t, http.StatusLocked, w.Code)
+
+	w = request(r, http.MethodPut, "/articles/1", "alice", `{"title":"Changed"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = request(r, http.MethodPut, "/articles/2", "bob", `{"title":"Unlocked"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Lists flag locked records
+	w = request(r, http.MethodGet, "/articles", "bob", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	for _, item := range response.Data {
+		if item["id"] == float64(1) {
+			assert.Equal(t, "alice", item[LockField].(map[string]interface{})["holder"])
+		} else {
+			assert.NotContains(t, item, LockField)
+		}
+	}
+
+	w = request(r, http.MethodPost, "/articles/1/heartbeat", "bob", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = request(r, http.MethodPost, "/articles/1/heartbeat", "alice", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request(r, http.MethodPost, "/articles/1/unlock", "alice", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request(r, http.MethodPut, "/articles/1", "bob", `{"title":"Mine now"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
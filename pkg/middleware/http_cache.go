@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// HTTPCacheConfig contains configuration for the HTTPCache middleware
+type HTTPCacheConfig struct {
+	// MaxAge is the time in seconds clients may reuse responses without revalidating
+	// them. 0 (the default) makes them revalidate every request, which costs a 304
+	// while the data is unchanged.
+	MaxAge int
+
+	// Public lets shared caches (proxies, CDNs) store responses. Responses are private
+	// by default, since they depend on the user.
+	Public bool
+
+	// VaryHeaders are the request headers responses depend on (default Accept,
+	// Accept-Encoding and Authorization)
+	VaryHeaders []string
+
+	// Version returns the version of the data of a request, e.g. the count and the
+	// latest updated_at of the records of a list. ETags are then computed from the URL
+	// and the version, and requests whose ETag matches are answered 304 Not Modified
+	// without running the handler. When Version is nil or fails, ETags are hashes of
+	// the response bodies, which saves the transfer but not the query.
+	Version func(c *gin.Context) (string, error)
+}
+
+// HTTPCache answers conditional GET requests: responses get an ETag and the
+// Cache-Control header of the config, and requests whose If-None-Match matches the
+// current ETag are answered 304 Not Modified with an empty body.
+func HTTPCache(config HTTPCacheConfig) gin.HandlerFunc {
+	if config.VaryHeaders == nil {
+		config.VaryHeaders = []string{"Accept", "Accept-Encoding", "Authorization"}
+	}
+	cacheControl := "private"
+	if config.Public {
+		cacheControl = "public"
+	}
+	if config.MaxAge > 0 {
+		cacheControl += fmt.Sprintf(", max-age=%d", config.MaxAge)
+	} else {
+		cacheControl += ", no-cache"
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		// Versioned data is validated before the handler runs
+		etag := ""
+		if config.Version != nil {
+			if version, err := config.Version(c); err == nil {
+				etag = utils.GenerateETag(c.Request.URL.Path + "?" + c.Request.URL.RawQuery + "|" + version)
+			}
+		}
+		if etag != "" && ETagMatches(c.GetHeader("If-None-Match"), etag) {
+			setHTTPCacheHeaders(c.Writer.Header(), etag, cacheControl, config.VaryHeaders)
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+
+		w := &httpCacheWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.status != http.StatusOK {
+			w.flush(w.status)
+			return
+		}
+		if etag == "" {
+			etag = utils.GenerateETag(w.body.String())
+		}
+		setHTTPCacheHeaders(w.Header(), etag, cacheControl, config.VaryHeaders)
+		if ETagMatches(c.GetHeader("If-None-Match"), etag) {
+			w.body.Reset()
+			w.Header().Del("Content-Length")
+			w.flush(http.StatusNotModified)
+			return
+		}
+		w.flush(http.StatusOK)
+	}
+}
+
+// ETagMatches reports whether an If-None-Match header matches an ETag. The header may
+// list several ETags and they are compared weakly, as RFC 9110 requires for GET.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setHTTPCacheHeaders sets the validators and caching policy of a response, replacing
+// those set by the handler
+func setHTTPCacheHeaders(header http.Header, etag, cacheControl string, varyHeaders []string) {
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", cacheControl)
+	header.Del("Expires")
+	header.Del("Pragma")
+	if len(varyHeaders) > 0 {
+		header.Set("Vary", strings.Join(varyHeaders, ", "))
+	}
+}
+
+// httpCacheWriter buffers the status and body of a response until its ETag is known
+type httpCacheWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+// WriteHeader records the status
+func (w *httpCacheWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+// WriteHeaderNow is deferred to flush
+func (w *httpCacheWriter) WriteHeaderNow() {}
+
+// Write buffers the body
+func (w *httpCacheWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// WriteString buffers the body
+func (w *httpCacheWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Status returns the recorded status
+func (w *httpCacheWriter) Status() int {
+	return w.status
+}
+
+// Size returns the size of the buffered body
+func (w *httpCacheWriter) Size() int {
+	return w.body.Len()
+}
+
+// Written reports whether a body was buffered
+func (w *httpCacheWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// flush writes the response with a status
+func (w *httpCacheWriter) flush(status int) {
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	w.ResponseWriter.WriteHeader(status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	} else {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
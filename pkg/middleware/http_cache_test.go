@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPCacheHashesBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := "v1"
+	r := gin.New()
+	r.GET("/items", HTTPCache(HTTPCacheConfig{MaxAge: 10, Public: true}), func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=60")
+		c.Header("ETag", `"query"`)
+		c.JSON(http.StatusOK, gin.H{"data": body})
+	})
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":"v1"}`, w.Body.String())
+	assert.Equal(t, "public, max-age=10", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEqual(t, `"query"`, etag)
+
+	w = get(`"other", W/` + etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	body = "v2"
+	w = get(etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":"v2"}`, w.Body.String())
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, ETagMatches(`"a"`, `"a"`))
+	assert.True(t, ETagMatches(`"b", W/"a"`, `"a"`))
+	assert.True(t, ETagMatches(`*`, `"a"`))
+	assert.False(t, ETagMatches(`"b"`, `"a"`))
+	assert.False(t, ETagMatches("", `"a"`))
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/auth"
+)
+
+// ClaimsContextKey is the key used to store the JWT claims in the context
+const ClaimsContextKey = "claims"
+
+// JWTAuth validates the bearer token of each request against config (HS256 with the
+// secret, or RS256 with the RSA keys; expiration, issuer and audience are checked) and
+// stores its jwt.MapClaims under ClaimsContextKey, where ExtractOwnerIDFromJWT and the
+// permission middleware read them. Refresh tokens issued by the auth routes are rejected.
+func JWTAuth(config auth.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := auth.BearerToken(c)
+		if err != nil {
+			apierror.Abort(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		claims, err := auth.ParseToken(config, tokenString)
+		if err != nil {
+			apierror.Abort(c, http.StatusUnauthorized, err)
+			return
+		}
+		if claims[auth.TokenTypeClaim] == auth.TokenTypeRefresh {
+			apierror.AbortMessage(c, http.StatusUnauthorized, "Refresh tokens cannot be used for authentication")
+			return
+		}
+
+		c.Set(ClaimsContextKey, claims)
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/auth"
+)
+
+func jwtAuthRouter(config auth.JWTConfig) *gin.Engine {
+	r := gin.New()
+	r.GET("/me", JWTAuth(config), OwnerContext(ExtractOwnerIDFromJWT("sub")), func(c *gin.Context) {
+		owner, _ := c.Get(OwnerContextKey)
+		c.JSON(http.StatusOK, gin.H{"data": owner})
+	})
+	return r
+}
+
+func jwtAuthRequest(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTAuthHS256(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := auth.DefaultJWTConfig()
+	config.Secret = "test-secret"
+	r := jwtAuthRouter(config)
+
+	token, err := auth.GenerateJWTWithStandardClaims(config, "user-1", nil)
+	require.NoError(t, err)
+	w := jwtAuthRequest(r, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":"user-1"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, "").Code)
+
+	other := config
+	other.Secret = "other-secret"
+	forged, err := auth.GenerateJWTWithStandardClaims(other, "user-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, forged).Code)
+
+	wrongAudience := config
+	wrongAudience.Audience = "another-api"
+	token, err = auth.GenerateJWTWithStandardClaims(wrongAudience, "user-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, token).Code)
+
+	expired, err := auth.GenerateJWT(config, jwt.MapClaims{
+		"sub": "user-1", "iss": config.Issuer, "aud": config.Audience,
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, expired).Code)
+
+	pair, err := auth.IssueTokens(config, &auth.User{ID: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, jwtAuthRequest(r, pair.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, pair.RefreshToken).Code)
+}
+
+func TestJWTAuthRS256(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	config := auth.DefaultJWTConfig()
+	config.SigningMethod = "RS256"
+	config.PrivateKey = key
+
+	token, err := auth.GenerateJWTWithStandardClaims(config, "user-2", nil)
+	require.NoError(t, err)
+
+	// Verifiers only need the public key
+	verifier := auth.DefaultJWTConfig()
+	verifier.SigningMethod = "RS256"
+	verifier.PublicKey = &key.PublicKey
+	r := jwtAuthRouter(verifier)
+	assert.JSONEq(t, `{"data":"user-2"}`, jwtAuthRequest(r, token).Body.String())
+
+	// HS256 tokens are rejected by an RS256 verifier
+	hs := auth.DefaultJWTConfig()
+	hsToken, err := auth.GenerateJWTWithStandardClaims(hs, "user-2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, jwtAuthRequest(r, hsToken).Code)
+}
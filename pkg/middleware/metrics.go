@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"gorm.io/gorm"
+)
+
+// Metric names exposed by a MetricsRegistry
+const (
+	MetricRequests        = "refine_http_requests_total"
+	MetricRequestDuration = "refine_http_request_duration_seconds"
+	MetricQueryDuration   = "refine_repository_query_duration_seconds"
+)
+
+// metricsContentType is the content type of the Prometheus text format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsStartKey is the statement instance key of the time a statement started
+const metricsStartKey = "metrics:start"
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the duration histograms,
+// those of the Prometheus clients
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultMetrics is the registry of Metrics, MetricsHandler and MetricsPlugin
+var DefaultMetrics = NewMetricsRegistry()
+
+// MetricsRegistry collects the metrics of requests and repository queries and exposes
+// them in the Prometheus text format, without a Prometheus client dependency:
+//
+//   - refine_http_requests_total counts requests by resource, operation, method and
+//     status
+//   - refine_http_request_duration_seconds is a histogram of their latency by
+//     resource, operation and method
+//   - refine_repository_query_duration_seconds is a histogram of the SQL statements
+//     run by repositories, by resource, operation and statement kind (query, create,
+//     update, delete, row, raw)
+//
+// Requests outside resources have empty resource and operation labels.
+type MetricsRegistry struct {
+	buckets []float64
+
+	mu               sync.Mutex
+	requests         map[string]uint64
+	requestDurations map[string]*histogram
+	queryDurations   map[string]*histogram
+}
+
+// NewMetricsRegistry creates a registry with the histogram buckets, in seconds
+// (DefaultDurationBuckets if none)
+func NewMetricsRegistry(buckets ...float64) *MetricsRegistry {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &MetricsRegistry{
+		buckets:          buckets,
+		requests:         make(map[string]uint64),
+		requestDurations: make(map[string]*histogram),
+		queryDurations:   make(map[string]*histogram),
+	}
+}
+
+// Metrics middleware records the count and latency of requests in DefaultMetrics.
+// Use it on the engine or a group above the resources, and expose the metrics with
+// MetricsHandler:
+//
+//	r.Use(middleware.Metrics())
+//	r.GET("/metrics", middleware.MetricsHandler())
+func Metrics() gin.HandlerFunc {
+	return DefaultMetrics.Middleware()
+}
+
+// MetricsHandler serves the metrics of DefaultMetrics in the Prometheus text format
+func MetricsHandler() gin.HandlerFunc {
+	return DefaultMetrics.Handler()
+}
+
+// MetricsPlugin returns a GORM plugin recording the duration of statements in
+// DefaultMetrics, installed with db.Use(middleware.MetricsPlugin())
+func MetricsPlugin() gorm.Plugin {
+	return DefaultMetrics.Plugin()
+}
+
+// Middleware records the count and latency of requests, labelled with the resource and
+// operation of generated handlers
+func (m *MetricsRegistry) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		elapsed := time.Since(started)
+
+		resourceName, operation := requestResource(c)
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests[formatLabels("resource", resourceName, "operation", operation, "method", c.Request.Method, "status", status)]++
+		m.observe(m.requestDurations, formatLabels("resource", resourceName, "operation", operation, "method", c.Request.Method), elapsed)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *MetricsRegistry) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var buf bytes.Buffer
+		m.write(&buf)
+		c.Data(http.StatusOK, metricsContentType, buf.Bytes())
+	}
+}
+
+// Plugin returns a GORM plugin recording the duration of statements. Statements of
+// requests are labelled with their resource and operation, read from the context
+// handlers pass to repositories.
+func (m *MetricsRegistry) Plugin() gorm.Plugin {
+	return metricsPlugin{registry: m}
+}
+
+// observe adds a duration to the histogram of a series. The caller holds the lock.
+func (m *MetricsRegistry) observe(series map[string]*histogram, labels string, elapsed time.Duration) {
+	h, ok := series[labels]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		series[labels] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// write writes the metrics in the Prometheus text format, with series sorted by labels
+func (m *MetricsRegistry) write(buf *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(buf, "# HELP %s Requests answered, by resource, operation, method and status.\n", MetricRequests)
+	fmt.Fprintf(buf, "# TYPE %s counter\n", MetricRequests)
+	for _, labels := range sortedKeys(m.requests) {
+		fmt.Fprintf(buf, "%s%s %d\n", MetricRequests, labels, m.requests[labels])
+	}
+	m.writeHistograms(buf, MetricRequestDuration, "Request latency in seconds, by resource, operation and method.", m.requestDurations)
+	m.writeHistograms(buf, MetricQueryDuration, "Duration of repository SQL statements in seconds, by resource, operation and statement.", m.queryDurations)
+}
+
+// writeHistograms writes the series of a histogram. The caller holds the lock.
+func (m *MetricsRegistry) writeHistograms(buf *bytes.Buffer, name, help string, series map[string]*histogram) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+	for _, labels := range sortedKeys(series) {
+		h := series[labels]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", strconv.FormatFloat(bound, 'g', -1, 64)), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), h.count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(buf, "%s_count%s %d\n", name, labels, h.count)
+	}
+}
+
+// histogram is a series of a histogram with cumulative bucket counts
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// metricsPlugin is the GORM plugin of a MetricsRegistry
+type metricsPlugin struct {
+	registry *MetricsRegistry
+}
+
+// Name returns the name of the plugin
+func (metricsPlugin) Name() string {
+	return "refine-gin:metrics"
+}
+
+// Initialize registers the callbacks timing statements
+func (p metricsPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", startQueryTimer),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", p.record("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", startQueryTimer),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", p.record("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", startQueryTimer),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", p.record("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", startQueryTimer),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", p.record("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", startQueryTimer),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", p.record("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", startQueryTimer),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", p.record("raw")),
+	)
+}
+
+// startQueryTimer records the time a statement starts
+func startQueryTimer(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// record returns a callback recording the duration of a statement of a kind
+func (p metricsPlugin) record(statement string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		started, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(started.(time.Time))
+		resourceName, operation := requestResource(db.Statement.Context)
+
+		p.registry.mu.Lock()
+		defer p.registry.mu.Unlock()
+		p.registry.observe(p.registry.queryDurations, formatLabels("resource", resourceName, "operation", operation, "statement", statement), elapsed)
+	}
+}
+
+// requestResource returns the names of the resource and operation of a request, empty
+// outside generated handlers
+func requestResource(ctx context.Context) (string, string) {
+	var resourceName, operation string
+	if res, ok := requestctx.Resource.Get(ctx); ok && res != nil {
+		resourceName = res.GetName()
+	}
+	if op, ok := requestctx.Operation.Get(ctx); ok {
+		operation = string(op)
+	}
+	return resourceName, operation
+}
+
+// formatLabels formats label name and value pairs as a Prometheus label set
+func formatLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, pairs[i]+`="`+escapeLabelValue(pairs[i+1])+`"`)
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// withLabel adds a label to a formatted label set
+func withLabel(labels, name, value string) string {
+	return strings.TrimSuffix(labels, "}") + "," + name + `="` + escapeLabelValue(value) + `"}`
+}
+
+// escapeLabelValue escapes a label value for the Prometheus text format
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type MeteredPost struct {
+	ID    uint `gorm:"primaryKey"`
+	Title string
+}
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&MeteredPost{}))
+
+	registry := NewMetricsRegistry(0.5, 0.1)
+	require.NoError(t, db.Use(registry.Plugin()))
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: MeteredPost{}})
+	r := gin.New()
+	r.Use(registry.Middleware())
+	r.GET("/metrics", registry.Handler())
+	posts := r.Group("/api/posts", requestctx.Middleware(res), func(c *gin.Context) {
+		requestctx.Operation.Set(c, resource.OperationList)
+	})
+	posts.GET("", func(c *gin.Context) {
+		var records []MeteredPost
+		if err := db.WithContext(c.Request.Context()).Find(&records).Error; err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, records)
+	})
+
+	for i := 0; i < 2; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/posts", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE refine_http_requests_total counter\n")
+	assert.Contains(t, body, `refine_http_requests_total{resource="posts",operation="list",method="GET",status="200"} 2`+"\n")
+	assert.Contains(t, body, `refine_http_requests_total{resource="",operation="",method="GET",status="404"} 1`+"\n")
+	assert.Contains(t, body, "# TYPE refine_http_request_duration_seconds histogram\n")
+	assert.Contains(t, body, `refine_http_request_duration_seconds_bucket{resource="posts",operation="list",method="GET",le="0.1"} `)
+	assert.Contains(t, body, `refine_http_request_duration_seconds_bucket{resource="posts",operation="list",method="GET",le="+Inf"} 2`+"\n")
+	assert.Contains(t, body, `refine_http_request_duration_seconds_count{resource="posts",operation="list",method="GET"} 2`+"\n")
+	assert.Contains(t, body, `refine_repository_query_duration_seconds_count{resource="posts",operation="list",statement="query"} 2`+"\n")
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := formatLabels("resource", `a"b\c`+"\n")
+	assert.Equal(t, `{resource="a\"b\\c\n"}`, labels)
+	assert.Equal(t, `{resource="a\"b\\c\n",le="+Inf"}`, withLabel(labels, "le", "+Inf"))
+}
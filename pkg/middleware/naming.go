@@ -5,26 +5,47 @@ import (
 	"encoding/json"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/naming"
 )
 
-// responseBodyWriter is a wrapper for gin.ResponseWriter
-type responseBodyWriter struct {
+// NamingConventionContextKey is the key of the naming convention of the request in
+// the gin context
+const NamingConventionContextKey = "namingConvention"
+
+// namingWriter buffers JSON responses so their keys can be converted
+type namingWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON bodies and passes everything else through
+func (w *namingWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
 }
 
-// Write writes the response body
-func (r responseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
-	return r.ResponseWriter.Write(b)
+// WriteString buffers JSON bodies and passes everything else through
+func (w *namingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
-// NamingConventionMiddleware converts JSON field names to the specified convention
+// NamingConventionMiddleware converts JSON field names to the specified convention.
+//
+// The middleware can be nested: globally on the API group, on route groups and per
+// resource (resource.Options.NamingConvention of RegisterResourceWithOptions). The
+// innermost convention wins, so a legacy resource can keep snake_case while the rest
+// of the API uses camelCase. Handlers read it with GetNamingConvention.
 func NamingConventionMiddleware(convention naming.NamingConvention) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		c.Set(NamingConventionContextKey, convention)
+
 		// Skip if not JSON request/response
 		if c.ContentType() != "application/json" && c.GetHeader("Accept") != "application/json" {
 			c.Next()
@@ -56,32 +77,41 @@ func NamingConventionMiddleware(convention naming.NamingConvention) gin.HandlerF
 			}
 		}
 
-		// Create a response body writer to capture the response
-		w := &responseBodyWriter{
-			ResponseWriter: c.Writer,
-			body:           &bytes.Buffer{},
-		}
+		// Capture JSON responses
+		w := &namingWriter{ResponseWriter: c.Writer}
 		c.Writer = w
 
 		// Continue processing the request
 		c.Next()
 
-		// Convert response body JSON keys
-		if w.Header().Get("Content-Type") == "application/json" {
-			responseBody := w.body.Bytes()
+		c.Writer = w.ResponseWriter
+		if !w.buffering {
+			return
+		}
+
+		// Convert response body JSON keys, unless a nested middleware already converted
+		// them to its own convention
+		responseBody := w.body.Bytes()
+		if current, _ := GetNamingConvention(c); current == convention {
 			var data map[string]interface{}
 			if err := json.Unmarshal(responseBody, &data); err == nil {
-				// Convert keys to desired convention
-				data = naming.ConvertKeys(data, convention)
-				// Marshal back to JSON
-				if newBody, err := json.Marshal(data); err == nil {
-					// Replace original response
-					w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(newBody)))
-					w.ResponseWriter.WriteHeader(w.Status())
-					w.ResponseWriter.Write(newBody)
-					return
+				if newBody, err := json.Marshal(naming.ConvertKeys(data, convention)); err == nil {
+					responseBody = newBody
 				}
 			}
 		}
+		w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(responseBody)))
+		w.ResponseWriter.Write(responseBody)
+	}
+}
+
+// GetNamingConvention returns the naming convention of the request, set by the
+// innermost NamingConventionMiddleware
+func GetNamingConvention(c *gin.Context) (naming.NamingConvention, bool) {
+	value, exists := c.Get(NamingConventionContextKey)
+	if !exists {
+		return "", false
 	}
+	convention, ok := value.(naming.NamingConvention)
+	return convention, ok
 }
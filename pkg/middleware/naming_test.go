@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/suranig/refine-gin/pkg/naming"
 )
 
@@ -202,6 +203,52 @@ func TestNamingConventionMiddleware(t *testing.T) {
 	}
 }
 
+func TestNamingConventionMiddleware_Nested(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// camelCase for the API, snake_case for a legacy group
+	r := gin.New()
+	api := r.Group("/api", NamingConventionMiddleware(naming.CamelCase))
+	echo := func(c *gin.Context) {
+		var data map[string]interface{}
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		convention, _ := GetNamingConvention(c)
+		data["convention"] = string(convention)
+		c.JSON(http.StatusOK, gin.H{"received": data, "last_page": 1})
+	}
+	api.POST("/users", echo)
+	api.Group("/legacy", NamingConventionMiddleware(naming.SnakeCase)).POST("/orders", echo)
+
+	request := func(path string) map[string]interface{} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"first_name":"John","orderTotal":10}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	body := request("/api/users")
+	assert.Contains(t, body, "lastPage")
+	received := body["received"].(map[string]interface{})
+	assert.Equal(t, "camelCase", received["convention"])
+	assert.Contains(t, received, "firstName")
+	assert.Contains(t, received, "orderTotal")
+
+	// The legacy group keeps snake_case in requests and responses
+	body = request("/api/legacy/orders")
+	assert.Contains(t, body, "last_page")
+	received = body["received"].(map[string]interface{})
+	assert.Equal(t, "snake_case", received["convention"])
+	assert.Contains(t, received, "first_name")
+	assert.Contains(t, received, "order_total")
+}
+
 func TestNamingConventionMiddleware_NonJSONRequest(t *testing.T) {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
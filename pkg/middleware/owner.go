@@ -8,9 +8,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 )
 
-// OwnerContextKey is the key used to store the owner ID in the context
+// OwnerContextKey is the key used to store the owner ID in the gin context
+// (see requestctx.OwnerID)
 const OwnerContextKey = "ownerID"
 
 // ErrOwnerIDNotFound is returned when the owner ID cannot be found
@@ -34,17 +37,15 @@ func OwnerContext(extractor ExtractOwnerIDFunc) gin.HandlerFunc {
 		ownerID, err := extractor(c)
 		if err != nil {
 			fmt.Printf("[DEBUG-MIDDLEWARE] Failed to extract owner ID: %v\n", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": err.Error(),
-			})
+			apierror.Abort(c, http.StatusUnauthorized, err)
 			return
 		}
 
 		// Log extracted owner ID
 		fmt.Printf("[DEBUG-MIDDLEWARE] Extracted owner ID: %v (type: %T)\n", ownerID, ownerID)
 
-		// Store owner ID in context
-		c.Set(OwnerContextKey, ownerID)
+		// Store owner ID in the gin and request contexts
+		requestctx.OwnerID.Set(c, ownerID)
 
 		// Verify the value was stored correctly
 		if storedID, exists := c.Get(OwnerContextKey); exists {
@@ -57,51 +58,77 @@ func OwnerContext(extractor ExtractOwnerIDFunc) gin.HandlerFunc {
 	}
 }
 
-// GetOwnerID extracts owner ID from the context
+// GetOwnerID extracts owner ID from a gin context or a context.Context
 func GetOwnerID(ctx context.Context) (interface{}, error) {
-	// Check if we have a gin context
-	gc, ok := ctx.(*gin.Context)
-	if ok {
-		// Extract from gin context
-		ownerID, exists := gc.Get(OwnerContextKey)
-		if exists {
-			// Debug info about the found owner ID
-			switch v := ownerID.(type) {
-			case string:
-				println("[DEBUG-MIDDLEWARE] Found owner ID in Gin context: '" + v + "', type: string")
-			default:
-				println("[DEBUG-MIDDLEWARE] Found owner ID in Gin context, type:", gc.GetString(OwnerContextKey+"_type"))
-			}
+	if ownerID, ok := requestctx.OwnerID.Get(ctx); ok && ownerID != nil {
+		return ownerID, nil
+	}
+
+	// Contexts built with context.WithValue(ctx, OwnerContextKey, id)
+	if ctx != nil {
+		if ownerID := ctx.Value(OwnerContextKey); ownerID != nil {
 			return ownerID, nil
 		}
-		println("[DEBUG-MIDDLEWARE] Owner ID not found in Gin context")
+	}
+	return nil, ErrOwnerIDNotFound
+}
 
-		// If we can't find the owner ID in the context, print all keys in the context
-		for k, v := range gc.Keys {
-			switch val := v.(type) {
-			case string:
-				println("[DEBUG-MIDDLEWARE] Gin context key:", k, "value:", val)
-			default:
-				println("[DEBUG-MIDDLEWARE] Gin context key:", k, "type:", gc.GetString(k+"_type"))
-			}
+// ResolveGroupIDsFunc returns the IDs of the groups (teams, organizations) an owner
+// belongs to, e.g. from a membership table
+type ResolveGroupIDsFunc func(c *gin.Context, ownerID interface{}) ([]interface{}, error)
+
+// OwnerGroups middleware resolves the groups of the owner stored by OwnerContext and
+// stores their IDs in the context (see requestctx.OwnerGroupIDs). Owner repositories of
+// resources with a group field (resource.OwnerConfig.GroupField) then give access to
+// the records of these groups. Requests without an owner are passed through.
+func OwnerGroups(resolver ResolveGroupIDsFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := GetOwnerID(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		groupIDs, err := resolver(c, ownerID)
+		if err != nil {
+			apierror.Abort(c, http.StatusInternalServerError, err)
+			return
 		}
-	} else {
-		println("[DEBUG-MIDDLEWARE] Not a Gin context")
+		requestctx.OwnerGroupIDs.Set(c, groupIDs)
+
+		c.Next()
 	}
+}
+
+// GetOwnerGroupIDs returns the group IDs of the owner stored by OwnerGroups, or nil
+func GetOwnerGroupIDs(ctx context.Context) []interface{} {
+	groupIDs, _ := requestctx.OwnerGroupIDs.Get(ctx)
+	return groupIDs
+}
+
+// ResolveGroupIDsFromJWT resolves the group IDs of the owner from an array claim of
+// the JWT claims
+func ResolveGroupIDsFromJWT(claimName string) ResolveGroupIDsFunc {
+	return func(c *gin.Context, ownerID interface{}) ([]interface{}, error) {
+		claimsValue, exists := c.Get("claims")
+		if !exists {
+			return nil, errors.New("JWT claims not found in context")
+		}
 
-	// Check if owner ID is set directly in the context
-	if ownerID := ctx.Value(OwnerContextKey); ownerID != nil {
-		switch v := ownerID.(type) {
-		case string:
-			println("[DEBUG-MIDDLEWARE] Found owner ID in regular context: '" + v + "', type: string")
+		claims, ok := claimsValue.(jwt.MapClaims)
+		if !ok {
+			return nil, errors.New("invalid JWT claims format")
+		}
+
+		switch groups := claims[claimName].(type) {
+		case nil:
+			return nil, nil
+		case []interface{}:
+			return groups, nil
 		default:
-			println("[DEBUG-MIDDLEWARE] Found owner ID in regular context, type not string")
+			return []interface{}{groups}, nil
 		}
-		return ownerID, nil
 	}
-
-	println("[DEBUG-MIDDLEWARE] Owner ID not found in any context")
-	return nil, ErrOwnerIDNotFound
 }
 
 // ExtractOwnerIDFromJWT extracts the owner ID from JWT claims
@@ -212,6 +212,34 @@ func TestExtractOwnerIDFromJWT(t *testing.T) {
 	})
 }
 
+func TestOwnerGroups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"sub": "user-1", "teams": []interface{}{"red", "blue"}})
+	})
+	router.GET("/anonymous", OwnerGroups(ResolveGroupIDsFromJWT("teams")), func(c *gin.Context) {
+		assert.Nil(t, GetOwnerGroupIDs(c))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/groups", OwnerContext(ExtractOwnerIDFromJWT("sub")), OwnerGroups(ResolveGroupIDsFromJWT("teams")), func(c *gin.Context) {
+		assert.Equal(t, []interface{}{"red", "blue"}, GetOwnerGroupIDs(c.Request.Context()))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/failing", OwnerContext(ExtractOwnerIDFromJWT("sub")), OwnerGroups(func(c *gin.Context, ownerID interface{}) ([]interface{}, error) {
+		return nil, errors.New("membership lookup failed")
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for path, status := range map[string]int{"/anonymous": http.StatusOK, "/groups": http.StatusOK, "/failing": http.StatusInternalServerError} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, status, w.Code, path)
+	}
+}
+
 func TestExtractOwnerIDFromHeader(t *testing.T) {
 	// Setup gin context
 	c, _ := gin.CreateTestContext(httptest.NewRecorder())
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+)
+
+// Default caps of QueryLimits
+const (
+	DefaultMaxQueryLength  = 8192
+	DefaultMaxQueryParams  = 100
+	DefaultMaxQueryFilters = 50
+)
+
+// QueryLimitsConfig caps the query string of requests. Zero values take the defaults
+// and negative values disable a cap.
+type QueryLimitsConfig struct {
+	// MaxLength caps the length of the raw query string in bytes (default 8192)
+	MaxLength int
+
+	// MaxParams caps the number of query parameters, repeated parameters counting once
+	// per value (default 100)
+	MaxParams int
+
+	// MaxFilters caps the number of filter clauses: filter[field][operator],
+	// filters[field] and refine's filters[i][field] parameters (default 50)
+	MaxFilters int
+}
+
+// DefaultQueryLimitsConfig returns the default query limits
+func DefaultQueryLimitsConfig() QueryLimitsConfig {
+	return QueryLimitsConfig{
+		MaxLength:  DefaultMaxQueryLength,
+		MaxParams:  DefaultMaxQueryParams,
+		MaxFilters: DefaultMaxQueryFilters,
+	}
+}
+
+// QueryLimits middleware rejects requests whose query string exceeds the caps before
+// the query parser runs, so clients generating thousands of filter parameters get a
+// clear error instead of slow, huge SQL queries. Query strings that are too long are
+// answered with 414, too many parameters or filters with 400; the error names the cap:
+//
+//	{"error": "too many filters: 120 (at most 50)", "message": "too many filters: 120 (at most 50)",
+//	 "statusCode": 400, "code": "bad_request", "limit": 50}
+//
+// Use it on the engine or a group above the resources, e.g.
+// r.Use(middleware.QueryLimits(middleware.DefaultQueryLimitsConfig())).
+func QueryLimits(config QueryLimitsConfig) gin.HandlerFunc {
+	defaults := DefaultQueryLimitsConfig()
+	if config.MaxLength == 0 {
+		config.MaxLength = defaults.MaxLength
+	}
+	if config.MaxParams == 0 {
+		config.MaxParams = defaults.MaxParams
+	}
+	if config.MaxFilters == 0 {
+		config.MaxFilters = defaults.MaxFilters
+	}
+
+	return func(c *gin.Context) {
+		rawQuery := c.Request.URL.RawQuery
+		if config.MaxLength > 0 && len(rawQuery) > config.MaxLength {
+			rejectQuery(c, http.StatusRequestURITooLong, "query string too long", len(rawQuery), config.MaxLength)
+			return
+		}
+
+		params, filters := countQueryParams(rawQuery)
+		if config.MaxParams > 0 && params > config.MaxParams {
+			rejectQuery(c, http.StatusBadRequest, "too many query parameters", params, config.MaxParams)
+			return
+		}
+		if config.MaxFilters > 0 && filters > config.MaxFilters {
+			rejectQuery(c, http.StatusBadRequest, "too many filters", filters, config.MaxFilters)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rejectQuery aborts a request exceeding a cap of QueryLimits
+func rejectQuery(c *gin.Context, status int, reason string, count, limit int) {
+	c.Abort()
+	apierror.WriteWith(c, apierror.New(status, fmt.Sprintf("%s: %d (at most %d)", reason, count, limit)), nil, gin.H{"limit": limit})
+}
+
+// countQueryParams counts the parameters and filter clauses of a raw query string.
+// Refine's filters[i][field]=...&filters[i][operator]=...&filters[i][value]=... is one
+// clause, counted by its field parameter.
+func countQueryParams(rawQuery string) (params, filters int) {
+	for rawQuery != "" {
+		var segment string
+		segment, rawQuery, _ = strings.Cut(rawQuery, "&")
+		if segment == "" {
+			continue
+		}
+		params++
+
+		key, _, _ := strings.Cut(segment, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		switch {
+		case strings.HasPrefix(key, "filter["):
+			filters++
+		case strings.HasPrefix(key, "filters["):
+			if !strings.Contains(key, "][") || strings.HasSuffix(key, "][field]") {
+				filters++
+			}
+		}
+	}
+	return params, filters
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(QueryLimits(QueryLimitsConfig{MaxLength: 200, MaxParams: 6, MaxFilters: 2}))
+	r.GET("/posts", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": []string{}})
+	})
+	get := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts?"+query, nil))
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	// Refine's filters count once per clause
+	code, _ := get("filters[0][field]=title&filters[0][operator]=eq&filters[0][value]=a&filter%5Bid%5D%5Bgt%5D=1&page=1")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, response := get("filter[id][gt]=1&filter[id][lt]=9&filters[status]=open")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "too many filters: 3 (at most 2)", response["error"])
+	assert.Equal(t, float64(2), response["limit"])
+	assert.Equal(t, "bad_request", response["code"])
+
+	code, response = get("a=1&b=2&c=3&d=4&e=5&f=6&f=7")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "too many query parameters: 7 (at most 6)", response["error"])
+
+	code, _ = get("q=" + strings.Repeat("x", 200))
+	assert.Equal(t, http.StatusRequestURITooLong, code)
+}
+
+func TestQueryLimitsDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(QueryLimits(QueryLimitsConfig{MaxParams: -1, MaxFilters: -1}))
+	r.GET("/posts", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts?"+strings.Repeat("filter[id][eq]=1&", 400), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The default length still applies
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts?q="+strings.Repeat("x", DefaultMaxQueryLength), nil))
+	assert.Equal(t, http.StatusRequestURITooLong, w.Code)
+}
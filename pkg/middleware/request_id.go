@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+// RequestIDHeader is the default header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDContextKey is the key used to store the request ID in the gin context
+// (see requestctx.RequestID)
+const RequestIDContextKey = "requestID"
+
+// maxRequestIDLength limits the length of incoming request IDs
+const maxRequestIDLength = 128
+
+// RequestIDConfig contains configuration for the request ID middleware
+type RequestIDConfig struct {
+	// Header carrying the request ID (default X-Request-ID)
+	Header string
+
+	// Generator creates new request IDs (default UUID v4)
+	Generator func() string
+
+	// IgnoreIncoming always generates a new ID instead of trusting the incoming header
+	IgnoreIncoming bool
+
+	// ErrorField is the field added to JSON error payloads (default "requestId", "-" disables)
+	ErrorField string
+}
+
+// DefaultRequestIDConfig returns default request ID configuration
+func DefaultRequestIDConfig() RequestIDConfig {
+	return RequestIDConfig{
+		Header:     RequestIDHeader,
+		Generator:  func() string { return uuid.NewString() },
+		ErrorField: "requestId",
+	}
+}
+
+// RequestID middleware propagates the incoming request ID or generates a new one. The ID is
+// stored in the gin context and the request context, echoed in the response header and added
+// to JSON error payloads.
+func RequestID(config RequestIDConfig) gin.HandlerFunc {
+	defaults := DefaultRequestIDConfig()
+	if config.Header == "" {
+		config.Header = defaults.Header
+	}
+	if config.Generator == nil {
+		config.Generator = defaults.Generator
+	}
+	if config.ErrorField == "" {
+		config.ErrorField = defaults.ErrorField
+	}
+
+	return func(c *gin.Context) {
+		id := ""
+		if !config.IgnoreIncoming {
+			id = c.GetHeader(config.Header)
+		}
+		if !validRequestID(id) {
+			id = config.Generator()
+		}
+
+		requestctx.RequestID.Set(c, id)
+		c.Writer.Header().Set(config.Header, id)
+
+		if config.ErrorField == "-" {
+			c.Next()
+			return
+		}
+
+		w := &errorPayloadWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		w.flush(config.ErrorField, id)
+	}
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestctx.RequestID.With(ctx, id)
+}
+
+// GetRequestID returns the request ID from a gin context or a context.Context,
+// or an empty string if none is set
+func GetRequestID(ctx context.Context) string {
+	id, _ := requestctx.RequestID.Get(ctx)
+	return id
+}
+
+// RequestIDLogFormatter is a gin log formatter that includes the request ID.
+// Use it with gin.LoggerWithFormatter.
+func RequestIDLogFormatter(param gin.LogFormatterParams) string {
+	requestID, _ := param.Keys[RequestIDContextKey].(string)
+	if requestID == "" {
+		requestID = "-"
+	}
+	return fmt.Sprintf("[GIN] %v | %s | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format(time.RFC3339),
+		requestID,
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+// validRequestID reports whether an incoming request ID is safe to propagate
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// errorPayloadWriter buffers JSON error responses so the request ID can be added to them
+type errorPayloadWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON error bodies and passes everything else through
+func (w *errorPayloadWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && w.isJSONError()) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString buffers JSON error bodies and passes everything else through
+func (w *errorPayloadWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// isJSONError reports whether the pending response is a JSON error
+func (w *errorPayloadWriter) isJSONError() bool {
+	return w.Status() >= http.StatusBadRequest &&
+		strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+// flush writes the buffered error body with the request ID added
+func (w *errorPayloadWriter) flush(field, id string) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if _, hasError := payload["error"]; hasError {
+			if _, exists := payload[field]; !exists {
+				payload[field] = id
+				if newBody, err := json.Marshal(payload); err == nil {
+					body = newBody
+				}
+			}
+		}
+	}
+
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRequestIDRouter(config RequestIDConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID(config))
+	r.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": GetRequestID(c.Request.Context())})
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+	})
+	r.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusInternalServerError, "boom")
+	})
+	return r
+}
+
+func TestRequestIDGeneratesID(t *testing.T) {
+	config := DefaultRequestIDConfig()
+	config.Generator = func() string { return "generated-id" }
+	r := setupRequestIDRouter(config)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "generated-id", w.Header().Get(RequestIDHeader))
+	assert.JSONEq(t, `{"data":"generated-id"}`, w.Body.String())
+}
+
+func TestRequestIDPropagatesIncomingID(t *testing.T) {
+	r := setupRequestIDRouter(RequestIDConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, "upstream-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "upstream-123", w.Header().Get(RequestIDHeader))
+
+	// Invalid incoming IDs are replaced
+	req = httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, strings.Repeat("x", maxRequestIDLength+1))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(RequestIDHeader), 36)
+}
+
+func TestRequestIDIgnoreIncoming(t *testing.T) {
+	r := setupRequestIDRouter(RequestIDConfig{
+		IgnoreIncoming: true,
+		Generator:      func() string { return "fresh" },
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, "spoofed")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "fresh", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDInErrorPayloads(t *testing.T) {
+	r := setupRequestIDRouter(RequestIDConfig{Generator: func() string { return "req-1" }})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Resource not found", body["error"])
+	assert.Equal(t, "req-1", body["requestId"])
+
+	// Non-JSON errors are left untouched
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/text", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "boom", w.Body.String())
+}
+
+func TestRequestIDErrorFieldDisabled(t *testing.T) {
+	r := setupRequestIDRouter(RequestIDConfig{ErrorField: "-"})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	assert.JSONEq(t, `{"error":"Resource not found"}`, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+}
+
+func TestGetRequestID(t *testing.T) {
+	assert.Equal(t, "", GetRequestID(context.Background()))
+	assert.Equal(t, "abc", GetRequestID(WithRequestID(context.Background(), "abc")))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "", GetRequestID(c))
+	c.Set(RequestIDContextKey, "from-gin")
+	assert.Equal(t, "from-gin", GetRequestID(c))
+}
+
+func TestRequestIDLogFormatter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	r := gin.New()
+	r.Use(RequestID(RequestIDConfig{Generator: func() string { return "log-id" }}))
+	r.Use(gin.LoggerWithConfig(gin.LoggerConfig{Formatter: RequestIDLogFormatter, Output: &out}))
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	assert.Contains(t, out.String(), "| log-id |")
+	assert.Contains(t, out.String(), "204")
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// StableJSON rewrites JSON responses with a deterministic key order: record fields
+// follow fieldOrder (usually the order of the resource metadata fields) and all other
+// keys, including keys of JSON fields, are sorted. See utils.MarshalStable.
+func StableJSON(fieldOrder []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &stableJSONWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.flush(fieldOrder)
+	}
+}
+
+// stableJSONWriter buffers JSON responses so their keys can be reordered
+type stableJSONWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON bodies and passes everything else through
+func (w *stableJSONWriter) Write(b []byte) (int, error) {
+	if w.buffering || (!w.ResponseWriter.Written() && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")) {
+		w.buffering = true
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString buffers JSON bodies and passes everything else through
+func (w *stableJSONWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flush writes the buffered body with reordered keys
+func (w *stableJSONWriter) flush(fieldOrder []string) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	if ordered, err := utils.ReorderJSON(body, fieldOrder); err == nil {
+		body = ordered
+	}
+
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.Write(body)
+}
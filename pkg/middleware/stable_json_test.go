@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStableJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(StableJSON([]string{"id", "title", "body"}))
+	r.GET("/posts/1", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": struct {
+			Body  string `json:"body"`
+			Title string `json:"title"`
+			ID    int    `json:"id"`
+		}{"text", "Hello", 1}})
+	})
+	r.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusOK, "plain")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":{"id":1,"title":"Hello","body":"text"}}`, w.Body.String())
+	assert.Equal(t, "47", w.Header().Get("Content-Length"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/text", nil))
+	assert.Equal(t, "plain", w.Body.String())
+}
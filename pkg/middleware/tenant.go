@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+// ErrTenantIDNotFound is returned when the tenant ID cannot be found
+var ErrTenantIDNotFound = errors.New("tenant ID not found")
+
+// ExtractTenantIDFunc is a function that extracts a tenant ID from a gin context
+type ExtractTenantIDFunc func(c *gin.Context) (string, error)
+
+// TenantContext middleware extracts the tenant of each request and stores it in the
+// context (see requestctx.TenantID), where repository.TenantRepository reads it.
+// Requests without a tenant are rejected with a 400.
+func TenantContext(extractor ExtractTenantIDFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := extractor(c)
+		if err != nil {
+			apierror.Abort(c, http.StatusBadRequest, err)
+			return
+		}
+		requestctx.TenantID.Set(c, tenantID)
+		c.Next()
+	}
+}
+
+// GetTenantID extracts the tenant ID from a gin context or a context.Context
+func GetTenantID(ctx context.Context) (string, error) {
+	if tenantID, ok := requestctx.TenantID.Get(ctx); ok && tenantID != "" {
+		return tenantID, nil
+	}
+	return "", ErrTenantIDNotFound
+}
+
+// ExtractTenantIDFromHeader extracts the tenant ID from an HTTP header
+func ExtractTenantIDFromHeader(headerName string) ExtractTenantIDFunc {
+	return func(c *gin.Context) (string, error) {
+		tenantID := strings.TrimSpace(c.GetHeader(headerName))
+		if tenantID == "" {
+			return "", errors.New("tenant ID header is empty")
+		}
+		return tenantID, nil
+	}
+}
+
+// ExtractTenantIDFromSubdomain extracts the tenant ID from the subdomain of the host
+// under baseDomain, e.g. "acme" for acme.example.com with baseDomain "example.com"
+func ExtractTenantIDFromSubdomain(baseDomain string) ExtractTenantIDFunc {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c *gin.Context) (string, error) {
+		host := strings.ToLower(c.Request.Host)
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return "", fmt.Errorf("host %q is not a subdomain of %s", host, strings.TrimPrefix(suffix, "."))
+		}
+		subdomain := strings.TrimSuffix(host, suffix)
+		if subdomain == "" || strings.Contains(subdomain, ".") {
+			return "", fmt.Errorf("host %q does not name a tenant", host)
+		}
+		return subdomain, nil
+	}
+}
+
+// ExtractTenantIDFromJWT extracts the tenant ID from a claim of the JWT claims stored
+// by JWTAuth
+func ExtractTenantIDFromJWT(claimName string) ExtractTenantIDFunc {
+	return func(c *gin.Context) (string, error) {
+		claimsValue, exists := c.Get(ClaimsContextKey)
+		if !exists {
+			return "", errors.New("JWT claims not found in context")
+		}
+
+		claims, ok := claimsValue.(jwt.MapClaims)
+		if !ok {
+			return "", errors.New("invalid JWT claims format")
+		}
+
+		tenantID, exists := claims[claimName]
+		if !exists || tenantID == nil || fmt.Sprint(tenantID) == "" {
+			return "", errors.New("tenant ID claim not found in JWT")
+		}
+		return fmt.Sprint(tenantID), nil
+	}
+}
+
+// CombineTenantExtractors chains multiple extractors and returns the first successful
+// result
+func CombineTenantExtractors(extractors ...ExtractTenantIDFunc) ExtractTenantIDFunc {
+	return func(c *gin.Context) (string, error) {
+		lastErr := ErrTenantIDNotFound
+		for _, extractor := range extractors {
+			tenantID, err := extractor(c)
+			if err == nil {
+				return tenantID, nil
+			}
+			lastErr = err
+		}
+		return "", lastErr
+	}
+}
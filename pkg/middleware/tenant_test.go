@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+func TestTenantContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if org := c.GetHeader("X-Claim-Org"); org != "" {
+			c.Set(ClaimsContextKey, jwt.MapClaims{"org": org})
+		}
+	})
+	router.Use(TenantContext(CombineTenantExtractors(
+		ExtractTenantIDFromJWT("org"),
+		ExtractTenantIDFromHeader("X-Tenant-ID"),
+		ExtractTenantIDFromSubdomain("example.com"),
+	)))
+	router.GET("/", func(c *gin.Context) {
+		tenantID, err := GetTenantID(c.Request.Context())
+		require.NoError(t, err)
+		c.String(http.StatusOK, tenantID)
+	})
+
+	request := func(host string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("api.local", map[string]string{"X-Claim-Org": "acme", "X-Tenant-ID": "globex"})
+	assert.Equal(t, "acme", w.Body.String())
+
+	w = request("api.local", map[string]string{"X-Tenant-ID": "globex"})
+	assert.Equal(t, "globex", w.Body.String())
+
+	w = request("Initech.Example.com:8080", nil)
+	assert.Equal(t, "initech", w.Body.String())
+
+	for _, host := range []string{"example.com", "a.b.example.com", "example.org"} {
+		w = request(host, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, host)
+	}
+}
+
+func TestGetTenantID(t *testing.T) {
+	_, err := GetTenantID(context.Background())
+	assert.ErrorIs(t, err, ErrTenantIDNotFound)
+
+	tenantID, err := GetTenantID(requestctx.TenantID.With(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", tenantID)
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+// Span attributes set by Tracing, following the OpenTelemetry HTTP conventions where
+// they exist
+const (
+	SpanAttrMethod    = "http.request.method"
+	SpanAttrRoute     = "http.route"
+	SpanAttrStatus    = "http.response.status_code"
+	SpanAttrResource  = "refine.resource"
+	SpanAttrOperation = "refine.operation"
+	SpanAttrOwner     = "refine.owner"
+)
+
+// Tracer starts the spans of requests. It has the shape of an OpenTelemetry tracer,
+// which is adapted in a few lines, so refine-gin doesn't depend on a tracing SDK:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, middleware.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start starts a span, returning a context carrying it
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	// SetAttribute sets an attribute of the span; values are strings or ints
+	SetAttribute(key string, value interface{})
+
+	// RecordError records an error of the request and marks the span as failed
+	RecordError(err error)
+
+	// End ends the span
+	End()
+}
+
+// TracerFunc adapts a function to a Tracer
+type TracerFunc func(ctx context.Context, name string) (context.Context, Span)
+
+// Start calls f
+func (f TracerFunc) Start(ctx context.Context, name string) (context.Context, Span) {
+	return f(ctx, name)
+}
+
+// Tracing middleware wraps each request in a span named after its method and route
+// (e.g. "GET /api/posts/:id"). The request context carries the span, so spans started
+// by repositories and the database are its children. Once the request is answered,
+// the span gets the status and the resource, operation and owner of generated
+// handlers; 5xx responses and gin errors are recorded as errors.
+//
+// Use it on the engine or a group above the resources, e.g.
+// r.Use(middleware.Tracing(tracer)).
+func Tracing(tracer Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracer.Start(c.Request.Context(), c.Request.Method+" "+route)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		span.SetAttribute(SpanAttrMethod, c.Request.Method)
+		span.SetAttribute(SpanAttrRoute, route)
+		span.SetAttribute(SpanAttrStatus, c.Writer.Status())
+		if res, ok := requestctx.Resource.Get(c); ok {
+			span.SetAttribute(SpanAttrResource, res.GetName())
+		}
+		if op, ok := requestctx.Operation.Get(c); ok {
+			span.SetAttribute(SpanAttrOperation, string(op))
+		}
+		if owner, ok := requestctx.OwnerID.Get(c); ok && owner != nil {
+			span.SetAttribute(SpanAttrOwner, fmt.Sprint(owner))
+		}
+
+		if err := c.Errors.Last(); err != nil {
+			span.RecordError(err.Err)
+		} else if c.Writer.Status() >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", c.Writer.Status(), http.StatusText(c.Writer.Status())))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type recordedSpan struct {
+	name       string
+	attributes map[string]interface{}
+	errors     []error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.errors = append(s.errors, err) }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+type spanKey struct{}
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var spans []*recordedSpan
+	tracer := TracerFunc(func(ctx context.Context, name string) (context.Context, Span) {
+		span := &recordedSpan{name: name, attributes: make(map[string]interface{})}
+		spans = append(spans, span)
+		return context.WithValue(ctx, spanKey{}, span), span
+	})
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: struct{ ID uint }{}})
+	r := gin.New()
+	r.Use(Tracing(tracer))
+	posts := r.Group("/api/posts", requestctx.Middleware(res), func(c *gin.Context) {
+		requestctx.Operation.Set(c, resource.OperationRead)
+		requestctx.OwnerID.Set(c, 7)
+	})
+	posts.GET("/:id", func(c *gin.Context) {
+		// Repositories receive the span with the request context
+		assert.NotNil(t, c.Request.Context().Value(spanKey{}))
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/posts/1", nil))
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /api/posts/:id", span.name)
+	assert.True(t, span.ended)
+	assert.Empty(t, span.errors)
+	assert.Equal(t, map[string]interface{}{
+		SpanAttrMethod:    "GET",
+		SpanAttrRoute:     "/api/posts/:id",
+		SpanAttrStatus:    http.StatusOK,
+		SpanAttrResource:  "posts",
+		SpanAttrOperation: "read",
+		SpanAttrOwner:     "7",
+	}, span.attributes)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	require.Len(t, spans, 2)
+	assert.Len(t, spans[1].errors, 1)
+	assert.NotContains(t, spans[1].attributes, SpanAttrResource)
+}
@@ -0,0 +1,484 @@
+// Package mirror maintains read-optimized, denormalized copies of resources: flat
+// tables composed of fields of a resource and of its relations, kept up to date by
+// lifecycle hooks and served as read-only resources.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"github.com/suranig/refine-gin/pkg/worker"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Mirror errors
+var (
+	ErrInvalidSource = errors.New("invalid mirror source")
+	ErrReadOnly      = errors.New("mirror resources are read-only")
+)
+
+// DefaultBatchSize is the number of records refreshed per query by Rebuild
+const DefaultBatchSize = 500
+
+// Field maps a field of the mirror model to a value of the source resource
+type Field struct {
+	// Name of the field of the mirror model (Go name)
+	Name string
+
+	// Source of the value: a field of the source model ("Title") or a field of a
+	// belongs-to relation of the source model ("Author.Name")
+	Source string
+}
+
+// Config contains configuration for a mirror
+type Config struct {
+	// Name of the read-only resource serving the mirror (default "<source>_mirror")
+	Name string
+
+	// Source resource the mirror is built from
+	Source resource.Resource
+
+	// Model of the mirror table. It needs the ID field of the source model and the
+	// fields listed in Fields.
+	Model interface{}
+
+	// Fields copied to the mirror
+	Fields []Field
+
+	// Registry the related resources are taken from by Attach (the global registry
+	// if nil)
+	Registry *resource.ResourceRegistry
+
+	// Pool refreshes the mirror off the request path (optional, refreshes run in the
+	// hooks without it)
+	Pool *worker.Pool
+
+	// BatchSize is the number of records refreshed per query by Rebuild
+	// (DefaultBatchSize if zero)
+	BatchSize int
+
+	// OnError is called when a refresh submitted to Pool cannot be queued. By default
+	// the error is logged.
+	OnError func(err error)
+}
+
+// dependency is a belongs-to relation of the source model the mirror copies fields of
+type dependency struct {
+	relation   string
+	foreignKey string
+	model      reflect.Type
+}
+
+// Mirror maintains the mirror table of a resource
+type Mirror struct {
+	db     *gorm.DB
+	config Config
+
+	source       *schema.Schema
+	mirror       *schema.Schema
+	dependencies []dependency
+
+	resource   resource.Resource
+	repository repository.Repository
+}
+
+// New creates a mirror, checking the fields against the source and mirror models.
+// The mirror table is not created: migrate Config.Model like other models.
+func New(db *gorm.DB, config Config) (*Mirror, error) {
+	if config.Source == nil || config.Model == nil {
+		return nil, fmt.Errorf("%w: source and model are required", ErrInvalidSource)
+	}
+	if config.Name == "" {
+		config.Name = config.Source.GetName() + "_mirror"
+	}
+	if config.Registry == nil {
+		config.Registry = resource.GlobalResourceRegistry
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			log.Printf("mirror %s: %v", config.Name, err)
+		}
+	}
+
+	m := &Mirror{db: db, config: config}
+	var err error
+	if m.source, err = parseModel(db, config.Source.GetModel()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	if m.source.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%w: %s has no primary key", ErrInvalidSource, m.source.Name)
+	}
+	if m.mirror, err = parseModel(db, config.Model); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSource, err)
+	}
+	if m.mirror.LookUpField(m.source.PrioritizedPrimaryField.Name) == nil {
+		return nil, fmt.Errorf("%w: mirror model has no %s field", ErrInvalidSource, m.source.PrioritizedPrimaryField.Name)
+	}
+	if err := m.resolveFields(); err != nil {
+		return nil, err
+	}
+
+	m.resource = resource.NewResource(resource.ResourceConfig{
+		Name:  config.Name,
+		Model: config.Model,
+		Operations: []resource.Operation{
+			resource.OperationList,
+			resource.OperationRead,
+			resource.OperationCount,
+		},
+	})
+	m.repository = &readOnlyRepository{Repository: repository.NewGenericRepositoryWithResource(db, m.resource)}
+	return m, nil
+}
+
+// parseModel returns the GORM schema of a model
+func parseModel(db *gorm.DB, model interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// resolveFields checks the fields of the mirror and collects the relations they copy
+// fields of
+func (m *Mirror) resolveFields() error {
+	seen := make(map[string]bool)
+	for _, field := range m.config.Fields {
+		if m.mirror.LookUpField(field.Name) == nil {
+			return fmt.Errorf("%w: mirror model has no %s field", ErrInvalidSource, field.Name)
+		}
+
+		path := strings.Split(field.Source, ".")
+		switch len(path) {
+		case 1:
+			if m.source.LookUpField(path[0]) == nil {
+				return fmt.Errorf("%w: %s has no %s field", ErrInvalidSource, m.source.Name, path[0])
+			}
+		case 2:
+			relation, ok := m.source.Relationships.Relations[path[0]]
+			if !ok || relation.Type != schema.BelongsTo {
+				return fmt.Errorf("%w: %s has no belongs-to relation %s", ErrInvalidSource, m.source.Name, path[0])
+			}
+			if relation.FieldSchema.LookUpField(path[1]) == nil {
+				return fmt.Errorf("%w: %s has no %s field", ErrInvalidSource, relation.FieldSchema.Name, path[1])
+			}
+			if !seen[relation.Name] {
+				seen[relation.Name] = true
+				m.dependencies = append(m.dependencies, dependency{
+					relation:   relation.Name,
+					foreignKey: relation.References[0].ForeignKey.DBName,
+					model:      relation.FieldSchema.ModelType,
+				})
+			}
+		default:
+			return fmt.Errorf("%w: unsupported source %q", ErrInvalidSource, field.Source)
+		}
+	}
+	return nil
+}
+
+// Resource returns the read-only resource serving the mirror
+func (m *Mirror) Resource() resource.Resource {
+	return m.resource
+}
+
+// Repository returns the read-only repository of the mirror
+func (m *Mirror) Repository() repository.Repository {
+	return m.repository
+}
+
+// Refresh rebuilds the mirror rows of source records, removing the rows of records
+// that no longer exist
+func (m *Mirror) Refresh(ctx context.Context, ids ...interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	primaryKey := m.source.PrioritizedPrimaryField
+
+	records := reflect.New(reflect.SliceOf(m.source.ModelType))
+	query := m.db.WithContext(ctx).Model(m.config.Source.GetModel())
+	for _, dep := range m.dependencies {
+		query = query.Preload(dep.relation)
+	}
+	if err := query.Where(clause.IN{Column: clause.Column{Name: primaryKey.DBName}, Values: ids}).Find(records.Interface()).Error; err != nil {
+		return err
+	}
+
+	records = records.Elem()
+	rows := reflect.New(reflect.SliceOf(m.mirror.ModelType)).Elem()
+	rows.Set(reflect.MakeSlice(rows.Type(), records.Len(), records.Len()))
+	found := make(map[string]bool, records.Len())
+	for i := 0; i < records.Len(); i++ {
+		record := records.Index(i)
+		id := record.FieldByIndex(primaryKey.StructField.Index)
+		found[fmt.Sprint(id.Interface())] = true
+		m.fill(rows.Index(i), record, id)
+	}
+
+	var missing []interface{}
+	for _, id := range ids {
+		if !found[fmt.Sprint(id)] {
+			missing = append(missing, id)
+		}
+	}
+
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if rows.Len() > 0 {
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(rows.Addr().Interface()).Error; err != nil {
+				return err
+			}
+		}
+		if len(missing) > 0 {
+			mirrorKey := m.mirror.LookUpField(primaryKey.Name).DBName
+			return tx.Where(clause.IN{Column: clause.Column{Name: mirrorKey}, Values: missing}).Delete(m.config.Model).Error
+		}
+		return nil
+	})
+}
+
+// Rebuild refreshes the whole mirror in batches and removes the rows of records that
+// no longer exist. It has the signature of a worker.Task, so rebuilds can be
+// submitted to a pool or scheduled.
+func (m *Mirror) Rebuild(ctx context.Context) error {
+	primaryKey := m.source.PrioritizedPrimaryField
+	for offset := 0; ; offset += m.config.BatchSize {
+		var ids []interface{}
+		err := m.db.WithContext(ctx).Model(m.config.Source.GetModel()).
+			Order(primaryKey.DBName).Offset(offset).Limit(m.config.BatchSize).
+			Pluck(primaryKey.DBName, &ids).Error
+		if err != nil {
+			return err
+		}
+		if err := m.Refresh(ctx, ids...); err != nil {
+			return err
+		}
+		if len(ids) < m.config.BatchSize {
+			break
+		}
+	}
+
+	mirrorKey := m.mirror.LookUpField(primaryKey.Name).DBName
+	existing := m.db.Model(m.config.Source.GetModel()).Select(primaryKey.DBName)
+	return m.db.WithContext(ctx).Where(clause.Expr{SQL: "? NOT IN (?)", Vars: []interface{}{clause.Column{Name: mirrorKey}, existing}}).
+		Delete(m.config.Model).Error
+}
+
+// RefreshRelated refreshes the mirror rows of source records referencing related
+// records, e.g. all posts of an author whose name changed
+func (m *Mirror) RefreshRelated(ctx context.Context, relation string, ids ...interface{}) error {
+	for _, dep := range m.dependencies {
+		if dep.relation != relation || len(ids) == 0 {
+			continue
+		}
+		var sourceIDs []interface{}
+		err := m.db.WithContext(ctx).Model(m.config.Source.GetModel()).
+			Where(clause.IN{Column: clause.Column{Name: dep.foreignKey}, Values: ids}).
+			Pluck(m.source.PrioritizedPrimaryField.DBName, &sourceIDs).Error
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(sourceIDs); start += m.config.BatchSize {
+			end := start + m.config.BatchSize
+			if end > len(sourceIDs) {
+				end = len(sourceIDs)
+			}
+			if err := m.Refresh(ctx, sourceIDs[start:end]...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fill copies the ID and the fields of a source record to a mirror row
+func (m *Mirror) fill(row, record, id reflect.Value) {
+	assign(row.FieldByIndex(m.mirror.LookUpField(m.source.PrioritizedPrimaryField.Name).StructField.Index), id)
+	for _, field := range m.config.Fields {
+		value := record
+		for _, name := range strings.Split(field.Source, ".") {
+			value = reflect.Indirect(value)
+			if !value.IsValid() {
+				break
+			}
+			value = value.FieldByName(m.fieldName(value.Type(), name))
+		}
+		assign(row.FieldByIndex(m.mirror.LookUpField(field.Name).StructField.Index), value)
+	}
+}
+
+// fieldName returns the Go name of a field of a source or related model
+func (m *Mirror) fieldName(t reflect.Type, name string) string {
+	if t == m.source.ModelType {
+		if field := m.source.LookUpField(name); field != nil {
+			return field.Name
+		}
+		return name
+	}
+	for _, dep := range m.dependencies {
+		if dep.model == t {
+			if field := m.source.Relationships.Relations[dep.relation].FieldSchema.LookUpField(name); field != nil {
+				return field.Name
+			}
+		}
+	}
+	return name
+}
+
+// assign sets dst to src, dereferencing, allocating and converting pointers as
+// needed. Invalid or nil sources leave dst at its zero value.
+func assign(dst, src reflect.Value) {
+	for src.IsValid() && src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			return
+		}
+		src = src.Elem()
+	}
+	if !src.IsValid() {
+		return
+	}
+	if dst.Kind() == reflect.Ptr {
+		if !src.Type().ConvertibleTo(dst.Type().Elem()) {
+			return
+		}
+		value := reflect.New(dst.Type().Elem())
+		value.Elem().Set(src.Convert(dst.Type().Elem()))
+		dst.Set(value)
+		return
+	}
+	if src.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(src.Convert(dst.Type()))
+	}
+}
+
+// run refreshes the mirror in the hook, or on the pool if configured. Errors of
+// refreshes on the pool are handled by the pool.
+func (m *Mirror) run(ctx context.Context, refresh func(ctx context.Context) error) error {
+	if m.config.Pool == nil {
+		return refresh(ctx)
+	}
+	if err := m.config.Pool.Submit("mirror:"+m.config.Name, refresh); err != nil {
+		m.config.OnError(err)
+	}
+	return nil
+}
+
+// recordIDs returns the IDs of a hook: a single ID or the IDs of a bulk operation
+func recordIDs(id interface{}) []interface{} {
+	value := reflect.ValueOf(id)
+	if value.Kind() != reflect.Slice {
+		return []interface{}{id}
+	}
+	ids := make([]interface{}, value.Len())
+	for i := range ids {
+		ids[i] = value.Index(i).Interface()
+	}
+	return ids
+}
+
+// Attach adds the hooks maintaining the mirror to the lifecycle hooks of the source
+// resource and of the resources of the mirrored relations, taken from the registry
+// by model. Hooks already set run first.
+func (m *Mirror) Attach() error {
+	source, err := lifecycleHooks(m.config.Source)
+	if err != nil {
+		return err
+	}
+	idField := m.config.Source.GetIDFieldName()
+
+	source.AfterCreate = chain(source.AfterCreate, func(ctx context.Context, _ resource.Resource, data interface{}) error {
+		id, err := utils.GetFieldValue(data, idField)
+		if err != nil {
+			return err
+		}
+		return m.run(ctx, func(ctx context.Context) error { return m.Refresh(ctx, id) })
+	})
+	source.AfterUpdate = chain(source.AfterUpdate, func(ctx context.Context, _ resource.Resource, _ interface{}) error {
+		id, ok := resource.RecordIDFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		return m.run(ctx, func(ctx context.Context) error { return m.Refresh(ctx, recordIDs(id)...) })
+	})
+	source.AfterDelete = chain(source.AfterDelete, func(ctx context.Context, _ resource.Resource, id interface{}) error {
+		return m.run(ctx, func(ctx context.Context) error { return m.Refresh(ctx, recordIDs(id)...) })
+	})
+
+	for _, dep := range m.dependencies {
+		related := m.relatedResource(dep.model)
+		if related == nil {
+			return fmt.Errorf("%w: no resource registered for relation %s", ErrInvalidSource, dep.relation)
+		}
+		hooks, err := lifecycleHooks(related)
+		if err != nil {
+			return err
+		}
+		relation := dep.relation
+		refreshRelated := func(ctx context.Context, ids []interface{}) error {
+			return m.run(ctx, func(ctx context.Context) error { return m.RefreshRelated(ctx, relation, ids...) })
+		}
+		hooks.AfterUpdate = chain(hooks.AfterUpdate, func(ctx context.Context, _ resource.Resource, _ interface{}) error {
+			id, ok := resource.RecordIDFromContext(ctx)
+			if !ok {
+				return nil
+			}
+			return refreshRelated(ctx, recordIDs(id))
+		})
+		hooks.AfterDelete = chain(hooks.AfterDelete, func(ctx context.Context, _ resource.Resource, id interface{}) error {
+			return refreshRelated(ctx, recordIDs(id))
+		})
+	}
+	return nil
+}
+
+// relatedResource returns the registered resource of a related model
+func (m *Mirror) relatedResource(model reflect.Type) resource.Resource {
+	for _, res := range m.config.Registry.GetAll() {
+		t := reflect.TypeOf(res.GetModel())
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == model {
+			return res
+		}
+	}
+	return nil
+}
+
+// lifecycleHooks returns the lifecycle hooks of a resource, creating them for
+// resources built with resource.NewResource
+func lifecycleHooks(res resource.Resource) (*resource.LifecycleHooks, error) {
+	if hooked, ok := res.(resource.LifecycleHookResource); ok && hooked.GetHooks() != nil {
+		return hooked.GetHooks(), nil
+	}
+	if defaultResource, ok := res.(*resource.DefaultResource); ok {
+		defaultResource.Hooks = &resource.LifecycleHooks{}
+		return defaultResource.Hooks, nil
+	}
+	return nil, fmt.Errorf("%w: %s does not support lifecycle hooks", ErrInvalidSource, res.GetName())
+}
+
+// chain returns a hook running first and then next
+func chain(first, next resource.LifecycleHook) resource.LifecycleHook {
+	if first == nil {
+		return next
+	}
+	return func(ctx context.Context, res resource.Resource, data interface{}) error {
+		if err := first(ctx, res, data); err != nil {
+			return err
+		}
+		return next(ctx, res, data)
+	}
+}
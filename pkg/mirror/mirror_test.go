@@ -0,0 +1,165 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type MirrorAuthor struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type MirrorPost struct {
+	ID       uint          `json:"id" gorm:"primaryKey"`
+	Title    string        `json:"title"`
+	AuthorID *uint         `json:"authorId"`
+	Author   *MirrorAuthor `json:"author,omitempty"`
+}
+
+type MirrorPostView struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Title      string `json:"title"`
+	AuthorName string `json:"authorName"`
+}
+
+func setupMirror(t *testing.T) (*gin.Engine, *gorm.DB, *Mirror) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&MirrorAuthor{}, &MirrorPost{}, &MirrorPostView{}))
+
+	operations := []resource.Operation{
+		resource.OperationList, resource.OperationCreate, resource.OperationUpdate, resource.OperationDelete,
+	}
+	registry := resource.NewResourceRegistry()
+	authors := resource.NewResource(resource.ResourceConfig{Name: "authors", Model: MirrorAuthor{}, Operations: operations})
+	posts := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: MirrorPost{}, Operations: operations})
+	registry.Register(authors)
+	registry.Register(posts)
+
+	m, err := New(db, Config{
+		Name:     "post_views",
+		Source:   posts,
+		Model:    MirrorPostView{},
+		Registry: registry,
+		Fields: []Field{
+			{Name: "Title", Source: "Title"},
+			{Name: "AuthorName", Source: "Author.Name"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.Attach())
+
+	r := gin.New()
+	api := r.Group("/api")
+	handler.RegisterResource(api, authors, repository.NewGenericRepository(db, authors))
+	handler.RegisterResource(api, posts, repository.NewGenericRepository(db, posts))
+	handler.RegisterResource(api, m.Resource(), m.Repository())
+	return r, db, m
+}
+
+func send(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func views(t *testing.T, db *gorm.DB) []MirrorPostView {
+	var rows []MirrorPostView
+	require.NoError(t, db.Order("id").Find(&rows).Error)
+	return rows
+}
+
+func TestMirrorHooks(t *testing.T) {
+	r, db, _ := setupMirror(t)
+
+	require.Equal(t, http.StatusCreated, send(r, http.MethodPost, "/api/authors", `{"name":"Ada"}`).Code)
+	require.Equal(t, http.StatusCreated, send(r, http.MethodPost, "/api/posts", `{"title":"First","authorId":1}`).Code)
+	require.Equal(t, http.StatusCreated, send(r, http.MethodPost, "/api/posts", `{"title":"Second","authorId":1}`).Code)
+	assert.Equal(t, []MirrorPostView{
+		{ID: 1, Title: "First", AuthorName: "Ada"},
+		{ID: 2, Title: "Second", AuthorName: "Ada"},
+	}, views(t, db))
+
+	// Source updates refresh their row
+	require.Equal(t, http.StatusOK, send(r, http.MethodPut, "/api/posts/1", `{"title":"Renamed","authorId":1}`).Code)
+	assert.Equal(t, "Renamed", views(t, db)[0].Title)
+
+	// Related updates refresh the rows referencing the related record
+	require.Equal(t, http.StatusOK, send(r, http.MethodPut, "/api/authors/1", `{"name":"Grace"}`).Code)
+	for _, row := range views(t, db) {
+		assert.Equal(t, "Grace", row.AuthorName)
+	}
+
+	// Source deletes remove their row
+	require.Equal(t, http.StatusNoContent, send(r, http.MethodDelete, "/api/posts/2", "").Code)
+	assert.Len(t, views(t, db), 1)
+
+	// The mirror is served as a read-only resource
+	w := send(r, http.MethodGet, "/api/post_views", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data  []MirrorPostView `json:"data"`
+		Total int64            `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []MirrorPostView{{ID: 1, Title: "Renamed", AuthorName: "Grace"}}, response.Data)
+	assert.Equal(t, http.StatusNotFound, send(r, http.MethodPost, "/api/post_views", `{"title":"x"}`).Code)
+}
+
+func TestMirrorRebuild(t *testing.T) {
+	_, db, m := setupMirror(t)
+	m.config.BatchSize = 2
+
+	author := MirrorAuthor{Name: "Ada"}
+	require.NoError(t, db.Create(&author).Error)
+	for _, title := range []string{"a", "b", "c"} {
+		require.NoError(t, db.Create(&MirrorPost{Title: title, AuthorID: &author.ID}).Error)
+	}
+	require.NoError(t, db.Create(&MirrorPost{Title: "orphan"}).Error)
+	require.NoError(t, db.Create(&MirrorPostView{ID: 99, Title: "stale"}).Error)
+
+	require.NoError(t, m.Rebuild(context.Background()))
+	assert.Equal(t, []MirrorPostView{
+		{ID: 1, Title: "a", AuthorName: "Ada"},
+		{ID: 2, Title: "b", AuthorName: "Ada"},
+		{ID: 3, Title: "c", AuthorName: "Ada"},
+		{ID: 4, Title: "orphan"},
+	}, views(t, db))
+
+	_, err := m.Repository().Create(context.Background(), &MirrorPostView{})
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestNewValidatesFields(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	posts := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: MirrorPost{}})
+
+	for _, field := range []Field{
+		{Name: "Missing", Source: "Title"},
+		{Name: "Title", Source: "Missing"},
+		{Name: "AuthorName", Source: "Author.Missing"},
+		{Name: "AuthorName", Source: "Title.Name"},
+		{Name: "AuthorName", Source: "Author.Name.First"},
+	} {
+		_, err := New(db, Config{Source: posts, Model: MirrorPostView{}, Fields: []Field{field}})
+		assert.ErrorIs(t, err, ErrInvalidSource, field.Source)
+	}
+}
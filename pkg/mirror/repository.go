@@ -0,0 +1,56 @@
+package mirror
+
+import (
+	"context"
+
+	"github.com/suranig/refine-gin/pkg/repository"
+)
+
+// readOnlyRepository rejects writes to a mirror, which is only changed by refreshes
+type readOnlyRepository struct {
+	repository.Repository
+}
+
+func (r *readOnlyRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *readOnlyRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *readOnlyRepository) Delete(ctx context.Context, id interface{}) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	return nil, ErrReadOnly
+}
+
+func (r *readOnlyRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+func (r *readOnlyRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+func (r *readOnlyRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	return ErrReadOnly
+}
+
+// WithRelations returns a read-only repository preloading relations
+func (r *readOnlyRepository) WithRelations(relations ...string) repository.Repository {
+	return &readOnlyRepository{Repository: r.Repository.WithRelations(relations...)}
+}
+
+// WithTransaction runs fn with a read-only transactional repository
+func (r *readOnlyRepository) WithTransaction(fn func(repository.Repository) error) error {
+	return r.Repository.WithTransaction(func(tx repository.Repository) error {
+		return fn(&readOnlyRepository{Repository: tx})
+	})
+}
@@ -0,0 +1,225 @@
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// PreferenceContextKey is the key used to store the applied preference in the gin context
+const PreferenceContextKey = "preference"
+
+// PreferenceRequest is the request body for saving a preference
+type PreferenceRequest struct {
+	Sort    string   `json:"sort"`
+	Order   string   `json:"order"`
+	PerPage int      `json:"perPage"`
+	Columns []string `json:"columns"`
+}
+
+// RegisterPreferencesEndpoints registers GET/PUT/DELETE /preferences/:resource endpoints.
+// The owner ID is taken from the context, so the group must use middleware.OwnerContext.
+func RegisterPreferencesEndpoints(router *gin.RouterGroup, store *Store) {
+	router.GET("/preferences/:resource", GenerateGetPreferenceHandler(store))
+	router.PUT("/preferences/:resource", GenerateSavePreferenceHandler(store))
+	router.DELETE("/preferences/:resource", GenerateDeletePreferenceHandler(store))
+}
+
+// GenerateGetPreferenceHandler creates a handler returning the owner's preference for a resource
+func GenerateGetPreferenceHandler(store *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := middleware.GetOwnerID(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		pref, err := store.Get(c.Request.Context(), ownerID, c.Param("resource"))
+		if errors.Is(err, ErrPreferenceNotFound) {
+			apierror.Respond(c, http.StatusNotFound, err)
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": pref})
+	}
+}
+
+// GenerateSavePreferenceHandler creates a handler storing the owner's preference for a resource
+func GenerateSavePreferenceHandler(store *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := middleware.GetOwnerID(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		resourceName := c.Param("resource")
+		res, ok := resource.GlobalResourceRegistry.GetByName(resourceName)
+		if !ok {
+			apierror.RespondMessage(c, http.StatusNotFound, "Resource not found")
+			return
+		}
+
+		var req PreferenceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := validatePreference(res, req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		pref, err := store.Save(c.Request.Context(), &Preference{
+			OwnerID:  fmt.Sprintf("%v", ownerID),
+			Resource: resourceName,
+			Sort:     req.Sort,
+			Order:    req.Order,
+			PerPage:  req.PerPage,
+			Columns:  req.Columns,
+		})
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		c.JSON(http.StatusOK, gin.H{"data": pref})
+	}
+}
+
+// GenerateDeletePreferenceHandler creates a handler removing the owner's preference for a resource
+func GenerateDeletePreferenceHandler(store *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := middleware.GetOwnerID(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		if err := store.Delete(c.Request.Context(), ownerID, c.Param("resource")); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// Query parameters setting the sort, order and page size of lists, in refine-gin's
+// conventions and those of refine's simple-rest data provider
+var (
+	sortParams     = []string{"sort", "_sort"}
+	orderParams    = []string{"order", "_order"}
+	pageSizeParams = []string{"pageSize", "per_page", "_start", "_end"}
+)
+
+// ApplyPreferences is a middleware that fills in sort, page size and visible columns (as
+// the sparse fieldset, see query.ParseFields) from the owner's saved preference when a
+// list request omits them. It must run before any handler reads the query.
+func ApplyPreferences(store *Store, resourceName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Only list requests on the collection are affected
+		if c.Request.Method != http.MethodGet || len(c.Params) > 0 {
+			c.Next()
+			return
+		}
+
+		ownerID, err := middleware.GetOwnerID(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		pref, err := store.Get(c.Request.Context(), ownerID, resourceName)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		values := c.Request.URL.Query()
+		changed := false
+
+		if pref.Sort != "" && !hasAny(values, sortParams) {
+			values.Set("sort", pref.Sort)
+			if pref.Order != "" && !hasAny(values, orderParams) {
+				values.Set("order", pref.Order)
+			}
+			changed = true
+		}
+
+		if pref.PerPage > 0 && !hasAny(values, pageSizeParams) {
+			values.Set("pageSize", fmt.Sprintf("%d", pref.PerPage))
+			changed = true
+		}
+
+		if len(pref.Columns) > 0 && values.Get(query.FieldsParam) == "" {
+			values.Set(query.FieldsParam, strings.Join(pref.Columns, ","))
+			changed = true
+		}
+
+		if changed {
+			c.Request.URL.RawQuery = values.Encode()
+		}
+
+		c.Set(PreferenceContextKey, pref)
+		c.Next()
+	}
+}
+
+// hasAny reports whether any of the parameters is set
+func hasAny(values url.Values, params []string) bool {
+	for _, param := range params {
+		if values.Get(param) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePreference checks the requested preference against the resource definition
+func validatePreference(res resource.Resource, req PreferenceRequest) error {
+	if req.Order != "" && req.Order != "asc" && req.Order != "desc" {
+		return fmt.Errorf("order must be 'asc' or 'desc'")
+	}
+
+	if req.PerPage < 0 {
+		return fmt.Errorf("perPage must be positive")
+	}
+
+	if req.Sort != "" && !containsString(res.GetSortableFields(), req.Sort) {
+		return fmt.Errorf("field '%s' is not sortable", req.Sort)
+	}
+
+	for _, column := range req.Columns {
+		if res.GetField(column) == nil {
+			return fmt.Errorf("unknown column '%s'", column)
+		}
+	}
+
+	return nil
+}
+
+// containsString checks if a slice contains a string
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package preferences
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPreferenceNotFound is returned when an owner has no saved preference for a resource
+var ErrPreferenceNotFound = errors.New("preference not found")
+
+// Preference stores an owner's default list view for a resource
+type Preference struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	OwnerID  string `json:"ownerId" gorm:"size:191;not null;uniqueIndex:idx_refine_preferences_owner_resource"`
+	Resource string `json:"resource" gorm:"size:191;not null;uniqueIndex:idx_refine_preferences_owner_resource"`
+
+	// Default sort field and order ("asc" or "desc")
+	Sort  string `json:"sort,omitempty"`
+	Order string `json:"order,omitempty" gorm:"column:sort_order"`
+
+	// Default page size
+	PerPage int `json:"perPage,omitempty"`
+
+	// Visible table columns in display order
+	Columns []string `json:"columns,omitempty" gorm:"serializer:json"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TableName returns the table used to store preferences
+func (Preference) TableName() string {
+	return "refine_preferences"
+}
+
+// Store persists owner-scoped preferences
+type Store struct {
+	DB *gorm.DB
+}
+
+// NewStore creates a new preferences store
+func NewStore(db *gorm.DB) *Store {
+	return &Store{DB: db}
+}
+
+// AutoMigrate creates or updates the preferences table
+func (s *Store) AutoMigrate() error {
+	return s.DB.AutoMigrate(&Preference{})
+}
+
+// Get returns the preference saved by an owner for a resource
+func (s *Store) Get(ctx context.Context, ownerID interface{}, resourceName string) (*Preference, error) {
+	var pref Preference
+	err := s.DB.WithContext(ctx).
+		Where("owner_id = ? AND resource = ?", fmt.Sprintf("%v", ownerID), resourceName).
+		First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPreferenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// Save creates or replaces the preference of an owner for a resource
+func (s *Store) Save(ctx context.Context, pref *Preference) (*Preference, error) {
+	err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_id"}, {Name: "resource"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sort", "sort_order", "per_page", "columns", "updated_at"}),
+	}).Create(pref).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, pref.OwnerID, pref.Resource)
+}
+
+// Delete removes the preference of an owner for a resource
+func (s *Store) Delete(ctx context.Context, ownerID interface{}, resourceName string) error {
+	return s.DB.WithContext(ctx).
+		Where("owner_id = ? AND resource = ?", fmt.Sprintf("%v", ownerID), resourceName).
+		Delete(&Preference{}).Error
+}
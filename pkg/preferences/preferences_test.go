@@ -0,0 +1,170 @@
+package preferences
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type PreferenceTestModel struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Rank  int    `json:"rank"`
+}
+
+func setupPreferenceTest(t *testing.T) (*gin.Engine, *Store) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewStore(db)
+	require.NoError(t, store.AutoMigrate())
+
+	resource.RegisterToRegistry(resource.NewResource(resource.ResourceConfig{
+		Name:  "pref_items",
+		Model: PreferenceTestModel{},
+	}))
+
+	r := gin.New()
+	api := r.Group("/api", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID")))
+	RegisterPreferencesEndpoints(api, store)
+	api.GET("/pref_items", ApplyPreferences(store, "pref_items"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"sort":     c.Query("sort"),
+			"order":    c.Query("order"),
+			"pageSize": c.Query("pageSize"),
+			"fields":   c.Query("fields"),
+		})
+	})
+
+	return r, store
+}
+
+func doRequest(r *gin.Engine, method, path, owner string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Owner-ID", owner)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPreferenceEndpoints(t *testing.T) {
+	r, _ := setupPreferenceTest(t)
+
+	t.Run("not found before saving", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/preferences/pref_items", "user-1", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("save and read", func(t *testing.T) {
+		w := doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{
+			Sort: "rank", Order: "desc", PerPage: 25, Columns: []string{"title", "rank"},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Saving again replaces the previous preference
+		w = doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{
+			Sort: "title", Order: "asc", PerPage: 50,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = doRequest(r, http.MethodGet, "/api/preferences/pref_items", "user-1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data Preference `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "title", response.Data.Sort)
+		assert.Equal(t, 50, response.Data.PerPage)
+		assert.Empty(t, response.Data.Columns)
+	})
+
+	t.Run("owners are isolated", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/preferences/pref_items", "user-2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		w := doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{Order: "up"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{Sort: "missing"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{Columns: []string{"missing"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = doRequest(r, http.MethodPut, "/api/preferences/unknown", "user-1", PreferenceRequest{})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		w := doRequest(r, http.MethodDelete, "/api/preferences/pref_items", "user-1", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = doRequest(r, http.MethodGet, "/api/preferences/pref_items", "user-1", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestApplyPreferences(t *testing.T) {
+	r, _ := setupPreferenceTest(t)
+
+	w := doRequest(r, http.MethodPut, "/api/preferences/pref_items", "user-1", PreferenceRequest{
+		Sort: "rank", Order: "desc", PerPage: 25, Columns: []string{"title", "rank"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	t.Run("fills in missing params", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/pref_items", "user-1", nil)
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "rank", response["sort"])
+		assert.Equal(t, "desc", response["order"])
+		assert.Equal(t, "25", response["pageSize"])
+		assert.Equal(t, "title,rank", response["fields"])
+	})
+
+	t.Run("explicit params win", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/pref_items?sort=title&per_page=5&fields=id", "user-1", nil)
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "title", response["sort"])
+		assert.Equal(t, "", response["order"])
+		assert.Equal(t, "", response["pageSize"])
+		assert.Equal(t, "id", response["fields"])
+	})
+
+	t.Run("refine simple-rest params win", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/pref_items?_sort=title&_order=asc&_start=0&_end=10", "user-1", nil)
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "", response["sort"])
+		assert.Equal(t, "", response["order"])
+		assert.Equal(t, "", response["pageSize"])
+	})
+
+	t.Run("no preference", func(t *testing.T) {
+		w := doRequest(r, http.MethodGet, "/api/pref_items", "user-2", nil)
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "", response["sort"])
+	})
+}
@@ -0,0 +1,173 @@
+package presence
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// Config contains configuration for presence endpoints
+type Config struct {
+	// Tracker holding the presence state
+	Tracker *Tracker
+
+	// User identifies the current user (default the owner ID set by middleware.OwnerContext)
+	User func(c *gin.Context) (string, error)
+
+	// Name of the record ID route parameter (default "id")
+	IDParam string
+}
+
+func (config Config) withDefaults() Config {
+	if config.User == nil {
+		config.User = DefaultUser
+	}
+	if config.IDParam == "" {
+		config.IDParam = "id"
+	}
+	return config
+}
+
+// DefaultUser uses the owner ID from the context as user
+func DefaultUser(c *gin.Context) (string, error) {
+	ownerID, err := middleware.GetOwnerID(c)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", ownerID), nil
+}
+
+// PresenceRequest is the request body for announcing presence
+type PresenceRequest struct {
+	Mode string `json:"mode"`
+}
+
+// RegisterPresenceRoutes registers GET/POST/DELETE /<resource>/:id/presence endpoints
+func RegisterPresenceRoutes(router *gin.RouterGroup, res resource.Resource, config Config) {
+	config = config.withDefaults()
+	path := "/" + res.GetName() + "/:" + config.IDParam + "/presence"
+	router.GET(path, GeneratePresenceHandler(res, config))
+	router.POST(path, GenerateTouchPresenceHandler(res, config))
+	router.DELETE(path, GenerateLeavePresenceHandler(res, config))
+}
+
+// GeneratePresenceHandler creates a handler returning the users present on a record.
+// Clients accepting text/event-stream join the record with the "mode" query parameter
+// (default viewing) for the lifetime of the connection and receive a "presence" event
+// after every change.
+func GeneratePresenceHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+		id := c.Param(config.IDParam)
+
+		if !strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+			c.JSON(http.StatusOK, gin.H{"data": config.Tracker.List(res.GetName(), id)})
+			return
+		}
+
+		user, err := config.User(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+		mode, err := parseMode(c.DefaultQuery("mode", ModeViewing))
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		updates, unsubscribe := config.Tracker.Subscribe(res.GetName(), id)
+		defer unsubscribe()
+		defer config.Tracker.Leave(res.GetName(), id, user)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		sent := config.Tracker.Touch(res.GetName(), id, user, mode)
+		c.SSEvent("presence", sent)
+		c.Writer.Flush()
+
+		ticker := time.NewTicker(config.Tracker.TTL() / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-ticker.C:
+				config.Tracker.Touch(res.GetName(), id, user, mode)
+			case list := <-updates:
+				if reflect.DeepEqual(list, sent) {
+					continue
+				}
+				sent = list
+				c.SSEvent("presence", list)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// GenerateTouchPresenceHandler creates a handler announcing or refreshing the current
+// user's presence on a record. Clients not using the event stream call it periodically.
+func GenerateTouchPresenceHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		user, err := config.User(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		var req PresenceRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				apierror.Respond(c, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if req.Mode == "" {
+			req.Mode = ModeViewing
+		}
+		mode, err := parseMode(req.Mode)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		utils.DisableCaching(c.Writer)
+		list := config.Tracker.Touch(res.GetName(), c.Param(config.IDParam), user, mode)
+		c.JSON(http.StatusOK, gin.H{"data": list})
+	}
+}
+
+// GenerateLeavePresenceHandler creates a handler removing the current user from a record
+func GenerateLeavePresenceHandler(res resource.Resource, config Config) gin.HandlerFunc {
+	config = config.withDefaults()
+	return func(c *gin.Context) {
+		user, err := config.User(c)
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		config.Tracker.Leave(res.GetName(), c.Param(config.IDParam), user)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func parseMode(mode string) (string, error) {
+	if mode != ModeViewing && mode != ModeEditing {
+		return "", fmt.Errorf("mode must be '%s' or '%s'", ModeViewing, ModeEditing)
+	}
+	return mode, nil
+}
@@ -0,0 +1,224 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Presence modes
+const (
+	// ModeViewing marks a user who has the record open
+	ModeViewing = "viewing"
+
+	// ModeEditing marks a user who has the record open in an edit form
+	ModeEditing = "editing"
+)
+
+// Presence describes a user currently viewing or editing a record
+type Presence struct {
+	User     string    `json:"user"`
+	Mode     string    `json:"mode"`
+	Since    time.Time `json:"since"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Tracker keeps in-memory presence per record and notifies subscribers about changes.
+// Entries not refreshed within the TTL expire.
+type Tracker struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu          sync.Mutex
+	records     map[string]map[string]*Presence
+	subscribers map[string]map[chan []Presence]struct{}
+}
+
+// NewTracker creates a new presence tracker. A ttl of zero defaults to 30 seconds.
+func NewTracker(ttl time.Duration) *Tracker {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Tracker{
+		ttl:         ttl,
+		now:         time.Now,
+		records:     make(map[string]map[string]*Presence),
+		subscribers: make(map[string]map[chan []Presence]struct{}),
+	}
+}
+
+// TTL returns the time after which an entry without refresh expires
+func (t *Tracker) TTL() time.Duration {
+	return t.ttl
+}
+
+func recordKey(resourceName string, recordID interface{}) string {
+	return fmt.Sprintf("%s/%v", resourceName, recordID)
+}
+
+// Touch records that a user is present on a record, or refreshes the user's entry
+func (t *Tracker) Touch(resourceName string, recordID interface{}, user, mode string) []Presence {
+	key := recordKey(resourceName, recordID)
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := t.prune(key, now)
+	users := t.records[key]
+	if users == nil {
+		users = make(map[string]*Presence)
+		t.records[key] = users
+	}
+	if entry, ok := users[user]; ok {
+		changed = changed || entry.Mode != mode
+		entry.Mode = mode
+		entry.LastSeen = now
+	} else {
+		users[user] = &Presence{User: user, Mode: mode, Since: now, LastSeen: now}
+		changed = true
+	}
+
+	list := t.list(key)
+	if changed {
+		t.notify(key, list)
+	}
+	return list
+}
+
+// Leave removes a user from a record
+func (t *Tracker) Leave(resourceName string, recordID interface{}, user string) []Presence {
+	key := recordKey(resourceName, recordID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := t.prune(key, t.now())
+	if _, ok := t.records[key][user]; ok {
+		delete(t.records[key], user)
+		changed = true
+	}
+
+	list := t.list(key)
+	if len(list) == 0 {
+		delete(t.records, key)
+	}
+	if changed {
+		t.notify(key, list)
+	}
+	return list
+}
+
+// List returns the users present on a record ordered by arrival
+func (t *Tracker) List(resourceName string, recordID interface{}) []Presence {
+	key := recordKey(resourceName, recordID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	list := t.list(key)
+	if t.prune(key, t.now()) {
+		list = t.list(key)
+		t.notify(key, list)
+	}
+	return list
+}
+
+// Subscribe returns a channel receiving the presence list of a record after every change.
+// Slow subscribers only receive the latest list. Call the returned function to unsubscribe.
+func (t *Tracker) Subscribe(resourceName string, recordID interface{}) (<-chan []Presence, func()) {
+	key := recordKey(resourceName, recordID)
+	ch := make(chan []Presence, 1)
+
+	t.mu.Lock()
+	if t.subscribers[key] == nil {
+		t.subscribers[key] = make(map[chan []Presence]struct{})
+	}
+	t.subscribers[key][ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(t.subscribers[key], ch)
+			if len(t.subscribers[key]) == 0 {
+				delete(t.subscribers, key)
+			}
+		})
+	}
+}
+
+// Sweep removes expired entries of all records and notifies their subscribers
+func (t *Tracker) Sweep() {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.records {
+		if t.prune(key, now) {
+			t.notify(key, t.list(key))
+		}
+		if len(t.records[key]) == 0 {
+			delete(t.records, key)
+		}
+	}
+}
+
+// StartSweeper periodically runs Sweep until ctx is done
+func (t *Tracker) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				t.Sweep()
+			}
+		}
+	}()
+}
+
+// prune removes expired entries of a record and reports whether any were removed
+func (t *Tracker) prune(key string, now time.Time) bool {
+	removed := false
+	for user, entry := range t.records[key] {
+		if now.Sub(entry.LastSeen) > t.ttl {
+			delete(t.records[key], user)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// list returns a copy of the entries of a record ordered by arrival
+func (t *Tracker) list(key string) []Presence {
+	list := make([]Presence, 0, len(t.records[key]))
+	for _, entry := range t.records[key] {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Since.Equal(list[j].Since) {
+			return list[i].Since.Before(list[j].Since)
+		}
+		return list[i].User < list[j].User
+	})
+	return list
+}
+
+// notify sends the list to all subscribers of a record, replacing undelivered lists
+func (t *Tracker) notify(key string, list []Presence) {
+	for ch := range t.subscribers[key] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- list
+	}
+}
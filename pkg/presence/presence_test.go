@@ -0,0 +1,144 @@
+package presence
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(time.Minute)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	updates, unsubscribe := tracker.Subscribe("articles", 1)
+	defer unsubscribe()
+
+	list := tracker.Touch("articles", 1, "alice", ModeViewing)
+	require.Len(t, list, 1)
+	assert.Equal(t, []Presence{{User: "alice", Mode: ModeViewing, Since: now, LastSeen: now}}, <-updates)
+
+	now = now.Add(10 * time.Second)
+	list = tracker.Touch("articles", "1", "bob", ModeEditing)
+	assert.Equal(t, []string{"alice", "bob"}, users(list))
+	<-updates
+
+	// Refreshing without a change does not notify
+	tracker.Touch("articles", 1, "bob", ModeEditing)
+	select {
+	case <-updates:
+		t.Fatal("unexpected notification")
+	default:
+	}
+
+	// Entries expire unless refreshed
+	now = now.Add(55 * time.Second)
+	tracker.Sweep()
+	assert.Equal(t, []string{"bob"}, users(<-updates))
+
+	assert.Empty(t, tracker.Leave("articles", 1, "bob"))
+	assert.Empty(t, <-updates)
+	assert.Empty(t, tracker.List("articles", 2))
+}
+
+func users(list []Presence) []string {
+	names := make([]string, len(list))
+	for i, p := range list {
+		names[i] = p.User
+	}
+	return names
+}
+
+func setupRouter(tracker *Tracker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "articles", Model: struct{ ID uint }{}})
+
+	r := gin.New()
+	RegisterPresenceRoutes(r.Group(""), res, Config{
+		Tracker: tracker,
+		User:    func(c *gin.Context) (string, error) { return c.GetHeader("X-User"), nil },
+	})
+	return r
+}
+
+func TestPresenceRoutes(t *testing.T) {
+	r := setupRouter(NewTracker(time.Minute))
+
+	request := func(method, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/articles/1/presence", strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "alice", `{"mode":"editing"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = request(http.MethodPost, "bob", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "bob", `{"mode":"typing"}`).Code)
+
+	w = request(http.MethodGet, "carol", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []Presence `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	assert.Equal(t, ModeEditing, response.Data[0].Mode)
+	assert.Equal(t, ModeViewing, response.Data[1].Mode)
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "alice", "").Code)
+	w = request(http.MethodGet, "carol", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Data, 1)
+}
+
+func TestPresenceStream(t *testing.T) {
+	tracker := NewTracker(time.Minute)
+	server := httptest.NewServer(setupRouter(tracker))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/articles/1/presence?mode=editing", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-User", "alice")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := bufio.NewScanner(resp.Body)
+	nextData := func() string {
+		for events.Scan() {
+			if line := events.Text(); strings.HasPrefix(line, "data:") {
+				return line
+			}
+		}
+		return ""
+	}
+
+	// The stream holder joins the record for the lifetime of the connection
+	assert.Contains(t, nextData(), `"user":"alice","mode":"editing"`)
+
+	tracker.Touch("articles", 1, "bob", ModeViewing)
+	assert.Contains(t, nextData(), `"user":"bob"`)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return len(tracker.List("articles", 1)) == 1
+	}, time.Second, 10*time.Millisecond)
+}
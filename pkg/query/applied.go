@@ -0,0 +1,167 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// appliedOperators are the filter operators filterCondition handles, and has_flag;
+// others compare with "eq", unless they are custom operators (see RegisterOperator)
+var appliedOperators = map[string]bool{
+	"eq": true, "ne": true, "lt": true, "gt": true, "lte": true, "gte": true,
+	"contains": true, "containsi": true, "startswith": true, "endswith": true,
+	"null": true, "in": true, "has_flag": true,
+}
+
+// AppliedQuery is the query a list ran with after validation, echoed in list responses
+// so clients notice when the server ignored part of their query instead of showing
+// records that don't match it
+type AppliedQuery struct {
+	// Filters are the applied filters with lowercase operators: the simple filters,
+	// then the advanced filters (see QueryOptions.AppliedFilters)
+	Filters []Filter `json:"filters"`
+
+	// FilterTree is the applied filter tree, without the filters on unknown fields
+	FilterTree []FilterNode `json:"filterTree,omitempty"`
+
+	Sorters []SortOption `json:"sorters"`
+	Search  string       `json:"q,omitempty"`
+
+	// Pagination is nil when the list is not paginated
+	Pagination *AppliedPagination `json:"pagination,omitempty"`
+
+	// Ignored are the parameters and filters that were dropped or changed
+	Ignored []IgnoredParam `json:"ignored,omitempty"`
+}
+
+// AppliedPagination is the page of an AppliedQuery
+type AppliedPagination struct {
+	Current  int `json:"current"`
+	PageSize int `json:"pageSize"`
+}
+
+// IgnoredParam is a query parameter or filter an AppliedQuery dropped or changed
+type IgnoredParam struct {
+	// Name is the query parameter, or the field of a filter or sort
+	Name string `json:"name"`
+
+	Reason string `json:"reason"`
+
+	// Suggestion is the closest known name, if any is similar
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// AppliedQuery returns the query Apply and the pagination run with the options.
+// Parameters of values that no list understands for the resource (see UnknownParams)
+// are reported as ignored, along with filters and sorts on unknown fields and filters
+// with unknown operators, which compare with "eq".
+func (o QueryOptions) AppliedQuery(values url.Values) AppliedQuery {
+	applied := AppliedQuery{
+		Filters: []Filter{},
+		Sorters: []SortOption{},
+		Search:  o.Search,
+	}
+	if o.Resource == nil {
+		return applied
+	}
+	fields := make([]string, 0, len(o.Resource.GetFields()))
+	for _, field := range o.Resource.GetFields() {
+		fields = append(fields, field.Name)
+	}
+	unknownField := func(name, reason string) {
+		applied.Ignored = append(applied.Ignored, IgnoredParam{Name: name, Reason: reason, Suggestion: Suggest(name, fields)})
+	}
+
+	for _, param := range UnknownParams(values, o.Resource) {
+		if filterParamPattern.MatchString(param.Name) {
+			// Reported by field with the other filters
+			continue
+		}
+		applied.Ignored = append(applied.Ignored, IgnoredParam{Name: param.Name, Reason: "unknown parameter", Suggestion: param.Suggestion})
+	}
+
+	// Filters, reporting those on unknown fields
+	simple := make([]string, 0, len(o.Filters))
+	for field := range o.Filters {
+		simple = append(simple, field)
+	}
+	sort.Strings(simple)
+	for _, field := range simple {
+		if o.Resource.GetField(field) == nil {
+			unknownField(field, "unknown filter field")
+		}
+	}
+	for _, filter := range o.AdvancedFilters {
+		if !o.knownFilterField(filter.Field) {
+			unknownField(filter.Field, "unknown filter field")
+		}
+	}
+	for _, filter := range o.AppliedFilters() {
+		applied.Filters = append(applied.Filters, applied.normalizeFilter(filter))
+	}
+	applied.FilterTree = applied.appliedFilterTree(o, o.FilterTree, unknownField)
+
+	// Sorts, reporting a single sort on an unknown field
+	applied.Sorters = append(applied.Sorters, o.SortFields()...)
+	if o.Sort != "" && !strings.Contains(o.Sort, ",") && o.Resource.GetField(o.Sort) == nil {
+		unknownField(o.Sort, "unknown sort field")
+	}
+
+	// Sorts and filters dropped by the query validation
+	applied.Ignored = append(applied.Ignored, o.Ignored...)
+
+	if !o.DisablePagination {
+		applied.Pagination = &AppliedPagination{Current: o.Page, PageSize: o.PerPage}
+	}
+	return applied
+}
+
+// knownFilterField reports whether filters on a field are applied: fields of the
+// resource and of its relations
+func (o QueryOptions) knownFilterField(field string) bool {
+	if _, _, ok := fieldColumn(o.Resource, field); ok {
+		return true
+	}
+	_, _, ok := relationFilterPath(o.Resource, field)
+	return ok
+}
+
+// normalizeFilter lowercases the operator of an applied filter, reporting unknown
+// operators, which compare with "eq"
+func (a *AppliedQuery) normalizeFilter(filter Filter) Filter {
+	operator := strings.ToLower(filter.Operator)
+	if _, custom := customOperator(operator); !appliedOperators[operator] && !custom {
+		a.Ignored = append(a.Ignored, IgnoredParam{
+			Name:   filter.Field,
+			Reason: fmt.Sprintf("unknown operator %q, compared with eq", filter.Operator),
+		})
+		operator = string(OperatorEqual)
+	}
+	filter.Operator = operator
+	return filter
+}
+
+// appliedFilterTree returns the applied nodes of a filter tree, reporting the filters
+// on unknown fields and the empty groups, which are dropped
+func (a *AppliedQuery) appliedFilterTree(o QueryOptions, nodes []FilterNode, unknownField func(name, reason string)) []FilterNode {
+	var applied []FilterNode
+	for _, node := range nodes {
+		if node.IsGroup() {
+			node.Operator = strings.ToLower(node.Operator)
+			node.Filters = a.appliedFilterTree(o, node.Filters, unknownField)
+			if len(node.Filters) > 0 {
+				applied = append(applied, node)
+			}
+			continue
+		}
+		if !o.knownFilterField(node.Field) {
+			unknownField(node.Field, "unknown filter field")
+			continue
+		}
+		node.Filter = a.normalizeFilter(node.Filter)
+		applied = append(applied, node)
+	}
+	return applied
+}
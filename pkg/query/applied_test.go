@@ -0,0 +1,60 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestAppliedQuery(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "status", Type: "string"},
+		},
+	})
+
+	c, _ := createTestContext("page=2&per_page=5&sort=title&order=desc&status=open" +
+		"&filter[title][CONTAINS]=go&filter[titel][eq]=x&filter[id][like]=1&perPgae=50")
+	options := ParseQueryOptions(c, res)
+	applied := options.AppliedQuery(c.Request.URL.Query())
+
+	assert.ElementsMatch(t, []Filter{
+		{Field: "status", Operator: "eq", Value: "open"},
+		{Field: "id", Operator: "eq", Value: "1"},
+		{Field: "title", Operator: "contains", Value: "go"},
+	}, applied.Filters)
+	assert.Equal(t, []SortOption{{Field: "title", Order: "desc"}}, applied.Sorters)
+	assert.Equal(t, &AppliedPagination{Current: 2, PageSize: 5}, applied.Pagination)
+	assert.ElementsMatch(t, []IgnoredParam{
+		{Name: "perPgae", Reason: "unknown parameter", Suggestion: "per_page"},
+		{Name: "titel", Reason: "unknown filter field", Suggestion: "title"},
+		{Name: "id", Reason: `unknown operator "like", compared with eq`},
+	}, applied.Ignored)
+
+	// Sorts on unknown fields are ignored
+	c, _ = createTestContext("sort=titel")
+	applied = ParseQueryOptions(c, res).AppliedQuery(c.Request.URL.Query())
+	assert.Contains(t, applied.Ignored, IgnoredParam{Name: "titel", Reason: "unknown sort field", Suggestion: "title"})
+
+	// Filter trees drop the filters on unknown fields and the groups left empty
+	c, _ = createTestContext("")
+	options = ParseQueryOptions(c, res)
+	options.FilterTree = []FilterNode{
+		{Filter: Filter{Field: "title", Operator: "eq", Value: "a"}},
+		{Filter: Filter{Operator: "OR"}, Filters: []FilterNode{
+			{Filter: Filter{Field: "missing", Operator: "eq", Value: "b"}},
+		}},
+	}
+	applied = options.AppliedQuery(c.Request.URL.Query())
+	assert.Equal(t, []FilterNode{{Filter: Filter{Field: "title", Operator: "eq", Value: "a"}}}, applied.FilterTree)
+	assert.Equal(t, []IgnoredParam{{Name: "missing", Reason: "unknown filter field"}}, applied.Ignored)
+
+	// Unpaginated lists have no pagination
+	options.DisablePagination = true
+	assert.Nil(t, options.AppliedQuery(nil).Pagination)
+}
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// Operators of filters on fields of each kind (see filterOperators)
+var (
+	equalityOperators   = []string{"eq", "ne", "in", "null"}
+	comparisonOperators = []string{"eq", "ne", "lt", "gt", "lte", "gte", "in", "null"}
+	textOperators       = []string{"eq", "ne", "lt", "gt", "lte", "gte", "contains", "containsi", "startswith", "endswith", "in", "null"}
+	booleanOperators    = []string{"eq", "ne", "null"}
+	flagsOperators      = []string{"eq", "ne", "has_flag", "null"}
+)
+
+// Capabilities describes the query parameters the lists of a resource understand:
+// the filter operators of its filterable fields, its sortable fields, page sizes,
+// loadable relations and search. Custom operators are those registered when it is
+// called.
+func Capabilities(res resource.Resource) *resource.QueryCapabilitiesMetadata {
+	fields := make(map[string]resource.Field)
+	for _, field := range res.GetFields() {
+		fields[field.Name] = field
+	}
+
+	capabilities := &resource.QueryCapabilitiesMetadata{
+		Params: resource.QueryParamsMetadata{
+			Page:      "current",
+			PageSize:  "pageSize",
+			Sort:      "sort",
+			Order:     "order",
+			Search:    "q",
+			Fields:    FieldsParam,
+			Include:   IncludeParam,
+			WithCount: WithCountParam,
+			Timezone:  TimezoneParam,
+		},
+		Filters:         []resource.FilterCapabilityMetadata{},
+		CustomOperators: RegisteredOperators(),
+		Sort: resource.SortCapabilityMetadata{
+			Fields:   apiNames(fields, res.GetSortableFields()),
+			Default:  res.GetDefaultSort(),
+			Multiple: true,
+		},
+		Pagination: resource.PaginationCapabilityMetadata{
+			DefaultPageSize:  DefaultPageSize,
+			CountModes:       []resource.CountMode{resource.CountExact, resource.CountNone, resource.CountEstimated},
+			DefaultCountMode: resource.CountExact,
+		},
+	}
+	if capped, ok := res.(resource.PageSizeResource); ok {
+		capabilities.Pagination.MaxPageSize = capped.GetMaxPageSize()
+	}
+	if counted, ok := res.(resource.CountModeResource); ok {
+		capabilities.Pagination.DefaultCountMode = counted.GetCountMode()
+	}
+	if sort := capabilities.Sort.Default; sort != nil {
+		if field, ok := fields[sort.Field]; ok {
+			capabilities.Sort.Default = &resource.Sort{Field: field.APIName(), Order: sort.Order}
+		}
+	}
+
+	for _, name := range res.GetFilterableFields() {
+		field, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if _, ok := field.Column(); !ok {
+			continue
+		}
+		capabilities.Filters = append(capabilities.Filters, resource.FilterCapabilityMetadata{
+			Field:     field.APIName(),
+			Type:      field.Type,
+			Operators: filterOperators(field),
+		})
+	}
+
+	for _, relation := range res.GetRelations() {
+		capabilities.RelationFilters = append(capabilities.RelationFilters, relation.Name)
+		capabilities.Includes = append(capabilities.Includes, relation.Name)
+	}
+
+	search := &resource.SearchCapabilityMetadata{Fields: apiNames(fields, res.GetSearchable()), Match: "contains"}
+	if searchable, ok := res.(resource.SearchableRelationsResource); ok {
+		search.RelationFields = searchable.GetSearchableRelationFields()
+	}
+	if len(search.Fields) > 0 || len(search.RelationFields) > 0 {
+		capabilities.Search = search
+	}
+
+	return capabilities
+}
+
+// filterOperators returns the built-in operators of filters on a field, by its type
+func filterOperators(field resource.Field) []string {
+	if field.Flags != nil {
+		return flagsOperators
+	}
+
+	fieldType := strings.ToLower(strings.TrimPrefix(field.Type, "*"))
+	switch {
+	case fieldType == "bool" || fieldType == "boolean" || fieldType == "checkbox":
+		return booleanOperators
+	case fieldType == "string" || fieldType == "text" || fieldType == "richtext" || fieldType == "select":
+		return textOperators
+	case strings.Contains(fieldType, "int") || strings.Contains(fieldType, "float") ||
+		strings.Contains(fieldType, "time") || strings.Contains(fieldType, "date") ||
+		fieldType == "number" || fieldType == "decimal" || fieldType == "double":
+		return comparisonOperators
+	default:
+		return equalityOperators
+	}
+}
+
+// apiNames returns the names of fields in API payloads (see resource.Field.APIName).
+// Names of unknown fields are kept.
+func apiNames(fields map[string]resource.Field, names []string) []string {
+	result := make([]string, len(names))
+	for i, name := range names {
+		result[i] = name
+		if field, ok := fields[name]; ok {
+			result[i] = field.APIName()
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm/clause"
+)
+
+type CapabilityPost struct {
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Views       int    `json:"views"`
+	Published   bool   `json:"published"`
+	Permissions int64  `json:"permissions" refine:"flags=read|write"`
+	Tags        []CapabilityTag
+}
+
+type CapabilityTag struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestCapabilities(t *testing.T) {
+	RegisterOperator("near", func(field resource.Field, column string, value interface{}) (clause.Expression, error) {
+		return clause.Expr{SQL: column + " = ?", Vars: []interface{}{value}}, nil
+	})
+	defer UnregisterOperator("near")
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:             "posts",
+		Model:            CapabilityPost{},
+		FilterableFields: []string{"title", "views", "published", "permissions"},
+		SortableFields:   []string{"title", "views"},
+		SearchableFields: []string{"title"},
+		DefaultSort:      &resource.Sort{Field: "views", Order: "desc"},
+		Relations:        []resource.Relation{{Name: "Tags", Type: resource.RelationTypeOneToMany, Resource: "tags"}},
+		CountMode:        resource.CountNone,
+		MaxPageSize:      50,
+	})
+
+	capabilities := Capabilities(res)
+	assert.Equal(t, "current", capabilities.Params.Page)
+	assert.Equal(t, "q", capabilities.Params.Search)
+	assert.Equal(t, WithCountParam, capabilities.Params.WithCount)
+
+	operators := make(map[string][]string)
+	for _, filter := range capabilities.Filters {
+		operators[filter.Field] = filter.Operators
+	}
+	assert.Contains(t, operators["title"], "containsi")
+	assert.Contains(t, operators["views"], "gte")
+	assert.NotContains(t, operators["views"], "contains")
+	assert.Equal(t, []string{"eq", "ne", "null"}, operators["published"])
+	assert.Contains(t, operators["permissions"], "has_flag")
+	assert.Equal(t, []string{"near"}, capabilities.CustomOperators)
+
+	assert.Equal(t, []string{"title", "views"}, capabilities.Sort.Fields)
+	assert.Equal(t, &resource.Sort{Field: "views", Order: "desc"}, capabilities.Sort.Default)
+	assert.Equal(t, 50, capabilities.Pagination.MaxPageSize)
+	assert.Equal(t, DefaultPageSize, capabilities.Pagination.DefaultPageSize)
+	assert.Equal(t, resource.CountNone, capabilities.Pagination.DefaultCountMode)
+	assert.Equal(t, []string{"Tags"}, capabilities.Includes)
+	assert.Equal(t, []string{"Tags"}, capabilities.RelationFilters)
+	require.NotNil(t, capabilities.Search)
+	assert.Equal(t, []string{"title"}, capabilities.Search.Fields)
+	assert.Equal(t, "contains", capabilities.Search.Match)
+
+	// Resources without searchable fields have no search
+	plain := resource.NewResource(resource.ResourceConfig{Name: "tags", Model: CapabilityTag{}})
+	assert.Nil(t, Capabilities(plain).Search)
+	assert.Zero(t, Capabilities(plain).Pagination.MaxPageSize)
+}
+
+func TestLimitPageSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: CapabilityPost{}, MaxPageSize: 50})
+
+	parse := func(url string) int {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", url, nil)
+		return NewQueryOptions(c, res).PerPage
+	}
+	assert.Equal(t, 50, parse("/posts?pageSize=500"))
+	assert.Equal(t, 20, parse("/posts?pageSize=20"))
+	assert.Equal(t, 50, parse("/posts?per_page=51"))
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// WithCountParam is the query parameter choosing how lists are counted:
+// ?withCount=false skips the count, ?withCount=estimated estimates it (see
+// resource.CountMode)
+const WithCountParam = "withCount"
+
+// ParseCountMode returns the count mode of a list request: the withCount parameter,
+// or the count mode of the resource. Invalid values are ignored.
+func ParseCountMode(c *gin.Context, res resource.Resource) resource.CountMode {
+	if mode, ok := resource.ParseCountMode(c.Query(WithCountParam)); ok {
+		return mode
+	}
+	if counted, ok := res.(resource.CountModeResource); ok {
+		return counted.GetCountMode()
+	}
+	return resource.CountExact
+}
+
+// HasNextPage reports whether records follow the page of the options, given the total
+// returned by List, exact or, without a count, a lower bound
+func (o QueryOptions) HasNextPage(total int64) bool {
+	return !o.DisablePagination && total > int64(o.Page*o.PerPage)
+}
+
+// PaginationMeta returns the meta of list responses: the page, its size, whether a
+// next page exists and, for counts that are not exact, the count mode
+func (o QueryOptions) PaginationMeta(total int64) map[string]interface{} {
+	meta := map[string]interface{}{
+		"page":        o.Page,
+		"pageSize":    o.PerPage,
+		"hasNextPage": o.HasNextPage(total),
+	}
+	if o.CountMode != "" && o.CountMode != resource.CountExact {
+		meta["countMode"] = o.CountMode
+	}
+	return meta
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestParseCountMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exact := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: struct{}{}})
+	huge := resource.NewResource(resource.ResourceConfig{Name: "events", Model: struct{}{}, CountMode: resource.CountNone})
+
+	parse := func(res resource.Resource, url string) resource.CountMode {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", url, nil)
+		return NewQueryOptions(c, res).CountMode
+	}
+
+	assert.Equal(t, resource.CountExact, parse(exact, "/posts"))
+	assert.Equal(t, resource.CountNone, parse(exact, "/posts?withCount=false"))
+	assert.Equal(t, resource.CountEstimated, parse(exact, "/posts?withCount=estimated"))
+	assert.Equal(t, resource.CountExact, parse(exact, "/posts?withCount=maybe"))
+	assert.Equal(t, resource.CountNone, parse(huge, "/events"))
+	assert.Equal(t, resource.CountExact, parse(huge, "/events?withCount=true"))
+}
+
+func TestPaginationMeta(t *testing.T) {
+	options := QueryOptions{Page: 2, PerPage: 10}
+	assert.Equal(t, map[string]interface{}{"page": 2, "pageSize": 10, "hasNextPage": true}, options.PaginationMeta(21))
+	assert.False(t, options.HasNextPage(20))
+
+	options.CountMode = resource.CountNone
+	assert.Equal(t, resource.CountNone, options.PaginationMeta(20)["countMode"])
+
+	options.DisablePagination = true
+	assert.False(t, options.HasNextPage(100))
+}
@@ -0,0 +1,152 @@
+package query
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// Cursor errors, client errors to be answered with 400
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrStaleCursor   = errors.New("cursor was issued for another query or has expired")
+)
+
+// CursorCodec encrypts the cursors of cursor (keyset) pagination and binds them to the
+// query that issued them. A cursor carries the position after the last record of a
+// page (the values of its sort fields, then of its ID) and the hash of the filters and
+// sorts of the query (see QueryOptions.QueryHash), so clients can neither read nor
+// tamper with it, nor reuse it with other filters or sorts, where the position means
+// nothing.
+type CursorCodec struct {
+	aead cipher.AEAD
+
+	// MaxAge expires cursors older than it (never if zero)
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// cursorPayload is the encrypted content of a cursor
+type cursorPayload struct {
+	Query    string        `json:"q"`
+	Position []interface{} `json:"p"`
+	IssuedAt int64         `json:"t"`
+}
+
+// NewCursorCodec creates a codec encrypting cursors with AES-256-GCM under a key derived
+// from the secret. Instances of a service must share the secret for cursors issued by
+// one to be accepted by the others.
+func NewCursorCodec(secret []byte) (*CursorCodec, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("cursor secret must not be empty")
+	}
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &CursorCodec{aead: aead, now: time.Now}, nil
+}
+
+// Encode returns the cursor of a position for the query of the options
+func (c *CursorCodec) Encode(options QueryOptions, position []interface{}) (string, error) {
+	payload, err := json.Marshal(cursorPayload{
+		Query:    options.QueryHash(),
+		Position: position,
+		IssuedAt: c.now().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, payload, nil)), nil
+}
+
+// Decode returns the position of a cursor issued for the query of the options. Cursors
+// that don't decrypt (tampered, truncated or encrypted with another secret) return
+// ErrInvalidCursor; cursors of other filters or sorts, or older than MaxAge, return
+// ErrStaleCursor. Integer positions decode as int64 and other numbers as float64.
+func (c *CursorCodec) Decode(options QueryOptions, token string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return nil, ErrInvalidCursor
+	}
+	nonceSize := c.aead.NonceSize()
+	plaintext, err := c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(plaintext))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Query != options.QueryHash() {
+		return nil, ErrStaleCursor
+	}
+	if c.MaxAge > 0 && c.now().Sub(time.Unix(payload.IssuedAt, 0)) > c.MaxAge {
+		return nil, ErrStaleCursor
+	}
+
+	for i, value := range payload.Position {
+		if number, ok := value.(json.Number); ok {
+			if n, err := number.Int64(); err == nil {
+				payload.Position[i] = n
+			} else if f, err := number.Float64(); err == nil {
+				payload.Position[i] = f
+			}
+		}
+	}
+	return payload.Position, nil
+}
+
+// QueryHash returns a hash of what selects and orders the records of the options: the
+// resource, the applied filters and filter tree, the search and the sorts. Pagination
+// and sparse fieldsets don't change it.
+func (o QueryOptions) QueryHash() string {
+	var name string
+	var filters []Filter
+	var sorts []SortOption
+	if o.Resource != nil {
+		name = o.Resource.GetName()
+		filters = o.AppliedFilters()
+		sorts = o.SortFields()
+	}
+
+	encoded := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		data, _ := json.Marshal(filter)
+		encoded = append(encoded, string(data))
+	}
+	// Advanced filters are parsed in no particular order
+	sort.Strings(encoded)
+
+	data, _ := json.Marshal(struct {
+		Resource string       `json:"resource"`
+		Filters  []string     `json:"filters"`
+		Tree     []FilterNode `json:"tree"`
+		Search   string       `json:"search"`
+		Sorts    []SortOption `json:"sorts"`
+	}{name, encoded, o.FilterTree, o.Search, sorts})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestCursorCodec(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "status", Type: "string"},
+		},
+	})
+	options := QueryOptions{
+		Resource:        res,
+		Page:            1,
+		PerPage:         10,
+		Sort:            "title",
+		Order:           "asc",
+		Filters:         map[string]interface{}{"status": "open"},
+		AdvancedFilters: []Filter{{Field: "id", Operator: "gt", Value: "3"}, {Field: "title", Operator: "contains", Value: "go"}},
+	}
+
+	codec, err := NewCursorCodec([]byte("secret"))
+	require.NoError(t, err)
+	token, err := codec.Encode(options, []interface{}{"Go tips", 42})
+	require.NoError(t, err)
+	assert.NotContains(t, token, "Go tips")
+
+	// Pagination and the order advanced filters were parsed in don't bind cursors
+	options.Page, options.PerPage = 3, 50
+	options.AdvancedFilters[0], options.AdvancedFilters[1] = options.AdvancedFilters[1], options.AdvancedFilters[0]
+	position, err := codec.Decode(options, token)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"Go tips", int64(42)}, position)
+
+	// Cursors of other filters or sorts are stale
+	other := options
+	other.Filters = map[string]interface{}{"status": "closed"}
+	_, err = codec.Decode(other, token)
+	assert.ErrorIs(t, err, ErrStaleCursor)
+	other = options
+	other.Order = "desc"
+	_, err = codec.Decode(other, token)
+	assert.ErrorIs(t, err, ErrStaleCursor)
+
+	// Tampered cursors and cursors of other secrets are invalid
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+	_, err = codec.Decode(options, string(tampered))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = codec.Decode(options, strings.Repeat("A", 8))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	otherCodec, err := NewCursorCodec([]byte("other"))
+	require.NoError(t, err)
+	_, err = otherCodec.Decode(options, token)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Expired cursors are stale
+	codec.MaxAge = time.Minute
+	codec.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = codec.Decode(options, token)
+	assert.ErrorIs(t, err, ErrStaleCursor)
+
+	_, err = NewCursorCodec(nil)
+	assert.Error(t, err)
+}
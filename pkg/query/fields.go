@@ -0,0 +1,62 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// FieldsParam is the query parameter selecting the fields returned by list and get
+// endpoints (a sparse fieldset), e.g. ?fields=id,name,price
+const FieldsParam = "fields"
+
+// ParseFields returns the fields selected by the fields query parameter, by field
+// name. Names match fields and relations of the resource by name or alias in any
+// naming convention; unknown names are ignored. The ID field is always selected.
+// Nil means all fields are returned.
+func ParseFields(c *gin.Context, res resource.Resource) []string {
+	param := strings.TrimSpace(c.Query(FieldsParam))
+	if param == "" {
+		return nil
+	}
+	return SelectFields(res, strings.Split(param, ","))
+}
+
+// SelectFields returns the fields of a sparse fieldset given as a list of names,
+// matched like those of the fields parameter (see ParseFields)
+func SelectFields(res resource.Resource, names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	known := make(map[string]string)
+	for _, field := range res.GetFields() {
+		known[normalizeFieldName(field.Name)] = field.Name
+		known[normalizeFieldName(field.APIName())] = field.Name
+	}
+	for _, relation := range res.GetRelations() {
+		known[normalizeFieldName(relation.Name)] = relation.Name
+	}
+
+	idField := res.GetIDFieldName()
+	if name, ok := known[normalizeFieldName(idField)]; ok {
+		idField = name
+	}
+	fields := []string{idField}
+	selected := map[string]bool{idField: true}
+	for _, name := range names {
+		field, ok := known[normalizeFieldName(strings.TrimSpace(name))]
+		if !ok || selected[field] {
+			continue
+		}
+		selected[field] = true
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// normalizeFieldName normalizes a field name so names in any naming convention match
+func normalizeFieldName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
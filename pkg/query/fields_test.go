@@ -0,0 +1,40 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type fieldsProduct struct {
+	ID          uint
+	Name        string
+	UnitPrice   float64
+	Description string
+}
+
+func TestParseFields(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: fieldsProduct{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "unit_price", Type: "float", Alias: "price"},
+			{Name: "description", Type: "string"},
+		},
+	})
+
+	parse := func(url string) []string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", url, nil)
+		return ParseFields(c, res)
+	}
+
+	assert.Nil(t, parse("/products"))
+	assert.Equal(t, []string{"id", "name", "unit_price"}, parse("/products?fields=name,price,unknown"))
+	assert.Equal(t, []string{"id", "unit_price"}, parse("/products?fields=unitPrice,ID,unit_price"))
+}
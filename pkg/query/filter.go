@@ -77,6 +77,9 @@ const (
 
 	// Not between operator (NOT BETWEEN)
 	OperatorNotBetween FilterOperator = "nbetween"
+
+	// Has flag operator, for bit flag fields (column & mask = mask)
+	OperatorHasFlag FilterOperator = "has_flag"
 )
 
 // ResourceFilterConfig defines filter configuration for a resource
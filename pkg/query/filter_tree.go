@@ -0,0 +1,141 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// FilterNode is a filter of a filter tree, in the format of refine's CrudFilters: a
+// field filter ({"field", "operator", "value"}) or a group of filters joined by its
+// operator, "and" or "or" ({"operator": "or", "value": [...]})
+type FilterNode struct {
+	Filter
+
+	// Filters of "and" and "or" groups
+	Filters []FilterNode `json:"-"`
+}
+
+// IsGroup reports whether the node is a group of filters
+func (n FilterNode) IsGroup() bool {
+	operator := strings.ToLower(n.Operator)
+	return n.Field == "" && (operator == "and" || operator == "or")
+}
+
+// UnmarshalJSON decodes a field filter or a group of filters
+func (n *FilterNode) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Field    string          `json:"field"`
+		Operator string          `json:"operator"`
+		Value    json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*n = FilterNode{Filter: Filter{Field: raw.Field, Operator: raw.Operator}}
+
+	if n.IsGroup() {
+		if err := json.Unmarshal(raw.Value, &n.Filters); err != nil {
+			return fmt.Errorf("filters of %q group: %w", raw.Operator, err)
+		}
+		return nil
+	}
+	if raw.Field == "" {
+		return errors.New("filter without field")
+	}
+	if len(raw.Value) > 0 {
+		return json.Unmarshal(raw.Value, &n.Value)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the node in the format it is decoded from
+func (n FilterNode) MarshalJSON() ([]byte, error) {
+	if n.IsGroup() {
+		return json.Marshal(map[string]interface{}{"operator": n.Operator, "value": n.Filters})
+	}
+	return json.Marshal(n.Filter)
+}
+
+// expandFilterTreeMacros resolves the filter macros of the field filters of a tree,
+// see ExpandFilterMacros
+func expandFilterTreeMacros(nodes []FilterNode, loc *time.Location) []FilterNode {
+	expanded := make([]FilterNode, len(nodes))
+	for i, node := range nodes {
+		switch {
+		case node.IsGroup():
+			node.Filters = expandFilterTreeMacros(node.Filters, loc)
+		case IsFilterMacro(node.Value):
+			// A macro becomes a range of two filters, which must both match
+			group := FilterNode{Filter: Filter{Operator: "and"}}
+			for _, filter := range ExpandFilterMacros([]Filter{node.Filter}, loc) {
+				group.Filters = append(group.Filters, FilterNode{Filter: filter})
+			}
+			node = group
+		}
+		expanded[i] = node
+	}
+	return expanded
+}
+
+// applyFilterTree keeps the records matching every filter of a tree
+func applyFilterTree(tx *gorm.DB, nodes []FilterNode, res resource.Resource) *gorm.DB {
+	for _, node := range nodes {
+		condition := filterNodeCondition(tx, node, res)
+		if condition != nil && condition.Error != nil {
+			return withError(tx, condition.Error)
+		}
+		if condition != nil {
+			tx = tx.Where(condition)
+		}
+	}
+	return tx
+}
+
+// filterNodeCondition returns the condition of a filter node as a group condition,
+// or nil if it has none: filters on unknown fields are ignored, like other advanced
+// filters, and so are empty groups. Conditions of rejected filters have their error.
+func filterNodeCondition(tx *gorm.DB, node FilterNode, res resource.Resource) *gorm.DB {
+	condition := tx.Session(&gorm.Session{NewDB: true})
+
+	if !node.IsGroup() {
+		if f, column, ok := fieldColumn(res, node.Field); ok {
+			return whereFilter(condition, *f, fmt.Sprintf("`%s`", column), node.Filter)
+		}
+		if relation, field, ok := relationFilterPath(res, node.Field); ok {
+			condition = applyRelationFilter(condition, res, relation, field, node.Filter)
+			if _, ok := condition.Statement.Clauses["WHERE"]; ok || condition.Error != nil {
+				return condition
+			}
+		}
+		return nil
+	}
+
+	or := strings.ToLower(node.Operator) == "or"
+	empty := true
+	for _, child := range node.Filters {
+		childCondition := filterNodeCondition(tx, child, res)
+		if childCondition == nil {
+			continue
+		}
+		if childCondition.Error != nil {
+			// Rejected filters fail the whole tree
+			return childCondition
+		}
+		if or && !empty {
+			condition = condition.Or(childCondition)
+		} else {
+			condition = condition.Where(childCondition)
+		}
+		empty = false
+	}
+	if empty {
+		return nil
+	}
+	return condition
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type treeTask struct {
+	ID       uint
+	Title    string
+	Status   string
+	Priority int
+	Due      time.Time
+	Tags     []filterTag `gorm:"many2many:tree_task_tags" relation:"resource=tags;type=many-to-many"`
+}
+
+func TestFilterNodeJSON(t *testing.T) {
+	var nodes []FilterNode
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"field": "Status", "operator": "eq", "value": "open"},
+		{"operator": "or", "value": [
+			{"field": "Priority", "operator": "gte", "value": 3},
+			{"operator": "and", "value": [{"field": "Title", "operator": "contains", "value": "urgent"}]}
+		]}
+	]`), &nodes))
+	require.Len(t, nodes, 2)
+	assert.Equal(t, Filter{Field: "Status", Operator: "eq", Value: "open"}, nodes[0].Filter)
+	assert.True(t, nodes[1].IsGroup())
+	require.Len(t, nodes[1].Filters, 2)
+	assert.Equal(t, float64(3), nodes[1].Filters[0].Value)
+	assert.Equal(t, "urgent", nodes[1].Filters[1].Filters[0].Value)
+
+	encoded, err := json.Marshal(nodes[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"operator": "or", "value": [
+		{"field": "Priority", "operator": "gte", "value": 3},
+		{"operator": "and", "value": [{"field": "Title", "operator": "contains", "value": "urgent"}]}
+	]}`, string(encoded))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"operator": "eq", "value": 1}`), &FilterNode{}))
+	assert.Error(t, json.Unmarshal([]byte(`{"operator": "or", "value": 1}`), &FilterNode{}))
+}
+
+func TestFilterTree(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&filterTag{}, &treeTask{}))
+
+	now := time.Now().UTC()
+	urgent := filterTag{Name: "urgent"}
+	require.NoError(t, db.Create(&urgent).Error)
+	require.NoError(t, db.Create([]*treeTask{
+		{Title: "a", Status: "open", Priority: 1, Due: now},
+		{Title: "b", Status: "open", Priority: 5, Due: now.AddDate(0, 0, -10)},
+		{Title: "c", Status: "done", Priority: 5, Due: now},
+		{Title: "d", Status: "open", Priority: 2, Due: now.AddDate(0, 0, -10), Tags: []filterTag{urgent}},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "tasks", Model: treeTask{}})
+	titles := func(tree string) []string {
+		var nodes []FilterNode
+		require.NoError(t, json.Unmarshal([]byte(tree), &nodes))
+		options := QueryOptions{Resource: res, FilterTree: nodes}
+
+		var tasks []treeTask
+		require.NoError(t, options.Apply(db.Model(&treeTask{})).Order("id").Find(&tasks).Error)
+		result := []string{}
+		for _, task := range tasks {
+			result = append(result, task.Title)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"b", "d"}, titles(`[
+		{"field": "Status", "operator": "eq", "value": "open"},
+		{"operator": "or", "value": [
+			{"field": "Priority", "operator": "gte", "value": 5},
+			{"field": "tags.name", "operator": "eq", "value": "urgent"}
+		]}
+	]`))
+
+	// Filter macros, and unknown fields which are ignored
+	assert.Equal(t, []string{"a", "c"}, titles(`[
+		{"field": "Due", "operator": "eq", "value": "@today"},
+		{"operator": "or", "value": [{"field": "missing", "operator": "eq", "value": 1}]}
+	]`))
+}
@@ -0,0 +1,36 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm/clause"
+)
+
+// hasFlagCondition returns the condition of a has_flag filter on a bit flag field,
+// keeping the records with all the named flags set. The value is a flag name, names
+// separated by commas or an array of names.
+func hasFlagCondition(field resource.Field, column string, value interface{}) (clause.Expression, error) {
+	if field.Flags == nil {
+		return nil, fmt.Errorf("%s only applies to flag fields", OperatorHasFlag)
+	}
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = strings.Split(v, ",")
+	case []string:
+		names = v
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, fmt.Sprint(item))
+		}
+	default:
+		names = []string{fmt.Sprint(v)}
+	}
+	mask, err := field.Flags.Mask(names)
+	if err != nil {
+		return nil, err
+	}
+	return clause.Expr{SQL: fmt.Sprintf("(%s & ?) = ?", column), Vars: []interface{}{mask, mask}}, nil
+}
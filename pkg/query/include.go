@@ -0,0 +1,249 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// IncludeParam is the query parameter selecting the relations loaded with records,
+// with optional modifiers per relation, e.g.
+// ?include=author,items(sort=created_at desc,limit=5,filter[status]=active)
+const IncludeParam = "include"
+
+// Include is a relation loaded with records
+type Include struct {
+	// Relation is the name of the relation
+	Relation string `json:"relation"`
+
+	// Sort and Order sort the related records (Order is "asc" or "desc")
+	Sort  string `json:"sort,omitempty"`
+	Order string `json:"order,omitempty"`
+
+	// Limit bounds the number of related records per record; zero means no limit
+	Limit int `json:"limit,omitempty"`
+
+	// Filters keep the related records whose fields equal the values
+	Filters map[string]string `json:"filters,omitempty"`
+}
+
+// hasModifiers reports whether the related records are sorted, limited or filtered
+func (i Include) hasModifiers() bool {
+	return i.Sort != "" || i.Limit > 0 || len(i.Filters) > 0
+}
+
+// ParseIncludes returns the relations selected by the include query parameter.
+// Relations match by name in any naming convention; unknown relations and malformed
+// modifiers are ignored. Nil means no relation was requested.
+func ParseIncludes(c *gin.Context, res resource.Resource) []Include {
+	return ParseIncludeList(res, c.Query(IncludeParam))
+}
+
+// ParseIncludeList returns the relations of an include list in the format of the
+// include parameter (see ParseIncludes)
+func ParseIncludeList(res resource.Resource, param string) []Include {
+	param = strings.TrimSpace(param)
+	if param == "" {
+		return nil
+	}
+
+	relations := make(map[string]string)
+	for _, relation := range res.GetRelations() {
+		relations[normalizeFieldName(relation.Name)] = relation.Name
+	}
+
+	var includes []Include
+	for _, item := range splitTopLevel(param) {
+		name, modifiers, hasModifiers := strings.Cut(item, "(")
+		relation, ok := relations[normalizeFieldName(strings.TrimSpace(name))]
+		if !ok {
+			continue
+		}
+		include := Include{Relation: relation}
+		if hasModifiers {
+			parseIncludeModifiers(&include, strings.TrimSuffix(strings.TrimSpace(modifiers), ")"))
+		}
+		includes = append(includes, include)
+	}
+	return includes
+}
+
+// parseIncludeModifiers parses the comma separated modifiers of an include:
+// sort=field [asc|desc], order=asc|desc, limit=n and filter[field]=value
+func parseIncludeModifiers(include *Include, modifiers string) {
+	for _, modifier := range strings.Split(modifiers, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(modifier), "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case key == "sort":
+			field, order, _ := strings.Cut(value, " ")
+			include.Sort = field
+			if order = strings.ToLower(strings.TrimSpace(order)); order == "asc" || order == "desc" {
+				include.Order = order
+			}
+		case key == "order":
+			if order := strings.ToLower(value); order == "asc" || order == "desc" {
+				include.Order = order
+			}
+		case key == "limit":
+			if limit, err := strconv.Atoi(value); err == nil && limit > 0 {
+				include.Limit = limit
+			}
+		case strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]"):
+			if include.Filters == nil {
+				include.Filters = make(map[string]string)
+			}
+			include.Filters[key[len("filter["):len(key)-1]] = value
+		}
+	}
+}
+
+// splitTopLevel splits s on commas outside parentheses
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// ApplyIncludes preloads the included relations of model. Modifiers are applied
+// through Preload scopes: filters and sorting to every relation, limits per record
+// to one-to-many relations. Sort and filter fields that are not fields of the related
+// model are ignored.
+func ApplyIncludes(tx *gorm.DB, model interface{}, includes []Include) *gorm.DB {
+	if len(includes) == 0 {
+		return tx
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return tx
+	}
+	for _, include := range includes {
+		relationship, ok := stmt.Schema.Relationships.Relations[include.Relation]
+		if !ok {
+			continue
+		}
+		if !include.hasModifiers() {
+			tx = tx.Preload(include.Relation)
+			continue
+		}
+		tx = tx.Preload(include.Relation, includeScope(include, relationship))
+	}
+	return tx
+}
+
+// includeScope returns the Preload scope applying the modifiers of an include
+func includeScope(include Include, relationship *schema.Relationship) func(*gorm.DB) *gorm.DB {
+	related := relationship.FieldSchema
+
+	var filters []clause.Expression
+	for name, value := range include.Filters {
+		if field := lookUpIncludeField(related, name); field != nil {
+			filters = append(filters, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+		}
+	}
+
+	var orderBy []clause.OrderByColumn
+	if field := lookUpIncludeField(related, include.Sort); field != nil {
+		orderBy = append(orderBy, clause.OrderByColumn{Column: clause.Column{Name: field.DBName}, Desc: include.Order == "desc"})
+	}
+	primaryKey := related.PrioritizedPrimaryField
+	if primaryKey != nil {
+		// Stable order of related records with equal sort values
+		orderBy = append(orderBy, clause.OrderByColumn{Column: clause.Column{Name: primaryKey.DBName}})
+	}
+
+	// The foreign key partitions one-to-many relations per record
+	var foreignKey string
+	if relationship.Type == schema.HasMany {
+		for _, reference := range relationship.References {
+			if reference.OwnPrimaryKey && reference.ForeignKey != nil {
+				foreignKey = reference.ForeignKey.DBName
+			}
+		}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filters) > 0 {
+			db = db.Where(clause.And(filters...))
+		}
+		for _, column := range orderBy {
+			db = db.Order(column)
+		}
+		if include.Limit == 0 || foreignKey == "" || primaryKey == nil {
+			return db
+		}
+
+		// Keep the first records of each parent with ROW_NUMBER(), since a plain
+		// LIMIT would bound the related records of all parents together
+		order := make([]string, len(orderBy))
+		for i, column := range orderBy {
+			order[i] = db.Statement.Quote(column.Column)
+			if column.Desc {
+				order[i] += " DESC"
+			}
+		}
+		ranked := db.Session(&gorm.Session{NewDB: true}).
+			Model(reflect.New(related.ModelType).Interface()).
+			Select(fmt.Sprintf("%s, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS include_rank",
+				db.Statement.Quote(primaryKey.DBName), db.Statement.Quote(foreignKey), strings.Join(order, ", ")))
+		if len(filters) > 0 {
+			ranked = ranked.Where(clause.And(filters...))
+		}
+		first := db.Session(&gorm.Session{NewDB: true}).
+			Table("(?) AS ranked", ranked).
+			Select(primaryKey.DBName).
+			Where("include_rank <= ?", include.Limit)
+		return db.Where(db.Statement.Quote(primaryKey.DBName)+" IN (?)", first)
+	}
+}
+
+// lookUpIncludeField returns the field of a related model by name, column or JSON
+// name in any naming convention, or nil. Fields not serialized to JSON (json:"-") are
+// never returned, so queries cannot filter or sort on hidden columns.
+func lookUpIncludeField(related *schema.Schema, name string) *schema.Field {
+	if name == "" {
+		return nil
+	}
+	if field := related.LookUpField(name); field != nil && field.DBName != "" {
+		if field.Tag.Get("json") == "-" {
+			return nil
+		}
+		return field
+	}
+	normalized := normalizeFieldName(name)
+	for _, field := range related.Fields {
+		if field.DBName == "" || field.Tag.Get("json") == "-" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if normalizeFieldName(field.Name) == normalized || normalizeFieldName(field.DBName) == normalized || (jsonName != "" && normalizeFieldName(jsonName) == normalized) {
+			return field
+		}
+	}
+	return nil
+}
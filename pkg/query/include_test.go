@@ -0,0 +1,96 @@
+package query
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type includeOrder struct {
+	ID         uint
+	Number     string
+	Items      []includeItem `gorm:"foreignKey:OrderID" relation:"resource=items;type=one-to-many"`
+	Customer   *includeCustomer
+	CustomerID uint
+}
+
+type includeItem struct {
+	ID       uint
+	OrderID  uint
+	Name     string
+	Status   string
+	Position int `json:"position"`
+}
+
+type includeCustomer struct {
+	ID   uint
+	Name string
+}
+
+func TestParseIncludes(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{Name: "orders", Model: includeOrder{}})
+
+	parse := func(include string) []Include {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/orders?include="+url.QueryEscape(include), nil)
+		return ParseIncludes(c, res)
+	}
+
+	assert.Nil(t, parse(""))
+	assert.Equal(t, []Include{{Relation: "Items"}}, parse("items,unknown"))
+	assert.Equal(t, []Include{
+		{Relation: "Items", Sort: "position", Order: "desc", Limit: 2, Filters: map[string]string{"status": "active"}},
+		{Relation: "Customer"},
+	}, parse("items(sort=position desc,limit=2,filter[status]=active),customer"))
+
+	// Malformed modifiers are ignored
+	assert.Equal(t, []Include{{Relation: "Items", Order: "asc"}}, parse("items(limit=-1,order=asc,bogus)"))
+}
+
+func TestApplyIncludes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&includeCustomer{}, &includeOrder{}, &includeItem{}))
+
+	for o := 1; o <= 2; o++ {
+		order := includeOrder{Number: fmt.Sprintf("O-%d", o), Customer: &includeCustomer{Name: "Ann"}}
+		for i := 1; i <= 4; i++ {
+			status := "active"
+			if i == 4 {
+				status = "cancelled"
+			}
+			order.Items = append(order.Items, includeItem{Name: fmt.Sprintf("%d-%d", o, i), Status: status, Position: i})
+		}
+		require.NoError(t, db.Create(&order).Error)
+	}
+
+	var orders []includeOrder
+	includes := []Include{
+		{Relation: "Items", Sort: "position", Order: "desc", Limit: 2, Filters: map[string]string{"status": "active"}},
+		{Relation: "Customer"},
+	}
+	require.NoError(t, ApplyIncludes(db, includeOrder{}, includes).Order("id").Find(&orders).Error)
+	require.Len(t, orders, 2)
+
+	// Limits apply per order, after filtering and sorting
+	for o, order := range orders {
+		require.Len(t, order.Items, 2)
+		assert.Equal(t, fmt.Sprintf("%d-3", o+1), order.Items[0].Name)
+		assert.Equal(t, fmt.Sprintf("%d-2", o+1), order.Items[1].Name)
+		require.NotNil(t, order.Customer)
+	}
+
+	// Without modifiers all related records are loaded
+	orders = nil
+	require.NoError(t, ApplyIncludes(db, includeOrder{}, []Include{{Relation: "Items"}}).Find(&orders).Error)
+	assert.Len(t, orders[0].Items, 4)
+	assert.Nil(t, orders[0].Customer)
+}
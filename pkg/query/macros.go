@@ -0,0 +1,294 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FilterMacroPrefix marks a filter value as a server-resolved macro (e.g. "@today")
+const FilterMacroPrefix = "@"
+
+// TimezoneParam is the query parameter used to select the timezone for macro resolution
+const TimezoneParam = "tz"
+
+// TimezoneHeader is the header used to select the timezone when no query parameter is given
+const TimezoneHeader = "X-Timezone"
+
+// MacroRange is a half-open time range [Start, End) produced by a filter macro
+type MacroRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FilterMacro defines a named value that is resolved on the server at query time
+type FilterMacro struct {
+	// Name of the macro without the prefix (e.g. "today")
+	Name string
+
+	// Label for display in quick filter menus
+	Label string
+
+	// Description of the resolved range
+	Description string
+
+	// Resolve returns the time range for the given reference time.
+	// The reference time is already converted to the requested timezone.
+	Resolve func(now time.Time) MacroRange
+}
+
+// FilterMacroMetadata describes a filter macro for the frontend
+type FilterMacroMetadata struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+var (
+	filterMacros      = make(map[string]FilterMacro)
+	filterMacrosMutex sync.RWMutex
+
+	// nowFunc returns the current time; replaced in tests
+	nowFunc = time.Now
+)
+
+func init() {
+	for _, macro := range defaultFilterMacros() {
+		RegisterFilterMacro(macro)
+	}
+}
+
+// RegisterFilterMacro adds a filter macro to the registry, replacing any macro with the same name
+func RegisterFilterMacro(macro FilterMacro) {
+	filterMacrosMutex.Lock()
+	defer filterMacrosMutex.Unlock()
+	filterMacros[strings.ToLower(macro.Name)] = macro
+}
+
+// GetFilterMacro returns a registered filter macro by name (with or without prefix)
+func GetFilterMacro(name string) (FilterMacro, bool) {
+	filterMacrosMutex.RLock()
+	defer filterMacrosMutex.RUnlock()
+	macro, ok := filterMacros[strings.ToLower(strings.TrimPrefix(name, FilterMacroPrefix))]
+	return macro, ok
+}
+
+// GetFilterMacrosMetadata returns metadata for all registered macros, sorted by name
+func GetFilterMacrosMetadata() []FilterMacroMetadata {
+	filterMacrosMutex.RLock()
+	defer filterMacrosMutex.RUnlock()
+
+	result := make([]FilterMacroMetadata, 0, len(filterMacros))
+	for name, macro := range filterMacros {
+		result = append(result, FilterMacroMetadata{
+			Name:        name,
+			Value:       FilterMacroPrefix + name,
+			Label:       macro.Label,
+			Description: macro.Description,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// IsFilterMacro checks if a value refers to a registered filter macro
+func IsFilterMacro(value interface{}) bool {
+	str, ok := value.(string)
+	if !ok || !strings.HasPrefix(str, FilterMacroPrefix) {
+		return false
+	}
+	_, exists := GetFilterMacro(str)
+	return exists
+}
+
+// ResolveFilterMacro resolves a macro value (e.g. "@today") in the given location
+func ResolveFilterMacro(value string, loc *time.Location) (MacroRange, bool) {
+	macro, ok := GetFilterMacro(value)
+	if !ok || !strings.HasPrefix(value, FilterMacroPrefix) {
+		return MacroRange{}, false
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return macro.Resolve(nowFunc().In(loc)), true
+}
+
+// ExpandFilterMacros replaces filters with macro values by equivalent range filters.
+// Filters without macros are returned unchanged.
+func ExpandFilterMacros(filters []Filter, loc *time.Location) []Filter {
+	result := make([]Filter, 0, len(filters))
+
+	for _, filter := range filters {
+		str, ok := filter.Value.(string)
+		if !ok {
+			result = append(result, filter)
+			continue
+		}
+
+		rng, ok := ResolveFilterMacro(str, loc)
+		if !ok {
+			result = append(result, filter)
+			continue
+		}
+
+		switch strings.ToLower(filter.Operator) {
+		case string(OperatorGreaterThan):
+			result = append(result, Filter{Field: filter.Field, Operator: string(OperatorGreaterThanEqual), Value: rng.End})
+		case string(OperatorGreaterThanEqual):
+			result = append(result, Filter{Field: filter.Field, Operator: string(OperatorGreaterThanEqual), Value: rng.Start})
+		case string(OperatorLessThan):
+			result = append(result, Filter{Field: filter.Field, Operator: string(OperatorLessThan), Value: rng.Start})
+		case string(OperatorLessThanEqual):
+			result = append(result, Filter{Field: filter.Field, Operator: string(OperatorLessThan), Value: rng.End})
+		default:
+			// Equality (and any other operator) matches the whole range
+			result = append(result,
+				Filter{Field: filter.Field, Operator: string(OperatorGreaterThanEqual), Value: rng.Start},
+				Filter{Field: filter.Field, Operator: string(OperatorLessThan), Value: rng.End},
+			)
+		}
+	}
+
+	return result
+}
+
+// ErrInvalidTimezone is returned for timezone names that are not IANA timezones
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// ParseTimezone resolves a timezone name, falling back to UTC for empty or unknown names
+func ParseTimezone(name string) *time.Location {
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LoadTimezone resolves a timezone name, UTC for an empty name. Unknown names return an
+// error wrapping ErrInvalidTimezone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// startOfDay returns midnight of the day containing t
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns midnight of the Monday of the week containing t
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// startOfMonth returns midnight of the first day of the month containing t
+func startOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// defaultFilterMacros returns the built-in filter macros
+func defaultFilterMacros() []FilterMacro {
+	return []FilterMacro{
+		{
+			Name:        "today",
+			Label:       "Today",
+			Description: "From the start of today until the start of tomorrow",
+			Resolve: func(now time.Time) MacroRange {
+				start := startOfDay(now)
+				return MacroRange{Start: start, End: start.AddDate(0, 0, 1)}
+			},
+		},
+		{
+			Name:        "yesterday",
+			Label:       "Yesterday",
+			Description: "The whole previous day",
+			Resolve: func(now time.Time) MacroRange {
+				end := startOfDay(now)
+				return MacroRange{Start: end.AddDate(0, 0, -1), End: end}
+			},
+		},
+		{
+			Name:        "tomorrow",
+			Label:       "Tomorrow",
+			Description: "The whole next day",
+			Resolve: func(now time.Time) MacroRange {
+				start := startOfDay(now).AddDate(0, 0, 1)
+				return MacroRange{Start: start, End: start.AddDate(0, 0, 1)}
+			},
+		},
+		{
+			Name:        "last_7_days",
+			Label:       "Last 7 days",
+			Description: "The last 7 days including today",
+			Resolve: func(now time.Time) MacroRange {
+				end := startOfDay(now).AddDate(0, 0, 1)
+				return MacroRange{Start: end.AddDate(0, 0, -7), End: end}
+			},
+		},
+		{
+			Name:        "last_30_days",
+			Label:       "Last 30 days",
+			Description: "The last 30 days including today",
+			Resolve: func(now time.Time) MacroRange {
+				end := startOfDay(now).AddDate(0, 0, 1)
+				return MacroRange{Start: end.AddDate(0, 0, -30), End: end}
+			},
+		},
+		{
+			Name:        "current_week",
+			Label:       "This week",
+			Description: "From Monday of the current week until next Monday",
+			Resolve: func(now time.Time) MacroRange {
+				start := startOfWeek(now)
+				return MacroRange{Start: start, End: start.AddDate(0, 0, 7)}
+			},
+		},
+		{
+			Name:        "current_month",
+			Label:       "This month",
+			Description: "From the first day of the current month until the first day of the next month",
+			Resolve: func(now time.Time) MacroRange {
+				start := startOfMonth(now)
+				return MacroRange{Start: start, End: start.AddDate(0, 1, 0)}
+			},
+		},
+		{
+			Name:        "last_month",
+			Label:       "Last month",
+			Description: "The whole previous calendar month",
+			Resolve: func(now time.Time) MacroRange {
+				end := startOfMonth(now)
+				return MacroRange{Start: end.AddDate(0, -1, 0), End: end}
+			},
+		},
+		{
+			Name:        "current_year",
+			Label:       "This year",
+			Description: "From January 1st of the current year until January 1st of the next year",
+			Resolve: func(now time.Time) MacroRange {
+				start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+				return MacroRange{Start: start, End: start.AddDate(1, 0, 0)}
+			},
+		},
+	}
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func withFixedNow(t *testing.T, now time.Time) {
+	original := nowFunc
+	nowFunc = func() time.Time { return now }
+	t.Cleanup(func() { nowFunc = original })
+}
+
+func TestResolveFilterMacro(t *testing.T) {
+	// Wednesday, 2024-05-15 10:30 UTC
+	withFixedNow(t, time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC))
+
+	t.Run("today", func(t *testing.T) {
+		rng, ok := ResolveFilterMacro("@today", time.UTC)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), rng.Start)
+		assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), rng.End)
+	})
+
+	t.Run("last 7 days", func(t *testing.T) {
+		rng, ok := ResolveFilterMacro("@last_7_days", time.UTC)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC), rng.Start)
+		assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), rng.End)
+	})
+
+	t.Run("current week starts on monday", func(t *testing.T) {
+		rng, ok := ResolveFilterMacro("@current_week", time.UTC)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), rng.Start)
+	})
+
+	t.Run("current month", func(t *testing.T) {
+		rng, ok := ResolveFilterMacro("@current_month", time.UTC)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), rng.Start)
+		assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), rng.End)
+	})
+
+	t.Run("timezone aware", func(t *testing.T) {
+		// 10:30 UTC is already the next day in UTC+14
+		loc := time.FixedZone("UTC+14", 14*3600)
+		rng, ok := ResolveFilterMacro("@today", loc)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, loc), rng.Start)
+	})
+
+	t.Run("unknown macro", func(t *testing.T) {
+		_, ok := ResolveFilterMacro("@someday", time.UTC)
+		assert.False(t, ok)
+	})
+
+	t.Run("missing prefix", func(t *testing.T) {
+		_, ok := ResolveFilterMacro("today", time.UTC)
+		assert.False(t, ok)
+	})
+}
+
+func TestExpandFilterMacros(t *testing.T) {
+	withFixedNow(t, time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC))
+	start := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)
+
+	filters := ExpandFilterMacros([]Filter{
+		{Field: "due", Operator: "eq", Value: "@today"},
+		{Field: "created_at", Operator: "lt", Value: "@today"},
+		{Field: "updated_at", Operator: "lte", Value: "@today"},
+		{Field: "name", Operator: "eq", Value: "@home"},
+	}, time.UTC)
+
+	assert.Equal(t, []Filter{
+		{Field: "due", Operator: "gte", Value: start},
+		{Field: "due", Operator: "lt", Value: end},
+		{Field: "created_at", Operator: "lt", Value: start},
+		{Field: "updated_at", Operator: "lt", Value: end},
+		{Field: "name", Operator: "eq", Value: "@home"},
+	}, filters)
+}
+
+func TestNewQueryOptionsWithMacros(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	withFixedNow(t, time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC))
+	res := createTestResource()
+
+	c, _ := createTestContext("name=@today&tz=Europe/Warsaw")
+	options := ParseQueryOptions(c, res)
+
+	assert.NotContains(t, options.Filters, "name")
+	assert.Len(t, options.AdvancedFilters, 2)
+	assert.Equal(t, "Europe/Warsaw", options.Timezone.String())
+	assert.Equal(t, "gte", options.AdvancedFilters[0].Operator)
+	assert.Equal(t, "lt", options.AdvancedFilters[1].Operator)
+
+	c, _ = createTestContext("name=@nothing")
+	options = ParseQueryOptions(c, res)
+	assert.Equal(t, "@nothing", options.Filters["name"])
+	assert.Equal(t, time.UTC, options.Timezone)
+	assert.NoError(t, options.Validate())
+
+	c, _ = createTestContext("name=@today&tz=Mars/Olympus")
+	options = ParseQueryOptions(c, res)
+	assert.Equal(t, time.UTC, options.Timezone)
+	assert.ErrorIs(t, options.Validate(), ErrInvalidTimezone)
+}
+
+func TestGetFilterMacrosMetadata(t *testing.T) {
+	RegisterFilterMacro(FilterMacro{
+		Name:  "fiscal_year",
+		Label: "Fiscal year",
+		Resolve: func(now time.Time) MacroRange {
+			return MacroRange{Start: now, End: now}
+		},
+	})
+
+	metadata := GetFilterMacrosMetadata()
+	found := false
+	for _, m := range metadata {
+		if m.Name == "fiscal_year" {
+			found = true
+			assert.Equal(t, "@fiscal_year", m.Value)
+			assert.Equal(t, "Fiscal year", m.Label)
+		}
+	}
+	assert.True(t, found)
+	assert.True(t, IsFilterMacro("@fiscal_year"))
+	assert.False(t, IsFilterMacro(42))
+}
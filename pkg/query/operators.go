@@ -0,0 +1,120 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// OperatorFunc returns the condition of a filter with a custom operator on a field,
+// e.g. clause.Expr{SQL: column + " @> ?", Vars: []interface{}{value}}. column is the
+// quoted column of the field. Returning an error rejects the filter, e.g. for fields
+// the operator doesn't apply to or invalid values; the query then fails with a
+// *FilterError.
+type OperatorFunc func(field resource.Field, column string, value interface{}) (clause.Expression, error)
+
+// FilterError is the error of queries with a filter rejected by its custom operator
+type FilterError struct {
+	Field    string
+	Operator string
+	Err      error
+}
+
+// Error returns the message of the error
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filter %s on %s: %v", e.Operator, e.Field, e.Err)
+}
+
+// Unwrap returns the error of the operator
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	customOperators      = make(map[string]OperatorFunc)
+	customOperatorsMutex sync.RWMutex
+)
+
+// RegisterOperator adds a custom filter operator, e.g. for geo distances, arrays or
+// JSONB path queries, replacing any custom operator with the same name. Operator names
+// are case-insensitive and the built-in operators can't be replaced. Filters use
+// custom operators like built-in ones, in every filter format and in filter trees.
+func RegisterOperator(name string, fn OperatorFunc) {
+	name = strings.ToLower(name)
+	if appliedOperators[name] {
+		panic(fmt.Sprintf("filter operator %q is built in", name))
+	}
+	customOperatorsMutex.Lock()
+	defer customOperatorsMutex.Unlock()
+	customOperators[name] = fn
+}
+
+// UnregisterOperator removes a custom filter operator
+func UnregisterOperator(name string) {
+	customOperatorsMutex.Lock()
+	defer customOperatorsMutex.Unlock()
+	delete(customOperators, strings.ToLower(name))
+}
+
+// RegisteredOperators returns the names of the custom filter operators, sorted
+func RegisteredOperators() []string {
+	customOperatorsMutex.RLock()
+	defer customOperatorsMutex.RUnlock()
+	names := make([]string, 0, len(customOperators))
+	for name := range customOperators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// customOperator returns the custom operator of a name
+func customOperator(name string) (OperatorFunc, bool) {
+	customOperatorsMutex.RLock()
+	defer customOperatorsMutex.RUnlock()
+	fn, ok := customOperators[strings.ToLower(name)]
+	return fn, ok
+}
+
+// whereFilter adds the condition of a filter on a field and its quoted column to a
+// query. Filters rejected by their custom operator, or has_flag filters with unknown
+// flags, add a *FilterError to it.
+func whereFilter(tx *gorm.DB, field resource.Field, column string, filter Filter) *gorm.DB {
+	fn, ok := customOperator(filter.Operator)
+	if strings.EqualFold(filter.Operator, string(OperatorHasFlag)) {
+		fn, ok = hasFlagCondition, true
+	}
+	if !ok {
+		condition, args := filterCondition(column, filter)
+		return tx.Where(condition, args...)
+	}
+	expression, err := fn(field, column, filter.Value)
+	if err != nil {
+		return withError(tx, &FilterError{Field: filter.Field, Operator: filter.Operator, Err: err})
+	}
+	return tx.Where(expression)
+}
+
+// withError returns a query failing with err. The error is added to a new session, so
+// the database the query was built from keeps working.
+func withError(tx *gorm.DB, err error) *gorm.DB {
+	tx = tx.Session(&gorm.Session{})
+	tx.AddError(err)
+	return tx
+}
+
+// relatedField describes a field of related records for custom operators, named by
+// its JSON name
+func relatedField(field *schema.Field) resource.Field {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		name = field.DBName
+	}
+	return resource.Field{Name: name, Type: string(field.DataType)}
+}
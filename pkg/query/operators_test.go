@@ -0,0 +1,95 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// longerThan keeps the records whose string field is longer than the value
+func longerThan(field resource.Field, column string, value interface{}) (clause.Expression, error) {
+	if field.Type != "string" {
+		return nil, fmt.Errorf("longer_than only applies to text fields")
+	}
+	var length int
+	if _, err := fmt.Sscan(fmt.Sprint(value), &length); err != nil {
+		return nil, fmt.Errorf("longer_than needs a number")
+	}
+	return clause.Expr{SQL: "LENGTH(" + column + ") > ?", Vars: []interface{}{length}}, nil
+}
+
+func TestRegisterOperator(t *testing.T) {
+	RegisterOperator("LONGER_THAN", longerThan)
+	defer UnregisterOperator("longer_than")
+	assert.Equal(t, []string{"longer_than"}, RegisteredOperators())
+	assert.Panics(t, func() { RegisterOperator("contains", longerThan) })
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&filterTag{}, &treeTask{}))
+	require.NoError(t, db.Create([]*treeTask{
+		{Title: "short", Priority: 1, Due: time.Now(), Tags: []filterTag{{Name: "go"}}},
+		{Title: "much longer", Priority: 2, Due: time.Now(), Tags: []filterTag{{Name: "databases"}}},
+	}).Error)
+	res := resource.NewResource(resource.ResourceConfig{Name: "tasks", Model: treeTask{}})
+
+	find := func(options QueryOptions) ([]string, error) {
+		options.Resource = res
+		var tasks []treeTask
+		err := options.Apply(db.Model(&treeTask{})).Order("id").Find(&tasks).Error
+		titles := []string{}
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles, err
+	}
+
+	titles, err := find(QueryOptions{AdvancedFilters: []Filter{{Field: "Title", Operator: "longer_than", Value: "5"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"much longer"}, titles)
+
+	// Filter trees and relation filters use custom operators too
+	var nodes []FilterNode
+	require.NoError(t, json.Unmarshal([]byte(`[{"operator": "or", "value": [
+		{"field": "Priority", "operator": "eq", "value": 1},
+		{"field": "Title", "operator": "longer_than", "value": 5}
+	]}]`), &nodes))
+	titles, err = find(QueryOptions{FilterTree: nodes})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"short", "much longer"}, titles)
+
+	titles, err = find(QueryOptions{AdvancedFilters: []Filter{{Field: "tags.name", Operator: "longer_than", Value: 3}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"much longer"}, titles)
+
+	// Rejected filters fail the query without breaking the database
+	_, err = find(QueryOptions{AdvancedFilters: []Filter{{Field: "Priority", Operator: "longer_than", Value: 1}}})
+	var filterErr *FilterError
+	require.True(t, errors.As(err, &filterErr), "%v", err)
+	assert.Equal(t, "Priority", filterErr.Field)
+	assert.EqualError(t, filterErr.Err, "longer_than only applies to text fields")
+
+	require.NoError(t, json.Unmarshal([]byte(`[{"operator": "and", "value": [
+		{"field": "Title", "operator": "longer_than", "value": "many"}
+	]}]`), &nodes))
+	_, err = find(QueryOptions{FilterTree: nodes})
+	assert.True(t, errors.As(err, &filterErr), "%v", err)
+
+	titles, err = find(QueryOptions{})
+	require.NoError(t, err)
+	assert.Len(t, titles, 2)
+
+	// Applied queries don't report custom operators as unknown
+	applied := QueryOptions{Resource: res, AdvancedFilters: []Filter{{Field: "Title", Operator: "Longer_Than", Value: "5"}}}.AppliedQuery(nil)
+	assert.Empty(t, applied.Ignored)
+	assert.Equal(t, "longer_than", applied.Filters[0].Operator)
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/resource"
@@ -27,6 +28,10 @@ type QueryOptions struct {
 	// Disable pagination for count operations
 	DisablePagination bool
 
+	// CountMode is how List counts the records (exact if empty), see
+	// resource.CountMode
+	CountMode resource.CountMode
+
 	// Search parameters
 	Search string
 
@@ -36,9 +41,27 @@ type QueryOptions struct {
 	// Advanced filters (with operators)
 	AdvancedFilters []Filter
 
+	// Filter tree with "and" and "or" groups, e.g. from the body of a count request
+	FilterTree []FilterNode
+
 	// Sorting
 	Sort  string
 	Order string
+
+	// Timezone used to resolve filter macros (e.g. "@today")
+	Timezone *time.Location
+
+	// Fields selected by the request (a sparse fieldset); all fields if empty
+	Fields []string
+
+	// Relations loaded with the records, with their modifiers
+	Includes []Include
+
+	// Sorts and filters dropped by Validate, reported by AppliedQuery
+	Ignored []IgnoredParam
+
+	// Error of the requested timezone, returned by Validate
+	timezoneErr error
 }
 
 // NewQueryOptions creates a new QueryOptions from a gin context
@@ -87,9 +110,21 @@ func NewQueryOptions(c *gin.Context, res resource.Resource) QueryOptions {
 		}
 	}
 
+	// Cap the page size
+	opt.LimitPageSize()
+
+	// Parse the count mode
+	opt.CountMode = ParseCountMode(c, res)
+
 	// Parse search
 	opt.Search = c.DefaultQuery("q", "")
 
+	// Parse the sparse fieldset
+	opt.Fields = ParseFields(c, res)
+
+	// Parse the included relations
+	opt.Includes = ParseIncludes(c, res)
+
 	// Parse filters
 	opt.Filters = make(map[string]interface{})
 	// Get filterable fields from resource
@@ -112,14 +147,17 @@ func NewQueryOptions(c *gin.Context, res resource.Resource) QueryOptions {
 	opt.AdvancedFilters = make([]Filter, 0)
 
 	// Parse Refine.dev advanced filters
-	// Format 1: filter[field][operator]=value
+	// Format 1: filter[field][operator]=value, or filter[field]=value for equality
 	for key, values := range c.Request.URL.Query() {
 		// Check if it's a filter parameter
-		if strings.HasPrefix(key, "filter[") && strings.Contains(key, "][") && strings.HasSuffix(key, "]") {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
 			// Extract field and operator from the key
 			key = strings.TrimPrefix(key, "filter[")
 			key = strings.TrimSuffix(key, "]")
 			parts := strings.Split(key, "][")
+			if len(parts) == 1 {
+				parts = append(parts, "eq")
+			}
 
 			if len(parts) == 2 && len(values) > 0 {
 				field := parts[0]
@@ -166,6 +204,27 @@ func NewQueryOptions(c *gin.Context, res resource.Resource) QueryOptions {
 		}
 	}
 
+	// Resolve filter macros (e.g. created_at=@last_7_days) into range filters
+	tz := c.Query(TimezoneParam)
+	if tz == "" {
+		tz = c.GetHeader(TimezoneHeader)
+	}
+	if opt.Timezone, opt.timezoneErr = LoadTimezone(tz); opt.timezoneErr != nil {
+		opt.Timezone = time.UTC
+	}
+
+	for field, value := range opt.Filters {
+		if IsFilterMacro(value) {
+			delete(opt.Filters, field)
+			opt.AdvancedFilters = append(opt.AdvancedFilters, Filter{
+				Field:    field,
+				Operator: string(OperatorEqual),
+				Value:    value,
+			})
+		}
+	}
+	opt.AdvancedFilters = ExpandFilterMacros(opt.AdvancedFilters, opt.Timezone)
+
 	// Parse sorting - support both standard (sort, order) and Refine.dev formats
 	if sort := c.DefaultQuery("sort", ""); sort != "" {
 		// Check if this is a multiple sort fields request (comma-separated)
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/gorm"
 )
 
@@ -69,3 +70,15 @@ func ExtractPaginate(c *gin.Context) PaginateOption {
 		PerPage: perPage,
 	}
 }
+
+// LimitPageSize lowers the page size of the options to the cap of their resource, if
+// it has one (see resource.PageSizeResource)
+func (o *QueryOptions) LimitPageSize() {
+	capped, ok := o.Resource.(resource.PageSizeResource)
+	if !ok {
+		return
+	}
+	if limit := capped.GetMaxPageSize(); limit > 0 && o.PerPage > limit {
+		o.PerPage = limit
+	}
+}
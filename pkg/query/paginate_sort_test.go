@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -93,3 +94,38 @@ func TestToResult(t *testing.T) {
 	assert.Equal(t, data, res["data"])
 	assert.Equal(t, meta, res["meta"])
 }
+
+func TestApplySortTiebreaker(t *testing.T) {
+	db := setupInMemoryDB(t)
+	res := createTestResource()
+	assert.NoError(t, db.Create(&[]TestModel{{ID: "6", Name: "Dan", Age: 30}, {ID: "7", Name: "Eve", Age: 30}}).Error)
+
+	toSQL := func(opts QueryOptions) string {
+		return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			var results []TestModel
+			return opts.ApplySortTiebreaker(opts.Apply(tx.Model(&TestModel{}))).Find(&results)
+		})
+	}
+	assert.Contains(t, toSQL(QueryOptions{Resource: res, Sort: "age", Order: "desc"}), "ORDER BY age desc,`test_models`.`id` DESC")
+	assert.Contains(t, toSQL(QueryOptions{Resource: res, Sort: "age desc, name asc"}), "ORDER BY age desc, name asc,`test_models`.`id`")
+	assert.Contains(t, toSQL(QueryOptions{Resource: res}), "ORDER BY `test_models`.`id`")
+	assert.NotContains(t, toSQL(QueryOptions{Resource: res, Sort: "id", Order: "desc"}), "`test_models`.`id`")
+
+	disabled := resource.NewResource(resource.ResourceConfig{Name: "tests", Model: TestModel{}, DisableSortTiebreaker: true})
+	assert.NotContains(t, toSQL(QueryOptions{Resource: disabled}), "ORDER BY")
+	owned := resource.NewOwnerResource(disabled, resource.OwnerConfig{})
+	assert.NotContains(t, toSQL(QueryOptions{Resource: owned}), "ORDER BY")
+
+	// Pages of records with equal sort values neither repeat nor skip records
+	var ids []string
+	for page := 1; page <= 4; page++ {
+		var results []TestModel
+		opts := QueryOptions{Resource: res, Sort: "age", Order: "asc", Page: page, PerPage: 2}
+		_, err := opts.ApplyWithPagination(db.Model(&TestModel{}), &results)
+		assert.NoError(t, err)
+		for _, result := range results {
+			ids = append(ids, result.ID)
+		}
+	}
+	assert.Equal(t, []string{"2", "5", "1", "6", "7", "4", "3"}, ids)
+}
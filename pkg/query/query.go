@@ -3,6 +3,7 @@ package query
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -16,45 +17,53 @@ func (o QueryOptions) Apply(tx *gorm.DB) *gorm.DB {
 	// Apply filters
 	for field, value := range o.Filters {
 		// Make sure field exists in resource schema
-		if f := o.Resource.GetField(field); f != nil {
-			tx = tx.Where(fmt.Sprintf("%s = ?", field), value)
+		if _, column, ok := fieldColumn(o.Resource, field); ok {
+			tx = tx.Where(fmt.Sprintf("%s = ?", column), value)
 		}
 	}
 
 	// Apply advanced filters
 	tx = applyAdvancedFilters(tx, o.AdvancedFilters, o.Resource)
 
+	// Apply the filter tree, resolving its filter macros
+	tx = applyFilterTree(tx, expandFilterTreeMacros(o.FilterTree, o.Timezone), o.Resource)
+
 	// Apply search if provided
-	if o.Search != "" && o.Resource.GetSearchable() != nil {
-		searchableFields := o.Resource.GetSearchable()
-		if len(searchableFields) > 0 {
-			var conditions []string
-			var values []interface{}
-			for _, field := range searchableFields {
-				conditions = append(conditions, fmt.Sprintf("%s LIKE ?", field))
-				values = append(values, fmt.Sprintf("%%%s%%", o.Search))
-			}
-			tx = tx.Where(strings.Join(conditions, " OR "), values...)
-		}
+	if o.Search != "" {
+		tx = applySearch(tx, o.Search, o.Resource)
 	}
 
-	// Apply sorting
-	if o.Sort != "" {
-		// Check if we have multiple sort fields (comma-separated)
-		if strings.Contains(o.Sort, ",") {
-			// The sort string already contains both field and order information
-			tx = tx.Order(o.Sort)
-		} else {
-			// Single sort field - validate existence in resource schema
-			if f := o.Resource.GetField(o.Sort); f != nil {
-				tx = tx.Order(fmt.Sprintf("%s %s", o.Sort, o.Order))
-			}
-		}
+	// Apply sorting, on fields of the resource only
+	if columns := o.sortColumns(); columns != "" {
+		tx = tx.Order(columns)
 	}
 
 	return tx
 }
 
+// AppliedFilters returns the filters Apply applies: the simple filters, as "eq"
+// filters sorted by field, then the advanced filters on fields of the resource and
+// of its relations
+func (o QueryOptions) AppliedFilters() []Filter {
+	var filters []Filter
+	for field, value := range o.Filters {
+		if _, _, ok := fieldColumn(o.Resource, field); ok {
+			filters = append(filters, Filter{Field: field, Operator: "eq", Value: value})
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Field < filters[j].Field
+	})
+	for _, filter := range o.AdvancedFilters {
+		if _, _, ok := fieldColumn(o.Resource, filter.Field); ok {
+			filters = append(filters, filter)
+		} else if _, _, ok := relationFilterPath(o.Resource, filter.Field); ok {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
 // ApplyWithPagination applies all query options including pagination to a GORM query
 // Returns the updated query and the total count of records before pagination
 func (o QueryOptions) ApplyWithPagination(tx *gorm.DB, dest interface{}) (int64, error) {
@@ -70,6 +79,7 @@ func (o QueryOptions) ApplyWithPagination(tx *gorm.DB, dest interface{}) (int64,
 
 	// Apply pagination if not disabled
 	if !o.DisablePagination && dest != nil {
+		tx = o.ApplySortTiebreaker(tx)
 		offset := (o.Page - 1) * o.PerPage
 		tx = tx.Offset(offset).Limit(o.PerPage)
 
@@ -82,60 +92,110 @@ func (o QueryOptions) ApplyWithPagination(tx *gorm.DB, dest interface{}) (int64,
 	return count, nil
 }
 
-// applyAdvancedFilters applies advanced filters with operators to a GORM query
+// applySearch keeps the records with the search term in a searchable field, or in a
+// searchable field of their related records (see joinSearchRelations)
+func applySearch(tx *gorm.DB, search string, res resource.Resource) *gorm.DB {
+	columns := res.GetSearchable()
+	tx, relationColumns := joinSearchRelations(tx, res)
+	columns = append(columns[:len(columns):len(columns)], relationColumns...)
+	if len(columns) == 0 {
+		return tx
+	}
+
+	conditions := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s LIKE ?", column)
+		values[i] = fmt.Sprintf("%%%s%%", search)
+	}
+	return tx.Where(strings.Join(conditions, " OR "), values...)
+}
+
+// applyAdvancedFilters applies advanced filters with operators to a GORM query.
+// Filters on fields of related records (tags.name) are applied by applyRelationFilter.
 func applyAdvancedFilters(tx *gorm.DB, filters []Filter, res resource.Resource) *gorm.DB {
 	for _, filter := range filters {
 		// Make sure field exists in resource schema
-		if f := res.GetField(filter.Field); f != nil {
-			// Apply based on operator
-			switch strings.ToLower(filter.Operator) {
-			case "eq":
-				tx = tx.Where(fmt.Sprintf("`%s` = ?", filter.Field), filter.Value)
-			case "ne":
-				tx = tx.Where(fmt.Sprintf("`%s` <> ?", filter.Field), filter.Value)
-			case "lt":
-				tx = tx.Where(fmt.Sprintf("`%s` < ?", filter.Field), filter.Value)
-			case "gt":
-				tx = tx.Where(fmt.Sprintf("`%s` > ?", filter.Field), filter.Value)
-			case "lte":
-				tx = tx.Where(fmt.Sprintf("`%s` <= ?", filter.Field), filter.Value)
-			case "gte":
-				tx = tx.Where(fmt.Sprintf("`%s` >= ?", filter.Field), filter.Value)
-			case "contains":
-				tx = tx.Where(fmt.Sprintf("`%s` LIKE ?", filter.Field), fmt.Sprintf("%%%v%%", filter.Value))
-			case "containsi":
-				tx = tx.Where(fmt.Sprintf("LOWER(`%s`) LIKE LOWER(?)", filter.Field), fmt.Sprintf("%%%v%%", filter.Value))
-			case "startswith":
-				tx = tx.Where(fmt.Sprintf("`%s` LIKE ?", filter.Field), fmt.Sprintf("%v%%", filter.Value))
-			case "endswith":
-				tx = tx.Where(fmt.Sprintf("`%s` LIKE ?", filter.Field), fmt.Sprintf("%%%v", filter.Value))
-			case "null":
-				value := filter.Value
-				boolValue, ok := value.(bool)
-				if ok && boolValue {
-					tx = tx.Where(fmt.Sprintf("`%s` IS NULL", filter.Field))
-				} else {
-					tx = tx.Where(fmt.Sprintf("`%s` IS NOT NULL", filter.Field))
-				}
-			case "in":
-				// Handle array values
-				if reflect.TypeOf(filter.Value).Kind() == reflect.String {
-					// If string, split by comma
-					values := strings.Split(filter.Value.(string), ",")
-					tx = tx.Where(fmt.Sprintf("`%s` IN ?", filter.Field), values)
-				} else {
-					// Already an array/slice
-					tx = tx.Where(fmt.Sprintf("`%s` IN ?", filter.Field), filter.Value)
-				}
-			default:
-				// Default to equality
-				tx = tx.Where(fmt.Sprintf("`%s` = ?", filter.Field), filter.Value)
-			}
+		if f, column, ok := fieldColumn(res, filter.Field); ok {
+			tx = whereFilter(tx, *f, fmt.Sprintf("`%s`", column), filter)
+		} else if relation, field, ok := relationFilterPath(res, filter.Field); ok {
+			tx = applyRelationFilter(tx, res, relation, field, filter)
 		}
 	}
 	return tx
 }
 
+// fieldColumn returns a field of a resource and the column filters and sorts on it
+// use, if it has one (see resource.Field.Column)
+func fieldColumn(res resource.Resource, name string) (*resource.Field, string, bool) {
+	field := res.GetField(name)
+	if field == nil {
+		return nil, "", false
+	}
+	column, ok := field.Column()
+	return field, column, ok
+}
+
+// sortColumns returns the comma separated "column order" list of the sorts of the
+// options (see SortFields). Sorts on fields the resource doesn't have, or without a
+// column, are dropped, so no other name reaches the SQL.
+func (o QueryOptions) sortColumns() string {
+	if o.Resource == nil {
+		return ""
+	}
+	var parts []string
+	for _, sort := range o.SortFields() {
+		if _, column, ok := fieldColumn(o.Resource, sort.Field); ok {
+			parts = append(parts, column+" "+sort.Order)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// filterCondition returns the SQL condition of an advanced filter on a quoted column
+// and its arguments
+func filterCondition(column string, filter Filter) (string, []interface{}) {
+	switch strings.ToLower(filter.Operator) {
+	case "eq":
+		return column + " = ?", []interface{}{filter.Value}
+	case "ne":
+		return column + " <> ?", []interface{}{filter.Value}
+	case "lt":
+		return column + " < ?", []interface{}{filter.Value}
+	case "gt":
+		return column + " > ?", []interface{}{filter.Value}
+	case "lte":
+		return column + " <= ?", []interface{}{filter.Value}
+	case "gte":
+		return column + " >= ?", []interface{}{filter.Value}
+	case "contains":
+		return column + " LIKE ?", []interface{}{fmt.Sprintf("%%%v%%", filter.Value)}
+	case "containsi":
+		return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", column), []interface{}{fmt.Sprintf("%%%v%%", filter.Value)}
+	case "startswith":
+		return column + " LIKE ?", []interface{}{fmt.Sprintf("%v%%", filter.Value)}
+	case "endswith":
+		return column + " LIKE ?", []interface{}{fmt.Sprintf("%%%v", filter.Value)}
+	case "null":
+		boolValue, ok := filter.Value.(bool)
+		if ok && boolValue {
+			return column + " IS NULL", nil
+		}
+		return column + " IS NOT NULL", nil
+	case "in":
+		// Handle array values
+		if reflect.TypeOf(filter.Value).Kind() == reflect.String {
+			// If string, split by comma
+			return column + " IN ?", []interface{}{strings.Split(filter.Value.(string), ",")}
+		}
+		// Already an array/slice
+		return column + " IN ?", []interface{}{filter.Value}
+	default:
+		// Default to equality
+		return column + " = ?", []interface{}{filter.Value}
+	}
+}
+
 // ParseQueryOptions parses query parameters from gin context
 func ParseQueryOptions(c *gin.Context, res resource.Resource) QueryOptions {
 	return NewQueryOptions(c, res)
@@ -0,0 +1,121 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// relationFilterPath splits the field of a relation filter (tags.name) into the name of
+// a relation of the resource and the field of the related records. Relations match by
+// name in any naming convention.
+func relationFilterPath(res resource.Resource, field string) (relation, relatedField string, ok bool) {
+	name, relatedField, found := strings.Cut(field, ".")
+	if !found || relatedField == "" || strings.Contains(relatedField, ".") {
+		return "", "", false
+	}
+	for _, rel := range res.GetRelations() {
+		if normalizeFieldName(rel.Name) == normalizeFieldName(name) {
+			return rel.Name, relatedField, true
+		}
+	}
+	return "", "", false
+}
+
+// relationFieldFilterable reports whether the field of a relation filter is filterable:
+// whitelisted as relation.field in the filterable fields of the resource, or filterable
+// in the registered resource of the relation
+func relationFieldFilterable(res resource.Resource, relationName, relatedField string) bool {
+	path := normalizeFieldName(relationName) + "." + normalizeFieldName(relatedField)
+	for _, name := range res.GetFilterableFields() {
+		if rel, field, found := strings.Cut(name, "."); found && normalizeFieldName(rel)+"."+normalizeFieldName(field) == path {
+			return true
+		}
+	}
+
+	for _, rel := range res.GetRelations() {
+		if rel.Name != relationName {
+			continue
+		}
+		related, ok := resource.GlobalResourceRegistry.GetByName(rel.Resource)
+		if !ok {
+			return false
+		}
+		for _, name := range related.GetFilterableFields() {
+			if normalizeFieldName(name) == normalizeFieldName(relatedField) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyRelationFilter keeps the records with at least one related record matching the
+// filter. The related records, joined through the join table of many-to-many
+// relations, are selected in a subquery matched with IN, so records with several
+// matching related records are returned and counted once.
+func applyRelationFilter(tx *gorm.DB, res resource.Resource, relationName, fieldName string, filter Filter) *gorm.DB {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return tx
+	}
+	relationship, ok := stmt.Schema.Relationships.Relations[relationName]
+	if !ok {
+		return tx
+	}
+	related := relationship.FieldSchema
+	field := lookUpIncludeField(related, fieldName)
+	if field == nil {
+		return tx
+	}
+
+	quote := tx.Statement.Quote
+	matching := whereFilter(tx.Session(&gorm.Session{NewDB: true}).Model(reflect.New(related.ModelType).Interface()),
+		relatedField(field), quote(clause.Column{Table: related.Table, Name: field.DBName}), filter)
+	if matching.Error != nil {
+		return withError(tx, matching.Error)
+	}
+
+	// ownColumn is the column of the filtered records matched against the selected one
+	var ownColumn string
+	var selected clause.Column
+	switch relationship.Type {
+	case schema.Many2Many:
+		joinTable := relationship.JoinTable.Table
+		var on []string
+		for _, reference := range relationship.References {
+			joinColumn := clause.Column{Table: joinTable, Name: reference.ForeignKey.DBName}
+			if reference.OwnPrimaryKey {
+				ownColumn, selected = reference.PrimaryKey.DBName, joinColumn
+			} else {
+				on = append(on, quote(joinColumn)+" = "+quote(clause.Column{Table: related.Table, Name: reference.PrimaryKey.DBName}))
+			}
+		}
+		matching = matching.Joins("JOIN " + quote(joinTable) + " ON " + strings.Join(on, " AND "))
+	case schema.HasOne, schema.HasMany:
+		for _, reference := range relationship.References {
+			column := clause.Column{Table: related.Table, Name: reference.ForeignKey.DBName}
+			if reference.OwnPrimaryKey {
+				ownColumn, selected = reference.PrimaryKey.DBName, column
+			} else if reference.PrimaryValue != "" {
+				// Type column of polymorphic relations
+				matching = matching.Where(clause.Eq{Column: column, Value: reference.PrimaryValue})
+			}
+		}
+	case schema.BelongsTo:
+		for _, reference := range relationship.References {
+			if !reference.OwnPrimaryKey {
+				ownColumn, selected = reference.ForeignKey.DBName, clause.Column{Table: related.Table, Name: reference.PrimaryKey.DBName}
+			}
+		}
+	}
+	if ownColumn == "" {
+		return tx
+	}
+
+	return tx.Where(quote(clause.Column{Table: stmt.Schema.Table, Name: ownColumn})+" IN (?)", matching.Select(quote(selected)))
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type filterPost struct {
+	ID       uint
+	Title    string
+	Tags     []filterTag     `gorm:"many2many:filter_post_tags" relation:"resource=tags;type=many-to-many"`
+	Comments []filterComment `gorm:"foreignKey:PostID" relation:"resource=comments;type=one-to-many"`
+	Author   *filterAuthor   `relation:"resource=authors;type=many-to-one"`
+	AuthorID uint
+}
+
+type filterTag struct {
+	ID   uint
+	Name string
+}
+
+type filterComment struct {
+	ID     uint
+	PostID uint
+	Body   string
+}
+
+type filterAuthor struct {
+	ID     uint
+	Name   string
+	Secret string `json:"-"`
+}
+
+func TestRelationFilters(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&filterAuthor{}, &filterTag{}, &filterPost{}, &filterComment{}))
+
+	golang, gopher, rust := filterTag{Name: "Go"}, filterTag{Name: "Gopher"}, filterTag{Name: "Rust"}
+	require.NoError(t, db.Create([]*filterTag{&golang, &gopher, &rust}).Error)
+	ann := filterAuthor{Name: "Ann", Secret: "hunter2"}
+	require.NoError(t, db.Create(&ann).Error)
+	require.NoError(t, db.Create([]*filterPost{
+		{Title: "Go tips", Tags: []filterTag{golang, gopher}, Comments: []filterComment{{Body: "nice"}, {Body: "nice too"}}, AuthorID: ann.ID},
+		{Title: "Rust intro", Tags: []filterTag{rust}},
+		{Title: "Go and Rust", Tags: []filterTag{golang, rust}},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: filterPost{}})
+	list := func(rawQuery string) ([]string, int64) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/posts?"+rawQuery, nil)
+		options := NewQueryOptions(c, res)
+
+		var posts []filterPost
+		total, err := options.ApplyWithPagination(db.Model(&filterPost{}).Order("id"), &posts)
+		require.NoError(t, err)
+		titles := make([]string, len(posts))
+		for i, post := range posts {
+			titles[i] = post.Title
+		}
+		return titles, total
+	}
+
+	titles, total := list("filter[tags.name]=Go")
+	assert.Equal(t, []string{"Go tips", "Go and Rust"}, titles)
+	assert.Equal(t, int64(2), total)
+
+	// Posts with several matching tags are returned and counted once
+	titles, total = list("filter[tags.name][startswith]=Go")
+	assert.Equal(t, []string{"Go tips", "Go and Rust"}, titles)
+	assert.Equal(t, int64(2), total)
+
+	titles, total = list("filter[tags.name]=Rust&filters[Title]=Go+and+Rust")
+	assert.Equal(t, []string{"Go and Rust"}, titles)
+	assert.Equal(t, int64(1), total)
+
+	titles, _ = list("filter[comments.body][contains]=nice")
+	assert.Equal(t, []string{"Go tips"}, titles)
+
+	titles, _ = list("filter[author.name]=Ann")
+	assert.Equal(t, []string{"Go tips"}, titles)
+
+	// Unknown relations and fields are ignored, and so are hidden fields
+	_, total = list("filter[labels.name]=Go&filter[tags.color]=red")
+	assert.Equal(t, int64(3), total)
+	_, total = list("filter[author.secret][startswith]=hunter")
+	assert.Equal(t, int64(3), total)
+	_, total = list("filter[author.Secret]=hunter2")
+	assert.Equal(t, int64(3), total)
+}
+
+func TestUnknownParamsRelationFilters(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: filterPost{}})
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/posts?filter[tags.name]=Go&filter[Title][eq]=x&filter[tgas.name]=Go", nil)
+	unknown := UnknownParams(c.Request.URL.Query(), res)
+	require.Len(t, unknown, 1)
+	assert.Equal(t, "filter[tgas.name]", unknown[0].Name)
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// joinSearchRelations joins the related records holding the searchable relation fields
+// of the resource (see resource.ResourceConfig.SearchableRelationFields) and returns
+// their quoted columns to search. Each relation is joined once with a LEFT JOIN, so
+// records without related records are kept and no lookup runs per record. The related
+// records are selected in a derived table whose columns are renamed after the
+// relation, so they never clash with the unqualified columns of filters and sorts.
+// Only to-one relations are joined: to-many relations would repeat the records.
+func joinSearchRelations(tx *gorm.DB, res resource.Resource) (*gorm.DB, []string) {
+	searchable, ok := res.(resource.SearchableRelationsResource)
+	if !ok || len(searchable.GetSearchableRelationFields()) == 0 {
+		return tx, nil
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return tx, nil
+	}
+
+	// Searched fields by relation, in the declared order
+	var relationships []*schema.Relationship
+	fields := make(map[string][]*schema.Field)
+	for _, path := range searchable.GetSearchableRelationFields() {
+		relationName, fieldName, ok := relationFilterPath(res, path)
+		if !ok {
+			continue
+		}
+		relationship, ok := stmt.Schema.Relationships.Relations[relationName]
+		if !ok || (relationship.Type != schema.BelongsTo && relationship.Type != schema.HasOne) {
+			continue
+		}
+		field := lookUpIncludeField(relationship.FieldSchema, fieldName)
+		if field == nil || field.DBName == "" {
+			continue
+		}
+		searched, seen := fields[relationName]
+		if !seen {
+			relationships = append(relationships, relationship)
+		}
+		if !containsSchemaField(searched, field) {
+			fields[relationName] = append(searched, field)
+		}
+	}
+
+	quote := tx.Statement.Quote
+	var columns []string
+	for _, relationship := range relationships {
+		alias := "search_" + tx.NamingStrategy.ColumnName("", relationship.Name)
+		related := relationship.FieldSchema
+		joined := tx.Session(&gorm.Session{NewDB: true}).Model(reflect.New(related.ModelType).Interface())
+
+		var selects, on []string
+		for i, reference := range relationship.References {
+			var ownColumn, relatedColumn string
+			switch {
+			case relationship.Type == schema.BelongsTo && !reference.OwnPrimaryKey:
+				ownColumn, relatedColumn = reference.ForeignKey.DBName, reference.PrimaryKey.DBName
+			case relationship.Type == schema.HasOne && reference.OwnPrimaryKey:
+				ownColumn, relatedColumn = reference.PrimaryKey.DBName, reference.ForeignKey.DBName
+			case reference.PrimaryValue != "":
+				// Type column of polymorphic relations
+				joined = joined.Where(clause.Eq{Column: clause.Column{Table: related.Table, Name: reference.ForeignKey.DBName}, Value: reference.PrimaryValue})
+				continue
+			default:
+				continue
+			}
+			key := fmt.Sprintf("%s_key%d", alias, i)
+			selects = append(selects, quote(clause.Column{Table: related.Table, Name: relatedColumn})+" AS "+quote(key))
+			on = append(on, quote(clause.Column{Table: alias, Name: key})+" = "+quote(clause.Column{Table: stmt.Schema.Table, Name: ownColumn}))
+		}
+		if len(on) == 0 {
+			continue
+		}
+		for _, field := range fields[relationship.Name] {
+			column := alias + "_" + field.DBName
+			selects = append(selects, quote(clause.Column{Table: related.Table, Name: field.DBName})+" AS "+quote(column))
+			columns = append(columns, quote(clause.Column{Table: alias, Name: column}))
+		}
+
+		tx = tx.Joins("LEFT JOIN (?) AS "+quote(alias)+" ON "+strings.Join(on, " AND "), joined.Select(strings.Join(selects, ", ")))
+	}
+	return tx, columns
+}
+
+// containsSchemaField reports whether a field is in a list
+func containsSchemaField(fields []*schema.Field, field *schema.Field) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
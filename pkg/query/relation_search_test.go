@@ -0,0 +1,101 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type searchOrder struct {
+	ID         uint
+	Name       string
+	CustomerID *uint
+	Customer   *searchCustomer `relation:"resource=customers;type=many-to-one"`
+	Shipment   *searchShipment `gorm:"foreignKey:OrderID" relation:"resource=shipments;type=one-to-one"`
+	Lines      []searchLine    `gorm:"foreignKey:OrderID" relation:"resource=lines;type=one-to-many"`
+}
+
+type searchCustomer struct {
+	ID    uint
+	Name  string
+	Email string
+}
+
+type searchShipment struct {
+	ID      uint
+	OrderID uint
+	Carrier string
+}
+
+type searchLine struct {
+	ID      uint
+	OrderID uint
+	Product string
+}
+
+func TestRelationSearch(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&searchCustomer{}, &searchOrder{}, &searchShipment{}, &searchLine{}))
+
+	ann, bob := searchCustomer{Name: "Ann Smith", Email: "ann@example.com"}, searchCustomer{Name: "Bob Jones", Email: "bob@example.com"}
+	require.NoError(t, db.Create([]*searchCustomer{&ann, &bob}).Error)
+	require.NoError(t, db.Create([]*searchOrder{
+		{Name: "A-1", CustomerID: &ann.ID, Shipment: &searchShipment{Carrier: "DHL"}, Lines: []searchLine{{Product: "Smith chair"}, {Product: "Desk"}}},
+		{Name: "A-2", CustomerID: &ann.ID},
+		{Name: "B-1", CustomerID: &bob.ID, Shipment: &searchShipment{Carrier: "UPS"}},
+		{Name: "Smith special"},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:                     "orders",
+		Model:                    searchOrder{},
+		SearchableFields:         []string{"name"},
+		SearchableRelationFields: []string{"customer.name", "customer.email", "shipment.carrier", "lines.product", "customer.missing"},
+	})
+	list := func(rawQuery string) ([]string, int64) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/orders?"+rawQuery, nil)
+		options := NewQueryOptions(c, res)
+
+		var orders []searchOrder
+		total, err := options.ApplyWithPagination(db.Model(&searchOrder{}), &orders)
+		require.NoError(t, err)
+		names := make([]string, len(orders))
+		for i, order := range orders {
+			names[i] = order.Name
+		}
+		return names, total
+	}
+
+	// Own fields and fields of the customer, keeping orders without a customer
+	names, total := list("q=smith&sort=id&order=asc")
+	assert.Equal(t, []string{"A-1", "A-2", "Smith special"}, names)
+	assert.Equal(t, int64(3), total)
+
+	names, _ = list("q=bob@")
+	assert.Equal(t, []string{"B-1"}, names)
+
+	names, _ = list("q=ups")
+	assert.Equal(t, []string{"B-1"}, names)
+
+	// Columns of the resource stay unambiguous in filters and sorts
+	names, total = list("q=smith&filters[Name]=A-2&sort=name&order=desc")
+	assert.Equal(t, []string{"A-2"}, names)
+	assert.Equal(t, int64(1), total)
+
+	// To-many relations are not searched
+	names, _ = list("q=chair")
+	assert.Empty(t, names)
+
+	// Owner resources search the relation fields of the wrapped resource
+	res = resource.NewOwnerResource(res, resource.OwnerConfig{})
+	names, _ = list("q=bob@")
+	assert.Equal(t, []string{"B-1"}, names)
+}
@@ -1,8 +1,12 @@
 package query
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SortOrder defines sort direction
@@ -18,8 +22,8 @@ const (
 
 // SortOption represents a sorting option
 type SortOption struct {
-	Field string
-	Order string // "asc" or "desc"
+	Field string `json:"field"`
+	Order string `json:"order"` // "asc" or "desc"
 }
 
 // ApplySort applies sorting to a GORM query
@@ -36,6 +40,94 @@ func ApplySort(query *gorm.DB, sort SortOption) *gorm.DB {
 	return query
 }
 
+// ApplySortTiebreaker orders by the ID after the sort of the options, in the direction
+// of the last sort field. Records with equal sort values (e.g. the same status, or a
+// created_at with second precision) then keep the same order on every query, so pages
+// neither repeat nor skip records and the order can be used for keyset pagination. It
+// does nothing if the sort already includes the ID or the resource disables it
+// (resource.SortTiebreakerResource).
+func (o QueryOptions) ApplySortTiebreaker(tx *gorm.DB) *gorm.DB {
+	if o.Resource == nil {
+		return tx
+	}
+	if res, ok := o.Resource.(resource.SortTiebreakerResource); ok && res.IsSortTiebreakerDisabled() {
+		return tx
+	}
+
+	idField := o.Resource.GetIDFieldName()
+	if idField == "" {
+		idField = "id"
+	}
+	idColumn := tx.NamingStrategy.ColumnName("", idField)
+
+	sorts := o.SortFields()
+	for _, sort := range sorts {
+		if strings.EqualFold(sort.Field, idField) || strings.EqualFold(sort.Field, idColumn) {
+			return tx
+		}
+	}
+	desc := len(sorts) > 0 && sorts[len(sorts)-1].Order == string(SortOrderDesc)
+	return tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: idColumn}, Desc: desc})
+}
+
+// SortFields returns the fields the options sort by, as applied by Apply: each field
+// of a comma separated "field order" list, or the single sort field if the resource
+// has it
+func (o QueryOptions) SortFields() []SortOption {
+	if o.Sort == "" {
+		return nil
+	}
+	if !strings.Contains(o.Sort, ",") {
+		if o.Resource == nil || o.Resource.GetField(o.Sort) == nil {
+			return nil
+		}
+		order := strings.ToLower(o.Order)
+		if order != string(SortOrderDesc) {
+			order = string(SortOrderAsc)
+		}
+		return []SortOption{{Field: o.Sort, Order: order}}
+	}
+
+	var sorts []SortOption
+	for _, part := range strings.Split(o.Sort, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		order := string(SortOrderAsc)
+		if len(tokens) > 1 && strings.EqualFold(tokens[1], string(SortOrderDesc)) {
+			order = string(SortOrderDesc)
+		}
+		sorts = append(sorts, SortOption{Field: tokens[0], Order: order})
+	}
+	return sorts
+}
+
+// SetSorts sorts the options by a list of fields, like the comma separated sort and
+// order parameters. Fields the resource doesn't have are dropped, so they never reach
+// the SQL; the current sort is kept if none is left.
+func (o *QueryOptions) SetSorts(sorts []SortOption) {
+	var expressions []string
+	for _, sort := range sorts {
+		if o.Resource == nil || o.Resource.GetField(sort.Field) == nil {
+			continue
+		}
+		order := strings.ToLower(sort.Order)
+		if order != string(SortOrderDesc) {
+			order = string(SortOrderAsc)
+		}
+		expressions = append(expressions, sort.Field+" "+order)
+	}
+
+	switch len(expressions) {
+	case 0:
+	case 1:
+		o.Sort, o.Order, _ = strings.Cut(expressions[0], " ")
+	default:
+		o.Sort = strings.Join(expressions, ", ")
+	}
+}
+
 // ExtractSort extracts sorting options from HTTP query parameters
 func ExtractSort(c *gin.Context, defaultSort *SortOption) SortOption {
 	field := c.Query("sort")
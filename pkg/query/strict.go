@@ -0,0 +1,140 @@
+package query
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// ExplainParam is the query parameter requesting an explain report of the generated
+// SQL in debug mode (see package explain)
+const ExplainParam = "_explain"
+
+// StandardParams are the query parameters understood by the generic handlers
+var StandardParams = []string{
+	"current", "page", "pageSize", "per_page", WithCountParam,
+	"q", "sort", "order", TimezoneParam, ExplainParam,
+	"include", FieldsParam, "format", "columns", "groupBy", "metrics",
+	"pagination[current]", "pagination[pageSize]",
+	"sort[field]", "sort[order]",
+}
+
+var (
+	// filterParamPattern matches filter[field][operator], filter[field], filters[field] and
+	// operators[field]
+	filterParamPattern = regexp.MustCompile(`^(?:filter\[([^\]]+)\](?:\[[^\]]+\])?|filters\[([^\]]+)\]|operators\[([^\]]+)\])$`)
+
+	// sortParamPattern matches sort[0][field] and sort[0][order]
+	sortParamPattern = regexp.MustCompile(`^sort\[\d+\]\[(?:field|order)\]$`)
+)
+
+// UnknownParam is a query parameter not understood for a resource
+type UnknownParam struct {
+	Name string `json:"name"`
+
+	// Suggestion is the closest known parameter, if any is similar
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// UnknownParams returns the query parameters that are not standard parameters, filters
+// on fields of the resource or of its relations, or explicitly allowed, sorted by name
+func UnknownParams(values url.Values, res resource.Resource, allowed ...string) []UnknownParam {
+	known := make(map[string]bool)
+	for _, name := range StandardParams {
+		known[name] = true
+	}
+	for _, name := range allowed {
+		known[name] = true
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range res.GetFields() {
+		fields[field.Name] = true
+	}
+	for _, name := range res.GetFilterableFields() {
+		fields[name] = true
+	}
+
+	var unknown []UnknownParam
+	for name := range values {
+		if known[name] || fields[name] || sortParamPattern.MatchString(name) {
+			continue
+		}
+		if match := filterParamPattern.FindStringSubmatch(name); match != nil {
+			field := match[1] + match[2] + match[3]
+			if _, _, ok := relationFilterPath(res, field); fields[field] || ok {
+				continue
+			}
+			param := UnknownParam{Name: name}
+			if suggestion := Suggest(field, keys(fields)); suggestion != "" {
+				param.Suggestion = strings.Replace(name, field, suggestion, 1)
+			}
+			unknown = append(unknown, param)
+			continue
+		}
+
+		candidates := keys(fields)
+		for name := range known {
+			candidates = append(candidates, name)
+		}
+		unknown = append(unknown, UnknownParam{Name: name, Suggestion: Suggest(name, candidates)})
+	}
+
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Name < unknown[j].Name })
+	return unknown
+}
+
+// Suggest returns the candidate closest to name, or "" when none is similar. Names
+// are compared ignoring case, underscores and dashes, so "perPgae" suggests "per_page".
+func Suggest(name string, candidates []string) string {
+	normalized := normalizeName(name)
+	best, bestDistance := "", 3
+	if len(normalized) <= 3 {
+		bestDistance = 2
+	}
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if distance := editDistance(normalized, normalizeName(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// normalizeName makes names comparable across naming conventions
+func normalizeName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// keys returns the keys of a set
+func keys(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	return names
+}
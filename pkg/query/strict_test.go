@@ -0,0 +1,40 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestUnknownParams(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+		},
+	})
+
+	values, _ := url.ParseQuery("page=1&per_page=10&sort=title&order=asc&q=go&title=x" +
+		"&filter[title][contains]=a&filters[id]=1&operators[id]=eq&sort[0][field]=id&tz=UTC&debug=1")
+	assert.Equal(t, []UnknownParam{{Name: "debug"}}, UnknownParams(values, res))
+	assert.Empty(t, UnknownParams(values, res, "debug"))
+
+	values, _ = url.ParseQuery("perPgae=50&filter[titel][eq]=a&sortt=title")
+	assert.Equal(t, []UnknownParam{
+		{Name: "filter[titel][eq]", Suggestion: "filter[title][eq]"},
+		{Name: "perPgae", Suggestion: "per_page"},
+		{Name: "sortt", Suggestion: "sort"},
+	}, UnknownParams(values, res))
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"page", "pageSize", "per_page", "title"}
+	assert.Equal(t, "per_page", Suggest("perPgae", candidates))
+	assert.Equal(t, "pageSize", Suggest("page-size", candidates))
+	assert.Equal(t, "title", Suggest("TITEL", candidates))
+	assert.Equal(t, "", Suggest("somethingElse", candidates))
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// Reasons of the sorts and filters rejected by Validate
+const (
+	reasonUnknownFilterField = "unknown filter field"
+	reasonNotFilterable      = "field is not filterable"
+	reasonUnknownSortField   = "unknown sort field"
+	reasonNotSortable        = "field is not sortable"
+)
+
+// FieldError is the error of queries sorting or filtering on fields that are not
+// sortable or filterable, in the strict query validation (see QueryOptions.Validate)
+type FieldError struct {
+	// Fields are the rejected sort and filter fields with the reason
+	Fields []IgnoredParam
+}
+
+// Error returns the message of the error, naming the fields
+func (e *FieldError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		parts[i] = fmt.Sprintf("%q %s", field.Name, field.Reason)
+		if field.Suggestion != "" {
+			parts[i] += fmt.Sprintf(" (did you mean %q?)", field.Suggestion)
+		}
+	}
+	return "Invalid query: " + strings.Join(parts, ", ")
+}
+
+// Validate checks the sorts and filters of the options against the sortable and
+// filterable fields of the resource, before hooks add their own. Filters on the fields
+// of relations (tags.name) are allowed when whitelisted as relation.field or filterable
+// in the registered resource of the relation, and a sort on the default sort field is
+// always allowed. In the strict query validation (resource.QueryValidationStrict)
+// other sorts and filters are rejected with a *FieldError; otherwise they are dropped
+// and listed in Ignored.
+// Resources not implementing resource.QueryValidationResource are not validated.
+// An unknown timezone (see TimezoneParam) is always rejected with ErrInvalidTimezone.
+func (o *QueryOptions) Validate() error {
+	if o.timezoneErr != nil {
+		return o.timezoneErr
+	}
+	validated, ok := o.Resource.(resource.QueryValidationResource)
+	if !ok {
+		return nil
+	}
+
+	rejected := o.validateFilters()
+	rejected = append(rejected, o.validateSorts()...)
+	if len(rejected) == 0 {
+		return nil
+	}
+	if validated.GetQueryValidation() == resource.QueryValidationStrict {
+		return &FieldError{Fields: rejected}
+	}
+	o.Ignored = append(o.Ignored, rejected...)
+	return nil
+}
+
+// validateFilters drops the filters on fields that are not filterable, returning them
+func (o *QueryOptions) validateFilters() []IgnoredParam {
+	if len(o.Filters) == 0 && len(o.AdvancedFilters) == 0 && len(o.FilterTree) == 0 {
+		return nil
+	}
+	filterable := o.Resource.GetFilterableFields()
+	allowed := make(map[string]bool, len(filterable))
+	for _, name := range filterable {
+		allowed[name] = true
+	}
+
+	seen := make(map[string]bool)
+	var rejected []IgnoredParam
+	check := func(name string) bool {
+		reason := reasonUnknownFilterField
+		if relation, relatedField, ok := relationFilterPath(o.Resource, name); ok {
+			if relationFieldFilterable(o.Resource, relation, relatedField) {
+				return true
+			}
+			reason = reasonNotFilterable
+		} else if _, _, ok := fieldColumn(o.Resource, name); ok {
+			if allowed[name] {
+				return true
+			}
+			reason = reasonNotFilterable
+		}
+		if !seen[name] {
+			seen[name] = true
+			rejected = append(rejected, IgnoredParam{Name: name, Reason: reason, Suggestion: Suggest(name, append([]string(nil), filterable...))})
+		}
+		return false
+	}
+
+	for _, name := range sortedKeys(o.Filters) {
+		if !check(name) {
+			delete(o.Filters, name)
+		}
+	}
+	var advanced []Filter
+	for _, filter := range o.AdvancedFilters {
+		if check(filter.Field) {
+			advanced = append(advanced, filter)
+		}
+	}
+	o.AdvancedFilters = advanced
+	o.FilterTree = validateFilterTree(o.FilterTree, check)
+	return rejected
+}
+
+// validateFilterTree drops the filters of a tree failing check, and the groups left empty
+func validateFilterTree(nodes []FilterNode, check func(string) bool) []FilterNode {
+	var valid []FilterNode
+	for _, node := range nodes {
+		if node.IsGroup() {
+			node.Filters = validateFilterTree(node.Filters, check)
+			if len(node.Filters) == 0 {
+				continue
+			}
+		} else if !check(node.Field) {
+			continue
+		}
+		valid = append(valid, node)
+	}
+	return valid
+}
+
+// validateSorts drops the sorts on fields that are not sortable, returning them. The
+// default sort replaces a sort left without fields.
+func (o *QueryOptions) validateSorts() []IgnoredParam {
+	if o.Sort == "" {
+		return nil
+	}
+	sorts := []SortOption{{Field: o.Sort, Order: o.Order}}
+	if strings.Contains(o.Sort, ",") {
+		sorts = o.SortFields()
+	}
+
+	defaultSort := o.Resource.GetDefaultSort()
+	sortable := o.Resource.GetSortableFields()
+	allowed := make(map[string]bool, len(sortable))
+	for _, name := range sortable {
+		allowed[name] = true
+	}
+
+	var kept []SortOption
+	var rejected []IgnoredParam
+	for _, option := range sorts {
+		reason := ""
+		switch {
+		case defaultSort != nil && option.Field == defaultSort.Field:
+		case o.Resource.GetField(option.Field) == nil:
+			reason = reasonUnknownSortField
+		case !allowed[option.Field]:
+			reason = reasonNotSortable
+		}
+		if reason == "" {
+			kept = append(kept, option)
+			continue
+		}
+		rejected = append(rejected, IgnoredParam{Name: option.Field, Reason: reason, Suggestion: Suggest(option.Field, append([]string(nil), sortable...))})
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	o.Sort, o.Order = "", string(SortOrderAsc)
+	if len(kept) > 0 {
+		o.SetSorts(kept)
+	} else if defaultSort != nil {
+		o.Sort, o.Order = defaultSort.Field, defaultSort.Order
+	}
+	return rejected
+}
+
+// sortedKeys returns the keys of a filter map in order
+func sortedKeys(filters map[string]interface{}) []string {
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
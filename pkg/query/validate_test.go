@@ -0,0 +1,152 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func validationTestResource(validation resource.QueryValidation) resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "status", Type: "string"},
+			{Name: "secret", Type: "string"},
+		},
+		Relations: []resource.Relation{
+			{Name: "tags", Type: resource.RelationTypeManyToMany, Resource: "tags"},
+		},
+		FilterableFields: []string{"title", "status"},
+		SortableFields:   []string{"title"},
+		DefaultSort:      &resource.Sort{Field: "id", Order: "desc"},
+		QueryValidation:  validation,
+	})
+}
+
+// registerTags registers the resource of the tags relation of the validation test
+// resource, whose name is filterable
+func registerTags(t *testing.T) {
+	registry := resource.GlobalResourceRegistry
+	resource.GlobalResourceRegistry = resource.NewResourceRegistry()
+	t.Cleanup(func() { resource.GlobalResourceRegistry = registry })
+
+	resource.RegisterToRegistry(resource.NewResource(resource.ResourceConfig{
+		Name:  "tags",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "name", Type: "string"},
+			{Name: "color", Type: "string"},
+		},
+		FilterableFields: []string{"name"},
+	}))
+}
+
+func TestValidateLenient(t *testing.T) {
+	registerTags(t)
+	res := validationTestResource("")
+
+	c, _ := createTestContext("status=open&filter[secret][eq]=x&filter[titel][eq]=a&filter[tags.name][eq]=go" +
+		"&sort=title,secret&order=asc,desc")
+	options := ParseQueryOptions(c, res)
+	require.NoError(t, options.Validate())
+
+	assert.Equal(t, map[string]interface{}{"status": "open"}, options.Filters)
+	assert.ElementsMatch(t, []Filter{{Field: "tags.name", Operator: "eq", Value: "go"}}, options.AdvancedFilters)
+	assert.Equal(t, []SortOption{{Field: "title", Order: "asc"}}, options.SortFields())
+	assert.ElementsMatch(t, []IgnoredParam{
+		{Name: "secret", Reason: "field is not filterable", Suggestion: Suggest("secret", []string{"title", "status"})},
+		{Name: "titel", Reason: "unknown filter field", Suggestion: "title"},
+		{Name: "secret", Reason: "field is not sortable"},
+	}, options.Ignored)
+	assert.ElementsMatch(t, options.Ignored, options.AppliedQuery(c.Request.URL.Query()).Ignored)
+
+	// The default sort replaces a sort left without fields, and is allowed itself
+	c, _ = createTestContext("sort=status")
+	options = ParseQueryOptions(c, res)
+	require.NoError(t, options.Validate())
+	assert.Equal(t, []SortOption{{Field: "id", Order: "desc"}}, options.SortFields())
+
+	c, _ = createTestContext("sort=id,title&order=asc,desc")
+	options = ParseQueryOptions(c, res)
+	require.NoError(t, options.Validate())
+	assert.Equal(t, []SortOption{{Field: "id", Order: "asc"}, {Field: "title", Order: "desc"}}, options.SortFields())
+	assert.Empty(t, options.Ignored)
+
+	// Filter trees drop the filters on fields that are not filterable
+	options = ParseQueryOptions(c, res)
+	options.FilterTree = []FilterNode{
+		{Filter: Filter{Field: "title", Operator: "eq", Value: "a"}},
+		{Filter: Filter{Operator: "OR"}, Filters: []FilterNode{
+			{Filter: Filter{Field: "secret", Operator: "eq", Value: "b"}},
+		}},
+	}
+	require.NoError(t, options.Validate())
+	assert.Equal(t, []FilterNode{{Filter: Filter{Field: "title", Operator: "eq", Value: "a"}}}, options.FilterTree)
+}
+
+func TestValidateStrict(t *testing.T) {
+	registerTags(t)
+	res := validationTestResource(resource.QueryValidationStrict)
+
+	c, _ := createTestContext("filter[titel][eq]=a&sort=status")
+	options := ParseQueryOptions(c, res)
+	err := options.Validate()
+
+	var fieldErr *FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, []IgnoredParam{
+		{Name: "titel", Reason: "unknown filter field", Suggestion: "title"},
+		{Name: "status", Reason: "field is not sortable"},
+	}, fieldErr.Fields)
+	assert.Equal(t, `Invalid query: "titel" unknown filter field (did you mean "title"?), "status" field is not sortable`, err.Error())
+
+	c, _ = createTestContext("status=open&filter[tags.name][eq]=go&sort=title")
+	options = ParseQueryOptions(c, res)
+	assert.NoError(t, options.Validate())
+}
+
+func TestValidateRelationFilters(t *testing.T) {
+	registerTags(t)
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: struct{}{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+		},
+		Relations: []resource.Relation{
+			{Name: "tags", Type: resource.RelationTypeManyToMany, Resource: "tags"},
+			{Name: "author", Type: resource.RelationTypeManyToOne, Resource: "users"},
+		},
+		FilterableFields: []string{"title", "author.name"},
+		QueryValidation:  resource.QueryValidationStrict,
+	})
+
+	// Fields filterable in the related resource or whitelisted as relation.field
+	c, _ := createTestContext("filter[tags.name][eq]=go&filter[Author.Name][eq]=ann")
+	options := ParseQueryOptions(c, res)
+	require.NoError(t, options.Validate())
+
+	c, _ = createTestContext("filter[tags.color][eq]=red&filter[author.passwordHash][startswith]=a")
+	options = ParseQueryOptions(c, res)
+	var fieldErr *FieldError
+	require.ErrorAs(t, options.Validate(), &fieldErr)
+	assert.ElementsMatch(t, []string{"tags.color", "author.passwordHash"}, []string{fieldErr.Fields[0].Name, fieldErr.Fields[1].Name})
+	for _, field := range fieldErr.Fields {
+		assert.Equal(t, reasonNotFilterable, field.Reason)
+	}
+}
+
+func TestSortColumnsDropUnknownFields(t *testing.T) {
+	res := validationTestResource("")
+
+	options := QueryOptions{Resource: res}
+	options.SetSorts([]SortOption{{Field: "title", Order: "asc"}, {Field: "id", Order: "desc"}})
+	options.Sort += ",(SELECT 1)"
+	assert.Equal(t, "title asc, id desc", options.sortColumns())
+}
@@ -0,0 +1,296 @@
+package refinegin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// CheckStatus is the outcome of a single self-test check
+type CheckStatus string
+
+// Check statuses
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckFail CheckStatus = "fail"
+)
+
+// minJWTSecretLength is the minimum length of an HMAC secret considered safe
+const minJWTSecretLength = 32
+
+// CheckResult is the result of a single self-test check
+type CheckResult struct {
+	Name     string      `json:"name"`
+	Status   CheckStatus `json:"status"`
+	Problems []string    `json:"problems,omitempty"`
+}
+
+// Check is a custom self-test check (e.g. storage provider credentials).
+// Returning an error fails the check.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// DoctorOptions configures the self-test
+type DoctorOptions struct {
+	// Database to check connectivity and migrations for (optional)
+	DB *gorm.DB
+
+	// Models whose tables and columns must exist in the database
+	Models []interface{}
+
+	// Resources to validate (defaults to all resources in the global registry)
+	Resources []resource.Resource
+
+	// Routes registers the application routes on a fresh engine to detect conflicts (optional)
+	Routes func(r *gin.Engine)
+
+	// JWT configuration whose key material is validated (optional)
+	JWT *auth.JWTConfig
+
+	// Additional checks, e.g. storage provider credentials
+	Checks []Check
+}
+
+// DoctorReport is a structured self-test report
+type DoctorReport struct {
+	Results []CheckResult `json:"results"`
+}
+
+// OK reports whether no check failed
+func (r *DoctorReport) OK() bool {
+	for _, result := range r.Results {
+		if result.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// ExitCode returns the process exit code for the report (0 when OK, 1 otherwise)
+func (r *DoctorReport) ExitCode() int {
+	if r.OK() {
+		return 0
+	}
+	return 1
+}
+
+// Print writes a human readable report
+func (r *DoctorReport) Print(w io.Writer) {
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "[%s] %s\n", strings.ToUpper(string(result.Status)), result.Name)
+		for _, problem := range result.Problems {
+			fmt.Fprintf(w, "       - %s\n", problem)
+		}
+	}
+	if r.OK() {
+		fmt.Fprintln(w, "All checks passed")
+	} else {
+		fmt.Fprintln(w, "Self-test failed")
+	}
+}
+
+// add appends a check result; problems turn the check into the given status
+func (r *DoctorReport) add(name string, status CheckStatus, problems []string) {
+	if len(problems) == 0 {
+		status = CheckOK
+	}
+	r.Results = append(r.Results, CheckResult{Name: name, Status: status, Problems: problems})
+}
+
+// Doctor validates configuration and connectivity and returns a structured report
+func Doctor(ctx context.Context, opts DoctorOptions) *DoctorReport {
+	report := &DoctorReport{}
+
+	resources := opts.Resources
+	if resources == nil {
+		resources = resource.GlobalResourceRegistry.GetAll()
+	}
+	// Sort a copy, the caller's slice keeps its order
+	resources = append([]resource.Resource(nil), resources...)
+	sort.Slice(resources, func(i, j int) bool { return resources[i].GetName() < resources[j].GetName() })
+	report.add("resource definitions", CheckFail, checkResources(resources))
+
+	if opts.DB != nil {
+		connectivity := checkDatabase(ctx, opts.DB)
+		report.add("database connectivity", CheckFail, connectivity)
+		if len(connectivity) == 0 && len(opts.Models) > 0 {
+			report.add("database migrations", CheckFail, checkMigrations(opts.DB, opts.Models))
+		}
+	}
+
+	if opts.JWT != nil {
+		report.add("jwt key material", CheckFail, checkJWT(*opts.JWT))
+	}
+
+	if opts.Routes != nil {
+		report.add("route conflicts", CheckFail, checkRoutes(opts.Routes))
+	}
+
+	for _, check := range opts.Checks {
+		var problems []string
+		if err := check.Run(ctx); err != nil {
+			problems = append(problems, err.Error())
+		}
+		report.add(check.Name, CheckFail, problems)
+	}
+
+	return report
+}
+
+// RunDoctor runs the self-test, prints the report to stdout and exits with a
+// non-zero code on failure. Intended for CI and deploy-time gating.
+func RunDoctor(opts DoctorOptions) {
+	report := Doctor(context.Background(), opts)
+	report.Print(os.Stdout)
+	os.Exit(report.ExitCode())
+}
+
+// checkResources validates resource definitions
+func checkResources(resources []resource.Resource) []string {
+	var problems []string
+	names := make(map[string]bool)
+
+	for _, res := range resources {
+		name := res.GetName()
+		if name == "" {
+			problems = append(problems, "resource without a name")
+			continue
+		}
+		if names[name] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate resource name", name))
+		}
+		names[name] = true
+
+		if res.GetModel() == nil {
+			problems = append(problems, fmt.Sprintf("%s: missing model", name))
+			continue
+		}
+		if len(res.GetFields()) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no fields defined", name))
+			continue
+		}
+
+		hasField := func(field string) bool {
+			if res.GetField(field) != nil {
+				return true
+			}
+			for _, f := range res.GetFields() {
+				if strings.EqualFold(f.Name, field) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if idField := res.GetIDFieldName(); !hasField(idField) {
+			problems = append(problems, fmt.Sprintf("%s: ID field %q not found", name, idField))
+		}
+		if sort := res.GetDefaultSort(); sort != nil && !hasField(sort.Field) {
+			problems = append(problems, fmt.Sprintf("%s: default sort field %q not found", name, sort.Field))
+		}
+
+		lists := map[string][]string{
+			"searchable": res.GetSearchable(),
+			"filterable": res.GetFilterableFields(),
+			"sortable":   res.GetSortableFields(),
+		}
+		for _, kind := range []string{"searchable", "filterable", "sortable"} {
+			for _, field := range lists[kind] {
+				if !hasField(field) {
+					problems = append(problems, fmt.Sprintf("%s: %s field %q not found", name, kind, field))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkDatabase verifies the database is reachable
+func checkDatabase(ctx context.Context, db *gorm.DB) []string {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// checkMigrations verifies that tables and columns of all models exist
+func checkMigrations(db *gorm.DB, models []interface{}) []string {
+	var problems []string
+	migrator := db.Migrator()
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			problems = append(problems, fmt.Sprintf("%T: %v", model, err))
+			continue
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(model) {
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				problems = append(problems, fmt.Sprintf("column %s.%s is missing", table, field.DBName))
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkJWT validates the JWT signing secret or RSA keys
+func checkJWT(config auth.JWTConfig) []string {
+	var problems []string
+	switch {
+	case config.SigningMethod == "RS256":
+		if config.PrivateKey == nil && config.PublicKey == nil {
+			problems = append(problems, "RS256 requires a private or public key")
+		}
+	case config.Secret == "":
+		problems = append(problems, "secret is empty")
+	case config.Secret == auth.DefaultJWTConfig().Secret:
+		problems = append(problems, "secret is the insecure default value")
+	case len(config.Secret) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("secret is shorter than %d bytes", minJWTSecretLength))
+	}
+	if config.ExpirationTime <= 0 {
+		problems = append(problems, "expiration time must be positive")
+	}
+	return problems
+}
+
+// checkRoutes registers routes on a fresh engine, reporting registration panics
+// (duplicate routes, conflicting wildcards) as conflicts
+func checkRoutes(register func(r *gin.Engine)) (problems []string) {
+	mode := gin.Mode()
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(mode)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			problems = append(problems, fmt.Sprint(recovered))
+		}
+	}()
+	register(gin.New())
+	return nil
+}
@@ -0,0 +1,153 @@
+package refinegin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type DoctorTask struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+}
+
+type DoctorProject struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+func findResult(t *testing.T, report *DoctorReport, name string) CheckResult {
+	for _, result := range report.Results {
+		if result.Name == name {
+			return result
+		}
+	}
+	t.Fatalf("check %q not found", name)
+	return CheckResult{}
+}
+
+func TestDoctorPasses(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&DoctorTask{}))
+
+	jwtConfig := auth.DefaultJWTConfig()
+	jwtConfig.Secret = "0123456789abcdef0123456789abcdef"
+
+	report := Doctor(context.Background(), DoctorOptions{
+		DB:     db,
+		Models: []interface{}{&DoctorTask{}},
+		Resources: []resource.Resource{
+			resource.NewResource(resource.ResourceConfig{Name: "tasks", Model: DoctorTask{}}),
+		},
+		JWT: &jwtConfig,
+		Routes: func(r *gin.Engine) {
+			r.GET("/tasks", func(c *gin.Context) {})
+			r.GET("/tasks/:id", func(c *gin.Context) {})
+		},
+		Checks: []Check{{Name: "storage", Run: func(ctx context.Context) error { return nil }}},
+	})
+
+	assert.True(t, report.OK())
+	assert.Equal(t, 0, report.ExitCode())
+	assert.Len(t, report.Results, 6)
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(t, out.String(), "[OK] database migrations")
+	assert.Contains(t, out.String(), "All checks passed")
+}
+
+func TestDoctorReportsFailures(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	jwtConfig := auth.DefaultJWTConfig()
+
+	report := Doctor(context.Background(), DoctorOptions{
+		DB:     db,
+		Models: []interface{}{&DoctorProject{}},
+		Resources: []resource.Resource{
+			resource.NewResource(resource.ResourceConfig{
+				Name:             "tasks",
+				Model:            DoctorTask{},
+				SearchableFields: []string{"missing"},
+				DefaultSort:      &resource.Sort{Field: "created_at", Order: "desc"},
+			}),
+		},
+		JWT: &jwtConfig,
+		Routes: func(r *gin.Engine) {
+			r.GET("/tasks/:id", func(c *gin.Context) {})
+			r.GET("/tasks/:taskId/comments", func(c *gin.Context) {})
+		},
+		Checks: []Check{{Name: "storage", Run: func(ctx context.Context) error {
+			return errors.New("invalid credentials")
+		}}},
+	})
+
+	assert.False(t, report.OK())
+	assert.Equal(t, 1, report.ExitCode())
+
+	resources := findResult(t, report, "resource definitions")
+	assert.Equal(t, CheckFail, resources.Status)
+	assert.Contains(t, resources.Problems, `tasks: searchable field "missing" not found`)
+	assert.Contains(t, resources.Problems, `tasks: default sort field "created_at" not found`)
+
+	assert.Equal(t, CheckOK, findResult(t, report, "database connectivity").Status)
+	assert.Equal(t, []string{"table doctor_projects is missing"}, findResult(t, report, "database migrations").Problems)
+	assert.Equal(t, []string{"secret is the insecure default value"}, findResult(t, report, "jwt key material").Problems)
+	assert.Equal(t, CheckFail, findResult(t, report, "route conflicts").Status)
+	assert.Equal(t, []string{"invalid credentials"}, findResult(t, report, "storage").Problems)
+}
+
+func TestDoctorDetectsMissingColumns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE doctor_tasks (id integer primary key)").Error)
+
+	report := Doctor(context.Background(), DoctorOptions{
+		DB:        db,
+		Models:    []interface{}{&DoctorTask{}},
+		Resources: []resource.Resource{},
+	})
+	assert.Equal(t, []string{"column doctor_tasks.title is missing"}, findResult(t, report, "database migrations").Problems)
+}
+
+func TestDoctorDatabaseUnavailable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	report := Doctor(ctx, DoctorOptions{DB: db, Models: []interface{}{&DoctorTask{}}, Resources: []resource.Resource{}})
+
+	assert.Equal(t, CheckFail, findResult(t, report, "database connectivity").Status)
+	for _, result := range report.Results {
+		assert.NotEqual(t, "database migrations", result.Name, "migrations are not checked without a connection")
+	}
+}
+
+func TestDoctorKeepsResourceOrder(t *testing.T) {
+	resources := []resource.Resource{
+		resource.NewResource(resource.ResourceConfig{Name: "tasks", Model: DoctorTask{}}),
+		resource.NewResource(resource.ResourceConfig{Name: "projects", Model: DoctorProject{}}),
+	}
+
+	Doctor(context.Background(), DoctorOptions{Resources: resources})
+
+	assert.Equal(t, "tasks", resources[0].GetName())
+	assert.Equal(t, "projects", resources[1].GetName())
+}
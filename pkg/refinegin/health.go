@@ -0,0 +1,78 @@
+package refinegin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// Health statuses
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// HealthOptions configures the health endpoint
+type HealthOptions struct {
+	// Database pinged by every check (optional)
+	DB *gorm.DB
+
+	// Migrations reports the progress of the migrations of the replica (optional)
+	Migrations *resource.MigrationCoordinator
+
+	// Additional checks, e.g. storage provider connectivity
+	Checks []Check
+}
+
+// HealthResponse is the body of the health endpoint
+type HealthResponse struct {
+	Status     string                    `json:"status"`
+	Migrations *resource.MigrationStatus `json:"migrations,omitempty"`
+	Checks     []CheckResult             `json:"checks,omitempty"`
+}
+
+// HealthHandler returns a health endpoint for load balancers and readiness probes. It
+// answers 200 when the database is reachable, the checks pass and the migrations are
+// completed, and 503 Service Unavailable otherwise, e.g. while the replica waits for
+// another one to migrate the schema.
+func HealthHandler(opts HealthOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		response := HealthResponse{Status: HealthOK}
+		add := func(name string, problems []string) {
+			result := CheckResult{Name: name, Status: CheckOK, Problems: problems}
+			if len(problems) > 0 {
+				result.Status = CheckFail
+				response.Status = HealthUnavailable
+			}
+			response.Checks = append(response.Checks, result)
+		}
+
+		if opts.DB != nil {
+			add("database connectivity", checkDatabase(ctx, opts.DB))
+		}
+		for _, check := range opts.Checks {
+			var problems []string
+			if err := check.Run(ctx); err != nil {
+				problems = append(problems, err.Error())
+			}
+			add(check.Name, problems)
+		}
+		if opts.Migrations != nil {
+			status := opts.Migrations.Status()
+			response.Migrations = &status
+			if !status.Ready() {
+				response.Status = HealthUnavailable
+			}
+		}
+
+		code := http.StatusOK
+		if response.Status != HealthOK {
+			code = http.StatusServiceUnavailable
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(code, response)
+	}
+}
@@ -0,0 +1,64 @@
+package refinegin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	tasks := resource.NewResource(resource.ResourceConfig{Name: "doctor_tasks", Model: DoctorTask{}})
+	migrations := resource.NewMigrationCoordinator(db, tasks)
+	healthy := true
+	opts := HealthOptions{
+		DB:         db,
+		Migrations: migrations,
+		Checks: []Check{{Name: "storage", Run: func(ctx context.Context) error {
+			if !healthy {
+				return errors.New("bucket unreachable")
+			}
+			return nil
+		}}},
+	}
+	r := gin.New()
+	r.GET("/health", HealthHandler(opts))
+	get := func() (int, HealthResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var response HealthResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	// Unavailable until migrated
+	code, response := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthUnavailable, response.Status)
+	assert.Equal(t, resource.MigrationPending, response.Migrations.State)
+
+	require.NoError(t, migrations.Migrate(context.Background()))
+	code, response = get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthOK, response.Status)
+	assert.Equal(t, resource.MigrationCompleted, response.Migrations.State)
+	assert.Len(t, response.Checks, 2)
+
+	healthy = false
+	code, response = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, CheckFail, response.Checks[1].Status)
+	assert.Equal(t, []string{"bucket unreachable"}, response.Checks[1].Problems)
+}
@@ -0,0 +1,241 @@
+package refinegin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// Plugin errors
+var (
+	ErrDuplicatePlugin   = errors.New("duplicate plugin")
+	ErrMissingDependency = errors.New("missing plugin dependency")
+	ErrDependencyCycle   = errors.New("plugin dependency cycle")
+	ErrPluginConflict    = errors.New("conflicting plugins")
+	ErrRouteConflict     = errors.New("plugin route conflict")
+)
+
+// Plugin packages a feature (audit log, webhooks, file uploads, authentication, ...)
+// so applications enable it with a single UsePlugins call
+type Plugin interface {
+	// Name identifies the plugin. Names are unique within an application.
+	Name() string
+
+	// Init prepares the plugin, e.g. registers its resources in the registry. Plugins
+	// are initialized after the plugins they depend on.
+	Init(registry *resource.ResourceRegistry) error
+
+	// Routes registers the endpoints of the plugin
+	Routes(group *gin.RouterGroup)
+
+	// Hooks returns the middleware, background jobs, closers and checks of the plugin
+	Hooks() Hooks
+}
+
+// Hooks connect a plugin to the application lifecycle
+type Hooks struct {
+	// Middleware applied to the routes of every plugin
+	Middleware []gin.HandlerFunc
+
+	// Background jobs drained on shutdown (see RunOptions.Jobs)
+	Jobs []Job
+
+	// Resources closed on shutdown (see RunOptions.Closers)
+	Closers []Closer
+
+	// Self-test checks (see DoctorOptions.Checks)
+	Checks []Check
+}
+
+// DependentPlugin is implemented by plugins requiring other plugins
+type DependentPlugin interface {
+	// DependsOn returns the names of the plugins the plugin requires
+	DependsOn() []string
+}
+
+// ConflictingPlugin is implemented by plugins that cannot be enabled together with
+// others, e.g. two authentication plugins
+type ConflictingPlugin interface {
+	// ConflictsWith returns the names of the plugins the plugin conflicts with
+	ConflictsWith() []string
+}
+
+// FuncPlugin builds a Plugin from functions, for plugins without their own type
+type FuncPlugin struct {
+	PluginName  string
+	Requires    []string
+	Conflicts   []string
+	InitFunc    func(registry *resource.ResourceRegistry) error
+	RoutesFunc  func(group *gin.RouterGroup)
+	PluginHooks Hooks
+}
+
+// Name returns PluginName
+func (p *FuncPlugin) Name() string { return p.PluginName }
+
+// Init calls InitFunc, if set
+func (p *FuncPlugin) Init(registry *resource.ResourceRegistry) error {
+	if p.InitFunc == nil {
+		return nil
+	}
+	return p.InitFunc(registry)
+}
+
+// Routes calls RoutesFunc, if set
+func (p *FuncPlugin) Routes(group *gin.RouterGroup) {
+	if p.RoutesFunc != nil {
+		p.RoutesFunc(group)
+	}
+}
+
+// Hooks returns PluginHooks
+func (p *FuncPlugin) Hooks() Hooks { return p.PluginHooks }
+
+// DependsOn returns Requires
+func (p *FuncPlugin) DependsOn() []string { return p.Requires }
+
+// ConflictsWith returns Conflicts
+func (p *FuncPlugin) ConflictsWith() []string { return p.Conflicts }
+
+// PluginSet is a set of plugins enabled together, in dependency order
+type PluginSet struct {
+	plugins []Plugin
+}
+
+// UsePlugins enables plugins: it checks their names, dependencies and conflicts, orders
+// them so dependencies come first, initializes them with the registry (the global
+// registry if nil), then adds the middleware of all plugins to group and registers
+// their routes. Routes registered twice are reported as ErrRouteConflict. Errors are
+// meant to abort the application startup.
+func UsePlugins(group *gin.RouterGroup, registry *resource.ResourceRegistry, plugins ...Plugin) (*PluginSet, error) {
+	ordered, err := orderPlugins(plugins)
+	if err != nil {
+		return nil, err
+	}
+	if registry == nil {
+		registry = resource.GlobalResourceRegistry
+	}
+
+	for _, plugin := range ordered {
+		if err := plugin.Init(registry); err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", plugin.Name(), err)
+		}
+	}
+	for _, plugin := range ordered {
+		group.Use(plugin.Hooks().Middleware...)
+	}
+	for _, plugin := range ordered {
+		if err := registerPluginRoutes(group, plugin); err != nil {
+			return nil, err
+		}
+	}
+	return &PluginSet{plugins: ordered}, nil
+}
+
+// Plugins returns the plugins in dependency order
+func (s *PluginSet) Plugins() []Plugin {
+	return append([]Plugin(nil), s.plugins...)
+}
+
+// RunOptions returns opts with the jobs and closers of the plugins added. Jobs are
+// drained in dependency order, closers close dependent plugins first.
+func (s *PluginSet) RunOptions(opts RunOptions) RunOptions {
+	opts.Jobs = append([]Job(nil), opts.Jobs...)
+	opts.Closers = append([]Closer(nil), opts.Closers...)
+	for _, plugin := range s.plugins {
+		opts.Jobs = append(opts.Jobs, plugin.Hooks().Jobs...)
+	}
+	for i := len(s.plugins) - 1; i >= 0; i-- {
+		opts.Closers = append(opts.Closers, s.plugins[i].Hooks().Closers...)
+	}
+	return opts
+}
+
+// DoctorOptions returns opts with the checks of the plugins added
+func (s *PluginSet) DoctorOptions(opts DoctorOptions) DoctorOptions {
+	opts.Checks = append([]Check(nil), opts.Checks...)
+	for _, plugin := range s.plugins {
+		opts.Checks = append(opts.Checks, plugin.Hooks().Checks...)
+	}
+	return opts
+}
+
+// registerPluginRoutes registers the routes of a plugin, reporting registration
+// panics (duplicate routes, conflicting wildcards) as errors
+func registerPluginRoutes(group *gin.RouterGroup, plugin Plugin) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("plugin %s: %w: %v", plugin.Name(), ErrRouteConflict, recovered)
+		}
+	}()
+	plugin.Routes(group)
+	return nil
+}
+
+// orderPlugins validates the plugins and sorts them so every plugin comes after its
+// dependencies, keeping the given order otherwise
+func orderPlugins(plugins []Plugin) ([]Plugin, error) {
+	byName := make(map[string]Plugin, len(plugins))
+	for _, plugin := range plugins {
+		name := plugin.Name()
+		if _, exists := byName[name]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatePlugin, name)
+		}
+		byName[name] = plugin
+	}
+
+	for _, plugin := range plugins {
+		if dependent, ok := plugin.(DependentPlugin); ok {
+			for _, dependency := range dependent.DependsOn() {
+				if _, exists := byName[dependency]; !exists {
+					return nil, fmt.Errorf("%w: %s requires %s", ErrMissingDependency, plugin.Name(), dependency)
+				}
+			}
+		}
+		if conflicting, ok := plugin.(ConflictingPlugin); ok {
+			for _, other := range conflicting.ConflictsWith() {
+				if _, exists := byName[other]; exists && other != plugin.Name() {
+					return nil, fmt.Errorf("%w: %s and %s", ErrPluginConflict, plugin.Name(), other)
+				}
+			}
+		}
+	}
+
+	// Unvisited plugins have no state
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int, len(plugins))
+	ordered := make([]Plugin, 0, len(plugins))
+	var visit func(plugin Plugin, path []string) error
+	visit = func(plugin Plugin, path []string) error {
+		name := plugin.Name()
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		if dependent, ok := plugin.(DependentPlugin); ok {
+			for _, dependency := range dependent.DependsOn() {
+				if err := visit(byName[dependency], append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = done
+		ordered = append(ordered, plugin)
+		return nil
+	}
+	for _, plugin := range plugins {
+		if err := visit(plugin, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,149 @@
+package refinegin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestUsePlugins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := resource.NewResourceRegistry()
+
+	var initialized []string
+	closer := func(name string) Closer {
+		return Closer{Name: name, Close: func(ctx context.Context) error { return nil }}
+	}
+	audit := &FuncPlugin{
+		PluginName: "audit",
+		InitFunc: func(registry *resource.ResourceRegistry) error {
+			initialized = append(initialized, "audit")
+			registry.Register(resource.NewResource(resource.ResourceConfig{Name: "audit_logs", Model: DoctorTask{}}))
+			return nil
+		},
+		RoutesFunc: func(group *gin.RouterGroup) {
+			group.GET("/audit", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("plugin")) })
+		},
+		PluginHooks: Hooks{
+			Middleware: []gin.HandlerFunc{func(c *gin.Context) { c.Set("plugin", "audited") }},
+			Closers:    []Closer{closer("audit store")},
+			Checks:     []Check{{Name: "audit store", Run: func(ctx context.Context) error { return nil }}},
+		},
+	}
+	webhooks := &FuncPlugin{
+		PluginName: "webhooks",
+		Requires:   []string{"audit"},
+		InitFunc: func(registry *resource.ResourceRegistry) error {
+			initialized = append(initialized, "webhooks")
+			return nil
+		},
+		RoutesFunc: func(group *gin.RouterGroup) {
+			group.GET("/webhooks", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("plugin")) })
+		},
+		PluginHooks: Hooks{
+			Jobs:    []Job{{Name: "deliveries", Drainer: DrainerFunc(func(ctx context.Context) error { return nil })}},
+			Closers: []Closer{closer("webhook queue")},
+		},
+	}
+
+	r := gin.New()
+	plugins, err := UsePlugins(r.Group("/api"), registry, webhooks, audit)
+	require.NoError(t, err)
+
+	// Dependencies are initialized first
+	assert.Equal(t, []string{"audit", "webhooks"}, initialized)
+	assert.Equal(t, []Plugin{audit, webhooks}, plugins.Plugins())
+	_, ok := registry.GetByName("audit_logs")
+	assert.True(t, ok)
+
+	// Middleware of every plugin applies to the routes of all plugins
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/webhooks", nil))
+	assert.Equal(t, "audited", w.Body.String())
+
+	opts := plugins.RunOptions(RunOptions{Closers: []Closer{closer("database")}})
+	assert.Len(t, opts.Jobs, 1)
+	var closers []string
+	for _, c := range opts.Closers {
+		closers = append(closers, c.Name)
+	}
+	assert.Equal(t, []string{"database", "webhook queue", "audit store"}, closers)
+	assert.Len(t, plugins.DoctorOptions(DoctorOptions{}).Checks, 1)
+}
+
+func TestUsePluginsErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	route := func(path string) func(group *gin.RouterGroup) {
+		return func(group *gin.RouterGroup) { group.GET(path, func(c *gin.Context) {}) }
+	}
+
+	tests := []struct {
+		name    string
+		plugins []Plugin
+		err     error
+		message string
+	}{
+		{
+			name:    "duplicate",
+			plugins: []Plugin{&FuncPlugin{PluginName: "auth"}, &FuncPlugin{PluginName: "auth"}},
+			err:     ErrDuplicatePlugin,
+		},
+		{
+			name:    "missing dependency",
+			plugins: []Plugin{&FuncPlugin{PluginName: "webhooks", Requires: []string{"audit"}}},
+			err:     ErrMissingDependency,
+			message: "webhooks requires audit",
+		},
+		{
+			name: "cycle",
+			plugins: []Plugin{
+				&FuncPlugin{PluginName: "a", Requires: []string{"b"}},
+				&FuncPlugin{PluginName: "b", Requires: []string{"a"}},
+			},
+			err:     ErrDependencyCycle,
+			message: "a -> b -> a",
+		},
+		{
+			name: "conflict",
+			plugins: []Plugin{
+				&FuncPlugin{PluginName: "jwt-auth", Conflicts: []string{"session-auth"}},
+				&FuncPlugin{PluginName: "session-auth"},
+			},
+			err: ErrPluginConflict,
+		},
+		{
+			name: "route conflict",
+			plugins: []Plugin{
+				&FuncPlugin{PluginName: "files", RoutesFunc: route("/uploads")},
+				&FuncPlugin{PluginName: "media", RoutesFunc: route("/uploads")},
+			},
+			err:     ErrRouteConflict,
+			message: "plugin media",
+		},
+		{
+			name: "init failure",
+			plugins: []Plugin{&FuncPlugin{PluginName: "search", InitFunc: func(*resource.ResourceRegistry) error {
+				return errors.New("index missing")
+			}}},
+			message: "plugin search: index missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UsePlugins(gin.New().Group("/"), resource.NewResourceRegistry(), tt.plugins...)
+			require.Error(t, err)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package refinegin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shutdown stages reported through RunOptions.OnProgress
+const (
+	StageServing  = "serving"
+	StageSignal   = "signal"
+	StageDraining = "draining requests"
+	StageJob      = "draining job"
+	StageClose    = "closing"
+	StageDone     = "done"
+)
+
+// ShutdownEvent describes the progress of the application lifecycle
+type ShutdownEvent struct {
+	Stage   string
+	Name    string
+	Err     error
+	Elapsed time.Duration
+}
+
+// Drainer is implemented by background job runners that can finish their work on shutdown
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// DrainerFunc adapts a function to the Drainer interface
+type DrainerFunc func(ctx context.Context) error
+
+// Drain calls f(ctx)
+func (f DrainerFunc) Drain(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Job is a named background job runner drained on shutdown
+type Job struct {
+	Name    string
+	Drainer Drainer
+}
+
+// Closer is a named resource (database, storage provider, ...) closed after draining
+type Closer struct {
+	Name  string
+	Close func(ctx context.Context) error
+}
+
+// RunOptions contains configuration for Run
+type RunOptions struct {
+	// Address to listen on (default ":8080"); ignored when Listener is set
+	Addr string
+
+	// Listener to serve on instead of Addr (optional)
+	Listener net.Listener
+
+	// Server is used as a template for the HTTP server (optional)
+	Server *http.Server
+
+	// Maximum time to drain requests, jobs and close resources (default 30s)
+	ShutdownTimeout time.Duration
+
+	// Signals triggering shutdown (default SIGINT and SIGTERM)
+	Signals []os.Signal
+
+	// Context triggering shutdown when done (optional)
+	Context context.Context
+
+	// Background jobs drained after in-flight requests, in order
+	Jobs []Job
+
+	// Resources closed after all jobs were drained, in order
+	Closers []Closer
+
+	// OnProgress receives lifecycle events (defaults to log.Printf)
+	OnProgress func(event ShutdownEvent)
+}
+
+// Run serves handler (typically a *gin.Engine) until a shutdown signal is received, then
+// stops accepting new requests, drains in-flight requests and background jobs and closes
+// resources within ShutdownTimeout. It returns nil after a clean shutdown.
+func Run(handler http.Handler, opts RunOptions) error {
+	opts = opts.withDefaults()
+
+	server := &http.Server{}
+	if opts.Server != nil {
+		server = opts.Server
+	}
+	server.Handler = handler
+	if server.Addr == "" {
+		server.Addr = opts.Addr
+	}
+
+	listener := opts.Listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", server.Addr); err != nil {
+			return err
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	opts.OnProgress(ShutdownEvent{Stage: StageServing, Name: listener.Addr().String()})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, opts.Signals...)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-signals:
+		opts.OnProgress(ShutdownEvent{Stage: StageSignal, Name: sig.String()})
+	case <-opts.Context.Done():
+		opts.OnProgress(ShutdownEvent{Stage: StageSignal, Name: "context done"})
+	}
+
+	return Shutdown(server, opts)
+}
+
+// Shutdown gracefully stops a running server, drains jobs and closes resources
+func Shutdown(server *http.Server, opts RunOptions) error {
+	opts = opts.withDefaults()
+	started := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	report := func(stage, name string, err error) {
+		opts.OnProgress(ShutdownEvent{Stage: stage, Name: name, Err: err, Elapsed: time.Since(started)})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", stage, name, err))
+		}
+	}
+
+	// Stop accepting new requests and wait for in-flight handlers
+	report(StageDraining, "http", server.Shutdown(ctx))
+
+	for _, job := range opts.Jobs {
+		report(StageJob, job.Name, job.Drainer.Drain(ctx))
+	}
+
+	for _, closer := range opts.Closers {
+		report(StageClose, closer.Name, closer.Close(ctx))
+	}
+
+	err := errors.Join(errs...)
+	opts.OnProgress(ShutdownEvent{Stage: StageDone, Err: err, Elapsed: time.Since(started)})
+	return err
+}
+
+// withDefaults fills in default options
+func (o RunOptions) withDefaults() RunOptions {
+	if o.Addr == "" {
+		o.Addr = ":8080"
+	}
+	if o.ShutdownTimeout <= 0 {
+		o.ShutdownTimeout = 30 * time.Second
+	}
+	if len(o.Signals) == 0 {
+		o.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	if o.OnProgress == nil {
+		o.OnProgress = logProgress
+	}
+	return o
+}
+
+// logProgress is the default progress reporter
+func logProgress(event ShutdownEvent) {
+	switch {
+	case event.Err != nil:
+		log.Printf("[refine-gin] %s %s failed after %s: %v", event.Stage, event.Name, event.Elapsed, event.Err)
+	case event.Stage == StageServing:
+		log.Printf("[refine-gin] serving on %s", event.Name)
+	case event.Stage == StageSignal:
+		log.Printf("[refine-gin] shutting down (%s)", event.Name)
+	default:
+		log.Printf("[refine-gin] %s %s (%s)", event.Stage, event.Name, event.Elapsed)
+	}
+}
+
+// CloseDB returns a closer for a GORM database connection
+func CloseDB(name string, db *gorm.DB) Closer {
+	return Closer{
+		Name: name,
+		Close: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	}
+}
+
+// JobGroup tracks background goroutines (webhook deliveries, async actions, ...) so
+// they can be drained on shutdown. It implements Drainer.
+type JobGroup struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	draining bool
+}
+
+// NewJobGroup creates a new job group
+func NewJobGroup() *JobGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine. It returns false without running fn once draining has started.
+// The context passed to fn is canceled when draining times out.
+func (g *JobGroup) Go(fn func(ctx context.Context)) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.draining {
+		return false
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+	return true
+}
+
+// Drain stops accepting new jobs and waits for running jobs until ctx is done
+func (g *JobGroup) Drain(ctx context.Context) error {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		g.cancel()
+		return nil
+	case <-ctx.Done():
+		// Ask remaining jobs to stop
+		g.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,127 @@
+package refinegin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDrainsRequestsJobsAndClosers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	requestStarted := make(chan struct{})
+	r := gin.New()
+	r.GET("/slow", func(c *gin.Context) {
+		close(requestStarted)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	jobs := NewJobGroup()
+	jobFinished := false
+	require.True(t, jobs.Go(func(ctx context.Context) {
+		time.Sleep(50 * time.Millisecond)
+		jobFinished = true
+	}))
+
+	var mu sync.Mutex
+	var stages []string
+	closed := false
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- Run(r, RunOptions{
+			Listener: listener,
+			Context:  ctx,
+			Jobs:     []Job{{Name: "webhooks", Drainer: jobs}},
+			Closers: []Closer{{Name: "db", Close: func(ctx context.Context) error {
+				closed = true
+				return nil
+			}}},
+			OnProgress: func(event ShutdownEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				stages = append(stages, event.Stage)
+			},
+		})
+	}()
+
+	// Start an in-flight request, then trigger shutdown
+	responseBody := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			responseBody <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responseBody <- string(body)
+	}()
+	<-requestStarted
+	cancel()
+
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+
+	assert.Equal(t, "done", <-responseBody)
+	assert.True(t, jobFinished)
+	assert.True(t, closed)
+	assert.False(t, jobs.Go(func(ctx context.Context) {}), "no new jobs after draining")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{StageServing, StageSignal, StageDraining, StageJob, StageClose, StageDone}, stages)
+}
+
+func TestShutdownReportsErrors(t *testing.T) {
+	server := &http.Server{}
+	err := Shutdown(server, RunOptions{
+		ShutdownTimeout: 50 * time.Millisecond,
+		Jobs: []Job{{Name: "stuck", Drainer: DrainerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})}},
+		Closers:    []Closer{{Name: "storage", Close: func(ctx context.Context) error { return errors.New("close failed") }}},
+		OnProgress: func(ShutdownEvent) {},
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "storage: close failed")
+}
+
+func TestJobGroupDrainTimeout(t *testing.T) {
+	jobs := NewJobGroup()
+	stopped := make(chan struct{})
+	jobs.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, jobs.Drain(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("job context was not canceled")
+	}
+}
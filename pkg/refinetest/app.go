@@ -0,0 +1,215 @@
+// Package refinetest provides helpers for testing applications built with refine-gin:
+// an in-memory app with chosen resources, fluent requests with assertions, fixtures,
+// fake owners and JWTs, and recorders for emitted hooks and webhooks.
+package refinetest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OwnerHeader is the request header the test app reads fake owner IDs from
+const OwnerHeader = "X-Refinetest-Owner"
+
+// databaseCounter keeps the in-memory databases of parallel tests apart
+var databaseCounter int64
+
+// Config contains configuration of a test app
+type Config struct {
+	// DB is the database used by the app, a fresh in-memory SQLite database by default
+	DB *gorm.DB
+
+	// BasePath is the path of the API group, "/api" by default
+	BasePath string
+
+	// JWT enables JWT authentication on the API group when set
+	JWT *auth.JWTConfig
+
+	// OwnerClaim is the JWT claim used as owner ID, "sub" by default
+	OwnerClaim string
+}
+
+// withDefaults returns the config with default values applied
+func (cfg Config) withDefaults() Config {
+	if cfg.BasePath == "" {
+		cfg.BasePath = "/api"
+	}
+	if cfg.OwnerClaim == "" {
+		cfg.OwnerClaim = "sub"
+	}
+	return cfg
+}
+
+// App is an in-memory refine-gin application for tests
+type App struct {
+	T      testing.TB
+	DB     *gorm.DB
+	Engine *gin.Engine
+	Group  *gin.RouterGroup
+	Config Config
+
+	repositories map[string]repository.Repository
+	resources    map[string]resource.Resource
+}
+
+// New creates a test app backed by a fresh in-memory SQLite database
+func New(t testing.TB) *App {
+	return NewWithConfig(t, Config{})
+}
+
+// NewWithConfig creates a test app with the given configuration
+func NewWithConfig(t testing.TB, cfg Config) *App {
+	t.Helper()
+	cfg = cfg.withDefaults()
+
+	if cfg.DB == nil {
+		name := fmt.Sprintf("file:refinetest_%d?mode=memory&cache=shared", atomic.AddInt64(&databaseCounter, 1))
+		db, err := gorm.Open(sqlite.Open(name), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("refinetest: open database: %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatalf("refinetest: open database: %v", err)
+		}
+		// The shared in-memory database lives as long as a connection is open
+		sqlDB.SetMaxIdleConns(1)
+		t.Cleanup(func() { sqlDB.Close() })
+		cfg.DB = db
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	group := engine.Group(cfg.BasePath)
+	if cfg.JWT != nil {
+		group.Use(auth.JWTMiddleware(*cfg.JWT))
+	}
+	group.Use(fakeOwner(cfg.OwnerClaim))
+
+	return &App{
+		T:            t,
+		DB:           cfg.DB,
+		Engine:       engine,
+		Group:        group,
+		Config:       cfg,
+		repositories: make(map[string]repository.Repository),
+		resources:    make(map[string]resource.Resource),
+	}
+}
+
+// fakeOwner sets the owner ID from the test owner header or the JWT owner claim,
+// leaving anonymous requests untouched
+func fakeOwner(claim string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if owner := c.GetHeader(OwnerHeader); owner != "" {
+			c.Set(middleware.OwnerContextKey, owner)
+		} else if claims, ok := c.Get("claims"); ok {
+			if mapClaims, ok := claims.(jwt.MapClaims); ok && mapClaims[claim] != nil {
+				c.Set(middleware.OwnerContextKey, mapClaims[claim])
+			}
+		}
+		c.Next()
+	}
+}
+
+// Register migrates the resource model and registers its routes with a generic repository.
+// Owner resources use an owner repository and owner routes.
+func (a *App) Register(res resource.Resource) repository.Repository {
+	a.T.Helper()
+	if err := a.DB.AutoMigrate(res.GetModel()); err != nil {
+		a.T.Fatalf("refinetest: migrate %s: %v", res.GetName(), err)
+	}
+
+	if ownerRes, ok := res.(resource.OwnerResource); ok {
+		repo, err := repository.NewOwnerRepository(a.DB, ownerRes)
+		if err != nil {
+			a.T.Fatalf("refinetest: owner repository for %s: %v", res.GetName(), err)
+		}
+		handler.RegisterOwnerResource(a.Group, ownerRes, repo)
+		return a.RegisterWithRepository(res, repo, false)
+	}
+
+	repo := repository.NewGenericRepositoryWithResource(a.DB, res)
+	return a.RegisterWithRepository(res, repo, true)
+}
+
+// RegisterWithRepository records a resource backed by a custom repository, registering
+// its routes when registerRoutes is set
+func (a *App) RegisterWithRepository(res resource.Resource, repo repository.Repository, registerRoutes bool) repository.Repository {
+	if registerRoutes {
+		handler.RegisterResource(a.Group, res, repo)
+	}
+	a.resources[res.GetName()] = res
+	a.repositories[res.GetName()] = repo
+	return repo
+}
+
+// Repository returns the repository of a registered resource
+func (a *App) Repository(name string) repository.Repository {
+	a.T.Helper()
+	repo, ok := a.repositories[name]
+	if !ok {
+		a.T.Fatalf("refinetest: resource %q is not registered", name)
+	}
+	return repo
+}
+
+// Seed inserts fixtures directly into the database. Records may be model pointers or
+// slices of models; they are filled with their generated IDs.
+func (a *App) Seed(records ...interface{}) {
+	a.T.Helper()
+	for _, record := range records {
+		if err := a.DB.Create(record).Error; err != nil {
+			a.T.Fatalf("refinetest: seed %T: %v", record, err)
+		}
+	}
+}
+
+// Token issues a JWT for the subject signed with the app JWT configuration
+// (or the default configuration when JWT is disabled)
+func (a *App) Token(subject string, claims map[string]interface{}) string {
+	a.T.Helper()
+	cfg := auth.DefaultJWTConfig()
+	if a.Config.JWT != nil {
+		cfg = *a.Config.JWT
+	}
+	return FakeJWT(a.T, cfg, subject, claims)
+}
+
+// FakeJWT issues a JWT with standard claims for tests
+func FakeJWT(t testing.TB, cfg auth.JWTConfig, subject string, claims map[string]interface{}) string {
+	t.Helper()
+	token, err := auth.GenerateJWTWithStandardClaims(cfg, subject, claims)
+	if err != nil {
+		t.Fatalf("refinetest: generate JWT: %v", err)
+	}
+	return token
+}
+
+// Server starts an HTTP server for the app, closed when the test ends
+func (a *App) Server() *httptest.Server {
+	server := httptest.NewServer(a.Engine)
+	a.T.Cleanup(server.Close)
+	return server
+}
+
+// Do serves a raw request and returns the wrapped response
+func (a *App) Do(req *http.Request) *Response {
+	w := httptest.NewRecorder()
+	a.Engine.ServeHTTP(w, req)
+	return &Response{T: a.T, Recorder: w}
+}
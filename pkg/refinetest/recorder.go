@@ -0,0 +1,195 @@
+package refinetest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/webhook"
+)
+
+// Event is a hook invocation captured by a HookRecorder
+type Event struct {
+	Name    string
+	Payload interface{}
+}
+
+// HookRecorder captures hook invocations so tests can assert what was emitted
+type HookRecorder struct {
+	T testing.TB
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewHookRecorder creates an empty hook recorder
+func NewHookRecorder(t testing.TB) *HookRecorder {
+	return &HookRecorder{T: t}
+}
+
+// Record captures an event; it is safe to call from concurrent handlers
+func (r *HookRecorder) Record(name string, payload interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, Event{Name: name, Payload: payload})
+}
+
+// Hook returns a function recording its payload under name, for use as a hook callback
+func (r *HookRecorder) Hook(name string) func(payload interface{}) {
+	return func(payload interface{}) { r.Record(name, payload) }
+}
+
+// Events returns the captured events with the name, or all events when name is empty
+func (r *HookRecorder) Events(name string) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []Event
+	for _, event := range r.events {
+		if name == "" || event.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Reset forgets the captured events
+func (r *HookRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// AssertEmitted fails the test unless the hook was emitted times times
+func (r *HookRecorder) AssertEmitted(name string, times int) []Event {
+	r.T.Helper()
+	events := r.Events(name)
+	if len(events) != times {
+		r.T.Errorf("refinetest: expected hook %q to be emitted %d times, got %d", name, times, len(events))
+	}
+	return events
+}
+
+// AssertNotEmitted fails the test if the hook was emitted
+func (r *HookRecorder) AssertNotEmitted(name string) {
+	r.T.Helper()
+	r.AssertEmitted(name, 0)
+}
+
+// Delivery is a webhook request captured by a WebhookRecorder
+type Delivery struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Decode decodes the delivery body into v
+func (d Delivery) Decode(v interface{}) error {
+	return json.Unmarshal(d.Body, v)
+}
+
+// WebhookRecorder is an HTTP server capturing outgoing webhook deliveries
+type WebhookRecorder struct {
+	T      testing.TB
+	Server *httptest.Server
+
+	// Status is the status code returned to deliveries, 200 by default
+	Status int
+
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// NewWebhookRecorder starts a webhook recorder, closed when the test ends
+func NewWebhookRecorder(t testing.TB) *WebhookRecorder {
+	r := &WebhookRecorder{T: t, Status: http.StatusOK}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Server.Close)
+	return r
+}
+
+// URL returns the URL of a path on the recorder server
+func (r *WebhookRecorder) URL(path string) string {
+	return r.Server.URL + path
+}
+
+// serveHTTP captures a delivery
+func (r *WebhookRecorder) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, Delivery{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	status := r.Status
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+// Deliveries returns the captured deliveries
+func (r *WebhookRecorder) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Delivery(nil), r.deliveries...)
+}
+
+// AssertReceived waits up to timeout for at least count deliveries and returns them;
+// webhooks are often sent asynchronously
+func (r *WebhookRecorder) AssertReceived(count int, timeout time.Duration) []Delivery {
+	r.T.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		deliveries := r.Deliveries()
+		if len(deliveries) >= count {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			r.T.Errorf("refinetest: expected %d webhook deliveries, got %d", count, len(deliveries))
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// AssertSigned fails the test unless the delivery carries a valid signature for the
+// inbound webhook configuration
+func (r *WebhookRecorder) AssertSigned(delivery Delivery, cfg webhook.InboundConfig) {
+	r.T.Helper()
+	if err := webhook.VerifySignature(cfg, delivery.Header, delivery.Body, time.Now()); err != nil {
+		r.T.Errorf("refinetest: invalid webhook signature: %v", err)
+	}
+}
+
+// SendWebhook posts a signed webhook to an inbound webhook endpoint of the app. The
+// payload is encoded as JSON unless it is a string or byte slice.
+func (a *App) SendWebhook(path string, cfg webhook.InboundConfig, deliveryID string, payload interface{}) *Response {
+	a.T.Helper()
+	var body []byte
+	switch v := payload.(type) {
+	case string:
+		body = []byte(v)
+	case []byte:
+		body = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			a.T.Fatalf("refinetest: encode webhook: %v", err)
+		}
+		body = encoded
+	}
+
+	req := a.Request(http.MethodPost, path).JSON(body)
+	headers := webhook.SignedHeaders(cfg, deliveryID, body, time.Now())
+	for key := range headers {
+		req.Header(key, headers.Get(key))
+	}
+	return req.Do()
+}
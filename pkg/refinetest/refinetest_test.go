@@ -0,0 +1,177 @@
+package refinetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/webhook"
+)
+
+type Note struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+func noteResource() resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  "notes",
+		Model: Note{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "done", Type: "bool"},
+		},
+		FilterableFields: []string{"title", "done"},
+		SortableFields:   []string{"title"},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate,
+			resource.OperationUpdate, resource.OperationDelete, resource.OperationCount,
+		},
+	})
+}
+
+func TestAppRequests(t *testing.T) {
+	app := New(t)
+	app.Register(noteResource())
+	app.Seed(&[]Note{{Title: "Write docs"}, {Title: "Fix bug", Done: true}, {Title: "Review"}})
+
+	app.List("notes").Sort("title", "asc").Page(1, 2).Do().
+		AssertStatus(http.StatusOK).
+		AssertTotal(3).
+		AssertLen(2).
+		AssertValue("data.0.title", "Fix bug")
+
+	app.List("notes").Filter("title", "contains", "bug").Do().AssertTotal(1)
+	app.Count("notes").Do().AssertCount(3)
+
+	var created Note
+	app.Create("notes", Note{Title: "Ship"}).Do().
+		AssertStatus(http.StatusCreated).
+		DecodeData(&created)
+	assert.Equal(t, uint(4), created.ID)
+
+	app.Update("notes", created.ID, map[string]interface{}{"title": "Shipped"}).Do().
+		AssertOK().
+		AssertValue("data.title", "Shipped")
+	app.Get("notes", created.ID).Do().AssertValue("data.title", "Shipped")
+
+	app.Delete("notes", created.ID).Do().AssertOK()
+	app.Get("notes", created.ID).Do().AssertStatus(http.StatusNotFound)
+
+	count, err := app.Repository("notes").Count(context.Background(), query.QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestAppOwners(t *testing.T) {
+	secret := auth.DefaultJWTConfig()
+	secret.Secret = "test-secret"
+	app := NewWithConfig(t, Config{JWT: &secret})
+
+	app.Group.GET("/me", func(c *gin.Context) {
+		owner, err := middleware.GetOwnerID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": owner})
+	})
+
+	token := app.Token("user-1", map[string]interface{}{"role": "admin"})
+	app.Request(http.MethodGet, "me").WithJWT(token).Do().AssertValue("data", "user-1")
+
+	// The fake owner header wins over the JWT subject
+	app.Request(http.MethodGet, "me").WithJWT(token).As(42).Do().AssertValue("data", "42")
+
+	app.Request(http.MethodGet, "me").Do().AssertError(http.StatusUnauthorized, "Authorization header is required")
+	app.Request(http.MethodGet, "me").WithJWT(FakeJWT(t, auth.DefaultJWTConfig(), "user-1", nil)).Do().
+		AssertStatus(http.StatusUnauthorized)
+}
+
+func TestHookRecorder(t *testing.T) {
+	hooks := NewHookRecorder(t)
+	afterCreate := hooks.Hook("afterCreate")
+
+	app := New(t)
+	app.Group.Use(func(c *gin.Context) {
+		c.Next()
+		if c.Request.Method == http.MethodPost && c.Writer.Status() == http.StatusCreated {
+			afterCreate(c.Request.URL.Path)
+		}
+	})
+	app.Register(noteResource())
+
+	app.Create("notes", `{"title":"Ship"}`).Do().AssertStatus(http.StatusCreated)
+	app.List("notes").Do().AssertOK()
+
+	events := hooks.AssertEmitted("afterCreate", 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, "/api/notes", events[0].Payload)
+	hooks.AssertNotEmitted("afterDelete")
+
+	hooks.Reset()
+	assert.Empty(t, hooks.Events(""))
+}
+
+func TestWebhooks(t *testing.T) {
+	cfg := webhook.InboundConfig{Name: "orders", Secret: "whsec"}
+
+	recorder := NewWebhookRecorder(t)
+	body := []byte(`{"event":"created"}`)
+	req, err := http.NewRequest(http.MethodPost, recorder.URL("/hooks"), bytes.NewReader(body))
+	require.NoError(t, err)
+	for key, values := range webhook.SignedHeaders(cfg, "d-1", body, time.Now()) {
+		req.Header[key] = values
+	}
+	go http.DefaultClient.Do(req)
+
+	deliveries := recorder.AssertReceived(1, time.Second)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "/hooks", deliveries[0].Path)
+	recorder.AssertSigned(deliveries[0], cfg)
+	var payload map[string]string
+	require.NoError(t, deliveries[0].Decode(&payload))
+	assert.Equal(t, "created", payload["event"])
+
+	// Signed inbound webhooks can be sent to the app
+	app := New(t)
+	log := webhook.NewDeliveryLog(app.DB)
+	require.NoError(t, log.AutoMigrate())
+	received := 0
+	cfg.Handler = func(c *gin.Context, data interface{}) (interface{}, error) {
+		received++
+		return fmt.Sprint(data.(map[string]interface{})["event"]), nil
+	}
+	webhook.RegisterInboundWebhook(app.Group, "/webhooks/orders", log, cfg)
+
+	app.SendWebhook("webhooks/orders", cfg, "d-1", map[string]string{"event": "paid"}).AssertOK()
+	app.SendWebhook("webhooks/orders", cfg, "d-1", map[string]string{"event": "paid"}).AssertValue("data.duplicate", true)
+	assert.Equal(t, 1, received)
+}
+
+func TestAssertSchemaCompatible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	assert.Empty(t, AssertSchemaCompatible(t, path, noteResource()))
+
+	added := resource.NewResource(resource.ResourceConfig{Name: "tags", Model: Note{}, Operations: []resource.Operation{resource.OperationList}})
+	changes := AssertSchemaCompatible(t, path, noteResource(), added)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "tags: resource added (additive)", changes[0].String())
+
+	inner := &testing.T{}
+	AssertSchemaCompatible(inner, path)
+	assert.True(t, inner.Failed(), "removing a resource is a breaking change")
+}
@@ -0,0 +1,278 @@
+package refinetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Request is a request to the test app built with fluent options
+type Request struct {
+	app    *App
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   io.Reader
+}
+
+// Request starts a request to a path relative to the API group
+func (a *App) Request(method, path string) *Request {
+	return &Request{
+		app:    a,
+		method: method,
+		path:   strings.TrimSuffix(a.Config.BasePath, "/") + "/" + strings.TrimPrefix(path, "/"),
+		query:  url.Values{},
+		header: http.Header{},
+	}
+}
+
+// List starts a list request for a resource
+func (a *App) List(resourceName string) *Request {
+	return a.Request(http.MethodGet, resourceName)
+}
+
+// Get starts a get request for a record
+func (a *App) Get(resourceName string, id interface{}) *Request {
+	return a.Request(http.MethodGet, fmt.Sprintf("%s/%v", resourceName, id))
+}
+
+// Create starts a create request for a resource with a JSON body
+func (a *App) Create(resourceName string, body interface{}) *Request {
+	return a.Request(http.MethodPost, resourceName).JSON(body)
+}
+
+// Update starts an update request for a record with a JSON body
+func (a *App) Update(resourceName string, id interface{}, body interface{}) *Request {
+	return a.Request(http.MethodPut, fmt.Sprintf("%s/%v", resourceName, id)).JSON(body)
+}
+
+// Delete starts a delete request for a record
+func (a *App) Delete(resourceName string, id interface{}) *Request {
+	return a.Request(http.MethodDelete, fmt.Sprintf("%s/%v", resourceName, id))
+}
+
+// Count starts a count request for a resource
+func (a *App) Count(resourceName string) *Request {
+	return a.Request(http.MethodGet, resourceName+"/count")
+}
+
+// Query adds a query parameter
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Filter adds an advanced filter (filter[field][operator]=value)
+func (r *Request) Filter(field, operator, value string) *Request {
+	return r.Query(fmt.Sprintf("filter[%s][%s]", field, operator), value)
+}
+
+// Sort sets the sort field and order
+func (r *Request) Sort(field, order string) *Request {
+	return r.Query("sort", field).Query("order", order)
+}
+
+// Page sets the page and page size
+func (r *Request) Page(page, perPage int) *Request {
+	return r.Query("page", fmt.Sprint(page)).Query("per_page", fmt.Sprint(perPage))
+}
+
+// Header sets a request header
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// As sends the request on behalf of a fake owner
+func (r *Request) As(ownerID interface{}) *Request {
+	return r.Header(OwnerHeader, fmt.Sprint(ownerID))
+}
+
+// WithJWT sends the request with a bearer token
+func (r *Request) WithJWT(token string) *Request {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// JSON sets a JSON body; strings and byte slices are sent as they are
+func (r *Request) JSON(body interface{}) *Request {
+	switch v := body.(type) {
+	case nil:
+		r.body = nil
+	case string:
+		r.body = strings.NewReader(v)
+	case []byte:
+		r.body = bytes.NewReader(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			r.app.T.Fatalf("refinetest: encode body: %v", err)
+		}
+		r.body = bytes.NewReader(encoded)
+	}
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// Do sends the request
+func (r *Request) Do() *Response {
+	target := r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	return r.app.Do(req)
+}
+
+// Response is a recorded response with fluent assertions
+type Response struct {
+	T        testing.TB
+	Recorder *httptest.ResponseRecorder
+
+	decoded map[string]interface{}
+}
+
+// Status returns the status code
+func (r *Response) Status() int {
+	return r.Recorder.Code
+}
+
+// Body returns the raw body
+func (r *Response) Body() []byte {
+	return r.Recorder.Body.Bytes()
+}
+
+// Decode decodes the body into v
+func (r *Response) Decode(v interface{}) *Response {
+	r.T.Helper()
+	if err := json.Unmarshal(r.Body(), v); err != nil {
+		r.T.Fatalf("refinetest: decode response: %v\n%s", err, r.Body())
+	}
+	return r
+}
+
+// DecodeData decodes the "data" value of the body into v
+func (r *Response) DecodeData(v interface{}) *Response {
+	r.T.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	r.Decode(&envelope)
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		r.T.Fatalf("refinetest: decode response data: %v\n%s", err, r.Body())
+	}
+	return r
+}
+
+// JSON returns the body decoded as an object
+func (r *Response) JSON() map[string]interface{} {
+	r.T.Helper()
+	if r.decoded == nil {
+		r.Decode(&r.decoded)
+	}
+	return r.decoded
+}
+
+// Value returns the value at a dot separated path such as "data.0.title", or nil
+func (r *Response) Value(path string) interface{} {
+	r.T.Helper()
+	var value interface{} = r.JSON()
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[part]
+		case []interface{}:
+			var index int
+			if _, err := fmt.Sscanf(part, "%d", &index); err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			value = v[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// AssertStatus fails the test unless the response has the status code
+func (r *Response) AssertStatus(status int) *Response {
+	r.T.Helper()
+	if r.Status() != status {
+		r.T.Errorf("refinetest: expected status %d, got %d\n%s", status, r.Status(), r.Body())
+	}
+	return r
+}
+
+// AssertOK fails the test unless the response has a 2xx status code
+func (r *Response) AssertOK() *Response {
+	r.T.Helper()
+	if r.Status() < 200 || r.Status() > 299 {
+		r.T.Errorf("refinetest: expected success status, got %d\n%s", r.Status(), r.Body())
+	}
+	return r
+}
+
+// AssertValue fails the test unless the value at path equals expected; numbers are
+// compared by their decimal representation
+func (r *Response) AssertValue(path string, expected interface{}) *Response {
+	r.T.Helper()
+	actual := r.Value(path)
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		r.T.Errorf("refinetest: expected %s to be %v, got %v\n%s", path, expected, actual, r.Body())
+	}
+	return r
+}
+
+// AssertTotal fails the test unless the list total equals expected
+func (r *Response) AssertTotal(expected int) *Response {
+	r.T.Helper()
+	return r.AssertValue("total", expected)
+}
+
+// AssertCount fails the test unless the count response equals expected
+func (r *Response) AssertCount(expected int) *Response {
+	r.T.Helper()
+	return r.AssertValue("count", expected)
+}
+
+// AssertLen fails the test unless the "data" array has expected items
+func (r *Response) AssertLen(expected int) *Response {
+	r.T.Helper()
+	items, ok := r.Value("data").([]interface{})
+	if !ok {
+		r.T.Errorf("refinetest: expected data to be an array\n%s", r.Body())
+	} else if len(items) != expected {
+		r.T.Errorf("refinetest: expected %d items, got %d\n%s", expected, len(items), r.Body())
+	}
+	return r
+}
+
+// AssertError fails the test unless the response has the status code and an error
+// message containing substr
+func (r *Response) AssertError(status int, substr string) *Response {
+	r.T.Helper()
+	r.AssertStatus(status)
+	message, _ := r.Value("error").(string)
+	if !strings.Contains(message, substr) {
+		r.T.Errorf("refinetest: expected error containing %q, got %q", substr, message)
+	}
+	return r
+}
+
+// AssertHeader fails the test unless the response header has the value
+func (r *Response) AssertHeader(key, expected string) *Response {
+	r.T.Helper()
+	if actual := r.Recorder.Header().Get(key); actual != expected {
+		r.T.Errorf("refinetest: expected header %s to be %q, got %q", key, expected, actual)
+	}
+	return r
+}
@@ -0,0 +1,35 @@
+package refinetest
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// UpdateSchemaEnv is the environment variable that makes AssertSchemaCompatible accept
+// the current schema as the new baseline
+const UpdateSchemaEnv = "REFINETEST_UPDATE_SCHEMA"
+
+// AssertSchemaCompatible fails the test when resources break the API contract recorded
+// in the baseline file at path: removed resources, fields, operations or relations,
+// changed field types and the like. Additive changes are logged. A missing baseline is
+// created; run with REFINETEST_UPDATE_SCHEMA=1 to accept intentional breaking changes.
+func AssertSchemaCompatible(t *testing.T, path string, resources ...resource.Resource) []resource.SchemaChange {
+	t.Helper()
+
+	changes, err := resource.CheckSchemaFile(path, os.Getenv(UpdateSchemaEnv) != "", resources...)
+	var incompatible *resource.IncompatibleSchemaError
+	if errors.As(err, &incompatible) {
+		t.Errorf("%v\nrun with %s=1 to accept the changes", err, UpdateSchemaEnv)
+		return changes
+	}
+	if err != nil {
+		t.Fatalf("schema check failed: %v", err)
+	}
+	for _, change := range changes {
+		t.Logf("schema change: %s", change)
+	}
+	return changes
+}
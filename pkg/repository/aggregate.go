@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/query"
+)
+
+// Aggregate functions
+const (
+	AggregateCount = "count"
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+)
+
+// Metric is an aggregate function of a field. Count may have no field, to count
+// records rather than values.
+type Metric struct {
+	Function string
+	Field    string
+}
+
+// Name returns the key of the metric in aggregate rows, e.g. "sum_price" or "count"
+func (m Metric) Name() string {
+	if m.Field == "" {
+		return m.Function
+	}
+	return m.Function + "_" + m.Field
+}
+
+// AggregateSpec describes an aggregation: the metrics computed for each group of
+// records with the same GroupBy values, or for all records without GroupBy
+type AggregateSpec struct {
+	GroupBy []string
+	Metrics []Metric
+}
+
+// AggregateRow is a group of an aggregation: the group by values and the metrics
+// keyed by their names
+type AggregateRow map[string]interface{}
+
+// AggregateProvider is implemented by repositories able to aggregate records
+type AggregateProvider interface {
+	Aggregate(ctx context.Context, options query.QueryOptions, spec AggregateSpec) ([]AggregateRow, error)
+}
+
+// ParseAggregateSpec parses comma separated group by fields and metrics written
+// function:field (or count), e.g. "category" and "sum:price,avg:price,count"
+func ParseAggregateSpec(groupBy, metrics string) (AggregateSpec, error) {
+	var spec AggregateSpec
+	for _, field := range strings.Split(groupBy, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			spec.GroupBy = append(spec.GroupBy, field)
+		}
+	}
+
+	for _, metric := range strings.Split(metrics, ",") {
+		if metric = strings.TrimSpace(metric); metric == "" {
+			continue
+		}
+		function, field, _ := strings.Cut(metric, ":")
+		function = strings.ToLower(strings.TrimSpace(function))
+		field = strings.TrimSpace(field)
+		switch function {
+		case AggregateCount:
+		case AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+			if field == "" {
+				return spec, fmt.Errorf("metric %q requires a field", metric)
+			}
+		default:
+			return spec, fmt.Errorf("unknown aggregate function %q", function)
+		}
+		spec.Metrics = append(spec.Metrics, Metric{Function: function, Field: field})
+	}
+	if len(spec.Metrics) == 0 {
+		return spec, fmt.Errorf("at least one metric is required")
+	}
+	return spec, nil
+}
+
+// Aggregate computes the metrics of the spec under the current filters using GROUP
+// BY, with the groups ordered by their values
+func (r *GenericRepository) Aggregate(ctx context.Context, options query.QueryOptions, spec AggregateSpec) ([]AggregateRow, error) {
+	// Sorting does not apply to grouped results
+	options.Sort = ""
+
+	var selects, groups []string
+	for i, field := range spec.GroupBy {
+		column, err := r.fieldColumn(field)
+		if err != nil {
+			return nil, err
+		}
+		column = fmt.Sprintf("`%s`", column)
+		selects = append(selects, fmt.Sprintf("%s AS g%d", column, i))
+		groups = append(groups, column)
+	}
+	for i, metric := range spec.Metrics {
+		argument := "*"
+		if metric.Field != "" {
+			column, err := r.fieldColumn(metric.Field)
+			if err != nil {
+				return nil, err
+			}
+			argument = fmt.Sprintf("`%s`", column)
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s) AS m%d", strings.ToUpper(metric.Function), argument, i))
+	}
+
+	tx := options.Apply(r.scoped(ctx).Model(r.Model)).Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+	rows, err := tx.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []AggregateRow{}
+	for rows.Next() {
+		values := make([]interface{}, len(selects))
+		pointers := make([]interface{}, len(selects))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(AggregateRow, len(selects))
+		for i, field := range spec.GroupBy {
+			row[field] = aggregateValue(values[i])
+		}
+		for i, metric := range spec.Metrics {
+			row[metric.Name()] = aggregateValue(values[len(spec.GroupBy)+i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// fieldColumn returns the database column of a field: the column of the resource
+// field (see resource.Field.Column) as mapped by the model, honoring gorm column tags.
+// Fields without a column and fields not serialized to JSON (json:"-") have none.
+func (r *GenericRepository) fieldColumn(name string) (string, error) {
+	column := name
+	if r.Resource != nil {
+		if field := r.Resource.GetField(name); field != nil {
+			var ok bool
+			if column, ok = field.Column(); !ok {
+				return "", fmt.Errorf("field %q has no column", name)
+			}
+			if field.ValueObject != nil {
+				return column, nil
+			}
+		}
+	}
+	field, err := lookUpSchemaField(r.DB, r.Model, column)
+	if err != nil {
+		return "", err
+	}
+	if field.DBName == "" || field.Tag.Get("json") == "-" {
+		return "", fmt.Errorf("field %q has no column", name)
+	}
+	return field.DBName, nil
+}
+
+// aggregateValue converts a scanned value for JSON, turning bytes into strings
+func aggregateValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
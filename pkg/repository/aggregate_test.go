@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestParseAggregateSpec(t *testing.T) {
+	spec, err := ParseAggregateSpec("category_id, in_stock", "SUM:price, count, count:name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"category_id", "in_stock"}, spec.GroupBy)
+	assert.Equal(t, []Metric{{Function: "sum", Field: "price"}, {Function: "count"}, {Function: "count", Field: "name"}}, spec.Metrics)
+	assert.Equal(t, "sum_price", spec.Metrics[0].Name())
+	assert.Equal(t, "count", spec.Metrics[1].Name())
+
+	for _, metrics := range []string{"", "avg", "median:price"} {
+		_, err := ParseAggregateSpec("", metrics)
+		assert.Error(t, err, metrics)
+	}
+}
+
+func TestGenericRepository_Aggregate(t *testing.T) {
+	db := setupTestDB(t)
+
+	products := []TestProduct{
+		{Name: "Phone", Price: 10, InStock: true, CategoryID: 1},
+		{Name: "Laptop", Price: 30, InStock: true, CategoryID: 1},
+		{Name: "Tablet", Price: 20, InStock: false, CategoryID: 2},
+	}
+	require.NoError(t, db.Create(&products).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: TestProduct{},
+	})
+	repo := NewGenericRepository(db, res).(*GenericRepository)
+
+	t.Run("grouped", func(t *testing.T) {
+		spec := AggregateSpec{
+			GroupBy: []string{"category_id"},
+			Metrics: []Metric{{Function: AggregateMin, Field: "price"}, {Function: AggregateCount}},
+		}
+		rows, err := repo.Aggregate(context.Background(), query.QueryOptions{Resource: res, Sort: "name"}, spec)
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.EqualValues(t, 1, rows[0]["category_id"])
+		assert.EqualValues(t, 10, rows[0]["min_price"])
+		assert.EqualValues(t, 2, rows[0]["count"])
+		assert.EqualValues(t, 2, rows[1]["category_id"])
+		assert.EqualValues(t, 1, rows[1]["count"])
+	})
+
+	t.Run("totals under current filters", func(t *testing.T) {
+		options := query.QueryOptions{
+			Resource:        res,
+			AdvancedFilters: []query.Filter{{Field: "price", Operator: "gte", Value: 20}},
+		}
+		rows, err := repo.Aggregate(context.Background(), options, AggregateSpec{Metrics: []Metric{{Function: AggregateSum, Field: "price"}}})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		assert.EqualValues(t, 50, rows[0]["sum_price"])
+	})
+
+	t.Run("fields on their columns", func(t *testing.T) {
+		type AggregatedPayout struct {
+			ID     uint    `json:"id" gorm:"primaryKey"`
+			Team   string  `json:"team" gorm:"column:team_name"`
+			Amount float64 `json:"amount" gorm:"column:amount_cents"`
+			Bonus  float64 `json:"-"`
+		}
+		require.NoError(t, db.AutoMigrate(&AggregatedPayout{}))
+		require.NoError(t, db.Create(&[]AggregatedPayout{{Team: "a", Amount: 5}, {Team: "a", Amount: 7}, {Team: "b", Amount: 1}}).Error)
+		payouts := resource.NewResource(resource.ResourceConfig{Name: "payouts", Model: AggregatedPayout{}})
+		repo := NewGenericRepository(db, payouts).(*GenericRepository)
+
+		rows, err := repo.Aggregate(context.Background(), query.QueryOptions{Resource: payouts}, AggregateSpec{
+			GroupBy: []string{"Team"},
+			Metrics: []Metric{{Function: AggregateSum, Field: "Amount"}},
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, "a", rows[0]["Team"])
+		assert.EqualValues(t, 12, rows[0]["sum_Amount"])
+
+		_, err = repo.Aggregate(context.Background(), query.QueryOptions{Resource: payouts}, AggregateSpec{
+			Metrics: []Metric{{Function: AggregateMax, Field: "Bonus"}},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// BulkItemResult is the outcome of one item of a partial bulk operation: the record
+// and its ID, or the error that made the item fail
+type BulkItemResult struct {
+	Index int         `json:"index"`
+	ID    interface{} `json:"id,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// Failed reports whether the item failed
+func (r BulkItemResult) Failed() bool {
+	return r.Error != ""
+}
+
+// PartialBulkCreator is implemented by repositories able to create a batch of records
+// keeping the items that succeed when others fail
+type PartialBulkCreator interface {
+	// CreateManyPartial creates each item (a pointer to a model) and returns one
+	// result per item, in order. The error is only set when the batch as a whole
+	// could not run.
+	CreateManyPartial(ctx context.Context, items []interface{}) ([]BulkItemResult, error)
+}
+
+// CreateManyPartial creates the items in a single transaction with a savepoint per
+// item, so a failing item is rolled back alone and the others are committed
+func (r *GenericRepository) CreateManyPartial(ctx context.Context, items []interface{}) ([]BulkItemResult, error) {
+	results := make([]BulkItemResult, len(items))
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, item := range items {
+			results[i] = BulkItemResult{Index: i}
+
+			savepoint := fmt.Sprintf("bulk_item_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			if err := r.assignID(ctx, item); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := r.assignSlugs(ctx, tx, item, nil); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := r.computeFields(ctx, item); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := r.serializeFields(ctx, item); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			err := tx.Create(item).Error
+			if hookErr := r.deserializeFields(ctx, item); err == nil {
+				err = hookErr
+			}
+			if err != nil {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+				results[i].Error = err.Error()
+				continue
+			}
+
+			results[i].ID = primaryKeyValue(tx, item)
+			results[i].Data = item
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CreateManyPartial sets ownership on every item before creating them
+func (r *OwnerGenericRepository) CreateManyPartial(ctx context.Context, items []interface{}) ([]BulkItemResult, error) {
+	for _, item := range items {
+		if err := r.setOwnership(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+	return r.GenericRepository.CreateManyPartial(ctx, items)
+}
+
+// CreateManyPartial creates the items if the decorated repository supports partial
+// bulk creates
+func (r *CircuitBreakerRepository) CreateManyPartial(ctx context.Context, items []interface{}) (results []BulkItemResult, err error) {
+	creator, ok := r.Repository.(PartialBulkCreator)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = r.call(func() error {
+		results, err = creator.CreateManyPartial(ctx, items)
+		return err
+	})
+	return results, err
+}
+
+// primaryKeyValue returns the primary key of a created record, or nil if it cannot
+// be determined
+func primaryKeyValue(db *gorm.DB, record interface{}) interface{} {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(record); err != nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil
+	}
+	value, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, reflect.Indirect(reflect.ValueOf(record)))
+	if zero {
+		return nil
+	}
+	return value
+}
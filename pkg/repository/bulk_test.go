@@ -164,3 +164,41 @@ func TestBulkUpdate(t *testing.T) {
 		assert.Equal(t, int64(0), count)
 	})
 }
+
+type TestPartialBulkModel struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Email string `json:"email" gorm:"uniqueIndex"`
+}
+
+func TestCreateManyPartial(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestPartialBulkModel{}))
+	require.NoError(t, db.Create(&TestPartialBulkModel{Email: "taken@example.com"}).Error)
+
+	repo := NewGenericRepository(db, &TestPartialBulkModel{}).(PartialBulkCreator)
+	results, err := repo.CreateManyPartial(context.Background(), []interface{}{
+		&TestPartialBulkModel{Email: "a@example.com"},
+		&TestPartialBulkModel{Email: "taken@example.com"},
+		&TestPartialBulkModel{Email: "b@example.com"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.False(t, results[0].Failed())
+	assert.Equal(t, uint(2), results[0].ID)
+	assert.Equal(t, "a@example.com", results[0].Data.(*TestPartialBulkModel).Email)
+
+	assert.True(t, results[1].Failed())
+	assert.Equal(t, 1, results[1].Index)
+	assert.Contains(t, results[1].Error, "UNIQUE")
+	assert.Nil(t, results[1].Data)
+
+	// The items after the failing one are still created
+	assert.False(t, results[2].Failed())
+	assert.Equal(t, uint(3), results[2].ID)
+
+	var count int64
+	require.NoError(t, db.Model(&TestPartialBulkModel{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
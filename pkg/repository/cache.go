@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/cache"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// ETagProvider is implemented by repositories versioning their results. The get and
+// list handlers use it for ETags that change whenever the data changes.
+type ETagProvider interface {
+	// ETag returns the ETag of a read, keyed by the record ID or the query string
+	ETag(ctx context.Context, key string) (string, error)
+}
+
+// CachedRepository decorates a repository with a result cache for Get, List and Count.
+//
+// Cached entries are keyed by the namespace, its version, the query options or ID and
+// the owner and scopes of the context. Every mutation made through the repository
+// replaces the version, so later reads miss the entries of the previous one, which
+// expire with their TTL. Results are stored as JSON, so models must survive a JSON
+// round trip.
+type CachedRepository struct {
+	Repository
+
+	// Cache stores the results and versions
+	Cache cache.Cache
+
+	// TTL of cached results
+	TTL time.Duration
+
+	// Namespace separates the keys of repositories sharing a cache. It defaults to
+	// the resource name of generic repositories and must be set when decorating
+	// repositories of other types.
+	Namespace string
+
+	// Result types learned on cache misses, used to decode cached results
+	types *sync.Map
+}
+
+// WithCache decorates a repository with a result cache
+func WithCache(repo Repository, c cache.Cache, ttl time.Duration) *CachedRepository {
+	return &CachedRepository{
+		Repository: repo,
+		Cache:      c,
+		TTL:        ttl,
+		Namespace:  cacheNamespace(repo),
+		types:      &sync.Map{},
+	}
+}
+
+// cacheNamespace returns the resource name or model type of generic repositories
+func cacheNamespace(repo Repository) string {
+	var generic *GenericRepository
+	switch r := repo.(type) {
+	case *GenericRepository:
+		generic = r
+	case *OwnerGenericRepository:
+		generic = &r.GenericRepository
+	}
+	if generic != nil {
+		if generic.Resource != nil {
+			return generic.Resource.GetName()
+		}
+		if generic.Model != nil {
+			return reflect.TypeOf(generic.Model).String()
+		}
+	}
+	return fmt.Sprintf("%T", repo)
+}
+
+type cachedList struct {
+	Data  json.RawMessage `json:"data"`
+	Total int64           `json:"total"`
+}
+
+// Get returns a cached record or reads it from the decorated repository
+func (r *CachedRepository) Get(ctx context.Context, id interface{}) (interface{}, error) {
+	key, ok := r.key(ctx, "get", id)
+	if ok {
+		if value, hit := r.lookup(ctx, key); hit {
+			if result, ok := r.decode("get", value); ok {
+				return result, nil
+			}
+		}
+	}
+
+	result, err := r.Repository.Get(ctx, id)
+	if err == nil && ok {
+		r.store(ctx, key, "get", result, result)
+	}
+	return result, err
+}
+
+// List returns a cached page or reads it from the decorated repository
+func (r *CachedRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	key, ok := r.key(ctx, "list", cacheableOptions(options))
+	if ok {
+		if value, hit := r.lookup(ctx, key); hit {
+			var list cachedList
+			if json.Unmarshal(value, &list) == nil {
+				if result, ok := r.decode("list", list.Data); ok {
+					return result, list.Total, nil
+				}
+			}
+		}
+	}
+
+	result, total, err := r.Repository.List(ctx, options)
+	if err == nil && ok {
+		if data, marshalErr := json.Marshal(result); marshalErr == nil {
+			r.store(ctx, key, "list", result, cachedList{Data: data, Total: total})
+		}
+	}
+	return result, total, err
+}
+
+// Count returns a cached count or reads it from the decorated repository
+func (r *CachedRepository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
+	key, ok := r.key(ctx, "count", cacheableOptions(options))
+	if ok {
+		if value, hit := r.lookup(ctx, key); hit {
+			var count int64
+			if json.Unmarshal(value, &count) == nil {
+				return count, nil
+			}
+		}
+	}
+
+	count, err := r.Repository.Count(ctx, options)
+	if err == nil && ok {
+		r.store(ctx, key, "", nil, count)
+	}
+	return count, err
+}
+
+// ETag returns an ETag for a read that changes with the cache version
+func (r *CachedRepository) ETag(ctx context.Context, key string) (string, error) {
+	version, err := r.version(ctx)
+	if err != nil {
+		return "", err
+	}
+	scope, err := json.Marshal(cacheContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return cache.ETag(r.Namespace, version, string(scope), key), nil
+}
+
+// Create creates a record and invalidates the cache
+func (r *CachedRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	result, err := r.Repository.Create(ctx, data)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return result, err
+}
+
+// Update updates a record and invalidates the cache
+func (r *CachedRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	result, err := r.Repository.Update(ctx, id, data)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return result, err
+}
+
+// Delete deletes a record and invalidates the cache
+func (r *CachedRepository) Delete(ctx context.Context, id interface{}) error {
+	err := r.Repository.Delete(ctx, id)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return err
+}
+
+// CreateMany creates records and invalidates the cache
+func (r *CachedRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	result, err := r.Repository.CreateMany(ctx, data)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return result, err
+}
+
+// CreateManyPartial forwards to the decorated repository and invalidates the cache
+func (r *CachedRepository) CreateManyPartial(ctx context.Context, items []interface{}) ([]BulkItemResult, error) {
+	creator, ok := r.Repository.(PartialBulkCreator)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	results, err := creator.CreateManyPartial(ctx, items)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return results, err
+}
+
+// UpdateMany updates records and invalidates the cache
+func (r *CachedRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	affected, err := r.Repository.UpdateMany(ctx, ids, data)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return affected, err
+}
+
+// DeleteMany deletes records and invalidates the cache
+func (r *CachedRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	affected, err := r.Repository.DeleteMany(ctx, ids)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return affected, err
+}
+
+// BulkCreate inserts records and invalidates the cache
+func (r *CachedRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	err := r.Repository.BulkCreate(ctx, data)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return err
+}
+
+// BulkUpdate updates records and invalidates the cache
+func (r *CachedRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	err := r.Repository.BulkUpdate(ctx, condition, updates)
+	if err == nil {
+		r.Invalidate(ctx)
+	}
+	return err
+}
+
+// WithRelations returns a cached repository preloading relations, in its own namespace
+func (r *CachedRepository) WithRelations(relations ...string) Repository {
+	cached := *r
+	cached.Repository = r.Repository.WithRelations(relations...)
+	cached.Namespace = fmt.Sprintf("%s+%v", r.Namespace, relations)
+	cached.types = &sync.Map{}
+	return &cached
+}
+
+// WithTransaction runs fn with the uncached transactional repository, so uncommitted
+// records are never cached, and invalidates the cache once the transaction commits
+func (r *CachedRepository) WithTransaction(fn func(Repository) error) error {
+	err := r.Repository.WithTransaction(fn)
+	if err == nil {
+		r.Invalidate(context.Background())
+	}
+	return err
+}
+
+// Invalidate replaces the cache version, so every cached result is missed. Call it
+// after changing the data without the repository.
+func (r *CachedRepository) Invalidate(ctx context.Context) error {
+	_, err := r.newVersion(ctx)
+	return err
+}
+
+// versionKey is the cache key of the namespace version
+func (r *CachedRepository) versionKey() string {
+	return r.Namespace + ":version"
+}
+
+// version returns the current version, creating it when missing or evicted
+func (r *CachedRepository) version(ctx context.Context) (string, error) {
+	value, found, err := r.Cache.Get(ctx, r.versionKey())
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return string(value), nil
+	}
+	return r.newVersion(ctx)
+}
+
+// newVersion stores a random version. Random rather than incremented versions never
+// repeat, even when the version key was evicted.
+func (r *CachedRepository) newVersion(ctx context.Context) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	version := hex.EncodeToString(buf)
+	if err := r.Cache.Set(ctx, r.versionKey(), []byte(version), 0); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+// key returns the cache key of a read; false when the read cannot be cached
+func (r *CachedRepository) key(ctx context.Context, operation string, params interface{}) (string, bool) {
+	version, err := r.version(ctx)
+	if err != nil {
+		return "", false
+	}
+	encoded, err := json.Marshal(struct {
+		Context interface{} `json:"context"`
+		Params  interface{} `json:"params"`
+	}{cacheContext(ctx), params})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(encoded)
+	return fmt.Sprintf("%s:%s:%s:%s", r.Namespace, version, operation, hex.EncodeToString(sum[:16])), true
+}
+
+// lookup reads a cached value, treating cache errors as misses
+func (r *CachedRepository) lookup(ctx context.Context, key string) ([]byte, bool) {
+	value, found, err := r.Cache.Get(ctx, key)
+	return value, found && err == nil
+}
+
+// store caches a value and remembers the result type of the operation. Cache errors
+// are ignored: the result is then read from the repository again.
+func (r *CachedRepository) store(ctx context.Context, key, operation string, result, value interface{}) {
+	if operation != "" {
+		if result == nil {
+			return
+		}
+		r.types.Store(operation, reflect.TypeOf(result))
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	_ = r.Cache.Set(ctx, key, encoded, r.TTL)
+}
+
+// decode decodes a cached result into the type returned by the repository
+func (r *CachedRepository) decode(operation string, value []byte) (interface{}, bool) {
+	resultType, ok := r.types.Load(operation)
+	if !ok {
+		return nil, false
+	}
+	result := reflect.New(resultType.(reflect.Type))
+	if err := json.Unmarshal(value, result.Interface()); err != nil {
+		return nil, false
+	}
+	return result.Elem().Interface(), true
+}
+
+// cacheContext returns the context values the repository results depend on. The
+// owner group IDs are sorted, so their order does not change the key.
+func cacheContext(ctx context.Context) interface{} {
+	ownerID, _ := middleware.GetOwnerID(ctx)
+	tenantID, _ := middleware.GetTenantID(ctx)
+	fields, _ := requestctx.Fields.Get(ctx)
+	includes, _ := requestctx.Includes.Get(ctx)
+
+	var groups []string
+	for _, id := range middleware.GetOwnerGroupIDs(ctx) {
+		groups = append(groups, fmt.Sprint(id))
+	}
+	sort.Strings(groups)
+
+	return struct {
+		Owner    interface{}     `json:"owner,omitempty"`
+		Groups   []string        `json:"groups,omitempty"`
+		Tenant   string          `json:"tenant,omitempty"`
+		Scopes   []Scope         `json:"scopes,omitempty"`
+		Fields   []string        `json:"fields,omitempty"`
+		Includes []query.Include `json:"includes,omitempty"`
+	}{ownerID, groups, tenantID, ScopesFromContext(ctx), fields, includes}
+}
+
+// cacheableOptions returns the query options without the resource
+func cacheableOptions(options query.QueryOptions) interface{} {
+	timezone := ""
+	if options.Timezone != nil {
+		timezone = options.Timezone.String()
+	}
+	return struct {
+		Page              int                    `json:"page"`
+		PerPage           int                    `json:"perPage"`
+		DisablePagination bool                   `json:"disablePagination"`
+		Search            string                 `json:"search"`
+		Filters           map[string]interface{} `json:"filters"`
+		AdvancedFilters   []query.Filter         `json:"advancedFilters"`
+		FilterTree        []query.FilterNode     `json:"filterTree,omitempty"`
+		Sort              string                 `json:"sort"`
+		Order             string                 `json:"order"`
+		Timezone          string                 `json:"timezone"`
+		Fields            []string               `json:"fields,omitempty"`
+		Includes          []query.Include        `json:"includes,omitempty"`
+		CountMode         resource.CountMode     `json:"countMode,omitempty"`
+	}{
+		options.Page, options.PerPage, options.DisablePagination, options.Search, options.Filters,
+		options.AdvancedFilters, options.FilterTree, options.Sort, options.Order, timezone, options.Fields, options.Includes,
+		options.CountMode,
+	}
+}
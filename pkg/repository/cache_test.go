@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/cache"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type CachedItem struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+func TestWithCache(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CachedItem{}))
+	require.NoError(t, db.Create(&[]CachedItem{{Name: "a", Kind: "x"}, {Name: "b", Kind: "y"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "items", Model: CachedItem{}})
+	store := cache.NewMemory(100)
+	repo := WithCache(NewGenericRepositoryWithResource(db, res), store, time.Minute)
+	assert.Equal(t, "items", repo.Namespace)
+	ctx := context.Background()
+	options := query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Sort: "id", Order: "asc"}
+
+	item, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	list, total, err := repo.List(ctx, options)
+	require.NoError(t, err)
+	count, err := repo.Count(ctx, options)
+	require.NoError(t, err)
+	etag, err := repo.ETag(ctx, "1")
+	require.NoError(t, err)
+
+	// Changes made behind the repository are not seen until it is invalidated
+	require.NoError(t, db.Model(&CachedItem{}).Where("id = ?", 1).Update("name", "changed").Error)
+
+	cachedItem, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, item, cachedItem)
+	assert.IsType(t, &CachedItem{}, cachedItem)
+	assert.Equal(t, "a", cachedItem.(*CachedItem).Name)
+
+	cachedList, cachedTotal, err := repo.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, list, cachedList)
+	assert.Equal(t, total, cachedTotal)
+
+	cachedCount, err := repo.Count(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, count, cachedCount)
+
+	// Other query options and scopes have their own entries
+	filtered, filteredTotal, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Filters: map[string]interface{}{"kind": "y"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), filteredTotal)
+	assert.Len(t, *filtered.(*[]CachedItem), 1)
+	_, err = repo.Get(WithScope(ctx, "kind", "y"), 1)
+	assert.Error(t, err)
+
+	// Mutations invalidate every cached result
+	_, err = repo.Create(ctx, &CachedItem{Name: "c", Kind: "x"})
+	require.NoError(t, err)
+
+	fresh, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "changed", fresh.(*CachedItem).Name)
+	_, total, err = repo.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	count, err = repo.Count(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	newETag, err := repo.ETag(ctx, "1")
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, newETag)
+
+	// Transactions invalidate once committed
+	require.NoError(t, repo.WithTransaction(func(tx Repository) error {
+		_, err := tx.Update(ctx, 2, map[string]interface{}{"name": "updated"})
+		return err
+	}))
+	updated, err := repo.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", updated.(*CachedItem).Name)
+
+	err = repo.Delete(ctx, 3)
+	require.NoError(t, err)
+	_, total, err = repo.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+func TestWithCacheOwnerGroups(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TeamDocument{}))
+	require.NoError(t, db.Create([]*TeamDocument{
+		{Title: "red", OwnerID: "bob", TeamID: "red"},
+		{Title: "blue", OwnerID: "bob", TeamID: "blue"},
+	}).Error)
+
+	config := resource.DefaultOwnerConfig()
+	config.GroupField = "TeamID"
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{Name: "documents", Model: &TeamDocument{}}), config)
+	owned, err := NewOwnerRepository(db, res)
+	require.NoError(t, err)
+	repo := WithCache(owned, cache.NewMemory(100), time.Minute)
+	options := query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Sort: "id", Order: "asc"}
+
+	inGroups := func(groups ...interface{}) context.Context {
+		return requestctx.With(context.Background(),
+			requestctx.OwnerID.Value("ann"),
+			requestctx.OwnerGroupIDs.Value(groups))
+	}
+	titles := func(ctx context.Context) []string {
+		records, _, err := repo.List(ctx, options)
+		require.NoError(t, err)
+		names := []string{}
+		for _, document := range *records.(*[]TeamDocument) {
+			names = append(names, document.Title)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"red"}, titles(inGroups("red")))
+	assert.Equal(t, []string{"blue"}, titles(inGroups("blue")))
+	assert.Equal(t, []string{"red", "blue"}, titles(inGroups("red", "blue")))
+
+	_, err = repo.Get(inGroups("red"), uint(1))
+	require.NoError(t, err)
+	_, err = repo.Get(inGroups("blue"), uint(1))
+	assert.ErrorIs(t, err, ErrOwnerMismatch)
+
+	// The order of the groups does not matter
+	key, ok := repo.key(inGroups("red", "blue"), "list", cacheableOptions(options))
+	require.True(t, ok)
+	reversed, ok := repo.key(inGroups("blue", "red"), "list", cacheableOptions(options))
+	require.True(t, ok)
+	assert.Equal(t, key, reversed)
+}
+
+func TestWithCacheVersionEviction(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CachedItem{}))
+	require.NoError(t, db.Create(&CachedItem{Name: "a"}).Error)
+
+	store := cache.NewMemory(100)
+	repo := WithCache(NewGenericRepository(db, CachedItem{}), store, 0)
+	assert.Equal(t, "repository.CachedItem", repo.Namespace)
+	ctx := context.Background()
+
+	_, err = repo.Get(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&CachedItem{}).Where("id = ?", 1).Update("name", "changed").Error)
+
+	// A lost version is never reused, so old entries are not served
+	require.NoError(t, store.Delete(ctx, repo.versionKey()))
+	item, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "changed", item.(*CachedItem).Name)
+}
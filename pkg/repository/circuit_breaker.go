@@ -0,0 +1,420 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"gorm.io/gorm"
+)
+
+// ErrCircuitOpen is returned while the circuit breaker short-circuits repository calls
+var ErrCircuitOpen = errors.New("circuit breaker is open: database unavailable")
+
+// CircuitState represents the state of a circuit breaker
+type CircuitState string
+
+// Circuit breaker states
+const (
+	CircuitClosed   CircuitState = "closed"    // calls pass through
+	CircuitOpen     CircuitState = "open"      // calls fail fast
+	CircuitHalfOpen CircuitState = "half-open" // a single trial call is allowed
+)
+
+// CircuitBreakerConfig contains configuration for a circuit breaker
+type CircuitBreakerConfig struct {
+	// Number of consecutive failures opening the circuit (default 5)
+	FailureThreshold int
+
+	// Time the circuit stays open before a trial call is allowed (default 30s)
+	Cooldown time.Duration
+
+	// IsFailure decides whether an error counts as a database failure.
+	// By default only connection errors count: network errors and timeouts,
+	// broken or closed connections.
+	IsFailure func(err error) bool
+
+	// OnStateChange is called whenever the circuit changes state (optional)
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker tracks consecutive failures and short-circuits calls while the database is down
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+// NewCircuitBreaker creates a new circuit breaker
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isDatabaseFailure
+	}
+	return &CircuitBreaker{config: config, state: CircuitClosed, now: time.Now}
+}
+
+// isDatabaseFailure is the default failure classification. Only errors of reaching
+// the database count: network errors and timeouts, broken or closed connections.
+// Errors of the request or its data (not found, constraint violations, conflicts,
+// validation, the caller's deadline) do not.
+func isDatabaseFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, gorm.ErrInvalidDB) ||
+		// database/sql does not export the error of a closed pool
+		strings.Contains(err.Error(), "sql: database is closed")
+}
+
+// State returns the current state of the circuit
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// RetryAfter returns the remaining cooldown while the circuit is open
+func (b *CircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != CircuitOpen {
+		return 0
+	}
+	remaining := b.config.Cooldown - b.now().Sub(b.openedAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Allow reports whether a call may proceed. In the half-open state only one trial call is allowed.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// Record records the result of a call allowed by Allow
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.trial = false
+	}
+
+	if !b.config.IsFailure(err) {
+		b.failures = 0
+		b.setState(CircuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// Probe runs a health check regardless of the circuit state and records its result,
+// closing the circuit as soon as the database is reachable again
+func (b *CircuitBreaker) Probe(ctx context.Context, check func(ctx context.Context) error) error {
+	err := check(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.trial = false
+		b.setState(CircuitClosed)
+	} else if b.state != CircuitClosed {
+		// Keep the circuit open for another cooldown period
+		b.openedAt = b.now()
+		b.trial = false
+		b.setState(CircuitOpen)
+	}
+	return err
+}
+
+// StartProbing runs the health check every interval while the circuit is not closed,
+// until the context is canceled
+func (b *CircuitBreaker) StartProbing(ctx context.Context, interval time.Duration, check func(ctx context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if b.State() != CircuitClosed {
+					_ = b.Probe(ctx, check)
+				}
+			}
+		}
+	}()
+}
+
+// advance moves an open circuit to half-open once the cooldown has passed (lock must be held)
+func (b *CircuitBreaker) advance() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		b.trial = false
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+// setState changes the state and notifies the listener (lock must be held)
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, state)
+	}
+}
+
+// PingDatabase returns a health check pinging the underlying database connection
+func PingDatabase(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// CircuitBreakerRepository decorates a repository with a circuit breaker
+type CircuitBreakerRepository struct {
+	Repository Repository
+	Breaker    *CircuitBreaker
+}
+
+// NewCircuitBreakerRepository wraps a repository with a circuit breaker
+func NewCircuitBreakerRepository(repo Repository, breaker *CircuitBreaker) Repository {
+	return &CircuitBreakerRepository{Repository: repo, Breaker: breaker}
+}
+
+// call runs fn through the circuit breaker
+func (r *CircuitBreakerRepository) call(fn func() error) error {
+	if !r.Breaker.Allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	r.Breaker.Record(err)
+	return err
+}
+
+// Get retrieves a single resource by its ID
+func (r *CircuitBreakerRepository) Get(ctx context.Context, id interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.Get(ctx, id)
+		return err
+	})
+	return result, err
+}
+
+// List returns a paginated list of resources
+func (r *CircuitBreakerRepository) List(ctx context.Context, options query.QueryOptions) (result interface{}, total int64, err error) {
+	err = r.call(func() error {
+		result, total, err = r.Repository.List(ctx, options)
+		return err
+	})
+	return result, total, err
+}
+
+// Create inserts a new resource
+func (r *CircuitBreakerRepository) Create(ctx context.Context, data interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.Create(ctx, data)
+		return err
+	})
+	return result, err
+}
+
+// Update modifies an existing resource
+func (r *CircuitBreakerRepository) Update(ctx context.Context, id interface{}, data interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.Update(ctx, id, data)
+		return err
+	})
+	return result, err
+}
+
+// Delete removes a resource
+func (r *CircuitBreakerRepository) Delete(ctx context.Context, id interface{}) error {
+	return r.call(func() error {
+		return r.Repository.Delete(ctx, id)
+	})
+}
+
+// Count returns the number of resources matching the query options
+func (r *CircuitBreakerRepository) Count(ctx context.Context, options query.QueryOptions) (total int64, err error) {
+	err = r.call(func() error {
+		total, err = r.Repository.Count(ctx, options)
+		return err
+	})
+	return total, err
+}
+
+// CreateMany inserts multiple resources
+func (r *CircuitBreakerRepository) CreateMany(ctx context.Context, data interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.CreateMany(ctx, data)
+		return err
+	})
+	return result, err
+}
+
+// UpdateMany updates multiple resources
+func (r *CircuitBreakerRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (affected int64, err error) {
+	err = r.call(func() error {
+		affected, err = r.Repository.UpdateMany(ctx, ids, data)
+		return err
+	})
+	return affected, err
+}
+
+// DeleteMany removes multiple resources
+func (r *CircuitBreakerRepository) DeleteMany(ctx context.Context, ids []interface{}) (affected int64, err error) {
+	err = r.call(func() error {
+		affected, err = r.Repository.DeleteMany(ctx, ids)
+		return err
+	})
+	return affected, err
+}
+
+// Facets returns value counts if the decorated repository supports facets
+func (r *CircuitBreakerRepository) Facets(ctx context.Context, options query.QueryOptions, fields []string) (result FacetCounts, err error) {
+	provider, ok := r.Repository.(FacetProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = r.call(func() error {
+		result, err = provider.Facets(ctx, options, fields)
+		return err
+	})
+	return result, err
+}
+
+// Aggregate computes metrics if the decorated repository supports aggregations
+func (r *CircuitBreakerRepository) Aggregate(ctx context.Context, options query.QueryOptions, spec AggregateSpec) (result []AggregateRow, err error) {
+	provider, ok := r.Repository.(AggregateProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	err = r.call(func() error {
+		result, err = provider.Aggregate(ctx, options, spec)
+		return err
+	})
+	return result, err
+}
+
+// WithRelations returns a decorated repository that preloads relations
+func (r *CircuitBreakerRepository) WithRelations(relations ...string) Repository {
+	return &CircuitBreakerRepository{Repository: r.Repository.WithRelations(relations...), Breaker: r.Breaker}
+}
+
+// GetWithRelations retrieves a single resource with relations
+func (r *CircuitBreakerRepository) GetWithRelations(ctx context.Context, id interface{}, relations []string) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.GetWithRelations(ctx, id, relations)
+		return err
+	})
+	return result, err
+}
+
+// ListWithRelations returns a paginated list of resources with relations
+func (r *CircuitBreakerRepository) ListWithRelations(ctx context.Context, options query.QueryOptions, relations []string) (result interface{}, total int64, err error) {
+	err = r.call(func() error {
+		result, total, err = r.Repository.ListWithRelations(ctx, options, relations)
+		return err
+	})
+	return result, total, err
+}
+
+// Query returns the underlying query builder; calls made through it are not guarded
+func (r *CircuitBreakerRepository) Query(ctx context.Context) *gorm.DB {
+	return r.Repository.Query(ctx)
+}
+
+// FindOneBy returns the first resource matching the condition
+func (r *CircuitBreakerRepository) FindOneBy(ctx context.Context, condition map[string]interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.FindOneBy(ctx, condition)
+		return err
+	})
+	return result, err
+}
+
+// FindAllBy returns all resources matching the condition
+func (r *CircuitBreakerRepository) FindAllBy(ctx context.Context, condition map[string]interface{}) (result interface{}, err error) {
+	err = r.call(func() error {
+		result, err = r.Repository.FindAllBy(ctx, condition)
+		return err
+	})
+	return result, err
+}
+
+// WithTransaction runs fn in a transaction guarded by the circuit breaker
+func (r *CircuitBreakerRepository) WithTransaction(fn func(Repository) error) error {
+	return r.call(func() error {
+		return r.Repository.WithTransaction(fn)
+	})
+}
+
+// BulkCreate inserts multiple resources
+func (r *CircuitBreakerRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	return r.call(func() error {
+		return r.Repository.BulkCreate(ctx, data)
+	})
+}
+
+// BulkUpdate updates resources matching the condition
+func (r *CircuitBreakerRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	return r.call(func() error {
+		return r.Repository.BulkUpdate(ctx, condition, updates)
+	})
+}
+
+// GetIDFieldName returns the name of the ID field
+func (r *CircuitBreakerRepository) GetIDFieldName() string {
+	return r.Repository.GetIDFieldName()
+}
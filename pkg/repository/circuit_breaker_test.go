@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	var transitions []CircuitState
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+		OnStateChange:    func(from, to CircuitState) { transitions = append(transitions, to) },
+	})
+	breaker.now = func() time.Time { return now }
+	dbErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	// Errors of requests and their data do not count as failures
+	require.True(t, breaker.Allow())
+	breaker.Record(dbErr)
+	for _, err := range []error{
+		gorm.ErrRecordNotFound,
+		gorm.ErrDuplicatedKey,
+		ErrOwnerMismatch,
+		context.DeadlineExceeded,
+		errors.New("UNIQUE constraint failed: products.name"),
+	} {
+		require.True(t, breaker.Allow())
+		breaker.Record(err)
+	}
+	require.True(t, breaker.Allow())
+	breaker.Record(dbErr)
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	require.True(t, breaker.Allow())
+	breaker.Record(dbErr)
+	assert.Equal(t, CircuitOpen, breaker.State())
+	assert.False(t, breaker.Allow())
+	assert.Equal(t, time.Minute, breaker.RetryAfter())
+
+	// After the cooldown a single trial call is allowed
+	now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	assert.True(t, breaker.Allow())
+	assert.False(t, breaker.Allow())
+
+	// A failed trial opens the circuit again
+	breaker.Record(dbErr)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	// A successful health probe closes it
+	require.NoError(t, breaker.Probe(context.Background(), func(ctx context.Context) error { return nil }))
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitClosed}, transitions)
+}
+
+func TestCircuitBreakerRepository(t *testing.T) {
+	db := setupTestDB(t)
+	res := resource.NewResource(resource.ResourceConfig{Name: "products", Model: TestProduct{}})
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Hour})
+	repo := NewCircuitBreakerRepository(NewGenericRepository(db, res), breaker)
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, &TestProduct{Name: "Phone"})
+	require.NoError(t, err)
+	_, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// Simulate the database going down
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	for i := 0; i < 2; i++ {
+		_, err = repo.Get(ctx, 1)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	assert.Equal(t, CircuitOpen, breaker.State())
+	_, err = repo.Get(ctx, 1)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	_, err = repo.Count(ctx, query.QueryOptions{Resource: res})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Health probes keep failing while the database is down
+	assert.Error(t, breaker.Probe(ctx, PingDatabase(db)))
+	assert.Equal(t, CircuitOpen, breaker.State())
+}
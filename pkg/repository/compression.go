@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCompressionThreshold is the size in bytes from which compressed columns are
+// compressed, unless their compression_threshold tag sets another
+const DefaultCompressionThreshold = 1024
+
+// Compressor compresses the values of compressed columns. Gzip is registered; other
+// algorithms (e.g. zstd) are plugged in with RegisterCompressor.
+type Compressor interface {
+	// Name identifies the compressor in compression tags, e.g. "gzip"
+	Name() string
+
+	// Magic is the prefix of compressed values, which tells them apart on read. It
+	// must not start a JSON document.
+	Magic() []byte
+
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+var compressors sync.Map
+
+// RegisterCompressor makes a compressor available to compressed columns, replacing
+// the compressor of the same name
+func RegisterCompressor(compressor Compressor) {
+	compressors.Store(strings.ToLower(compressor.Name()), compressor)
+}
+
+func init() {
+	RegisterCompressor(GzipCompressor{})
+	schema.RegisterSerializer("compressed", CompressedJSONSerializer{})
+}
+
+// GzipCompressor compresses values with gzip
+type GzipCompressor struct {
+	// Level of compression (default gzip.DefaultCompression)
+	Level int
+}
+
+// Name returns "gzip"
+func (GzipCompressor) Name() string { return "gzip" }
+
+// Magic returns the gzip header
+func (GzipCompressor) Magic() []byte { return []byte{0x1f, 0x8b} }
+
+// Compress gzips data
+func (c GzipCompressor) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress gunzips data
+func (GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// CompressedJSONSerializer is the GORM serializer of compressed JSON columns, used
+// with the serializer:compressed tag. Values are stored as JSON, compressed when their
+// JSON reaches the threshold of the field and compression makes them smaller:
+//
+//	Settings map[string]interface{} `gorm:"serializer:compressed;compression:gzip;compression_threshold:4096;type:blob"`
+//
+// compression names a registered Compressor (default gzip) and compression_threshold
+// sets the threshold in bytes (default DefaultCompressionThreshold). Compressed values
+// are binary, so columns should be of a binary type (blob, bytea). Values are
+// recognized by the magic of their compressor on read, so plain JSON stored before a
+// column was compressed, and values of another registered compressor, stay readable.
+type CompressedJSONSerializer struct{}
+
+// Scan decompresses and unmarshals a stored value into the field
+func (CompressedJSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var data []byte
+		switch v := dbValue.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			return fmt.Errorf("failed to unmarshal compressed value of %s: %#v", field.Name, dbValue)
+		}
+
+		var err error
+		if data, err = decompressValue(data); err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", field.Name, err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, fieldValue.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value marshals the field to JSON, compressed if it reaches the threshold
+func (CompressedJSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	data, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		if field.TagSettings["NOT NULL"] != "" {
+			return "", nil
+		}
+		return nil, nil
+	}
+
+	compressor, threshold, err := fieldCompression(field)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < threshold {
+		return data, nil
+	}
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", field.Name, err)
+	}
+	if len(compressed) >= len(data) {
+		return data, nil
+	}
+	return compressed, nil
+}
+
+// fieldCompression returns the compressor and threshold set by the tags of a field
+func fieldCompression(field *schema.Field) (Compressor, int, error) {
+	name := field.TagSettings["COMPRESSION"]
+	if name == "" {
+		name = GzipCompressor{}.Name()
+	}
+	compressor, ok := compressors.Load(strings.ToLower(name))
+	if !ok {
+		return nil, 0, fmt.Errorf("field %s: unknown compression %q", field.Name, name)
+	}
+
+	threshold := DefaultCompressionThreshold
+	if value := field.TagSettings["COMPRESSION_THRESHOLD"]; value != "" {
+		var err error
+		if threshold, err = strconv.Atoi(value); err != nil {
+			return nil, 0, fmt.Errorf("field %s: invalid compression threshold %q", field.Name, value)
+		}
+	}
+	return compressor.(Compressor), threshold, nil
+}
+
+// decompressValue decompresses a stored value with the compressor whose magic it
+// starts with, returning other values unchanged
+func decompressValue(data []byte) ([]byte, error) {
+	var decompressed []byte
+	var err error
+	found := false
+	compressors.Range(func(_, value interface{}) bool {
+		compressor := value.(Compressor)
+		if magic := compressor.Magic(); len(magic) > 0 && bytes.HasPrefix(data, magic) {
+			decompressed, err = compressor.Decompress(data)
+			found = true
+			return false
+		}
+		return true
+	})
+	if !found {
+		return data, nil
+	}
+	return decompressed, err
+}
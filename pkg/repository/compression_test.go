@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type CompressedConfig struct {
+	ID       uint                   `json:"id" gorm:"primaryKey"`
+	Settings map[string]interface{} `json:"settings" gorm:"serializer:compressed;compression_threshold:64;type:blob"`
+	Layout   []string               `json:"layout" gorm:"serializer:compressed;compression:tagged;compression_threshold:1;type:blob"`
+}
+
+// taggedCompressor gzips values behind its own magic, to test pluggable compressors
+type taggedCompressor struct{}
+
+func (taggedCompressor) Name() string  { return "tagged" }
+func (taggedCompressor) Magic() []byte { return []byte("\x00tag") }
+
+func (taggedCompressor) Compress(data []byte) ([]byte, error) {
+	compressed, err := GzipCompressor{}.Compress(data)
+	return append([]byte("\x00tag"), compressed...), err
+}
+
+func (taggedCompressor) Decompress(data []byte) ([]byte, error) {
+	return GzipCompressor{}.Decompress(bytes.TrimPrefix(data, []byte("\x00tag")))
+}
+
+func TestCompressedJSONColumns(t *testing.T) {
+	RegisterCompressor(taggedCompressor{})
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CompressedConfig{}))
+	res := resource.NewResource(resource.ResourceConfig{Name: "compressed_configs", Model: CompressedConfig{}})
+	repo := NewGenericRepositoryWithResource(db, res)
+	ctx := context.Background()
+
+	stored := func(column string, id uint) []byte {
+		var value []byte
+		require.NoError(t, db.Table("compressed_configs").Select(column).Where("id = ?", id).Row().Scan(&value))
+		return value
+	}
+
+	// Values reaching the threshold are gzipped
+	large := map[string]interface{}{"theme": strings.Repeat("dark ", 100)}
+	layout := []string{strings.Repeat("column ", 20)}
+	_, err = repo.Create(ctx, &CompressedConfig{Settings: large, Layout: layout})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(stored("settings", 1), []byte{0x1f, 0x8b}))
+	assert.Less(t, len(stored("settings", 1)), 500)
+	assert.True(t, bytes.HasPrefix(stored("layout", 1), []byte("\x00tag")))
+
+	record, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, large, record.(*CompressedConfig).Settings)
+	assert.Equal(t, layout, record.(*CompressedConfig).Layout)
+
+	// Smaller values are stored as JSON
+	_, err = repo.Create(ctx, &CompressedConfig{Settings: map[string]interface{}{"theme": "dark"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"theme":"dark"}`, string(stored("settings", 2)))
+
+	// JSON stored before compression stays readable
+	require.NoError(t, db.Exec(`UPDATE compressed_configs SET settings = '{"theme":"light"}' WHERE id = 1`).Error)
+	list, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Sort: "id", Order: "asc"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Equal(t, "light", (*list.(*[]CompressedConfig))[0].Settings["theme"])
+
+	// Unknown compressors fail writes
+	type UnknownCompression struct {
+		ID       uint
+		Settings map[string]interface{} `gorm:"serializer:compressed;compression:brotli;compression_threshold:1"`
+	}
+	require.NoError(t, db.AutoMigrate(&UnknownCompression{}))
+	err = db.Create(&UnknownCompression{Settings: map[string]interface{}{"a": 1}}).Error
+	assert.ErrorContains(t, err, `unknown compression "brotli"`)
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/suranig/refine-gin/pkg/expr"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// loadFields prepares records read from the database: it applies the Deserialize hooks
+// of the resource fields and computes the server-side computed fields
+func (r *GenericRepository) loadFields(ctx context.Context, data interface{}) error {
+	if err := r.deserializeFields(ctx, data); err != nil {
+		return err
+	}
+	return r.computeFields(ctx, data)
+}
+
+// computeFields sets the server-side computed fields of the resource (see
+// resource.ComputedFieldConfig) on a record or a slice of records. Maps are left
+// unchanged, as their values are not complete records.
+func (r *GenericRepository) computeFields(ctx context.Context, data interface{}) error {
+	if r.Resource == nil || data == nil {
+		return nil
+	}
+	fields := resource.ComputedFields(r.Resource)
+	if len(fields) == 0 {
+		return nil
+	}
+	programs := make([]*expr.Program, len(fields))
+	for i, field := range fields {
+		program, err := field.Computed.Compile()
+		if err != nil {
+			return fmt.Errorf("computed field %s: %w", field.Name, err)
+		}
+		programs[i] = program
+	}
+
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Slice {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			if err := r.computeRecordFields(ctx, item.Interface(), fields, programs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return r.computeRecordFields(ctx, data, fields, programs)
+}
+
+// computeRecordFields evaluates the computed fields of a pointer to a struct in order,
+// so each expression sees the values computed before it
+func (r *GenericRepository) computeRecordFields(ctx context.Context, record interface{}, fields []resource.Field, programs []*expr.Program) error {
+	value := reflect.ValueOf(record)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	for i, field := range fields {
+		schemaField, err := lookUpSchemaField(r.DB, record, field.Name)
+		if err != nil {
+			return fmt.Errorf("computed field %s: %w", field.Name, err)
+		}
+		computed, err := programs[i].Eval(ctx, map[string]interface{}{"record": record})
+		if err != nil {
+			return fmt.Errorf("computed field %s: %w", field.Name, err)
+		}
+		if err := schemaField.Set(ctx, value.Elem(), computed); err != nil {
+			return fmt.Errorf("computed field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ComputedOrderLine struct {
+	ID       uint    `json:"id" gorm:"primaryKey"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+	Total    float64 `json:"total"`
+	Label    string  `json:"label" gorm:"-"`
+}
+
+func TestComputedFields(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ComputedOrderLine{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "order_lines",
+		Model: ComputedOrderLine{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "price", Type: "float64"},
+			{Name: "quantity", Type: "int"},
+			{Name: "total", Type: "float64", Computed: &resource.ComputedFieldConfig{
+				DependsOn:  []string{"price", "quantity"},
+				Expression: "record.price * record.quantity",
+				Persist:    true,
+			}},
+			{Name: "label", Type: "string", Computed: &resource.ComputedFieldConfig{
+				Expression:   `string(record.quantity) + " x " + string(record.total)`,
+				ComputeOrder: 1,
+			}},
+		},
+	})
+	repo := NewGenericRepositoryWithResource(db, res)
+	ctx := context.Background()
+	stored := func(id uint) ComputedOrderLine {
+		var line ComputedOrderLine
+		require.NoError(t, db.First(&line, id).Error)
+		return line
+	}
+
+	created, err := repo.Create(ctx, &ComputedOrderLine{Price: 2.5, Quantity: 4})
+	require.NoError(t, err)
+	line := created.(*ComputedOrderLine)
+	assert.Equal(t, 10.0, line.Total)
+	assert.Equal(t, "4 x 10", line.Label)
+	assert.Equal(t, 10.0, stored(line.ID).Total)
+
+	// Updates recompute persisted values from the complete record
+	_, err = repo.Update(ctx, line.ID, map[string]interface{}{"quantity": 2})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, stored(line.ID).Total)
+
+	// Reads compute values that are not stored
+	require.NoError(t, db.Create(&ComputedOrderLine{Price: 1, Quantity: 3, Total: 3}).Error)
+	list, _, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	lines := *list.(*[]ComputedOrderLine)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "2 x 5", lines[0].Label)
+	assert.Equal(t, "3 x 3", lines[1].Label)
+
+	// Client-side fields are left to the frontend
+	res.GetFields()[4].Computed.ClientSide = true
+	record, err := repo.Get(ctx, line.ID)
+	require.NoError(t, err)
+	assert.Empty(t, record.(*ComputedOrderLine).Label)
+}
+
+func TestComputedFieldsInvalidExpression(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ComputedOrderLine{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "order_lines",
+		Model: ComputedOrderLine{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "total", Type: "float64", Computed: &resource.ComputedFieldConfig{
+				DependsOn:  []string{"price"},
+				Expression: "record.price * record.quantity",
+			}},
+		},
+	})
+	_, err = NewGenericRepositoryWithResource(db, res).Create(context.Background(), &ComputedOrderLine{Price: 1, Quantity: 1})
+	assert.ErrorContains(t, err, "computed field total")
+}
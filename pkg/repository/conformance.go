@@ -0,0 +1,428 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// ConformanceRecord is the model stored by repositories under conformance tests
+type ConformanceRecord struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Score    int    `json:"score"`
+}
+
+// ConformanceFactory creates an empty repository storing ConformanceRecord for the
+// resource. It is called once per test case; use t.Cleanup to release resources.
+type ConformanceFactory func(t *testing.T, res resource.Resource) Repository
+
+// ConformanceOptions skips parts of the contract a repository does not support
+type ConformanceOptions struct {
+	// SkipTransactions skips the WithTransaction commit and rollback tests
+	SkipTransactions bool
+}
+
+// ConformanceResource returns the resource passed to conformance factories
+func ConformanceResource() resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  "conformance_records",
+		Model: ConformanceRecord{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "category", Type: "string"},
+			{Name: "score", Type: "int"},
+		},
+		FilterableFields: []string{"id", "name", "category", "score"},
+		SortableFields:   []string{"id", "name", "score"},
+		SearchableFields: []string{"name"},
+	})
+}
+
+// RunConformanceTests verifies that a repository implements the Repository contract the
+// way the generic handlers expect: filtering, sorting, pagination, bulk operations,
+// transactions and not-found errors.
+func RunConformanceTests(t *testing.T, factory ConformanceFactory) {
+	RunConformanceTestsWithOptions(t, factory, ConformanceOptions{})
+}
+
+// RunConformanceTestsWithOptions runs the conformance tests with options
+func RunConformanceTestsWithOptions(t *testing.T, factory ConformanceFactory, opts ConformanceOptions) {
+	res := ConformanceResource()
+	ctx := context.Background()
+
+	// setup returns a repository seeded with the conformance fixtures
+	setup := func(t *testing.T) (Repository, []ConformanceRecord) {
+		repo := factory(t, res)
+		seeded := make([]ConformanceRecord, 0, len(conformanceFixtures))
+		for _, fixture := range conformanceFixtures {
+			record := fixture
+			created, err := repo.Create(ctx, &record)
+			if err != nil {
+				t.Fatalf("Create(%s): %v", fixture.Name, err)
+			}
+			seeded = append(seeded, mustRecord(t, created))
+		}
+		return repo, seeded
+	}
+
+	listOptions := func(opts query.QueryOptions) query.QueryOptions {
+		opts.Resource = res
+		if opts.Page == 0 {
+			opts.Page = 1
+		}
+		if opts.PerPage == 0 {
+			opts.PerPage = 100
+		}
+		return opts
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo, seeded := setup(t)
+		ids := map[uint]bool{}
+		for _, record := range seeded {
+			if record.ID == 0 {
+				t.Fatalf("Create did not assign an ID to %s", record.Name)
+			}
+			ids[record.ID] = true
+		}
+		if len(ids) != len(seeded) {
+			t.Errorf("Create assigned duplicate IDs: %v", seeded)
+		}
+
+		got, err := repo.Get(ctx, seeded[1].ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if record := mustRecord(t, got); record != seeded[1] {
+			t.Errorf("Get returned %+v, want %+v", record, seeded[1])
+		}
+
+		// Handlers pass IDs as strings taken from the URL
+		if _, err := repo.Get(ctx, fmt.Sprint(seeded[1].ID)); err != nil {
+			t.Errorf("Get with string ID: %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo, _ := setup(t)
+		if _, err := repo.Get(ctx, 99999); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Get of missing record returned %v, want gorm.ErrRecordNotFound", err)
+		}
+		if _, err := repo.FindOneBy(ctx, map[string]interface{}{"name": "missing"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindOneBy of missing record returned %v, want gorm.ErrRecordNotFound", err)
+		}
+		if _, err := repo.Update(ctx, 99999, map[string]interface{}{"name": "x"}); err == nil {
+			t.Error("Update of missing record returned no error")
+		}
+	})
+
+	t.Run("ListPagination", func(t *testing.T) {
+		repo, seeded := setup(t)
+		total := len(seeded)
+
+		cases := []struct {
+			page, perPage, want int
+		}{
+			{1, 4, 4},
+			{2, 4, 2},
+			{3, 4, 0},
+			{1, 100, total},
+		}
+		for _, tc := range cases {
+			result, count, err := repo.List(ctx, listOptions(query.QueryOptions{Page: tc.page, PerPage: tc.perPage, Sort: "id", Order: "asc"}))
+			if err != nil {
+				t.Fatalf("List page %d/%d: %v", tc.page, tc.perPage, err)
+			}
+			if count != int64(total) {
+				t.Errorf("List page %d/%d total = %d, want %d", tc.page, tc.perPage, count, total)
+			}
+			if records := mustRecords(t, result); len(records) != tc.want {
+				t.Errorf("List page %d/%d returned %d records, want %d", tc.page, tc.perPage, len(records), tc.want)
+			}
+		}
+
+		// Pages do not overlap and cover every record
+		seen := map[uint]bool{}
+		for page := 1; page <= 3; page++ {
+			result, _, err := repo.List(ctx, listOptions(query.QueryOptions{Page: page, PerPage: 2, Sort: "id", Order: "asc"}))
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for _, record := range mustRecords(t, result) {
+				if seen[record.ID] {
+					t.Errorf("record %d returned on more than one page", record.ID)
+				}
+				seen[record.ID] = true
+			}
+		}
+		if len(seen) != total {
+			t.Errorf("pages covered %d records, want %d", len(seen), total)
+		}
+
+		result, count, err := repo.List(ctx, listOptions(query.QueryOptions{DisablePagination: true}))
+		if err != nil {
+			t.Fatalf("List without pagination: %v", err)
+		}
+		if records := mustRecords(t, result); len(records) != total || count != int64(total) {
+			t.Errorf("List without pagination returned %d records (total %d), want %d", len(records), count, total)
+		}
+	})
+
+	t.Run("ListSorting", func(t *testing.T) {
+		repo, _ := setup(t)
+		for _, order := range []string{"asc", "desc"} {
+			result, _, err := repo.List(ctx, listOptions(query.QueryOptions{Sort: "score", Order: order}))
+			if err != nil {
+				t.Fatalf("List sorted %s: %v", order, err)
+			}
+			scores := []int{}
+			for _, record := range mustRecords(t, result) {
+				scores = append(scores, record.Score)
+			}
+			sorted := sort.SliceIsSorted(scores, func(i, j int) bool {
+				if order == "desc" {
+					return scores[i] > scores[j]
+				}
+				return scores[i] < scores[j]
+			})
+			if !sorted {
+				t.Errorf("List sorted by score %s returned %v", order, scores)
+			}
+		}
+	})
+
+	t.Run("ListFiltering", func(t *testing.T) {
+		repo, seeded := setup(t)
+		cases := []struct {
+			name string
+			opts query.QueryOptions
+			want []string
+		}{
+			{"equality", query.QueryOptions{Filters: map[string]interface{}{"category": "tools"}}, []string{"Drill", "Hammer", "Saw"}},
+			{"eq", filterOptions("category", "eq", "garden"), []string{"Rake", "Shovel"}},
+			{"ne", filterOptions("category", "ne", "tools"), []string{"Lamp", "Rake", "Shovel"}},
+			{"gt", filterOptions("score", "gt", "30"), []string{"Lamp", "Rake", "Saw"}},
+			{"gte", filterOptions("score", "gte", "30"), []string{"Hammer", "Lamp", "Rake", "Saw"}},
+			{"lt", filterOptions("score", "lt", "30"), []string{"Drill", "Shovel"}},
+			{"lte", filterOptions("score", "lte", "30"), []string{"Drill", "Hammer", "Shovel"}},
+			{"contains", filterOptions("name", "contains", "am"), []string{"Hammer", "Lamp"}},
+			{"startswith", filterOptions("name", "startswith", "S"), []string{"Saw", "Shovel"}},
+			{"endswith", filterOptions("name", "endswith", "er"), []string{"Hammer"}},
+			{"in", filterOptions("name", "in", "Saw,Lamp"), []string{"Lamp", "Saw"}},
+			{"id", filterOptions("id", "eq", fmt.Sprint(seeded[0].ID)), []string{seeded[0].Name}},
+			{"search", query.QueryOptions{Search: "ham"}, []string{"Hammer"}},
+			{"combined", query.QueryOptions{
+				Filters:         map[string]interface{}{"category": "tools"},
+				AdvancedFilters: []query.Filter{{Field: "score", Operator: "gte", Value: "30"}},
+			}, []string{"Hammer", "Saw"}},
+		}
+		for _, tc := range cases {
+			opts := listOptions(tc.opts)
+			result, total, err := repo.List(ctx, opts)
+			if err != nil {
+				t.Errorf("%s: List: %v", tc.name, err)
+				continue
+			}
+			if names := recordNames(mustRecords(t, result)); fmt.Sprint(names) != fmt.Sprint(tc.want) {
+				t.Errorf("%s: List returned %v, want %v", tc.name, names, tc.want)
+			}
+			if total != int64(len(tc.want)) {
+				t.Errorf("%s: List total = %d, want %d", tc.name, total, len(tc.want))
+			}
+			count, err := repo.Count(ctx, opts)
+			if err != nil {
+				t.Errorf("%s: Count: %v", tc.name, err)
+			} else if count != int64(len(tc.want)) {
+				t.Errorf("%s: Count = %d, want %d", tc.name, count, len(tc.want))
+			}
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo, seeded := setup(t)
+		updated, err := repo.Update(ctx, fmt.Sprint(seeded[0].ID), map[string]interface{}{"score": 99})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		want := seeded[0]
+		want.Score = 99
+		if record := mustRecord(t, updated); record != want {
+			t.Errorf("Update returned %+v, want %+v", record, want)
+		}
+		got, err := repo.Get(ctx, seeded[0].ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if record := mustRecord(t, got); record != want {
+			t.Errorf("Get after Update returned %+v, want %+v", record, want)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo, seeded := setup(t)
+		if err := repo.Delete(ctx, fmt.Sprint(seeded[0].ID)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.Get(ctx, seeded[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Get after Delete returned %v, want gorm.ErrRecordNotFound", err)
+		}
+		if count, _ := repo.Count(ctx, listOptions(query.QueryOptions{})); count != int64(len(seeded)-1) {
+			t.Errorf("Count after Delete = %d, want %d", count, len(seeded)-1)
+		}
+	})
+
+	t.Run("BulkOperations", func(t *testing.T) {
+		repo, seeded := setup(t)
+
+		created, err := repo.CreateMany(ctx, &[]ConformanceRecord{
+			{Name: "Ladder", Category: "tools", Score: 5},
+			{Name: "Hose", Category: "garden", Score: 15},
+		})
+		if err != nil {
+			t.Fatalf("CreateMany: %v", err)
+		}
+		records := mustRecords(t, created)
+		if len(records) != 2 || records[0].ID == 0 || records[1].ID == 0 {
+			t.Errorf("CreateMany returned %+v, want two records with IDs", records)
+		}
+
+		updated, err := repo.UpdateMany(ctx, []interface{}{seeded[0].ID, seeded[1].ID}, map[string]interface{}{"category": "archived"})
+		if err != nil {
+			t.Fatalf("UpdateMany: %v", err)
+		}
+		if updated != 2 {
+			t.Errorf("UpdateMany affected %d records, want 2", updated)
+		}
+		archived, err := repo.FindAllBy(ctx, map[string]interface{}{"category": "archived"})
+		if err != nil {
+			t.Fatalf("FindAllBy: %v", err)
+		}
+		if names := recordNames(mustRecords(t, archived)); fmt.Sprint(names) != fmt.Sprint(recordNames(seeded[:2])) {
+			t.Errorf("FindAllBy returned %v after UpdateMany", names)
+		}
+
+		found, err := repo.FindOneBy(ctx, map[string]interface{}{"name": "Hose"})
+		if err != nil {
+			t.Fatalf("FindOneBy: %v", err)
+		}
+		if record := mustRecord(t, found); record.Category != "garden" {
+			t.Errorf("FindOneBy returned %+v", record)
+		}
+
+		deleted, err := repo.DeleteMany(ctx, []interface{}{seeded[0].ID, seeded[1].ID, 99999})
+		if err != nil {
+			t.Fatalf("DeleteMany: %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("DeleteMany deleted %d records, want 2", deleted)
+		}
+		if count, _ := repo.Count(ctx, listOptions(query.QueryOptions{})); count != int64(len(seeded)) {
+			t.Errorf("Count after bulk operations = %d, want %d", count, len(seeded))
+		}
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		if opts.SkipTransactions {
+			t.Skip("transactions are not supported")
+		}
+		repo, seeded := setup(t)
+
+		err := repo.WithTransaction(func(tx Repository) error {
+			_, err := tx.Create(ctx, &ConformanceRecord{Name: "Committed", Category: "tx"})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTransaction: %v", err)
+		}
+
+		rollback := errors.New("rollback")
+		err = repo.WithTransaction(func(tx Repository) error {
+			if _, err := tx.Create(ctx, &ConformanceRecord{Name: "Rolled back", Category: "tx"}); err != nil {
+				return err
+			}
+			if err := tx.Delete(ctx, seeded[0].ID); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Errorf("WithTransaction returned %v, want the callback error", err)
+		}
+
+		result, err := repo.FindAllBy(ctx, map[string]interface{}{"category": "tx"})
+		if err != nil {
+			t.Fatalf("FindAllBy: %v", err)
+		}
+		if names := recordNames(mustRecords(t, result)); fmt.Sprint(names) != "[Committed]" {
+			t.Errorf("records after transactions = %v, want [Committed]", names)
+		}
+		if _, err := repo.Get(ctx, seeded[0].ID); err != nil {
+			t.Errorf("record deleted in a rolled back transaction is missing: %v", err)
+		}
+	})
+}
+
+// conformanceFixtures are created by every conformance test case
+var conformanceFixtures = []ConformanceRecord{
+	{Name: "Hammer", Category: "tools", Score: 30},
+	{Name: "Drill", Category: "tools", Score: 10},
+	{Name: "Rake", Category: "garden", Score: 40},
+	{Name: "Saw", Category: "tools", Score: 50},
+	{Name: "Lamp", Category: "home", Score: 35},
+	{Name: "Shovel", Category: "garden", Score: 20},
+}
+
+// filterOptions returns query options with a single advanced filter
+func filterOptions(field, operator, value string) query.QueryOptions {
+	return query.QueryOptions{AdvancedFilters: []query.Filter{{Field: field, Operator: operator, Value: value}}}
+}
+
+// mustRecord converts a repository result into a ConformanceRecord
+func mustRecord(t *testing.T, value interface{}) ConformanceRecord {
+	t.Helper()
+	var record ConformanceRecord
+	if err := convertConformance(value, &record); err != nil {
+		t.Fatalf("unexpected result %T: %v", value, err)
+	}
+	return record
+}
+
+// mustRecords converts a repository list result into ConformanceRecords
+func mustRecords(t *testing.T, value interface{}) []ConformanceRecord {
+	t.Helper()
+	var records []ConformanceRecord
+	if err := convertConformance(value, &records); err != nil {
+		t.Fatalf("unexpected result %T: %v", value, err)
+	}
+	return records
+}
+
+// convertConformance round-trips a value through JSON, accepting structs, pointers,
+// slices and maps alike
+func convertConformance(value interface{}, dest interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// recordNames returns the sorted names of records
+func recordNames(records []ConformanceRecord) []string {
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = record.Name
+	}
+	sort.Strings(names)
+	return names
+}
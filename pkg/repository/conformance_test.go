@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var conformanceDatabases int64
+
+func TestGenericRepositoryConformance(t *testing.T) {
+	RunConformanceTests(t, func(t *testing.T, res resource.Resource) Repository {
+		name := fmt.Sprintf("file:conformance_%d?mode=memory&cache=shared", atomic.AddInt64(&conformanceDatabases, 1))
+		db, err := gorm.Open(sqlite.Open(name), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, _ := db.DB()
+		t.Cleanup(func() { sqlDB.Close() })
+		if err := db.AutoMigrate(res.GetModel()); err != nil {
+			t.Fatal(err)
+		}
+		return NewGenericRepositoryWithResource(db, res)
+	})
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"reflect"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exactCountBelow is the number of rows below which estimated counts are replaced
+// with exact counts, which are cheap at that size and exact right after inserts
+const exactCountBelow = 10000
+
+// countList counts the records of a list query, exactly or, in the estimated count
+// mode, from the planner statistics when possible
+func (r *GenericRepository) countList(tx *gorm.DB, mode resource.CountMode) (int64, error) {
+	if mode == resource.CountEstimated {
+		if estimate, ok := r.estimateCount(tx); ok && estimate >= exactCountBelow {
+			return estimate, nil
+		}
+	}
+
+	var total int64
+	err := tx.Model(r.Model).Count(&total).Error
+	return total, err
+}
+
+// estimateCount returns the number of rows of the table of an unfiltered query from
+// pg_class on PostgreSQL. false is returned for filtered queries, other databases and
+// tables without statistics.
+func (r *GenericRepository) estimateCount(tx *gorm.DB) (int64, bool) {
+	if tx.Dialector.Name() != "postgres" || hasConditions(tx) {
+		return 0, false
+	}
+
+	table := tx.Statement.Table
+	if table == "" {
+		stmt := &gorm.Statement{DB: r.DB}
+		if err := stmt.Parse(r.Model); err != nil {
+			return 0, false
+		}
+		table = stmt.Schema.Table
+	}
+
+	var estimate *float64
+	err := r.DB.WithContext(tx.Statement.Context).
+		Raw("SELECT reltuples FROM pg_class WHERE oid = to_regclass(?)", table).
+		Scan(&estimate).Error
+	// Tables never analyzed have no estimate (-1)
+	if err != nil || estimate == nil || *estimate < 0 {
+		return 0, false
+	}
+	return int64(*estimate), true
+}
+
+// hasConditions reports whether a query has WHERE conditions
+func hasConditions(tx *gorm.DB) bool {
+	where, ok := tx.Statement.Clauses["WHERE"]
+	if !ok {
+		return false
+	}
+	conditions, ok := where.Expression.(clause.Where)
+	return !ok || len(conditions.Exprs) > 0
+}
+
+// countPage drops the record read past the page in the CountNone mode and returns the
+// lower bound of the total: the records up to the page, plus one when a next page
+// exists
+func countPage(result interface{}, options query.QueryOptions) int64 {
+	records := reflect.ValueOf(result).Elem()
+	read := records.Len()
+	if options.DisablePagination {
+		return int64(read)
+	}
+	if read > options.PerPage {
+		records.Set(records.Slice(0, options.PerPage))
+	}
+	return int64((options.Page-1)*options.PerPage + read)
+}
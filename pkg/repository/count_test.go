@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type CountedEvent struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Kind string `json:"kind"`
+}
+
+func TestListCountModes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CountedEvent{}))
+	require.NoError(t, db.Create(&[]CountedEvent{
+		{Kind: "click"}, {Kind: "view"}, {Kind: "click"}, {Kind: "view"}, {Kind: "click"},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "events", Model: CountedEvent{}})
+	repo := NewGenericRepositoryWithResource(db, res)
+	list := func(options query.QueryOptions) ([]CountedEvent, int64) {
+		options.Resource = res
+		options.Sort, options.Order = "id", "asc"
+		result, total, err := repo.List(context.Background(), options)
+		require.NoError(t, err)
+		return *result.(*[]CountedEvent), total
+	}
+
+	// Without a count, the page is trimmed and the total is a lower bound
+	events, total := list(query.QueryOptions{Page: 1, PerPage: 2, CountMode: resource.CountNone})
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(3), total)
+
+	events, total = list(query.QueryOptions{Page: 3, PerPage: 2, CountMode: resource.CountNone})
+	require.Len(t, events, 1)
+	assert.Equal(t, uint(5), events[0].ID)
+	assert.Equal(t, int64(5), total)
+
+	events, total = list(query.QueryOptions{Page: 2, PerPage: 2, CountMode: resource.CountNone, Filters: map[string]interface{}{"kind": "click"}})
+	assert.Len(t, events, 1)
+	assert.Equal(t, int64(3), total)
+
+	events, total = list(query.QueryOptions{DisablePagination: true, CountMode: resource.CountNone})
+	assert.Len(t, events, 5)
+	assert.Equal(t, int64(5), total)
+
+	// Estimates fall back to exact counts outside PostgreSQL
+	_, total = list(query.QueryOptions{Page: 1, PerPage: 2, CountMode: resource.CountEstimated})
+	assert.Equal(t, int64(5), total)
+
+	// Lists with relations count the same way
+	result, total, err := repo.(*GenericRepository).ListWithRelations(context.Background(),
+		query.QueryOptions{Resource: res, Page: 1, PerPage: 4, CountMode: resource.CountNone}, nil)
+	require.NoError(t, err)
+	assert.Len(t, *result.(*[]CountedEvent), 4)
+	assert.Equal(t, int64(5), total)
+}
+
+func TestListWithoutCountSkipsCountQuery(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&CountedEvent{}))
+
+	var statements []string
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}))
+	res := resource.NewResource(resource.ResourceConfig{Name: "events", Model: CountedEvent{}})
+	repo := NewGenericRepositoryWithResource(db, res)
+
+	_, _, err = repo.List(context.Background(), query.QueryOptions{Resource: res, Page: 1, PerPage: 10, CountMode: resource.CountNone})
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.NotContains(t, statements[0], "count(")
+	assert.Contains(t, statements[0], "LIMIT 11")
+}
+
+func TestHasConditions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	assert.False(t, hasConditions(db.Model(&CountedEvent{})))
+	assert.False(t, hasConditions(db.Model(&CountedEvent{}).Order("id")))
+	assert.True(t, hasConditions(db.Model(&CountedEvent{}).Where("kind = ?", "click")))
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/suranig/refine-gin/pkg/query"
+)
+
+// FacetCounts maps a field name to the number of records for each of its values
+type FacetCounts map[string]map[string]int64
+
+// FacetProvider is implemented by repositories able to count records per field value
+type FacetProvider interface {
+	Facets(ctx context.Context, options query.QueryOptions, fields []string) (FacetCounts, error)
+}
+
+// facetNullKey is the key used for records without a value
+const facetNullKey = "null"
+
+// Facets returns value counts for each field under the current filters using GROUP BY
+func (r *GenericRepository) Facets(ctx context.Context, options query.QueryOptions, fields []string) (FacetCounts, error) {
+	// Sorting does not apply to grouped results
+	options.Sort = ""
+
+	result := make(FacetCounts, len(fields))
+	for _, field := range fields {
+		column, err := r.fieldColumn(field)
+		if err != nil {
+			return nil, err
+		}
+
+		tx := options.Apply(r.scoped(ctx).Model(r.Model))
+		rows, err := tx.Select(fmt.Sprintf("`%s` AS value, COUNT(*) AS count", column)).
+			Group(fmt.Sprintf("`%s`", column)).
+			Rows()
+		if err != nil {
+			return nil, err
+		}
+
+		counts := make(map[string]int64)
+		for rows.Next() {
+			var value interface{}
+			var count int64
+			if err := rows.Scan(&value, &count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts[facetKey(value)] = count
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		result[field] = counts
+	}
+
+	return result, nil
+}
+
+// facetKey converts a grouped value into a facet key
+func facetKey(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return facetNullKey
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
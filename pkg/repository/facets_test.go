@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+func TestGenericRepository_Facets(t *testing.T) {
+	db := setupTestDB(t)
+
+	products := []TestProduct{
+		{Name: "Phone", Price: 10, InStock: true, CategoryID: 1},
+		{Name: "Laptop", Price: 20, InStock: true, CategoryID: 1},
+		{Name: "Tablet", Price: 20, InStock: false, CategoryID: 2},
+	}
+	require.NoError(t, db.Create(&products).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: TestProduct{},
+	})
+	repo := NewGenericRepository(db, res).(*GenericRepository)
+
+	t.Run("all records", func(t *testing.T) {
+		facets, err := repo.Facets(context.Background(), query.QueryOptions{Resource: res, Sort: "name"}, []string{"category_id", "in_stock"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"1": 2, "2": 1}, facets["category_id"])
+		assert.Equal(t, int64(2), facets["in_stock"]["1"])
+	})
+
+	t.Run("under current filters", func(t *testing.T) {
+		options := query.QueryOptions{
+			Resource:        res,
+			AdvancedFilters: []query.Filter{{Field: "price", Operator: "eq", Value: 20}},
+		}
+		facets, err := repo.Facets(context.Background(), options, []string{"category_id"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"1": 1, "2": 1}, facets["category_id"])
+	})
+
+	t.Run("fields on their columns", func(t *testing.T) {
+		type FacetedTicket struct {
+			ID       uint   `json:"id" gorm:"primaryKey"`
+			Priority string `json:"priority" gorm:"column:ticket_priority"`
+		}
+		require.NoError(t, db.AutoMigrate(&FacetedTicket{}))
+		require.NoError(t, db.Create(&[]FacetedTicket{{Priority: "high"}, {Priority: "low"}, {Priority: "high"}}).Error)
+		tickets := resource.NewResource(resource.ResourceConfig{Name: "tickets", Model: FacetedTicket{}})
+		repo := NewGenericRepository(db, tickets).(*GenericRepository)
+
+		facets, err := repo.Facets(context.Background(), query.QueryOptions{Resource: tickets}, []string{"Priority"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"high": 2, "low": 1}, facets["Priority"])
+	})
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// serializeFields applies the Serialize hooks of the resource fields (see
+// resource.FieldHook) to a record, a slice of records or a map of updates
+func (r *GenericRepository) serializeFields(ctx context.Context, data interface{}) error {
+	return r.applyFieldHooks(ctx, data, true)
+}
+
+// deserializeFields applies the Deserialize hooks of the resource fields to a record
+// or a slice of records
+func (r *GenericRepository) deserializeFields(ctx context.Context, data interface{}) error {
+	return r.applyFieldHooks(ctx, data, false)
+}
+
+// serializedUpdates returns updates with serialized values. Maps are copied, so the
+// updates of the caller are left unchanged.
+func (r *GenericRepository) serializedUpdates(ctx context.Context, updates map[string]interface{}) (map[string]interface{}, error) {
+	if r.Resource == nil || len(resource.HookFields(r.Resource)) == 0 {
+		return updates, nil
+	}
+	serialized := make(map[string]interface{}, len(updates))
+	for key, value := range updates {
+		serialized[key] = value
+	}
+	if err := r.serializeFields(ctx, serialized); err != nil {
+		return nil, err
+	}
+	return serialized, nil
+}
+
+// applyFieldHooks runs the Serialize or Deserialize hooks on maps, slices and pointers
+// to structs. Other values are left unchanged.
+func (r *GenericRepository) applyFieldHooks(ctx context.Context, data interface{}, serialize bool) error {
+	if r.Resource == nil || data == nil {
+		return nil
+	}
+	fields := resource.HookFields(r.Resource)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch values := data.(type) {
+	case map[string]interface{}:
+		for key, value := range values {
+			field, ok := r.hookFieldForKey(fields, key)
+			if !ok || value == nil {
+				continue
+			}
+			converted, err := runFieldHook(ctx, field, value, serialize)
+			if err != nil {
+				return err
+			}
+			values[key] = converted
+		}
+		return nil
+	case []interface{}:
+		for _, item := range values {
+			if err := r.applyFieldHooks(ctx, item, serialize); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Slice {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			if err := r.applyFieldHooks(ctx, item.Interface(), serialize); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	for _, field := range fields {
+		schemaField, err := lookUpSchemaField(r.DB, data, field.Name)
+		if err != nil {
+			continue
+		}
+		current, zero := schemaField.ValueOf(ctx, value.Elem())
+		if zero {
+			continue
+		}
+		converted, err := runFieldHook(ctx, field, current, serialize)
+		if err != nil {
+			return err
+		}
+		if err := schemaField.Set(ctx, value.Elem(), converted); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// hookFieldForKey returns the hooked field a map key refers to by name or column
+func (r *GenericRepository) hookFieldForKey(fields []resource.Field, key string) (resource.Field, bool) {
+	for _, field := range fields {
+		if strings.EqualFold(key, field.Name) || key == r.DB.NamingStrategy.ColumnName("", field.Name) {
+			return field, true
+		}
+	}
+	return resource.Field{}, false
+}
+
+// runFieldHook runs the Serialize or Deserialize hook of a field, if set
+func runFieldHook(ctx context.Context, field resource.Field, value interface{}, serialize bool) (interface{}, error) {
+	hook, name := field.Hook.Deserialize, "deserialize"
+	if serialize {
+		hook, name = field.Hook.Serialize, "serialize"
+	}
+	if hook == nil {
+		return value, nil
+	}
+	converted, err := hook(ctx, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s field %s: %w", name, field.Name, err)
+	}
+	return converted, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type HookedContact struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+	Secret string `json:"secret"`
+}
+
+func TestFieldHooks(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&HookedContact{}))
+
+	encrypt := &resource.FieldHook{
+		Serialize: func(ctx context.Context, value interface{}) (interface{}, error) {
+			if strings.Contains(value.(string), "!") {
+				return nil, errors.New("invalid character")
+			}
+			return "enc:" + value.(string), nil
+		},
+		Deserialize: func(ctx context.Context, value interface{}) (interface{}, error) {
+			return strings.TrimPrefix(value.(string), "enc:"), nil
+		},
+	}
+	normalize := &resource.FieldHook{
+		Serialize: func(ctx context.Context, value interface{}) (interface{}, error) {
+			return strings.ReplaceAll(value.(string), " ", ""), nil
+		},
+	}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "contacts",
+		Model: HookedContact{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "phone", Type: "string", Hook: normalize},
+			{Name: "secret", Type: "string", Hook: encrypt},
+		},
+	})
+	repo := NewGenericRepositoryWithResource(db, res)
+	ctx := context.Background()
+	stored := func(id uint) HookedContact {
+		var contact HookedContact
+		require.NoError(t, db.First(&contact, id).Error)
+		return contact
+	}
+
+	// Writes store serialized values, the returned records are deserialized
+	created, err := repo.Create(ctx, &HookedContact{Name: "Ann", Phone: "+48 123 456", Secret: "s3"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3", created.(*HookedContact).Secret)
+	assert.Equal(t, HookedContact{ID: 1, Name: "Ann", Phone: "+48123456", Secret: "enc:s3"}, stored(1))
+
+	// Reads deserialize
+	record, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "s3", record.(*HookedContact).Secret)
+	list, _, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "s3", (*list.(*[]HookedContact))[0].Secret)
+	found, err := repo.FindOneBy(ctx, map[string]interface{}{"name": "Ann"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3", found.(*HookedContact).Secret)
+
+	// Map and struct updates are serialized once
+	updated, err := repo.Update(ctx, 1, map[string]interface{}{"phone": "+48 999"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3", updated.(*HookedContact).Secret)
+	assert.Equal(t, HookedContact{ID: 1, Name: "Ann", Phone: "+48999", Secret: "enc:s3"}, stored(1))
+
+	data := &HookedContact{ID: 1, Name: "Ann", Phone: "+48 999", Secret: "s4"}
+	_, err = repo.Update(ctx, 1, data)
+	require.NoError(t, err)
+	assert.Equal(t, "s4", data.Secret)
+	assert.Equal(t, "enc:s4", stored(1).Secret)
+
+	// Bulk updates match fields by name or column
+	updates := map[string]interface{}{"Secret": "s5"}
+	_, err = repo.UpdateMany(ctx, []interface{}{1}, updates)
+	require.NoError(t, err)
+	assert.Equal(t, "s5", updates["Secret"])
+	assert.Equal(t, "enc:s5", stored(1).Secret)
+
+	created, err = repo.CreateMany(ctx, &[]HookedContact{{Name: "Bob", Secret: "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, "b", (*created.(*[]HookedContact))[0].Secret)
+	assert.Equal(t, "enc:b", stored(2).Secret)
+
+	// Hook errors abort the write
+	_, err = repo.Create(ctx, &HookedContact{Name: "Eve", Secret: "oops!"})
+	assert.ErrorContains(t, err, "failed to serialize field secret: invalid character")
+	count, err := repo.Count(ctx, query.QueryOptions{Resource: res})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
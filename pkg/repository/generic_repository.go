@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/gorm"
 )
@@ -66,26 +67,46 @@ func (r *GenericRepository) List(ctx context.Context, options query.QueryOptions
 	sliceType := reflect.SliceOf(elemType)
 	result := reflect.New(sliceType).Interface()
 
-	tx := r.DB.WithContext(ctx)
+	tx := r.scoped(ctx)
 
 	// Apply query options (filters, sorting, etc.)
 	tx = options.Apply(tx)
 
-	// Get total count before pagination
+	// Get total count before pagination, unless the count is skipped
 	var total int64
-	if err := tx.Model(r.Model).Count(&total).Error; err != nil {
-		return nil, 0, err
+	countless := options.CountMode == resource.CountNone
+	if !countless {
+		var err error
+		if total, err = r.countList(tx, options.CountMode); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	// Apply pagination if enabled
+	// Apply pagination if enabled; countless lists read one more record to tell
+	// whether a next page exists
 	if !options.DisablePagination {
 		offset := (options.Page - 1) * options.PerPage
-		tx = tx.Offset(offset).Limit(options.PerPage)
+		limit := options.PerPage
+		if countless {
+			limit++
+		}
+		tx = tx.Offset(offset).Limit(limit)
 	}
 
+	// Stable order across pages
+	tx = options.ApplySortTiebreaker(tx)
+	tx = r.selectFields(tx, options.Fields)
+	tx = query.ApplyIncludes(tx, r.Model, options.Includes)
+
 	if err := tx.Find(result).Error; err != nil {
 		return nil, 0, err
 	}
+	if countless {
+		total = countPage(result, options)
+	}
+	if err := r.loadFields(ctx, result); err != nil {
+		return nil, 0, err
+	}
 
 	return result, total, nil
 }
@@ -112,7 +133,17 @@ func (r *GenericRepository) Get(ctx context.Context, id interface{}) (interface{
 	// Get the proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	if err := r.DB.WithContext(ctx).Where(idColumnName+" = ?", id).First(result).Error; err != nil {
+	tx := r.scoped(ctx)
+	if fields, ok := requestctx.Fields.Get(ctx); ok {
+		tx = r.selectFields(tx, fields)
+	}
+	if includes, ok := requestctx.Includes.Get(ctx); ok {
+		tx = query.ApplyIncludes(tx, r.Model, includes)
+	}
+	if err := tx.Where(idColumnName+" = ?", id).First(result).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadFields(ctx, result); err != nil {
 		return nil, err
 	}
 
@@ -121,7 +152,23 @@ func (r *GenericRepository) Get(ctx context.Context, id interface{}) (interface{
 
 // Create inserts a new resource into the database
 func (r *GenericRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
-	if err := r.DB.WithContext(ctx).Create(data).Error; err != nil {
+	if err := r.assignID(ctx, data); err != nil {
+		return nil, err
+	}
+	if err := r.assignSlugs(ctx, r.DB.WithContext(ctx), data, nil); err != nil {
+		return nil, err
+	}
+	if err := r.computeFields(ctx, data); err != nil {
+		return nil, err
+	}
+	if err := r.serializeFields(ctx, data); err != nil {
+		return nil, err
+	}
+	err := r.DB.WithContext(ctx).Create(data).Error
+	if hookErr := r.deserializeFields(ctx, data); err == nil {
+		err = hookErr
+	}
+	if err != nil {
 		return nil, err
 	}
 	return data, nil
@@ -188,6 +235,36 @@ func (r *GenericRepository) Update(ctx context.Context, id interface{}, data int
 		idSetter.SetID(id)
 	}
 
+	// Slugs only change through RegenerateSlug
+	if err := r.keepSlugs(ctx, existingRecord, updateData); err != nil {
+		return nil, err
+	}
+
+	// Computed fields are recomputed from the complete record, stored values are
+	// serialized and the caller's data is restored afterwards
+	if err := r.computeFields(ctx, updateData); err != nil {
+		return nil, err
+	}
+	if err := r.serializeFields(ctx, updateData); err != nil {
+		return nil, err
+	}
+	if !isMap {
+		defer r.deserializeFields(ctx, updateData)
+	}
+
+	// Versioned records are only saved if nobody modified them in the meantime
+	if field := r.versionField(); field != nil {
+		if value := reflect.ValueOf(updateData); value.Kind() != reflect.Ptr {
+			pointer := reflect.New(value.Type())
+			pointer.Elem().Set(value)
+			updateData = pointer.Interface()
+		}
+		if err := r.saveVersioned(ctx, field, id, existingRecord, updateData); err != nil {
+			return nil, err
+		}
+		return r.Get(ctx, id)
+	}
+
 	// Save the modified record - this will correctly handle JSON serialization
 	if err := r.DB.WithContext(ctx).Save(updateData).Error; err != nil {
 		return nil, err
@@ -200,10 +277,16 @@ func (r *GenericRepository) Update(ctx context.Context, id interface{}, data int
 // Delete removes a resource from the database
 func (r *GenericRepository) Delete(ctx context.Context, id interface{}) error {
 	tx := r.DB.WithContext(ctx)
+	for _, condition := range r.scopeConditions(ctx) {
+		tx = tx.Where(condition).Session(&gorm.Session{})
+	}
 
 	// If id is a map, use it directly as a condition
 	if conditions, ok := id.(map[string]interface{}); ok {
-		return tx.Delete(r.Model, conditions).Error
+		if _, trashed, err := r.trash(tx.Where(conditions)); trashed {
+			return err
+		}
+		return tx.Delete(r.newModel(), conditions).Error
 	}
 
 	// Otherwise, use the ID field name
@@ -215,13 +298,16 @@ func (r *GenericRepository) Delete(ctx context.Context, id interface{}) error {
 	// Get the proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	return tx.Where(idColumnName+" = ?", id).Delete(r.Model).Error
+	if _, trashed, err := r.trash(tx.Where(idColumnName+" = ?", id)); trashed {
+		return err
+	}
+	return tx.Where(idColumnName+" = ?", id).Delete(r.newModel()).Error
 }
 
 // Count returns the total number of resources matching the query options
 func (r *GenericRepository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
 	var total int64
-	tx := r.DB.WithContext(ctx).Model(r.Model)
+	tx := r.scoped(ctx).Model(r.Model)
 
 	// Apply query options (filters only)
 	tx = options.Apply(tx)
@@ -244,7 +330,30 @@ func (r *GenericRepository) CreateMany(ctx context.Context, data interface{}) (i
 		return data, fmt.Errorf("unsupported data type: %v: Table not set, please set it like: db.Model(&user) or db.Table(\"users\")", data)
 	}
 
+	taken := make(map[string]bool)
+	for i := 0; i < val.Len(); i++ {
+		item := val.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		if err := r.assignID(ctx, item.Interface()); err != nil {
+			return reflect.Zero(val.Type()).Interface(), err
+		}
+		if err := r.assignSlugs(ctx, r.DB.WithContext(ctx), item.Interface(), taken); err != nil {
+			return reflect.Zero(val.Type()).Interface(), err
+		}
+	}
+
+	if err := r.computeFields(ctx, data); err != nil {
+		return reflect.Zero(val.Type()).Interface(), err
+	}
+	if err := r.serializeFields(ctx, data); err != nil {
+		return reflect.Zero(val.Type()).Interface(), err
+	}
 	err := r.DB.WithContext(ctx).Create(data).Error
+	if hookErr := r.deserializeFields(ctx, data); err == nil {
+		err = hookErr
+	}
 	if err != nil {
 		// Return a nil slice of the same type as the input
 		return reflect.Zero(val.Type()).Interface(), err
@@ -262,7 +371,20 @@ func (r *GenericRepository) UpdateMany(ctx context.Context, ids []interface{}, d
 	// Get the proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	result := r.DB.WithContext(ctx).Model(r.Model).Where(idColumnName+" IN ?", ids).Updates(data)
+	if updates, ok := data.(map[string]interface{}); ok {
+		serialized, err := r.serializedUpdates(ctx, updates)
+		if err != nil {
+			return 0, err
+		}
+		data = serialized
+	} else {
+		if err := r.serializeFields(ctx, data); err != nil {
+			return 0, err
+		}
+		defer r.deserializeFields(ctx, data)
+	}
+
+	result := r.scoped(ctx).Model(r.Model).Where(idColumnName+" IN ?", ids).Updates(data)
 	return result.RowsAffected, result.Error
 }
 
@@ -276,7 +398,16 @@ func (r *GenericRepository) DeleteMany(ctx context.Context, ids []interface{}) (
 	// Get the proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	result := r.DB.WithContext(ctx).Where(idColumnName+" IN ?", ids).Delete(r.Model)
+	tx := r.DB.WithContext(ctx)
+	for _, condition := range r.scopeConditions(ctx) {
+		tx = tx.Where(condition).Session(&gorm.Session{})
+	}
+
+	if deleted, trashed, err := r.trash(tx.Where(idColumnName+" IN ?", ids)); trashed {
+		return deleted, err
+	}
+
+	result := tx.Where(idColumnName+" IN ?", ids).Delete(r.newModel())
 	return result.RowsAffected, result.Error
 }
 
@@ -304,17 +435,31 @@ func (r *GenericRepository) WithRelations(relations ...string) Repository {
 
 // BulkCreate creates multiple records at once
 func (r *GenericRepository) BulkCreate(ctx context.Context, items interface{}) error {
+	if err := r.assignIDs(ctx, items); err != nil {
+		return err
+	}
+	if err := r.computeFields(ctx, items); err != nil {
+		return err
+	}
+	if err := r.serializeFields(ctx, items); err != nil {
+		return err
+	}
+	defer r.deserializeFields(ctx, items)
 	return r.DB.WithContext(ctx).Create(items).Error
 }
 
 // BulkUpdate updates multiple records at once based on a condition
 func (r *GenericRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
-	return r.DB.WithContext(ctx).Model(r.Model).Where(condition).Updates(updates).Error
+	updates, err := r.serializedUpdates(ctx, updates)
+	if err != nil {
+		return err
+	}
+	return r.scoped(ctx).Model(r.Model).Where(condition).Updates(updates).Error
 }
 
 // Query returns a query builder for custom queries
 func (r *GenericRepository) Query(ctx context.Context) *gorm.DB {
-	return r.DB.WithContext(ctx).Model(r.Model)
+	return r.scoped(ctx).Model(r.Model)
 }
 
 // GetWithRelations retrieves a single resource by its ID with related entities
@@ -331,7 +476,7 @@ func (r *GenericRepository) GetWithRelations(ctx context.Context, id interface{}
 		result = reflect.New(modelType).Interface()
 	}
 
-	query := r.DB.WithContext(ctx)
+	query := r.scoped(ctx)
 
 	// Add preloads for all relations
 	for _, relation := range relations {
@@ -341,6 +486,9 @@ func (r *GenericRepository) GetWithRelations(ctx context.Context, id interface{}
 	if err := query.First(result, id).Error; err != nil {
 		return nil, err
 	}
+	if err := r.loadFields(ctx, result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -364,7 +512,7 @@ func (r *GenericRepository) ListWithRelations(ctx context.Context, options query
 	sliceType := reflect.SliceOf(elemType)
 	result := reflect.New(sliceType).Interface()
 
-	tx := r.DB.WithContext(ctx)
+	tx := r.scoped(ctx)
 
 	// Add preloads for all relations
 	for _, relation := range relations {
@@ -374,21 +522,39 @@ func (r *GenericRepository) ListWithRelations(ctx context.Context, options query
 	// Apply query options (filters, sorting, etc.)
 	tx = options.Apply(tx)
 
-	// Get total count before pagination
+	// Get total count before pagination, unless the count is skipped
 	var total int64
-	if err := tx.Model(r.Model).Count(&total).Error; err != nil {
-		return nil, 0, err
+	countless := options.CountMode == resource.CountNone
+	if !countless {
+		var err error
+		if total, err = r.countList(tx, options.CountMode); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	// Apply pagination if enabled
+	// Apply pagination if enabled; countless lists read one more record to tell
+	// whether a next page exists
 	if !options.DisablePagination {
 		offset := (options.Page - 1) * options.PerPage
-		tx = tx.Offset(offset).Limit(options.PerPage)
+		limit := options.PerPage
+		if countless {
+			limit++
+		}
+		tx = tx.Offset(offset).Limit(limit)
 	}
 
+	// Stable order across pages
+	tx = options.ApplySortTiebreaker(tx)
+
 	if err := tx.Find(result).Error; err != nil {
 		return nil, 0, err
 	}
+	if countless {
+		total = countPage(result, options)
+	}
+	if err := r.loadFields(ctx, result); err != nil {
+		return nil, 0, err
+	}
 
 	return result, total, nil
 }
@@ -407,7 +573,10 @@ func (r *GenericRepository) FindOneBy(ctx context.Context, condition map[string]
 		result = reflect.New(modelType).Interface()
 	}
 
-	if err := r.DB.WithContext(ctx).Where(condition).First(result).Error; err != nil {
+	if err := r.scoped(ctx).Where(condition).First(result).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadFields(ctx, result); err != nil {
 		return nil, err
 	}
 
@@ -433,7 +602,10 @@ func (r *GenericRepository) FindAllBy(ctx context.Context, condition map[string]
 	sliceType := reflect.SliceOf(elemType)
 	result := reflect.New(sliceType).Interface()
 
-	if err := r.DB.WithContext(ctx).Where(condition).Find(result).Error; err != nil {
+	if err := r.scoped(ctx).Where(condition).Find(result).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadFields(ctx, result); err != nil {
 		return nil, err
 	}
 
@@ -452,3 +624,21 @@ func (r *GenericRepository) GetIDFieldName() string {
 	// Default to "id"
 	return "id"
 }
+
+// selectFields restricts a query to the columns of the selected fields (a sparse
+// fieldset). Fields that are not columns of the model, such as relations and
+// computed fields, are skipped.
+func (r *GenericRepository) selectFields(tx *gorm.DB, fields []string) *gorm.DB {
+	if len(fields) == 0 {
+		return tx
+	}
+	columns := []string{r.idColumn()}
+	for _, name := range fields {
+		field, err := lookUpSchemaField(r.DB, r.Model, name)
+		if err != nil || field.DBName == "" || field.DBName == columns[0] {
+			continue
+		}
+		columns = append(columns, field.DBName)
+	}
+	return tx.Select(columns)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
 	"github.com/suranig/refine-gin/pkg/resource"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -196,6 +197,39 @@ func TestGenericRepository_Basic(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGenericRepository_SelectFields(t *testing.T) {
+	db := setupTestDB(t)
+	_, product := createTestData(t, db)
+
+	productResource := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: TestProduct{},
+	})
+	repo := NewGenericRepository(db, productResource)
+	ctx := context.Background()
+
+	// Only the selected columns and the ID are read
+	listResult, count, err := repo.List(ctx, query.QueryOptions{
+		Resource: productResource,
+		Page:     1,
+		PerPage:  10,
+		Fields:   []string{"name", "Category", "unknown"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	products := *listResult.(*[]TestProduct)
+	assert.Len(t, products, 1)
+	assert.Equal(t, product.ID, products[0].ID)
+	assert.Equal(t, product.Name, products[0].Name)
+	assert.Zero(t, products[0].Price)
+
+	result, err := repo.Get(requestctx.Fields.With(ctx, []string{"price"}), product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, product.ID, result.(*TestProduct).ID)
+	assert.Equal(t, product.Price, result.(*TestProduct).Price)
+	assert.Empty(t, result.(*TestProduct).Name)
+}
+
 func TestGenericRepository_Relations(t *testing.T) {
 	db := setupTestDB(t)
 	category, product := createTestData(t, db)
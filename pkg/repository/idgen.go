@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/suranig/refine-gin/pkg/idgen"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// assignID sets the ID of a new record from the ID generator of the resource (see
+// resource.ResourceConfig.IDGenerator). IDs set by the client are kept.
+func (r *GenericRepository) assignID(ctx context.Context, record interface{}) error {
+	generatorResource, ok := r.Resource.(resource.IDGeneratorResource)
+	if !ok || generatorResource.GetIDGenerator() == nil {
+		return nil
+	}
+	idFieldName := r.Resource.GetIDFieldName()
+	if _, set := schemaFieldValue(ctx, r.DB, record, idFieldName); set {
+		return nil
+	}
+	var id interface{}
+	var err error
+	if dbGenerator, ok := generatorResource.GetIDGenerator().(idgen.DBGenerator); ok {
+		id, err = dbGenerator.NewIDFromDB(ctx, r.DB)
+	} else {
+		id, err = generatorResource.GetIDGenerator().NewID()
+	}
+	if err != nil {
+		return err
+	}
+	return setSchemaField(ctx, r.DB, record, idFieldName, id)
+}
+
+// assignIDs sets the IDs of a slice of new records, see assignID
+func (r *GenericRepository) assignIDs(ctx context.Context, records interface{}) error {
+	val := reflect.Indirect(reflect.ValueOf(records))
+	if val.Kind() != reflect.Slice {
+		return r.assignID(ctx, records)
+	}
+	for i := 0; i < val.Len(); i++ {
+		item := val.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		if err := r.assignID(ctx, item.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/idgen"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ULIDNote struct {
+	ID   string `json:"id" gorm:"primaryKey"`
+	Text string `json:"text"`
+}
+
+type OwnedULIDNote struct {
+	ID      string `json:"id" gorm:"primaryKey"`
+	OwnerID string `json:"ownerId"`
+	Text    string `json:"text"`
+}
+
+type SnowflakeEvent struct {
+	ID   int64  `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	Name string `json:"name"`
+}
+
+type SequenceTicket struct {
+	ID    int64  `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	Title string `json:"title"`
+}
+
+func TestGeneratedIDs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ULIDNote{}, &SnowflakeEvent{}, &SequenceTicket{}))
+	ctx := context.Background()
+
+	t.Run("ULIDs on create", func(t *testing.T) {
+		res := resource.NewResource(resource.ResourceConfig{
+			Name:        "notes",
+			Model:       ULIDNote{},
+			IDGenerator: idgen.NewULID(),
+		})
+		repo := NewGenericRepositoryWithResource(db, res)
+
+		first, err := repo.Create(ctx, &ULIDNote{Text: "first"})
+		require.NoError(t, err)
+		second, err := repo.Create(ctx, &ULIDNote{Text: "second"})
+		require.NoError(t, err)
+		assert.Len(t, first.(*ULIDNote).ID, 26)
+		assert.Less(t, first.(*ULIDNote).ID, second.(*ULIDNote).ID)
+
+		// IDs set by the client are kept
+		kept, err := repo.Create(ctx, &ULIDNote{ID: "custom", Text: "kept"})
+		require.NoError(t, err)
+		assert.Equal(t, "custom", kept.(*ULIDNote).ID)
+	})
+
+	t.Run("ULIDs on owner resources", func(t *testing.T) {
+		require.NoError(t, db.AutoMigrate(&OwnedULIDNote{}))
+		res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+			Name:        "owned_notes",
+			Model:       OwnedULIDNote{},
+			IDGenerator: idgen.NewULID(),
+		}), resource.DefaultOwnerConfig())
+		repo, err := NewOwnerRepository(db, res)
+		require.NoError(t, err)
+
+		created, err := repo.Create(requestctx.OwnerID.With(ctx, "ann"), &OwnedULIDNote{Text: "mine"})
+		require.NoError(t, err)
+		assert.Len(t, created.(*OwnedULIDNote).ID, 26)
+		assert.Equal(t, "ann", created.(*OwnedULIDNote).OwnerID)
+	})
+
+	t.Run("snowflakes on create many", func(t *testing.T) {
+		generator, err := idgen.NewSnowflake(7)
+		require.NoError(t, err)
+		res := resource.NewResource(resource.ResourceConfig{
+			Name:        "events",
+			Model:       SnowflakeEvent{},
+			IDGenerator: generator,
+		})
+		repo := NewGenericRepositoryWithResource(db, res)
+
+		_, err = repo.CreateMany(ctx, &[]SnowflakeEvent{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+		require.NoError(t, err)
+
+		// Generated IDs follow the insertion order
+		events, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Sort: "id", Order: "asc"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		names := []string{}
+		for _, event := range *events.(*[]SnowflakeEvent) {
+			assert.NotZero(t, event.ID)
+			names = append(names, event.Name)
+		}
+		assert.Equal(t, []string{"a", "b", "c"}, names)
+	})
+	t.Run("sequences from the database", func(t *testing.T) {
+		// sqlite has no sequences, the query stands in for one
+		res := resource.NewResource(resource.ResourceConfig{
+			Name:        "tickets",
+			Model:       SequenceTicket{},
+			IDGenerator: &idgen.Sequence{Name: "ticket_ids", Query: "SELECT COALESCE(MAX(id), 999) + 1 FROM sequence_tickets"},
+		})
+		repo := NewGenericRepositoryWithResource(db, res)
+
+		first, err := repo.Create(ctx, &SequenceTicket{Title: "first"})
+		require.NoError(t, err)
+		second, err := repo.Create(ctx, &SequenceTicket{Title: "second"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000), first.(*SequenceTicket).ID)
+		assert.Equal(t, int64(1001), second.(*SequenceTicket).ID)
+	})
+}
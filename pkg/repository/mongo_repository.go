@@ -0,0 +1,856 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// MongoRepositoryConfig configures a repository backed by a MongoDB collection
+type MongoRepositoryConfig struct {
+	// Database holding the collection (default "refine")
+	Database string
+
+	// Collection name (defaults to the resource name)
+	Collection string
+
+	// Collection holding the sequences used for numeric IDs (default "refine_counters")
+	CountersCollection string
+}
+
+// MongoRepository implements Repository on top of a MongoDB collection.
+// Documents use the JSON field names of the model, with the ID stored in "_id".
+// String IDs default to generated ObjectID hex strings; numeric IDs are taken from
+// a sequence kept in the counters collection. Relations are not loaded.
+type MongoRepository struct {
+	Client     *mongo.Client
+	Collection *mongo.Collection
+	Config     MongoRepositoryConfig
+	Model      interface{}
+	Resource   resource.Resource
+
+	// session is set on repositories passed to WithTransaction callbacks
+	session mongo.Session
+}
+
+// NewMongoRepository creates a repository for a resource stored in the "refine" database
+func NewMongoRepository(client *mongo.Client, res resource.Resource) Repository {
+	return NewMongoRepositoryWithConfig(client, res, MongoRepositoryConfig{})
+}
+
+// NewMongoRepositoryWithConfig creates a repository for a resource stored in MongoDB
+func NewMongoRepositoryWithConfig(client *mongo.Client, res resource.Resource, config MongoRepositoryConfig) Repository {
+	config = config.withDefaults(res)
+	collection := client.Database(config.Database).Collection(config.Collection,
+		options.Collection().SetBSONOptions(&options.BSONOptions{UseJSONStructTags: true}))
+	return &MongoRepository{
+		Client:     client,
+		Collection: collection,
+		Config:     config,
+		Model:      res.GetModel(),
+		Resource:   res,
+	}
+}
+
+// withDefaults fills in the database and collection names
+func (c MongoRepositoryConfig) withDefaults(res resource.Resource) MongoRepositoryConfig {
+	if c.Database == "" {
+		c.Database = "refine"
+	}
+	if c.Collection == "" {
+		c.Collection = res.GetName()
+	}
+	if c.CountersCollection == "" {
+		c.CountersCollection = "refine_counters"
+	}
+	return c
+}
+
+// ctx binds the transaction session, if any, to a context
+func (r *MongoRepository) ctx(ctx context.Context) context.Context {
+	if r.session != nil {
+		return mongo.NewSessionContext(ctx, r.session)
+	}
+	return ctx
+}
+
+// modelType returns the element type of the model
+func (r *MongoRepository) modelType() reflect.Type {
+	t := reflect.TypeOf(r.Model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// idField returns the struct field holding the record ID
+func (r *MongoRepository) idField() (reflect.StructField, bool) {
+	return mongoStructField(r.modelType(), r.GetIDFieldName())
+}
+
+// idKey returns the JSON name of the ID field
+func (r *MongoRepository) idKey() string {
+	if field, ok := r.idField(); ok {
+		return jsonFieldName(field)
+	}
+	return "id"
+}
+
+// mongoStructField finds a struct field by Go name or JSON name
+func mongoStructField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if nested, ok := mongoStructField(field.Type, name); ok {
+				return nested, true
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if strings.EqualFold(field.Name, name) || strings.EqualFold(jsonFieldName(field), name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonFieldName returns the JSON name of a struct field
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// documentKey returns the document key of a field name, mapping the ID field to "_id"
+func (r *MongoRepository) documentKey(name string) string {
+	if strings.EqualFold(name, r.idKey()) || strings.EqualFold(name, r.GetIDFieldName()) {
+		return "_id"
+	}
+	if field, ok := mongoStructField(r.modelType(), name); ok {
+		return jsonFieldName(field)
+	}
+	return name
+}
+
+// convertValue converts a filter or ID value (often a string from the query) to the
+// type of a model field
+func convertValue(t reflect.Type, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(primitive.ObjectID{}):
+		return primitive.ObjectIDFromHex(s)
+	case t == reflect.TypeOf(time.Time{}):
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", s)
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	}
+	return s, nil
+}
+
+// fieldValue converts a filter value to the type of the named field
+func (r *MongoRepository) fieldValue(name string, value interface{}) (interface{}, error) {
+	field, ok := mongoStructField(r.modelType(), name)
+	if !ok {
+		return value, nil
+	}
+	return convertValue(field.Type, value)
+}
+
+// fieldValues converts a list filter value ("a,b" or a slice) to the type of the named field
+func (r *MongoRepository) fieldValues(name string, value interface{}) ([]interface{}, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			items = []interface{}{value}
+			break
+		}
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	}
+
+	for i, item := range items {
+		converted, err := r.fieldValue(name, item)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = converted
+	}
+	return items, nil
+}
+
+// convertID converts an ID to the type of the model ID field
+func (r *MongoRepository) convertID(id interface{}) (interface{}, error) {
+	field, ok := r.idField()
+	if !ok {
+		return id, nil
+	}
+	return convertValue(field.Type, id)
+}
+
+// buildFilter translates query options into a MongoDB filter
+func (r *MongoRepository) buildFilter(options query.QueryOptions) (bson.M, error) {
+	var conditions []bson.M
+
+	for field, value := range options.Filters {
+		converted, err := r.fieldValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", field, err)
+		}
+		conditions = append(conditions, bson.M{r.documentKey(field): converted})
+	}
+
+	for _, filter := range options.AdvancedFilters {
+		condition, err := r.buildCondition(filter)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", filter.Field, err)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if options.Search != "" && r.Resource != nil {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(options.Search), Options: "i"}
+		var search []bson.M
+		for _, field := range r.Resource.GetSearchable() {
+			search = append(search, bson.M{r.documentKey(field): pattern})
+		}
+		if len(search) > 0 {
+			conditions = append(conditions, bson.M{"$or": search})
+		}
+	}
+
+	switch len(conditions) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return conditions[0], nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
+
+// buildCondition translates a filter with an operator into a MongoDB condition
+func (r *MongoRepository) buildCondition(filter query.Filter) (bson.M, error) {
+	key := r.documentKey(filter.Field)
+	text := fmt.Sprint(filter.Value)
+
+	regex := func(pattern, flags string) bson.M {
+		return bson.M{key: primitive.Regex{Pattern: pattern, Options: flags}}
+	}
+	not := func(condition bson.M) bson.M {
+		return bson.M{key: bson.M{"$not": condition[key]}}
+	}
+	compare := func(op string) (bson.M, error) {
+		value, err := r.fieldValue(filter.Field, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{key: bson.M{op: value}}, nil
+	}
+	list := func(op string) (bson.M, error) {
+		values, err := r.fieldValues(filter.Field, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{key: bson.M{op: values}}, nil
+	}
+
+	switch strings.ToLower(filter.Operator) {
+	case "", string(query.OperatorEqual):
+		return compare("$eq")
+	case string(query.OperatorNotEqual):
+		return compare("$ne")
+	case string(query.OperatorGreaterThan):
+		return compare("$gt")
+	case string(query.OperatorGreaterThanEqual):
+		return compare("$gte")
+	case string(query.OperatorLessThan):
+		return compare("$lt")
+	case string(query.OperatorLessThanEqual):
+		return compare("$lte")
+	case string(query.OperatorIn):
+		return list("$in")
+	case string(query.OperatorNotIn):
+		return list("$nin")
+	case string(query.OperatorContains), string(query.OperatorLike), "containsi":
+		return regex(regexp.QuoteMeta(text), "i"), nil
+	case string(query.OperatorNotContains):
+		return not(regex(regexp.QuoteMeta(text), "i")), nil
+	case string(query.OperatorContainsSensitive):
+		return regex(regexp.QuoteMeta(text), ""), nil
+	case string(query.OperatorNotContainsSensitive):
+		return not(regex(regexp.QuoteMeta(text), "")), nil
+	case string(query.OperatorStartsWith):
+		return regex("^"+regexp.QuoteMeta(text), "i"), nil
+	case string(query.OperatorNotStartsWith):
+		return not(regex("^"+regexp.QuoteMeta(text), "i")), nil
+	case string(query.OperatorEndsWith):
+		return regex(regexp.QuoteMeta(text)+"$", "i"), nil
+	case string(query.OperatorNotEndsWith):
+		return not(regex(regexp.QuoteMeta(text)+"$", "i")), nil
+	case string(query.OperatorNull), string(query.OperatorIsNull):
+		if isNull, ok := filter.Value.(bool); ok && !isNull || text == "false" {
+			return bson.M{key: bson.M{"$ne": nil}}, nil
+		}
+		return bson.M{key: nil}, nil
+	case string(query.OperatorNotNull):
+		return bson.M{key: bson.M{"$ne": nil}}, nil
+	case string(query.OperatorBetween), string(query.OperatorNotBetween):
+		values, err := r.fieldValues(filter.Field, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("between requires two values")
+		}
+		if strings.ToLower(filter.Operator) == string(query.OperatorNotBetween) {
+			return bson.M{"$or": []bson.M{
+				{key: bson.M{"$lt": values[0]}},
+				{key: bson.M{"$gt": values[1]}},
+			}}, nil
+		}
+		return bson.M{key: bson.M{"$gte": values[0], "$lte": values[1]}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", filter.Operator)
+}
+
+// findOptions translates sorting and pagination into find options
+func (r *MongoRepository) findOptions(queryOptions query.QueryOptions) *options.FindOptions {
+	opts := options.Find()
+
+	direction := 1
+	if strings.EqualFold(queryOptions.Order, "desc") {
+		direction = -1
+	}
+	sort := bson.D{}
+	if queryOptions.Sort != "" {
+		sort = append(sort, bson.E{Key: r.documentKey(queryOptions.Sort), Value: direction})
+	}
+	if queryOptions.Sort == "" || r.documentKey(queryOptions.Sort) != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: 1})
+	}
+	opts.SetSort(sort)
+
+	if !queryOptions.DisablePagination && queryOptions.PerPage > 0 {
+		page := queryOptions.Page
+		if page < 1 {
+			page = 1
+		}
+		opts.SetSkip(int64((page - 1) * queryOptions.PerPage))
+		opts.SetLimit(int64(queryOptions.PerPage))
+	}
+	return opts
+}
+
+// toDocument converts a model or map into a document. With partial set, zero-valued
+// struct fields and the ID are left out so the document can be used with $set.
+func (r *MongoRepository) toDocument(data interface{}, partial bool) (bson.M, error) {
+	doc := bson.M{}
+
+	if fields, ok := data.(map[string]interface{}); ok {
+		for key, value := range fields {
+			doc[r.documentKey(key)] = value
+		}
+	} else {
+		var buf bytes.Buffer
+		vw, err := bsonrw.NewBSONValueWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		encoder, err := bson.NewEncoder(vw)
+		if err != nil {
+			return nil, err
+		}
+		encoder.UseJSONStructTags()
+		encoder.NilSliceAsEmpty()
+		if err := encoder.Encode(data); err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(buf.Bytes(), &doc); err != nil {
+			return nil, err
+		}
+
+		if id, ok := doc[r.idKey()]; ok {
+			delete(doc, r.idKey())
+			doc["_id"] = id
+		}
+		if partial {
+			for _, key := range zeroFields(data) {
+				delete(doc, r.documentKey(key))
+			}
+		}
+	}
+
+	if partial {
+		delete(doc, "_id")
+	}
+	return doc, nil
+}
+
+// zeroFields returns the JSON names of zero-valued top-level struct fields
+func zeroFields(data interface{}) []string {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, zeroFields(v.Field(i).Interface())...)
+			continue
+		}
+		if v.Field(i).IsZero() {
+			names = append(names, jsonFieldName(field))
+		}
+	}
+	return names
+}
+
+// decode converts a document into target, mapping "_id" to the ID field
+func (r *MongoRepository) decode(doc bson.M, target interface{}) error {
+	if id, ok := doc["_id"]; ok {
+		delete(doc, "_id")
+		doc[r.idKey()] = id
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return err
+	}
+	decoder.UseJSONStructTags()
+	return decoder.Decode(target)
+}
+
+// decodeAll decodes the documents of a cursor into a pointer to a slice of models
+func (r *MongoRepository) decodeAll(ctx context.Context, cursor *mongo.Cursor) (interface{}, error) {
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(r.modelType()), len(docs), len(docs))
+	for i, doc := range docs {
+		if err := r.decode(doc, slice.Index(i).Addr().Interface()); err != nil {
+			return nil, err
+		}
+	}
+	result := reflect.New(slice.Type())
+	result.Elem().Set(slice)
+	return result.Interface(), nil
+}
+
+// nextID generates an ID for a new record based on the type of the ID field
+func (r *MongoRepository) nextID(ctx context.Context) (interface{}, error) {
+	field, ok := r.idField()
+	if !ok {
+		return primitive.NewObjectID(), nil
+	}
+
+	t := field.Type
+	switch {
+	case t == reflect.TypeOf(primitive.ObjectID{}):
+		return primitive.NewObjectID(), nil
+	case t.Kind() == reflect.String:
+		return primitive.NewObjectID().Hex(), nil
+	}
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := r.Client.Database(r.Config.Database).Collection(r.Config.CountersCollection).FindOneAndUpdate(
+		r.ctx(ctx),
+		bson.M{"_id": r.Config.Collection},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return nil, err
+	}
+	return counter.Seq, nil
+}
+
+// setID stores an ID in the ID field of a model
+func (r *MongoRepository) setID(data interface{}, id interface{}) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	field, ok := r.idField()
+	if !ok {
+		return
+	}
+	target := v.FieldByIndex(field.Index)
+	value := reflect.ValueOf(id)
+	if value.Type().ConvertibleTo(target.Type()) {
+		target.Set(value.Convert(target.Type()))
+	}
+}
+
+// List returns a page of records matching the query options
+func (r *MongoRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	filter, err := r.buildFilter(options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.Collection.CountDocuments(r.ctx(ctx), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := r.Collection.Find(r.ctx(ctx), filter, r.findOptions(options))
+	if err != nil {
+		return nil, 0, err
+	}
+	records, err := r.decodeAll(ctx, cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// Get retrieves a single record by its ID
+func (r *MongoRepository) Get(ctx context.Context, id interface{}) (interface{}, error) {
+	key, err := r.convertID(id)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var doc bson.M
+	err = r.Collection.FindOne(r.ctx(ctx), bson.M{"_id": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.New(r.modelType()).Interface()
+	if err := r.decode(doc, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Create inserts a record, generating its ID when it is not set
+func (r *MongoRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	doc, err := r.toDocument(data, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if id, ok := doc["_id"]; !ok || reflect.ValueOf(id).IsZero() {
+		id, err := r.nextID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		doc["_id"] = id
+		r.setID(data, id)
+	}
+
+	if _, err := r.Collection.InsertOne(r.ctx(ctx), doc); err != nil {
+		return nil, err
+	}
+	if _, ok := data.(map[string]interface{}); ok {
+		return r.Get(ctx, doc["_id"])
+	}
+	return data, nil
+}
+
+// Update sets the non-zero fields of a model (or all keys of a map) on a record
+func (r *MongoRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	key, err := r.convertID(id)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	doc, err := r.toDocument(data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc) > 0 {
+		result, err := r.Collection.UpdateOne(r.ctx(ctx), bson.M{"_id": key}, bson.M{"$set": doc})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	return r.Get(ctx, key)
+}
+
+// Delete removes a record by its ID
+func (r *MongoRepository) Delete(ctx context.Context, id interface{}) error {
+	key, err := r.convertID(id)
+	if err != nil {
+		return gorm.ErrRecordNotFound
+	}
+	result, err := r.Collection.DeleteOne(r.ctx(ctx), bson.M{"_id": key})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Count returns the number of records matching the query options
+func (r *MongoRepository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
+	filter, err := r.buildFilter(options)
+	if err != nil {
+		return 0, err
+	}
+	return r.Collection.CountDocuments(r.ctx(ctx), filter)
+}
+
+// CreateMany inserts each record of a slice
+func (r *MongoRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	items := reflect.ValueOf(data)
+	if items.Kind() == reflect.Ptr {
+		items = items.Elem()
+	}
+	if items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected slice, got %s", items.Kind())
+	}
+
+	docs := make([]interface{}, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		if item.Kind() != reflect.Ptr && item.CanAddr() {
+			item = item.Addr()
+		}
+		doc, err := r.toDocument(item.Interface(), false)
+		if err != nil {
+			return nil, err
+		}
+		if id, ok := doc["_id"]; !ok || reflect.ValueOf(id).IsZero() {
+			id, err := r.nextID(ctx)
+			if err != nil {
+				return nil, err
+			}
+			doc["_id"] = id
+			r.setID(item.Interface(), id)
+		}
+		docs = append(docs, doc)
+	}
+
+	if len(docs) > 0 {
+		if _, err := r.Collection.InsertMany(r.ctx(ctx), docs); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// idFilter returns a filter matching the given IDs
+func (r *MongoRepository) idFilter(ids []interface{}) (bson.M, error) {
+	keys := make([]interface{}, len(ids))
+	for i, id := range ids {
+		key, err := r.convertID(id)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return bson.M{"_id": bson.M{"$in": keys}}, nil
+}
+
+// UpdateMany sets the same fields on all records with the given IDs
+func (r *MongoRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	filter, err := r.idFilter(ids)
+	if err != nil {
+		return 0, err
+	}
+	doc, err := r.toDocument(data, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(doc) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.Collection.UpdateMany(r.ctx(ctx), filter, bson.M{"$set": doc})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteMany removes all records with the given IDs
+func (r *MongoRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	filter, err := r.idFilter(ids)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.Collection.DeleteMany(r.ctx(ctx), filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// WithRelations returns the repository unchanged; relations are not supported for MongoDB
+func (r *MongoRepository) WithRelations(relations ...string) Repository {
+	return r
+}
+
+// GetWithRelations retrieves a single record; relations are not loaded
+func (r *MongoRepository) GetWithRelations(ctx context.Context, id interface{}, relations []string) (interface{}, error) {
+	return r.Get(ctx, id)
+}
+
+// ListWithRelations lists records; relations are not loaded
+func (r *MongoRepository) ListWithRelations(ctx context.Context, options query.QueryOptions, relations []string) (interface{}, int64, error) {
+	return r.List(ctx, options)
+}
+
+// Query returns nil as there is no GORM database behind a MongoDB repository
+func (r *MongoRepository) Query(ctx context.Context) *gorm.DB {
+	return nil
+}
+
+// conditionFilter converts an equality condition map into a filter
+func (r *MongoRepository) conditionFilter(condition map[string]interface{}) (bson.M, error) {
+	return r.buildFilter(query.QueryOptions{Filters: condition})
+}
+
+// FindOneBy returns the first record matching the condition
+func (r *MongoRepository) FindOneBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	filter, err := r.conditionFilter(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.M
+	err = r.Collection.FindOne(r.ctx(ctx), filter, options.FindOne().SetSort(bson.D{{Key: "_id", Value: 1}})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.New(r.modelType()).Interface()
+	if err := r.decode(doc, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindAllBy returns all records matching the condition
+func (r *MongoRepository) FindAllBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	records, _, err := r.List(ctx, query.QueryOptions{Filters: condition, DisablePagination: true})
+	return records, err
+}
+
+// WithTransaction runs fn in a MongoDB transaction. Transactions require a replica set
+// or sharded cluster.
+func (r *MongoRepository) WithTransaction(fn func(Repository) error) error {
+	ctx := context.Background()
+	session, err := r.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		txRepo := *r
+		txRepo.session = session
+		return nil, fn(&txRepo)
+	})
+	return err
+}
+
+// BulkCreate inserts each record of a slice
+func (r *MongoRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	_, err := r.CreateMany(ctx, data)
+	return err
+}
+
+// BulkUpdate sets fields on all records matching the condition
+func (r *MongoRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	filter, err := r.conditionFilter(condition)
+	if err != nil {
+		return err
+	}
+	doc, err := r.toDocument(updates, true)
+	if err != nil {
+		return err
+	}
+	_, err = r.Collection.UpdateMany(r.ctx(ctx), filter, bson.M{"$set": doc})
+	return err
+}
+
+// GetIDFieldName returns the name of the ID field
+func (r *MongoRepository) GetIDFieldName() string {
+	if r.Resource != nil {
+		return r.Resource.GetIDFieldName()
+	}
+	return "ID"
+}
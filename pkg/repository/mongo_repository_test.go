@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+type MongoAddress struct {
+	City string `json:"city"`
+}
+
+type MongoProduct struct {
+	ID        uint         `json:"id"`
+	Name      string       `json:"name"`
+	Price     float64      `json:"price"`
+	Active    bool         `json:"active"`
+	Tags      []string     `json:"tags"`
+	Address   MongoAddress `json:"address"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func newTestMongoRepository(t *testing.T, uri string) (*MongoRepository, *mongo.Client) {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri).SetTimeout(5*time.Second))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: MongoProduct{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "price", Type: "float"},
+		},
+		SearchableFields: []string{"name"},
+	})
+	repo := NewMongoRepositoryWithConfig(client, res, MongoRepositoryConfig{Database: "refine_gin_test"}).(*MongoRepository)
+	return repo, client
+}
+
+func TestMongoRepositoryFilters(t *testing.T) {
+	repo, _ := newTestMongoRepository(t, "mongodb://localhost:27017")
+
+	filter, err := repo.buildFilter(query.QueryOptions{
+		Filters: map[string]interface{}{"id": "3"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"_id": int64(3)}, filter)
+
+	filter, err = repo.buildFilter(query.QueryOptions{
+		Search: "a.b",
+		AdvancedFilters: []query.Filter{
+			{Field: "price", Operator: "gte", Value: "9.5"},
+			{Field: "active", Operator: "eq", Value: "true"},
+			{Field: "name", Operator: "startswith", Value: "Pro"},
+			{Field: "id", Operator: "in", Value: "1,2"},
+			{Field: "created_at", Operator: "between", Value: []interface{}{"2024-01-01", "2024-02-01"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"price": bson.M{"$gte": 9.5}},
+		{"active": bson.M{"$eq": true}},
+		{"name": primitive.Regex{Pattern: "^Pro", Options: "i"}},
+		{"_id": bson.M{"$in": []interface{}{int64(1), int64(2)}}},
+		{"created_at": bson.M{
+			"$gte": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			"$lte": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"$or": []bson.M{{"name": primitive.Regex{Pattern: `a\.b`, Options: "i"}}}},
+	}}, filter)
+
+	_, err = repo.buildFilter(query.QueryOptions{AdvancedFilters: []query.Filter{{Field: "price", Operator: "gt", Value: "abc"}}})
+	assert.Error(t, err)
+	_, err = repo.buildFilter(query.QueryOptions{AdvancedFilters: []query.Filter{{Field: "price", Operator: "near", Value: "1"}}})
+	assert.Error(t, err)
+
+	opts := repo.findOptions(query.QueryOptions{Page: 3, PerPage: 10, Sort: "price", Order: "desc"})
+	assert.Equal(t, bson.D{{Key: "price", Value: -1}, {Key: "_id", Value: 1}}, opts.Sort)
+	assert.Equal(t, int64(20), *opts.Skip)
+	assert.Equal(t, int64(10), *opts.Limit)
+}
+
+func TestMongoRepositoryDocuments(t *testing.T) {
+	repo, _ := newTestMongoRepository(t, "mongodb://localhost:27017")
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	doc, err := repo.toDocument(&MongoProduct{ID: 7, Name: "Lamp", Address: MongoAddress{City: "Oslo"}, CreatedAt: created}, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), doc["_id"])
+	assert.Equal(t, "Lamp", doc["name"])
+	assert.Equal(t, bson.M{"city": "Oslo"}, doc["address"])
+	assert.NotContains(t, doc, "id")
+
+	// Partial documents only contain set fields and never the ID
+	doc, err = repo.toDocument(&MongoProduct{ID: 7, Price: 12}, true)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"price": 12.0}, doc)
+
+	doc, err = repo.toDocument(map[string]interface{}{"id": 1, "name": "Desk"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, bson.M{"name": "Desk"}, doc)
+
+	var product MongoProduct
+	require.NoError(t, repo.decode(bson.M{
+		"_id":        int64(7),
+		"name":       "Lamp",
+		"tags":       bson.A{"home"},
+		"address":    bson.M{"city": "Oslo"},
+		"created_at": primitive.NewDateTimeFromTime(created),
+	}, &product))
+	assert.Equal(t, MongoProduct{ID: 7, Name: "Lamp", Tags: []string{"home"}, Address: MongoAddress{City: "Oslo"}, CreatedAt: created}, product)
+}
+
+// TestMongoRepositoryIntegration runs against a real server when REFINE_GIN_MONGO_URI is set
+func TestMongoRepositoryIntegration(t *testing.T) {
+	uri := os.Getenv("REFINE_GIN_MONGO_URI")
+	if uri == "" {
+		t.Skip("REFINE_GIN_MONGO_URI not set")
+	}
+	repo, client := newTestMongoRepository(t, uri)
+	ctx := context.Background()
+	require.NoError(t, client.Database("refine_gin_test").Drop(ctx))
+
+	created, err := repo.Create(ctx, &MongoProduct{Name: "Lamp", Price: 10, Active: true})
+	require.NoError(t, err)
+	lamp := created.(*MongoProduct)
+	assert.Equal(t, uint(1), lamp.ID)
+
+	_, err = repo.CreateMany(ctx, []MongoProduct{{Name: "Desk", Price: 100}, {Name: "Chair", Price: 50}})
+	require.NoError(t, err)
+
+	records, total, err := repo.List(ctx, query.QueryOptions{Page: 1, PerPage: 2, Sort: "price", Order: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	items := reflect.ValueOf(records).Elem().Interface().([]MongoProduct)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Desk", items[0].Name)
+
+	updated, err := repo.Update(ctx, "1", &MongoProduct{Price: 15})
+	require.NoError(t, err)
+	assert.Equal(t, "Lamp", updated.(*MongoProduct).Name)
+	assert.Equal(t, 15.0, updated.(*MongoProduct).Price)
+
+	count, err := repo.Count(ctx, query.QueryOptions{AdvancedFilters: []query.Filter{{Field: "price", Operator: "gte", Value: "50"}}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	deleted, err := repo.DeleteMany(ctx, []interface{}{"2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	require.NoError(t, repo.Delete(ctx, 1))
+	_, err = repo.Get(ctx, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TeamDocument struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Title   string `json:"title"`
+	OwnerID string `json:"ownerId"`
+	TeamID  string `json:"teamId"`
+}
+
+func TestOwnerRepositoryGroups(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TeamDocument{}))
+	require.NoError(t, db.Create([]*TeamDocument{
+		{Title: "mine", OwnerID: "ann"},
+		{Title: "team", OwnerID: "bob", TeamID: "red"},
+		{Title: "other team", OwnerID: "bob", TeamID: "blue"},
+	}).Error)
+
+	config := resource.DefaultOwnerConfig()
+	config.GroupField = "TeamID"
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{Name: "documents", Model: &TeamDocument{}}), config)
+	repo, err := NewOwnerRepository(db, res)
+	require.NoError(t, err)
+
+	ctx := requestctx.With(context.Background(),
+		requestctx.OwnerID.Value("ann"),
+		requestctx.OwnerGroupIDs.Value([]interface{}{"red"}))
+
+	titles := func(records interface{}) []string {
+		names := []string{}
+		for _, document := range *records.(*[]TeamDocument) {
+			names = append(names, document.Title)
+		}
+		return names
+	}
+	records, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10, Sort: "id", Order: "asc"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []string{"mine", "team"}, titles(records))
+
+	// Without groups only owned records are accessible
+	records, _, err = repo.List(requestctx.OwnerID.With(context.Background(), "ann"), query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mine"}, titles(records))
+
+	_, err = repo.Get(ctx, uint(2))
+	assert.NoError(t, err)
+	_, err = repo.Get(ctx, uint(3))
+	assert.ErrorIs(t, err, ErrOwnerMismatch)
+
+	_, err = repo.Update(ctx, uint(2), map[string]interface{}{"title": "team notes"})
+	assert.NoError(t, err)
+	_, err = repo.Update(ctx, uint(2), map[string]interface{}{"team_id": "blue"})
+	assert.ErrorIs(t, err, ErrOwnerMismatch)
+
+	// Records can only be created in groups of the owner
+	_, err = repo.Create(ctx, &TeamDocument{Title: "new", TeamID: "red"})
+	assert.NoError(t, err)
+	_, err = repo.Create(ctx, &TeamDocument{Title: "foreign", TeamID: "blue"})
+	assert.ErrorIs(t, err, ErrOwnerMismatch)
+
+	found, err := repo.FindAllBy(ctx, map[string]interface{}{"owner_id": "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team notes"}, titles(found))
+
+	assert.ErrorIs(t, repo.Delete(ctx, uint(3)), ErrOwnerMismatch)
+	assert.NoError(t, repo.Delete(ctx, uint(2)))
+}
@@ -105,12 +105,70 @@ func (r *OwnerGenericRepository) applyOwnerFilter(ctx context.Context, tx *gorm.
 		return tx, nil
 	}
 
-	// Get the owner field and convert to column name
-	ownerField := r.Resource.GetOwnerField()
-	columnName := tx.Config.NamingStrategy.ColumnName("", ownerField)
-
 	// Apply the owner filter
-	return tx.Where(columnName+" = ?", ownerID), nil
+	return tx.Scopes(r.ownedBy(ctx, ownerID)), nil
+}
+
+// ownedBy returns a scope restricting a query to the records of the owner and, for
+// resources with a group field, of the groups of the owner (see middleware.OwnerGroups)
+func (r *OwnerGenericRepository) ownedBy(ctx context.Context, ownerID interface{}) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		ownerColumn := tx.NamingStrategy.ColumnName("", r.Resource.GetOwnerField())
+		groupField := r.Resource.GetOwnerConfig().GroupField
+		groupIDs := middleware.GetOwnerGroupIDs(ctx)
+		if groupField == "" || len(groupIDs) == 0 {
+			return tx.Where(ownerColumn+" = ?", ownerID)
+		}
+		groupColumn := tx.NamingStrategy.ColumnName("", groupField)
+		return tx.Where(fmt.Sprintf("(%s = ? OR %s IN ?)", ownerColumn, groupColumn), ownerID, groupIDs)
+	}
+}
+
+// inOwnerGroups reports whether a group ID is one of the groups of the owner
+func inOwnerGroups(ctx context.Context, groupID interface{}) bool {
+	for _, id := range middleware.GetOwnerGroupIDs(ctx) {
+		if fmt.Sprintf("%v", id) == fmt.Sprintf("%v", groupID) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyGroup rejects group IDs that are not among the groups of the owner, so
+// records can't be given to foreign groups. Zero values leave a record without group.
+func (r *OwnerGenericRepository) verifyGroup(ctx context.Context, groupID interface{}) error {
+	if groupID == nil || reflect.ValueOf(groupID).IsZero() || inOwnerGroups(ctx, groupID) {
+		return nil
+	}
+	return ErrOwnerMismatch
+}
+
+// groupValue returns the group ID of a record or a map of updates, if set
+func (r *OwnerGenericRepository) groupValue(data interface{}) (interface{}, bool) {
+	groupField := r.Resource.GetOwnerConfig().GroupField
+	if groupField == "" {
+		return nil, false
+	}
+	if values, ok := data.(map[string]interface{}); ok {
+		for key, value := range values {
+			if strings.EqualFold(key, groupField) || key == r.DB.NamingStrategy.ColumnName("", groupField) {
+				return value, true
+			}
+		}
+		return nil, false
+	}
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field := value.FieldByName(groupField)
+	if !field.IsValid() {
+		return nil, false
+	}
+	return field.Interface(), true
 }
 
 // verifyOwnership checks if the user owns a record
@@ -153,6 +211,10 @@ func (r *OwnerGenericRepository) verifyOwnership(ctx context.Context, id interfa
 	contextOwnerID := fmt.Sprintf("%v", ownerID)
 
 	if recordOwnerID != contextOwnerID {
+		// Members of the group owning the record have access too
+		if groupID, ok := r.groupValue(record); ok && inOwnerGroups(ctx, groupID) {
+			return nil
+		}
 		return ErrOwnerMismatch
 	}
 
@@ -204,6 +266,12 @@ func (r *OwnerGenericRepository) setOwnership(ctx context.Context, data interfac
 				}
 				field.Set(reflect.ValueOf(converted))
 			}
+
+			if groupID, ok := r.groupValue(item.Interface()); ok {
+				if err := r.verifyGroup(ctx, groupID); err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	}
@@ -224,6 +292,9 @@ func (r *OwnerGenericRepository) setOwnership(ctx context.Context, data interfac
 		field.Set(reflect.ValueOf(converted))
 	}
 
+	if groupID, ok := r.groupValue(data); ok {
+		return r.verifyGroup(ctx, groupID)
+	}
 	return nil
 }
 
@@ -287,11 +358,12 @@ func (r *OwnerGenericRepository) List(ctx context.Context, options query.QueryOp
 	}
 
 	// Use GenericRepository to complete the operation with modified query
+	origDB := r.DB
 	r.DB = tx
 	result, total, err := r.GenericRepository.List(ctx, options)
 
 	// Reset DB to original
-	r.DB = r.GenericRepository.DB
+	r.DB = origDB
 
 	return result, total, err
 }
@@ -302,7 +374,7 @@ func (r *OwnerGenericRepository) Get(ctx context.Context, id interface{}) (inter
 	fmt.Printf("[DEBUG-REPO] Get request for ID: %v\n", id)
 
 	// Log the context owner ID
-	if ownerID, exists := ctx.Value(middleware.OwnerContextKey).(string); exists {
+	if ownerID, err := middleware.GetOwnerID(ctx); err == nil {
 		fmt.Printf("[DEBUG-REPO] Context owner ID: %v\n", ownerID)
 	} else {
 		fmt.Printf("[DEBUG-REPO] WARNING: No owner ID found in context\n")
@@ -343,15 +415,15 @@ func (r *OwnerGenericRepository) Get(ctx context.Context, id interface{}) (inter
 		result = reflect.New(modelType.Elem()).Interface()
 	}
 
-	// Build query directly with proper column names
-	query := r.DB.Model(r.Model)
+	// Build query directly with proper column names, leaving out soft-deleted records
+	query := r.untrashed(r.DB.Model(r.Model))
 
 	// Add the ID condition - use column name from naming strategy
 	query = query.Where(fmt.Sprintf("%s = ?", idColumnName), id)
 
 	// Add owner ID condition if enforced
 	if r.Resource != nil && r.Resource.IsOwnershipEnforced() && ownerID != nil {
-		query = query.Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID)
+		query = query.Scopes(r.ownedBy(ctx, ownerID))
 	}
 
 	// Log the SQL query
@@ -372,7 +444,7 @@ func (r *OwnerGenericRepository) Get(ctx context.Context, id interface{}) (inter
 		// Check if record exists without owner filter
 		if r.Resource != nil && r.Resource.IsOwnershipEnforced() && err == gorm.ErrRecordNotFound {
 			var exists bool
-			checkQuery := r.DB.Model(r.Model).Where(fmt.Sprintf("%s = ?", idColumnName), id)
+			checkQuery := r.untrashed(r.DB.Model(r.Model).Where(fmt.Sprintf("%s = ?", idColumnName), id))
 
 			if err := checkQuery.Select("1").Limit(1).Find(&exists).Error; err != nil {
 				return nil, err
@@ -413,7 +485,7 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 	TrySetID(data, id)
 
 	// Log the context owner ID
-	if ownerID, exists := ctx.Value(middleware.OwnerContextKey).(string); exists {
+	if ownerID, err := middleware.GetOwnerID(ctx); err == nil {
 		fmt.Printf("[DEBUG-REPO] Context owner ID: %v\n", ownerID)
 	} else {
 		fmt.Printf("[DEBUG-REPO] WARNING: No owner ID found in context\n")
@@ -448,7 +520,7 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 
 	// Add owner condition if ownership is enforced
 	if r.Resource != nil && r.Resource.IsOwnershipEnforced() && ownerID != nil {
-		checkQuery = checkQuery.Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID)
+		checkQuery = checkQuery.Scopes(r.ownedBy(ctx, ownerID))
 	}
 
 	// Execute the check query
@@ -486,6 +558,15 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 		}
 	}
 
+	// Records can only be moved to groups of the owner
+	if r.Resource != nil && r.Resource.IsOwnershipEnforced() && ownerID != nil {
+		if groupID, ok := r.groupValue(data); ok {
+			if err := r.verifyGroup(ctx, groupID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Handle different update methods based on data type
 	dataMap, isMap := data.(map[string]interface{})
 	if isMap && r.Resource != nil && r.Resource.IsOwnershipEnforced() {
@@ -511,6 +592,11 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 		fmt.Printf("[DEBUG-REPO] Modified update data: %+v\n", dataMap)
 	}
 
+	// Versioned records are saved by the generic update, which checks their version
+	if r.versionField() != nil {
+		return r.GenericRepository.Update(ctx, id, data)
+	}
+
 	// First try to get the existing record
 	var result interface{}
 	modelType := reflect.TypeOf(r.Model)
@@ -603,7 +689,7 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 
 			// Add owner condition if ownership is enforced
 			if r.Resource != nil && r.Resource.IsOwnershipEnforced() && ownerID != nil {
-				updateQuery = updateQuery.Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID)
+				updateQuery = updateQuery.Scopes(r.ownedBy(ctx, ownerID))
 			}
 
 			// Save the entire record
@@ -628,7 +714,7 @@ func (r *OwnerGenericRepository) Update(ctx context.Context, id interface{}, dat
 
 	// Add owner condition if ownership is enforced
 	if r.Resource != nil && r.Resource.IsOwnershipEnforced() && ownerID != nil {
-		updateQuery = updateQuery.Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID)
+		updateQuery = updateQuery.Scopes(r.ownedBy(ctx, ownerID))
 	}
 
 	// Log the SQL query
@@ -701,16 +787,12 @@ func (r *OwnerGenericRepository) Delete(ctx context.Context, id interface{}) err
 	// Get proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	// Get the owner field name
-	ownerField := r.Resource.GetOwnerField()
-	ownerColumnName := r.DB.NamingStrategy.ColumnName("", ownerField)
-
 	// Check if the record exists and belongs to the owner - Start with fresh query
 	var exists bool
-	result := r.DB.WithContext(ctx).
+	result := r.untrashed(r.DB.WithContext(ctx).
 		Model(r.Model). // Use Model to ensure we reset any previous conditions
 		Where(fmt.Sprintf("%s = ?", idColumnName), id).
-		Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID).
+		Scopes(r.ownedBy(ctx, ownerID))).
 		Select("COUNT(*) > 0").
 		Find(&exists)
 
@@ -721,9 +803,9 @@ func (r *OwnerGenericRepository) Delete(ctx context.Context, id interface{}) err
 	if !exists {
 		// Check if record exists at all - Start with fresh query
 		var recordExists bool
-		r.DB.WithContext(ctx).
+		r.untrashed(r.DB.WithContext(ctx).
 			Model(r.Model). // Use Model to ensure we reset any previous conditions
-			Where(fmt.Sprintf("%s = ?", idColumnName), id).
+			Where(fmt.Sprintf("%s = ?", idColumnName), id)).
 			Select("COUNT(*) > 0").
 			Find(&recordExists)
 
@@ -735,12 +817,17 @@ func (r *OwnerGenericRepository) Delete(ctx context.Context, id interface{}) err
 		return gorm.ErrRecordNotFound
 	}
 
-	// Delete with both ID and owner filter - Start with fresh query
-	return r.DB.WithContext(ctx).
-		Model(r.Model). // Use Model to ensure we reset any previous conditions
-		Where(fmt.Sprintf("%s = ?", idColumnName), id).
-		Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID).
-		Delete(r.Model).Error
+	// Delete with both ID and owner filter, or move the record to the trash - Start
+	// with fresh queries
+	owned := func() *gorm.DB {
+		return r.DB.WithContext(ctx).
+			Where(fmt.Sprintf("%s = ?", idColumnName), id).
+			Scopes(r.ownedBy(ctx, ownerID))
+	}
+	if _, trashed, err := r.trash(owned()); trashed {
+		return err
+	}
+	return owned().Delete(r.newModel()).Error
 }
 
 // Count returns the total number of resources filtered by owner
@@ -807,10 +894,6 @@ func (r *OwnerGenericRepository) DeleteMany(ctx context.Context, ids []interface
 	// Get proper column name using GORM's naming strategy
 	idColumnName := r.DB.NamingStrategy.ColumnName("", idFieldName)
 
-	// Get the owner field name
-	ownerField := r.Resource.GetOwnerField()
-	ownerColumnName := r.DB.NamingStrategy.ColumnName("", ownerField)
-
 	// Extract owner ID from context
 	ownerID, err := r.extractOwnerID(ctx)
 	if err != nil {
@@ -825,10 +908,10 @@ func (r *OwnerGenericRepository) DeleteMany(ctx context.Context, ids []interface
 	// Check if all records exist and belong to the owner
 	for _, id := range ids {
 		var exists bool
-		result := r.DB.WithContext(ctx).
+		result := r.untrashed(r.DB.WithContext(ctx).
 			Model(r.Model).
 			Where(fmt.Sprintf("%s = ?", idColumnName), id).
-			Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID).
+			Scopes(r.ownedBy(ctx, ownerID))).
 			Select("COUNT(*) > 0").
 			Find(&exists)
 
@@ -839,9 +922,9 @@ func (r *OwnerGenericRepository) DeleteMany(ctx context.Context, ids []interface
 		if !exists {
 			// Check if record exists at all
 			var recordExists bool
-			r.DB.WithContext(ctx).
+			r.untrashed(r.DB.WithContext(ctx).
 				Model(r.Model).
-				Where(fmt.Sprintf("%s = ?", idColumnName), id).
+				Where(fmt.Sprintf("%s = ?", idColumnName), id)).
 				Select("COUNT(*) > 0").
 				Find(&recordExists)
 
@@ -854,13 +937,16 @@ func (r *OwnerGenericRepository) DeleteMany(ctx context.Context, ids []interface
 		}
 	}
 
-	// Delete all records with both ID and owner filter
-	result := r.DB.WithContext(ctx).
-		Model(r.Model).
-		Where(fmt.Sprintf("%s IN ?", idColumnName), ids).
-		Where(fmt.Sprintf("%s = ?", ownerColumnName), ownerID).
-		Delete(r.Model)
-
+	// Delete all records with both ID and owner filter, or move them to the trash
+	owned := func() *gorm.DB {
+		return r.DB.WithContext(ctx).
+			Where(fmt.Sprintf("%s IN ?", idColumnName), ids).
+			Scopes(r.ownedBy(ctx, ownerID))
+	}
+	if deleted, trashed, err := r.trash(owned()); trashed {
+		return deleted, err
+	}
+	result := owned().Delete(r.newModel())
 	return result.RowsAffected, result.Error
 }
 
@@ -886,10 +972,9 @@ func (r *OwnerGenericRepository) FindOneBy(ctx context.Context, condition map[st
 
 	// Add owner condition if enforced
 	if ownerID != nil && r.Resource != nil && r.Resource.IsOwnershipEnforced() {
-		// Get owner field name and convert to column name using GORM's naming strategy
-		ownerField := r.Resource.GetOwnerField()
-		ownerColumnName := r.DB.NamingStrategy.ColumnName("", ownerField)
-		condition[ownerColumnName] = ownerID
+		origDB := r.DB
+		r.DB = r.DB.Scopes(r.ownedBy(ctx, ownerID))
+		defer func() { r.DB = origDB }()
 	}
 
 	return r.GenericRepository.FindOneBy(ctx, condition)
@@ -905,10 +990,9 @@ func (r *OwnerGenericRepository) FindAllBy(ctx context.Context, condition map[st
 
 	// Add owner condition if enforced
 	if ownerID != nil && r.Resource != nil && r.Resource.IsOwnershipEnforced() {
-		// Get owner field name and convert to column name using GORM's naming strategy
-		ownerField := r.Resource.GetOwnerField()
-		ownerColumnName := r.DB.NamingStrategy.ColumnName("", ownerField)
-		condition[ownerColumnName] = ownerID
+		origDB := r.DB
+		r.DB = r.DB.Scopes(r.ownedBy(ctx, ownerID))
+		defer func() { r.DB = origDB }()
 	}
 
 	return r.GenericRepository.FindAllBy(ctx, condition)
@@ -943,11 +1027,12 @@ func (r *OwnerGenericRepository) ListWithRelations(ctx context.Context, options
 	}
 
 	// Use GenericRepository to complete the operation with modified query
+	origDB := r.DB
 	r.DB = tx
 	result, total, err := r.GenericRepository.ListWithRelations(ctx, options, relations)
 
 	// Reset DB to original
-	r.DB = r.GenericRepository.DB
+	r.DB = origDB
 
 	return result, total, err
 }
@@ -981,10 +1066,9 @@ func (r *OwnerGenericRepository) BulkUpdate(ctx context.Context, condition map[s
 
 	// Add owner condition if enforced
 	if ownerID != nil && r.Resource != nil && r.Resource.IsOwnershipEnforced() {
-		// Get owner field name and convert to column name using GORM's naming strategy
-		ownerField := r.Resource.GetOwnerField()
-		ownerColumnName := r.DB.NamingStrategy.ColumnName("", ownerField)
-		condition[ownerColumnName] = ownerID
+		origDB := r.DB
+		r.DB = r.DB.Scopes(r.ownedBy(ctx, ownerID))
+		defer func() { r.DB = origDB }()
 	}
 
 	return r.GenericRepository.BulkUpdate(ctx, condition, updates)
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrRelationNotManaged is returned for relations that are not one-to-many or
+	// many-to-many relations of the model
+	ErrRelationNotManaged = errors.New("relation not found or not a to-many relation")
+
+	// ErrRelatedNotFound is returned when some related records do not exist
+	ErrRelatedNotFound = errors.New("related records not found")
+)
+
+// RelationRepository is implemented by repositories managing which records are linked
+// to a record through its one-to-many and many-to-many relations. Relations are named
+// by their field (e.g. "Tags"), in any naming convention. Records that don't exist
+// are reported with gorm.ErrRecordNotFound, related records with ErrRelatedNotFound.
+type RelationRepository interface {
+	// AttachRelated links the related records with the IDs to the record
+	AttachRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error
+
+	// DetachRelated unlinks the related records with the IDs from the record
+	DetachRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error
+
+	// ReplaceRelated links exactly the related records with the IDs to the record,
+	// unlinking the others
+	ReplaceRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error
+}
+
+// AttachRelated links the related records to the record with a GORM association:
+// many-to-many relations get join table rows, one-to-many relations set the foreign
+// key of the related records
+func (r *GenericRepository) AttachRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error {
+	return r.manageRelated(ctx, id, relation, relatedIDs, func(association *gorm.Association, related interface{}) error {
+		return association.Append(related)
+	})
+}
+
+// DetachRelated unlinks the related records from the record: many-to-many relations
+// lose their join table rows, one-to-many relations clear the foreign key of the
+// related records, which must be nullable. The related records are not deleted.
+func (r *GenericRepository) DetachRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error {
+	return r.manageRelated(ctx, id, relation, relatedIDs, func(association *gorm.Association, related interface{}) error {
+		return association.Delete(related)
+	})
+}
+
+// ReplaceRelated links exactly the related records to the record, unlinking the
+// others like DetachRelated. No IDs unlink every related record.
+func (r *GenericRepository) ReplaceRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}) error {
+	return r.manageRelated(ctx, id, relation, relatedIDs, func(association *gorm.Association, related interface{}) error {
+		if reflect.ValueOf(related).Elem().Len() == 0 {
+			return association.Clear()
+		}
+		return association.Replace(related)
+	})
+}
+
+// manageRelated loads the record and the related records in a transaction, then
+// changes the association of the relation with them
+func (r *GenericRepository) manageRelated(ctx context.Context, id interface{}, relation string, relatedIDs []interface{}, change func(*gorm.Association, interface{}) error) error {
+	relationship, err := r.managedRelationship(relation)
+	if err != nil {
+		return err
+	}
+
+	return r.scoped(ctx).Transaction(func(tx *gorm.DB) error {
+		record := r.newModel()
+		if err := tx.Where(r.idColumn()+" = ?", id).First(record).Error; err != nil {
+			return err
+		}
+
+		related, err := findRelated(tx.Session(&gorm.Session{NewDB: true}), relationship.FieldSchema, relatedIDs)
+		if err != nil {
+			return err
+		}
+
+		association := tx.Session(&gorm.Session{NewDB: true}).Model(record).Association(relationship.Name)
+		if association.Error != nil {
+			return association.Error
+		}
+		return change(association, related)
+	})
+}
+
+// managedRelationship returns the one-to-many or many-to-many relationship of the
+// model with the name in any naming convention
+func (r *GenericRepository) managedRelationship(name string) (*schema.Relationship, error) {
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(r.Model); err != nil {
+		return nil, err
+	}
+	key := normalizeRelationName(name)
+	for relationName, relationship := range stmt.Schema.Relationships.Relations {
+		if normalizeRelationName(relationName) != key {
+			continue
+		}
+		if relationship.Type != schema.HasMany && relationship.Type != schema.Many2Many {
+			break
+		}
+		return relationship, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRelationNotManaged, name)
+}
+
+// findRelated returns a pointer to a slice of the related records with the IDs,
+// failing with ErrRelatedNotFound unless all of them exist
+func findRelated(tx *gorm.DB, related *schema.Schema, ids []interface{}) (interface{}, error) {
+	records := reflect.New(reflect.SliceOf(related.ModelType))
+	primaryKey := related.PrioritizedPrimaryField
+	if len(ids) == 0 {
+		return records.Interface(), nil
+	}
+	if primaryKey == nil {
+		return nil, fmt.Errorf("%w: %s has no primary key", ErrRelatedNotFound, related.Name)
+	}
+
+	if err := tx.Where(clause.IN{Column: clause.Column{Table: related.Table, Name: primaryKey.DBName}, Values: ids}).Find(records.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for i := 0; i < records.Elem().Len(); i++ {
+		value, _ := primaryKey.ValueOf(tx.Statement.Context, records.Elem().Index(i))
+		found[relatedKey(value)] = true
+	}
+	var missing []string
+	for _, id := range ids {
+		if !found[relatedKey(id)] {
+			missing = append(missing, fmt.Sprint(id))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRelatedNotFound, strings.Join(missing, ", "))
+	}
+	return records.Interface(), nil
+}
+
+// relatedKey formats an ID for comparisons, so IDs decoded from JSON numbers match
+// the integer IDs of records
+func relatedKey(id interface{}) string {
+	if number, ok := id.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(id)
+}
+
+// normalizeRelationName folds the naming conventions of a relation name (Tags, tags,
+// blog_tags, blogTags, blog-tags)
+func normalizeRelationName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
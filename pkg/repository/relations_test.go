@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type LinkedPost struct {
+	ID       uint            `json:"id" gorm:"primaryKey"`
+	Title    string          `json:"title"`
+	Tags     []LinkedTag     `json:"tags" gorm:"many2many:linked_post_tags"`
+	Comments []LinkedComment `json:"comments" gorm:"foreignKey:PostID"`
+}
+
+type LinkedTag struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type LinkedComment struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	PostID *uint  `json:"post_id"`
+	Body   string `json:"body"`
+}
+
+func TestRelationRepository(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&LinkedPost{}, &LinkedTag{}, &LinkedComment{}))
+	require.NoError(t, db.Create(&[]LinkedTag{{Name: "go"}, {Name: "sql"}, {Name: "web"}}).Error)
+	require.NoError(t, db.Create(&[]LinkedComment{{Body: "first"}, {Body: "second"}}).Error)
+	post := LinkedPost{Title: "Hello"}
+	require.NoError(t, db.Create(&post).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "posts", Model: LinkedPost{}})
+	repo := NewGenericRepositoryWithResource(db, res).(RelationRepository)
+	ctx := context.Background()
+
+	tagIDs := func() []uint {
+		var loaded LinkedPost
+		require.NoError(t, db.Preload("Tags").First(&loaded, post.ID).Error)
+		ids := []uint{}
+		for _, tag := range loaded.Tags {
+			ids = append(ids, tag.ID)
+		}
+		return ids
+	}
+
+	// IDs decoded from JSON are float64
+	require.NoError(t, repo.AttachRelated(ctx, post.ID, "tags", []interface{}{float64(1), float64(2)}))
+	assert.ElementsMatch(t, []uint{1, 2}, tagIDs())
+	require.NoError(t, repo.AttachRelated(ctx, post.ID, "Tags", []interface{}{float64(2)}), "attaching twice is a no-op")
+	assert.ElementsMatch(t, []uint{1, 2}, tagIDs())
+
+	require.NoError(t, repo.DetachRelated(ctx, post.ID, "tags", []interface{}{"1"}))
+	assert.ElementsMatch(t, []uint{2}, tagIDs())
+
+	require.NoError(t, repo.ReplaceRelated(ctx, post.ID, "tags", []interface{}{float64(1), float64(3)}))
+	assert.ElementsMatch(t, []uint{1, 3}, tagIDs())
+	require.NoError(t, repo.ReplaceRelated(ctx, post.ID, "tags", nil))
+	assert.Empty(t, tagIDs())
+
+	var tags int64
+	require.NoError(t, db.Model(&LinkedTag{}).Count(&tags).Error)
+	assert.Equal(t, int64(3), tags, "related records are not deleted")
+
+	// One-to-many relations set and clear the foreign key
+	require.NoError(t, repo.AttachRelated(ctx, post.ID, "comments", []interface{}{float64(1), float64(2)}))
+	var linked int64
+	require.NoError(t, db.Model(&LinkedComment{}).Where("post_id = ?", post.ID).Count(&linked).Error)
+	assert.Equal(t, int64(2), linked)
+	require.NoError(t, repo.DetachRelated(ctx, post.ID, "comments", []interface{}{"2"}))
+	var comment LinkedComment
+	require.NoError(t, db.First(&comment, 2).Error)
+	assert.Nil(t, comment.PostID)
+	assert.Equal(t, "second", comment.Body)
+
+	err = repo.AttachRelated(ctx, post.ID, "tags", []interface{}{float64(1), float64(9)})
+	assert.True(t, errors.Is(err, ErrRelatedNotFound))
+	assert.Contains(t, err.Error(), "9")
+	assert.Empty(t, tagIDs(), "nothing is attached when a related record is missing")
+
+	assert.True(t, errors.Is(repo.AttachRelated(ctx, 99, "tags", []interface{}{float64(1)}), gorm.ErrRecordNotFound))
+	assert.True(t, errors.Is(repo.AttachRelated(ctx, post.ID, "title", []interface{}{float64(1)}), ErrRelationNotManaged))
+}
@@ -0,0 +1,576 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// ErrNotSupported is returned for operations a repository cannot perform
+var ErrNotSupported = errors.New("operation not supported by repository")
+
+// Pagination styles understood by the remote API
+const (
+	PaginationStylePage   = "page"   // ?page=2&per_page=10
+	PaginationStyleOffset = "offset" // ?offset=10&limit=10
+)
+
+// RemoteError is returned when the remote API responds with a non-2xx status
+type RemoteError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote API responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// RestRepositoryConfig configures a repository proxying to a remote REST API
+type RestRepositoryConfig struct {
+	// Base URL of the remote collection (e.g. "https://users.internal/api/users")
+	BaseURL string
+
+	// HTTP client used for requests (defaults to a client with a 30s timeout)
+	Client *http.Client
+
+	// Static headers added to every request
+	Headers map[string]string
+
+	// Auth is called for every request to add credentials (see BearerAuth)
+	Auth func(req *http.Request) error
+
+	// FieldMapping maps local JSON field names to remote field names
+	FieldMapping map[string]string
+
+	// Pagination translation
+	PaginationStyle string // PaginationStylePage (default) or PaginationStyleOffset
+	PageParam       string // default "page"
+	PerPageParam    string // default "per_page"
+	OffsetParam     string // default "offset"
+	LimitParam      string // default "limit"
+
+	// Sorting and search parameters
+	SortParam   string // default "sort"
+	OrderParam  string // default "order"
+	SearchParam string // default "q"
+
+	// FilterParam builds the query parameter for a filter (defaults to "field" for eq
+	// and "field_operator" otherwise, json-server style)
+	FilterParam func(field, operator string) string
+
+	// Response envelope. When DataKey is empty the list response is a plain array.
+	DataKey     string // key holding the list in list responses (e.g. "data")
+	ItemKey     string // key holding the record in single responses (e.g. "data")
+	TotalKey    string // key holding the total in list responses (e.g. "total")
+	TotalHeader string // header holding the total (default "X-Total-Count")
+
+	// HTTP method used for updates (default PUT)
+	UpdateMethod string
+}
+
+// RestRepository implements Repository by proxying to a remote REST API
+type RestRepository struct {
+	Config   RestRepositoryConfig
+	Model    interface{}
+	Resource resource.Resource
+}
+
+// NewRestRepository creates a new repository proxying to a remote REST API.
+// The model or resource describes records returned by the remote service.
+func NewRestRepository(modelOrResource interface{}, config RestRepositoryConfig) Repository {
+	repo := &RestRepository{Config: config.withDefaults()}
+	if res, ok := modelOrResource.(resource.Resource); ok {
+		repo.Model = res.GetModel()
+		repo.Resource = res
+	} else {
+		repo.Model = modelOrResource
+	}
+	return repo
+}
+
+// BearerAuth returns an Auth function sending a static bearer token
+func BearerAuth(token string) func(req *http.Request) error {
+	return func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// withDefaults fills in default parameter names
+func (c RestRepositoryConfig) withDefaults() RestRepositoryConfig {
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.PaginationStyle == "" {
+		c.PaginationStyle = PaginationStylePage
+	}
+	if c.PageParam == "" {
+		c.PageParam = "page"
+	}
+	if c.PerPageParam == "" {
+		c.PerPageParam = "per_page"
+	}
+	if c.OffsetParam == "" {
+		c.OffsetParam = "offset"
+	}
+	if c.LimitParam == "" {
+		c.LimitParam = "limit"
+	}
+	if c.SortParam == "" {
+		c.SortParam = "sort"
+	}
+	if c.OrderParam == "" {
+		c.OrderParam = "order"
+	}
+	if c.SearchParam == "" {
+		c.SearchParam = "q"
+	}
+	if c.FilterParam == nil {
+		c.FilterParam = func(field, operator string) string {
+			if operator == "" || operator == "eq" {
+				return field
+			}
+			return field + "_" + operator
+		}
+	}
+	if c.TotalHeader == "" {
+		c.TotalHeader = "X-Total-Count"
+	}
+	if c.UpdateMethod == "" {
+		c.UpdateMethod = http.MethodPut
+	}
+	return c
+}
+
+// remoteField returns the remote name of a local field
+func (r *RestRepository) remoteField(field string) string {
+	if remote, ok := r.Config.FieldMapping[field]; ok {
+		return remote
+	}
+	return field
+}
+
+// localField returns the local name of a remote field
+func (r *RestRepository) localField(field string) string {
+	for local, remote := range r.Config.FieldMapping {
+		if remote == field {
+			return local
+		}
+	}
+	return field
+}
+
+// modelType returns the element type of the model
+func (r *RestRepository) modelType() reflect.Type {
+	t := reflect.TypeOf(r.Model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// itemURL returns the URL of a single record
+func (r *RestRepository) itemURL(id interface{}) string {
+	return r.Config.BaseURL + "/" + url.PathEscape(fmt.Sprint(id))
+}
+
+// buildListQuery translates query options into remote query parameters. Filter trees
+// (nested and/or groups) have no query parameter form and are not supported.
+func (r *RestRepository) buildListQuery(options query.QueryOptions) (url.Values, error) {
+	if len(options.FilterTree) > 0 {
+		return nil, ErrNotSupported
+	}
+	params := url.Values{}
+
+	if !options.DisablePagination && options.PerPage > 0 {
+		page := options.Page
+		if page < 1 {
+			page = 1
+		}
+		if r.Config.PaginationStyle == PaginationStyleOffset {
+			params.Set(r.Config.OffsetParam, strconv.Itoa((page-1)*options.PerPage))
+			params.Set(r.Config.LimitParam, strconv.Itoa(options.PerPage))
+		} else {
+			params.Set(r.Config.PageParam, strconv.Itoa(page))
+			params.Set(r.Config.PerPageParam, strconv.Itoa(options.PerPage))
+		}
+	}
+
+	if options.Sort != "" {
+		params.Set(r.Config.SortParam, r.remoteField(options.Sort))
+		if options.Order != "" {
+			params.Set(r.Config.OrderParam, options.Order)
+		}
+	}
+
+	if options.Search != "" {
+		params.Set(r.Config.SearchParam, options.Search)
+	}
+
+	for field, value := range options.Filters {
+		params.Add(r.Config.FilterParam(r.remoteField(field), "eq"), fmt.Sprint(value))
+	}
+	for _, filter := range options.AdvancedFilters {
+		params.Add(r.Config.FilterParam(r.remoteField(filter.Field), filter.Operator), formatFilterValue(filter.Value))
+	}
+
+	return params, nil
+}
+
+// formatFilterValue converts a filter value to a query parameter value
+func formatFilterValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(v, ",")
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// do performs a request against the remote API and returns the response body
+func (r *RestRepository) do(ctx context.Context, method, rawURL string, body interface{}) ([]byte, http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range r.Config.Headers {
+		req.Header.Set(key, value)
+	}
+	if r.Config.Auth != nil {
+		if err := r.Config.Auth(req); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resp, err := r.Config.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, resp.Header, gorm.ErrRecordNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, resp.Header, &RemoteError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	return data, resp.Header, nil
+}
+
+// toRemote converts local data into a remote payload, renaming mapped fields
+func (r *RestRepository) toRemote(data interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	remote := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		remote[r.remoteField(field)] = value
+	}
+	return remote, nil
+}
+
+// toLocal renames mapped fields of a remote record and decodes it into target
+func (r *RestRepository) toLocal(record map[string]interface{}, target interface{}) error {
+	local := make(map[string]interface{}, len(record))
+	for field, value := range record {
+		local[r.localField(field)] = value
+	}
+	raw, err := json.Marshal(local)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// unwrap extracts a value from a response envelope
+func unwrap(body []byte, key string) (json.RawMessage, map[string]json.RawMessage, error) {
+	if key == "" {
+		return body, nil, nil
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, err
+	}
+	return envelope[key], envelope, nil
+}
+
+// decodeItem decodes a single record response into a new model instance
+func (r *RestRepository) decodeItem(body []byte) (interface{}, error) {
+	raw, _, err := unwrap(body, r.Config.ItemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+
+	result := reflect.New(r.modelType()).Interface()
+	if err := r.toLocal(record, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// List returns a page of records from the remote API
+func (r *RestRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	params, err := r.buildListQuery(options)
+	if err != nil {
+		return nil, 0, err
+	}
+	listURL := r.Config.BaseURL
+	if len(params) > 0 {
+		listURL += "?" + params.Encode()
+	}
+
+	body, header, err := r.do(ctx, http.MethodGet, listURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	raw, envelope, err := unwrap(body, r.Config.DataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, 0, err
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(r.modelType()), len(records), len(records))
+	for i, record := range records {
+		if err := r.toLocal(record, slice.Index(i).Addr().Interface()); err != nil {
+			return nil, 0, err
+		}
+	}
+	result := reflect.New(slice.Type())
+	result.Elem().Set(slice)
+
+	// Total from the envelope, the total header, or the number of records returned
+	total := int64(len(records))
+	if r.Config.TotalKey != "" && envelope != nil {
+		if rawTotal, ok := envelope[r.Config.TotalKey]; ok {
+			if err := json.Unmarshal(rawTotal, &total); err != nil {
+				return nil, 0, err
+			}
+		}
+	} else if value := header.Get(r.Config.TotalHeader); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+			total = parsed
+		}
+	}
+
+	return result.Interface(), total, nil
+}
+
+// Get retrieves a single record by its ID
+func (r *RestRepository) Get(ctx context.Context, id interface{}) (interface{}, error) {
+	body, _, err := r.do(ctx, http.MethodGet, r.itemURL(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeItem(body)
+}
+
+// Create creates a record in the remote API
+func (r *RestRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	payload, err := r.toRemote(data)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := r.do(ctx, http.MethodPost, r.Config.BaseURL, payload)
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeItem(body)
+}
+
+// Update modifies a record in the remote API
+func (r *RestRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	TrySetID(data, id)
+	payload, err := r.toRemote(data)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := r.do(ctx, r.Config.UpdateMethod, r.itemURL(id), payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return r.Get(ctx, id)
+	}
+	return r.decodeItem(body)
+}
+
+// Delete removes a record from the remote API
+func (r *RestRepository) Delete(ctx context.Context, id interface{}) error {
+	_, _, err := r.do(ctx, http.MethodDelete, r.itemURL(id), nil)
+	return err
+}
+
+// Count returns the number of records matching the query options
+func (r *RestRepository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
+	options.Page = 1
+	options.PerPage = 1
+	options.DisablePagination = false
+	_, total, err := r.List(ctx, options)
+	return total, err
+}
+
+// CreateMany creates each record of a slice in the remote API
+func (r *RestRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	items := reflect.ValueOf(data)
+	if items.Kind() == reflect.Ptr {
+		items = items.Elem()
+	}
+	if items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected slice, got %s", items.Kind())
+	}
+
+	results := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(r.modelType())), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		created, err := r.Create(ctx, items.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		results = reflect.Append(results, reflect.ValueOf(created))
+	}
+	return results.Interface(), nil
+}
+
+// UpdateMany applies the same update to each record in the remote API
+func (r *RestRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	var updated int64
+	for _, id := range ids {
+		if _, err := r.Update(ctx, id, data); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// DeleteMany removes each record from the remote API
+func (r *RestRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	var deleted int64
+	for _, id := range ids {
+		if err := r.Delete(ctx, id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// WithRelations returns the repository unchanged; relations are resolved by the remote API
+func (r *RestRepository) WithRelations(relations ...string) Repository {
+	return r
+}
+
+// GetWithRelations retrieves a single record; relations are resolved by the remote API
+func (r *RestRepository) GetWithRelations(ctx context.Context, id interface{}, relations []string) (interface{}, error) {
+	return r.Get(ctx, id)
+}
+
+// ListWithRelations lists records; relations are resolved by the remote API
+func (r *RestRepository) ListWithRelations(ctx context.Context, options query.QueryOptions, relations []string) (interface{}, int64, error) {
+	return r.List(ctx, options)
+}
+
+// Query returns nil as there is no database behind a remote repository
+func (r *RestRepository) Query(ctx context.Context) *gorm.DB {
+	return nil
+}
+
+// FindOneBy returns the first record matching the condition
+func (r *RestRepository) FindOneBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	records, _, err := r.List(ctx, query.QueryOptions{Page: 1, PerPage: 1, Filters: condition})
+	if err != nil {
+		return nil, err
+	}
+	slice := reflect.ValueOf(records).Elem()
+	if slice.Len() == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return slice.Index(0).Addr().Interface(), nil
+}
+
+// FindAllBy returns all records matching the condition
+func (r *RestRepository) FindAllBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	records, _, err := r.List(ctx, query.QueryOptions{Filters: condition, DisablePagination: true})
+	return records, err
+}
+
+// WithTransaction is not supported by remote repositories, as remote calls cannot be
+// made atomic
+func (r *RestRepository) WithTransaction(fn func(Repository) error) error {
+	return ErrNotSupported
+}
+
+// BulkCreate creates each record of a slice in the remote API
+func (r *RestRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	_, err := r.CreateMany(ctx, data)
+	return err
+}
+
+// BulkUpdate is not supported by remote repositories
+func (r *RestRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	return ErrNotSupported
+}
+
+// GetIDFieldName returns the name of the ID field
+func (r *RestRepository) GetIDFieldName() string {
+	if r.Resource != nil {
+		return r.Resource.GetIDFieldName()
+	}
+	return "ID"
+}
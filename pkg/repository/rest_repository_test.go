@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"gorm.io/gorm"
+)
+
+type RemoteUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// newRemoteUserServer starts a fake remote API using "full_name" for the name field
+func newRemoteUserServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	users := map[string]map[string]interface{}{
+		"1": {"id": "1", "full_name": "Alice", "email": "alice@example.com"},
+		"2": {"id": "2", "full_name": "Bob", "email": "bob@example.com"},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*requests = append(*requests, req)
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.TrimPrefix(req.URL.Path, "/users")
+		id = strings.TrimPrefix(id, "/")
+
+		switch {
+		case req.Method == http.MethodGet && id == "":
+			w.Header().Set("X-Total-Count", "42")
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{users["1"], users["2"]})
+		case req.Method == http.MethodGet:
+			user, ok := users[id]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(user)
+		case req.Method == http.MethodPost || req.Method == http.MethodPut:
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+			if id == "" {
+				body["id"] = "3"
+			}
+			_ = json.NewEncoder(w).Encode(body)
+		case req.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func newTestRestRepository(serverURL string) Repository {
+	return NewRestRepository(RemoteUser{}, RestRepositoryConfig{
+		BaseURL:      serverURL + "/users",
+		Auth:         BearerAuth("secret"),
+		FieldMapping: map[string]string{"name": "full_name"},
+	})
+}
+
+func TestRestRepositoryList(t *testing.T) {
+	var requests []*http.Request
+	server := newRemoteUserServer(t, &requests)
+	defer server.Close()
+
+	repo := newTestRestRepository(server.URL)
+	result, total, err := repo.List(context.Background(), query.QueryOptions{
+		Page:    2,
+		PerPage: 10,
+		Sort:    "name",
+		Order:   "desc",
+		Search:  "al",
+		Filters: map[string]interface{}{"email": "alice@example.com"},
+		AdvancedFilters: []query.Filter{
+			{Field: "name", Operator: "contains", Value: "Al"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), total)
+
+	users := *result.(*[]RemoteUser)
+	require.Len(t, users, 2)
+	assert.Equal(t, "Alice", users[0].Name)
+
+	params := requests[0].URL.Query()
+	assert.Equal(t, "2", params.Get("page"))
+	assert.Equal(t, "10", params.Get("per_page"))
+	assert.Equal(t, "full_name", params.Get("sort"))
+	assert.Equal(t, "desc", params.Get("order"))
+	assert.Equal(t, "al", params.Get("q"))
+	assert.Equal(t, "alice@example.com", params.Get("email"))
+	assert.Equal(t, "Al", params.Get("full_name_contains"))
+}
+
+func TestRestRepositoryUnsupportedOperations(t *testing.T) {
+	var requests []*http.Request
+	server := newRemoteUserServer(t, &requests)
+	defer server.Close()
+	repo := newTestRestRepository(server.URL)
+
+	_, _, err := repo.List(context.Background(), query.QueryOptions{
+		FilterTree: []query.FilterNode{{
+			Filter: query.Filter{Operator: "or"},
+			Filters: []query.FilterNode{
+				{Filter: query.Filter{Field: "name", Operator: "eq", Value: "Alice"}},
+				{Filter: query.Filter{Field: "name", Operator: "eq", Value: "Bob"}},
+			},
+		}},
+	})
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	called := false
+	err = repo.WithTransaction(func(Repository) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.False(t, called)
+	assert.Empty(t, requests)
+}
+
+func TestRestRepositoryOffsetPaginationAndEnvelope(t *testing.T) {
+	var params map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params = req.URL.Query()
+		_, _ = w.Write([]byte(`{"items":[{"id":"1","name":"Alice"}],"meta":7}`))
+	}))
+	defer server.Close()
+
+	repo := NewRestRepository(&RemoteUser{}, RestRepositoryConfig{
+		BaseURL:         server.URL,
+		PaginationStyle: PaginationStyleOffset,
+		DataKey:         "items",
+		TotalKey:        "meta",
+	})
+
+	result, total, err := repo.List(context.Background(), query.QueryOptions{Page: 3, PerPage: 5})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+	assert.Len(t, *result.(*[]RemoteUser), 1)
+	assert.Equal(t, []string{"10"}, params["offset"])
+	assert.Equal(t, []string{"5"}, params["limit"])
+}
+
+func TestRestRepositoryCRUD(t *testing.T) {
+	var requests []*http.Request
+	server := newRemoteUserServer(t, &requests)
+	defer server.Close()
+
+	repo := newTestRestRepository(server.URL)
+	ctx := context.Background()
+
+	t.Run("get", func(t *testing.T) {
+		user, err := repo.Get(ctx, "1")
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", user.(*RemoteUser).Name)
+	})
+
+	t.Run("get not found", func(t *testing.T) {
+		_, err := repo.Get(ctx, "99")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("create maps fields", func(t *testing.T) {
+		created, err := repo.Create(ctx, &RemoteUser{Name: "Carol"})
+		require.NoError(t, err)
+		assert.Equal(t, "3", created.(*RemoteUser).ID)
+		assert.Equal(t, "Carol", created.(*RemoteUser).Name)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated, err := repo.Update(ctx, "2", &RemoteUser{ID: "2", Name: "Bobby"})
+		require.NoError(t, err)
+		assert.Equal(t, "Bobby", updated.(*RemoteUser).Name)
+		assert.Equal(t, http.MethodPut, requests[len(requests)-1].Method)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.Delete(ctx, "2"))
+	})
+
+	t.Run("count", func(t *testing.T) {
+		count, err := repo.Count(ctx, query.QueryOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+	})
+
+	t.Run("remote error", func(t *testing.T) {
+		unauthorized := NewRestRepository(RemoteUser{}, RestRepositoryConfig{BaseURL: server.URL + "/users"})
+		_, err := unauthorized.Get(ctx, "1")
+		var remoteErr *RemoteError
+		require.ErrorAs(t, err, &remoteErr)
+		assert.Equal(t, http.StatusUnauthorized, remoteErr.StatusCode)
+	})
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm/clause"
+)
+
+// Scope restricts the records a repository reads to those with a value in a field
+type Scope struct {
+	// Field name or column
+	Field string
+	Value interface{}
+}
+
+type scopesKey struct{}
+
+// WithScope returns a context restricting GenericRepository reads (List, Get, Count,
+// FindOneBy, ...) to records whose field has the value. Nested routes use it to keep
+// child records under their parent.
+func WithScope(ctx context.Context, field string, value interface{}) context.Context {
+	scopes := append(ScopesFromContext(ctx), Scope{Field: field, Value: value})
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// ScopesFromContext returns the scopes set with WithScope
+func ScopesFromContext(ctx context.Context) []Scope {
+	scopes, _ := ctx.Value(scopesKey{}).([]Scope)
+	return append([]Scope(nil), scopes...)
+}
+
+// scopeConditions returns the conditions of the scopes in the context
+func (r *GenericRepository) scopeConditions(ctx context.Context) []clause.Expression {
+	scopes := ScopesFromContext(ctx)
+	conditions := make([]clause.Expression, len(scopes))
+	for i, scope := range scopes {
+		conditions[i] = clause.Eq{Column: clause.Column{Name: r.DB.NamingStrategy.ColumnName("", scope.Field)}, Value: scope.Value}
+	}
+	return conditions
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ScopedItem struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	OrderID uint   `json:"order_id"`
+	Name    string `json:"name"`
+}
+
+func TestWithScope(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ScopedItem{}))
+	require.NoError(t, db.Create(&[]ScopedItem{{OrderID: 1, Name: "a"}, {OrderID: 1, Name: "b"}, {OrderID: 2, Name: "c"}}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "items", Model: ScopedItem{}})
+	repo := NewGenericRepositoryWithResource(db, res)
+	ctx := WithScope(context.Background(), "OrderID", 1)
+	assert.Len(t, ScopesFromContext(ctx), 1)
+
+	_, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	count, err := repo.Count(ctx, query.QueryOptions{Resource: res})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	_, err = repo.Get(ctx, 3)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Records outside the scope are not deleted
+	require.NoError(t, repo.Delete(ctx, 3))
+	_, err = repo.Get(context.Background(), 3)
+	assert.NoError(t, err)
+}
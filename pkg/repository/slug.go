@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// defaultSlug is used when the source field of a slug is empty
+const defaultSlug = "untitled"
+
+// SlugRepository is implemented by repositories generating slugs for fields with a
+// resource.SlugConfig. Slugs are generated on create and kept on update.
+type SlugRepository interface {
+	// GetBySlug returns the record with a slug; scope holds the values of the
+	// SlugConfig.Scope fields the slug is unique within
+	GetBySlug(ctx context.Context, field, slug string, scope map[string]interface{}) (interface{}, error)
+
+	// RegenerateSlug generates a new slug for a record from the current value of
+	// its source field
+	RegenerateSlug(ctx context.Context, id interface{}, field string) (interface{}, error)
+}
+
+// GetBySlug returns the record with a slug, or gorm.ErrRecordNotFound
+func (r *GenericRepository) GetBySlug(ctx context.Context, field, slug string, scope map[string]interface{}) (interface{}, error) {
+	result := r.newModel()
+	tx := r.scoped(ctx).Where(r.DB.NamingStrategy.ColumnName("", field)+" = ?", slug)
+	for name, value := range scope {
+		tx = tx.Where(r.DB.NamingStrategy.ColumnName("", name)+" = ?", value)
+	}
+	if err := tx.First(result).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadFields(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RegenerateSlug replaces the slug of a record with one generated from its source field
+func (r *GenericRepository) RegenerateSlug(ctx context.Context, id interface{}, field string) (interface{}, error) {
+	config, ok := r.slugConfig(field)
+	if !ok {
+		return nil, fmt.Errorf("field %q is not a slug field", field)
+	}
+
+	record, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.DB.WithContext(ctx)
+	slug, err := r.generateSlug(ctx, db, record, field, *config, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Model(record).Update(r.DB.NamingStrategy.ColumnName("", field), slug).Error; err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// slugConfig returns the slug configuration of a field of the resource
+func (r *GenericRepository) slugConfig(field string) (*resource.SlugConfig, bool) {
+	if r.Resource == nil {
+		return nil, false
+	}
+	for _, f := range resource.SlugFields(r.Resource) {
+		if f.Name == field {
+			return f.Slug, true
+		}
+	}
+	return nil, false
+}
+
+// assignSlugs generates the slugs of a new record. Slugs set by the client are
+// normalized and made unique as well. taken holds the slugs assigned earlier in the
+// same batch, which are not in the database yet.
+func (r *GenericRepository) assignSlugs(ctx context.Context, db *gorm.DB, record interface{}, taken map[string]bool) error {
+	if r.Resource == nil {
+		return nil
+	}
+	for _, field := range resource.SlugFields(r.Resource) {
+		slug, err := r.generateSlug(ctx, db, record, field.Name, *field.Slug, nil, taken)
+		if err != nil {
+			return err
+		}
+		if slug == "" {
+			continue
+		}
+		if err := setSchemaField(ctx, db, record, field.Name, slug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepSlugs copies the slugs of the existing record into the updated one, so slugs
+// stay stable when their source changes
+func (r *GenericRepository) keepSlugs(ctx context.Context, existing, updated interface{}) error {
+	if r.Resource == nil {
+		return nil
+	}
+	for _, field := range resource.SlugFields(r.Resource) {
+		value, ok := schemaFieldValue(ctx, r.DB, existing, field.Name)
+		if !ok {
+			continue
+		}
+		if err := setSchemaField(ctx, r.DB, updated, field.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateSlug returns a unique slug for a record: its current slug, or its source
+// field, slugified and suffixed with -2, -3, ... if needed. Slugs of soft-deleted
+// records are taken too. The record with excludeID is ignored. It returns "" for
+// records that are not structs.
+func (r *GenericRepository) generateSlug(ctx context.Context, db *gorm.DB, record interface{}, field string, config resource.SlugConfig, excludeID interface{}, taken map[string]bool) (string, error) {
+	if reflect.Indirect(reflect.ValueOf(record)).Kind() != reflect.Struct {
+		return "", nil
+	}
+
+	text := ""
+	if excludeID == nil {
+		if current, ok := schemaFieldValue(ctx, db, record, field); ok {
+			text = fmt.Sprintf("%v", current)
+		}
+	}
+	if text == "" {
+		source, ok := schemaFieldValue(ctx, db, record, config.Source)
+		if !ok && source == nil {
+			return "", fmt.Errorf("source field %q of slug %q not found in model", config.Source, field)
+		}
+		if source != nil {
+			text = fmt.Sprintf("%v", source)
+		}
+	}
+
+	base := resource.Slugify(text, config)
+	if base == "" {
+		base = defaultSlug
+	}
+	separator := config.Separator
+	if separator == "" {
+		separator = "-"
+	}
+
+	column := r.DB.NamingStrategy.ColumnName("", field)
+	tx := db.Unscoped().Model(r.Model).Where(column+" = ? OR "+column+" LIKE ?", base, base+separator+"%")
+	scopeKey := ""
+	for _, name := range config.Scope {
+		value, _ := schemaFieldValue(ctx, db, record, name)
+		tx = tx.Where(r.DB.NamingStrategy.ColumnName("", name)+" = ?", value)
+		scopeKey += fmt.Sprintf("%v\x00", value)
+	}
+	if excludeID != nil {
+		tx = tx.Where(r.DB.NamingStrategy.ColumnName("", r.GetIDFieldName())+" <> ?", excludeID)
+	}
+
+	var existing []string
+	if err := tx.Pluck(column, &existing).Error; err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(existing))
+	for _, slug := range existing {
+		used[slug] = true
+	}
+
+	slug := base
+	for n := 2; used[slug] || taken[scopeKey+slug]; n++ {
+		slug = fmt.Sprintf("%s%s%d", base, separator, n)
+	}
+	if taken != nil {
+		taken[scopeKey+slug] = true
+	}
+	return slug, nil
+}
+
+// lookUpSchemaField returns the schema field of a model for a field or column name
+func lookUpSchemaField(db *gorm.DB, record interface{}, name string) (*schema.Field, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(record); err != nil {
+		return nil, err
+	}
+	if field := stmt.Schema.LookUpField(name); field != nil {
+		return field, nil
+	}
+	if field := stmt.Schema.LookUpField(db.NamingStrategy.ColumnName("", name)); field != nil {
+		return field, nil
+	}
+	for _, field := range stmt.Schema.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, nil
+		}
+	}
+	return nil, fmt.Errorf("field %q not found in model", name)
+}
+
+// schemaFieldValue returns the value of a model field, with false when the field does
+// not exist or has its zero value
+func schemaFieldValue(ctx context.Context, db *gorm.DB, record interface{}, name string) (interface{}, bool) {
+	field, err := lookUpSchemaField(db, record, name)
+	if err != nil {
+		return nil, false
+	}
+	value, zero := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(record)))
+	return value, !zero
+}
+
+// setSchemaField sets a model field. Records not passed by pointer are left unchanged.
+func setSchemaField(ctx context.Context, db *gorm.DB, record interface{}, name string, value interface{}) error {
+	if reflect.ValueOf(record).Kind() != reflect.Ptr {
+		return nil
+	}
+	field, err := lookUpSchemaField(db, record, name)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, reflect.Indirect(reflect.ValueOf(record)), value)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type SlugPost struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Title      string `json:"title"`
+	Slug       string `json:"slug"`
+	CategoryID uint   `json:"categoryId"`
+	DeletedAt  gorm.DeletedAt
+}
+
+func setupSlugRepository(t *testing.T) (Repository, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&SlugPost{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "posts",
+		Model: SlugPost{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "slug", Type: "string", Slug: &resource.SlugConfig{Source: "title", Scope: []string{"categoryId"}}},
+			{Name: "categoryId", Type: "int"},
+		},
+	})
+	return NewGenericRepositoryWithResource(db, res), db
+}
+
+func createSlugPost(t *testing.T, repo Repository, post SlugPost) *SlugPost {
+	created, err := repo.Create(context.Background(), &post)
+	require.NoError(t, err)
+	return created.(*SlugPost)
+}
+
+func TestSlugGeneration(t *testing.T) {
+	repo, db := setupSlugRepository(t)
+	ctx := context.Background()
+
+	assert.Equal(t, "hello-world", createSlugPost(t, repo, SlugPost{Title: "Hello World", CategoryID: 1}).Slug)
+	assert.Equal(t, "hello-world-2", createSlugPost(t, repo, SlugPost{Title: "Hello, world!", CategoryID: 1}).Slug)
+
+	// Slugs are unique within the scope
+	assert.Equal(t, "hello-world", createSlugPost(t, repo, SlugPost{Title: "Hello World", CategoryID: 2}).Slug)
+
+	// Client slugs are normalized, empty sources get a placeholder
+	assert.Equal(t, "my-post", createSlugPost(t, repo, SlugPost{Title: "x", Slug: "My Post", CategoryID: 1}).Slug)
+	assert.Equal(t, "untitled", createSlugPost(t, repo, SlugPost{CategoryID: 1}).Slug)
+
+	// Soft-deleted records keep their slug
+	require.NoError(t, repo.Delete(ctx, 1))
+	assert.Equal(t, "hello-world-3", createSlugPost(t, repo, SlugPost{Title: "Hello World", CategoryID: 1}).Slug)
+
+	// Batches do not collide with themselves
+	posts := []SlugPost{{Title: "Batch", CategoryID: 1}, {Title: "Batch", CategoryID: 1}}
+	_, err := repo.CreateMany(ctx, &posts)
+	require.NoError(t, err)
+	assert.Equal(t, "batch", posts[0].Slug)
+	assert.Equal(t, "batch-2", posts[1].Slug)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&SlugPost{}).Count(&count).Error)
+	assert.Equal(t, int64(8), count)
+}
+
+func TestSlugStableOnUpdate(t *testing.T) {
+	repo, _ := setupSlugRepository(t)
+	ctx := context.Background()
+	post := createSlugPost(t, repo, SlugPost{Title: "Original", CategoryID: 1})
+	createSlugPost(t, repo, SlugPost{Title: "Renamed", CategoryID: 1})
+
+	updated, err := repo.Update(ctx, post.ID, map[string]interface{}{"title": "Renamed", "slug": "hijacked"})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.(*SlugPost).Title)
+	assert.Equal(t, "original", updated.(*SlugPost).Slug)
+
+	slugRepo := repo.(SlugRepository)
+	regenerated, err := slugRepo.RegenerateSlug(ctx, post.ID, "slug")
+	require.NoError(t, err)
+	assert.Equal(t, "renamed-2", regenerated.(*SlugPost).Slug)
+
+	// Regenerating again keeps the record's own slug
+	regenerated, err = slugRepo.RegenerateSlug(ctx, post.ID, "slug")
+	require.NoError(t, err)
+	assert.Equal(t, "renamed-2", regenerated.(*SlugPost).Slug)
+
+	_, err = slugRepo.RegenerateSlug(ctx, post.ID, "title")
+	assert.Error(t, err)
+}
+
+func TestGetBySlug(t *testing.T) {
+	repo, _ := setupSlugRepository(t)
+	ctx := context.Background()
+	createSlugPost(t, repo, SlugPost{Title: "News", CategoryID: 1})
+	createSlugPost(t, repo, SlugPost{Title: "News", CategoryID: 2})
+
+	slugRepo := repo.(SlugRepository)
+	found, err := slugRepo.GetBySlug(ctx, "slug", "news", map[string]interface{}{"categoryId": 2})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), found.(*SlugPost).ID)
+
+	_, err = slugRepo.GetBySlug(ctx, "slug", "missing", nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrSoftDeleteNotSupported is returned by trash operations on models without a soft delete field
+var ErrSoftDeleteNotSupported = errors.New("resource does not support soft delete")
+
+// SoftDeleteRepository is implemented by repositories that can move records to a trash
+// instead of deleting them
+type SoftDeleteRepository interface {
+	// SoftDeletes reports whether Delete moves records to the trash
+	SoftDeletes() bool
+
+	// ListTrashed returns a paginated list of soft-deleted records
+	ListTrashed(ctx context.Context, options query.QueryOptions) (interface{}, int64, error)
+
+	// Restore brings a soft-deleted record back, returning gorm.ErrRecordNotFound
+	// unless the record is in the trash
+	Restore(ctx context.Context, id interface{}) (interface{}, error)
+
+	// ForceDelete permanently deletes a record, trashed or not
+	ForceDelete(ctx context.Context, id interface{}) error
+}
+
+// softDeleteColumn describes the column marking soft-deleted records
+type softDeleteColumn struct {
+	Name string
+
+	// Native is set for gorm.DeletedAt fields, which GORM scopes by itself
+	Native bool
+}
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// softDeleteColumn returns the soft delete column of the model, or nil when records
+// are deleted permanently
+func (r *GenericRepository) softDeleteColumn() *softDeleteColumn {
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(r.Model); err != nil {
+		return nil
+	}
+
+	configured := ""
+	if res, ok := r.Resource.(resource.SoftDeleteResource); ok {
+		configured = res.GetSoftDeleteField()
+	}
+
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if configured == "" {
+			if field.FieldType == deletedAtType {
+				return &softDeleteColumn{Name: field.DBName, Native: true}
+			}
+			continue
+		}
+		if matchesField(field, configured) {
+			return &softDeleteColumn{Name: field.DBName, Native: field.FieldType == deletedAtType}
+		}
+	}
+	return nil
+}
+
+// matchesField reports whether a schema field is the resource field name (JSON, Go or column name)
+func matchesField(field *schema.Field, name string) bool {
+	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+	return strings.EqualFold(jsonName, name) || strings.EqualFold(field.Name, name) || field.DBName == name
+}
+
+// scoped returns a session excluding soft-deleted records and records outside the
+// scopes of the context (see WithScope). GORM excludes soft-deleted records for
+// gorm.DeletedAt fields, other soft delete fields are filtered explicitly.
+func (r *GenericRepository) scoped(ctx context.Context) *gorm.DB {
+	tx := r.untrashed(r.DB.WithContext(ctx))
+	for _, condition := range r.scopeConditions(ctx) {
+		tx = tx.Where(condition)
+	}
+	return tx
+}
+
+// untrashed excludes soft-deleted records from a query. GORM excludes them by itself
+// for gorm.DeletedAt fields.
+func (r *GenericRepository) untrashed(tx *gorm.DB) *gorm.DB {
+	if column := r.softDeleteColumn(); column != nil && !column.Native {
+		return tx.Where(column.Name + " IS NULL")
+	}
+	return tx
+}
+
+// idColumn returns the database column of the ID field
+func (r *GenericRepository) idColumn() string {
+	idFieldName := "id"
+	if r.Resource != nil {
+		idFieldName = r.Resource.GetIDFieldName()
+	}
+	return r.DB.NamingStrategy.ColumnName("", idFieldName)
+}
+
+// newModel returns a pointer to a new model instance; GORM needs one to soft delete
+func (r *GenericRepository) newModel() interface{} {
+	modelType := reflect.TypeOf(r.Model)
+	if modelType == nil {
+		return r.Model
+	}
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	return reflect.New(modelType).Interface()
+}
+
+// SoftDeletes reports whether the model has a soft delete field
+func (r *GenericRepository) SoftDeletes() bool {
+	return r.softDeleteColumn() != nil
+}
+
+// ListTrashed returns a paginated list of soft-deleted records
+func (r *GenericRepository) ListTrashed(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	column := r.softDeleteColumn()
+	if column == nil {
+		return nil, 0, ErrSoftDeleteNotSupported
+	}
+
+	elemType := reflect.TypeOf(r.Model)
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	result := reflect.New(reflect.SliceOf(elemType)).Interface()
+
+	tx := r.DB.WithContext(ctx).Unscoped().Where(column.Name + " IS NOT NULL")
+	tx = options.Apply(tx)
+
+	var total int64
+	if err := tx.Model(r.Model).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if !options.DisablePagination {
+		tx = tx.Offset((options.Page - 1) * options.PerPage).Limit(options.PerPage)
+	}
+	if options.Sort == "" {
+		// Most recently deleted first
+		tx = tx.Order(column.Name + " DESC")
+	}
+
+	// Stable order across pages
+	tx = options.ApplySortTiebreaker(tx)
+
+	if err := tx.Find(result).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadFields(ctx, result); err != nil {
+		return nil, 0, err
+	}
+	return result, total, nil
+}
+
+// Restore clears the soft delete marker of a trashed record and returns it
+func (r *GenericRepository) Restore(ctx context.Context, id interface{}) (interface{}, error) {
+	column := r.softDeleteColumn()
+	if column == nil {
+		return nil, ErrSoftDeleteNotSupported
+	}
+
+	result := r.DB.WithContext(ctx).Unscoped().Model(r.Model).
+		Where(r.idColumn()+" = ? AND "+column.Name+" IS NOT NULL", id).
+		Update(column.Name, nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+// ForceDelete permanently deletes a record, trashed or not
+func (r *GenericRepository) ForceDelete(ctx context.Context, id interface{}) error {
+	result := r.DB.WithContext(ctx).Unscoped().Where(r.idColumn()+" = ?", id).Delete(r.newModel())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// trash marks the records matched by the query as deleted when the model uses a
+// non-native soft delete field. It returns false when the caller should delete.
+func (r *GenericRepository) trash(tx *gorm.DB) (int64, bool, error) {
+	column := r.softDeleteColumn()
+	if column == nil || column.Native {
+		return 0, false, nil
+	}
+	result := tx.Model(r.Model).Where(column.Name+" IS NULL").Update(column.Name, time.Now())
+	return result.RowsAffected, true, result.Error
+}
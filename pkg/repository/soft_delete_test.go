@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TrashedPost struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Title     string         `json:"title"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+type ArchivedPost struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Title     string     `json:"title"`
+	RemovedAt *time.Time `json:"removed_at"`
+}
+
+func TestSoftDeleteRepository(t *testing.T) {
+	cases := []struct {
+		name   string
+		config resource.ResourceConfig
+	}{
+		{"gorm.DeletedAt", resource.ResourceConfig{Name: "posts", Model: TrashedPost{}}},
+		{"configured field", resource.ResourceConfig{Name: "posts", Model: ArchivedPost{}, SoftDeleteField: "removed_at"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+			require.NoError(t, err)
+			res := resource.NewResource(tc.config)
+			require.NoError(t, db.AutoMigrate(res.GetModel()))
+
+			repo := NewGenericRepositoryWithResource(db, res).(*GenericRepository)
+			require.True(t, repo.SoftDeletes())
+			ctx := context.Background()
+			for _, title := range []string{"a", "b", "c"} {
+				require.NoError(t, db.Model(res.GetModel()).Create(map[string]interface{}{"title": title}).Error)
+			}
+			opts := query.QueryOptions{Resource: res, Page: 1, PerPage: 10}
+
+			require.NoError(t, repo.Delete(ctx, "1"))
+			deleted, err := repo.DeleteMany(ctx, []interface{}{2})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), deleted)
+
+			// Trashed records are hidden from regular queries
+			_, total, err := repo.List(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			count, err := repo.Count(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+			_, err = repo.Get(ctx, 1)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+			_, total, err = repo.ListTrashed(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), total)
+
+			restored, err := repo.Restore(ctx, "1")
+			require.NoError(t, err)
+			assert.NotNil(t, restored)
+			_, err = repo.Restore(ctx, "1")
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			_, err = repo.Get(ctx, 1)
+			assert.NoError(t, err)
+
+			require.NoError(t, repo.ForceDelete(ctx, "2"))
+			assert.ErrorIs(t, repo.ForceDelete(ctx, "2"), gorm.ErrRecordNotFound)
+			_, total, err = repo.ListTrashed(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), total)
+		})
+	}
+
+	repo := NewGenericRepository(nil, ConformanceRecord{}).(*GenericRepository)
+	repo.DB, _ = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.False(t, repo.SoftDeletes())
+	_, err := repo.Restore(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrSoftDeleteNotSupported)
+}
+
+type OwnedArchivedPost struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Title     string     `json:"title"`
+	OwnerID   string     `json:"ownerId"`
+	RemovedAt *time.Time `json:"removed_at"`
+}
+
+func TestOwnerRepositorySoftDelete(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OwnedArchivedPost{}))
+	require.NoError(t, db.Create([]*OwnedArchivedPost{
+		{Title: "first", OwnerID: "ann"},
+		{Title: "second", OwnerID: "ann"},
+		{Title: "third", OwnerID: "ann"},
+	}).Error)
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name: "posts", Model: OwnedArchivedPost{}, SoftDeleteField: "removed_at",
+	}), resource.DefaultOwnerConfig())
+	repo, err := NewOwnerRepository(db, res)
+	require.NoError(t, err)
+	trash, ok := repo.(SoftDeleteRepository)
+	require.True(t, ok)
+	assert.True(t, trash.SoftDeletes())
+
+	ctx := requestctx.OwnerID.With(context.Background(), "ann")
+	require.NoError(t, repo.Delete(ctx, uint(1)))
+	deleted, err := repo.DeleteMany(ctx, []interface{}{uint(2)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// The records are kept in the trash
+	var count int64
+	require.NoError(t, db.Model(&OwnedArchivedPost{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	_, err = repo.Get(ctx, uint(1))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, uint(1)), gorm.ErrRecordNotFound)
+	records, total, err := repo.List(ctx, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, *records.(*[]OwnedArchivedPost), 1)
+}
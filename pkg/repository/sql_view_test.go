@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ViewCustomer struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type ViewOrder struct {
+	ID             uint `gorm:"primaryKey"`
+	ViewCustomerID uint
+	Total          float64
+}
+
+type CustomerRevenue struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	Name       string  `json:"name"`
+	OrderCount int     `json:"orderCount"`
+	Revenue    float64 `json:"revenue"`
+}
+
+func TestSQLViewRepository(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ViewCustomer{}, &ViewOrder{}))
+	require.NoError(t, db.Create([]*ViewCustomer{{Name: "Acme"}, {Name: "Globex"}, {Name: "Initech"}}).Error)
+	require.NoError(t, db.Create([]*ViewOrder{
+		{ViewCustomerID: 1, Total: 10},
+		{ViewCustomerID: 1, Total: 30},
+		{ViewCustomerID: 2, Total: 5},
+	}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:       "customer_revenues",
+		Model:      CustomerRevenue{},
+		Operations: SQLViewOperations,
+	})
+	repo := NewSQLViewRepository(db, `
+		SELECT view_customers.id, view_customers.name, COUNT(view_orders.id) AS order_count,
+			COALESCE(SUM(view_orders.total), 0) AS revenue
+		FROM view_customers LEFT JOIN view_orders ON view_orders.view_customer_id = view_customers.id
+		WHERE view_customers.name <> ?
+		GROUP BY view_customers.id, view_customers.name`, res, "Initech")
+	ctx := context.Background()
+
+	// Filters and sorts apply to the projected columns
+	options := query.QueryOptions{
+		Resource:        res,
+		Page:            1,
+		PerPage:         10,
+		Sort:            "revenue",
+		Order:           "desc",
+		AdvancedFilters: []query.Filter{{Field: "order_count", Operator: "gte", Value: 1}},
+	}
+	data, total, err := repo.List(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	rows := *data.(*[]CustomerRevenue)
+	require.Len(t, rows, 2)
+	assert.Equal(t, CustomerRevenue{ID: 1, Name: "Acme", OrderCount: 2, Revenue: 40}, rows[0])
+	assert.Equal(t, "Globex", rows[1].Name)
+
+	// Conditions don't leak between requests
+	count, err := repo.Count(ctx, query.QueryOptions{Resource: res})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	row, err := repo.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Globex", row.(*CustomerRevenue).Name)
+
+	// Rows are read-only
+	_, err = repo.Create(ctx, &CustomerRevenue{Name: "New"})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, repo.Delete(ctx, 1), ErrNotSupported)
+}
@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// ErrTenantIDNotFound is returned by TenantRepository for contexts without a tenant
+var ErrTenantIDNotFound = errors.New("tenant ID not found in context")
+
+// TenantResolver returns the repository holding the records of a tenant
+type TenantResolver func(ctx context.Context, tenantID string) (Repository, error)
+
+// TenantRepository decorates a repository for multi-tenant applications. Every
+// operation requires the tenant of the context (see middleware.TenantContext) and:
+//
+//   - with Field set, reads are scoped to records of the tenant with WithScope,
+//     created records get the tenant in Field and updates cannot change it;
+//   - with Resolve set, operations run on the tenant's own repository, e.g. on its
+//     database schema (TenantSchemaResolver) or connection.
+//
+// Scoping by Field relies on the decorated repository honouring WithScope, as
+// GenericRepository does.
+type TenantRepository struct {
+	Repository
+
+	// Field holding the tenant ID (Go, JSON or column name)
+	Field string
+
+	// Resolve returns the repository of a tenant (optional, the decorated repository
+	// is used without it)
+	Resolve TenantResolver
+
+	// Relations preloaded by repositories returned by Resolve
+	relations []string
+}
+
+// NewTenantRepository decorates a repository scoping its records by a tenant field
+func NewTenantRepository(repo Repository, field string) *TenantRepository {
+	return &TenantRepository{Repository: repo, Field: field}
+}
+
+// ForTenant returns the repository and context the operations of the context's tenant
+// run with
+func (r *TenantRepository) ForTenant(ctx context.Context) (Repository, context.Context, error) {
+	tenantID, err := middleware.GetTenantID(ctx)
+	if err != nil {
+		return nil, ctx, ErrTenantIDNotFound
+	}
+
+	repo := r.Repository
+	if r.Resolve != nil {
+		if repo, err = r.Resolve(ctx, tenantID); err != nil {
+			return nil, ctx, err
+		}
+		if len(r.relations) > 0 {
+			repo = repo.WithRelations(r.relations...)
+		}
+	}
+	if r.Field != "" {
+		ctx = WithScope(ctx, r.Field, r.tenantValue(repo, tenantID))
+	}
+	return repo, ctx, nil
+}
+
+// tenantValue converts the tenant ID to the type of the tenant field of generic
+// repositories, so numeric tenant columns are compared with numbers
+func (r *TenantRepository) tenantValue(repo Repository, tenantID string) interface{} {
+	var model interface{}
+	switch generic := repo.(type) {
+	case *GenericRepository:
+		model = generic.Model
+	case *OwnerGenericRepository:
+		model = generic.Model
+	}
+	if model == nil {
+		return tenantID
+	}
+	field, ok := tenantStructField(reflect.TypeOf(model), r.Field)
+	if !ok {
+		return tenantID
+	}
+	if converted, err := convertToFieldType(tenantID, field.Type); err == nil {
+		return converted
+	}
+	return tenantID
+}
+
+// setTenant sets the tenant field of new records
+func (r *TenantRepository) setTenant(ctx context.Context, data interface{}) error {
+	if r.Field == "" {
+		return nil
+	}
+	tenantID, err := middleware.GetTenantID(ctx)
+	if err != nil {
+		return ErrTenantIDNotFound
+	}
+	return r.assignTenant(data, tenantID, true)
+}
+
+// protectTenant removes the tenant field from updates, so records cannot be moved to
+// another tenant. Struct updates replace whole records, so they get the tenant set.
+func (r *TenantRepository) protectTenant(ctx context.Context, data interface{}) error {
+	if r.Field == "" {
+		return nil
+	}
+	tenantID, err := middleware.GetTenantID(ctx)
+	if err != nil {
+		return ErrTenantIDNotFound
+	}
+	return r.assignTenant(data, tenantID, false)
+}
+
+// assignTenant sets the tenant field of records, slices of records and, with
+// setMaps, maps. Without setMaps the tenant keys of maps are removed.
+func (r *TenantRepository) assignTenant(data interface{}, tenantID string, setMaps bool) error {
+	switch values := data.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key := range values {
+			if tenantKeyMatches(key, r.Field) {
+				delete(values, key)
+			}
+		}
+		if setMaps {
+			values[r.Field] = tenantID
+		}
+		return nil
+	case []interface{}:
+		for _, item := range values {
+			if err := r.assignTenant(item, tenantID, setMaps); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			if err := r.assignTenant(item.Interface(), tenantID, setMaps); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		field, ok := tenantStructField(value.Type(), r.Field)
+		if !ok {
+			return fmt.Errorf("tenant field '%s' not found in record", r.Field)
+		}
+		target := value.FieldByIndex(field.Index)
+		if !target.CanSet() {
+			return nil
+		}
+		converted, err := convertToFieldType(tenantID, target.Type())
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(converted))
+	}
+	return nil
+}
+
+// tenantStructField returns the struct field matching a Go, JSON or column name
+func tenantStructField(modelType reflect.Type, name string) (reflect.StructField, bool) {
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tenantKeyMatches(field.Name, name) || (jsonName != "" && tenantKeyMatches(jsonName, name)) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tenantKeyMatches compares field names across naming conventions
+func tenantKeyMatches(key, field string) bool {
+	normalize := strings.NewReplacer("_", "", "-", "")
+	return strings.EqualFold(normalize.Replace(key), normalize.Replace(field))
+}
+
+// Get returns a record of the tenant
+func (r *TenantRepository) Get(ctx context.Context, id interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Get(ctx, id)
+}
+
+// List returns records of the tenant
+func (r *TenantRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return repo.List(ctx, options)
+}
+
+// Create creates a record of the tenant
+func (r *TenantRepository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setTenant(ctx, data); err != nil {
+		return nil, err
+	}
+	return repo.Create(ctx, data)
+}
+
+// Update updates a record of the tenant
+func (r *TenantRepository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.protectTenant(ctx, data); err != nil {
+		return nil, err
+	}
+	return repo.Update(ctx, id, data)
+}
+
+// Delete deletes a record of the tenant
+func (r *TenantRepository) Delete(ctx context.Context, id interface{}) error {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return err
+	}
+	return repo.Delete(ctx, id)
+}
+
+// Count counts records of the tenant
+func (r *TenantRepository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return repo.Count(ctx, options)
+}
+
+// CreateMany creates records of the tenant
+func (r *TenantRepository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setTenant(ctx, data); err != nil {
+		return nil, err
+	}
+	return repo.CreateMany(ctx, data)
+}
+
+// CreateManyPartial creates records of the tenant if the decorated repository
+// supports partial bulk creates
+func (r *TenantRepository) CreateManyPartial(ctx context.Context, items []interface{}) ([]BulkItemResult, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creator, ok := repo.(PartialBulkCreator)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if err := r.setTenant(ctx, items); err != nil {
+		return nil, err
+	}
+	return creator.CreateManyPartial(ctx, items)
+}
+
+// UpdateMany updates records of the tenant
+func (r *TenantRepository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.protectTenant(ctx, data); err != nil {
+		return 0, err
+	}
+	return repo.UpdateMany(ctx, ids, data)
+}
+
+// DeleteMany deletes records of the tenant
+func (r *TenantRepository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return repo.DeleteMany(ctx, ids)
+}
+
+// Facets returns value counts of the tenant's records if the decorated repository
+// supports facets
+func (r *TenantRepository) Facets(ctx context.Context, options query.QueryOptions, fields []string) (FacetCounts, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := repo.(FacetProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return provider.Facets(ctx, options, fields)
+}
+
+// Aggregate computes metrics over the tenant's records if the decorated repository
+// supports aggregations
+func (r *TenantRepository) Aggregate(ctx context.Context, options query.QueryOptions, spec AggregateSpec) ([]AggregateRow, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := repo.(AggregateProvider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return provider.Aggregate(ctx, options, spec)
+}
+
+// WithRelations returns a tenant repository preloading relations
+func (r *TenantRepository) WithRelations(relations ...string) Repository {
+	tenant := *r
+	tenant.Repository = r.Repository.WithRelations(relations...)
+	tenant.relations = append(append([]string(nil), r.relations...), relations...)
+	return &tenant
+}
+
+// GetWithRelations returns a record of the tenant with related entities
+func (r *TenantRepository) GetWithRelations(ctx context.Context, id interface{}, relations []string) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetWithRelations(ctx, id, relations)
+}
+
+// ListWithRelations returns records of the tenant with related entities
+func (r *TenantRepository) ListWithRelations(ctx context.Context, options query.QueryOptions, relations []string) (interface{}, int64, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return repo.ListWithRelations(ctx, options, relations)
+}
+
+// Query returns a query builder scoped to the tenant. Without a tenant in the context
+// the query fails with ErrTenantIDNotFound.
+func (r *TenantRepository) Query(ctx context.Context) *gorm.DB {
+	repo, tenantCtx, err := r.ForTenant(ctx)
+	if err != nil {
+		db := r.Repository.Query(ctx)
+		if db != nil {
+			db.AddError(err)
+		}
+		return db
+	}
+	return repo.Query(tenantCtx)
+}
+
+// FindOneBy finds the first record of the tenant matching the condition
+func (r *TenantRepository) FindOneBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.FindOneBy(ctx, condition)
+}
+
+// FindAllBy finds the records of the tenant matching the condition
+func (r *TenantRepository) FindAllBy(ctx context.Context, condition map[string]interface{}) (interface{}, error) {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return repo.FindAllBy(ctx, condition)
+}
+
+// WithTransaction runs fn with a tenant repository on the transaction of the decorated
+// repository. Repositories returned by Resolve are not known without a context, so
+// with Resolve set start transactions on the repository returned by ForTenant.
+func (r *TenantRepository) WithTransaction(fn func(Repository) error) error {
+	return r.Repository.WithTransaction(func(tx Repository) error {
+		tenant := *r
+		tenant.Repository = tx
+		tenant.Resolve = nil
+		return fn(&tenant)
+	})
+}
+
+// BulkCreate creates records of the tenant
+func (r *TenantRepository) BulkCreate(ctx context.Context, data interface{}) error {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.setTenant(ctx, data); err != nil {
+		return err
+	}
+	return repo.BulkCreate(ctx, data)
+}
+
+// BulkUpdate updates records of the tenant matching the condition
+func (r *TenantRepository) BulkUpdate(ctx context.Context, condition map[string]interface{}, updates map[string]interface{}) error {
+	repo, ctx, err := r.ForTenant(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.protectTenant(ctx, updates); err != nil {
+		return err
+	}
+	return repo.BulkUpdate(ctx, condition, updates)
+}
+
+// schemaNamePattern restricts the schema names built from tenant IDs
+var schemaNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// TenantSchemaResolver returns a TenantResolver giving each tenant a GenericRepository
+// on the resource table in its own database schema (PostgreSQL schemas, MySQL
+// databases, attached SQLite databases), named by schemaName. Without schemaName the
+// tenant ID is the schema name. Schema names may only hold letters, digits and
+// underscores. Repositories are cheap and created on every call, so no state is kept
+// per tenant however many tenant IDs are seen.
+func TenantSchemaResolver(db *gorm.DB, res resource.Resource, schemaName func(tenantID string) string) TenantResolver {
+	return func(ctx context.Context, tenantID string) (Repository, error) {
+		schema := tenantID
+		if schemaName != nil {
+			schema = schemaName(tenantID)
+		}
+		if !schemaNamePattern.MatchString(schema) {
+			return nil, fmt.Errorf("invalid schema name %q for tenant %q", schema, tenantID)
+		}
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(res.GetModel()); err != nil {
+			return nil, err
+		}
+
+		return &GenericRepository{
+			DB:       db.Table(schema + "." + stmt.Schema.Table).Session(&gorm.Session{}),
+			Model:    res.GetModel(),
+			Resource: res,
+		}, nil
+	}
+}
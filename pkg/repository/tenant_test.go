@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TenantProject struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+}
+
+func TestTenantRepository(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TenantProject{}))
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "projects", Model: TenantProject{}})
+	repo := NewTenantRepository(NewGenericRepositoryWithResource(db, res), "TenantID")
+	acme := requestctx.TenantID.With(context.Background(), "acme")
+	globex := requestctx.TenantID.With(context.Background(), "globex")
+
+	// Created records belong to the tenant of the context, whatever the client sent
+	_, err = repo.Create(acme, &TenantProject{Name: "rocket", TenantID: "globex"})
+	require.NoError(t, err)
+	_, err = repo.CreateMany(acme, &[]TenantProject{{Name: "anvil"}})
+	require.NoError(t, err)
+	_, err = repo.Create(globex, &TenantProject{Name: "merger"})
+	require.NoError(t, err)
+
+	var stored []TenantProject
+	require.NoError(t, db.Order("id").Find(&stored).Error)
+	assert.Equal(t, []string{"acme", "acme", "globex"}, []string{stored[0].TenantID, stored[1].TenantID, stored[2].TenantID})
+
+	// Reads only see the records of the tenant
+	_, total, err := repo.List(acme, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	count, err := repo.Count(globex, query.QueryOptions{Resource: res})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	_, err = repo.Get(globex, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Updates cannot reach or move records of other tenants
+	_, err = repo.Update(globex, 1, map[string]interface{}{"name": "stolen"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	updated, err := repo.Update(acme, 1, map[string]interface{}{"name": "rocket 2", "tenant_id": "globex"})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", updated.(*TenantProject).TenantID)
+	assert.Equal(t, "rocket 2", updated.(*TenantProject).Name)
+
+	affected, err := repo.DeleteMany(globex, []interface{}{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	// Contexts without a tenant are rejected
+	_, _, err = repo.List(context.Background(), query.QueryOptions{Resource: res})
+	assert.ErrorIs(t, err, ErrTenantIDNotFound)
+	_, err = repo.Create(context.Background(), &TenantProject{Name: "orphan"})
+	assert.ErrorIs(t, err, ErrTenantIDNotFound)
+	assert.ErrorIs(t, repo.Query(context.Background()).Find(&stored).Error, ErrTenantIDNotFound)
+}
+
+func TestTenantSchemaResolver(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	for _, schema := range []string{"tenant_acme", "tenant_globex"} {
+		require.NoError(t, db.Exec("ATTACH DATABASE ':memory:' AS "+schema).Error)
+		require.NoError(t, db.Exec("CREATE TABLE "+schema+".tenant_projects (id integer PRIMARY KEY, tenant_id text, name text)").Error)
+	}
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "projects", Model: TenantProject{}})
+	repo := &TenantRepository{
+		Repository: NewGenericRepositoryWithResource(db, res),
+		Resolve: TenantSchemaResolver(db, res, func(tenantID string) string {
+			return "tenant_" + tenantID
+		}),
+	}
+	acme := requestctx.TenantID.With(context.Background(), "acme")
+	globex := requestctx.TenantID.With(context.Background(), "globex")
+
+	_, err = repo.Create(acme, &TenantProject{Name: "rocket"})
+	require.NoError(t, err)
+	_, err = repo.Create(acme, &TenantProject{Name: "anvil"})
+	require.NoError(t, err)
+
+	_, total, err := repo.List(acme, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	_, total, err = repo.List(globex, query.QueryOptions{Resource: res, Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	updated, err := repo.Update(acme, 2, map[string]interface{}{"name": "anvil 2"})
+	require.NoError(t, err)
+	assert.Equal(t, "anvil 2", updated.(*TenantProject).Name)
+
+	var names []string
+	require.NoError(t, db.Table("tenant_acme.tenant_projects").Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"rocket", "anvil 2"}, names)
+
+	_, err = repo.Get(requestctx.TenantID.With(context.Background(), "x; DROP TABLE"), 1)
+	assert.ErrorContains(t, err, "invalid schema name")
+}
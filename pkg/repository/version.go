@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrVersionConflict is returned when a record was modified since the version an update
+// was based on
+var ErrVersionConflict = errors.New("version conflict: the record was modified by another request")
+
+// VersionConflictError reports an optimistic locking conflict with the current version
+// of the record. It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	Current interface{}
+}
+
+func (e *VersionConflictError) Error() string {
+	return ErrVersionConflict.Error()
+}
+
+// Is reports whether target is ErrVersionConflict
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a context making GenericRepository.Update fail with a
+// VersionConflictError unless the record still has the version. Custom repositories
+// can read it with ExpectedVersionFromContext.
+func WithExpectedVersion(ctx context.Context, version interface{}) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// ExpectedVersionFromContext returns the version set with WithExpectedVersion
+func ExpectedVersionFromContext(ctx context.Context) (interface{}, bool) {
+	version := ctx.Value(expectedVersionKey{})
+	return version, version != nil
+}
+
+// VersionMatches reports whether a stored version equals a version sent by a client,
+// e.g. 3 and "3", or a time and its RFC 3339 representation
+func VersionMatches(stored, expected interface{}) bool {
+	stored = indirectValue(stored)
+	expected = indirectValue(expected)
+
+	if storedTime, ok := stored.(time.Time); ok {
+		switch v := expected.(type) {
+		case time.Time:
+			return storedTime.Equal(v)
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			return err == nil && storedTime.Equal(parsed)
+		}
+		return false
+	}
+	return versionString(stored) == versionString(expected)
+}
+
+// versionString formats a version for comparison
+func versionString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case string:
+		return strings.TrimSpace(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// indirectValue dereferences pointers, returning nil for nil pointers
+func indirectValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// versionField returns the schema field of the resource's version field, or nil
+func (r *GenericRepository) versionField() *schema.Field {
+	res, ok := r.Resource.(resource.VersionedResource)
+	if !ok || res.GetVersionField() == "" {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(r.Model); err != nil {
+		return nil
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" && matchesField(field, res.GetVersionField()) {
+			return field
+		}
+	}
+	return nil
+}
+
+// saveVersioned checks the expected version of the context against the existing
+// record, increments the version of updateData and saves it only if the stored version
+// did not change in the meantime
+func (r *GenericRepository) saveVersioned(ctx context.Context, field *schema.Field, id, existingRecord, updateData interface{}) error {
+	current, _ := field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(existingRecord)))
+	current = indirectValue(current)
+	if expected, ok := ExpectedVersionFromContext(ctx); ok && !VersionMatches(current, expected) {
+		return &VersionConflictError{Current: current}
+	}
+
+	next, err := r.nextVersion(field, current)
+	if err != nil {
+		return err
+	}
+	if err := field.Set(ctx, reflect.ValueOf(updateData).Elem(), next); err != nil {
+		return err
+	}
+
+	result := r.DB.WithContext(ctx).Model(updateData).
+		Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: current}).
+		Select("*").Updates(updateData)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		latest := current
+		if record, err := r.Get(ctx, id); err == nil {
+			latest, _ = field.ValueOf(ctx, reflect.Indirect(reflect.ValueOf(record)))
+			latest = indirectValue(latest)
+		}
+		return &VersionConflictError{Current: latest}
+	}
+	return nil
+}
+
+// nextVersion returns the version following current: the next number for integer
+// fields and the current time for time fields
+func (r *GenericRepository) nextVersion(field *schema.Field, current interface{}) (interface{}, error) {
+	fieldType := field.FieldType
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, _ := strconv.ParseInt(versionString(current), 10, 64)
+		return number + 1, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, _ := strconv.ParseUint(versionString(current), 10, 64)
+		return number + 1, nil
+	}
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		return r.DB.NowFunc(), nil
+	}
+	return nil, fmt.Errorf("version field %s must be an integer or a time, got %s", field.Name, fieldType)
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type VersionedDocument struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+func TestUpdateWithVersionField(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&VersionedDocument{}))
+	require.NoError(t, db.Create(&VersionedDocument{Title: "draft", Version: 1}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "documents", Model: VersionedDocument{}, VersionField: "version"})
+	repo := NewGenericRepositoryWithResource(db, res)
+
+	// Updates increment the version
+	updated, err := repo.Update(WithExpectedVersion(context.Background(), float64(1)), 1, map[string]interface{}{"title": "first"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated.(*VersionedDocument).Version)
+
+	// A stale version is rejected without saving
+	_, err = repo.Update(WithExpectedVersion(context.Background(), "1"), 1, map[string]interface{}{"title": "stale"})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 2, conflict.Current)
+
+	// Struct updates and updates without an expected version are versioned too
+	updated, err = repo.Update(context.Background(), 1, &VersionedDocument{Title: "second"})
+	require.NoError(t, err)
+	assert.Equal(t, "second", updated.(*VersionedDocument).Title)
+	assert.Equal(t, 3, updated.(*VersionedDocument).Version)
+
+	var stored VersionedDocument
+	require.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "second", stored.Title)
+	assert.Equal(t, 3, stored.Version)
+}
+
+func TestUpdateConcurrentVersionChange(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&VersionedDocument{}))
+	require.NoError(t, db.Create(&VersionedDocument{Title: "draft", Version: 1}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "documents", Model: VersionedDocument{}, VersionField: "Version"})
+	repo := NewGenericRepositoryWithResource(db, res).(*GenericRepository)
+	field := repo.versionField()
+	require.NotNil(t, field)
+
+	// Another request saves between reading and writing the record
+	existing, err := repo.Get(context.Background(), 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&VersionedDocument{}).Where("id = ?", 1).Update("version", 5).Error)
+
+	err = repo.saveVersioned(context.Background(), field, 1, existing, &VersionedDocument{ID: 1, Title: "lost"})
+	var conflict *VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 5, conflict.Current)
+
+	var stored VersionedDocument
+	require.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "draft", stored.Title)
+}
+
+func TestVersionMatches(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC)
+	assert.True(t, VersionMatches(3, float64(3)))
+	assert.True(t, VersionMatches(uint(3), "3"))
+	assert.False(t, VersionMatches(3, "4"))
+	assert.True(t, VersionMatches(now, now.Format(time.RFC3339Nano)))
+	assert.True(t, VersionMatches(&now, now.In(time.FixedZone("CET", 3600))))
+	assert.False(t, VersionMatches(now, "yesterday"))
+}
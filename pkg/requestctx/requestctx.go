@@ -0,0 +1,172 @@
+// Package requestctx defines the values refine-gin stores per request (owner, tenant,
+// roles, locale, request ID, resource, operation, selected fields, included relations)
+// as typed keys, so middleware, handlers and repositories agree on names and types
+// instead of casting ctx.Value("...") results.
+//
+// Values set on a gin context are visible both through the gin context (c.Get) and
+// through c.Request.Context(), which is what handlers pass to repositories:
+//
+//	requestctx.OwnerID.Set(c, "user-1")
+//	owner, ok := requestctx.OwnerID.Get(ctx) // in a repository
+package requestctx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// Key is a typed request value. Its name is also the gin context key, so values
+// remain readable with c.Get(name).
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a typed key stored under name in gin contexts
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+var (
+	// OwnerID is the owner of the records accessed by the request
+	OwnerID = NewKey[interface{}]("ownerID")
+
+	// OwnerGroupIDs are the groups (teams, organizations) of the owner
+	OwnerGroupIDs = NewKey[[]interface{}]("ownerGroupIDs")
+
+	// TenantID is the tenant the request belongs to
+	TenantID = NewKey[string]("tenantID")
+
+	// Roles are the roles of the authenticated user
+	Roles = NewKey[[]string]("userRoles")
+
+	// Locale is the preferred locale of the request
+	Locale = NewKey[string]("locale")
+
+	// RequestID identifies the request in logs and error responses
+	RequestID = NewKey[string]("requestID")
+
+	// Resource is the resource handling the request
+	Resource = NewKey[resource.Resource]("resource")
+
+	// Operation is the resource operation performed by the request
+	Operation = NewKey[resource.Operation]("operation")
+
+	// Fields are the fields selected by the request (a sparse fieldset, see
+	// query.ParseFields)
+	Fields = NewKey[[]string]("fields")
+
+	// Includes are the relations loaded with the requested record (see
+	// query.ParseIncludes)
+	Includes = NewKey[[]query.Include]("includes")
+
+	// Gin is the gin context of the request, for code that only receives
+	// c.Request.Context() but needs request details such as query parameters
+	Gin = NewKey[*gin.Context]("ginContext")
+)
+
+// Name returns the gin context key of the value
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// With returns a copy of ctx carrying the value
+func (k Key[T]) With(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k, value)
+}
+
+// Get returns the value from a gin context (its keys, then its request context) or
+// from a context.Context. The second result is false when the value is not set or
+// has another type.
+func (k Key[T]) Get(ctx context.Context) (T, bool) {
+	var zero T
+	if ctx == nil {
+		return zero, false
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if value, exists := c.Get(k.name); exists {
+			typed, ok := value.(T)
+			return typed, ok
+		}
+		if c.Request == nil {
+			return zero, false
+		}
+		ctx = c.Request.Context()
+	}
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+// MustGet returns the value, panicking with a descriptive message when it is not set
+func (k Key[T]) MustGet(ctx context.Context) T {
+	value, ok := k.Get(ctx)
+	if !ok {
+		panic(fmt.Sprintf("requestctx: %s is not set in the request context", k.name))
+	}
+	return value
+}
+
+// Set stores the value in the gin context and in its request context
+func (k Key[T]) Set(c *gin.Context, value T) {
+	c.Set(k.name, value)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(k.With(c.Request.Context(), value))
+	}
+}
+
+// Value pairs the key with a value for With and TestContext
+func (k Key[T]) Value(value T) Value {
+	return keyValue[T]{key: k, value: value}
+}
+
+// Value is a key with its value
+type Value interface {
+	apply(ctx context.Context) context.Context
+	set(c *gin.Context)
+}
+
+type keyValue[T any] struct {
+	key   Key[T]
+	value T
+}
+
+func (v keyValue[T]) apply(ctx context.Context) context.Context { return v.key.With(ctx, v.value) }
+func (v keyValue[T]) set(c *gin.Context)                        { v.key.Set(c, v.value) }
+
+// With returns a copy of ctx carrying values, e.g. for calling repositories in tests:
+//
+//	ctx := requestctx.With(context.Background(), requestctx.OwnerID.Value("user-1"))
+func With(ctx context.Context, values ...Value) context.Context {
+	for _, value := range values {
+		ctx = value.apply(ctx)
+	}
+	return ctx
+}
+
+// TestContext returns a gin context for a GET / request carrying values, for testing
+// handlers and middleware without a router
+func TestContext(values ...Value) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, value := range values {
+		value.set(c)
+	}
+	return c
+}
+
+// Middleware stores the resource and the gin context of each request, so
+// repositories can read them from the context they receive
+func Middleware(res resource.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if res != nil {
+			Resource.Set(c, res)
+		}
+		Gin.Set(c, c)
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package requestctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type testModel struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestKeyGetFromContext(t *testing.T) {
+	ctx := TenantID.With(context.Background(), "acme")
+
+	tenant, ok := TenantID.Get(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+
+	_, ok = Locale.Get(ctx)
+	assert.False(t, ok)
+
+	_, ok = Locale.Get(nil)
+	assert.False(t, ok)
+}
+
+func TestKeysDoNotCollideWithStringKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "tenantID", "legacy")
+
+	_, ok := TenantID.Get(ctx)
+	assert.False(t, ok)
+}
+
+func TestKeySetOnGinContext(t *testing.T) {
+	c := TestContext()
+	Roles.Set(c, []string{"admin"})
+
+	// Readable through the gin context, its keys and its request context
+	roles, ok := Roles.Get(c)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	raw, exists := c.Get("userRoles")
+	assert.True(t, exists)
+	assert.Equal(t, []string{"admin"}, raw)
+
+	roles, ok = Roles.Get(c.Request.Context())
+	assert.True(t, ok)
+	assert.Equal(t, []string{"admin"}, roles)
+}
+
+func TestKeyGetWithWrongType(t *testing.T) {
+	c := TestContext()
+	c.Set("locale", 42)
+
+	_, ok := Locale.Get(c)
+	assert.False(t, ok)
+}
+
+func TestKeyGetFromGinContextWithoutRequest(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, ok := RequestID.Get(c)
+	assert.False(t, ok)
+}
+
+func TestMustGet(t *testing.T) {
+	ctx := With(context.Background(), OwnerID.Value("user-1"), Locale.Value("pl"))
+
+	assert.Equal(t, "user-1", OwnerID.MustGet(ctx))
+	assert.Equal(t, "pl", Locale.MustGet(ctx))
+	assert.PanicsWithValue(t, "requestctx: tenantID is not set in the request context", func() {
+		TenantID.MustGet(ctx)
+	})
+}
+
+func TestTestContext(t *testing.T) {
+	c := TestContext(TenantID.Value("acme"), RequestID.Value("req-1"))
+
+	assert.Equal(t, "acme", TenantID.MustGet(c.Request.Context()))
+	assert.Equal(t, "req-1", RequestID.MustGet(c))
+	assert.Equal(t, "tenantID", TenantID.Name())
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	res := resource.NewResource(resource.ResourceConfig{Name: "items", Model: testModel{}})
+
+	var fromResource resource.Resource
+	var fromGin *gin.Context
+	router := gin.New()
+	router.GET("/items", Middleware(res), func(c *gin.Context) {
+		// Repositories only receive the request context
+		ctx := c.Request.Context()
+		fromResource, _ = Resource.Get(ctx)
+		fromGin, _ = Gin.Get(ctx)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?include=x", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, res, fromResource)
+	if assert.NotNil(t, fromGin) {
+		assert.Equal(t, "x", fromGin.Query("include"))
+	}
+}
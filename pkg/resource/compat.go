@@ -0,0 +1,296 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SchemaSnapshot is the API contract of a set of resources as exposed to clients. It is
+// committed as a baseline file and compared in CI to catch breaking changes.
+type SchemaSnapshot struct {
+	Resources map[string]ResourceSnapshot `json:"resources"`
+}
+
+// ResourceSnapshot is the API contract of a single resource
+type ResourceSnapshot struct {
+	IDField    string                   `json:"idField"`
+	Operations []Operation              `json:"operations"`
+	Fields     map[string]FieldSnapshot `json:"fields"`
+	Relations  map[string]string        `json:"relations,omitempty"`
+	Filterable []string                 `json:"filterable,omitempty"`
+	Sortable   []string                 `json:"sortable,omitempty"`
+}
+
+// FieldSnapshot is the API contract of a single field
+type FieldSnapshot struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+// SchemaChange describes a difference between a baseline and the current schema
+type SchemaChange struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+
+	// Breaking is set for changes that can break existing clients
+	Breaking bool `json:"breaking"`
+}
+
+// String formats the change for test and CI output
+func (c SchemaChange) String() string {
+	kind := "additive"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	target := c.Resource
+	if c.Field != "" {
+		target += "." + c.Field
+	}
+	return fmt.Sprintf("%s: %s (%s)", target, c.Message, kind)
+}
+
+// IncompatibleSchemaError is returned when the current schema breaks the baseline
+type IncompatibleSchemaError struct {
+	Changes []SchemaChange
+}
+
+func (e *IncompatibleSchemaError) Error() string {
+	lines := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		lines[i] = "  " + change.String()
+	}
+	return fmt.Sprintf("schema has %d breaking change(s):\n%s", len(e.Changes), strings.Join(lines, "\n"))
+}
+
+// Snapshot captures the API contract of resources. Aliased fields are recorded under
+// their aliases, as clients see them.
+func Snapshot(resources ...Resource) SchemaSnapshot {
+	snapshot := SchemaSnapshot{Resources: make(map[string]ResourceSnapshot, len(resources))}
+	for _, res := range resources {
+		metadata := GenerateResourceMetadata(res)
+
+		required := make(map[string]bool)
+		for _, name := range metadata.RequiredFields {
+			required[name] = true
+		}
+
+		resourceSnapshot := ResourceSnapshot{
+			IDField:    metadata.IDFieldName,
+			Operations: append([]Operation{}, metadata.Operations...),
+			Fields:     make(map[string]FieldSnapshot, len(metadata.Fields)),
+			Filterable: sortedCopy(metadata.FilterableFields),
+			Sortable:   sortedCopy(metadata.SortableFields),
+		}
+		sort.Slice(resourceSnapshot.Operations, func(i, j int) bool {
+			return resourceSnapshot.Operations[i] < resourceSnapshot.Operations[j]
+		})
+		for _, field := range metadata.Fields {
+			resourceSnapshot.Fields[field.Name] = FieldSnapshot{
+				Type:     field.Type,
+				Required: field.Required || required[field.Name],
+				ReadOnly: field.ReadOnly,
+			}
+		}
+		for _, relation := range metadata.Relations {
+			if resourceSnapshot.Relations == nil {
+				resourceSnapshot.Relations = make(map[string]string)
+			}
+			resourceSnapshot.Relations[relation.Name] = relation.Resource
+		}
+		snapshot.Resources[res.GetName()] = resourceSnapshot
+	}
+	return snapshot
+}
+
+// SnapshotRegistry captures the API contract of all resources of a registry
+func SnapshotRegistry(registry *ResourceRegistry) SchemaSnapshot {
+	return Snapshot(registry.GetAll()...)
+}
+
+// CompareSchemas returns the changes from baseline to current, sorted by resource and
+// field. Removing a resource, field, relation, operation, filter or sort, changing a
+// field type or ID field, and making a field required or read-only are breaking; all
+// other changes are additive.
+func CompareSchemas(baseline, current SchemaSnapshot) []SchemaChange {
+	var changes []SchemaChange
+	add := func(resource, field string, breaking bool, format string, args ...interface{}) {
+		changes = append(changes, SchemaChange{Resource: resource, Field: field, Breaking: breaking, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for name, old := range baseline.Resources {
+		cur, ok := current.Resources[name]
+		if !ok {
+			add(name, "", true, "resource removed")
+			continue
+		}
+
+		if old.IDField != cur.IDField {
+			add(name, "", true, "ID field changed from %q to %q", old.IDField, cur.IDField)
+		}
+		for _, op := range missingNames(operationNames(old.Operations), operationNames(cur.Operations)) {
+			add(name, "", true, "operation %q removed", op)
+		}
+		for _, op := range missingNames(operationNames(cur.Operations), operationNames(old.Operations)) {
+			add(name, "", false, "operation %q added", op)
+		}
+
+		for fieldName, oldField := range old.Fields {
+			curField, ok := cur.Fields[fieldName]
+			switch {
+			case !ok:
+				add(name, fieldName, true, "field removed")
+				continue
+			case oldField.Type != curField.Type:
+				add(name, fieldName, true, "type changed from %q to %q", oldField.Type, curField.Type)
+			}
+			if !oldField.Required && curField.Required {
+				add(name, fieldName, true, "field became required")
+			} else if oldField.Required && !curField.Required {
+				add(name, fieldName, false, "field became optional")
+			}
+			if !oldField.ReadOnly && curField.ReadOnly {
+				add(name, fieldName, true, "field became read-only")
+			}
+		}
+		for fieldName, curField := range cur.Fields {
+			if _, ok := old.Fields[fieldName]; !ok {
+				// New required fields break clients creating records
+				add(name, fieldName, curField.Required, "field added")
+			}
+		}
+
+		for relation, target := range old.Relations {
+			if curTarget, ok := cur.Relations[relation]; !ok {
+				add(name, relation, true, "relation removed")
+			} else if curTarget != target {
+				add(name, relation, true, "relation target changed from %q to %q", target, curTarget)
+			}
+		}
+		// Removed fields are reported once, as removed
+		for _, field := range missingNames(old.Filterable, cur.Filterable) {
+			if _, ok := cur.Fields[field]; ok {
+				add(name, field, true, "no longer filterable")
+			}
+		}
+		for _, field := range missingNames(old.Sortable, cur.Sortable) {
+			if _, ok := cur.Fields[field]; ok {
+				add(name, field, true, "no longer sortable")
+			}
+		}
+	}
+
+	for name := range current.Resources {
+		if _, ok := baseline.Resources[name]; !ok {
+			add(name, "", false, "resource added")
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Resource != changes[j].Resource {
+			return changes[i].Resource < changes[j].Resource
+		}
+		if changes[i].Field != changes[j].Field {
+			return changes[i].Field < changes[j].Field
+		}
+		return changes[i].Message < changes[j].Message
+	})
+	return changes
+}
+
+// BreakingChanges returns only the breaking changes
+func BreakingChanges(changes []SchemaChange) []SchemaChange {
+	var breaking []SchemaChange
+	for _, change := range changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// WriteSchemaFile writes a snapshot as indented JSON
+func WriteSchemaFile(path string, snapshot SchemaSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadSchemaFile reads a snapshot written by WriteSchemaFile
+func ReadSchemaFile(path string) (SchemaSnapshot, error) {
+	var snapshot SchemaSnapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// CheckSchemaFile compares resources against the baseline at path and returns all
+// changes. It returns an *IncompatibleSchemaError when there are breaking changes.
+// A missing baseline is created, and with update set the baseline is rewritten to the
+// current schema after reporting, accepting its changes.
+func CheckSchemaFile(path string, update bool, resources ...Resource) ([]SchemaChange, error) {
+	current := Snapshot(resources...)
+
+	baseline, err := ReadSchemaFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, WriteSchemaFile(path, current)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes := CompareSchemas(baseline, current)
+	if update {
+		return changes, WriteSchemaFile(path, current)
+	}
+	if breaking := BreakingChanges(changes); len(breaking) > 0 {
+		return changes, &IncompatibleSchemaError{Changes: breaking}
+	}
+	return changes, nil
+}
+
+// operationNames converts operations to strings
+func operationNames(operations []Operation) []string {
+	names := make([]string, len(operations))
+	for i, op := range operations {
+		names[i] = string(op)
+	}
+	return names
+}
+
+// missingNames returns the names of from that are not in to
+func missingNames(from, to []string) []string {
+	present := make(map[string]bool, len(to))
+	for _, name := range to {
+		present[name] = true
+	}
+	var result []string
+	for _, name := range from {
+		if !present[name] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// sortedCopy returns a sorted copy of names
+func sortedCopy(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	result := append([]string{}, names...)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,116 @@
+package resource
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type compatOrder struct {
+	ID     uint   `json:"id"`
+	Number string `json:"number"`
+	Total  int    `json:"total"`
+}
+
+func compatResource(fields []Field, operations ...Operation) Resource {
+	return NewResource(ResourceConfig{
+		Name:             "orders",
+		Model:            compatOrder{},
+		Fields:           fields,
+		Operations:       operations,
+		FilterableFields: []string{"number"},
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	snapshot := Snapshot(compatResource([]Field{
+		{Name: "id", Type: "int"},
+		{Name: "number", Type: "string", Validation: &Validation{Required: true}},
+		{Name: "legacy_total", Alias: "total", Type: "int", ReadOnly: true},
+	}, OperationRead, OperationList))
+
+	orders := snapshot.Resources["orders"]
+	assert.Equal(t, []Operation{OperationList, OperationRead}, orders.Operations)
+	assert.Equal(t, FieldSnapshot{Type: "string", Required: true}, orders.Fields["number"])
+	assert.Equal(t, FieldSnapshot{Type: "int", ReadOnly: true}, orders.Fields["total"])
+	assert.Equal(t, []string{"number"}, orders.Filterable)
+}
+
+func TestCompareSchemas(t *testing.T) {
+	baseline := Snapshot(compatResource([]Field{
+		{Name: "id", Type: "int"},
+		{Name: "number", Type: "string"},
+		{Name: "total", Type: "int"},
+	}, OperationList, OperationDelete))
+
+	// Additive changes only
+	changes := CompareSchemas(baseline, Snapshot(compatResource([]Field{
+		{Name: "id", Type: "int"},
+		{Name: "number", Type: "string"},
+		{Name: "total", Type: "int"},
+		{Name: "note", Type: "string"},
+	}, OperationList, OperationDelete, OperationCreate)))
+	assert.Empty(t, BreakingChanges(changes))
+	assert.Equal(t, []string{
+		`orders: operation "create" added (additive)`,
+		"orders.note: field added (additive)",
+	}, changeStrings(changes))
+
+	// Breaking changes
+	changes = CompareSchemas(baseline, Snapshot(compatResource([]Field{
+		{Name: "id", Type: "int"},
+		{Name: "total", Type: "float64"},
+		{Name: "code", Type: "string", Validation: &Validation{Required: true}},
+	}, OperationList)))
+	assert.Equal(t, []string{
+		`orders: operation "delete" removed (breaking)`,
+		"orders.code: field added (breaking)",
+		"orders.number: field removed (breaking)",
+		`orders.total: type changed from "int" to "float64" (breaking)`,
+	}, changeStrings(changes))
+
+	changes = CompareSchemas(baseline, SchemaSnapshot{Resources: map[string]ResourceSnapshot{}})
+	assert.Equal(t, []string{"orders: resource removed (breaking)"}, changeStrings(changes))
+}
+
+func TestCheckSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	original := compatResource([]Field{{Name: "id", Type: "int"}, {Name: "number", Type: "string"}}, OperationList)
+
+	// A missing baseline is created
+	changes, err := CheckSchemaFile(path, false, original)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	changes, err = CheckSchemaFile(path, false, original)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	broken := compatResource([]Field{{Name: "id", Type: "int"}}, OperationList)
+	changes, err = CheckSchemaFile(path, false, broken)
+	var incompatible *IncompatibleSchemaError
+	require.True(t, errors.As(err, &incompatible))
+	assert.Len(t, changes, 1)
+	assert.Contains(t, err.Error(), "orders.number: field removed (breaking)")
+
+	// Updating accepts the changes
+	_, err = CheckSchemaFile(path, true, broken)
+	require.NoError(t, err)
+	changes, err = CheckSchemaFile(path, false, broken)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func changeStrings(changes []SchemaChange) []string {
+	result := make([]string, len(changes))
+	for i, change := range changes {
+		result[i] = change.String()
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package resource
+
+// CountMode is how list responses count the records matching their query
+type CountMode string
+
+const (
+	// CountExact counts the records with a COUNT query (the default)
+	CountExact CountMode = "exact"
+
+	// CountNone skips the COUNT query. One record more than the page is read to tell
+	// whether a next page exists, and the total is a lower bound: the records up to
+	// the page, plus one when a next page exists.
+	CountNone CountMode = "none"
+
+	// CountEstimated reads the number of rows of unfiltered lists from the planner
+	// statistics on PostgreSQL (pg_class.reltuples). Filtered lists, small tables and
+	// other databases are counted exactly.
+	CountEstimated CountMode = "estimated"
+)
+
+// CountModeResource is implemented by resources configuring how lists are counted
+type CountModeResource interface {
+	GetCountMode() CountMode
+}
+
+// ParseCountMode parses a count mode: a CountMode, or a boolean as in ?withCount=false
+// for CountNone. false is returned for other values.
+func ParseCountMode(value string) (CountMode, bool) {
+	switch CountMode(value) {
+	case CountExact, CountNone, CountEstimated:
+		return CountMode(value), true
+	}
+	switch value {
+	case "true", "1":
+		return CountExact, true
+	case "false", "0":
+		return CountNone, true
+	}
+	return "", false
+}
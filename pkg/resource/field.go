@@ -1,15 +1,19 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
-	"strings"
+
+	"github.com/suranig/refine-gin/pkg/expr"
 )
 
 // Field represents a resource field
 type Field struct {
 	Name        string
+	Alias       string // API-facing name used in requests, responses and metadata instead of Name
 	Type        string
 	Label       string
 	Validation  *Validation
@@ -25,8 +29,31 @@ type Field struct {
 	RichText    *RichTextConfig      // Configuration for rich text fields
 	Select      *SelectConfig        // Configuration for select fields
 	Computed    *ComputedFieldConfig // Configuration for computed fields
+	Slug        *SlugConfig          // Configuration for slug fields generated on create
+	Hook        *FieldHook           // Persistence hooks transforming the value on write and read
 	AntDesign   *AntDesignConfig     // Configuration specific to Ant Design
 	Permissions map[string][]string  // Map of operations to roles with permission
+	ValueObject *ValueObjectConfig   // Set on the fields of value objects (e.g. "address.city")
+	Flags       *FlagsConfig         // Named bits of bit flag fields
+}
+
+// APIName returns the name the field is exposed under: its alias if set, otherwise its name
+func (f Field) APIName() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FieldAliases returns the aliases declared on fields, keyed by field name
+func FieldAliases(fields []Field) map[string]string {
+	aliases := make(map[string]string)
+	for _, field := range fields {
+		if field.Alias != "" && field.Alias != field.Name {
+			aliases[field.Name] = field.Alias
+		}
+	}
+	return aliases
 }
 
 // JsonConfig defines configuration for JSON fields
@@ -34,6 +61,10 @@ type JsonConfig struct {
 	// Schema for JSON field validation and UI
 	Schema map[string]interface{} `json:"schema,omitempty"`
 
+	// JSONSchema is a JSON Schema (draft 2020-12) the field value is validated
+	// against in create and update requests
+	JSONSchema map[string]interface{} `json:"jsonSchema,omitempty"`
+
 	// Properties defines nested fields in the JSON structure
 	Properties []JsonProperty `json:"properties,omitempty"`
 
@@ -365,6 +396,9 @@ type FileConfig struct {
 	// Base URL for accessing the files
 	BaseURL string `json:"baseURL,omitempty"`
 
+	// Whether the field holds multiple files (stored as a JSON array)
+	Multiple bool `json:"multiple,omitempty"`
+
 	// Whether this is an image field (enables preview and image-specific features)
 	IsImage bool `json:"isImage,omitempty"`
 
@@ -377,8 +411,26 @@ type FileConfig struct {
 
 	// Thumbnail sizes (if generateThumbnails is true)
 	ThumbnailSizes []ThumbnailSize `json:"thumbnailSizes,omitempty"`
+
+	// How uploads of content the field already holds in some record are handled
+	// (needs a content index, see storage.ContentIndex)
+	OnDuplicate DuplicatePolicy `json:"onDuplicate,omitempty"`
 }
 
+// DuplicatePolicy is how a file field handles uploads of content it already holds
+type DuplicatePolicy string
+
+const (
+	// DuplicateAllow accepts duplicate uploads (the default)
+	DuplicateAllow DuplicatePolicy = ""
+
+	// DuplicateWarn accepts duplicate uploads and lists the records holding the content
+	DuplicateWarn DuplicatePolicy = "warn"
+
+	// DuplicateReject rejects duplicate uploads
+	DuplicateReject DuplicatePolicy = "reject"
+)
+
 // ThumbnailSize defines a thumbnail configuration
 type ThumbnailSize struct {
 	// Name of the thumbnail (e.g. "small", "medium")
@@ -454,10 +506,13 @@ type ComputedFieldConfig struct {
 	// Fields this computed field depends on
 	DependsOn []string `json:"dependsOn,omitempty"`
 
-	// Expression to compute the value (can be JS expression for frontend, or Go template for backend)
+	// Expression to compute the value. Client-side fields use the expression language
+	// of the frontend; server-side fields an expression of package expr seeing the
+	// record as record, e.g. `record.price * record.quantity`.
 	Expression string `json:"expression,omitempty"`
 
-	// Whether the computation happens on the client-side
+	// Whether the computation happens on the client-side. Otherwise GenericRepository
+	// computes the value when records are read and before they are saved.
 	ClientSide bool `json:"clientSide,omitempty"`
 
 	// Format for displaying the computed value (only applies to client-side)
@@ -470,6 +525,26 @@ type ComputedFieldConfig struct {
 	ComputeOrder int `json:"computeOrder,omitempty"`
 }
 
+// Compile compiles the expression of a server-side computed field. Only the fields
+// listed in DependsOn can be read from the record, or all of them when none are listed.
+func (c *ComputedFieldConfig) Compile() (*expr.Program, error) {
+	return expr.Compile(c.Expression, expr.Env{Variables: map[string][]string{"record": c.DependsOn}})
+}
+
+// ComputedFields returns the fields of a resource computed on the server, in compute order
+func ComputedFields(res Resource) []Field {
+	var fields []Field
+	for _, field := range res.GetFields() {
+		if field.Computed != nil && !field.Computed.ClientSide && field.Computed.Expression != "" {
+			fields = append(fields, field)
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].Computed.ComputeOrder < fields[j].Computed.ComputeOrder
+	})
+	return fields
+}
+
 // AntDesignConfig defines configuration specific to Ant Design components
 type AntDesignConfig struct {
 	// Component type to use (Input, Select, DatePicker, etc.)
@@ -493,6 +568,7 @@ type ConditionalValidator struct {
 	Field       string                  // The field this validation depends on
 	Operator    string                  // Comparison operator (eq, neq, gt, lt, etc.)
 	Value       interface{}             // Value to compare against
+	Condition   string                  // Expression (see package expr) over record and value, replacing Field, Operator and Value
 	Message     string                  // Custom message when condition fails
 	ValidateFn  func(interface{}) error // Validation function to apply conditionally
 	ModelGetter func() interface{}      // Function to get the full model for accessing other fields
@@ -510,16 +586,27 @@ func (v ConditionalValidator) Validate(value interface{}) error {
 		return nil // No model to check conditions against
 	}
 
-	// Get the value of the dependent field
-	fieldValue, err := GetFieldValue(model, v.Field)
-	if err != nil {
-		return nil // Can't get dependent field, skip validation
-	}
-
 	// Check the condition
-	conditionMet, err := evaluateCondition(fmt.Sprintf("%v", fieldValue), v.Operator, v.Value)
-	if err != nil {
-		return nil // Can't evaluate condition, skip validation
+	var conditionMet bool
+	if v.Condition != "" {
+		program, err := expr.Compile(v.Condition, expr.Env{Variables: map[string][]string{"record": nil, "value": {}}})
+		if err != nil {
+			return err
+		}
+		if conditionMet, err = program.EvalBool(context.Background(), map[string]interface{}{"record": model, "value": value}); err != nil {
+			return err
+		}
+	} else {
+		// Get the value of the dependent field
+		fieldValue, err := GetFieldValue(model, v.Field)
+		if err != nil {
+			return nil // Can't get dependent field, skip validation
+		}
+
+		conditionMet, err = evaluateCondition(fmt.Sprintf("%v", fieldValue), v.Operator, v.Value)
+		if err != nil {
+			return nil // Can't evaluate condition, skip validation
+		}
 	}
 
 	// If condition is met, apply the validation
@@ -535,83 +622,34 @@ func (v ConditionalValidator) Validate(value interface{}) error {
 	return nil
 }
 
-// evaluateCondition compares a field value against a condition
-func evaluateCondition(fieldValue, operator string, compareValue interface{}) (bool, error) {
-	// Convert fieldValue to comparable format
-	var fieldValueFloat float64
-	var compareValueFloat float64
-	var err error
-
-	// For numeric comparisons
-	if operator == "gt" || operator == "lt" || operator == "gte" || operator == "lte" {
-		fieldValueFloat, err = convertToFloat(fieldValue)
-		if err != nil {
-			return false, err
-		}
-
-		compareValueFloat, err = convertToFloat(compareValue)
-		if err != nil {
-			return false, err
-		}
-	}
+// conditionPrograms are the expressions of the operators of conditional validations,
+// comparing the field value with the compared value
+var conditionPrograms = map[string]*expr.Program{
+	"eq":         compileCondition(`value == compare`),
+	"neq":        compileCondition(`value != compare`),
+	"gt":         compileCondition(`float(value) > float(compare)`),
+	"lt":         compileCondition(`float(value) < float(compare)`),
+	"gte":        compileCondition(`float(value) >= float(compare)`),
+	"lte":        compileCondition(`float(value) <= float(compare)`),
+	"contains":   compileCondition(`value.contains(compare)`),
+	"startsWith": compileCondition(`value.startsWith(compare)`),
+	"endsWith":   compileCondition(`value.endsWith(compare)`),
+}
 
-	// Perform comparison based on operator
-	switch operator {
-	case "eq":
-		return fmt.Sprintf("%v", fieldValue) == fmt.Sprintf("%v", compareValue), nil
-	case "neq":
-		return fmt.Sprintf("%v", fieldValue) != fmt.Sprintf("%v", compareValue), nil
-	case "gt":
-		return fieldValueFloat > compareValueFloat, nil
-	case "lt":
-		return fieldValueFloat < compareValueFloat, nil
-	case "gte":
-		return fieldValueFloat >= compareValueFloat, nil
-	case "lte":
-		return fieldValueFloat <= compareValueFloat, nil
-	case "contains":
-		return strings.Contains(fmt.Sprintf("%v", fieldValue), fmt.Sprintf("%v", compareValue)), nil
-	case "startsWith":
-		return strings.HasPrefix(fmt.Sprintf("%v", fieldValue), fmt.Sprintf("%v", compareValue)), nil
-	case "endsWith":
-		return strings.HasSuffix(fmt.Sprintf("%v", fieldValue), fmt.Sprintf("%v", compareValue)), nil
-	default:
-		return false, fmt.Errorf("unsupported operator: %s", operator)
-	}
+func compileCondition(source string) *expr.Program {
+	return expr.MustCompile(source, expr.Env{Variables: map[string][]string{"value": {}, "compare": {}}})
 }
 
-// convertToFloat converts an interface{} to float64 for numeric comparisons
-func convertToFloat(value interface{}) (float64, error) {
-	switch v := value.(type) {
-	case float64:
-		return v, nil
-	case float32:
-		return float64(v), nil
-	case int:
-		return float64(v), nil
-	case int8:
-		return float64(v), nil
-	case int16:
-		return float64(v), nil
-	case int32:
-		return float64(v), nil
-	case int64:
-		return float64(v), nil
-	case uint:
-		return float64(v), nil
-	case uint8:
-		return float64(v), nil
-	case uint16:
-		return float64(v), nil
-	case uint32:
-		return float64(v), nil
-	case uint64:
-		return float64(v), nil
-	case string:
-		return strconv.ParseFloat(v, 64)
-	default:
-		return 0, fmt.Errorf("cannot convert %T to float64", value)
+// evaluateCondition compares a field value against a condition
+func evaluateCondition(fieldValue, operator string, compareValue interface{}) (bool, error) {
+	program, ok := conditionPrograms[operator]
+	if !ok {
+		return false, fmt.Errorf("unsupported operator: %s", operator)
 	}
+	return program.EvalBool(context.Background(), map[string]interface{}{
+		"value":   fieldValue,
+		"compare": fmt.Sprintf("%v", compareValue),
+	})
 }
 
 // CustomValidator validates using a custom expression
@@ -636,6 +674,11 @@ func (v CustomValidator) Validate(value interface{}) error {
 	return nil
 }
 
+// FieldCheck is a server-side check of a field value, e.g. against other records or an
+// external service. values holds the other values of the form; the returned error is
+// shown as the message of the field.
+type FieldCheck func(ctx context.Context, value interface{}, values map[string]interface{}) error
+
 // AsyncValidator represents a validator that performs asynchronous validation
 type AsyncValidator struct {
 	URL     string // URL to send validation request to
@@ -180,46 +180,34 @@ func TestReadOnlyAndHiddenFields(t *testing.T) {
 	assert.True(t, field.Validation.Required, "Field should be marked as required")
 }
 
+func TestFieldAliases(t *testing.T) {
+	field := &Field{Name: "legacy_ref", Type: "string"}
+	ParseFieldTag(field, "alias=externalId; label=External ID")
+	assert.Equal(t, "externalId", field.Alias)
+	assert.Equal(t, "externalId", field.APIName())
+	assert.Equal(t, "title", Field{Name: "title"}.APIName())
+
+	fields := []Field{*field, {Name: "title"}, {Name: "code", Alias: "code"}}
+	assert.Equal(t, map[string]string{"legacy_ref": "externalId"}, FieldAliases(fields))
+
+	metadata := GenerateResourceMetadata(NewResource(ResourceConfig{
+		Name:             "orders",
+		Model:            struct{ Title string }{},
+		Fields:           fields,
+		FilterableFields: []string{"legacy_ref", "title"},
+		DefaultSort:      &Sort{Field: "legacy_ref", Order: "desc"},
+	}))
+	assert.Equal(t, "externalId", metadata.Fields[0].Name)
+	assert.Equal(t, []string{"externalId", "title"}, metadata.FilterableFields)
+	assert.Equal(t, "externalId", metadata.DefaultSort.Field)
+}
+
 // Test helper model for conditional validation
 type conditionalModel struct {
 	Age    int
 	Status string
 }
 
-func TestConvertToFloat(t *testing.T) {
-	tests := []struct {
-		name    string
-		input   interface{}
-		want    float64
-		wantErr bool
-	}{
-		{"int", 10, 10, false},
-		{"int8", int8(5), 5, false},
-		{"int16", int16(6), 6, false},
-		{"uint", uint(7), 7, false},
-		{"uint32", uint32(8), 8, false},
-		{"uint64", uint64(9), 9, false},
-		{"float32", float32(3.5), 3.5, false},
-		{"float64", float64(1.23), 1.23, false},
-		{"string number", "2.5", 2.5, false},
-		{"invalid string", "abc", 0, true},
-		{"unsupported bool", true, 0, true},
-		{"unsupported type", struct{}{}, 0, true},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := convertToFloat(tt.input)
-			if tt.wantErr {
-				assert.Error(t, err)
-				return
-			}
-			assert.NoError(t, err)
-			assert.Equal(t, tt.want, got)
-		})
-	}
-}
-
 func TestEvaluateCondition(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -236,6 +224,10 @@ func TestEvaluateCondition(t *testing.T) {
 		{"contains", "hello world", "contains", "world", true, false},
 		{"invalid operator", "a", "unknown", "b", false, true},
 		{"bad number", "abc", "gt", 1, false, true},
+		{"gte unsigned", uint(7), "gte", 7, true, false},
+		{"lte float", float32(3.5), "lte", "3.5", true, false},
+		{"starts with", "hello", "startsWith", "he", true, false},
+		{"not a number", 1, "lt", true, false, true},
 	}
 
 	for _, tt := range tests {
@@ -290,6 +282,27 @@ func TestConditionalValidatorValidate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConditionalValidatorCondition(t *testing.T) {
+	model := &conditionalModel{Age: 20, Status: "active"}
+	validator := ConditionalValidator{
+		Condition: `record.Status == "active" && size(value) < 3`,
+		Message:   "active records need a longer code",
+		ValidateFn: func(v interface{}) error {
+			return fmt.Errorf("code validated")
+		},
+		ModelGetter: func() interface{} { return model },
+	}
+
+	assert.EqualError(t, validator.Validate("ab"), "active records need a longer code")
+	assert.NoError(t, validator.Validate("abcd"))
+
+	model.Status = "inactive"
+	assert.NoError(t, validator.Validate("ab"))
+
+	validator.Condition = "record.Status =="
+	assert.Error(t, validator.Validate("ab"))
+}
+
 func TestCustomValidatorValidate(t *testing.T) {
 	validator := CustomValidator{Expression: "1 == 1", Message: "should pass"}
 	err := validator.Validate("anything")
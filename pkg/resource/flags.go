@@ -0,0 +1,91 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FieldTypeFlags is the type of bit flag fields (see FlagsConfig)
+const FieldTypeFlags = "flags"
+
+// FlagsConfig configures a bit flag field: an integer column holding several boolean
+// capabilities, one per bit, as in legacy schemas. API payloads carry the names of
+// the set flags instead of the number, e.g. ["read", "write"] for 3.
+type FlagsConfig struct {
+	// Flags are the named bits of the column, in display order
+	Flags []Flag `json:"flags"`
+}
+
+// Flag is a named bit of a bit flag field
+type Flag struct {
+	// Name is the name of the flag in API payloads and filters
+	Name string `json:"name"`
+
+	// Bit is the position of the bit, e.g. 2 for the mask 4
+	Bit uint `json:"bit"`
+
+	// Label is the label of the flag in the UI (default: the name)
+	Label string `json:"label,omitempty"`
+}
+
+// Mask returns the value of the flag in the column
+func (f Flag) Mask() int64 {
+	return 1 << f.Bit
+}
+
+// Mask returns the column value with the named flags set. Names are case-insensitive.
+func (c FlagsConfig) Mask(names []string) (int64, error) {
+	var mask int64
+	for _, name := range names {
+		flag, ok := c.flag(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown flag %q", name)
+		}
+		mask |= flag.Mask()
+	}
+	return mask, nil
+}
+
+// Names returns the names of the flags set in a column value, in the order of Flags.
+// Bits without a flag are dropped.
+func (c FlagsConfig) Names(mask int64) []string {
+	names := []string{}
+	for _, flag := range c.Flags {
+		if mask&flag.Mask() != 0 {
+			names = append(names, flag.Name)
+		}
+	}
+	return names
+}
+
+// flag returns the flag of a name
+func (c FlagsConfig) flag(name string) (Flag, bool) {
+	for _, flag := range c.Flags {
+		if strings.EqualFold(flag.Name, strings.TrimSpace(name)) {
+			return flag, true
+		}
+	}
+	return Flag{}, false
+}
+
+// parseFlags parses the flags of the refine tag: names separated by "|", on the bits
+// 0, 1, 2... or on explicit bits ("read:0|write:1|admin:4")
+func parseFlags(value string) *FlagsConfig {
+	config := &FlagsConfig{}
+	var bit uint
+	for _, part := range strings.Split(value, "|") {
+		name, position, explicit := strings.Cut(strings.TrimSpace(part), ":")
+		if name == "" {
+			continue
+		}
+		if explicit {
+			if parsed, err := strconv.ParseUint(position, 10, 6); err == nil {
+				bit = uint(parsed)
+			}
+		}
+		config.Flags = append(config.Flags, Flag{Name: name, Bit: bit})
+		bit++
+	}
+	return config
+}
@@ -0,0 +1,37 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagsField(t *testing.T) {
+	type account struct {
+		ID           uint `json:"id"`
+		Capabilities int  `json:"capabilities" refine:"flags=read|write|admin:4"`
+	}
+	fields := GenerateFieldsFromModel(account{})
+	require.Len(t, fields, 2)
+	field := fields[1]
+	assert.Equal(t, FieldTypeFlags, field.Type)
+	require.NotNil(t, field.Flags)
+	assert.Equal(t, []Flag{{Name: "read", Bit: 0}, {Name: "write", Bit: 1}, {Name: "admin", Bit: 4}}, field.Flags.Flags)
+
+	mask, err := field.Flags.Mask([]string{"Admin", "read"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), mask)
+	_, err = field.Flags.Mask([]string{"delete"})
+	assert.EqualError(t, err, `unknown flag "delete"`)
+
+	// Bits without a flag are dropped
+	assert.Equal(t, []string{"read", "admin"}, field.Flags.Names(17|8))
+	assert.Equal(t, []string{}, field.Flags.Names(0))
+
+	meta := GenerateFieldsMetadata(fields)
+	assert.Equal(t, field.Flags.Flags, meta[1].Flags)
+	require.NotNil(t, meta[1].AntDesign)
+	assert.Equal(t, "Checkbox.Group", meta[1].AntDesign.ComponentType)
+	assert.Len(t, meta[1].AntDesign.Props["options"], 3)
+}
@@ -0,0 +1,27 @@
+package resource
+
+import "context"
+
+// FieldHook transforms the value of a field at the persistence boundary, e.g. to
+// normalize phone numbers, encrypt secrets or compress large texts. GenericRepository
+// serializes values before writing them and deserializes them after reading, for both
+// struct and map payloads, so handlers and clients only see deserialized values.
+// Zero values are not passed to the hooks.
+type FieldHook struct {
+	// Serialize converts a value before it is stored
+	Serialize func(ctx context.Context, value interface{}) (interface{}, error) `json:"-"`
+
+	// Deserialize converts a stored value after it is read
+	Deserialize func(ctx context.Context, value interface{}) (interface{}, error) `json:"-"`
+}
+
+// HookFields returns the fields of a resource with persistence hooks
+func HookFields(res Resource) []Field {
+	var fields []Field
+	for _, field := range res.GetFields() {
+		if field.Hook != nil {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+)
+
+// LifecycleHook is a callback run by the generic handlers. data is the record being
+// created, the update payload, the deleted ID, the created or updated record, or the
+// *query.QueryOptions of a list, depending on the hook. Errors returned by Before
+// hooks abort the operation.
+type LifecycleHook func(ctx context.Context, res Resource, data interface{}) error
+
+// LifecycleHooks inject business logic (defaults, invariants, notifications, ...) into
+// the generic handlers without writing a custom repository. Hooks may modify data
+// before it is stored. The ID of the updated or deleted record (the IDs for bulk
+// operations) is available with RecordIDFromContext. Bulk creates run the create
+// hooks for every record, bulk updates and deletes run their hooks once with the
+// update payload or the IDs, or for every record in partial mode.
+//
+// Errors of Before hooks are reported with the status of a *HookError or 422
+// Unprocessable Entity. After hooks run once the change is stored, so their errors
+// are reported as 500 Internal Server Error without undoing it.
+type LifecycleHooks struct {
+	BeforeCreate LifecycleHook
+	AfterCreate  LifecycleHook
+	BeforeUpdate LifecycleHook
+	AfterUpdate  LifecycleHook
+	BeforeDelete LifecycleHook
+	AfterDelete  LifecycleHook
+	BeforeList   LifecycleHook
+}
+
+// HookError is returned by lifecycle hooks to reject a request with a specific status
+type HookError struct {
+	Status  int
+	Message string
+
+	// Fields are the messages of the rejected fields by JSON name, which refine forms
+	// show under the fields
+	Fields map[string][]string
+}
+
+// NewHookError creates a HookError
+func NewHookError(status int, message string) *HookError {
+	return &HookError{Status: status, Message: message}
+}
+
+// NewValidationError creates a HookError rejecting fields with 422 Unprocessable Entity
+func NewValidationError(message string, fields map[string][]string) *HookError {
+	return &HookError{Status: http.StatusUnprocessableEntity, Message: message, Fields: fields}
+}
+
+func (e *HookError) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status of the error, 422 if not set
+func (e *HookError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusUnprocessableEntity
+	}
+	return e.Status
+}
+
+type recordIDKey struct{}
+
+// WithRecordID returns a context carrying the ID of the record a hook runs for
+func WithRecordID(ctx context.Context, id interface{}) context.Context {
+	return context.WithValue(ctx, recordIDKey{}, id)
+}
+
+// RecordIDFromContext returns the ID of the updated or deleted record, or the IDs of
+// a bulk update or delete
+func RecordIDFromContext(ctx context.Context) (interface{}, bool) {
+	id := ctx.Value(recordIDKey{})
+	return id, id != nil
+}
@@ -33,9 +33,16 @@ type ResourceMetadata struct {
 	// Searchable fields
 	Searchable []string `json:"searchable,omitempty"`
 
+	// Searchable fields of related records (e.g. "customer.name")
+	SearchableRelations []string `json:"searchableRelations,omitempty"`
+
 	// ID field name
 	IDFieldName string `json:"idFieldName,omitempty"`
 
+	// Strategy generating the IDs of created records (e.g. "ulid"), empty when the
+	// database assigns them
+	IDStrategy string `json:"idStrategy,omitempty"`
+
 	// Additional field lists for UI
 	FilterableFields []string `json:"filterableFields,omitempty"`
 	SortableFields   []string `json:"sortableFields,omitempty"`
@@ -45,6 +52,10 @@ type ResourceMetadata struct {
 
 	// Permissions at resource level (operation -> roles)
 	Permissions map[string][]string `json:"permissions,omitempty"`
+
+	// Query parameters understood by lists, set by the metadata endpoints (see
+	// query.Capabilities)
+	QueryCapabilities *QueryCapabilitiesMetadata `json:"queryCapabilities,omitempty"`
 }
 
 // FieldMetadata represents metadata for a resource field
@@ -97,6 +108,12 @@ type FieldMetadata struct {
 	// Computed field configuration
 	Computed *ComputedFieldConfigMetadata `json:"computed,omitempty"`
 
+	// Slug field configuration
+	Slug *SlugConfigMetadata `json:"slug,omitempty"`
+
+	// Named flags of bit flag fields, whose values are arrays of flag names
+	Flags []Flag `json:"flags,omitempty"`
+
 	// Ant Design specific configuration
 	AntDesign *AntDesignConfigMetadata `json:"antDesign,omitempty"`
 
@@ -172,6 +189,9 @@ type JsonConfigMetadata struct {
 	// Schema for JSON field validation and UI
 	Schema map[string]interface{} `json:"schema,omitempty"`
 
+	// JSONSchema the field value is validated against on the server
+	JSONSchema map[string]interface{} `json:"jsonSchema,omitempty"`
+
 	// Properties defines nested fields in the JSON structure
 	Properties []JsonPropertyMetadata `json:"properties,omitempty"`
 
@@ -332,6 +352,9 @@ type FileConfigMetadata struct {
 	// Base URL for accessing the files
 	BaseURL string `json:"baseURL,omitempty"`
 
+	// Whether the field holds multiple files
+	Multiple bool `json:"multiple,omitempty"`
+
 	// Whether this is an image field (enables preview and image-specific features)
 	IsImage bool `json:"isImage,omitempty"`
 
@@ -344,6 +367,9 @@ type FileConfigMetadata struct {
 
 	// Thumbnail sizes (if generateThumbnails is true)
 	ThumbnailSizes []ThumbnailSizeMetadata `json:"thumbnailSizes,omitempty"`
+
+	// How uploads of content the field already holds are handled
+	OnDuplicate DuplicatePolicy `json:"onDuplicate,omitempty"`
 }
 
 // ThumbnailSizeMetadata defines metadata for a thumbnail configuration
@@ -443,6 +469,15 @@ type ComputedFieldConfigMetadata struct {
 	ComputeOrder int `json:"computeOrder,omitempty"`
 }
 
+// SlugConfigMetadata represents metadata for slug fields
+type SlugConfigMetadata struct {
+	// Field the slug is generated from
+	Source string `json:"source"`
+
+	// Fields scoping the uniqueness of the slug
+	Scope []string `json:"scope,omitempty"`
+}
+
 // AntDesignConfigMetadata represents metadata for Ant Design components
 type AntDesignConfigMetadata struct {
 	// Component type to use (Input, Select, DatePicker, etc.)
@@ -579,9 +614,32 @@ func GenerateResourceMetadata(res Resource) ResourceMetadata {
 		Permissions:      res.GetPermissions(),
 	}
 
+	if r, ok := res.(SearchableRelationsResource); ok {
+		metadata.SearchableRelations = r.GetSearchableRelationFields()
+	}
+
+	if r, ok := res.(IDGeneratorResource); ok && r.GetIDGenerator() != nil {
+		metadata.IDStrategy = r.GetIDGenerator().Strategy()
+	}
+
 	// Generate field metadata
 	metadata.Fields = GenerateFieldsMetadata(res.GetFields())
 
+	// Expose aliased fields under their aliases
+	if aliases := FieldAliases(res.GetFields()); len(aliases) > 0 {
+		metadata.Searchable = aliasNames(metadata.Searchable, aliases)
+		metadata.FilterableFields = aliasNames(metadata.FilterableFields, aliases)
+		metadata.SortableFields = aliasNames(metadata.SortableFields, aliases)
+		metadata.TableFields = aliasNames(metadata.TableFields, aliases)
+		metadata.FormFields = aliasNames(metadata.FormFields, aliases)
+		metadata.RequiredFields = aliasNames(metadata.RequiredFields, aliases)
+		if sort := metadata.DefaultSort; sort != nil {
+			if alias, ok := aliases[sort.Field]; ok {
+				metadata.DefaultSort = &Sort{Field: alias, Order: sort.Order}
+			}
+		}
+	}
+
 	// Generate relation metadata
 	if rels := res.GetRelations(); len(rels) > 0 {
 		metadata.Relations = GenerateRelationsMetadata(rels)
@@ -590,6 +648,21 @@ func GenerateResourceMetadata(res Resource) ResourceMetadata {
 	return metadata
 }
 
+// aliasNames returns a copy of names with aliased field names replaced by their aliases
+func aliasNames(names []string, aliases map[string]string) []string {
+	if names == nil {
+		return nil
+	}
+	result := make([]string, len(names))
+	for i, name := range names {
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+		result[i] = name
+	}
+	return result
+}
+
 // GenerateFieldsMetadata generates metadata for fields
 func GenerateFieldsMetadata(fields []Field) []FieldMetadata {
 	metadata := make([]FieldMetadata, 0, len(fields))
@@ -608,7 +681,7 @@ func GenerateFieldsMetadata(fields []Field) []FieldMetadata {
 		}
 
 		fieldMeta := FieldMetadata{
-			Name:        field.Name,
+			Name:        field.APIName(),
 			Type:        field.Type,
 			Label:       field.Label,
 			Filterable:  isFilterable,
@@ -697,6 +770,16 @@ func GenerateFieldsMetadata(fields []Field) []FieldMetadata {
 			fieldMeta.Computed = GenerateComputedFieldConfigMetadata(field.Computed)
 		}
 
+		// Add slug configuration if present
+		if field.Slug != nil {
+			fieldMeta.Slug = GenerateSlugConfigMetadata(field.Slug)
+		}
+
+		// Add flags if present
+		if field.Flags != nil {
+			fieldMeta.Flags = field.Flags.Flags
+		}
+
 		// Add Ant Design configuration if present
 		if field.AntDesign != nil {
 			fieldMeta.AntDesign = GenerateAntDesignConfigMetadata(field.AntDesign, field.Validation)
@@ -800,6 +883,22 @@ func generateDefaultAntDesignConfig(field *Field) *AntDesignConfigMetadata {
 			config.Props["placeholder"] = field.Select.Placeholder
 		}
 
+	case FieldTypeFlags:
+		if field.Flags != nil {
+			options := make([]map[string]interface{}, 0, len(field.Flags.Flags))
+			for _, flag := range field.Flags.Flags {
+				label := flag.Label
+				if label == "" {
+					label = flag.Name
+				}
+				options = append(options, map[string]interface{}{
+					"value": flag.Name,
+					"label": label,
+				})
+			}
+			config.Props["options"] = options
+		}
+
 	case "file":
 		if field.File != nil && field.File.IsImage {
 			config.ComponentType = "Upload.Image"
@@ -908,6 +1007,7 @@ func GenerateJsonConfigMetadata(config *JsonConfig) *JsonConfigMetadata {
 
 	meta := &JsonConfigMetadata{
 		Schema:          config.Schema,
+		JSONSchema:      config.JSONSchema,
 		DefaultExpanded: config.DefaultExpanded,
 		PathPrefix:      config.PathPrefix,
 		EditorType:      config.EditorType,
@@ -1031,10 +1131,12 @@ func GenerateFileConfigMetadata(config *FileConfig) *FileConfigMetadata {
 		AllowedTypes:       config.AllowedTypes,
 		MaxSize:            config.MaxSize,
 		BaseURL:            config.BaseURL,
+		Multiple:           config.Multiple,
 		IsImage:            config.IsImage,
 		MaxWidth:           config.MaxWidth,
 		MaxHeight:          config.MaxHeight,
 		GenerateThumbnails: config.GenerateThumbnails,
+		OnDuplicate:        config.OnDuplicate,
 	}
 
 	// Convert thumbnail sizes
@@ -1121,6 +1223,18 @@ func GenerateComputedFieldConfigMetadata(config *ComputedFieldConfig) *ComputedF
 	}
 }
 
+// GenerateSlugConfigMetadata generates metadata for slug field configuration
+func GenerateSlugConfigMetadata(config *SlugConfig) *SlugConfigMetadata {
+	if config == nil {
+		return nil
+	}
+
+	return &SlugConfigMetadata{
+		Source: config.Source,
+		Scope:  config.Scope,
+	}
+}
+
 // GenerateAntDesignConfigMetadata generates metadata for Ant Design configuration
 func GenerateAntDesignConfigMetadata(config *AntDesignConfig, validation *Validation) *AntDesignConfigMetadata {
 	if config == nil {
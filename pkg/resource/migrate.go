@@ -0,0 +1,310 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Kinds of migration steps
+const (
+	MigrationCreateTable = "create_table"
+	MigrationAddColumn   = "add_column"
+	MigrationAddIndex    = "add_index"
+)
+
+// MigrationStep is a change of the database schema a migration makes
+type MigrationStep struct {
+	Resource string
+	Table    string
+
+	// Kind is MigrationCreateTable, MigrationAddColumn or MigrationAddIndex
+	Kind string
+
+	// Name of the column or index added
+	Name string
+
+	// SQL run by the step
+	SQL string
+}
+
+// MigrationPlan lists the changes AutoMigrateAll would make to a database
+type MigrationPlan struct {
+	Steps []MigrationStep
+}
+
+// String returns the SQL of the plan, one statement per line
+func (p *MigrationPlan) String() string {
+	var sb strings.Builder
+	for _, step := range p.Steps {
+		sb.WriteString(step.SQL)
+		sb.WriteString(";\n")
+	}
+	return sb.String()
+}
+
+// AutoMigrateAll migrates the tables of resources (all registered resources if none
+// are given): GORM's AutoMigrate creates the tables, columns and indexes of their
+// models, then the indexes of their query fields are created (see PlanMigration).
+// It replaces the db.AutoMigrate calls listing every model of an app.
+func AutoMigrateAll(db *gorm.DB, resources ...Resource) error {
+	targets, err := migrationTargets(db, resources)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := db.AutoMigrate(target.model); err != nil {
+			return fmt.Errorf("migrate %s: %w", target.resource.GetName(), err)
+		}
+		for _, index := range target.queryIndexes() {
+			if db.Migrator().HasIndex(target.model, index.name) {
+				continue
+			}
+			if err := db.Exec(index.sql()).Error; err != nil {
+				return fmt.Errorf("migrate %s: %w", target.resource.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// PlanMigration diffs the database schema against the models of resources (all
+// registered resources if none are given) and returns the changes AutoMigrateAll would
+// make, without making them: missing tables, missing columns, missing indexes declared
+// by the models, and missing indexes of query fields.
+//
+// Query fields are the searchable fields, the field of the default sort, and the
+// filterable and sortable fields of resources that narrow them (all fields are
+// filterable and sortable by default, which doesn't call for indexes). Fields already
+// starting an index, primary keys and serialized fields are not indexed.
+//
+// Changes to the types of existing columns are not planned, though AutoMigrate may make
+// them.
+func PlanMigration(db *gorm.DB, resources ...Resource) (*MigrationPlan, error) {
+	targets, err := migrationTargets(db, resources)
+	if err != nil {
+		return nil, err
+	}
+	recorder := &sqlRecorder{}
+	dryRun := db.Session(&gorm.Session{DryRun: true, Logger: recorder})
+	migrator := db.Migrator()
+
+	plan := &MigrationPlan{}
+	step := func(target migrationTarget, kind, name string, run func() error) error {
+		recorder.statements = nil
+		if err := run(); err != nil {
+			return fmt.Errorf("plan %s: %w", target.resource.GetName(), err)
+		}
+		for _, sql := range recorder.statements {
+			plan.Steps = append(plan.Steps, MigrationStep{
+				Resource: target.resource.GetName(),
+				Table:    target.schema.Table,
+				Kind:     kind,
+				Name:     name,
+				SQL:      sql,
+			})
+		}
+		return nil
+	}
+
+	for _, target := range targets {
+		if !migrator.HasTable(target.model) {
+			if err := step(target, MigrationCreateTable, target.schema.Table, func() error {
+				return dryRun.Migrator().CreateTable(target.model)
+			}); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, field := range target.schema.Fields {
+				if field.DBName == "" || migrator.HasColumn(target.model, field.DBName) {
+					continue
+				}
+				if err := step(target, MigrationAddColumn, field.DBName, func() error {
+					return dryRun.Migrator().AddColumn(target.model, field.DBName)
+				}); err != nil {
+					return nil, err
+				}
+			}
+			for _, index := range target.schema.ParseIndexes() {
+				if migrator.HasIndex(target.model, index.Name) {
+					continue
+				}
+				if err := step(target, MigrationAddIndex, index.Name, func() error {
+					return dryRun.Migrator().CreateIndex(target.model, index.Name)
+				}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, index := range target.queryIndexes() {
+			if migrator.HasIndex(target.model, index.name) {
+				continue
+			}
+			plan.Steps = append(plan.Steps, MigrationStep{
+				Resource: target.resource.GetName(),
+				Table:    target.schema.Table,
+				Kind:     MigrationAddIndex,
+				Name:     index.name,
+				SQL:      index.sql(),
+			})
+		}
+	}
+	return plan, nil
+}
+
+// migrationTarget is a resource with the parsed schema of its model
+type migrationTarget struct {
+	resource Resource
+	model    interface{}
+	schema   *schema.Schema
+	db       *gorm.DB
+}
+
+// migrationTargets parses the models of resources, skipping resources without a
+// struct model and models shared with a resource listed before
+func migrationTargets(db *gorm.DB, resources []Resource) ([]migrationTarget, error) {
+	if len(resources) == 0 {
+		resources = GlobalResourceRegistry.GetAll()
+	}
+	var targets []migrationTarget
+	seen := make(map[string]bool)
+	for _, res := range resources {
+		model := res.GetModel()
+		modelType := reflect.TypeOf(model)
+		for modelType != nil && modelType.Kind() == reflect.Ptr {
+			modelType = modelType.Elem()
+		}
+		if modelType == nil || modelType.Kind() != reflect.Struct || modelType.NumField() == 0 {
+			continue
+		}
+		model = reflect.New(modelType).Interface()
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("parse model of %s: %w", res.GetName(), err)
+		}
+		if seen[stmt.Schema.Table] {
+			continue
+		}
+		seen[stmt.Schema.Table] = true
+		targets = append(targets, migrationTarget{resource: res, model: model, schema: stmt.Schema, db: db})
+	}
+	return targets, nil
+}
+
+// queryIndex is the index of a query field
+type queryIndex struct {
+	name   string
+	table  string
+	column string
+	db     *gorm.DB
+}
+
+// sql returns the statement creating the index
+func (i queryIndex) sql() string {
+	quote := func(name string) string {
+		var sb strings.Builder
+		i.db.Dialector.QuoteTo(&sb, name)
+		return sb.String()
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", quote(i.name), quote(i.table), quote(i.column))
+}
+
+// queryIndexes returns the indexes of the query fields of the resource, in the order of
+// the fields of the model
+func (t migrationTarget) queryIndexes() []queryIndex {
+	wanted := make(map[string]bool)
+	for _, name := range t.resource.GetSearchable() {
+		wanted[name] = true
+	}
+	if sort := t.resource.GetDefaultSort(); sort != nil {
+		wanted[sort.Field] = true
+	}
+	// Restricted field lists are indexed; the default lists have the fields with a column
+	fields := 0
+	for _, field := range t.resource.GetFields() {
+		if _, ok := field.Column(); ok {
+			fields++
+		}
+	}
+	if filterable := t.resource.GetFilterableFields(); len(filterable) < fields {
+		for _, name := range filterable {
+			wanted[name] = true
+		}
+	}
+	if sortable := t.resource.GetSortableFields(); len(sortable) < fields {
+		for _, name := range sortable {
+			wanted[name] = true
+		}
+	}
+
+	// Flattened value object fields are wanted by column
+	for _, field := range t.resource.GetFields() {
+		if column, ok := field.Column(); ok && wanted[field.Name] {
+			wanted[column] = true
+		}
+	}
+
+	// Columns starting a declared index need no other
+	indexed := make(map[string]bool)
+	for _, index := range t.schema.ParseIndexes() {
+		if len(index.Fields) > 0 {
+			indexed[index.Fields[0].DBName] = true
+		}
+	}
+
+	var indexes []queryIndex
+	for _, field := range t.schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.Unique || field.Serializer != nil || indexed[field.DBName] {
+			continue
+		}
+		if !wanted[field.Name] && !wanted[field.DBName] && !wantedFold(wanted, field.Name) {
+			continue
+		}
+		indexed[field.DBName] = true
+		indexes = append(indexes, queryIndex{
+			name:   fmt.Sprintf("idx_%s_%s", t.schema.Table, field.DBName),
+			table:  t.schema.Table,
+			column: field.DBName,
+			db:     t.db,
+		})
+	}
+	return indexes
+}
+
+// wantedFold reports whether a field name is wanted ignoring case (e.g. the JSON name
+// "createdAt" of CreatedAt)
+func wantedFold(wanted map[string]bool, name string) bool {
+	for wantedName := range wanted {
+		if strings.EqualFold(wantedName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlRecorder is a GORM logger recording the statements of a dry run
+type sqlRecorder struct {
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface { return r }
+
+func (r *sqlRecorder) Info(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+// Trace records the statement
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
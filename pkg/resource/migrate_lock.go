@@ -0,0 +1,273 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// States of a migration coordinator
+const (
+	MigrationPending   = "pending"
+	MigrationWaiting   = "waiting"
+	MigrationRunning   = "migrating"
+	MigrationCompleted = "completed"
+	MigrationFailed    = "failed"
+)
+
+// DefaultMigrationLockName is the name of the lock serializing migrations
+const DefaultMigrationLockName = "refine_gin_migrations"
+
+// ErrMigrationLockTimeout is returned when the migration lock is held by another
+// replica for longer than MigrationCoordinator.LockTimeout
+var ErrMigrationLockTimeout = errors.New("timed out waiting for the migration lock")
+
+// MigrationStatus is the progress of the migrations of a replica
+type MigrationStatus struct {
+	State      string     `json:"state"`
+	Steps      int        `json:"steps"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Ready reports whether the migrations are completed
+func (s MigrationStatus) Ready() bool {
+	return s.State == MigrationCompleted
+}
+
+// MigrationCoordinator serializes the migrations of replicas booting at once, which
+// would otherwise run AutoMigrate concurrently and fail on each other's tables and
+// indexes. Migrate takes a database lock (an advisory lock on PostgreSQL, GET_LOCK on
+// MySQL, a row of a lock table on other databases), then migrates unless a replica
+// holding the lock before did it already. Status reports the progress, e.g. for a
+// health endpoint.
+type MigrationCoordinator struct {
+	DB *gorm.DB
+
+	// Resources to migrate (default: the registered resources)
+	Resources []Resource
+
+	// LockName identifies the lock (default DefaultMigrationLockName)
+	LockName string
+
+	// LockTimeout is the maximum wait for the lock (default 5 minutes)
+	LockTimeout time.Duration
+
+	// PollInterval is the wait between attempts to take the lock (default 1 second)
+	PollInterval time.Duration
+
+	// StaleAfter releases lock table rows older than it, left by replicas that died
+	// while migrating (default 10 minutes). Advisory locks are released by the
+	// database when their connection closes.
+	StaleAfter time.Duration
+
+	mu     sync.RWMutex
+	status MigrationStatus
+}
+
+// NewMigrationCoordinator creates a coordinator migrating resources (all registered
+// resources if none are given)
+func NewMigrationCoordinator(db *gorm.DB, resources ...Resource) *MigrationCoordinator {
+	return &MigrationCoordinator{DB: db, Resources: resources, status: MigrationStatus{State: MigrationPending}}
+}
+
+// Status returns the progress of the migrations
+func (m *MigrationCoordinator) Status() MigrationStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.status.State == "" {
+		return MigrationStatus{State: MigrationPending}
+	}
+	return m.status
+}
+
+func (m *MigrationCoordinator) setStatus(update func(status *MigrationStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	update(&m.status)
+}
+
+// Migrate waits for the migration lock, migrates the resources (see AutoMigrateAll)
+// and releases the lock. Replicas taking the lock after another migrated find nothing
+// to do.
+func (m *MigrationCoordinator) Migrate(ctx context.Context) (err error) {
+	started := time.Now()
+	m.setStatus(func(status *MigrationStatus) {
+		*status = MigrationStatus{State: MigrationWaiting, StartedAt: &started}
+	})
+	defer func() {
+		finished := time.Now()
+		m.setStatus(func(status *MigrationStatus) {
+			status.FinishedAt = &finished
+			if err != nil {
+				status.State = MigrationFailed
+				status.Error = err.Error()
+			} else {
+				status.State = MigrationCompleted
+			}
+		})
+	}()
+
+	release, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := release(); err == nil && releaseErr != nil {
+			err = fmt.Errorf("release migration lock: %w", releaseErr)
+		}
+	}()
+
+	m.setStatus(func(status *MigrationStatus) { status.State = MigrationRunning })
+	plan, err := PlanMigration(m.DB.WithContext(ctx), m.Resources...)
+	if err != nil {
+		return err
+	}
+	if len(plan.Steps) == 0 {
+		return nil
+	}
+	if err := AutoMigrateAll(m.DB.WithContext(ctx), m.Resources...); err != nil {
+		return err
+	}
+	m.setStatus(func(status *MigrationStatus) { status.Steps = len(plan.Steps) })
+	return nil
+}
+
+// lock waits for the migration lock and returns its release
+func (m *MigrationCoordinator) lock(ctx context.Context) (func() error, error) {
+	name := m.LockName
+	if name == "" {
+		name = DefaultMigrationLockName
+	}
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var try func(ctx context.Context) (bool, func() error, error)
+	switch m.DB.Dialector.Name() {
+	case "postgres", "mysql":
+		try = func(ctx context.Context) (bool, func() error, error) {
+			return m.tryAdvisoryLock(ctx, name)
+		}
+	default:
+		if err := m.DB.WithContext(ctx).AutoMigrate(&migrationLock{}); err != nil {
+			return nil, fmt.Errorf("create migration lock table: %w", err)
+		}
+		owner := lockOwner()
+		try = func(ctx context.Context) (bool, func() error, error) {
+			return m.tryTableLock(ctx, name, owner)
+		}
+	}
+
+	for {
+		acquired, release, err := try(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("take migration lock: %w", err)
+		}
+		if acquired {
+			return release, nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrMigrationLockTimeout
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// tryAdvisoryLock takes a session lock of PostgreSQL or MySQL on a dedicated
+// connection, which the lock belongs to until it is released
+func (m *MigrationCoordinator) tryAdvisoryLock(ctx context.Context, name string) (bool, func() error, error) {
+	sqlDB, err := m.DB.DB()
+	if err != nil {
+		return false, nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var lockSQL, unlockSQL string
+	var key interface{}
+	if m.DB.Dialector.Name() == "postgres" {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		lockSQL, unlockSQL, key = "SELECT pg_try_advisory_lock($1)", "SELECT pg_advisory_unlock($1)", int64(h.Sum64())
+	} else {
+		lockSQL, unlockSQL, key = "SELECT GET_LOCK(?, 0) = 1", "SELECT RELEASE_LOCK(?)", name
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, lockSQL, key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return false, nil, err
+	}
+	return true, func() error {
+		defer conn.Close()
+		var released sql.NullBool
+		// The lock is released once done, even if the migration was cancelled
+		return conn.QueryRowContext(context.Background(), unlockSQL, key).Scan(&released)
+	}, nil
+}
+
+// migrationLock is a row of the lock table of databases without advisory locks
+type migrationLock struct {
+	Name       string `gorm:"primaryKey;size:191"`
+	Owner      string `gorm:"size:191"`
+	AcquiredAt time.Time
+}
+
+func (migrationLock) TableName() string {
+	return "refine_migration_locks"
+}
+
+// tryTableLock inserts the row of the lock, which fails while another replica holds it
+func (m *MigrationCoordinator) tryTableLock(ctx context.Context, name, owner string) (bool, func() error, error) {
+	db := m.DB.WithContext(ctx)
+	staleAfter := m.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = 10 * time.Minute
+	}
+	if err := db.Where("name = ? AND acquired_at < ?", name, time.Now().Add(-staleAfter)).Delete(&migrationLock{}).Error; err != nil {
+		return false, nil, err
+	}
+
+	// Failed inserts are expected while the lock is held
+	quiet := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+	if err := quiet.Create(&migrationLock{Name: name, Owner: owner, AcquiredAt: time.Now()}).Error; err != nil {
+		var held int64
+		if countErr := db.Model(&migrationLock{}).Where("name = ?", name).Count(&held).Error; countErr != nil || held == 0 {
+			return false, nil, err
+		}
+		return false, nil, nil
+	}
+	return true, func() error {
+		return m.DB.Where("name = ? AND owner = ?", name, owner).Delete(&migrationLock{}).Error
+	}, nil
+}
+
+// lockOwner identifies the replica holding a lock
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return host + "/" + uuid.NewString()
+}
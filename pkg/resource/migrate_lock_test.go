@@ -0,0 +1,50 @@
+package resource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrationCoordinator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	articles := NewResource(ResourceConfig{Name: "migrated_articles", Model: MigratedArticle{}})
+	ctx := context.Background()
+
+	first := NewMigrationCoordinator(db, articles)
+	assert.Equal(t, MigrationPending, first.Status().State)
+	assert.False(t, first.Status().Ready())
+
+	require.NoError(t, first.Migrate(ctx))
+	status := first.Status()
+	assert.True(t, status.Ready())
+	assert.Positive(t, status.Steps)
+	assert.NotNil(t, status.FinishedAt)
+	assert.True(t, db.Migrator().HasTable(&MigratedArticle{}))
+
+	// The lock is released, and replicas migrating after find nothing to do
+	second := NewMigrationCoordinator(db, articles)
+	require.NoError(t, second.Migrate(ctx))
+	assert.True(t, second.Status().Ready())
+	assert.Zero(t, second.Status().Steps)
+
+	// Replicas wait while another holds the lock
+	require.NoError(t, db.Create(&migrationLock{Name: DefaultMigrationLockName, Owner: "other", AcquiredAt: time.Now()}).Error)
+	waiting := NewMigrationCoordinator(db, articles)
+	waiting.LockTimeout = 50 * time.Millisecond
+	waiting.PollInterval = 10 * time.Millisecond
+	assert.ErrorIs(t, waiting.Migrate(ctx), ErrMigrationLockTimeout)
+	assert.Equal(t, MigrationFailed, waiting.Status().State)
+	assert.NotEmpty(t, waiting.Status().Error)
+
+	// Locks left by dead replicas expire
+	waiting.StaleAfter = time.Nanosecond
+	require.NoError(t, waiting.Migrate(ctx))
+	assert.True(t, waiting.Status().Ready())
+}
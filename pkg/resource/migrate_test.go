@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type MigratedArticle struct {
+	ID       uint `gorm:"primaryKey"`
+	Title    string
+	Status   string
+	Slug     string `gorm:"uniqueIndex"`
+	AuthorID uint   `gorm:"index"`
+	Body     string
+}
+
+// migratedArticleV1 is the table of MigratedArticle before Status and the index of
+// AuthorID were added
+type migratedArticleV1 struct {
+	ID       uint `gorm:"primaryKey"`
+	Title    string
+	Slug     string `gorm:"uniqueIndex"`
+	AuthorID uint
+	Body     string
+}
+
+func (migratedArticleV1) TableName() string {
+	return "migrated_articles"
+}
+
+func TestPlanMigration(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	articles := NewResource(ResourceConfig{
+		Name:             "migrated_articles",
+		Model:            MigratedArticle{},
+		SearchableFields: []string{"Title"},
+		FilterableFields: []string{"status", "author_id"},
+		DefaultSort:      &Sort{Field: "Title", Order: "asc"},
+	})
+
+	// Missing tables are created
+	plan, err := PlanMigration(db, articles)
+	require.NoError(t, err)
+	require.NotEmpty(t, plan.Steps)
+	assert.Equal(t, MigrationCreateTable, plan.Steps[0].Kind)
+	assert.Contains(t, plan.String(), "CREATE TABLE `migrated_articles`")
+	assert.False(t, db.Migrator().HasTable(&MigratedArticle{}), "plans don't migrate")
+
+	// Missing columns and indexes are added
+	require.NoError(t, db.AutoMigrate(&migratedArticleV1{}))
+	plan, err = PlanMigration(db, articles)
+	require.NoError(t, err)
+	var steps []string
+	for _, step := range plan.Steps {
+		steps = append(steps, step.Kind+" "+step.Name)
+	}
+	assert.Equal(t, []string{
+		"add_column status",
+		"add_index idx_migrated_articles_author_id",
+		"add_index idx_migrated_articles_title",
+		"add_index idx_migrated_articles_status",
+	}, steps)
+	assert.Contains(t, plan.String(), "ALTER TABLE `migrated_articles` ADD `status` text;\n")
+	assert.Contains(t, plan.String(), "CREATE INDEX `idx_migrated_articles_title` ON `migrated_articles` (`title`);\n")
+
+	// Applying the plan leaves nothing to do
+	require.NoError(t, AutoMigrateAll(db, articles))
+	assert.True(t, db.Migrator().HasIndex(&MigratedArticle{}, "idx_migrated_articles_status"))
+	plan, err = PlanMigration(db, articles)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Steps)
+
+	// Resources without a struct model are skipped
+	plan, err = PlanMigration(db, NewResource(ResourceConfig{Name: "empty", Model: struct{}{}}))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Steps)
+}
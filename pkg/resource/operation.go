@@ -23,6 +23,18 @@ const (
 	// OperationCount represents the COUNT operation for counting resources
 	OperationCount Operation = "count"
 
+	// OperationFacets represents the FACETS operation returning filter value counts (GET /resources/facets)
+	OperationFacets Operation = "facets"
+
+	// OperationAggregate represents the AGGREGATE operation returning grouped metrics (GET /resources/aggregate)
+	OperationAggregate Operation = "aggregate"
+
+	// OperationImport represents the CSV IMPORT operation (POST /resources/import/inspect and /resources/import/run)
+	OperationImport Operation = "import"
+
+	// OperationExport represents the EXPORT operation streaming filtered records as CSV, XLSX or JSON (GET /resources/export)
+	OperationExport Operation = "export"
+
 	// Bulk operations compatible with Refine.dev
 
 	// OperationCreateMany represents bulk CREATE operation (POST /resources/batch)
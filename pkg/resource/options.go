@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"github.com/gin-gonic/gin"
 	"github.com/suranig/refine-gin/pkg/naming"
 )
 
@@ -23,6 +24,25 @@ func DefaultCacheOptions() CacheOptions {
 	}
 }
 
+// HTTPCacheOptions configures conditional GETs of the list and read endpoints of a
+// resource (see middleware.HTTPCache)
+type HTTPCacheOptions struct {
+	// Enabled turns on ETags, 304 Not Modified responses and Cache-Control headers
+	Enabled bool
+
+	// MaxAge is the time in seconds clients may reuse responses by operation
+	// (OperationList, OperationRead) without revalidating them. Operations without one
+	// are revalidated on every request.
+	MaxAge map[Operation]int
+
+	// Public lets shared caches store responses, which are private by default
+	Public bool
+
+	// VersionField is the field whose latest value versions lists and records (default
+	// updated_at if the model has it). Without one, ETags hash the response bodies.
+	VersionField string
+}
+
 // Options holds global configuration for resource
 type Options struct {
 	// Operations that are allowed for this resource
@@ -33,6 +53,15 @@ type Options struct {
 	NamingConvention naming.NamingConvention
 	// Cache options for resource
 	Cache CacheOptions
+	// HTTPCache configures ETags and 304 responses of list and read endpoints
+	HTTPCache HTTPCacheOptions
+	// StableJSON emits response fields in metadata order and other keys sorted
+	StableJSON bool
+	// Strict rejects unknown query parameters and body fields with 400
+	Strict bool
+	// HandlerOverrides replace the generated handlers of operations, keeping their
+	// routes and middleware
+	HandlerOverrides map[Operation]gin.HandlerFunc
 }
 
 // DefaultOptions returns default options
@@ -100,6 +129,43 @@ func (o Options) WithCacheVaryHeaders(headers []string) Options {
 	return o
 }
 
+// WithHTTPCache sets the HTTP caching of list and read endpoints
+func (o Options) WithHTTPCache(options HTTPCacheOptions) Options {
+	o.HTTPCache = options
+	return o
+}
+
+// WithStableJSON enables or disables deterministic JSON key ordering
+func (o Options) WithStableJSON(enabled bool) Options {
+	o.StableJSON = enabled
+	return o
+}
+
+// WithStrict enables or disables rejecting unknown query parameters and body fields
+func (o Options) WithStrict(enabled bool) Options {
+	o.Strict = enabled
+	return o
+}
+
+// WithHandlerOverride replaces the generated handler of an operation. The route, the
+// resource middleware and the metadata stay the same; for OperationUpdate the handler
+// serves both PUT and PATCH.
+func (o Options) WithHandlerOverride(op Operation, handler gin.HandlerFunc) Options {
+	overrides := make(map[Operation]gin.HandlerFunc, len(o.HandlerOverrides)+1)
+	for existing, h := range o.HandlerOverrides {
+		overrides[existing] = h
+	}
+	overrides[op] = handler
+	o.HandlerOverrides = overrides
+	return o
+}
+
+// GetHandlerOverride returns the handler replacing the generated handler of an
+// operation, or nil if not set
+func (o Options) GetHandlerOverride(op Operation) gin.HandlerFunc {
+	return o.HandlerOverrides[op]
+}
+
 // GetQueryOption returns the value of a query option, or nil if not set
 func (o Options) GetQueryOption(key string) interface{} {
 	if value, exists := o.QueryOptions[key]; exists {
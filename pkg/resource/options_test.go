@@ -3,6 +3,7 @@ package resource
 import (
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/suranig/refine-gin/pkg/naming"
 )
@@ -220,3 +221,15 @@ func TestGetQueryOptionReturnsNilForMissingKey(t *testing.T) {
 
 	assert.Nil(t, opts.GetQueryOption("limit"))
 }
+
+func TestWithHandlerOverride(t *testing.T) {
+	list := func(c *gin.Context) {}
+	opts := DefaultOptions()
+	result := opts.WithHandlerOverride(OperationList, list)
+
+	assert.NotNil(t, result.GetHandlerOverride(OperationList))
+	assert.Nil(t, result.GetHandlerOverride(OperationRead))
+
+	// The original options are left unchanged
+	assert.Nil(t, opts.GetHandlerOverride(OperationList))
+}
@@ -2,6 +2,8 @@ package resource
 
 import (
 	"reflect"
+
+	"github.com/suranig/refine-gin/pkg/idgen"
 )
 
 // OwnerConfig contains configuration for creating owner-based resources
@@ -14,6 +16,12 @@ type OwnerConfig struct {
 
 	// Default owner ID to use if none is provided in the context
 	DefaultOwnerID interface{}
+
+	// GroupField is the field of the model storing the group (team, organization)
+	// owning a record. Records are then accessible to their owner and to the members
+	// of their group, whose group IDs are resolved by middleware.OwnerGroups. Records
+	// can only be given to groups of their owner.
+	GroupField string
 }
 
 // DefaultOwnerConfig returns a default owner configuration
@@ -62,6 +70,15 @@ func NewOwnerResource(res Resource, config OwnerConfig) OwnerResource {
 			panic("Owner field '" + config.OwnerField + "' not found in model " + modelType.Name())
 		}
 	}
+	if config.GroupField != "" {
+		modelType := reflect.TypeOf(res.GetModel())
+		if modelType.Kind() == reflect.Ptr {
+			modelType = modelType.Elem()
+		}
+		if _, found := modelType.FieldByName(config.GroupField); !found {
+			panic("Group field '" + config.GroupField + "' not found in model " + modelType.Name())
+		}
+	}
 
 	return &DefaultOwnerResource{
 		Resource: res,
@@ -89,6 +106,132 @@ func (r *DefaultOwnerResource) GetOwnerConfig() OwnerConfig {
 	return r.Config
 }
 
+// GetSoftDeleteField returns the soft delete field of the wrapped resource, or ""
+func (r *DefaultOwnerResource) GetSoftDeleteField() string {
+	if softDeleted, ok := r.Resource.(SoftDeleteResource); ok {
+		return softDeleted.GetSoftDeleteField()
+	}
+	return ""
+}
+
+// GetBodySchema returns the JSON Schema of request bodies of the wrapped resource, or nil
+func (r *DefaultOwnerResource) GetBodySchema() map[string]interface{} {
+	if schemaRes, ok := r.Resource.(BodySchemaResource); ok {
+		return schemaRes.GetBodySchema()
+	}
+	return nil
+}
+
+// GetVersionField returns the version field of the wrapped resource, or ""
+func (r *DefaultOwnerResource) GetVersionField() string {
+	if versioned, ok := r.Resource.(VersionedResource); ok {
+		return versioned.GetVersionField()
+	}
+	return ""
+}
+
+// GetIDGenerator returns the ID generator of the wrapped resource, or nil
+func (r *DefaultOwnerResource) GetIDGenerator() idgen.Generator {
+	if generated, ok := r.Resource.(IDGeneratorResource); ok {
+		return generated.GetIDGenerator()
+	}
+	return nil
+}
+
+// IsSortTiebreakerDisabled reports whether lists of the wrapped resource are sorted
+// without the ID tiebreaker
+func (r *DefaultOwnerResource) IsSortTiebreakerDisabled() bool {
+	if sorted, ok := r.Resource.(SortTiebreakerResource); ok {
+		return sorted.IsSortTiebreakerDisabled()
+	}
+	return false
+}
+
+// GetUniqueFields returns the unique fields of the wrapped resource
+func (r *DefaultOwnerResource) GetUniqueFields() []string {
+	if declared, ok := r.Resource.(UniqueFieldsResource); ok {
+		return declared.GetUniqueFields()
+	}
+	return nil
+}
+
+// GetSearchableRelationFields returns the searched fields of related records of the
+// wrapped resource
+func (r *DefaultOwnerResource) GetSearchableRelationFields() []string {
+	if searchable, ok := r.Resource.(SearchableRelationsResource); ok {
+		return searchable.GetSearchableRelationFields()
+	}
+	return nil
+}
+
+// GetFieldChecks returns the server-side field checks of the wrapped resource, or nil
+func (r *DefaultOwnerResource) GetFieldChecks() map[string]FieldCheck {
+	if checked, ok := r.Resource.(FieldCheckResource); ok {
+		return checked.GetFieldChecks()
+	}
+	return nil
+}
+
+// GetDTOConfig returns the DTOs of the wrapped resource
+func (r *DefaultOwnerResource) GetDTOConfig() DTOConfig {
+	if declared, ok := r.Resource.(DTOResource); ok {
+		return declared.GetDTOConfig()
+	}
+	return DTOConfig{}
+}
+
+// GetHooks returns the lifecycle hooks of the wrapped resource, or nil
+func (r *DefaultOwnerResource) GetHooks() *LifecycleHooks {
+	if hooked, ok := r.Resource.(LifecycleHookResource); ok {
+		return hooked.GetHooks()
+	}
+	return nil
+}
+
+// GetCountMode returns how lists of the wrapped resource are counted
+func (r *DefaultOwnerResource) GetCountMode() CountMode {
+	if counted, ok := r.Resource.(CountModeResource); ok {
+		return counted.GetCountMode()
+	}
+	return CountExact
+}
+
+// GetMaxPageSize returns the page size cap of lists of the wrapped resource, 0 if
+// unlimited
+func (r *DefaultOwnerResource) GetMaxPageSize() int {
+	if capped, ok := r.Resource.(PageSizeResource); ok {
+		return capped.GetMaxPageSize()
+	}
+	return 0
+}
+
+// GetQueryValidation returns how sorts and filters of lists of the wrapped resource
+// are validated
+func (r *DefaultOwnerResource) GetQueryValidation() QueryValidation {
+	if validated, ok := r.Resource.(QueryValidationResource); ok {
+		return validated.GetQueryValidation()
+	}
+	return QueryValidationLenient
+}
+
+// GetResponseTransformer returns the transformer of response records of the wrapped
+// resource, or nil
+func (r *DefaultOwnerResource) GetResponseTransformer() ResponseTransformer {
+	if transformed, ok := r.Resource.(ResponseTransformerResource); ok {
+		return transformed.GetResponseTransformer()
+	}
+	return nil
+}
+
+// GetResponseListTransformer returns the transformer of list responses of the wrapped
+// resource, or nil
+func (r *DefaultOwnerResource) GetResponseListTransformer() ResponseListTransformer {
+	if transformed, ok := r.Resource.(ResponseTransformerResource); ok {
+		return transformed.GetResponseListTransformer()
+	}
+	return nil
+}
+
 // PromoteToOwnerResource converts a regular resource to an owner resource with default configuration
 func PromoteToOwnerResource(res Resource) OwnerResource {
 	if ownerRes, ok := res.(OwnerResource); ok {
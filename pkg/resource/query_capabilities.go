@@ -0,0 +1,88 @@
+package resource
+
+// QueryCapabilitiesMetadata describes the query parameters the lists of a resource
+// understand, so generic frontends and SDK generators can build query UIs without
+// hardcoding the conventions of the framework
+type QueryCapabilitiesMetadata struct {
+	// Params are the names of the query parameters
+	Params QueryParamsMetadata `json:"params"`
+
+	// Filters are the filterable fields with their operators (filter[field][operator])
+	Filters []FilterCapabilityMetadata `json:"filters"`
+
+	// RelationFilters are the relations whose fields are filterable as relation.field
+	// (filter[tags.name][eq])
+	RelationFilters []string `json:"relationFilters,omitempty"`
+
+	// CustomOperators are the registered custom filter operators, which may reject
+	// some fields
+	CustomOperators []string `json:"customOperators,omitempty"`
+
+	// Sort describes the sortable fields
+	Sort SortCapabilityMetadata `json:"sort"`
+
+	// Pagination describes the page sizes and count modes
+	Pagination PaginationCapabilityMetadata `json:"pagination"`
+
+	// Includes are the relations loadable with the include parameter
+	Includes []string `json:"includes,omitempty"`
+
+	// Search describes the search parameter, nil when nothing is searchable
+	Search *SearchCapabilityMetadata `json:"search,omitempty"`
+}
+
+// QueryParamsMetadata are the names of the query parameters of lists
+type QueryParamsMetadata struct {
+	Page      string `json:"page"`
+	PageSize  string `json:"pageSize"`
+	Sort      string `json:"sort"`
+	Order     string `json:"order"`
+	Search    string `json:"search"`
+	Fields    string `json:"fields"`
+	Include   string `json:"include"`
+	WithCount string `json:"withCount"`
+	Timezone  string `json:"timezone"`
+}
+
+// FilterCapabilityMetadata is a filterable field with its operators
+type FilterCapabilityMetadata struct {
+	Field     string   `json:"field"`
+	Type      string   `json:"type"`
+	Operators []string `json:"operators"`
+}
+
+// SortCapabilityMetadata describes the sorts of lists
+type SortCapabilityMetadata struct {
+	Fields  []string `json:"fields"`
+	Default *Sort    `json:"default,omitempty"`
+
+	// Multiple is set when lists sort by several fields (sort=a,b&order=asc,desc)
+	Multiple bool `json:"multiple"`
+}
+
+// PaginationCapabilityMetadata describes the pagination of lists
+type PaginationCapabilityMetadata struct {
+	DefaultPageSize int `json:"defaultPageSize"`
+
+	// MaxPageSize caps the page size, unlimited if zero
+	MaxPageSize int `json:"maxPageSize,omitempty"`
+
+	// CountModes are the values of the withCount parameter
+	CountModes       []CountMode `json:"countModes"`
+	DefaultCountMode CountMode   `json:"defaultCountMode"`
+}
+
+// SearchCapabilityMetadata describes the search parameter of lists
+type SearchCapabilityMetadata struct {
+	// Fields are the searched fields, and RelationFields those of related records
+	Fields         []string `json:"fields"`
+	RelationFields []string `json:"relationFields,omitempty"`
+
+	// Match is how the term matches the fields ("contains")
+	Match string `json:"match"`
+}
+
+// PageSizeResource is implemented by resources capping the page size of lists
+type PageSizeResource interface {
+	GetMaxPageSize() int
+}
@@ -0,0 +1,21 @@
+package resource
+
+// QueryValidation is how lists treat sorts and filters on fields that are not sortable
+// or filterable
+type QueryValidation string
+
+const (
+	// QueryValidationLenient drops sorts and filters on fields that are not sortable or
+	// filterable (the default). Applied query echoes list them as ignored.
+	QueryValidationLenient QueryValidation = "lenient"
+
+	// QueryValidationStrict rejects queries sorting or filtering on fields that are not
+	// sortable or filterable with a 400 naming the fields
+	QueryValidationStrict QueryValidation = "strict"
+)
+
+// QueryValidationResource is implemented by resources configuring how the sorts and
+// filters of lists are validated
+type QueryValidationResource interface {
+	GetQueryValidation() QueryValidation
+}
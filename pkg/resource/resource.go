@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/suranig/refine-gin/pkg/idgen"
 	"github.com/suranig/refine-gin/pkg/utils"
 )
 
@@ -85,6 +86,140 @@ type ResourceConfig struct {
 	RequiredFields   []string
 	UniqueFields     []string
 	EditableFields   []string // Fields that can be edited
+
+	// SearchableRelationFields extend the search (q) to fields of related records, as
+	// relation.field pairs (e.g. "customer.name"). Only these fields are searched, in
+	// to-one relations joined with LEFT JOINs.
+	SearchableRelationFields []string
+
+	// SoftDeleteField names a nullable time field marking deleted records. Models with
+	// a gorm.DeletedAt field are detected without it.
+	SoftDeleteField string
+
+	// BodySchema is a JSON Schema (draft 2020-12) the bodies of create and update
+	// requests are validated against
+	BodySchema map[string]interface{}
+
+	// VersionField names an integer or time field used for optimistic locking. Updates
+	// carrying a stale version (in the body or an If-Match header) are rejected.
+	VersionField string
+
+	// IDGenerator generates the IDs of created records whose ID is not set, e.g.
+	// idgen.NewUUIDv7(), idgen.NewULID() or a snowflake generator with a node ID per
+	// instance, so records created by different writers never collide.
+	// idgen.NewSequence reads them from a database sequence instead.
+	IDGenerator idgen.Generator
+
+	// DisableSortTiebreaker stops list queries from ordering by the ID after the
+	// requested sort
+	DisableSortTiebreaker bool
+
+	// CountMode is how list responses count the records (CountExact if empty), e.g.
+	// CountNone for huge tables. Requests choose another mode with ?withCount.
+	CountMode CountMode
+
+	// MaxPageSize caps the page size of lists (unlimited if zero); larger requested
+	// page sizes are lowered to it
+	MaxPageSize int
+
+	// QueryValidation is how lists treat sorts and filters on fields missing from
+	// SortableFields and FilterableFields (QueryValidationLenient if empty)
+	QueryValidation QueryValidation
+
+	// Hooks run by the generic handlers around creates, updates, deletes and lists
+	Hooks *LifecycleHooks
+
+	// FieldChecks are server-side checks of field values run by the validation
+	// endpoint (POST /<resource>/validate/:field), keyed by field name, e.g. whether a
+	// username is reserved
+	FieldChecks map[string]FieldCheck
+
+	// CreateDTO, UpdateDTO and ResponseDTO are the request and response models of the
+	// resource (optional, e.g. CreateUserDTO{}). Requests are bound and validated
+	// against them before being mapped to the model.
+	CreateDTO   interface{}
+	UpdateDTO   interface{}
+	ResponseDTO interface{}
+
+	// AutoDTO generates the DTOs not declared above from the model: read-only and
+	// hidden fields are left out of requests, hidden fields out of responses, and
+	// update DTOs only change the fields present in the request
+	AutoDTO bool
+
+	// ResponseTransformer reshapes each record of list and get responses, after the
+	// DTO transformation, e.g. to compute display fields or mask personal data
+	ResponseTransformer ResponseTransformer
+
+	// ResponseListTransformer reshapes the records of list responses at once, after
+	// the ResponseTransformer
+	ResponseListTransformer ResponseListTransformer
+}
+
+// SoftDeleteResource is implemented by resources configuring a soft delete field
+type SoftDeleteResource interface {
+	GetSoftDeleteField() string
+}
+
+// BodySchemaResource is implemented by resources validating request bodies against a
+// JSON Schema
+type BodySchemaResource interface {
+	GetBodySchema() map[string]interface{}
+}
+
+// VersionedResource is implemented by resources configuring a version field for
+// optimistic locking
+type VersionedResource interface {
+	GetVersionField() string
+}
+
+// IDGeneratorResource is implemented by resources generating the IDs of created records
+type IDGeneratorResource interface {
+	GetIDGenerator() idgen.Generator
+}
+
+// SortTiebreakerResource is implemented by resources configuring the ID tiebreaker of
+// list queries
+type SortTiebreakerResource interface {
+	IsSortTiebreakerDisabled() bool
+}
+
+// LifecycleHookResource is implemented by resources configuring lifecycle hooks
+type LifecycleHookResource interface {
+	GetHooks() *LifecycleHooks
+}
+
+// UniqueFieldsResource is implemented by resources declaring unique fields
+type UniqueFieldsResource interface {
+	GetUniqueFields() []string
+}
+
+// SearchableRelationsResource is implemented by resources searching fields of related
+// records
+type SearchableRelationsResource interface {
+	GetSearchableRelationFields() []string
+}
+
+// FieldCheckResource is implemented by resources configuring server-side field checks
+type FieldCheckResource interface {
+	GetFieldChecks() map[string]FieldCheck
+}
+
+// DTOResource is implemented by resources declaring request and response models
+type DTOResource interface {
+	GetDTOConfig() DTOConfig
+}
+
+// DTOConfig contains the DTOs of a resource (see ResourceConfig.CreateDTO)
+type DTOConfig struct {
+	CreateDTO   interface{}
+	UpdateDTO   interface{}
+	ResponseDTO interface{}
+	AutoDTO     bool
+}
+
+// IsZero reports whether no DTO is declared
+func (c DTOConfig) IsZero() bool {
+	return c.CreateDTO == nil && c.UpdateDTO == nil && c.ResponseDTO == nil && !c.AutoDTO
 }
 
 // DefaultResource implements the Resource interface
@@ -112,6 +247,49 @@ type DefaultResource struct {
 	UniqueFields     []string
 	EditableFields   []string // Fields that can be edited
 
+	// Searched fields of related records (optional, see ResourceConfig.SearchableRelationFields)
+	SearchableRelationFields []string
+
+	// Soft delete field (optional, see ResourceConfig.SoftDeleteField)
+	SoftDeleteField string
+
+	// JSON Schema of request bodies (optional, see ResourceConfig.BodySchema)
+	BodySchema map[string]interface{}
+
+	// Optimistic locking version field (optional, see ResourceConfig.VersionField)
+	VersionField string
+
+	// ID generator of created records (optional, see ResourceConfig.IDGenerator)
+	IDGenerator idgen.Generator
+
+	// Whether list queries are not ordered by the ID last (see ResourceConfig.DisableSortTiebreaker)
+	DisableSortTiebreaker bool
+
+	// How lists are counted (see ResourceConfig.CountMode)
+	CountMode CountMode
+
+	// Page size cap of lists (see ResourceConfig.MaxPageSize)
+	MaxPageSize int
+
+	// How sorts and filters are validated (see ResourceConfig.QueryValidation)
+	QueryValidation QueryValidation
+
+	// Lifecycle hooks (optional, see ResourceConfig.Hooks)
+	Hooks *LifecycleHooks
+
+	// Server-side field checks (optional, see ResourceConfig.FieldChecks)
+	FieldChecks map[string]FieldCheck
+
+	// Request and response models (optional, see ResourceConfig.CreateDTO)
+	CreateDTO   interface{}
+	UpdateDTO   interface{}
+	ResponseDTO interface{}
+	AutoDTO     bool
+
+	// Response transformers (optional, see ResourceConfig.ResponseTransformer)
+	ResponseTransformer     ResponseTransformer
+	ResponseListTransformer ResponseListTransformer
+
 	// Form layout configuration
 	FormLayout *FormLayout
 }
@@ -195,17 +373,21 @@ func NewResource(config ResourceConfig) Resource {
 	// Set default field lists if not provided
 	filterableFields := config.FilterableFields
 	if len(filterableFields) == 0 {
-		// By default, all fields are filterable
+		// By default, all fields with a column are filterable
 		for _, f := range fields {
-			filterableFields = append(filterableFields, f.Name)
+			if _, ok := f.Column(); ok {
+				filterableFields = append(filterableFields, f.Name)
+			}
 		}
 	}
 
 	sortableFields := config.SortableFields
 	if len(sortableFields) == 0 {
-		// By default, all fields are sortable
+		// By default, all fields with a column are sortable
 		for _, f := range fields {
-			sortableFields = append(sortableFields, f.Name)
+			if _, ok := f.Column(); ok {
+				sortableFields = append(sortableFields, f.Name)
+			}
 		}
 	}
 
@@ -270,6 +452,27 @@ func NewResource(config ResourceConfig) Resource {
 		RequiredFields:   requiredFields,
 		UniqueFields:     config.UniqueFields,
 		EditableFields:   editableFields,
+
+		SearchableRelationFields: config.SearchableRelationFields,
+
+		SoftDeleteField: config.SoftDeleteField,
+		BodySchema:      config.BodySchema,
+		VersionField:    config.VersionField,
+		IDGenerator:     config.IDGenerator,
+
+		DisableSortTiebreaker: config.DisableSortTiebreaker,
+		CountMode:             config.CountMode,
+		MaxPageSize:           config.MaxPageSize,
+		QueryValidation:       config.QueryValidation,
+		Hooks:                 config.Hooks,
+		FieldChecks:           config.FieldChecks,
+		CreateDTO:             config.CreateDTO,
+		UpdateDTO:             config.UpdateDTO,
+		ResponseDTO:           config.ResponseDTO,
+		AutoDTO:               config.AutoDTO,
+
+		ResponseTransformer:     config.ResponseTransformer,
+		ResponseListTransformer: config.ResponseListTransformer,
 	}
 }
 
@@ -289,6 +492,12 @@ func GenerateFieldsFromModel(model interface{}) []Field {
 			continue
 		}
 
+		// Value objects are exposed by their fields
+		if storage, prefix, ok := valueObjectStorage(field); ok {
+			fields = append(fields, valueObjectFields(field, storage, prefix)...)
+			continue
+		}
+
 		fieldDef := Field{
 			Name:  field.Name,
 			Type:  field.Type.String(),
@@ -377,6 +586,11 @@ func ParseFieldTag(field *Field, tag string) {
 			continue
 		}
 
+		if strings.HasPrefix(part, "alias=") {
+			field.Alias = part[6:]
+			continue
+		}
+
 		if strings.HasPrefix(part, "placeholder=") {
 			if field.Form == nil {
 				field.Form = &FormConfig{}
@@ -419,6 +633,12 @@ func ParseFieldTag(field *Field, tag string) {
 			continue
 		}
 
+		if strings.HasPrefix(part, "flags=") {
+			field.Type = FieldTypeFlags
+			field.Flags = parseFlags(part[6:])
+			continue
+		}
+
 		// Handle readOnly and hidden tags
 		if part == "readOnly" {
 			field.ReadOnly = true
@@ -766,3 +986,89 @@ func (r *DefaultResource) HasPermission(operation string, role string) bool {
 func (r *DefaultResource) GetFormLayout() *FormLayout {
 	return r.FormLayout
 }
+
+func (r *DefaultResource) GetSoftDeleteField() string {
+	return r.SoftDeleteField
+}
+
+// GetBodySchema returns the JSON Schema of request bodies, or nil
+func (r *DefaultResource) GetBodySchema() map[string]interface{} {
+	return r.BodySchema
+}
+
+// GetVersionField returns the optimistic locking version field, or ""
+func (r *DefaultResource) GetVersionField() string {
+	return r.VersionField
+}
+
+// GetIDGenerator returns the ID generator of created records, or nil
+func (r *DefaultResource) GetIDGenerator() idgen.Generator {
+	return r.IDGenerator
+}
+
+// IsSortTiebreakerDisabled reports whether list queries are not ordered by the ID last
+func (r *DefaultResource) IsSortTiebreakerDisabled() bool {
+	return r.DisableSortTiebreaker
+}
+
+// GetCountMode returns how lists are counted
+func (r *DefaultResource) GetCountMode() CountMode {
+	if r.CountMode == "" {
+		return CountExact
+	}
+	return r.CountMode
+}
+
+// GetMaxPageSize returns the page size cap of lists, 0 if unlimited
+func (r *DefaultResource) GetMaxPageSize() int {
+	return r.MaxPageSize
+}
+
+// GetQueryValidation returns how sorts and filters of lists are validated
+func (r *DefaultResource) GetQueryValidation() QueryValidation {
+	if r.QueryValidation == "" {
+		return QueryValidationLenient
+	}
+	return r.QueryValidation
+}
+
+// GetHooks returns the lifecycle hooks of the resource, or nil
+func (r *DefaultResource) GetHooks() *LifecycleHooks {
+	return r.Hooks
+}
+
+// GetResponseTransformer returns the transformer of response records, or nil
+func (r *DefaultResource) GetResponseTransformer() ResponseTransformer {
+	return r.ResponseTransformer
+}
+
+// GetResponseListTransformer returns the transformer of list responses, or nil
+func (r *DefaultResource) GetResponseListTransformer() ResponseListTransformer {
+	return r.ResponseListTransformer
+}
+
+// GetUniqueFields returns the fields whose values must be unique
+func (r *DefaultResource) GetUniqueFields() []string {
+	return r.UniqueFields
+}
+
+// GetSearchableRelationFields returns the searched fields of related records, as
+// relation.field pairs
+func (r *DefaultResource) GetSearchableRelationFields() []string {
+	return r.SearchableRelationFields
+}
+
+// GetFieldChecks returns the server-side field checks, or nil
+func (r *DefaultResource) GetFieldChecks() map[string]FieldCheck {
+	return r.FieldChecks
+}
+
+// GetDTOConfig returns the request and response models of the resource
+func (r *DefaultResource) GetDTOConfig() DTOConfig {
+	return DTOConfig{
+		CreateDTO:   r.CreateDTO,
+		UpdateDTO:   r.UpdateDTO,
+		ResponseDTO: r.ResponseDTO,
+		AutoDTO:     r.AutoDTO,
+	}
+}
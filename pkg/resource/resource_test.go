@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/suranig/refine-gin/pkg/idgen"
 )
 
 // Example model for testing
@@ -171,6 +172,23 @@ func TestIDFieldName(t *testing.T) {
 	assert.Equal(t, "UID", res.GetIDFieldName())
 }
 
+func TestIDGenerator(t *testing.T) {
+	res := NewResource(ResourceConfig{
+		Name:  "tests",
+		Model: TestUser{},
+	})
+	assert.Nil(t, res.(IDGeneratorResource).GetIDGenerator())
+	assert.Empty(t, GenerateResourceMetadata(res).IDStrategy)
+
+	res = NewResource(ResourceConfig{
+		Name:        "tests",
+		Model:       TestUser{},
+		IDGenerator: idgen.NewKSUID(),
+	})
+	assert.Equal(t, idgen.StrategyKSUID, res.(IDGeneratorResource).GetIDGenerator().Strategy())
+	assert.Equal(t, idgen.StrategyKSUID, GenerateResourceMetadata(res).IDStrategy)
+}
+
 func TestSetCustomID(t *testing.T) {
 	type CustomStringIDStruct struct {
 		UID  string
@@ -0,0 +1,105 @@
+package resource
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SlugConfig configures a slug field generated from another field on create
+type SlugConfig struct {
+	// Source is the field the slug is generated from
+	Source string `json:"source"`
+
+	// Scope lists fields whose values scope uniqueness: slugs only need to be unique
+	// among records with the same values (default: unique in the whole table)
+	Scope []string `json:"scope,omitempty"`
+
+	// Separator replaces spaces and punctuation (default "-")
+	Separator string `json:"separator,omitempty"`
+
+	// MaxLength limits the length of the generated slug, before any numeric suffix
+	// (default 100)
+	MaxLength int `json:"maxLength,omitempty"`
+
+	// Transliterate converts the source text to ASCII (default Transliterate)
+	Transliterate func(text string) string `json:"-"`
+}
+
+// transliterations maps common non-ASCII letters to ASCII
+var transliterations = map[rune]string{
+	'ą': "a", 'ć': "c", 'ę': "e", 'ł': "l", 'ń': "n", 'ó': "o", 'ś': "s", 'ź': "z", 'ż': "z",
+	'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n", 'ň': "n",
+	'ò': "o", 'ô': "o", 'õ': "o", 'ø': "o", 'œ': "oe",
+	'ř': "r", 'š': "s", 'ť': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ů': "u",
+	'ý': "y", 'ÿ': "y", 'ž': "z",
+}
+
+// Transliterate lowercases text and replaces common accented Latin letters with their
+// ASCII equivalents. Other non-ASCII characters are kept.
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if replacement, ok := transliterations[r]; ok {
+			b.WriteString(replacement)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Slugify converts text to a slug: transliterated, lowercase ASCII letters and digits
+// joined by the separator ("Zażółć gęślą jaźń!" → "zazolc-gesla-jazn")
+func Slugify(text string, config SlugConfig) string {
+	separator := config.Separator
+	if separator == "" {
+		separator = "-"
+	}
+	maxLength := config.MaxLength
+	if maxLength <= 0 {
+		maxLength = 100
+	}
+	transliterate := config.Transliterate
+	if transliterate == nil {
+		transliterate = Transliterate
+	}
+
+	var words []string
+	var word strings.Builder
+	for _, r := range strings.ToLower(transliterate(text)) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			word.WriteRune(r)
+			continue
+		}
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	slug := strings.Join(words, separator)
+	if len(slug) > maxLength {
+		slug = strings.TrimRight(slug[:maxLength], separator)
+	}
+	return slug
+}
+
+// SlugFields returns the fields of a resource with a slug configuration
+func SlugFields(res Resource) []Field {
+	var fields []Field
+	for _, field := range res.GetFields() {
+		if field.Slug != nil {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
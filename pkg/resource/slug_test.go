@@ -0,0 +1,52 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text     string
+		config   SlugConfig
+		expected string
+	}{
+		{"Hello, World!", SlugConfig{}, "hello-world"},
+		{"Zażółć gęślą jaźń", SlugConfig{}, "zazolc-gesla-jazn"},
+		{"Straße über Köln", SlugConfig{}, "strasse-ueber-koeln"},
+		{"  --Go 1.23 release--  ", SlugConfig{}, "go-1-23-release"},
+		{"Hello World", SlugConfig{Separator: "_"}, "hello_world"},
+		{"one two three", SlugConfig{MaxLength: 8}, "one-two"},
+		{"日本語", SlugConfig{}, ""},
+		{"ÆON", SlugConfig{Transliterate: strings.ToLower}, "on"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Slugify(tt.text, tt.config), tt.text)
+	}
+}
+
+func TestSlugFields(t *testing.T) {
+	type Post struct {
+		ID    uint
+		Title string
+		Slug  string
+	}
+	res := NewResource(ResourceConfig{
+		Name:  "posts",
+		Model: Post{},
+		Fields: []Field{
+			{Name: "title", Type: "string"},
+			{Name: "slug", Type: "string", Slug: &SlugConfig{Source: "title", Scope: []string{"categoryId"}}},
+		},
+	})
+
+	fields := SlugFields(res)
+	assert.Len(t, fields, 1)
+	assert.Equal(t, "slug", fields[0].Name)
+
+	metadata := GenerateFieldsMetadata(res.GetFields())
+	assert.Equal(t, &SlugConfigMetadata{Source: "title", Scope: []string{"categoryId"}}, metadata[1].Slug)
+}
@@ -0,0 +1,19 @@
+package resource
+
+import "context"
+
+// ResponseTransformer reshapes a record of list and get responses, e.g. to compute
+// display fields, mask personal data or hide internal fields. It receives the record
+// after the DTO transformation and returns the value written in its place.
+type ResponseTransformer func(ctx context.Context, item interface{}) interface{}
+
+// ResponseListTransformer reshapes the records of a list response at once, e.g. to
+// load display data for a whole page with one query. It runs after the
+// ResponseTransformer of each record.
+type ResponseListTransformer func(ctx context.Context, items []interface{}) []interface{}
+
+// ResponseTransformerResource is implemented by resources transforming their responses
+type ResponseTransformerResource interface {
+	GetResponseTransformer() ResponseTransformer
+	GetResponseListTransformer() ResponseListTransformer
+}
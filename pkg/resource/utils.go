@@ -696,6 +696,8 @@ func AutoDetectAntDesignComponent(field *Field) string {
 		return detectSelectComponent(field.Type)
 	case "checkbox":
 		return "Checkbox"
+	case FieldTypeFlags:
+		return "Checkbox.Group"
 	case "radio":
 		return "Radio"
 	default:
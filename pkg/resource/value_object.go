@@ -0,0 +1,141 @@
+package resource
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// Storages of value objects
+const (
+	// ValueObjectColumns stores the fields of a value object in flattened columns of
+	// the record (gorm:"embedded", with an optional embeddedPrefix)
+	ValueObjectColumns = "columns"
+
+	// ValueObjectJSON stores a value object in a JSON column (gorm:"serializer:json",
+	// or a json or jsonb column type). Structs in JSON columns are JSON fields unless
+	// tagged refine:"valueObject".
+	ValueObjectJSON = "json"
+)
+
+// ValueObjectConfig describes a field of a value object: a struct field of the model
+// holding several values, e.g. the Address of a Customer. Its fields are exposed under
+// nested names ("address.city"), while records keep the struct as a nested object.
+type ValueObjectConfig struct {
+	// Parent is the JSON name of the struct field, e.g. "address"
+	Parent string
+
+	// Key is the JSON name of the field in the value object, e.g. "city"
+	Key string
+
+	// Storage is ValueObjectColumns or ValueObjectJSON
+	Storage string
+
+	// Column is the column of a field stored in flattened columns, e.g. "address_city"
+	Column string
+}
+
+// Column returns the column of a field for filters and sorts: its name, or the column
+// of a value object field stored in flattened columns. Fields of value objects stored
+// in JSON columns have none.
+func (f Field) Column() (string, bool) {
+	if f.ValueObject == nil {
+		return f.Name, true
+	}
+	if f.ValueObject.Storage != ValueObjectColumns || f.ValueObject.Column == "" {
+		return "", false
+	}
+	return f.ValueObject.Column, true
+}
+
+// valueObjectStorage returns the storage of a struct field of a model holding a value
+// object, and the prefix of its flattened columns
+func valueObjectStorage(field reflect.StructField) (storage, prefix string, ok bool) {
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Struct || fieldType == reflect.TypeOf(time.Time{}) || field.Anonymous {
+		return "", "", false
+	}
+
+	settings := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")
+	if _, embedded := settings["EMBEDDED"]; embedded {
+		return ValueObjectColumns, settings["EMBEDDEDPREFIX"], true
+	}
+	if _, tagged := schema.ParseTagSetting(field.Tag.Get("refine"), ";")["VALUEOBJECT"]; !tagged {
+		return "", "", false
+	}
+	columnType := strings.ToLower(settings["TYPE"])
+	if strings.EqualFold(settings["SERIALIZER"], "json") || columnType == "json" || columnType == "jsonb" {
+		return ValueObjectJSON, "", true
+	}
+	return "", "", false
+}
+
+// valueObjectFields generates the fields of a value object of a model
+func valueObjectFields(field reflect.StructField, storage, prefix string) []Field {
+	parent := field.Name
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		parent = name
+	}
+	structType := field.Type
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	var fields []Field
+	for i := 0; i < structType.NumField(); i++ {
+		sub := structType.Field(i)
+		if sub.PkgPath != "" {
+			continue
+		}
+		name := sub.Name
+		if tag := strings.Split(sub.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		fieldDef := Field{
+			Name:  parent + "." + name,
+			Type:  sub.Type.String(),
+			Label: field.Name + " " + sub.Name,
+			List: &ListConfig{
+				Width:    200,
+				Ellipsis: true,
+			},
+			Form: &FormConfig{
+				Placeholder: "Enter " + strings.ToLower(sub.Name),
+			},
+			ValueObject: &ValueObjectConfig{Parent: parent, Key: name, Storage: storage},
+		}
+		if storage == ValueObjectColumns {
+			column := schema.ParseTagSetting(sub.Tag.Get("gorm"), ";")["COLUMN"]
+			if column == "" {
+				column = schema.NamingStrategy{}.ColumnName("", sub.Name)
+			}
+			fieldDef.ValueObject.Column = prefix + column
+		}
+		if tag, ok := sub.Tag.Lookup("refine"); ok {
+			ParseFieldTag(&fieldDef, tag)
+		}
+		fields = append(fields, fieldDef)
+	}
+	return fields
+}
+
+// ValueObjectParents returns the JSON names of the value objects of fields, with the
+// names of their fields in the value objects
+func ValueObjectParents(fields []Field) map[string][]string {
+	parents := make(map[string][]string)
+	for _, field := range fields {
+		if field.ValueObject != nil {
+			parent := field.ValueObject.Parent
+			parents[parent] = append(parents[parent], field.ValueObject.Key)
+		}
+	}
+	return parents
+}
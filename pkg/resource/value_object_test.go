@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type voAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city" refine:"required"`
+	Zip    string `json:"zip" gorm:"column:postal_code"`
+}
+
+type voCustomer struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Address  voAddress  `json:"address" gorm:"embedded;embeddedPrefix:address_"`
+	Shipping *voAddress `json:"shipping" gorm:"serializer:json" refine:"valueObject"`
+}
+
+func TestValueObjectFields(t *testing.T) {
+	fields := GenerateFieldsFromModel(voCustomer{})
+
+	byName := make(map[string]Field)
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	assert.NotContains(t, byName, "Address")
+	assert.NotContains(t, byName, "Shipping")
+
+	city, ok := byName["address.city"]
+	require.True(t, ok)
+	assert.Equal(t, "Address City", city.Label)
+	assert.Equal(t, &ValueObjectConfig{Parent: "address", Key: "city", Storage: ValueObjectColumns, Column: "address_city"}, city.ValueObject)
+	assert.True(t, city.Validation != nil && city.Validation.Required)
+	column, ok := city.Column()
+	assert.True(t, ok)
+	assert.Equal(t, "address_city", column)
+
+	column, _ = byName["address.zip"].Column()
+	assert.Equal(t, "address_postal_code", column)
+
+	// Value objects in JSON columns have no column to filter or sort on
+	shippingCity, ok := byName["shipping.city"]
+	require.True(t, ok)
+	assert.Equal(t, ValueObjectJSON, shippingCity.ValueObject.Storage)
+	_, ok = shippingCity.Column()
+	assert.False(t, ok)
+
+	res := NewResource(ResourceConfig{Name: "customers", Model: voCustomer{}})
+	assert.Contains(t, res.GetFilterableFields(), "address.city")
+	assert.Contains(t, res.GetSortableFields(), "address.city")
+	assert.NotContains(t, res.GetFilterableFields(), "shipping.city")
+
+	// Without the tag, structs in JSON columns stay JSON fields
+	type settings struct {
+		Theme string `json:"theme"`
+	}
+	type account struct {
+		Settings settings `json:"settings" gorm:"serializer:json"`
+	}
+	accountFields := GenerateFieldsFromModel(account{})
+	require.Len(t, accountFields, 1)
+	assert.Equal(t, "settings", accountFields[0].Name)
+	assert.Nil(t, accountFields[0].ValueObject)
+
+	assert.Equal(t, map[string][]string{
+		"address":  {"street", "city", "zip"},
+		"shipping": {"street", "city", "zip"},
+	}, ValueObjectParents(fields))
+}
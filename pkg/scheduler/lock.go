@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/worker"
+	"gorm.io/gorm"
+)
+
+// Locker makes sure a scheduled run of a job happens on one replica only
+type Locker interface {
+	// Run runs job for the run scheduled at slot unless another replica claimed it,
+	// and reports whether it ran
+	Run(ctx context.Context, name string, slot time.Time, job worker.Task) (bool, error)
+}
+
+// ScheduledRun records the last run of a job claimed by a replica
+type ScheduledRun struct {
+	Name       string     `json:"name" gorm:"primaryKey;size:191"`
+	Slot       time.Time  `json:"slot"`
+	Owner      string     `json:"owner"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// TableName returns the table of scheduled runs
+func (ScheduledRun) TableName() string {
+	return "scheduler_runs"
+}
+
+// DBLocker coordinates replicas through the database. A replica claims a run by
+// moving the slot of the job forward in the scheduler_runs table, which succeeds on
+// one replica only. On PostgreSQL and MySQL the run also holds an advisory lock, so
+// a run outlasting its schedule is not overlapped by the next one on another replica.
+type DBLocker struct {
+	db    *gorm.DB
+	owner string
+}
+
+// NewDBLocker creates a database locker. owner identifies the replica in the runs
+// table (the hostname if empty).
+func NewDBLocker(db *gorm.DB, owner string) *DBLocker {
+	if owner == "" {
+		owner, _ = os.Hostname()
+	}
+	return &DBLocker{db: db, owner: owner}
+}
+
+// Migrate creates the scheduler_runs table
+func (l *DBLocker) Migrate() error {
+	return l.db.AutoMigrate(&ScheduledRun{})
+}
+
+// Run claims the run of a job at slot and runs it with the advisory lock of the job
+func (l *DBLocker) Run(ctx context.Context, name string, slot time.Time, job worker.Task) (bool, error) {
+	slot = slot.UTC()
+	claimed, err := l.claim(ctx, name, slot)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	ran, err := l.withAdvisoryLock(ctx, name, job)
+	finished := time.Now().UTC()
+	message := ""
+	switch {
+	case err != nil:
+		message = err.Error()
+	case !ran:
+		message = "skipped: the previous run is still going on"
+	}
+	l.db.WithContext(ctx).Model(&ScheduledRun{}).
+		Where("name = ? AND slot = ?", name, slot).
+		Updates(map[string]interface{}{"finished_at": finished, "error": message})
+	return ran, err
+}
+
+// claim moves the slot of a job forward, reporting whether this replica did
+func (l *DBLocker) claim(ctx context.Context, name string, slot time.Time) (bool, error) {
+	db := l.db.WithContext(ctx)
+	now := time.Now().UTC()
+	result := db.Model(&ScheduledRun{}).
+		Where("name = ? AND slot < ?", name, slot).
+		Updates(map[string]interface{}{"slot": slot, "owner": l.owner, "started_at": now, "finished_at": nil, "error": ""})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// First run of the job, unless the row exists with a later or the same slot
+	err := db.Create(&ScheduledRun{Name: name, Slot: slot, Owner: l.owner, StartedAt: now}).Error
+	if err == nil {
+		return true, nil
+	}
+	var count int64
+	if countErr := db.Model(&ScheduledRun{}).Where("name = ?", name).Count(&count).Error; countErr == nil && count > 0 {
+		return false, nil
+	}
+	return false, err
+}
+
+// withAdvisoryLock runs job holding the advisory lock of the job on a dedicated
+// connection, or without it on databases lacking advisory locks
+func (l *DBLocker) withAdvisoryLock(ctx context.Context, name string, job worker.Task) (bool, error) {
+	var acquire, release string
+	var key interface{}
+	switch l.db.Dialector.Name() {
+	case "postgres":
+		acquire, release = "SELECT pg_try_advisory_lock(?)", "SELECT pg_advisory_unlock(?)"
+		key = lockKey(name)
+	case "mysql":
+		acquire, release = "SELECT GET_LOCK(?, 0) = 1", "SELECT RELEASE_LOCK(?)"
+		key = "scheduler:" + name
+	default:
+		return true, job(ctx)
+	}
+
+	ran := false
+	err := l.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw(acquire, key).Scan(&locked).Error; err != nil || !locked {
+			return err
+		}
+		defer conn.Exec(release, key)
+		ran = true
+		return job(ctx)
+	})
+	return ran, err
+}
+
+// lockKey returns the PostgreSQL advisory lock key of a job
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("scheduler:" + name))
+	return int64(hash.Sum64())
+}
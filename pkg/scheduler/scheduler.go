@@ -0,0 +1,324 @@
+// Package scheduler runs package jobs (retention, snapshots, recomputations, garbage
+// collection, ...) on cron schedules, once per schedule across replicas when a Locker
+// is configured.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/worker"
+)
+
+// Scheduler errors
+var (
+	ErrJobExists   = errors.New("job already registered")
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// Default is the scheduler used by the package level functions
+var Default = New(Config{})
+
+// Register registers a job on the default scheduler
+func Register(name, spec string, job worker.Task) error {
+	return Default.Register(name, spec, job)
+}
+
+// Config contains configuration for a scheduler
+type Config struct {
+	// Locker makes sure each scheduled run happens on one replica only (optional,
+	// every replica runs the jobs without it)
+	Locker Locker
+
+	// Location the schedules are evaluated in (default time.Local)
+	Location *time.Location
+
+	// OnError is called when a run fails. By default the error is logged.
+	OnError func(name string, err error)
+}
+
+// JobStatus describes a registered job and its last run on this replica
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	NextRun      time.Time  `json:"nextRun"`
+	Running      bool       `json:"running"`
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration string     `json:"lastDuration,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	Runs         int64      `json:"runs"`
+	Failures     int64      `json:"failures"`
+
+	// Skipped counts the scheduled runs claimed by other replicas or skipped because
+	// the previous run was still going on
+	Skipped int64 `json:"skipped"`
+}
+
+// entry is a registered job
+type entry struct {
+	schedule *Schedule
+	job      worker.Task
+	status   JobStatus
+}
+
+// Scheduler runs registered jobs on their cron schedules. Runs of a job never
+// overlap on a replica. It implements refinegin.Drainer, so it can be drained on
+// shutdown.
+type Scheduler struct {
+	config Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	started bool
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	running sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// New creates a scheduler
+func New(config Config) *Scheduler {
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.OnError == nil {
+		config.OnError = func(name string, err error) {
+			log.Printf("scheduler: job %s failed: %v", name, err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Register registers a job running on a cron schedule (see Parse), e.g.
+//
+//	scheduler.Register("purge-soft-deleted", "0 3 * * *", job)
+func (s *Scheduler) Register(name, spec string, job worker.Task) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.entries[name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	s.entries[name] = &entry{
+		schedule: schedule,
+		job:      job,
+		status: JobStatus{
+			Name:     name,
+			Schedule: spec,
+			NextRun:  schedule.Next(s.now().In(s.config.Location)),
+		},
+	}
+	s.notify()
+	return nil
+}
+
+// Start starts running the jobs in the background
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stop, s.done)
+}
+
+// Drain stops scheduling runs and waits for running jobs to finish. When ctx is done
+// first, the context of the running jobs is canceled.
+func (s *Scheduler) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	var done chan struct{}
+	if s.started {
+		s.started = false
+		close(s.stop)
+		done = s.done
+	}
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+// Status returns the registered jobs sorted by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Run runs a job now, outside its schedule and without the locker, and waits for it
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if e.status.Running {
+		s.mu.Unlock()
+		return fmt.Errorf("job %s is already running", name)
+	}
+	e.status.Running = true
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	return s.execute(ctx, name, e, time.Time{})
+}
+
+// notify wakes the loop up to recompute the next run
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop starts the due jobs until stop is closed
+func (s *Scheduler) loop(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		timer := time.NewTimer(s.untilNextRun())
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+			s.startDue()
+		}
+	}
+}
+
+// untilNextRun returns the time until the earliest next run
+func (s *Scheduler) untilNextRun() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := time.Hour
+	now := s.now()
+	for _, e := range s.entries {
+		if wait := e.status.NextRun.Sub(now); wait < next {
+			next = wait
+		}
+	}
+	if next < 0 {
+		return 0
+	}
+	return next
+}
+
+// startDue starts the jobs whose next run has come
+func (s *Scheduler) startDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().In(s.config.Location)
+	for name, e := range s.entries {
+		if e.status.NextRun.After(now) {
+			continue
+		}
+		slot := e.status.NextRun
+		e.status.NextRun = e.schedule.Next(now)
+		if e.status.Running {
+			e.status.Skipped++
+			continue
+		}
+		e.status.Running = true
+		s.running.Add(1)
+		go s.execute(s.ctx, name, e, slot)
+	}
+}
+
+// execute runs a job for a slot (through the locker unless the slot is zero) and
+// records the outcome
+func (s *Scheduler) execute(ctx context.Context, name string, e *entry, slot time.Time) error {
+	defer s.running.Done()
+
+	started := s.now()
+	ran := true
+	var err error
+	if s.config.Locker != nil && !slot.IsZero() {
+		ran, err = s.config.Locker.Run(ctx, name, slot, e.job)
+	} else {
+		err = e.job(ctx)
+	}
+
+	s.mu.Lock()
+	e.status.Running = false
+	switch {
+	case !ran && err == nil:
+		e.status.Skipped++
+	default:
+		e.status.Runs++
+		e.status.LastRun = &started
+		e.status.LastDuration = s.now().Sub(started).String()
+		e.status.LastError = ""
+		if err != nil {
+			e.status.Failures++
+			e.status.LastError = err.Error()
+		}
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.config.OnError(name, err)
+	}
+	return err
+}
+
+// RegisterSchedulerRoutes registers GET <path> listing the jobs of a scheduler with
+// their schedules and last runs
+func RegisterSchedulerRoutes(router *gin.RouterGroup, path string, s *Scheduler) {
+	router.GET(path, GenerateStatusHandler(s))
+}
+
+// GenerateStatusHandler generates a handler listing the jobs of a scheduler
+func GenerateStatusHandler(s *Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": s.Status()})
+	}
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSchedulerRunsDueJobs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 2, 59, 0, 0, time.UTC)
+	s := New(Config{Location: time.UTC, OnError: func(string, error) {}})
+	s.now = func() time.Time { return now }
+
+	var runs int32
+	require.NoError(t, s.Register("purge", "0 3 * * *", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}))
+	require.NoError(t, s.Register("failing", "* * * * *", func(ctx context.Context) error {
+		return errors.New("boom")
+	}))
+	assert.ErrorIs(t, s.Register("purge", "@daily", nil), ErrJobExists)
+	assert.Error(t, s.Register("invalid", "bad", nil))
+
+	now = now.Add(time.Minute)
+	s.startDue()
+	require.NoError(t, s.Drain(context.Background()))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	statuses := s.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "failing", statuses[0].Name)
+	assert.Equal(t, "boom", statuses[0].LastError)
+	assert.Equal(t, int64(1), statuses[0].Failures)
+	assert.Equal(t, "purge", statuses[1].Name)
+	assert.Equal(t, int64(1), statuses[1].Runs)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC), statuses[1].NextRun)
+
+	// Jobs that are not due are not started
+	s.startDue()
+	require.NoError(t, s.Drain(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	require.NoError(t, s.Run(context.Background(), "purge"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.ErrorIs(t, s.Run(context.Background(), "missing"), ErrJobNotFound)
+}
+
+func TestSchedulerStartAndDrain(t *testing.T) {
+	s := New(Config{})
+	started := make(chan struct{})
+	require.NoError(t, s.Register("every-minute", "* * * * *", func(ctx context.Context) error {
+		close(started)
+		return nil
+	}))
+	// Make the job due right away
+	s.entries["every-minute"].status.NextRun = time.Now()
+
+	s.Start()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job did not run")
+	}
+	require.NoError(t, s.Drain(context.Background()))
+}
+
+func TestDBLocker(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	first, second := NewDBLocker(db, "replica-1"), NewDBLocker(db, "replica-2")
+	require.NoError(t, first.Migrate())
+
+	var runs int32
+	job := func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}
+	slot := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	ran, err := first.Run(context.Background(), "purge", slot, job)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	ran, err = second.Run(context.Background(), "purge", slot, job)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	// The next slot can be claimed by any replica
+	ran, err = second.Run(context.Background(), "purge", slot.Add(24*time.Hour), func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	assert.True(t, ran)
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	var run ScheduledRun
+	require.NoError(t, db.First(&run, "name = ?", "purge").Error)
+	assert.Equal(t, "replica-2", run.Owner)
+	assert.Equal(t, "boom", run.Error)
+	assert.NotNil(t, run.FinishedAt)
+}
+
+func TestStatusHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := New(Config{})
+	require.NoError(t, s.Register("gc", "@daily", func(ctx context.Context) error { return nil }))
+
+	r := gin.New()
+	RegisterSchedulerRoutes(r.Group("/admin"), "/jobs", s)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []JobStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "gc", response.Data[0].Name)
+	assert.Equal(t, "@daily", response.Data[0].Schedule)
+}
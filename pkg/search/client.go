@@ -0,0 +1,155 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Error is returned when Elasticsearch responds with a non-2xx status
+type Error struct {
+	StatusCode int
+	Type       string
+	Reason     string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("elasticsearch error %d (%s): %s", e.StatusCode, e.Type, e.Reason)
+}
+
+// Client is a minimal Elasticsearch/OpenSearch REST client
+type Client struct {
+	// Base URL of the cluster (e.g. "http://localhost:9200")
+	BaseURL string
+
+	// HTTP client used for requests
+	HTTPClient *http.Client
+
+	// Basic authentication credentials (optional)
+	Username string
+	Password string
+
+	// API key sent as "Authorization: ApiKey <key>" (optional)
+	APIKey string
+}
+
+// NewClient creates a new client for the cluster at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SearchHit is a single document returned by a search
+type SearchHit struct {
+	ID     string          `json:"_id"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// SearchResponse is the subset of the search response used by the repository
+type SearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []SearchHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]json.RawMessage `json:"aggregations"`
+}
+
+// do performs a request and decodes the response into out (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.APIKey)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(data, &failure)
+		return &Error{StatusCode: resp.StatusCode, Type: failure.Error.Type, Reason: failure.Error.Reason}
+	}
+
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// CreateIndex creates an index with the given mappings; an existing index is left untouched
+func (c *Client) CreateIndex(ctx context.Context, index string, mappings map[string]interface{}) error {
+	err := c.do(ctx, http.MethodPut, "/"+url.PathEscape(index), map[string]interface{}{"mappings": mappings}, nil)
+	if esErr, ok := err.(*Error); ok && esErr.Type == "resource_already_exists_exception" {
+		return nil
+	}
+	return err
+}
+
+// IndexDocument creates or replaces a document
+func (c *Client) IndexDocument(ctx context.Context, index, id string, document interface{}, refresh string) error {
+	path := "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id)
+	if refresh != "" {
+		path += "?refresh=" + url.QueryEscape(refresh)
+	}
+	return c.do(ctx, http.MethodPut, path, document, nil)
+}
+
+// DeleteDocument removes a document; missing documents are ignored
+func (c *Client) DeleteDocument(ctx context.Context, index, id string, refresh string) error {
+	path := "/" + url.PathEscape(index) + "/_doc/" + url.PathEscape(id)
+	if refresh != "" {
+		path += "?refresh=" + url.QueryEscape(refresh)
+	}
+	err := c.do(ctx, http.MethodDelete, path, nil, nil)
+	if esErr, ok := err.(*Error); ok && esErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Search runs a search request against an index
+func (c *Client) Search(ctx context.Context, index string, body map[string]interface{}) (*SearchResponse, error) {
+	var response SearchResponse
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
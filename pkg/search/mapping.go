@@ -0,0 +1,91 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// GenerateMapping builds index mappings from the fields of a resource.
+// Searchable string fields are indexed as text with a keyword sub-field,
+// other string fields as keyword so they can be filtered, sorted and aggregated.
+func GenerateMapping(res resource.Resource) map[string]interface{} {
+	searchable := make(map[string]bool)
+	for _, name := range res.GetSearchable() {
+		searchable[documentField(res, name)] = true
+	}
+
+	properties := make(map[string]interface{})
+	for _, field := range res.GetFields() {
+		name := documentField(res, field.Name)
+		if property := fieldMapping(strings.TrimPrefix(field.Type, "*"), searchable[name]); property != nil {
+			properties[name] = property
+		}
+	}
+
+	return map[string]interface{}{"properties": properties}
+}
+
+// fieldMapping returns the mapping of a single field, or nil for types that are not indexed
+func fieldMapping(goType string, searchable bool) map[string]interface{} {
+	switch utils.GetTypeMapping(goType).Category {
+	case utils.TypeString:
+		if searchable {
+			return map[string]interface{}{
+				"type":   "text",
+				"fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword"}},
+			}
+		}
+		return map[string]interface{}{"type": "keyword"}
+	case utils.TypeInteger:
+		return map[string]interface{}{"type": "long"}
+	case utils.TypeNumber:
+		return map[string]interface{}{"type": "double"}
+	case utils.TypeBoolean:
+		return map[string]interface{}{"type": "boolean"}
+	case utils.TypeDateTime:
+		return map[string]interface{}{"type": "date"}
+	default:
+		return nil
+	}
+}
+
+// documentField returns the JSON name under which a resource field is stored in the index
+func documentField(res resource.Resource, name string) string {
+	model := res.GetModel()
+	if model == nil {
+		return name
+	}
+
+	modelType := reflect.TypeOf(model)
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	if modelType.Kind() != reflect.Struct {
+		return name
+	}
+
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if jsonName == "-" {
+			continue
+		}
+		if strings.EqualFold(field.Name, name) || strings.EqualFold(jsonName, name) {
+			return jsonName
+		}
+	}
+	return name
+}
+
+// isTextField reports whether a document field is indexed as text
+func isTextField(mapping map[string]interface{}, field string) bool {
+	properties, _ := mapping["properties"].(map[string]interface{})
+	property, _ := properties[field].(map[string]interface{})
+	return property["type"] == "text"
+}
@@ -0,0 +1,466 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// Config contains configuration for a search-backed repository
+type Config struct {
+	// Client connected to the Elasticsearch/OpenSearch cluster
+	Client *Client
+
+	// Prefix prepended to the resource name to build the index name
+	IndexPrefix string
+
+	// Refresh policy used for index writes ("", "true" or "wait_for")
+	Refresh string
+
+	// OnSyncError is called when the index could not be updated after a successful
+	// write to the primary repository. When nil, the error is returned to the caller.
+	OnSyncError func(err error)
+}
+
+// Repository serves list and count operations from a search index while all
+// writes go to the primary repository and are then synced to the index.
+// Operations it does not override are served by the primary repository.
+type Repository struct {
+	repository.Repository
+
+	Client      *Client
+	Resource    resource.Resource
+	Index       string
+	Refresh     string
+	OnSyncError func(err error)
+
+	// Maximum number of values returned per facet (default 100)
+	FacetSize int
+
+	mapping map[string]interface{}
+}
+
+// NewRepository wraps a primary repository with a search index for the resource
+func NewRepository(primary repository.Repository, res resource.Resource, config Config) *Repository {
+	return &Repository{
+		Repository:  primary,
+		Client:      config.Client,
+		Resource:    res,
+		Index:       config.IndexPrefix + res.GetName(),
+		Refresh:     config.Refresh,
+		OnSyncError: config.OnSyncError,
+		mapping:     GenerateMapping(res),
+	}
+}
+
+// Mapping returns the index mappings generated from the resource fields
+func (r *Repository) Mapping() map[string]interface{} {
+	return r.mapping
+}
+
+// EnsureIndex creates the index with the generated mappings if it does not exist
+func (r *Repository) EnsureIndex(ctx context.Context) error {
+	return r.Client.CreateIndex(ctx, r.Index, r.mapping)
+}
+
+// Sync writes a single record to the index
+func (r *Repository) Sync(ctx context.Context, record interface{}) error {
+	id, err := utils.GetFieldValue(record, r.GetIDFieldName())
+	if err != nil {
+		return err
+	}
+	return r.Client.IndexDocument(ctx, r.Index, fmt.Sprint(id), record, r.Refresh)
+}
+
+// Reindex copies all records of the primary repository into the index
+func (r *Repository) Reindex(ctx context.Context) (int64, error) {
+	if err := r.EnsureIndex(ctx); err != nil {
+		return 0, err
+	}
+
+	records, _, err := r.Repository.List(ctx, query.QueryOptions{Resource: r.Resource, DisablePagination: true})
+	if err != nil {
+		return 0, err
+	}
+
+	items := reflect.ValueOf(records)
+	if items.Kind() == reflect.Ptr {
+		items = items.Elem()
+	}
+
+	var indexed int64
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		if item.Kind() != reflect.Ptr {
+			item = item.Addr()
+		}
+		if err := r.Sync(ctx, item.Interface()); err != nil {
+			return indexed, err
+		}
+		indexed++
+	}
+	return indexed, nil
+}
+
+// syncResult reports an index sync error according to OnSyncError
+func (r *Repository) syncResult(err error) error {
+	if err == nil {
+		return nil
+	}
+	if r.OnSyncError != nil {
+		r.OnSyncError(err)
+		return nil
+	}
+	return fmt.Errorf("search index sync failed: %w", err)
+}
+
+// syncByID loads a record from the primary repository and writes it to the index
+func (r *Repository) syncByID(ctx context.Context, id interface{}) error {
+	record, err := r.Repository.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.Sync(ctx, record)
+}
+
+// List returns records matching the query options from the search index
+func (r *Repository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	response, err := r.Client.Search(ctx, r.Index, r.buildSearchBody(options))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	modelType := reflect.TypeOf(r.Resource.GetModel())
+	if modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+
+	hits := response.Hits.Hits
+	slice := reflect.MakeSlice(reflect.SliceOf(modelType), len(hits), len(hits))
+	for i, hit := range hits {
+		if err := json.Unmarshal(hit.Source, slice.Index(i).Addr().Interface()); err != nil {
+			return nil, 0, err
+		}
+	}
+	result := reflect.New(slice.Type())
+	result.Elem().Set(slice)
+
+	return result.Interface(), response.Hits.Total.Value, nil
+}
+
+// Count returns the number of records matching the query options from the search index
+func (r *Repository) Count(ctx context.Context, options query.QueryOptions) (int64, error) {
+	options.DisablePagination = true
+	body := r.buildSearchBody(options)
+	body["size"] = 0
+
+	response, err := r.Client.Search(ctx, r.Index, body)
+	if err != nil {
+		return 0, err
+	}
+	return response.Hits.Total.Value, nil
+}
+
+// Create creates a record in the primary repository and indexes it
+func (r *Repository) Create(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.Create(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return created, r.syncResult(r.Sync(ctx, created))
+}
+
+// Update updates a record in the primary repository and re-indexes it
+func (r *Repository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
+	updated, err := r.Repository.Update(ctx, id, data)
+	if err != nil {
+		return nil, err
+	}
+	return updated, r.syncResult(r.Sync(ctx, updated))
+}
+
+// Delete deletes a record from the primary repository and the index
+func (r *Repository) Delete(ctx context.Context, id interface{}) error {
+	if err := r.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.syncResult(r.Client.DeleteDocument(ctx, r.Index, fmt.Sprint(id), r.Refresh))
+}
+
+// CreateMany creates records in the primary repository and indexes them
+func (r *Repository) CreateMany(ctx context.Context, data interface{}) (interface{}, error) {
+	created, err := r.Repository.CreateMany(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := reflect.ValueOf(created)
+	if items.Kind() == reflect.Ptr {
+		items = items.Elem()
+	}
+	if items.Kind() == reflect.Slice {
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i)
+			if item.Kind() != reflect.Ptr && item.CanAddr() {
+				item = item.Addr()
+			}
+			if err := r.syncResult(r.Sync(ctx, item.Interface())); err != nil {
+				return created, err
+			}
+		}
+	}
+	return created, nil
+}
+
+// UpdateMany updates records in the primary repository and re-indexes them
+func (r *Repository) UpdateMany(ctx context.Context, ids []interface{}, data interface{}) (int64, error) {
+	updated, err := r.Repository.UpdateMany(ctx, ids, data)
+	if err != nil {
+		return updated, err
+	}
+	for _, id := range ids {
+		if err := r.syncResult(r.syncByID(ctx, id)); err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
+
+// DeleteMany deletes records from the primary repository and the index
+func (r *Repository) DeleteMany(ctx context.Context, ids []interface{}) (int64, error) {
+	deleted, err := r.Repository.DeleteMany(ctx, ids)
+	if err != nil {
+		return deleted, err
+	}
+	for _, id := range ids {
+		if err := r.syncResult(r.Client.DeleteDocument(ctx, r.Index, fmt.Sprint(id), r.Refresh)); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Facets returns value counts for each field under the current filters using terms aggregations
+func (r *Repository) Facets(ctx context.Context, options query.QueryOptions, fields []string) (repository.FacetCounts, error) {
+	aggregations := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		aggregations[field] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": r.exactField(field), "size": r.facetSize()},
+		}
+	}
+
+	options.DisablePagination = true
+	options.Sort = ""
+	body := r.buildSearchBody(options)
+	body["size"] = 0
+	body["aggs"] = aggregations
+
+	response, err := r.Client.Search(ctx, r.Index, body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(repository.FacetCounts, len(fields))
+	for _, field := range fields {
+		var aggregation struct {
+			Buckets []struct {
+				Key         interface{} `json:"key"`
+				KeyAsString string      `json:"key_as_string"`
+				DocCount    int64       `json:"doc_count"`
+			} `json:"buckets"`
+		}
+		if raw, ok := response.Aggregations[field]; ok {
+			if err := json.Unmarshal(raw, &aggregation); err != nil {
+				return nil, err
+			}
+		}
+
+		counts := make(map[string]int64, len(aggregation.Buckets))
+		for _, bucket := range aggregation.Buckets {
+			key := bucket.KeyAsString
+			if key == "" {
+				key = fmt.Sprint(bucket.Key)
+			}
+			counts[key] = bucket.DocCount
+		}
+		result[field] = counts
+	}
+	return result, nil
+}
+
+// facetSize returns the maximum number of values returned per facet
+func (r *Repository) facetSize() int {
+	if r.FacetSize > 0 {
+		return r.FacetSize
+	}
+	return 100
+}
+
+// WithRelations returns a search repository wrapping the primary repository with relations
+func (r *Repository) WithRelations(relations ...string) repository.Repository {
+	clone := *r
+	clone.Repository = r.Repository.WithRelations(relations...)
+	return &clone
+}
+
+// WithTransaction runs fn in a transaction of the primary repository, keeping index sync
+func (r *Repository) WithTransaction(fn func(repository.Repository) error) error {
+	return r.Repository.WithTransaction(func(tx repository.Repository) error {
+		clone := *r
+		clone.Repository = tx
+		return fn(&clone)
+	})
+}
+
+// buildSearchBody translates query options into a search request body
+func (r *Repository) buildSearchBody(options query.QueryOptions) map[string]interface{} {
+	body := map[string]interface{}{
+		"query":            r.buildQuery(options),
+		"track_total_hits": true,
+	}
+
+	if !options.DisablePagination && options.PerPage > 0 {
+		page := options.Page
+		if page < 1 {
+			page = 1
+		}
+		body["from"] = (page - 1) * options.PerPage
+		body["size"] = options.PerPage
+	}
+
+	if sort := r.buildSort(options); len(sort) > 0 {
+		body["sort"] = sort
+	}
+
+	return body
+}
+
+// buildQuery translates filters and search into a bool query
+func (r *Repository) buildQuery(options query.QueryOptions) map[string]interface{} {
+	must := []interface{}{}
+	filter := []interface{}{}
+	mustNot := []interface{}{}
+
+	if options.Search != "" {
+		multiMatch := map[string]interface{}{"query": options.Search}
+		if searchable := r.Resource.GetSearchable(); len(searchable) > 0 {
+			fields := make([]string, len(searchable))
+			for i, name := range searchable {
+				fields[i] = documentField(r.Resource, name)
+			}
+			multiMatch["fields"] = fields
+		}
+		must = append(must, map[string]interface{}{"multi_match": multiMatch})
+	}
+
+	for field, value := range options.Filters {
+		filter = append(filter, term(r.exactField(field), value))
+	}
+
+	for _, f := range options.AdvancedFilters {
+		field := r.exactField(f.Field)
+		switch strings.ToLower(f.Operator) {
+		case "ne":
+			mustNot = append(mustNot, term(field, f.Value))
+		case "lt", "gt", "lte", "gte":
+			filter = append(filter, map[string]interface{}{
+				"range": map[string]interface{}{field: map[string]interface{}{strings.ToLower(f.Operator): f.Value}},
+			})
+		case "contains", "containsi":
+			filter = append(filter, wildcard(field, fmt.Sprintf("*%v*", f.Value), strings.ToLower(f.Operator) == "containsi"))
+		case "startswith":
+			filter = append(filter, map[string]interface{}{
+				"prefix": map[string]interface{}{field: fmt.Sprint(f.Value)},
+			})
+		case "endswith":
+			filter = append(filter, wildcard(field, fmt.Sprintf("*%v", f.Value), false))
+		case "null":
+			exists := map[string]interface{}{"exists": map[string]interface{}{"field": field}}
+			if isNull, ok := f.Value.(bool); ok && isNull {
+				mustNot = append(mustNot, exists)
+			} else {
+				filter = append(filter, exists)
+			}
+		case "in":
+			values := f.Value
+			if s, ok := values.(string); ok {
+				values = strings.Split(s, ",")
+			}
+			filter = append(filter, map[string]interface{}{"terms": map[string]interface{}{field: values}})
+		default:
+			filter = append(filter, term(field, f.Value))
+		}
+	}
+
+	if len(must) == 0 && len(filter) == 0 && len(mustNot) == 0 {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	boolQuery := map[string]interface{}{}
+	if len(must) > 0 {
+		boolQuery["must"] = must
+	}
+	if len(filter) > 0 {
+		boolQuery["filter"] = filter
+	}
+	if len(mustNot) > 0 {
+		boolQuery["must_not"] = mustNot
+	}
+	return map[string]interface{}{"bool": boolQuery}
+}
+
+// buildSort translates the sort options, supporting comma separated "field order" lists
+func (r *Repository) buildSort(options query.QueryOptions) []interface{} {
+	if options.Sort == "" {
+		return nil
+	}
+
+	var sort []interface{}
+	for _, part := range strings.Split(options.Sort, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		order := options.Order
+		if len(tokens) > 1 {
+			order = tokens[1]
+		}
+		if order == "" {
+			order = "asc"
+		}
+		sort = append(sort, map[string]interface{}{
+			r.exactField(tokens[0]): map[string]interface{}{"order": strings.ToLower(order)},
+		})
+	}
+	return sort
+}
+
+// exactField returns the field used for exact matching, sorting and aggregations
+func (r *Repository) exactField(name string) string {
+	field := documentField(r.Resource, name)
+	if isTextField(r.mapping, field) {
+		return field + ".keyword"
+	}
+	return field
+}
+
+// term builds a term query
+func term(field string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]interface{}{field: value}}
+}
+
+// wildcard builds a wildcard query
+func wildcard(field, pattern string, caseInsensitive bool) map[string]interface{} {
+	return map[string]interface{}{
+		"wildcard": map[string]interface{}{
+			field: map[string]interface{}{"value": pattern, "case_insensitive": caseInsensitive},
+		},
+	}
+}
@@ -0,0 +1,245 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Article struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	Title     string  `json:"title"`
+	Category  string  `json:"category"`
+	Rating    float64 `json:"rating"`
+	Published bool    `json:"published"`
+}
+
+// fakeCluster is a minimal in-memory stand-in for an Elasticsearch cluster
+type fakeCluster struct {
+	mu         sync.Mutex
+	mappings   map[string]interface{}
+	documents  map[string]json.RawMessage
+	lastSearch map[string]interface{}
+
+	// aggregations returned with every search response
+	aggregations map[string]interface{}
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.Method == http.MethodPut && len(parts) == 1:
+		var body map[string]interface{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		f.mappings = body["mappings"].(map[string]interface{})
+	case req.Method == http.MethodPut && len(parts) == 3:
+		var doc json.RawMessage
+		_ = json.NewDecoder(req.Body).Decode(&doc)
+		f.documents[parts[2]] = doc
+	case req.Method == http.MethodDelete && len(parts) == 3:
+		if _, ok := f.documents[parts[2]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.documents, parts[2])
+	case req.Method == http.MethodPost && parts[len(parts)-1] == "_search":
+		_ = json.NewDecoder(req.Body).Decode(&f.lastSearch)
+		ids := make([]string, 0, len(f.documents))
+		for id := range f.documents {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		hits := []map[string]interface{}{}
+		for _, id := range ids {
+			hits = append(hits, map[string]interface{}{"_id": id, "_source": f.documents[id]})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"hits": map[string]interface{}{
+				"total": map[string]interface{}{"value": len(hits)},
+				"hits":  hits,
+			},
+			"aggregations": f.aggregations,
+		})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func setupSearchTest(t *testing.T) (*Repository, *fakeCluster) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Article{}))
+
+	cluster := &fakeCluster{documents: map[string]json.RawMessage{}}
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:             "articles",
+		Model:            Article{},
+		SearchableFields: []string{"Title"},
+	})
+
+	repo := NewRepository(repository.NewGenericRepository(db, res), res, Config{
+		Client:      NewClient(server.URL),
+		IndexPrefix: "app_",
+	})
+	return repo, cluster
+}
+
+func TestGenerateMapping(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:             "articles",
+		Model:            Article{},
+		SearchableFields: []string{"Title"},
+	})
+
+	properties := GenerateMapping(res)["properties"].(map[string]interface{})
+	assert.Equal(t, "text", properties["title"].(map[string]interface{})["type"])
+	assert.Equal(t, "keyword", properties["category"].(map[string]interface{})["type"])
+	assert.Equal(t, "double", properties["rating"].(map[string]interface{})["type"])
+	assert.Equal(t, "boolean", properties["published"].(map[string]interface{})["type"])
+	assert.Equal(t, "long", properties["id"].(map[string]interface{})["type"])
+}
+
+func TestBuildSearchBody(t *testing.T) {
+	repo, _ := setupSearchTest(t)
+
+	body := repo.buildSearchBody(query.QueryOptions{
+		Page:    2,
+		PerPage: 20,
+		Search:  "golang",
+		Sort:    "title",
+		Order:   "desc",
+		Filters: map[string]interface{}{"category": "tech"},
+		AdvancedFilters: []query.Filter{
+			{Field: "rating", Operator: "gte", Value: 4},
+			{Field: "category", Operator: "ne", Value: "spam"},
+		},
+	})
+
+	assert.Equal(t, 20, body["from"])
+	assert.Equal(t, 20, body["size"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"title.keyword": map[string]interface{}{"order": "desc"}},
+	}, body["sort"])
+
+	boolQuery := body["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"multi_match": map[string]interface{}{"query": "golang", "fields": []string{"title"}}},
+	}, boolQuery["must"])
+	assert.Contains(t, boolQuery["filter"], term("category", "tech"))
+	assert.Contains(t, boolQuery["filter"], map[string]interface{}{
+		"range": map[string]interface{}{"rating": map[string]interface{}{"gte": 4}},
+	})
+	assert.Equal(t, []interface{}{term("category", "spam")}, boolQuery["must_not"])
+}
+
+func TestSearchRepositorySync(t *testing.T) {
+	repo, cluster := setupSearchTest(t)
+	ctx := context.Background()
+	assert.Equal(t, "app_articles", repo.Index)
+
+	require.NoError(t, repo.EnsureIndex(ctx))
+	assert.NotNil(t, cluster.mappings["properties"])
+
+	created, err := repo.Create(ctx, &Article{Title: "Go search", Category: "tech"})
+	require.NoError(t, err)
+	id := created.(*Article).ID
+	assert.Contains(t, cluster.documents, "1")
+
+	_, err = repo.Update(ctx, id, &Article{ID: id, Title: "Go search updated", Category: "tech"})
+	require.NoError(t, err)
+	assert.Contains(t, string(cluster.documents["1"]), "Go search updated")
+
+	result, total, err := repo.List(ctx, query.QueryOptions{Page: 1, PerPage: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	articles := *result.(*[]Article)
+	require.Len(t, articles, 1)
+	assert.Equal(t, "Go search updated", articles[0].Title)
+
+	require.NoError(t, repo.Delete(ctx, id))
+	assert.Empty(t, cluster.documents)
+}
+
+func TestSearchRepositoryReindex(t *testing.T) {
+	repo, cluster := setupSearchTest(t)
+	ctx := context.Background()
+
+	// Write directly to the primary repository, bypassing the index
+	for _, title := range []string{"one", "two", "three"} {
+		_, err := repo.Repository.Create(ctx, &Article{Title: title})
+		require.NoError(t, err)
+	}
+	assert.Empty(t, cluster.documents)
+
+	indexed, err := repo.Reindex(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), indexed)
+	assert.Len(t, cluster.documents, 3)
+
+	count, err := repo.Count(ctx, query.QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, float64(0), cluster.lastSearch["size"])
+}
+
+func TestSearchRepositorySyncErrors(t *testing.T) {
+	repo, _ := setupSearchTest(t)
+	repo.Client = NewClient("http://127.0.0.1:1")
+
+	_, err := repo.Create(context.Background(), &Article{Title: "offline"})
+	assert.ErrorContains(t, err, "search index sync failed")
+
+	var reported error
+	repo.OnSyncError = func(err error) { reported = err }
+	_, err = repo.Create(context.Background(), &Article{Title: "offline"})
+	assert.NoError(t, err)
+	assert.Error(t, reported)
+}
+
+func TestSearchRepositoryFacets(t *testing.T) {
+	repo, cluster := setupSearchTest(t)
+	cluster.aggregations = map[string]interface{}{
+		"category": map[string]interface{}{"buckets": []interface{}{
+			map[string]interface{}{"key": "tech", "doc_count": 3},
+			map[string]interface{}{"key": "news", "doc_count": 1},
+		}},
+		"published": map[string]interface{}{"buckets": []interface{}{
+			map[string]interface{}{"key": 1, "key_as_string": "true", "doc_count": 2},
+		}},
+	}
+
+	facets, err := repo.Facets(context.Background(), query.QueryOptions{
+		Filters: map[string]interface{}{"rating": 5},
+		Sort:    "title",
+	}, []string{"category", "published", "title"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int64{"tech": 3, "news": 1}, facets["category"])
+	assert.Equal(t, map[string]int64{"true": 2}, facets["published"])
+	assert.Empty(t, facets["title"])
+
+	aggs := cluster.lastSearch["aggs"].(map[string]interface{})
+	assert.Equal(t, "title.keyword", aggs["title"].(map[string]interface{})["terms"].(map[string]interface{})["field"])
+	assert.Equal(t, float64(0), cluster.lastSearch["size"])
+	assert.Nil(t, cluster.lastSearch["sort"])
+	assert.NotNil(t, cluster.lastSearch["query"].(map[string]interface{})["bool"])
+}
@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDuplicateFile is returned for uploads of content the file field already holds
+var ErrDuplicateFile = errors.New("file was already uploaded")
+
+// StoredContent is content uploaded to a file field, stored once under Key however
+// often it is uploaded to the field. Scope is the field, as <resource>.<field>.
+type StoredContent struct {
+	Scope       string    `json:"scope" gorm:"primaryKey;size:191"`
+	Hash        string    `json:"hash" gorm:"primaryKey;size:64"`
+	Key         string    `json:"key" gorm:"column:storage_key;size:512;not null;index"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty" gorm:"size:191"`
+	RefCount    int       `json:"refCount" gorm:"not null;default:0"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// TableName returns the table used to store content hashes
+func (StoredContent) TableName() string {
+	return "refine_file_contents"
+}
+
+// ContentIndex maps the SHA-256 of content uploaded to a file field to the storage key
+// holding it, so identical uploads to the field share one stored file. Fields keep
+// their own files, which carry the field's storage path, base URL and thumbnails.
+// Attachments add a reference and failed
+// uploads release theirs. Records changed outside the upload routes make the counts
+// drift; the garbage collector reconciles them with the records and forgets the
+// content of the files it deletes. Files are never deleted when their count drops to
+// zero, that is left to the garbage collector.
+type ContentIndex struct {
+	DB *gorm.DB
+}
+
+// NewContentIndex creates a new content index
+func NewContentIndex(db *gorm.DB) *ContentIndex {
+	return &ContentIndex{DB: db}
+}
+
+// AutoMigrate creates or updates the content table
+func (i *ContentIndex) AutoMigrate() error {
+	return i.DB.AutoMigrate(&StoredContent{})
+}
+
+// Find returns the content of a scope with a hash, or nil when it is not stored
+func (i *ContentIndex) Find(ctx context.Context, scope, hash string) (*StoredContent, error) {
+	var contents []StoredContent
+	if err := i.DB.WithContext(ctx).Where("scope = ? AND hash = ?", scope, hash).Limit(1).Find(&contents).Error; err != nil {
+		return nil, err
+	}
+	if len(contents) == 0 {
+		return nil, nil
+	}
+	return &contents[0], nil
+}
+
+// Acquire adds a reference to content. When content of the scope with the same hash is
+// already indexed, its count is incremented and the indexed content, whose key may differ, is
+// returned.
+func (i *ContentIndex) Acquire(ctx context.Context, content StoredContent) (*StoredContent, error) {
+	var stored StoredContent
+	err := i.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		content.RefCount = 1
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&content)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			err := tx.Model(&StoredContent{}).Where("scope = ? AND hash = ?", content.Scope, content.Hash).
+				UpdateColumn("ref_count", gorm.Expr("ref_count + 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("scope = ? AND hash = ?", content.Scope, content.Hash).Take(&stored).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Release removes a reference to the content stored under a key. Content without
+// references is forgotten.
+func (i *ContentIndex) Release(ctx context.Context, key string) error {
+	return i.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&StoredContent{}).Where("storage_key = ? AND ref_count > 0", key).
+			UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("storage_key = ? AND ref_count <= 0", key).Delete(&StoredContent{}).Error
+	})
+}
+
+// Forget removes the content stored under keys from the index
+func (i *ContentIndex) Forget(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return i.DB.WithContext(ctx).Where("storage_key IN ?", keys).Delete(&StoredContent{}).Error
+}
+
+// Reconcile sets the reference counts to the number of references to each key and
+// forgets the content of deleted files
+func (i *ContentIndex) Reconcile(ctx context.Context, references map[string]int, deleted []string) error {
+	if err := i.Forget(ctx, deleted...); err != nil {
+		return err
+	}
+
+	var contents []StoredContent
+	if err := i.DB.WithContext(ctx).Find(&contents).Error; err != nil {
+		return err
+	}
+	for _, content := range contents {
+		if content.RefCount == references[content.Key] {
+			continue
+		}
+		err := i.DB.WithContext(ctx).Model(&StoredContent{}).Where("scope = ? AND hash = ?", content.Scope, content.Hash).
+			UpdateColumn("ref_count", references[content.Key]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Store stores content once per scope and hash. When a stored file of the scope already
+// holds content with the same hash, a reference to it is added and its object is returned without
+// calling put. Otherwise put stores the content and the object it returns is indexed.
+// The returned object carries the hash.
+func (i *ContentIndex) Store(ctx context.Context, provider Provider, content StoredContent, put func() (*Object, error)) (*Object, error) {
+	existing, err := i.Find(ctx, content.Scope, content.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		object, err := provider.Stat(ctx, existing.Key)
+		switch {
+		case err == nil:
+			if _, err := i.Acquire(ctx, *existing); err != nil {
+				return nil, err
+			}
+			object.Hash = content.Hash
+			return object, nil
+		case errors.Is(err, ErrNotFound):
+			// The file was removed outside the garbage collector, store it again
+			if err := i.Forget(ctx, existing.Key); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	object, err := put()
+	if err != nil {
+		return nil, err
+	}
+	content.Key, content.Size = object.Key, object.Size
+	stored, err := i.Acquire(ctx, content)
+	if err != nil {
+		_ = provider.Delete(ctx, object.Key)
+		return nil, err
+	}
+	// The same content was stored concurrently, keep the indexed file
+	if stored.Key != object.Key {
+		_ = provider.Delete(ctx, object.Key)
+		if object, err = provider.Stat(ctx, stored.Key); err != nil {
+			return nil, err
+		}
+	}
+	object.Hash = content.Hash
+	return object, nil
+}
+
+// contentScope returns the scope of the content of a file field
+func contentScope(res resource.Resource, field resource.Field) string {
+	return res.GetName() + "." + field.Name
+}
+
+// hashContent returns the hex encoded SHA-256 of a file and rewinds it
+func hashContent(file io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hashObject returns the hex encoded SHA-256 of a stored object
+func hashObject(ctx context.Context, provider Provider, key string) (string, error) {
+	content, err := provider.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// findDuplicates returns the IDs of the records the repository may access whose file
+// field holds content with a hash. Nothing is looked up for fields accepting duplicates.
+func findDuplicates(ctx context.Context, contents *ContentIndex, db *gorm.DB, repo repository.Repository, res resource.Resource, field resource.Field, hash string, valueFunc func(resource.Field, string) string) ([]interface{}, error) {
+	if field.File == nil || field.File.OnDuplicate == resource.DuplicateAllow {
+		return nil, nil
+	}
+	content, err := contents.Find(ctx, contentScope(res, field), hash)
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	idColumn, column, err := fileColumns(db, res, field)
+	if err != nil {
+		return nil, err
+	}
+	value := valueFunc(field, content.Key)
+	query := repo.Query(ctx).Select(idColumn, column)
+	if field.File.Multiple {
+		query, err = whereFileElement(query, column, value)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		query = query.Where(column+" = ?", value)
+	}
+	var rows []map[string]interface{}
+	if err := query.Order(idColumn).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Values are compared as the garbage collector reads them
+	ids := []interface{}{}
+	for _, row := range rows {
+		for _, stored := range fileValues(row[column]) {
+			if stored == value {
+				ids = append(ids, row[idColumn])
+				break
+			}
+		}
+	}
+	return ids, nil
+}
+
+// whereFileElement restricts a query to the records whose multi-file column holds a
+// value: as the string element of a JSON array, as the key or url of an object
+// element, or as the whole column like fileValues reads it. Databases without JSON
+// functions are narrowed to the records with files.
+func whereFileElement(query *gorm.DB, column, value string) (*gorm.DB, error) {
+	candidates := []interface{}{value}
+	for _, element := range []interface{}{value, map[string]string{"key": value}, map[string]string{"url": value}} {
+		encoded, err := json.Marshal([]interface{}{element})
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, string(encoded))
+	}
+
+	switch query.Dialector.Name() {
+	case "sqlite":
+		return query.Where(column+" = ? OR EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid("+column+") THEN "+column+" ELSE '[]' END) AS element"+
+			" WHERE (element.type = 'text' AND element.value = ?)"+
+			" OR json_extract(CASE WHEN element.type = 'object' THEN element.value END, '$.key') = ?"+
+			" OR json_extract(CASE WHEN element.type = 'object' THEN element.value END, '$.url') = ?)", value, value, value, value), nil
+	case "postgres":
+		return query.Where(column+"::text = ? OR (CASE WHEN left(ltrim("+column+"::text), 1) = '[' THEN "+column+"::jsonb ELSE '[]'::jsonb END)"+
+			" @> ANY(ARRAY[?::jsonb, ?::jsonb, ?::jsonb])", candidates...), nil
+	case "mysql":
+		return query.Where(column+" = ? OR CASE WHEN JSON_VALID("+column+") THEN JSON_CONTAINS("+column+", ?)"+
+			" OR JSON_CONTAINS("+column+", ?) OR JSON_CONTAINS("+column+", ?) ELSE 0 END", candidates...), nil
+	default:
+		return query.Where(column + " IS NOT NULL AND " + column + " <> ''"), nil
+	}
+}
+
+// checkContentIndex panics when a resource has file fields checking duplicates but no
+// content index is configured
+func checkContentIndex(res resource.Resource, contents *ContentIndex) {
+	if contents != nil {
+		return
+	}
+	for _, field := range res.GetFields() {
+		if field.File != nil && field.File.OnDuplicate != resource.DuplicateAllow {
+			panic(fmt.Sprintf("file field %s.%s checks duplicates but the upload has no content index", res.GetName(), field.Name))
+		}
+	}
+}
+
+// respondDuplicate writes the response rejecting a duplicate upload
+func respondDuplicate(c *gin.Context, duplicates []interface{}) {
+	apierror.WriteWith(c, apierror.New(http.StatusConflict, ErrDuplicateFile.Error()), ErrDuplicateFile, gin.H{"duplicates": duplicates})
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDedupeTest(t *testing.T) (*gin.Engine, *gorm.DB, presigningProvider, *ContentIndex, resource.Resource) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Gallery{}))
+	require.NoError(t, db.Create(&[]Gallery{{ID: 1}, {ID: 2}}).Error)
+	contents := NewContentIndex(db)
+	require.NoError(t, contents.AutoMigrate())
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "galleries",
+		Model: Gallery{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "photo", Type: "file", File: &resource.FileConfig{StoragePath: "photos/"}},
+			{Name: "cover", Type: "file", File: &resource.FileConfig{StoragePath: "covers/", OnDuplicate: resource.DuplicateReject}},
+			{Name: "files", Type: "file", File: &resource.FileConfig{StoragePath: "files/", Multiple: true, OnDuplicate: resource.DuplicateWarn}},
+		},
+	})
+
+	provider := presigningProvider{NewLocalProvider(t.TempDir())}
+	r := gin.New()
+	api := r.Group("/api")
+	RegisterFileUploadRoutes(api, res, FileUploadConfig{Provider: provider, DB: db, Contents: contents})
+	RegisterSignedUploadRoutes(api, res, SignedUploadConfig{Provider: provider, DB: db, Secret: "secret", Contents: contents})
+	return r, db, provider, contents, res
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFileUploadDeduplicatesContent(t *testing.T) {
+	r, db, provider, contents, _ := setupDedupeTest(t)
+	ctx := context.Background()
+
+	w, first := uploadFile(r, "/api/galleries/1/files/photo", "a.txt", "text/plain", []byte("same content"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, sha256Hex("same content"), first.Data.Object.Hash)
+
+	w, second := uploadFile(r, "/api/galleries/2/files/photo", "b.txt", "text/plain", []byte("same content"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, first.Data.Object.Key, second.Data.Object.Key)
+	assert.Equal(t, first.Data.Object.Key, second.Data.Value)
+
+	objects, err := provider.List(ctx, "photos/")
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+
+	content, err := contents.Find(ctx, "galleries.photo", sha256Hex("same content"))
+	require.NoError(t, err)
+	require.NotNil(t, content)
+	assert.Equal(t, 2, content.RefCount)
+
+	// Failed attachments release their reference
+	w, _ = uploadFile(r, "/api/galleries/99/files/photo", "c.txt", "text/plain", []byte("same content"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	content, err = contents.Find(ctx, "galleries.photo", sha256Hex("same content"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, content.RefCount)
+
+	var gallery Gallery
+	require.NoError(t, db.First(&gallery, 2).Error)
+	assert.Equal(t, first.Data.Object.Key, gallery.Photo)
+}
+
+func TestFileUploadDuplicatePolicies(t *testing.T) {
+	r, _, _, _, _ := setupDedupeTest(t)
+
+	w, _ := uploadFile(r, "/api/galleries/1/files/cover", "a.txt", "text/plain", []byte("cover"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// The content of another field is no duplicate of the cover
+	w, _ = uploadFile(r, "/api/galleries/1/files/photo", "b.txt", "text/plain", []byte("photo"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w, _ = uploadFile(r, "/api/galleries/2/files/cover", "b.txt", "text/plain", []byte("photo"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w, _ = uploadFile(r, "/api/galleries/2/files/cover", "a.txt", "text/plain", []byte("cover"))
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var rejected struct {
+		Error      string        `json:"error"`
+		Duplicates []interface{} `json:"duplicates"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rejected))
+	assert.Equal(t, ErrDuplicateFile.Error(), rejected.Error)
+	assert.Equal(t, []interface{}{float64(1)}, rejected.Duplicates)
+
+	w, _ = uploadFile(r, "/api/galleries/1/files/files", "a.txt", "text/plain", []byte("file"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w, _ = uploadFile(r, "/api/galleries/2/files/files", "a.txt", "text/plain", []byte("file"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var warned struct {
+		Data struct {
+			Duplicates []interface{} `json:"duplicates"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &warned))
+	assert.Equal(t, []interface{}{float64(1)}, warned.Data.Duplicates)
+}
+
+func TestFileUploadDeduplicatesPerField(t *testing.T) {
+	r, _, provider, contents, _ := setupDedupeTest(t)
+	ctx := context.Background()
+
+	w, photo := uploadFile(r, "/api/galleries/1/files/photo", "a.txt", "text/plain", []byte("shared"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w, cover := uploadFile(r, "/api/galleries/1/files/cover", "a.txt", "text/plain", []byte("shared"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Each field stores the content under its own storage path
+	assert.True(t, strings.HasPrefix(photo.Data.Object.Key, "photos/"))
+	assert.True(t, strings.HasPrefix(cover.Data.Object.Key, "covers/"))
+	_, err := provider.Stat(ctx, cover.Data.Object.Key)
+	require.NoError(t, err)
+
+	for scope, key := range map[string]string{"galleries.photo": photo.Data.Object.Key, "galleries.cover": cover.Data.Object.Key} {
+		content, err := contents.Find(ctx, scope, sha256Hex("shared"))
+		require.NoError(t, err)
+		require.NotNil(t, content)
+		assert.Equal(t, key, content.Key)
+		assert.Equal(t, 1, content.RefCount)
+	}
+}
+
+func TestFindDuplicatesMatchesFileElements(t *testing.T) {
+	_, db, _, contents, res := setupDedupeTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Create(&[]Gallery{
+		{ID: 3, Files: `["files/a_b.txt"]`},
+		{ID: 4, Files: `["files/aXb.txt","files/a_b.txt.bak"]`},
+		{ID: 5, Files: `[{"key":"files/a_b.txt","name":"a.txt"}]`},
+		{ID: 6, Files: `files/a_b.txt`},
+	}).Error)
+	_, err := contents.Acquire(ctx, StoredContent{Scope: "galleries.files", Hash: "abc", Key: "files/a_b.txt"})
+	require.NoError(t, err)
+
+	ids, err := findDuplicates(ctx, contents, db, repository.NewGenericRepositoryWithResource(db, res), res, *res.GetField("files"), "abc", func(_ resource.Field, key string) string { return key })
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{uint(3), uint(5), uint(6)}, ids)
+
+	// Records are matched in the database, not with patterns
+	query, err := whereFileElement(db.Model(&Gallery{}), "files", "files/a_b.txt")
+	require.NoError(t, err)
+	var matched []uint
+	require.NoError(t, query.Order("id").Pluck("id", &matched).Error)
+	assert.Equal(t, []uint{3, 5, 6}, matched)
+}
+
+func TestSignedUploadDeduplicatesContent(t *testing.T) {
+	r, db, provider, _, _ := setupDedupeTest(t)
+	ctx := context.Background()
+
+	upload := func(id string) (string, *uploadResponse) {
+		_, resp := postUpload(r, "/api/galleries/"+id+"/uploads", UploadRequest{Field: "photo", Filename: "a.txt", ContentType: "text/plain"})
+		_, err := provider.Put(ctx, resp.Data.Key, strings.NewReader("uploaded"), "text/plain")
+		require.NoError(t, err)
+		w, confirmed := postUpload(r, "/api/galleries/"+id+"/uploads/confirm", UploadConfirmation{Field: "photo", Key: resp.Data.Key, Token: resp.Data.Token})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return resp.Data.Key, &confirmed
+	}
+
+	firstKey, first := upload("1")
+	assert.Equal(t, firstKey, first.Data.Value)
+	secondKey, second := upload("2")
+	assert.Equal(t, firstKey, second.Data.Value)
+
+	// The second upload was dropped
+	_, err := provider.Stat(ctx, secondKey)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var gallery Gallery
+	require.NoError(t, db.First(&gallery, 2).Error)
+	assert.Equal(t, firstKey, gallery.Photo)
+}
+
+func TestGCReconcilesContentIndex(t *testing.T) {
+	r, db, provider, contents, res := setupDedupeTest(t)
+	ctx := context.Background()
+
+	_, resp := uploadFile(r, "/api/galleries/1/files/photo", "a.txt", "text/plain", []byte("shared"))
+	uploadFile(r, "/api/galleries/2/files/photo", "a.txt", "text/plain", []byte("shared"))
+	_, unused := uploadFile(r, "/api/galleries/1/files/cover", "b.txt", "text/plain", []byte("replaced"))
+
+	// Records changed outside the upload routes
+	require.NoError(t, db.Model(&Gallery{}).Where("id = ?", 2).Update("photo", "").Error)
+	require.NoError(t, db.Model(&Gallery{}).Where("id = ?", 1).Update("cover", "").Error)
+
+	gc := NewGarbageCollector(GCConfig{Provider: provider, DB: db, Resources: []resource.Resource{res}, Contents: contents})
+	gc.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	report, err := gc.Cleanup(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{unused.Data.Object.Key}, report.Deleted)
+
+	content, err := contents.Find(ctx, "galleries.photo", sha256Hex("shared"))
+	require.NoError(t, err)
+	require.NotNil(t, content)
+	assert.Equal(t, resp.Data.Object.Key, content.Key)
+	assert.Equal(t, 1, content.RefCount)
+
+	content, err = contents.Find(ctx, "galleries.cover", sha256Hex("replaced"))
+	require.NoError(t, err)
+	assert.Nil(t, content)
+}
+
+func TestContentIndexRelease(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	contents := NewContentIndex(db)
+	require.NoError(t, contents.AutoMigrate())
+	ctx := context.Background()
+
+	stored, err := contents.Acquire(ctx, StoredContent{Scope: "galleries.files", Hash: "abc", Key: "files/a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.RefCount)
+
+	// The first key of a content is kept
+	stored, err = contents.Acquire(ctx, StoredContent{Scope: "galleries.files", Hash: "abc", Key: "files/b.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, "files/a.txt", stored.Key)
+	assert.Equal(t, 2, stored.RefCount)
+
+	require.NoError(t, contents.Release(ctx, "files/a.txt"))
+	stored, err = contents.Find(ctx, "galleries.files", "abc")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.RefCount)
+
+	require.NoError(t, contents.Release(ctx, "files/a.txt"))
+	stored, err = contents.Find(ctx, "galleries.files", "abc")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestDuplicateChecksNeedContentIndex(t *testing.T) {
+	_, db, provider, _, res := setupDedupeTest(t)
+
+	assert.Panics(t, func() {
+		GenerateFileUploadHandler(res, FileUploadConfig{Provider: provider, DB: db})
+	})
+	assert.Panics(t, func() {
+		GenerateUploadConfirmHandler(res, SignedUploadConfig{Provider: provider, DB: db})
+	})
+}
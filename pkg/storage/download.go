@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// RangeOpener is implemented by providers that can read part of an object. Providers
+// whose Open result is not an io.ReadSeeker need it to serve Range requests.
+type RangeOpener interface {
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// DownloadConfig contains configuration for file downloads
+type DownloadConfig struct {
+	// Storage provider holding the files
+	Provider Provider
+
+	// Database holding the records
+	DB *gorm.DB
+
+	// Default Content-Disposition type, "attachment" or "inline" (default "attachment").
+	// Clients may override it with ?disposition=inline|attachment.
+	Disposition string
+
+	// Cache-Control max-age in seconds (default 0, revalidate with ETag/Last-Modified)
+	MaxAge int
+
+	// KeyFromValue converts a stored field value to a storage key (default DefaultKeyFromValue)
+	KeyFromValue func(field resource.Field, value string) string
+
+	// Repository of the resource, through which records are read (see
+	// FileUploadConfig.Repository)
+	Repository repository.Repository
+}
+
+// RegisterDownloadRoutes registers GET /<resource>/:id/files/:field serving the file
+// referenced by a record's file field. Multi-file fields select a file with ?key=,
+// and image fields serve their thumbnails with ?thumbnail=<name>.
+func RegisterDownloadRoutes(router *gin.RouterGroup, res resource.Resource, config DownloadConfig) {
+	router.GET("/"+res.GetName()+"/:id/files/:field", GenerateDownloadHandler(res, config))
+}
+
+// GenerateDownloadHandler generates a handler serving stored files with support for
+// Range requests, conditional requests and Content-Disposition
+func GenerateDownloadHandler(res resource.Resource, config DownloadConfig) gin.HandlerFunc {
+	if config.Disposition == "" {
+		config.Disposition = "attachment"
+	}
+	if config.KeyFromValue == nil {
+		config.KeyFromValue = DefaultKeyFromValue
+	}
+	config.Repository = recordRepository(config.DB, res, config.Repository)
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		field, err := fileField(res, c.Param("field"))
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		value, err := loadFileValue(ctx, config.DB, config.Repository, res, field, c.Param("id"))
+		if err != nil {
+			respondRecordError(c, err)
+			return
+		}
+
+		key := selectFileKey(field, fileValues(value), c.Query("key"), config.KeyFromValue)
+		if key == "" {
+			apierror.RespondMessage(c, http.StatusNotFound, "File not found")
+			return
+		}
+		if name := c.Query("thumbnail"); name != "" {
+			if _, ok := thumbnailSize(field, name); !ok {
+				apierror.RespondMessage(c, http.StatusNotFound, "Thumbnail not found")
+				return
+			}
+			key = ThumbnailKey(key, name)
+		}
+
+		object, err := config.Provider.Stat(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			apierror.RespondMessage(c, http.StatusNotFound, "File not found")
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		disposition := config.Disposition
+		if requested := c.Query("disposition"); requested == "inline" || requested == "attachment" {
+			disposition = requested
+		}
+
+		header := c.Writer.Header()
+		header.Set("ETag", objectETag(object))
+		header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", config.MaxAge))
+		header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(key)}))
+		if object.ContentType != "" {
+			header.Set("Content-Type", object.ContentType)
+		}
+
+		content, err := openSeekable(ctx, config.Provider, object)
+		if errors.Is(err, ErrNotFound) {
+			apierror.RespondMessage(c, http.StatusNotFound, "File not found")
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		defer content.Close()
+
+		if seeker, ok := content.(io.ReadSeeker); ok {
+			http.ServeContent(c.Writer, c.Request, path.Base(key), object.ModTime, seeker)
+			return
+		}
+
+		// The provider cannot seek, serve the whole file
+		if utils.IsETagMatch(header.Get("ETag"), c.GetHeader("If-None-Match")) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		header.Set("Accept-Ranges", "none")
+		header.Set("Content-Length", strconv.FormatInt(object.Size, 10))
+		if !object.ModTime.IsZero() {
+			header.Set("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
+		}
+		c.Status(http.StatusOK)
+		if c.Request.Method != http.MethodHead {
+			_, _ = io.Copy(c.Writer, content)
+		}
+	}
+}
+
+// selectFileKey returns the storage key to serve, honouring an explicitly requested key
+func selectFileKey(field resource.Field, values []string, requested string, keyFromValue func(resource.Field, string) string) string {
+	for _, value := range values {
+		key := keyFromValue(field, value)
+		if key == "" {
+			continue
+		}
+		if requested == "" || requested == key || requested == value {
+			return key
+		}
+	}
+	return ""
+}
+
+// objectETag derives a strong ETag from the object's key, size and modification time
+func objectETag(object *Object) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", object.Key, object.Size, object.ModTime.UnixNano())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// openSeekable opens an object, wrapping range capable providers in a seeker
+func openSeekable(ctx context.Context, provider Provider, object *Object) (io.ReadCloser, error) {
+	if opener, ok := provider.(RangeOpener); ok {
+		return &rangeSeeker{ctx: ctx, opener: opener, key: object.Key, size: object.Size}, nil
+	}
+	return provider.Open(ctx, object.Key)
+}
+
+// rangeSeeker implements io.ReadSeeker on top of ranged reads
+type rangeSeeker struct {
+	ctx    context.Context
+	opener RangeOpener
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// Read reads from the current offset, opening a ranged read when needed
+func (s *rangeSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.body == nil {
+		body, err := s.opener.OpenRange(s.ctx, s.key, s.offset, s.size-s.offset)
+		if err != nil {
+			return 0, err
+		}
+		s.body = body
+	}
+	n, err := s.body.Read(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+// Seek moves the offset; the next Read opens a new ranged read
+func (s *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	if offset != s.offset && s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+	s.offset = offset
+	return offset, nil
+}
+
+// Close closes the current ranged read
+func (s *rangeSeeker) Close() error {
+	if s.body != nil {
+		return s.body.Close()
+	}
+	return nil
+}
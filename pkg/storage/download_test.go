@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupDownloadTest(t *testing.T, provider Provider) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+	require.NoError(t, db.Create(&Document{
+		ID:          1,
+		Cover:       "https://cdn.example.com/covers/video.mp4",
+		Attachments: `["attachments/a.txt","attachments/b.txt"]`,
+	}).Error)
+	require.NoError(t, db.Create(&Document{ID: 2, Cover: "covers/missing.mp4"}).Error)
+
+	ctx := context.Background()
+	_, err = provider.Put(ctx, "covers/video.mp4", strings.NewReader("0123456789"), "video/mp4")
+	require.NoError(t, err)
+	_, err = provider.Put(ctx, "attachments/a.txt", strings.NewReader("first"), "text/plain")
+	require.NoError(t, err)
+	_, err = provider.Put(ctx, "attachments/b.txt", strings.NewReader("second"), "text/plain")
+	require.NoError(t, err)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "documents",
+		Model: Document{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "cover", Type: "file", File: &resource.FileConfig{BaseURL: "https://cdn.example.com"}},
+			{Name: "attachments", Type: "file", File: &resource.FileConfig{Multiple: true}},
+		},
+	})
+
+	r := gin.New()
+	RegisterDownloadRoutes(r.Group("/api"), res, DownloadConfig{Provider: provider, DB: db})
+	return r
+}
+
+func download(r *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func testDownloads(t *testing.T, r *gin.Engine) {
+	w := download(r, "/api/documents/1/files/cover", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, "video/mp4", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, `attachment; filename=video.mp4`, w.Header().Get("Content-Disposition"))
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = download(r, "/api/documents/1/files/cover", map[string]string{"Range": "bytes=2-5"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+	assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+
+	// Resume from an offset
+	w = download(r, "/api/documents/1/files/cover", map[string]string{"Range": "bytes=7-", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "789", w.Body.String())
+
+	w = download(r, "/api/documents/1/files/cover", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = download(r, "/api/documents/1/files/cover?disposition=inline", nil)
+	assert.Equal(t, `inline; filename=video.mp4`, w.Header().Get("Content-Disposition"))
+
+	w = download(r, "/api/documents/1/files/attachments", nil)
+	assert.Equal(t, "first", w.Body.String())
+	w = download(r, "/api/documents/1/files/attachments?key=attachments/b.txt", nil)
+	assert.Equal(t, "second", w.Body.String())
+
+	// Only files referenced by the record can be downloaded
+	w = download(r, "/api/documents/1/files/attachments?key=covers/video.mp4", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = download(r, "/api/documents/2/files/cover", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = download(r, "/api/documents/3/files/cover", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = download(r, "/api/documents/1/files/title", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDownloadLocal(t *testing.T) {
+	testDownloads(t, setupDownloadTest(t, NewLocalProvider(t.TempDir())))
+}
+
+func TestDownloadS3Ranges(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{objects: map[string]string{}, types: map[string]string{}})
+	defer server.Close()
+
+	provider := NewS3Provider(S3Config{Bucket: "bucket", Endpoint: server.URL, PathStyle: true, AccessKeyID: "key", SecretAccessKey: "secret"})
+	testDownloads(t, setupDownloadTest(t, provider))
+}
+
+// streamingProvider hides the seeker of the local provider
+type streamingProvider struct {
+	*LocalProvider
+}
+
+func (p streamingProvider) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := p.LocalProvider.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{file, file}, nil
+}
+
+func TestDownloadWithoutRangeSupport(t *testing.T) {
+	r := setupDownloadTest(t, streamingProvider{NewLocalProvider(t.TempDir())})
+
+	w := download(r, "/api/documents/1/files/cover", map[string]string{"Range": "bytes=2-5"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, "none", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+
+	w = download(r, "/api/documents/1/files/cover", map[string]string{"If-None-Match": w.Header().Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestDownloadScopedToOwner(t *testing.T) {
+	provider := NewLocalProvider(t.TempDir())
+	r, db := setupOwnedFileTest(t, func(api *gin.RouterGroup, db *gorm.DB, res resource.Resource) {
+		RegisterDownloadRoutes(api, res, DownloadConfig{Provider: provider, DB: db})
+	})
+	for id, key := range map[uint]string{1: "photos/alice.txt", 2: "photos/bob.txt", 3: "photos/deleted.txt"} {
+		_, err := provider.Put(context.Background(), key, strings.NewReader(key), "text/plain")
+		require.NoError(t, err)
+		require.NoError(t, db.Unscoped().Model(&OwnedGallery{}).Where("id = ?", id).Update("photo", key).Error)
+	}
+
+	w := download(r, "/api/galleries/1/files/photo?owner=alice", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "photos/alice.txt", w.Body.String())
+
+	// Files of other owners' records and of deleted records are not served
+	assert.Equal(t, http.StatusNotFound, download(r, "/api/galleries/2/files/photo?owner=alice", nil).Code)
+	assert.Equal(t, http.StatusNotFound, download(r, "/api/galleries/3/files/photo?owner=alice", nil).Code)
+	assert.Equal(t, http.StatusOK, download(r, "/api/galleries/2/files/photo?owner=bob", nil).Code)
+}
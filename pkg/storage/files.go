@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Image upload errors
+var (
+	ErrInvalidImage  = errors.New("file is not a valid image")
+	ErrImageTooLarge = errors.New("image exceeds the maximum dimensions")
+)
+
+// defaultMaxUploadSize limits uploads to fields without FileConfig.MaxSize
+const defaultMaxUploadSize = 32 << 20
+
+// FileUploadConfig contains configuration for uploads through the API
+type FileUploadConfig struct {
+	// Storage provider receiving the files
+	Provider Provider
+
+	// Database holding the records
+	DB *gorm.DB
+
+	// Maximum upload size for fields without FileConfig.MaxSize (default 32MB)
+	MaxUploadSize int64
+
+	// KeyFunc generates the storage key (default <StoragePath><uuid><ext>)
+	KeyFunc func(field resource.Field, recordID, filename string) string
+
+	// ValueFunc converts a storage key to the value stored in the record (default
+	// <BaseURL>/<key>, or the key when the field has no BaseURL)
+	ValueFunc func(field resource.Field, key string) string
+
+	// Contents stores identical uploads once and detects duplicates (see
+	// FileConfig.OnDuplicate). Uploads are not deduplicated without it.
+	Contents *ContentIndex
+
+	// Repository of the resource, through which records are read and files attached,
+	// so its owner, tenant and WithScope scopes apply. Defaults to an owner repository
+	// for owner resources and a generic repository otherwise; tenant resources must set
+	// it.
+	Repository repository.Repository
+}
+
+// RegisterFileUploadRoutes registers POST /<resource>/:id/files/:field receiving a
+// multipart upload (form field "file") for a record's file field. Register the
+// download routes on the same router to serve the files without a BaseURL.
+func RegisterFileUploadRoutes(router *gin.RouterGroup, res resource.Resource, config FileUploadConfig) {
+	router.POST("/"+res.GetName()+"/:id/files/:field", GenerateFileUploadHandler(res, config))
+}
+
+// GenerateFileUploadHandler generates a handler storing an uploaded file, validating it
+// against the field's FileConfig (type, size, image dimensions), generating thumbnails
+// for image fields and attaching the file to the record. With a content index, files
+// whose content is already stored are attached without storing them again.
+func GenerateFileUploadHandler(res resource.Resource, config FileUploadConfig) gin.HandlerFunc {
+	checkContentIndex(res, config.Contents)
+	if config.MaxUploadSize <= 0 {
+		config.MaxUploadSize = defaultMaxUploadSize
+	}
+	signed := SignedUploadConfig{KeyFunc: config.KeyFunc, ValueFunc: config.ValueFunc}.withDefaults()
+	config.KeyFunc, config.ValueFunc = signed.KeyFunc, signed.ValueFunc
+	config.Repository = recordRepository(config.DB, res, config.Repository)
+
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+		ctx := c.Request.Context()
+
+		field, err := fileField(res, c.Param("field"))
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		maxSize := config.MaxUploadSize
+		if field.File != nil && field.File.MaxSize > 0 {
+			maxSize = field.File.MaxSize
+		}
+		// Leave room for the multipart envelope, the file size is checked below
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.RespondMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s of %d bytes", ErrFileTooLarge, maxSize))
+				return
+			}
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		defer file.Close()
+
+		contentType, err := detectContentType(file, header)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := validateFile(field, contentType, header.Size); err != nil {
+			apierror.Respond(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		var img image.Image
+		var format string
+		if field.File != nil && field.File.IsImage {
+			if img, format, err = validateImage(file, field); err != nil {
+				apierror.Respond(c, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+
+		id := c.Param("id")
+		if _, err := loadFileValue(ctx, config.DB, config.Repository, res, field, id); err != nil {
+			respondRecordError(c, err)
+			return
+		}
+
+		var hash string
+		var duplicates []interface{}
+		if config.Contents != nil {
+			hash, err = hashContent(file)
+			if err == nil {
+				duplicates, err = findDuplicates(ctx, config.Contents, config.DB, config.Repository, res, field, hash, config.ValueFunc)
+			}
+			if err != nil {
+				apierror.Respond(c, http.StatusInternalServerError, err)
+				return
+			}
+			if len(duplicates) > 0 && field.File.OnDuplicate == resource.DuplicateReject {
+				respondDuplicate(c, duplicates)
+				return
+			}
+		}
+
+		key := config.KeyFunc(field, id, header.Filename)
+		put := func() (*Object, error) {
+			return config.Provider.Put(ctx, key, file, contentType)
+		}
+		var object *Object
+		if config.Contents != nil {
+			object, err = config.Contents.Store(ctx, config.Provider, StoredContent{Scope: contentScope(res, field), Hash: hash, ContentType: contentType}, put)
+		} else {
+			object, err = put()
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		// Stored content is shared with earlier uploads to the field, which keep it
+		reused := object.Key != key
+		key = object.Key
+		removeUpload := func() {
+			if config.Contents != nil {
+				_ = config.Contents.Release(ctx, key)
+			}
+			if reused {
+				return
+			}
+			_ = config.Provider.Delete(ctx, key)
+			for _, thumbnailKey := range ThumbnailKeys(field, key) {
+				_ = config.Provider.Delete(ctx, thumbnailKey)
+			}
+		}
+
+		thumbnails := map[string]string{}
+		if img != nil {
+			if _, err := GenerateThumbnails(ctx, config.Provider, field, key, img, format); err != nil {
+				removeUpload()
+				apierror.Respond(c, http.StatusInternalServerError, err)
+				return
+			}
+			for _, size := range field.File.ThumbnailSizes {
+				thumbnails[size.Name] = FileURL(c, field, key, size.Name)
+			}
+		}
+
+		stored, err := attachFile(ctx, config.DB, config.Repository, res, field, id, config.ValueFunc(field, key))
+		if err != nil {
+			removeUpload()
+			respondRecordError(c, err)
+			return
+		}
+
+		data := gin.H{
+			"field":      field.Name,
+			"value":      stored,
+			"url":        FileURL(c, field, key, ""),
+			"thumbnails": thumbnails,
+			"object":     object,
+		}
+		if len(duplicates) > 0 {
+			data["duplicates"] = duplicates
+		}
+		c.JSON(http.StatusCreated, gin.H{"data": data})
+	}
+}
+
+// FileURL returns the URL of a stored file, or of one of its thumbnails. Fields with a
+// BaseURL are served from it; others through the download route of the current
+// request path (/<resource>/:id/files/:field).
+func FileURL(c *gin.Context, field resource.Field, key, thumbnail string) string {
+	if field.File != nil && field.File.BaseURL != "" {
+		if thumbnail != "" {
+			key = ThumbnailKey(key, thumbnail)
+		}
+		return strings.TrimSuffix(field.File.BaseURL, "/") + "/" + key
+	}
+
+	query := url.Values{}
+	if field.File != nil && field.File.Multiple {
+		query.Set("key", key)
+	}
+	if thumbnail != "" {
+		query.Set("thumbnail", thumbnail)
+	}
+	if len(query) == 0 {
+		return c.Request.URL.Path
+	}
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+// detectContentType sniffs the content type of an upload, falling back to the type
+// declared by the client for content the sniffer does not recognise
+func detectContentType(file multipart.File, header *multipart.FileHeader) (string, error) {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(sniff[:n])
+	if declared := header.Header.Get("Content-Type"); contentType == "application/octet-stream" && declared != "" {
+		contentType = declared
+	}
+	return contentType, nil
+}
+
+// validateImage checks that an upload is an image within the field's maximum
+// dimensions. The image is decoded only when thumbnails are generated.
+func validateImage(file multipart.File, field resource.Field) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if (field.File.MaxWidth > 0 && config.Width > field.File.MaxWidth) || (field.File.MaxHeight > 0 && config.Height > field.File.MaxHeight) {
+		return nil, "", fmt.Errorf("%w of %dx%d", ErrImageTooLarge, field.File.MaxWidth, field.File.MaxHeight)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	if !HasThumbnails(field) {
+		return nil, format, nil
+	}
+
+	img, format, err := image.Decode(file)
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
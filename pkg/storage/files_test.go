@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Gallery struct {
+	ID    uint   `json:"id" gorm:"primaryKey"`
+	Photo string `json:"photo"`
+	Cover string `json:"cover"`
+	Files string `json:"files"`
+}
+
+type fileUploadResponse struct {
+	Data struct {
+		Value      interface{}       `json:"value"`
+		URL        string            `json:"url"`
+		Thumbnails map[string]string `json:"thumbnails"`
+		Object     Object            `json:"object"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+func setupFileUploadTest(t *testing.T) (*gin.Engine, *gorm.DB, *LocalProvider, resource.Resource) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Gallery{}))
+	require.NoError(t, db.Create(&Gallery{ID: 1}).Error)
+
+	imageConfig := func(baseURL string) *resource.FileConfig {
+		return &resource.FileConfig{
+			StoragePath:        "photos/",
+			BaseURL:            baseURL,
+			AllowedTypes:       []string{"image/png", "image/jpeg"},
+			MaxSize:            1 << 16,
+			IsImage:            true,
+			MaxWidth:           100,
+			MaxHeight:          100,
+			GenerateThumbnails: true,
+			ThumbnailSizes:     []resource.ThumbnailSize{{Name: "small", Width: 10, Height: 10, KeepAspectRatio: true}},
+		}
+	}
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "galleries",
+		Model: Gallery{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "photo", Type: "file", File: imageConfig("")},
+			{Name: "cover", Type: "file", File: imageConfig("https://cdn.example.com")},
+			{Name: "files", Type: "file", File: &resource.FileConfig{StoragePath: "files/", Multiple: true, MaxSize: 16}},
+		},
+	})
+
+	provider := NewLocalProvider(t.TempDir())
+	r := gin.New()
+	api := r.Group("/api")
+	RegisterFileUploadRoutes(api, res, FileUploadConfig{Provider: provider, DB: db})
+	RegisterDownloadRoutes(api, res, DownloadConfig{Provider: provider, DB: db})
+	return r, db, provider, res
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func uploadFile(r *gin.Engine, path, filename, contentType string, content []byte) (*httptest.ResponseRecorder, fileUploadResponse) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(map[string][]string)
+	header["Content-Disposition"] = []string{`form-data; name="file"; filename="` + filename + `"`}
+	header["Content-Type"] = []string{contentType}
+	part, _ := writer.CreatePart(header)
+	part.Write(content)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r.ServeHTTP(w, req)
+
+	var resp fileUploadResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestFileUploadWithThumbnails(t *testing.T) {
+	r, db, provider, _ := setupFileUploadTest(t)
+	ctx := context.Background()
+
+	w, resp := uploadFile(r, "/api/galleries/1/files/photo", "Sunset.PNG", "image/png", testPNG(t, 40, 20))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	key := resp.Data.Object.Key
+	assert.True(t, strings.HasPrefix(key, "photos/"))
+	assert.True(t, strings.HasSuffix(key, ".png"))
+	assert.Equal(t, "image/png", resp.Data.Object.ContentType)
+	assert.Equal(t, key, resp.Data.Value)
+	assert.Equal(t, "/api/galleries/1/files/photo", resp.Data.URL)
+	assert.Equal(t, "/api/galleries/1/files/photo?thumbnail=small", resp.Data.Thumbnails["small"])
+
+	var gallery Gallery
+	require.NoError(t, db.First(&gallery, 1).Error)
+	assert.Equal(t, key, gallery.Photo)
+
+	// The thumbnail keeps the aspect ratio within 10x10
+	content, err := provider.Open(ctx, ThumbnailKey(key, "small"))
+	require.NoError(t, err)
+	defer content.Close()
+	thumbnail, err := png.DecodeConfig(content)
+	require.NoError(t, err)
+	assert.Equal(t, 10, thumbnail.Width)
+	assert.Equal(t, 5, thumbnail.Height)
+
+	// Both are served by the download route
+	dw := httptest.NewRecorder()
+	r.ServeHTTP(dw, httptest.NewRequest(http.MethodGet, resp.Data.Thumbnails["small"], nil))
+	assert.Equal(t, http.StatusOK, dw.Code)
+	assert.Equal(t, "image/png", dw.Header().Get("Content-Type"))
+
+	dw = httptest.NewRecorder()
+	r.ServeHTTP(dw, httptest.NewRequest(http.MethodGet, "/api/galleries/1/files/photo?thumbnail=large", nil))
+	assert.Equal(t, http.StatusNotFound, dw.Code)
+}
+
+func TestFileUploadURLs(t *testing.T) {
+	r, _, _, _ := setupFileUploadTest(t)
+
+	w, resp := uploadFile(r, "/api/galleries/1/files/cover", "cover.png", "image/png", testPNG(t, 8, 8))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	key := resp.Data.Object.Key
+	assert.Equal(t, "https://cdn.example.com/"+key, resp.Data.Value)
+	assert.Equal(t, "https://cdn.example.com/"+key, resp.Data.URL)
+	assert.Equal(t, "https://cdn.example.com/"+ThumbnailKey(key, "small"), resp.Data.Thumbnails["small"])
+
+	w, resp = uploadFile(r, "/api/galleries/1/files/files", "notes.txt", "text/plain", []byte("hello"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, []interface{}{resp.Data.Object.Key}, resp.Data.Value)
+	assert.Equal(t, "/api/galleries/1/files/files?key="+strings.ReplaceAll(resp.Data.Object.Key, "/", "%2F"), resp.Data.URL)
+	assert.Empty(t, resp.Data.Thumbnails)
+
+	dw := httptest.NewRecorder()
+	r.ServeHTTP(dw, httptest.NewRequest(http.MethodGet, resp.Data.URL, nil))
+	assert.Equal(t, http.StatusOK, dw.Code)
+	assert.Equal(t, "hello", dw.Body.String())
+}
+
+func TestFileUploadValidation(t *testing.T) {
+	r, _, provider, _ := setupFileUploadTest(t)
+
+	w, _ := uploadFile(r, "/api/galleries/1/files/id", "a.png", "image/png", testPNG(t, 4, 4))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The declared type is not trusted
+	w, resp := uploadFile(r, "/api/galleries/1/files/photo", "a.png", "image/png", []byte("not an image"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, resp.Error, ErrFileTypeNotAllowed.Error())
+
+	w, resp = uploadFile(r, "/api/galleries/1/files/photo", "big.png", "image/png", testPNG(t, 200, 20))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, resp.Error, ErrImageTooLarge.Error())
+
+	w, resp = uploadFile(r, "/api/galleries/1/files/files", "big.txt", "text/plain", []byte(strings.Repeat("x", 17)))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, resp.Error, ErrFileTooLarge.Error())
+
+	w, _ = uploadFile(r, "/api/galleries/99/files/photo", "a.png", "image/png", testPNG(t, 4, 4))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Nothing was stored
+	objects, err := provider.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+type OwnedGallery struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	OwnerID   string         `json:"ownerId"`
+	Photo     string         `json:"photo"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// setupOwnedFileTest registers file routes of galleries owned by the user of the owner
+// query parameter: alice owns gallery 1, bob gallery 2, and alice's gallery 3 is deleted
+func setupOwnedFileTest(t *testing.T, register func(api *gin.RouterGroup, db *gorm.DB, res resource.Resource)) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&OwnedGallery{}))
+	require.NoError(t, db.Create(&[]OwnedGallery{{ID: 1, OwnerID: "alice"}, {ID: 2, OwnerID: "bob"}, {ID: 3, OwnerID: "alice"}}).Error)
+	require.NoError(t, db.Delete(&OwnedGallery{}, 3).Error)
+
+	res := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:  "galleries",
+		Model: OwnedGallery{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "photo", Type: "file", File: &resource.FileConfig{StoragePath: "photos/"}},
+		},
+	}), resource.OwnerConfig{OwnerField: "OwnerID", EnforceOwnership: true})
+
+	r := gin.New()
+	api := r.Group("/api", func(c *gin.Context) {
+		requestctx.OwnerID.Set(c, c.Query("owner"))
+	})
+	register(api, db, res)
+	return r, db
+}
+
+func TestFileUploadScopedToOwner(t *testing.T) {
+	provider := NewLocalProvider(t.TempDir())
+	r, db := setupOwnedFileTest(t, func(api *gin.RouterGroup, db *gorm.DB, res resource.Resource) {
+		RegisterFileUploadRoutes(api, res, FileUploadConfig{Provider: provider, DB: db})
+	})
+
+	w, _ := uploadFile(r, "/api/galleries/1/files/photo?owner=alice", "a.txt", "text/plain", []byte("mine"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Records of other owners and deleted records are not found
+	w, _ = uploadFile(r, "/api/galleries/2/files/photo?owner=alice", "a.txt", "text/plain", []byte("theirs"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = uploadFile(r, "/api/galleries/3/files/photo?owner=alice", "a.txt", "text/plain", []byte("deleted"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var galleries []OwnedGallery
+	require.NoError(t, db.Unscoped().Order("id").Find(&galleries).Error)
+	assert.NotEmpty(t, galleries[0].Photo)
+	assert.Empty(t, galleries[1].Photo)
+	assert.Empty(t, galleries[2].Photo)
+}
+
+func TestGCKeepsThumbnails(t *testing.T) {
+	r, db, provider, res := setupFileUploadTest(t)
+
+	w, resp := uploadFile(r, "/api/galleries/1/files/photo", "a.png", "image/png", testPNG(t, 20, 20))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	_, err := provider.Put(context.Background(), "photos/orphan_small.png", strings.NewReader("x"), "image/png")
+	require.NoError(t, err)
+
+	gc := NewGarbageCollector(GCConfig{Provider: provider, DB: db, Resources: []resource.Resource{res}})
+	gc.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	report, err := gc.Scan(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, report.Orphans, 1)
+	assert.Equal(t, "photos/orphan_small.png", report.Orphans[0].Key)
+	assert.NotEqual(t, ThumbnailKey(resp.Data.Object.Key, "small"), report.Orphans[0].Key)
+}
+
+func TestResizeImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+
+	assert.Equal(t, image.Rect(0, 0, 20, 10), resizeImage(img, resource.ThumbnailSize{Width: 20, Height: 20, KeepAspectRatio: true}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 20, 20), resizeImage(img, resource.ThumbnailSize{Width: 20, Height: 20}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 60, 30), resizeImage(img, resource.ThumbnailSize{Height: 30}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 100, 50), resizeImage(img, resource.ThumbnailSize{Width: 400, Height: 400, KeepAspectRatio: true}).Bounds())
+}
@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrTooManyOrphans is returned by Cleanup when more orphans were found than allowed,
+// which usually indicates a misconfiguration (wrong database, prefix or base URL)
+var ErrTooManyOrphans = errors.New("too many orphaned files, refusing to clean up")
+
+// FileReference is a reference to a stored file from a record
+type FileReference struct {
+	Resource string      `json:"resource"`
+	RecordID interface{} `json:"recordId"`
+	Field    string      `json:"field"`
+	Key      string      `json:"key"`
+}
+
+// GCReport is the result of a garbage collection scan
+type GCReport struct {
+	ScannedAt  time.Time `json:"scannedAt"`
+	Referenced int       `json:"referenced"`
+	Stored     int       `json:"stored"`
+
+	// Orphans are stored files no longer referenced by any record
+	Orphans []Object `json:"orphans"`
+
+	// Missing are references to files that do not exist in storage
+	Missing []FileReference `json:"missing"`
+
+	// Deleted lists keys removed by a cleanup run
+	Deleted []string `json:"deleted,omitempty"`
+
+	// references counts the references to each key
+	references map[string]int
+}
+
+// GCConfig contains configuration for the file garbage collector
+type GCConfig struct {
+	// Storage provider holding the files
+	Provider Provider
+
+	// Database holding the records
+	DB *gorm.DB
+
+	// Resources whose file fields are scanned (defaults to the global registry)
+	Resources []resource.Resource
+
+	// Storage prefixes scanned for orphans (defaults to the StoragePath of all file
+	// fields, or the whole storage when a file field has no StoragePath)
+	Prefixes []string
+
+	// Files modified within this period are never reported as orphans, so uploads
+	// not yet attached to a record survive (default 24h)
+	GracePeriod time.Duration
+
+	// Maximum number of orphans Cleanup deletes in one run (default 1000)
+	MaxDeletes int
+
+	// Number of records loaded per query (default 500)
+	BatchSize int
+
+	// KeyFromValue converts a stored field value to a storage key. Return an empty
+	// string to ignore the value (default strips FileConfig.BaseURL and leading slashes,
+	// ignoring external URLs).
+	KeyFromValue func(field resource.Field, value string) string
+
+	// Contents is the content index of the uploads, whose reference counts Cleanup
+	// reconciles with the records and whose deleted files it forgets
+	Contents *ContentIndex
+}
+
+// GarbageCollector detects orphaned files and stale file references
+type GarbageCollector struct {
+	config GCConfig
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewGarbageCollector creates a new garbage collector
+func NewGarbageCollector(config GCConfig) *GarbageCollector {
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = 24 * time.Hour
+	}
+	if config.MaxDeletes <= 0 {
+		config.MaxDeletes = 1000
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.KeyFromValue == nil {
+		config.KeyFromValue = DefaultKeyFromValue
+	}
+	return &GarbageCollector{config: config, now: time.Now}
+}
+
+// DefaultKeyFromValue strips the field's BaseURL and leading slashes from a stored value.
+// Absolute URLs pointing elsewhere are ignored.
+func DefaultKeyFromValue(field resource.Field, value string) string {
+	if field.File != nil && field.File.BaseURL != "" {
+		value = strings.TrimPrefix(value, strings.TrimSuffix(field.File.BaseURL, "/")+"/")
+	}
+	if strings.Contains(value, "://") {
+		return ""
+	}
+	return strings.TrimLeft(value, "/")
+}
+
+// IsFileField reports whether a field stores file references
+func IsFileField(field resource.Field) bool {
+	return field.Type == "file" || field.File != nil
+}
+
+// Scan compares file references in the database with the files in storage
+func (g *GarbageCollector) Scan(ctx context.Context) (*GCReport, error) {
+	report := &GCReport{ScannedAt: g.now(), Orphans: []Object{}, Missing: []FileReference{}}
+
+	resources := g.config.Resources
+	if resources == nil {
+		resources = resource.GlobalResourceRegistry.GetAll()
+	}
+
+	referenced := make(map[string]bool)
+	report.references = make(map[string]int)
+	var references []FileReference
+	prefixes := g.config.Prefixes
+	scanAll := false
+
+	for _, res := range resources {
+		var fields []resource.Field
+		for _, field := range res.GetFields() {
+			if IsFileField(field) {
+				fields = append(fields, field)
+				if field.File == nil || field.File.StoragePath == "" {
+					scanAll = true
+				} else {
+					prefixes = appendPrefix(prefixes, field.File.StoragePath)
+				}
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		refs, err := g.collectReferences(ctx, res, fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", res.GetName(), err)
+		}
+		for _, ref := range refs {
+			referenced[ref.Key] = true
+			report.references[ref.Key]++
+			// Thumbnails live as long as their image
+			if field := res.GetField(ref.Field); field != nil {
+				for _, key := range ThumbnailKeys(*field, ref.Key) {
+					referenced[key] = true
+				}
+			}
+		}
+		references = append(references, refs...)
+	}
+	report.Referenced = len(referenced)
+
+	if len(g.config.Prefixes) > 0 {
+		prefixes = g.config.Prefixes
+	} else if scanAll || len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+
+	stored := make(map[string]bool)
+	cutoff := report.ScannedAt.Add(-g.config.GracePeriod)
+	for _, prefix := range prefixes {
+		objects, err := g.config.Provider.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			if stored[object.Key] {
+				continue
+			}
+			stored[object.Key] = true
+			if !referenced[object.Key] && object.ModTime.Before(cutoff) {
+				report.Orphans = append(report.Orphans, object)
+			}
+		}
+	}
+	report.Stored = len(stored)
+
+	for _, ref := range references {
+		if stored[ref.Key] {
+			continue
+		}
+		// The reference may point outside the scanned prefixes
+		if _, err := g.config.Provider.Stat(ctx, ref.Key); errors.Is(err, ErrNotFound) {
+			report.Missing = append(report.Missing, ref)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Key < report.Orphans[j].Key })
+	return report, nil
+}
+
+// Cleanup scans and deletes orphaned files and reconciles the content index. Stale
+// references are only reported.
+// With dryRun the report lists what would be deleted without deleting anything.
+func (g *GarbageCollector) Cleanup(ctx context.Context, dryRun bool) (*GCReport, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	report, err := g.Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Orphans) > g.config.MaxDeletes {
+		return report, fmt.Errorf("%w: %d found, at most %d allowed", ErrTooManyOrphans, len(report.Orphans), g.config.MaxDeletes)
+	}
+
+	report.Deleted = []string{}
+	for _, object := range report.Orphans {
+		if !dryRun {
+			if err := g.config.Provider.Delete(ctx, object.Key); err != nil {
+				return report, fmt.Errorf("delete %s: %w", object.Key, err)
+			}
+		}
+		report.Deleted = append(report.Deleted, object.Key)
+	}
+	if g.config.Contents != nil && !dryRun {
+		if err := g.config.Contents.Reconcile(ctx, report.references, report.Deleted); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// StartCleanup periodically runs Cleanup until ctx is done. Results are passed to
+// onResult when set.
+func (g *GarbageCollector) StartCleanup(ctx context.Context, interval time.Duration, onResult func(*GCReport, error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := g.Cleanup(ctx, false)
+				if onResult != nil {
+					onResult(report, err)
+				}
+			}
+		}
+	}()
+}
+
+// collectReferences loads all file references of a resource, including soft-deleted
+// records whose files must survive a restore
+func (g *GarbageCollector) collectReferences(ctx context.Context, res resource.Resource, fields []resource.Field) ([]FileReference, error) {
+	stmt := &gorm.Statement{DB: g.config.DB}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return nil, err
+	}
+
+	idColumn := columnFor(stmt.Schema, res.GetIDFieldName())
+	if idColumn == "" {
+		return nil, fmt.Errorf("ID field %q not found", res.GetIDFieldName())
+	}
+	columns := map[string]string{}
+	selectColumns := []string{idColumn}
+	for _, field := range fields {
+		column := columnFor(stmt.Schema, field.Name)
+		if column == "" {
+			return nil, fmt.Errorf("file field %q has no column", field.Name)
+		}
+		columns[field.Name] = column
+		selectColumns = append(selectColumns, column)
+	}
+
+	var references []FileReference
+	for offset := 0; ; offset += g.config.BatchSize {
+		var rows []map[string]interface{}
+		err := g.config.DB.WithContext(ctx).Unscoped().Model(res.GetModel()).
+			Select(selectColumns).Order(idColumn).
+			Limit(g.config.BatchSize).Offset(offset).
+			Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			for _, field := range fields {
+				for _, value := range fileValues(row[columns[field.Name]]) {
+					key := g.config.KeyFromValue(field, value)
+					if key == "" {
+						continue
+					}
+					references = append(references, FileReference{
+						Resource: res.GetName(),
+						RecordID: row[idColumn],
+						Field:    field.Name,
+						Key:      key,
+					})
+				}
+			}
+		}
+
+		if len(rows) < g.config.BatchSize {
+			return references, nil
+		}
+	}
+}
+
+// columnFor returns the database column of a resource field (matched by JSON or Go name)
+func columnFor(s *schema.Schema, name string) string {
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(jsonName, name) || strings.EqualFold(field.Name, name) || field.DBName == name {
+			return field.DBName
+		}
+	}
+	return ""
+}
+
+// fileValues extracts file references from a column value. Multi-file fields store
+// a JSON array of strings or of objects with a "key" or "url" property.
+func fileValues(value interface{}) []string {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case nil:
+		return nil
+	default:
+		raw = fmt.Sprint(v)
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	if !strings.HasPrefix(raw, "[") {
+		return []string{raw}
+	}
+
+	var items []interface{}
+	if err := json.Unmarshal([]byte(raw), &items); err != nil {
+		return []string{raw}
+	}
+	var values []string
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			values = append(values, v)
+		case map[string]interface{}:
+			for _, key := range []string{"key", "url"} {
+				if s, ok := v[key].(string); ok && s != "" {
+					values = append(values, s)
+					break
+				}
+			}
+		}
+	}
+	return values
+}
+
+// appendPrefix adds a prefix unless it is already covered
+func appendPrefix(prefixes []string, prefix string) []string {
+	prefix = strings.TrimLeft(prefix, "/")
+	for _, existing := range prefixes {
+		if existing == prefix {
+			return prefixes
+		}
+	}
+	return append(prefixes, prefix)
+}
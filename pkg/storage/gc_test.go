@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Document struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Title       string         `json:"title"`
+	Cover       string         `json:"cover"`
+	Attachments string         `json:"attachments"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func setupGCTest(t *testing.T) (*GarbageCollector, *LocalProvider, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "documents",
+		Model: Document{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "cover", Type: "file", File: &resource.FileConfig{IsImage: true, StoragePath: "covers/", BaseURL: "https://cdn.example.com"}},
+			{Name: "attachments", Type: "file", File: &resource.FileConfig{StoragePath: "attachments/"}},
+		},
+	})
+
+	provider := NewLocalProvider(t.TempDir())
+	ctx := context.Background()
+	for _, key := range []string{"covers/a.png", "covers/orphan.png", "attachments/1.pdf", "attachments/2.pdf", "attachments/old.pdf", "other/unrelated.txt"} {
+		_, err := provider.Put(ctx, key, strings.NewReader(key), "")
+		require.NoError(t, err)
+	}
+
+	// Everything except the fresh upload is older than the grace period
+	old := time.Now().Add(-48 * time.Hour)
+	for _, key := range []string{"covers/a.png", "covers/orphan.png", "attachments/1.pdf", "attachments/2.pdf", "attachments/old.pdf"} {
+		require.NoError(t, os.Chtimes(filepath.Join(provider.Root, key), old, old))
+	}
+	_, err = provider.Put(ctx, "attachments/fresh.pdf", strings.NewReader("fresh"), "")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&Document{Title: "one", Cover: "https://cdn.example.com/covers/a.png", Attachments: `["attachments/1.pdf",{"key":"attachments/missing.pdf"}]`}).Error)
+	deleted := &Document{Title: "two", Attachments: `["/attachments/2.pdf"]`}
+	require.NoError(t, db.Create(deleted).Error)
+	require.NoError(t, db.Delete(deleted).Error)
+	require.NoError(t, db.Create(&Document{Title: "three", Cover: "https://elsewhere.example.com/x.png"}).Error)
+
+	gc := NewGarbageCollector(GCConfig{
+		Provider:  provider,
+		DB:        db,
+		Resources: []resource.Resource{res},
+		BatchSize: 2,
+	})
+	return gc, provider, db
+}
+
+func TestGarbageCollectorScan(t *testing.T) {
+	gc, _, _ := setupGCTest(t)
+
+	report, err := gc.Scan(context.Background())
+	require.NoError(t, err)
+
+	var orphans []string
+	for _, object := range report.Orphans {
+		orphans = append(orphans, object.Key)
+	}
+	assert.Equal(t, []string{"attachments/old.pdf", "covers/orphan.png"}, orphans)
+	assert.Equal(t, 4, report.Referenced)
+	assert.Equal(t, 6, report.Stored)
+
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, "documents", report.Missing[0].Resource)
+	assert.Equal(t, "attachments", report.Missing[0].Field)
+	assert.Equal(t, "attachments/missing.pdf", report.Missing[0].Key)
+}
+
+func TestGarbageCollectorCleanup(t *testing.T) {
+	gc, provider, _ := setupGCTest(t)
+	ctx := context.Background()
+
+	report, err := gc.Cleanup(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"attachments/old.pdf", "covers/orphan.png"}, report.Deleted)
+	_, err = provider.Stat(ctx, "covers/orphan.png")
+	assert.NoError(t, err, "dry run keeps files")
+
+	report, err = gc.Cleanup(ctx, false)
+	require.NoError(t, err)
+	assert.Len(t, report.Deleted, 2)
+	_, err = provider.Stat(ctx, "covers/orphan.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"covers/a.png", "attachments/1.pdf", "attachments/2.pdf", "attachments/fresh.pdf", "other/unrelated.txt"} {
+		_, err := provider.Stat(ctx, key)
+		assert.NoError(t, err, key)
+	}
+}
+
+func TestGarbageCollectorCleanupLimit(t *testing.T) {
+	gc, provider, _ := setupGCTest(t)
+	gc.config.MaxDeletes = 1
+
+	_, err := gc.Cleanup(context.Background(), false)
+	assert.ErrorIs(t, err, ErrTooManyOrphans)
+	_, err = provider.Stat(context.Background(), "covers/orphan.png")
+	assert.NoError(t, err)
+}
+
+func TestGCRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gc, _, _ := setupGCTest(t)
+
+	r := gin.New()
+	RegisterGCRoutes(r.Group("/files"), gc)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/files/gc", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Data GCReport `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data.Orphans, 2)
+	assert.Len(t, body.Data.Missing, 1)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/gc/cleanup?dryRun=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"deleted":["attachments/old.pdf","covers/orphan.png"]`)
+
+	gc.config.MaxDeletes = 1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/files/gc/cleanup", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestFileValues(t *testing.T) {
+	assert.Nil(t, fileValues(nil))
+	assert.Nil(t, fileValues(""))
+	assert.Equal(t, []string{"a.png"}, fileValues("a.png"))
+	assert.Equal(t, []string{"a.png", "b.png"}, fileValues([]byte(`["a.png",{"url":"b.png"}]`)))
+	assert.Equal(t, []string{"[broken"}, fileValues("[broken"))
+}
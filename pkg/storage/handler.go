@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/utils"
+)
+
+// RegisterGCRoutes registers the garbage collection report and cleanup endpoints:
+// GET /gc returns the report and POST /gc/cleanup deletes orphaned files (?dryRun=true
+// lists them only)
+func RegisterGCRoutes(router *gin.RouterGroup, gc *GarbageCollector) {
+	router.GET("/gc", GenerateGCReportHandler(gc))
+	router.POST("/gc/cleanup", GenerateGCCleanupHandler(gc))
+}
+
+// GenerateGCReportHandler generates a handler returning the garbage collection report
+func GenerateGCReportHandler(gc *GarbageCollector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := gc.Scan(c.Request.Context())
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": report})
+	}
+}
+
+// GenerateGCCleanupHandler generates a handler deleting orphaned files
+func GenerateGCCleanupHandler(gc *GarbageCollector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		dryRun := c.Query("dryRun") == "true"
+		report, err := gc.Cleanup(c.Request.Context(), dryRun)
+		if errors.Is(err, ErrTooManyOrphans) {
+			apierror.WriteWith(c, apierror.New(http.StatusConflict, err.Error()), err, gin.H{"data": report})
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": report})
+	}
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalProvider stores files on the local filesystem below Root
+type LocalProvider struct {
+	Root string
+}
+
+// NewLocalProvider creates a new local filesystem provider
+func NewLocalProvider(root string) *LocalProvider {
+	return &LocalProvider{Root: root}
+}
+
+// Put stores the content under key
+func (p *LocalProvider) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	filename, err := p.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so readers never see partial content
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return p.Stat(ctx, key)
+}
+
+// Open returns the content of the object as an *os.File
+func (p *LocalProvider) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	filename, err := p.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Stat returns object information
+func (p *LocalProvider) Stat(ctx context.Context, key string) (*Object, error) {
+	filename, err := p.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filename)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return localObject(key, info), nil
+}
+
+// Delete removes the object
+func (p *LocalProvider) Delete(ctx context.Context, key string) error {
+	filename, err := p.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns all objects whose key starts with prefix
+func (p *LocalProvider) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(p.Root, func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(p.Root, filename)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *localObject(key, info))
+		return nil
+	})
+	return objects, err
+}
+
+// path resolves a key to a filename, rejecting keys escaping the root
+func (p *LocalProvider) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(p.Root, filepath.FromSlash(cleaned)), nil
+}
+
+// localObject builds an Object from file information
+func localObject(key string, info fs.FileInfo) *Object {
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalProvider(t *testing.T) {
+	ctx := context.Background()
+	p := NewLocalProvider(t.TempDir())
+
+	object, err := p.Put(ctx, "avatars/a.png", strings.NewReader("png"), "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, "avatars/a.png", object.Key)
+	assert.Equal(t, int64(3), object.Size)
+	assert.Equal(t, "image/png", object.ContentType)
+
+	_, err = p.Put(ctx, "docs/b.txt", strings.NewReader("text"), "text/plain")
+	require.NoError(t, err)
+
+	file, err := p.Open(ctx, "avatars/a.png")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, "png", string(content))
+
+	objects, err := p.List(ctx, "avatars/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "avatars/a.png", objects[0].Key)
+
+	objects, err = p.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	require.NoError(t, p.Delete(ctx, "avatars/a.png"))
+	require.NoError(t, p.Delete(ctx, "avatars/a.png"), "deleting a missing file is not an error")
+	_, err = p.Stat(ctx, "avatars/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = p.Open(ctx, "avatars/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalProviderRejectsEscapingKeys(t *testing.T) {
+	p := NewLocalProvider(t.TempDir())
+	_, err := p.Put(context.Background(), "../outside.txt", strings.NewReader("x"), "")
+	assert.Error(t, err)
+	_, err = p.Stat(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestLocalProviderListMissingRoot(t *testing.T) {
+	p := NewLocalProvider(t.TempDir() + "/missing")
+	objects, err := p.List(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+}
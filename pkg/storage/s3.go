@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is used as the payload hash of presigned and streamed requests
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Config contains configuration for the S3 provider
+type S3Config struct {
+	// Bucket name
+	Bucket string
+
+	// Region of the bucket (default "us-east-1")
+	Region string
+
+	// Endpoint for S3 compatible services such as MinIO (default https://s3.<region>.amazonaws.com)
+	Endpoint string
+
+	// PathStyle addresses the bucket in the path instead of the host name
+	PathStyle bool
+
+	// Credentials
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// HTTP client used for requests (default http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// S3Provider stores files in an S3 (or S3 compatible) bucket using Signature V4
+type S3Provider struct {
+	config S3Config
+	now    func() time.Time
+}
+
+// S3Error is returned when S3 responds with an unexpected status
+type S3Error struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("s3 returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// NewS3Provider creates a new S3 provider
+func NewS3Provider(config S3Config) *S3Provider {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &S3Provider{config: config, now: time.Now}
+}
+
+// Put uploads the content under key
+func (p *S3Provider) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	// S3 requires a Content-Length, so the body is buffered
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := p.do(ctx, http.MethodPut, key, nil, header, body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return &Object{Key: key, Size: int64(len(body)), ContentType: contentType, ModTime: p.now()}, nil
+}
+
+// Open downloads the object
+func (p *S3Provider) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// OpenRange downloads length bytes of the object starting at offset
+func (p *S3Provider) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := p.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Stat returns object information using a HEAD request
+func (p *S3Provider) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := p.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	object := &Object{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.ModTime = modified
+	}
+	return object, nil
+}
+
+// Delete removes the object
+func (p *S3Provider) Delete(ctx context.Context, key string) error {
+	resp, err := p.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3ListResult is the ListObjectsV2 response
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns all objects whose key starts with prefix
+func (p *S3Provider) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := p.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, ModTime: content.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// PresignPut returns a presigned PUT request for uploading directly to the bucket.
+// The content type is part of the signature, so the upload must send the same header.
+func (p *S3Provider) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (*PresignedRequest, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return p.presign(http.MethodPut, key, header, expires)
+}
+
+// PresignGet returns a presigned GET request for downloading directly from the bucket
+func (p *S3Provider) PresignGet(ctx context.Context, key string, expires time.Duration) (*PresignedRequest, error) {
+	return p.presign(http.MethodGet, key, http.Header{}, expires)
+}
+
+// presign creates a presigned request using query string authentication
+func (p *S3Provider) presign(method, key string, header http.Header, expires time.Duration) (*PresignedRequest, error) {
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return nil, fmt.Errorf("presigned URL expiration must be between 1s and 7 days")
+	}
+
+	u := p.objectURL(key)
+	now := p.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := p.scope(now)
+
+	signedHeaders := signedHeaderNames(header)
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", p.config.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", strings.Join(signedHeaders, ";"))
+	if p.config.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", p.config.SessionToken)
+	}
+
+	canonical := canonicalRequest(method, u, query, u.Host, header, signedHeaders, unsignedPayload)
+	query.Set("X-Amz-Signature", p.signature(now, amzDate, scope, canonical))
+	u.RawQuery = canonicalQuery(query)
+
+	headers := map[string]string{}
+	for name := range header {
+		headers[name] = header.Get(name)
+	}
+	return &PresignedRequest{Method: method, URL: u.String(), Headers: headers, ExpiresAt: now.Add(expires)}, nil
+}
+
+// do sends a signed request and maps error statuses
+func (p *S3Provider) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := p.objectURL(key)
+	if header == nil {
+		header = http.Header{}
+	}
+
+	now := p.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	header.Set("X-Amz-Date", amzDate)
+	header.Set("X-Amz-Content-Sha256", payloadHash)
+	if p.config.SessionToken != "" {
+		header.Set("X-Amz-Security-Token", p.config.SessionToken)
+	}
+
+	scope := p.scope(now)
+	signedHeaders := signedHeaderNames(header)
+	canonical := canonicalRequest(method, u, query, u.Host, header, signedHeaders, payloadHash)
+	header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.config.AccessKeyID, scope, strings.Join(signedHeaders, ";"), p.signature(now, amzDate, scope, canonical)))
+
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.ContentLength = int64(len(body))
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &S3Error{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+	return resp, nil
+}
+
+// objectURL returns the URL of an object (or of the bucket for an empty key)
+func (p *S3Provider) objectURL(key string) *url.URL {
+	u, _ := url.Parse(p.config.Endpoint)
+	escaped := escapePath(key)
+	if p.config.PathStyle {
+		u.Path = "/" + p.config.Bucket + "/" + key
+		u.RawPath = "/" + p.config.Bucket + "/" + escaped
+	} else {
+		u.Host = p.config.Bucket + "." + u.Host
+		u.Path = "/" + key
+		u.RawPath = "/" + escaped
+	}
+	return u
+}
+
+// scope returns the credential scope for a date
+func (p *S3Provider) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + p.config.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request
+func (p *S3Provider) signature(now time.Time, amzDate, scope, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+p.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, p.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest builds the Signature V4 canonical request
+func canonicalRequest(method string, u *url.URL, query url.Values, host string, header http.Header, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := host
+		if name != "host" {
+			value = strings.TrimSpace(header.Get(name))
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	return strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// signedHeaderNames returns the sorted lower-case names of the signed headers
+func signedHeaderNames(header http.Header) []string {
+	names := []string{"host"}
+	for name := range header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// canonicalQuery encodes query parameters as required by Signature V4
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes each segment of an object key
+func escapePath(key string) string {
+	return uriEncode(key, false)
+}
+
+// uriEncode encodes a string per the Signature V4 rules
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 computes an HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
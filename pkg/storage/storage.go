@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist in the storage provider
+var ErrNotFound = errors.New("file not found in storage")
+
+// Object describes a stored file
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType,omitempty"`
+	ModTime     time.Time `json:"modTime"`
+
+	// Hash is the hex encoded SHA-256 of the content, when known
+	Hash string `json:"hash,omitempty"`
+}
+
+// Provider is a file storage backend (local disk, S3, ...)
+type Provider interface {
+	// Put stores the content under key, replacing an existing object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error)
+
+	// Open returns the content of the object; it returns ErrNotFound for missing objects
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Stat returns object information; it returns ErrNotFound for missing objects
+	Stat(ctx context.Context, key string) (*Object, error)
+
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns all objects whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// HasThumbnails reports whether thumbnails are generated for a field's images
+func HasThumbnails(field resource.Field) bool {
+	return field.File != nil && field.File.IsImage && field.File.GenerateThumbnails && len(field.File.ThumbnailSizes) > 0
+}
+
+// ThumbnailKey returns the storage key of a named thumbnail of a file
+// ("covers/a.png" → "covers/a_small.png")
+func ThumbnailKey(key, name string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + name + ext
+}
+
+// ThumbnailKeys returns the storage keys of all thumbnails of a file
+func ThumbnailKeys(field resource.Field, key string) []string {
+	if !HasThumbnails(field) {
+		return nil
+	}
+	keys := make([]string, len(field.File.ThumbnailSizes))
+	for i, size := range field.File.ThumbnailSizes {
+		keys[i] = ThumbnailKey(key, size.Name)
+	}
+	return keys
+}
+
+// thumbnailSize returns the named thumbnail size of a field
+func thumbnailSize(field resource.Field, name string) (resource.ThumbnailSize, bool) {
+	if HasThumbnails(field) {
+		for _, size := range field.File.ThumbnailSizes {
+			if size.Name == name {
+				return size, true
+			}
+		}
+	}
+	return resource.ThumbnailSize{}, false
+}
+
+// GenerateThumbnails stores the thumbnails of an image under ThumbnailKey and returns
+// their keys by name. JPEG images get JPEG thumbnails, all others PNG.
+func GenerateThumbnails(ctx context.Context, provider Provider, field resource.Field, key string, img image.Image, format string) (map[string]string, error) {
+	if !HasThumbnails(field) {
+		return nil, nil
+	}
+
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+	}
+
+	keys := make(map[string]string, len(field.File.ThumbnailSizes))
+	for _, size := range field.File.ThumbnailSizes {
+		var buf bytes.Buffer
+		thumbnail := resizeImage(img, size)
+		var err error
+		if format == "jpeg" {
+			err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumbnail)
+		}
+		if err != nil {
+			return keys, err
+		}
+
+		thumbnailKey := ThumbnailKey(key, size.Name)
+		if _, err := provider.Put(ctx, thumbnailKey, &buf, contentType); err != nil {
+			return keys, err
+		}
+		keys[size.Name] = thumbnailKey
+	}
+	return keys, nil
+}
+
+// resizeImage scales an image to a thumbnail size. With KeepAspectRatio the image is
+// fitted within the size; a zero width or height is derived from the other one.
+// Images are never enlarged.
+func resizeImage(img image.Image, size resource.ThumbnailSize) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return img
+	}
+
+	width, height := size.Width, size.Height
+	if size.KeepAspectRatio || width <= 0 || height <= 0 {
+		scale := 1.0
+		if width > 0 {
+			scale = float64(width) / float64(srcW)
+		}
+		if height > 0 && (width <= 0 || float64(height)/float64(srcH) < scale) {
+			scale = float64(height) / float64(srcH)
+		}
+		if scale > 1 {
+			scale = 1
+		}
+		width, height = int(float64(srcW)*scale+0.5), int(float64(srcH)*scale+0.5)
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	// Box filter: each target pixel averages the source pixels it covers
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					offset := src.PixOffset(sx, sy)
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,463 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// maxAttachAttempts limits compare-and-swap retries when attaching a file concurrently
+const maxAttachAttempts = 5
+
+// Upload errors
+var (
+	ErrNotFileField        = errors.New("field is not a file field")
+	ErrFileTooLarge        = errors.New("file exceeds the maximum size")
+	ErrFileTypeNotAllowed  = errors.New("file type is not allowed")
+	ErrInvalidUploadToken  = errors.New("invalid or expired upload token")
+	ErrPresignNotSupported = errors.New("storage provider does not support presigned uploads")
+)
+
+// PresignedRequest is a request the client sends directly to the storage provider
+type PresignedRequest struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// Presigner is implemented by providers supporting direct-to-storage uploads
+type Presigner interface {
+	PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (*PresignedRequest, error)
+}
+
+// SignedUploadConfig contains configuration for the signed upload flow
+type SignedUploadConfig struct {
+	// Storage provider; must implement Presigner
+	Provider Provider
+
+	// Database holding the records
+	DB *gorm.DB
+
+	// Secret used to sign upload tokens
+	Secret string
+
+	// Validity of presigned upload URLs (default 15m)
+	Expires time.Duration
+
+	// Time after the URL expired during which the upload can still be confirmed (default 1h)
+	ConfirmWindow time.Duration
+
+	// KeyFunc generates the storage key (default <StoragePath><uuid><ext>)
+	KeyFunc func(field resource.Field, recordID, filename string) string
+
+	// ValueFunc converts a storage key to the value stored in the record (default
+	// <BaseURL>/<key>, or the key when the field has no BaseURL)
+	ValueFunc func(field resource.Field, key string) string
+
+	// Contents stores identical uploads once and detects duplicates (see
+	// FileConfig.OnDuplicate). Confirmations read the uploaded object to hash it.
+	Contents *ContentIndex
+
+	// Repository of the resource, through which records are checked and files attached
+	// (see FileUploadConfig.Repository)
+	Repository repository.Repository
+}
+
+// UploadRequest is the body of a presigned upload request
+type UploadRequest struct {
+	Field       string `json:"field" binding:"required"`
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"contentType" binding:"required"`
+	Size        int64  `json:"size"`
+}
+
+// UploadConfirmation is the body of an upload confirmation
+type UploadConfirmation struct {
+	Field string `json:"field" binding:"required"`
+	Key   string `json:"key" binding:"required"`
+	Token string `json:"token" binding:"required"`
+}
+
+// RegisterSignedUploadRoutes registers the direct upload endpoints of a resource:
+// POST /<resource>/:id/uploads mints a presigned upload URL and
+// POST /<resource>/:id/uploads/confirm attaches the uploaded file to the record
+func RegisterSignedUploadRoutes(router *gin.RouterGroup, res resource.Resource, config SignedUploadConfig) {
+	router.POST("/"+res.GetName()+"/:id/uploads", GenerateSignedUploadHandler(res, config))
+	router.POST("/"+res.GetName()+"/:id/uploads/confirm", GenerateUploadConfirmHandler(res, config))
+}
+
+// GenerateSignedUploadHandler generates a handler minting presigned upload URLs
+func GenerateSignedUploadHandler(res resource.Resource, config SignedUploadConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	repo := recordRepository(config.DB, res, config.Repository)
+
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		presigner, ok := config.Provider.(Presigner)
+		if !ok {
+			apierror.Respond(c, http.StatusNotImplemented, ErrPresignNotSupported)
+			return
+		}
+
+		var req UploadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		field, err := fileField(res, req.Field)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if err := validateFile(field, req.ContentType, req.Size); err != nil {
+			apierror.Respond(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		id := c.Param("id")
+		if _, err := loadFileValue(c.Request.Context(), config.DB, repo, res, field, id); err != nil {
+			respondRecordError(c, err)
+			return
+		}
+
+		key := config.KeyFunc(field, id, req.Filename)
+		upload, err := presigner.PresignPut(c.Request.Context(), key, req.ContentType, config.Expires)
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		expires := upload.ExpiresAt.Add(config.ConfirmWindow)
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{
+			"key":    key,
+			"token":  signUploadToken(config.Secret, res.GetName(), id, field.Name, key, expires),
+			"upload": upload,
+		}})
+	}
+}
+
+// GenerateUploadConfirmHandler generates a handler validating an uploaded object and
+// attaching it to the record's file field. With a content index, uploads of content
+// already stored are deleted and the stored file is attached instead.
+func GenerateUploadConfirmHandler(res resource.Resource, config SignedUploadConfig) gin.HandlerFunc {
+	config = config.withDefaults()
+	checkContentIndex(res, config.Contents)
+	repo := recordRepository(config.DB, res, config.Repository)
+
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+		ctx := c.Request.Context()
+
+		var req UploadConfirmation
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		field, err := fileField(res, req.Field)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		id := c.Param("id")
+		if !verifyUploadToken(config.Secret, req.Token, res.GetName(), id, field.Name, req.Key, time.Now()) {
+			apierror.Respond(c, http.StatusForbidden, ErrInvalidUploadToken)
+			return
+		}
+
+		object, err := config.Provider.Stat(ctx, req.Key)
+		if errors.Is(err, ErrNotFound) {
+			apierror.RespondMessage(c, http.StatusUnprocessableEntity, "uploaded file not found")
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		// Remove rejected uploads so they do not leak storage
+		if err := validateFile(field, object.ContentType, object.Size); err != nil {
+			_ = config.Provider.Delete(ctx, req.Key)
+			apierror.Respond(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		var duplicates []interface{}
+		if config.Contents != nil {
+			hash, err := hashObject(ctx, config.Provider, req.Key)
+			if err == nil {
+				duplicates, err = findDuplicates(ctx, config.Contents, config.DB, repo, res, field, hash, config.ValueFunc)
+			}
+			if err != nil {
+				apierror.Respond(c, http.StatusInternalServerError, err)
+				return
+			}
+			if len(duplicates) > 0 && field.File.OnDuplicate == resource.DuplicateReject {
+				_ = config.Provider.Delete(ctx, req.Key)
+				respondDuplicate(c, duplicates)
+				return
+			}
+
+			uploaded := object
+			object, err = config.Contents.Store(ctx, config.Provider, StoredContent{Scope: contentScope(res, field), Hash: hash, ContentType: uploaded.ContentType}, func() (*Object, error) {
+				return uploaded, nil
+			})
+			if err != nil {
+				apierror.Respond(c, http.StatusInternalServerError, err)
+				return
+			}
+			// The content is stored already, drop the copy
+			if object.Key != req.Key {
+				_ = config.Provider.Delete(ctx, req.Key)
+			}
+		}
+
+		value := config.ValueFunc(field, object.Key)
+		stored, err := attachFile(ctx, config.DB, repo, res, field, id, value)
+		if err != nil {
+			if config.Contents != nil {
+				_ = config.Contents.Release(ctx, object.Key)
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) && object.Key == req.Key {
+				_ = config.Provider.Delete(ctx, req.Key)
+			}
+			respondRecordError(c, err)
+			return
+		}
+
+		data := gin.H{
+			"field":  field.Name,
+			"value":  stored,
+			"object": object,
+		}
+		if len(duplicates) > 0 {
+			data["duplicates"] = duplicates
+		}
+		c.JSON(http.StatusOK, gin.H{"data": data})
+	}
+}
+
+// withDefaults fills in default configuration
+func (c SignedUploadConfig) withDefaults() SignedUploadConfig {
+	if c.Expires <= 0 {
+		c.Expires = 15 * time.Minute
+	}
+	if c.ConfirmWindow <= 0 {
+		c.ConfirmWindow = time.Hour
+	}
+	if c.KeyFunc == nil {
+		c.KeyFunc = defaultUploadKey
+	}
+	if c.ValueFunc == nil {
+		c.ValueFunc = defaultUploadValue
+	}
+	return c
+}
+
+// defaultUploadKey places uploads below the field's storage path under a random name
+func defaultUploadKey(field resource.Field, recordID, filename string) string {
+	prefix := ""
+	if field.File != nil && field.File.StoragePath != "" {
+		prefix = strings.TrimLeft(strings.TrimSuffix(field.File.StoragePath, "/"), "/") + "/"
+	}
+	return prefix + uuid.NewString() + strings.ToLower(path.Ext(filename))
+}
+
+// defaultUploadValue prefixes the key with the field's BaseURL
+func defaultUploadValue(field resource.Field, key string) string {
+	if field.File != nil && field.File.BaseURL != "" {
+		return strings.TrimSuffix(field.File.BaseURL, "/") + "/" + key
+	}
+	return key
+}
+
+// fileField returns the named file field of a resource
+func fileField(res resource.Resource, name string) (resource.Field, error) {
+	field := res.GetField(name)
+	if field == nil || !IsFileField(*field) {
+		return resource.Field{}, fmt.Errorf("%w: %s", ErrNotFileField, name)
+	}
+	return *field, nil
+}
+
+// validateFile checks the content type and size against the field's file configuration
+func validateFile(field resource.Field, contentType string, size int64) error {
+	if field.File == nil {
+		return nil
+	}
+	if field.File.MaxSize > 0 && size > field.File.MaxSize {
+		return fmt.Errorf("%w of %d bytes", ErrFileTooLarge, field.File.MaxSize)
+	}
+	if len(field.File.AllowedTypes) > 0 && !typeAllowed(field.File.AllowedTypes, contentType) {
+		return fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, contentType)
+	}
+	return nil
+}
+
+// typeAllowed matches a content type against allowed types ("image/*" wildcards supported)
+func typeAllowed(allowed []string, contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, t := range allowed {
+		t = strings.ToLower(t)
+		if t == contentType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileColumns returns the ID and file field columns of a resource
+func fileColumns(db *gorm.DB, res resource.Resource, field resource.Field) (string, string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(res.GetModel()); err != nil {
+		return "", "", err
+	}
+	idColumn := columnFor(stmt.Schema, res.GetIDFieldName())
+	column := columnFor(stmt.Schema, field.Name)
+	if idColumn == "" || column == "" {
+		return "", "", fmt.Errorf("no column for field %q", field.Name)
+	}
+	return idColumn, column, nil
+}
+
+// recordRepository returns the repository the records of a resource are read and
+// changed through: repo when set, otherwise an owner repository for owner resources
+// and a generic one for others
+func recordRepository(db *gorm.DB, res resource.Resource, repo repository.Repository) repository.Repository {
+	if repo != nil {
+		return repo
+	}
+	if resource.IsOwnerResource(res) {
+		owned, err := repository.NewOwnerRepository(db, res)
+		if err != nil {
+			panic(fmt.Sprintf("file routes of %s: %v", res.GetName(), err))
+		}
+		return owned
+	}
+	return repository.NewGenericRepositoryWithResource(db, res)
+}
+
+// loadFileValue returns the current value of a file field of a record the repository
+// may access
+func loadFileValue(ctx context.Context, db *gorm.DB, repo repository.Repository, res resource.Resource, field resource.Field, id string) (interface{}, error) {
+	idColumn, column, err := fileColumns(db, res, field)
+	if err != nil {
+		return nil, err
+	}
+	row := map[string]interface{}{}
+	err = repo.Query(ctx).
+		Select(column).Where(idColumn+" = ?", id).
+		Take(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	return row[column], nil
+}
+
+// attachFile stores a file value in a record the repository may access. Multi-file
+// fields get the value appended to their JSON array. A compare-and-swap update keeps
+// concurrent attachments from overwriting each other.
+func attachFile(ctx context.Context, db *gorm.DB, repo repository.Repository, res resource.Resource, field resource.Field, id, value string) (interface{}, error) {
+	idColumn, column, err := fileColumns(db, res, field)
+	if err != nil {
+		return nil, err
+	}
+	multiple := field.File != nil && field.File.Multiple
+
+	for attempt := 0; attempt < maxAttachAttempts; attempt++ {
+		current, err := loadFileValue(ctx, db, repo, res, field, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var next interface{} = value
+		if multiple {
+			values := fileValues(current)
+			for _, existing := range values {
+				if existing == value {
+					return values, nil
+				}
+			}
+			encoded, err := json.Marshal(append(values, value))
+			if err != nil {
+				return nil, err
+			}
+			next = string(encoded)
+		}
+
+		query := repo.Query(ctx).Where(idColumn+" = ?", id)
+		if current == nil {
+			query = query.Where(column + " IS NULL")
+		} else {
+			query = query.Where(column+" = ?", current)
+		}
+		result := query.Update(column, next)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			if multiple {
+				return fileValues(next), nil
+			}
+			return next, nil
+		}
+	}
+	return nil, errors.New("record was modified concurrently, please retry")
+}
+
+// respondRecordError writes the error response for record lookups and updates
+func respondRecordError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.RespondMessage(c, http.StatusNotFound, "Resource not found")
+		return
+	}
+	apierror.Respond(c, http.StatusInternalServerError, err)
+}
+
+// signUploadToken signs an upload for a record field
+func signUploadToken(secret, resourceName, id, field, key string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + uploadSignature(secret, resourceName, id, field, key, exp)
+}
+
+// verifyUploadToken checks the signature and expiry of an upload token
+func verifyUploadToken(secret, token, resourceName, id, field, key string, now time.Time) bool {
+	exp, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	expected := uploadSignature(secret, resourceName, id, field, key, exp)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// uploadSignature computes the HMAC of an upload token
+func uploadSignature(secret, resourceName, id, field, key, exp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{resourceName, id, field, key, exp}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// presigningProvider adds presigned uploads to the local provider for tests
+type presigningProvider struct {
+	*LocalProvider
+}
+
+func (p presigningProvider) PresignPut(ctx context.Context, key, contentType string, expires time.Duration) (*PresignedRequest, error) {
+	return &PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       "https://storage.example.com/" + key,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ExpiresAt: time.Now().Add(expires),
+	}, nil
+}
+
+type uploadResponse struct {
+	Data struct {
+		Key    string           `json:"key"`
+		Token  string           `json:"token"`
+		Upload PresignedRequest `json:"upload"`
+		Value  interface{}      `json:"value"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+func setupUploadTest(t *testing.T, provider Provider) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Document{}))
+	require.NoError(t, db.Create(&Document{ID: 1, Title: "one"}).Error)
+
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "documents",
+		Model: Document{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int"},
+			{Name: "title", Type: "string"},
+			{Name: "cover", Type: "file", File: &resource.FileConfig{
+				StoragePath:  "covers/",
+				BaseURL:      "https://cdn.example.com",
+				AllowedTypes: []string{"image/*"},
+				MaxSize:      10,
+			}},
+			{Name: "attachments", Type: "file", File: &resource.FileConfig{StoragePath: "attachments/", Multiple: true}},
+		},
+	})
+
+	r := gin.New()
+	RegisterSignedUploadRoutes(r.Group("/api"), res, SignedUploadConfig{
+		Provider: provider,
+		DB:       db,
+		Secret:   "upload-secret",
+	})
+	return r, db
+}
+
+func postUpload(r *gin.Engine, path string, body interface{}) (*httptest.ResponseRecorder, uploadResponse) {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	var resp uploadResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestSignedUploadFlow(t *testing.T) {
+	provider := presigningProvider{NewLocalProvider(t.TempDir())}
+	r, db := setupUploadTest(t, provider)
+	ctx := context.Background()
+
+	w, resp := postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "cover", Filename: "Photo.PNG", ContentType: "image/png", Size: 3})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, strings.HasPrefix(resp.Data.Key, "covers/"))
+	assert.True(t, strings.HasSuffix(resp.Data.Key, ".png"))
+	assert.Equal(t, http.MethodPut, resp.Data.Upload.Method)
+	assert.NotEmpty(t, resp.Data.Token)
+
+	// The client uploads directly to storage
+	_, err := provider.Put(ctx, resp.Data.Key, strings.NewReader("png"), "image/png")
+	require.NoError(t, err)
+
+	w, confirmed := postUpload(r, "/api/documents/1/uploads/confirm", UploadConfirmation{Field: "cover", Key: resp.Data.Key, Token: resp.Data.Token})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "https://cdn.example.com/"+resp.Data.Key, confirmed.Data.Value)
+
+	var doc Document
+	require.NoError(t, db.First(&doc, 1).Error)
+	assert.Equal(t, "https://cdn.example.com/"+resp.Data.Key, doc.Cover)
+}
+
+func TestSignedUploadScopedToOwner(t *testing.T) {
+	provider := presigningProvider{NewLocalProvider(t.TempDir())}
+	r, db := setupOwnedFileTest(t, func(api *gin.RouterGroup, db *gorm.DB, res resource.Resource) {
+		repo, err := repository.NewOwnerRepository(db, res)
+		require.NoError(t, err)
+		RegisterSignedUploadRoutes(api, res, SignedUploadConfig{Provider: provider, DB: db, Secret: "secret", Repository: repo})
+	})
+	ctx := context.Background()
+	request := UploadRequest{Field: "photo", Filename: "a.txt", ContentType: "text/plain"}
+
+	// Uploads to records of other owners and to deleted records are not signed
+	w, _ := postUpload(r, "/api/galleries/2/uploads?owner=alice", request)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w, _ = postUpload(r, "/api/galleries/3/uploads?owner=alice", request)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// nor confirmed with a token of the owner
+	w, resp := postUpload(r, "/api/galleries/2/uploads?owner=bob", request)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err := provider.Put(ctx, resp.Data.Key, strings.NewReader("x"), "text/plain")
+	require.NoError(t, err)
+	confirmation := UploadConfirmation{Field: "photo", Key: resp.Data.Key, Token: resp.Data.Token}
+	w, _ = postUpload(r, "/api/galleries/2/uploads/confirm?owner=alice", confirmation)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var gallery OwnedGallery
+	require.NoError(t, db.First(&gallery, 2).Error)
+	assert.Empty(t, gallery.Photo)
+}
+
+func TestSignedUploadMultipleFiles(t *testing.T) {
+	provider := presigningProvider{NewLocalProvider(t.TempDir())}
+	r, db := setupUploadTest(t, provider)
+
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		_, resp := postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "attachments", Filename: name, ContentType: "application/pdf"})
+		_, err := provider.Put(context.Background(), resp.Data.Key, strings.NewReader("pdf"), "application/pdf")
+		require.NoError(t, err)
+		w, _ := postUpload(r, "/api/documents/1/uploads/confirm", UploadConfirmation{Field: "attachments", Key: resp.Data.Key, Token: resp.Data.Token})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var doc Document
+	require.NoError(t, db.First(&doc, 1).Error)
+	assert.Len(t, fileValues(doc.Attachments), 2)
+}
+
+func TestSignedUploadValidation(t *testing.T) {
+	provider := presigningProvider{NewLocalProvider(t.TempDir())}
+	r, _ := setupUploadTest(t, provider)
+	ctx := context.Background()
+
+	w, _ := postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "title", Filename: "a.png", ContentType: "image/png"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "cover", Filename: "a.pdf", ContentType: "application/pdf"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w, _ = postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "cover", Filename: "a.png", ContentType: "image/png", Size: 100})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w, _ = postUpload(r, "/api/documents/99/uploads", UploadRequest{Field: "cover", Filename: "a.png", ContentType: "image/png"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, resp := postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "cover", Filename: "a.png", ContentType: "image/png", Size: 3})
+
+	// Tokens are bound to the record, field and key
+	w, _ = postUpload(r, "/api/documents/1/uploads/confirm", UploadConfirmation{Field: "cover", Key: "covers/other.png", Token: resp.Data.Token})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w, _ = postUpload(r, "/api/documents/1/uploads/confirm", UploadConfirmation{Field: "cover", Key: resp.Data.Key, Token: resp.Data.Token})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "object was not uploaded")
+
+	// The uploaded object is larger than declared and gets removed
+	_, err := provider.Put(ctx, resp.Data.Key, strings.NewReader("much too large"), "image/png")
+	require.NoError(t, err)
+	w, _ = postUpload(r, "/api/documents/1/uploads/confirm", UploadConfirmation{Field: "cover", Key: resp.Data.Key, Token: resp.Data.Token})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	_, err = provider.Stat(ctx, resp.Data.Key)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSignedUploadRequiresPresigner(t *testing.T) {
+	r, _ := setupUploadTest(t, NewLocalProvider(t.TempDir()))
+
+	w, _ := postUpload(r, "/api/documents/1/uploads", UploadRequest{Field: "cover", Filename: "a.png", ContentType: "image/png"})
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestUploadToken(t *testing.T) {
+	now := time.Now()
+	token := signUploadToken("secret", "documents", "1", "cover", "covers/a.png", now.Add(time.Minute))
+
+	assert.True(t, verifyUploadToken("secret", token, "documents", "1", "cover", "covers/a.png", now))
+	assert.False(t, verifyUploadToken("secret", token, "documents", "2", "cover", "covers/a.png", now))
+	assert.False(t, verifyUploadToken("other", token, "documents", "1", "cover", "covers/a.png", now))
+	assert.False(t, verifyUploadToken("secret", token, "documents", "1", "cover", "covers/a.png", now.Add(2*time.Minute)))
+	assert.False(t, verifyUploadToken("secret", "garbage", "documents", "1", "cover", "covers/a.png", now))
+}
@@ -0,0 +1,220 @@
+package swagger
+
+import (
+	"fmt"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// generateBulkPaths documents the bulk endpoints of a resource (/<resource>/batch)
+// with the request and response bodies of handler.GenerateCreateManyHandler,
+// GenerateUpdateManyHandler and GenerateDeleteManyHandler
+func generateBulkPaths(openAPI *OpenAPI, res resource.Resource) {
+	name := res.GetName()
+	record := Schema{Ref: schemaRefPrefix + name}
+	pathItem := PathItem{}
+
+	if res.HasOperation(resource.OperationCreateMany) {
+		pathItem["post"] = Operation{
+			Summary:     fmt.Sprintf("Bulk create %s", name),
+			Description: fmt.Sprintf("Create multiple %s at once. By default one invalid item fails the request; in partial mode each item is created on its own.", name),
+			OperationID: fmt.Sprintf("bulkCreate%s", capitalize(name)),
+			Tags:        []string{name},
+			Parameters:  []Parameter{partialParameter("Create the valid items even if others fail")},
+			RequestBody: &RequestBody{
+				Description: fmt.Sprintf("Array of %s objects to be created", name),
+				Required:    true,
+				Content: jsonContent(Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"values":  {Type: "array", Items: &record},
+						"partial": {Type: "boolean", Description: "Create the valid items even if others fail"},
+					},
+					Required: []string{"values"},
+				}),
+			},
+			Responses: map[string]Response{
+				"201": {
+					Description: "Resources created",
+					Content: jsonContent(Schema{
+						OneOf: []Schema{
+							dataSchema(Schema{Type: "array", Items: &record}),
+							bulkItemResultsSchema(&record, "created"),
+						},
+					}),
+				},
+				"207": {
+					Description: "Some items were created (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(&record, "created")),
+				},
+				"400": {
+					Description: "Invalid input",
+				},
+				"422": {
+					Description: "No item was created (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(&record, "created")),
+				},
+			},
+		}
+	}
+
+	if res.HasOperation(resource.OperationUpdateMany) {
+		pathItem["put"] = Operation{
+			Summary:     fmt.Sprintf("Bulk update %s", name),
+			Description: fmt.Sprintf("Apply the same changes to multiple %s. By default the records are updated together; in partial mode each record is updated on its own.", name),
+			OperationID: fmt.Sprintf("bulkUpdate%s", capitalize(name)),
+			Tags:        []string{name},
+			Parameters:  []Parameter{partialParameter("Update the records that can be updated even if others fail")},
+			RequestBody: &RequestBody{
+				Description: "IDs of the resources and the changes applied to them",
+				Required:    true,
+				Content: jsonContent(Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"ids":     idsSchema(),
+						"values":  record,
+						"partial": {Type: "boolean", Description: "Update the records that can be updated even if others fail"},
+					},
+					Required: []string{"ids", "values"},
+				}),
+			},
+			Responses: map[string]Response{
+				"200": {
+					Description: "Resources updated",
+					Content: jsonContent(Schema{
+						OneOf: []Schema{countSchema(), bulkItemResultsSchema(&record, "updated")},
+					}),
+				},
+				"207": {
+					Description: "Some records were updated (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(&record, "updated")),
+				},
+				"400": {
+					Description: "Invalid input",
+				},
+				"422": {
+					Description: "No record was updated (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(&record, "updated")),
+				},
+			},
+		}
+	}
+
+	if res.HasOperation(resource.OperationDeleteMany) {
+		pathItem["delete"] = Operation{
+			Summary:     fmt.Sprintf("Bulk delete %s", name),
+			Description: fmt.Sprintf("Delete multiple %s at once. By default the records are deleted together; in partial mode each record is deleted on its own.", name),
+			OperationID: fmt.Sprintf("bulkDelete%s", capitalize(name)),
+			Tags:        []string{name},
+			Parameters:  []Parameter{partialParameter("Delete the records that can be deleted even if others fail")},
+			RequestBody: &RequestBody{
+				Description: "IDs of the resources to delete",
+				Required:    true,
+				Content: jsonContent(Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"ids":     idsSchema(),
+						"partial": {Type: "boolean", Description: "Delete the records that can be deleted even if others fail"},
+					},
+					Required: []string{"ids"},
+				}),
+			},
+			Responses: map[string]Response{
+				"200": {
+					Description: "Resources deleted",
+					Content: jsonContent(Schema{
+						OneOf: []Schema{countSchema(), bulkItemResultsSchema(nil, "deleted")},
+					}),
+				},
+				"207": {
+					Description: "Some records were deleted (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(nil, "deleted")),
+				},
+				"400": {
+					Description: "Invalid input",
+				},
+				"422": {
+					Description: "No record was deleted (partial mode)",
+					Content:     jsonContent(bulkItemResultsSchema(nil, "deleted")),
+				},
+			},
+		}
+	}
+
+	if len(pathItem) > 0 {
+		openAPI.Paths[fmt.Sprintf("/%s/batch", name)] = pathItem
+	}
+}
+
+// jsonContent returns the application/json content of a request or response body
+func jsonContent(schema Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// dataSchema wraps a schema in the {"data": ...} envelope of responses
+func dataSchema(data Schema) Schema {
+	return Schema{
+		Type:       "object",
+		Properties: map[string]Schema{"data": data},
+		Required:   []string{"data"},
+	}
+}
+
+// idsSchema describes the IDs of a bulk request: an array of IDs or a single ID
+func idsSchema() Schema {
+	id := Schema{OneOf: []Schema{{Type: "string"}, {Type: "integer"}}}
+	return Schema{OneOf: []Schema{{Type: "array", Items: &id}, id}}
+}
+
+// countSchema describes the {"data": {"count": n}} response of bulk updates and deletes
+func countSchema() Schema {
+	return dataSchema(Schema{
+		Type:       "object",
+		Properties: map[string]Schema{"count": {Type: "integer", Format: "int64"}},
+	})
+}
+
+// partialParameter describes the partial query parameter of bulk endpoints
+func partialParameter(description string) Parameter {
+	return Parameter{
+		Name:        "partial",
+		In:          "query",
+		Description: description,
+		Schema:      Schema{Type: "boolean"},
+	}
+}
+
+// bulkItemResultsSchema describes the per-item results of a partial bulk operation
+// (handler.BulkCreateResponse, BulkUpdateResponse and BulkDeleteResponse). record is
+// the schema of the returned records, nil if none are returned; succeeded names the
+// count of succeeded items in meta.
+func bulkItemResultsSchema(record *Schema, succeeded string) Schema {
+	item := map[string]Schema{
+		"index": {Type: "integer"},
+		"id":    {},
+		"error": {Type: "string"},
+	}
+	if record != nil {
+		item["data"] = *record
+	}
+	return Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"data": {
+				Type: "array",
+				Items: &Schema{
+					Type:       "object",
+					Properties: item,
+					Required:   []string{"index"},
+				},
+			},
+			"meta": {
+				Type: "object",
+				Properties: map[string]Schema{
+					succeeded: {Type: "integer"},
+					"failed":  {Type: "integer"},
+				},
+			},
+		},
+	}
+}
@@ -1,10 +1,22 @@
 package swagger
 
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
 // CustomEndpoint defines a custom endpoint for Swagger documentation.
 type CustomEndpoint struct {
 	Method    string    // HTTP method in lowercase (e.g., "get", "post")
 	Path      string    // The URL path, e.g., "/auth/login"
 	Operation Operation // The operation object that defines the endpoint.
+
+	// Schemas referenced by the operation, added to the components of the spec
+	Schemas map[string]Schema
 }
 
 var customEndpoints []CustomEndpoint
@@ -23,3 +35,81 @@ func GetCustomEndpoints() []CustomEndpoint {
 func ResetCustomEndpoints() {
 	customEndpoints = []CustomEndpoint{}
 }
+
+// RegisterCustomActions registers the custom actions of a resource (see
+// handler.RegisterCustomActions) as custom endpoints. The request body and the data of
+// the response are described by the types of the Request and Response values of the
+// actions.
+func RegisterCustomActions(res resource.Resource, actions []handler.CustomAction) {
+	for _, action := range actions {
+		RegisterCustomEndpoint(customActionEndpoint(res, action))
+	}
+}
+
+// customActionEndpoint documents a custom action
+func customActionEndpoint(res resource.Resource, action handler.CustomAction) CustomEndpoint {
+	name := res.GetName()
+	method := strings.ToLower(action.Method)
+	switch method {
+	case "get", "post", "put", "patch", "delete":
+	default:
+		// Unknown methods are routed as POST
+		method = "post"
+	}
+
+	path := "/" + name
+	var parameters []Parameter
+	if action.RequiresID {
+		path += "/{id}"
+		parameters = append(parameters, Parameter{
+			Name:        "id",
+			In:          "path",
+			Description: "Resource ID",
+			Required:    true,
+			Schema:      Schema{Type: "string"},
+		})
+	}
+	path += "/actions/" + action.Name
+
+	summary := action.Summary
+	if summary == "" {
+		summary = fmt.Sprintf("%s action on %s", action.Name, name)
+	}
+
+	schemas := make(map[string]Schema)
+	data := Schema{}
+	if action.Response != nil {
+		data = TypeSchema(reflect.TypeOf(action.Response), schemas)
+	}
+
+	operation := Operation{
+		Summary:     summary,
+		OperationID: fmt.Sprintf("%s%sAction", name, capitalize(action.Name)),
+		Tags:        []string{name},
+		Parameters:  parameters,
+		Responses: map[string]Response{
+			"200": {
+				Description: "Action result",
+				Content:     jsonContent(dataSchema(data)),
+			},
+			"500": {
+				Description: "Action failed",
+			},
+		},
+	}
+	if action.Jobs != nil {
+		operation.Responses = map[string]Response{
+			"202": {Description: "Action started as a background job; poll the URL of the Location header"},
+			"503": {Description: "Job could not be started"},
+		}
+	}
+	if action.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Description: fmt.Sprintf("Input of the %s action", action.Name),
+			Required:    true,
+			Content:     jsonContent(TypeSchema(reflect.TypeOf(action.Request), schemas)),
+		}
+	}
+
+	return CustomEndpoint{Method: method, Path: path, Operation: operation, Schemas: schemas}
+}
@@ -0,0 +1,189 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// RedactedValue replaces the values of redacted fields in examples
+const RedactedValue = "[REDACTED]"
+
+// ExampleConfig configures the request and response examples added to the operations
+// of resources. Examples are built from the fixture registered for a resource with
+// RegisterExample or, when there is none, from a sample record loaded from DB.
+type ExampleConfig struct {
+	// DB loads a sample record of resources without a registered fixture
+	DB *gorm.DB
+
+	// Redact lists the fields whose values are replaced with RedactedValue by
+	// resource name; fields listed under "*" are redacted in every resource. Hidden
+	// fields are always left out.
+	Redact map[string][]string
+}
+
+var exampleFixtures = make(map[string]interface{})
+
+// RegisterExample registers the fixture record used as the example of a resource.
+// Records may be model values, model pointers or maps.
+func RegisterExample(resourceName string, record interface{}) {
+	exampleFixtures[resourceName] = record
+}
+
+// ResetExamples clears all registered example fixtures (useful in testing scenarios).
+func ResetExamples() {
+	exampleFixtures = make(map[string]interface{})
+}
+
+// AddExamples adds examples to the list, get, create, update and patch operations of
+// the resources. Resources without a fixture or sample record are left untouched.
+func AddExamples(openAPI *OpenAPI, resources []resource.Resource, config ExampleConfig) {
+	for _, res := range resources {
+		record, ok := config.exampleRecord(res)
+		if !ok {
+			continue
+		}
+		example := sanitizeExample(res, record, config.Redact)
+		input := inputExample(res, example)
+		name := res.GetName()
+
+		setResponseExample(openAPI, "/"+name, "get", "200", map[string]interface{}{
+			"data":  []interface{}{example},
+			"total": 1,
+		})
+		setResponseExample(openAPI, "/"+name, "post", "201", map[string]interface{}{"data": example})
+		setRequestExample(openAPI, "/"+name, "post", input)
+
+		itemPath := "/" + name + "/{id}"
+		for _, method := range []string{"get", "put", "patch"} {
+			setResponseExample(openAPI, itemPath, method, "200", map[string]interface{}{"data": example})
+		}
+		setRequestExample(openAPI, itemPath, "put", input)
+		setRequestExample(openAPI, itemPath, "patch", input)
+	}
+}
+
+// exampleRecord returns the fixture of a resource, or a sample record from the
+// database
+func (config ExampleConfig) exampleRecord(res resource.Resource) (interface{}, bool) {
+	if record, ok := exampleFixtures[res.GetName()]; ok {
+		return record, true
+	}
+	if config.DB == nil || res.GetModel() == nil {
+		return nil, false
+	}
+
+	modelType := reflect.TypeOf(res.GetModel())
+	for modelType.Kind() == reflect.Ptr {
+		modelType = modelType.Elem()
+	}
+	record := reflect.New(modelType).Interface()
+	if err := config.DB.Model(record).Take(record).Error; err != nil {
+		return nil, false
+	}
+	return record, true
+}
+
+// sanitizeExample encodes a record as a JSON object without hidden fields, with
+// redacted values replaced and fields named by their API names
+func sanitizeExample(res resource.Resource, record interface{}, redact map[string][]string) map[string]interface{} {
+	example := make(map[string]interface{})
+	encoded, err := json.Marshal(record)
+	if err != nil || json.Unmarshal(encoded, &example) != nil {
+		return map[string]interface{}{}
+	}
+
+	redacted := make(map[string]bool)
+	for _, name := range append(redact["*"], redact[res.GetName()]...) {
+		redacted[exampleKey(name)] = true
+	}
+
+	sanitized := make(map[string]interface{}, len(example))
+	for key, value := range example {
+		field := exampleField(res, key)
+		switch {
+		case field != nil && field.Hidden:
+			continue
+		case redacted[exampleKey(key)] || (field != nil && (redacted[exampleKey(field.Name)] || redacted[exampleKey(field.APIName())])):
+			value = RedactedValue
+		}
+		if field != nil && field.Alias != "" {
+			key = field.Alias
+		}
+		sanitized[key] = value
+	}
+	return sanitized
+}
+
+// inputExample returns the example without the ID and read-only fields, which are
+// not accepted in create and update requests
+func inputExample(res resource.Resource, example map[string]interface{}) map[string]interface{} {
+	input := make(map[string]interface{}, len(example))
+	for key, value := range example {
+		if exampleKey(key) == exampleKey(res.GetIDFieldName()) {
+			continue
+		}
+		if field := exampleField(res, key); field != nil && field.ReadOnly {
+			continue
+		}
+		input[key] = value
+	}
+	return input
+}
+
+// exampleField returns the field of a resource encoded under a JSON key
+func exampleField(res resource.Resource, key string) *resource.Field {
+	key = exampleKey(key)
+	for _, field := range res.GetFields() {
+		if exampleKey(field.Name) == key || exampleKey(field.APIName()) == key {
+			field := field
+			return &field
+		}
+	}
+	return nil
+}
+
+// exampleKey normalizes a field name so names in any naming convention match
+func exampleKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// setResponseExample sets the JSON example of an operation response, if documented
+func setResponseExample(openAPI *OpenAPI, path, method, status string, example interface{}) {
+	operation, ok := openAPI.Paths[path][method]
+	if !ok {
+		return
+	}
+	response, ok := operation.Responses[status]
+	if !ok {
+		return
+	}
+	mediaType, ok := response.Content["application/json"]
+	if !ok {
+		return
+	}
+	mediaType.Example = example
+	response.Content["application/json"] = mediaType
+	operation.Responses[status] = response
+	openAPI.Paths[path][method] = operation
+}
+
+// setRequestExample sets the example of every media type of an operation request
+// body, if documented. JSON Patch bodies are documents of operations and get none.
+func setRequestExample(openAPI *OpenAPI, path, method string, example interface{}) {
+	operation, ok := openAPI.Paths[path][method]
+	if !ok || operation.RequestBody == nil {
+		return
+	}
+	for contentType, mediaType := range operation.RequestBody.Content {
+		if contentType == "application/json-patch+json" {
+			continue
+		}
+		mediaType.Example = example
+		operation.RequestBody.Content[contentType] = mediaType
+	}
+	openAPI.Paths[path][method] = operation
+}
@@ -0,0 +1,90 @@
+package swagger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ExampleCustomer struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Nickname string `json:"nickname"`
+}
+
+func exampleCustomerResource() resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  "customers",
+		Model: ExampleCustomer{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int", ReadOnly: true},
+			{Name: "name", Type: "string"},
+			{Name: "email", Type: "string"},
+			{Name: "password", Type: "string", Hidden: true},
+			{Name: "nickname", Type: "string", Alias: "handle"},
+		},
+		Operations: []resource.Operation{
+			resource.OperationList, resource.OperationRead, resource.OperationCreate, resource.OperationUpdate,
+		},
+	})
+}
+
+func TestGenerateOpenAPIExamplesFromFixture(t *testing.T) {
+	ResetExamples()
+	defer ResetExamples()
+
+	res := exampleCustomerResource()
+	RegisterExample("customers", ExampleCustomer{ID: 7, Name: "Ada", Email: "ada@example.com", Password: "secret", Nickname: "countess"})
+
+	info := DefaultSwaggerInfo()
+	info.Examples = &ExampleConfig{Redact: map[string][]string{"customers": {"email"}}}
+	openAPI := GenerateOpenAPI([]resource.Resource{res}, info)
+
+	record := map[string]interface{}{"id": float64(7), "name": "Ada", "email": RedactedValue, "handle": "countess"}
+	input := map[string]interface{}{"name": "Ada", "email": RedactedValue, "handle": "countess"}
+
+	list := openAPI.Paths["/customers"]["get"].Responses["200"].Content["application/json"]
+	assert.Equal(t, map[string]interface{}{"data": []interface{}{record}, "total": 1}, list.Example)
+
+	get := openAPI.Paths["/customers/{id}"]["get"].Responses["200"].Content["application/json"]
+	assert.Equal(t, map[string]interface{}{"data": record}, get.Example)
+
+	create := openAPI.Paths["/customers"]["post"]
+	assert.Equal(t, input, create.RequestBody.Content["application/json"].Example)
+	assert.Equal(t, map[string]interface{}{"data": record}, create.Responses["201"].Content["application/json"].Example)
+
+	patch := openAPI.Paths["/customers/{id}"]["patch"]
+	assert.Equal(t, input, patch.RequestBody.Content["application/merge-patch+json"].Example)
+	assert.Nil(t, patch.RequestBody.Content["application/json-patch+json"].Example)
+}
+
+func TestGenerateOpenAPIExamplesFromDatabase(t *testing.T) {
+	ResetExamples()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ExampleCustomer{}))
+
+	res := exampleCustomerResource()
+	info := DefaultSwaggerInfo()
+	info.Examples = &ExampleConfig{DB: db, Redact: map[string][]string{"*": {"name"}}}
+
+	// Without records there is nothing to show
+	openAPI := GenerateOpenAPI([]resource.Resource{res}, info)
+	assert.Nil(t, openAPI.Paths["/customers/{id}"]["get"].Responses["200"].Content["application/json"].Example)
+
+	require.NoError(t, db.Create(&ExampleCustomer{Name: "Grace", Email: "grace@example.com", Password: "hunter2"}).Error)
+	openAPI = GenerateOpenAPI([]resource.Resource{res}, info)
+
+	example := openAPI.Paths["/customers/{id}"]["get"].Responses["200"].Content["application/json"].Example
+	data := example.(map[string]interface{})["data"].(map[string]interface{})
+	assert.Equal(t, RedactedValue, data["name"])
+	assert.Equal(t, "grace@example.com", data["email"])
+	assert.NotContains(t, data, "password")
+}
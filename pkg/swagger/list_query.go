@@ -0,0 +1,136 @@
+package swagger
+
+import (
+	"fmt"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// generateListQueryPath documents the POST list endpoint of a resource
+// (/<resource>/query) with the handler.ListQueryRequest body
+func generateListQueryPath(openAPI *OpenAPI, res resource.Resource) {
+	name := res.GetName()
+	filter := Schema{
+		Type:        "object",
+		Description: `A field filter ({"field", "operator", "value"}) or a group of filters ({"operator": "and" | "or", "value": [filters]})`,
+		Properties: map[string]Schema{
+			"field":    {Type: "string"},
+			"operator": {Type: "string"},
+			"value":    {},
+		},
+		Required: []string{"operator"},
+	}
+	sorter := Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"field": {Type: "string"},
+			"order": {Type: "string", Enum: []interface{}{"asc", "desc"}},
+		},
+		Required: []string{"field"},
+	}
+	names := Schema{Type: "array", Items: &Schema{Type: "string"}}
+
+	openAPI.Paths[fmt.Sprintf("/%s/query", name)] = PathItem{
+		"post": Operation{
+			Summary:     fmt.Sprintf("Query %s", name),
+			Description: fmt.Sprintf("Get a list of %s with the query in the body, for filters too long for a URL. The response is that of the list endpoint.", name),
+			OperationID: fmt.Sprintf("query%s", capitalize(name)),
+			Tags:        []string{name},
+			RequestBody: &RequestBody{
+				Description: "Filters, sorters, pagination, fields and included relations of the list",
+				Required:    true,
+				Content: jsonContent(Schema{
+					Type: "object",
+					Properties: map[string]Schema{
+						"filters": {Type: "array", Items: &filter},
+						"sorters": {Type: "array", Items: &sorter},
+						"pagination": {
+							Type: "object",
+							Properties: map[string]Schema{
+								"current":  {Type: "integer"},
+								"pageSize": {Type: "integer"},
+							},
+						},
+						"fields":  names,
+						"include": names,
+						"q":       {Type: "string", Description: "Search term"},
+					},
+				}),
+			},
+			Responses: map[string]Response{
+				"200": {
+					Description: "Successful operation",
+					Content:     jsonContent(listResponseSchema(res)),
+				},
+				"400": {
+					Description: "Invalid query",
+				},
+			},
+		},
+	}
+}
+
+// listResponseSchema describes the response of list endpoints: a page of records, the
+// total, the pagination and the applied query
+func listResponseSchema(res resource.Resource) Schema {
+	filter := Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"field":    {Type: "string"},
+			"operator": {Type: "string"},
+			"value":    {},
+		},
+	}
+	sorter := Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"field": {Type: "string"},
+			"order": {Type: "string", Enum: []interface{}{"asc", "desc"}},
+		},
+	}
+	return Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			"data": {
+				Type:  "array",
+				Items: &Schema{Ref: schemaRefPrefix + res.GetName()},
+			},
+			"total": {Type: "integer"},
+			"meta": {
+				Type: "object",
+				Properties: map[string]Schema{
+					"page":     {Type: "integer"},
+					"pageSize": {Type: "integer"},
+				},
+			},
+			"appliedQuery": {
+				Type:        "object",
+				Description: "The filters, sorters and pagination the list ran with, and the parameters that were ignored",
+				Properties: map[string]Schema{
+					"filters":    {Type: "array", Items: &filter},
+					"filterTree": {Type: "array", Items: &Schema{Type: "object"}},
+					"sorters":    {Type: "array", Items: &sorter},
+					"q":          {Type: "string"},
+					"pagination": {
+						Type: "object",
+						Properties: map[string]Schema{
+							"current":  {Type: "integer"},
+							"pageSize": {Type: "integer"},
+						},
+					},
+					"ignored": {
+						Type: "array",
+						Items: &Schema{
+							Type: "object",
+							Properties: map[string]Schema{
+								"name":       {Type: "string"},
+								"reason":     {Type: "string"},
+								"suggestion": {Type: "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
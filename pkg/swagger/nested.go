@@ -0,0 +1,225 @@
+package swagger
+
+import (
+	"fmt"
+
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/naming"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// RegisterNestedResource registers the routes of a nested resource (see
+// handler.RegisterNestedResourceWithParams) as custom endpoints, with the route
+// parameters of params, e.g. /orders/{orderCode}/items/{itemId}
+func RegisterNestedResource(parent, child resource.Resource, params handler.RouteParams) {
+	for _, endpoint := range nestedEndpoints(parent, child, params) {
+		RegisterCustomEndpoint(endpoint)
+	}
+}
+
+// RegisterRelationRoutes registers the relation routes of a resource (see
+// handler.RegisterRelationRoutesWithParams) as custom endpoints, with the route
+// parameters of params. relations names the relations like the handler does.
+func RegisterRelationRoutes(res resource.Resource, params handler.RouteParams, relations ...string) {
+	for _, endpoint := range relationEndpoints(res, params, relations) {
+		RegisterCustomEndpoint(endpoint)
+	}
+}
+
+// nestedEndpoints documents the routes of a nested resource
+func nestedEndpoints(parent, child resource.Resource, params handler.RouteParams) []CustomEndpoint {
+	if params.Parent == "" {
+		params.Parent = handler.NestedParentParam
+	}
+	if params.Child == "" {
+		params.Child = handler.NestedChildParam
+	}
+	name := child.GetName()
+	listPath := fmt.Sprintf("/%s/{%s}/%s", parent.GetName(), params.Parent, name)
+	itemPath := fmt.Sprintf("%s/{%s}", listPath, params.Child)
+	parentParameter := pathParameter(params.Parent, fmt.Sprintf("ID of the parent %s", parent.GetName()))
+	childParameter := pathParameter(params.Child, fmt.Sprintf("ID of the %s", name))
+	operationID := func(action string) string {
+		return fmt.Sprintf("%s%sOf%s", action, capitalize(name), capitalize(parent.GetName()))
+	}
+	record := dataSchema(Schema{Ref: "#/components/schemas/" + name})
+	notFound := Response{Description: "Resource not found"}
+
+	var endpoints []CustomEndpoint
+	add := func(method, path string, operation Operation) {
+		operation.Tags = []string{name}
+		endpoints = append(endpoints, CustomEndpoint{Method: method, Path: path, Operation: operation})
+	}
+
+	if child.HasOperation(resource.OperationList) {
+		add("get", listPath, Operation{
+			Summary:     fmt.Sprintf("List %s of a %s", name, parent.GetName()),
+			OperationID: operationID("list"),
+			Parameters:  append([]Parameter{parentParameter}, generateListParameters()...),
+			Responses: map[string]Response{
+				"200": {Description: "Successful operation", Content: jsonContent(listResponseSchema(child))},
+			},
+		})
+	}
+	if child.HasOperation(resource.OperationCount) {
+		add("get", listPath+"/count", Operation{
+			Summary:     fmt.Sprintf("Count %s of a %s", name, parent.GetName()),
+			OperationID: operationID("count"),
+			Parameters:  []Parameter{parentParameter},
+			Responses: map[string]Response{
+				"200": {Description: "Successful operation", Content: jsonContent(countSchema())},
+			},
+		})
+	}
+	if child.HasOperation(resource.OperationCreate) {
+		add("post", listPath, Operation{
+			Summary:     fmt.Sprintf("Create %s in a %s", name, parent.GetName()),
+			OperationID: operationID("create"),
+			Parameters:  []Parameter{parentParameter},
+			RequestBody: &RequestBody{
+				Description: fmt.Sprintf("%s object to be created; the reference to the %s is set from the path", name, parent.GetName()),
+				Required:    true,
+				Content:     jsonContent(Schema{Ref: "#/components/schemas/" + name}),
+			},
+			Responses: map[string]Response{
+				"201": {Description: "Resource created", Content: jsonContent(record)},
+				"400": {Description: "Invalid input"},
+			},
+		})
+	}
+	if child.HasOperation(resource.OperationRead) {
+		add("get", itemPath, Operation{
+			Summary:     fmt.Sprintf("Get %s of a %s by ID", name, parent.GetName()),
+			OperationID: operationID("get"),
+			Parameters:  []Parameter{parentParameter, childParameter},
+			Responses: map[string]Response{
+				"200": {Description: "Successful operation", Content: jsonContent(record)},
+				"404": notFound,
+			},
+		})
+	}
+	if child.HasOperation(resource.OperationUpdate) {
+		for method, action := range map[string]string{"put": "update", "patch": "patch"} {
+			add(method, itemPath, Operation{
+				Summary:     fmt.Sprintf("%s %s of a %s", capitalize(action), name, parent.GetName()),
+				OperationID: operationID(action),
+				Parameters:  []Parameter{parentParameter, childParameter},
+				RequestBody: &RequestBody{
+					Description: fmt.Sprintf("Changes to the %s", name),
+					Required:    true,
+					Content:     jsonContent(Schema{Type: "object"}),
+				},
+				Responses: map[string]Response{
+					"200": {Description: "Resource updated", Content: jsonContent(record)},
+					"400": {Description: "Invalid input"},
+					"404": notFound,
+				},
+			})
+		}
+	}
+	if child.HasOperation(resource.OperationDelete) {
+		add("delete", itemPath, Operation{
+			Summary:     fmt.Sprintf("Delete %s of a %s", name, parent.GetName()),
+			OperationID: operationID("delete"),
+			Parameters:  []Parameter{parentParameter, childParameter},
+			Responses: map[string]Response{
+				"204": {Description: "Resource deleted"},
+				"404": notFound,
+			},
+		})
+	}
+	return endpoints
+}
+
+// relationEndpoints documents the relation routes of a resource
+func relationEndpoints(res resource.Resource, params handler.RouteParams, relations []string) []CustomEndpoint {
+	if !res.HasOperation(resource.OperationUpdate) {
+		return nil
+	}
+	if params.Parent == "" {
+		params.Parent = "id"
+	}
+	if params.Child == "" {
+		params.Child = handler.RelatedParam
+	}
+	name := res.GetName()
+	idParameter := pathParameter(params.Parent, "ID of the resource")
+	idsBody := &RequestBody{
+		Description: "IDs of the related records",
+		Required:    true,
+		Content: jsonContent(Schema{
+			Type:       "object",
+			Properties: map[string]Schema{"ids": {Type: "array", Items: &Schema{}}},
+		}),
+	}
+	responses := func() map[string]Response {
+		return map[string]Response{
+			"204": {Description: "Relation changed"},
+			"404": {Description: "Resource not found"},
+			"422": {Description: "Related record not found"},
+		}
+	}
+
+	var endpoints []CustomEndpoint
+	for _, relation := range res.GetRelations() {
+		if relation.Type != resource.RelationTypeOneToMany && relation.Type != resource.RelationTypeManyToMany {
+			continue
+		}
+		if !relationSelected(relation, relations) {
+			continue
+		}
+		path := fmt.Sprintf("/%s/{%s}/%s", name, params.Parent, naming.ToSnakeCase(relation.Name))
+		relationName := capitalize(naming.ToCamelCase(relation.Name))
+		endpoints = append(endpoints,
+			CustomEndpoint{Method: "post", Path: path, Operation: Operation{
+				Summary:     fmt.Sprintf("Attach %s to %s", relation.Name, name),
+				OperationID: fmt.Sprintf("attach%s%s", capitalize(name), relationName),
+				Tags:        []string{name},
+				Parameters:  []Parameter{idParameter},
+				RequestBody: idsBody,
+				Responses:   responses(),
+			}},
+			CustomEndpoint{Method: "put", Path: path, Operation: Operation{
+				Summary:     fmt.Sprintf("Replace %s of %s", relation.Name, name),
+				OperationID: fmt.Sprintf("replace%s%s", capitalize(name), relationName),
+				Tags:        []string{name},
+				Parameters:  []Parameter{idParameter},
+				RequestBody: idsBody,
+				Responses:   responses(),
+			}},
+			CustomEndpoint{Method: "delete", Path: fmt.Sprintf("%s/{%s}", path, params.Child), Operation: Operation{
+				Summary:     fmt.Sprintf("Detach %s from %s", relation.Name, name),
+				OperationID: fmt.Sprintf("detach%s%s", capitalize(name), relationName),
+				Tags:        []string{name},
+				Parameters:  []Parameter{idParameter, pathParameter(params.Child, "ID of the related record")},
+				Responses:   responses(),
+			}},
+		)
+	}
+	return endpoints
+}
+
+// relationSelected reports whether relations (in any naming convention) name a
+// relation, or are empty
+func relationSelected(relation resource.Relation, relations []string) bool {
+	if len(relations) == 0 {
+		return true
+	}
+	for _, name := range relations {
+		if naming.ToSnakeCase(name) == naming.ToSnakeCase(relation.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathParameter describes a required path parameter
+func pathParameter(name, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      Schema{Type: "string"},
+	}
+}
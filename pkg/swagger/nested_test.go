@@ -0,0 +1,57 @@
+package swagger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type swaggerOrder struct {
+	ID   uint         `json:"id"`
+	Tags []swaggerTag `json:"tags" relation:"resource=tags;type=many-to-many"`
+}
+
+type swaggerTag struct {
+	ID uint `json:"id"`
+}
+
+type swaggerItem struct {
+	ID      uint `json:"id"`
+	OrderID uint `json:"order_id"`
+}
+
+func TestRegisterNestedResourceSwagger(t *testing.T) {
+	ResetCustomEndpoints()
+	defer ResetCustomEndpoints()
+
+	operations := []resource.Operation{resource.OperationList, resource.OperationRead, resource.OperationUpdate, resource.OperationDelete}
+	orders := resource.NewResource(resource.ResourceConfig{Name: "orders", Model: swaggerOrder{}, Operations: operations})
+	items := resource.NewResource(resource.ResourceConfig{Name: "items", Model: swaggerItem{}, Operations: operations})
+
+	RegisterNestedResource(orders, items, handler.RouteParams{Parent: "orderCode", Child: "itemId"})
+	RegisterRelationRoutes(orders, handler.RouteParams{Parent: "orderCode"})
+	openAPI := GenerateOpenAPI([]resource.Resource{orders, items}, DefaultSwaggerInfo())
+
+	list, ok := openAPI.Paths["/orders/{orderCode}/items"]["get"]
+	require.True(t, ok)
+	assert.Equal(t, "orderCode", list.Parameters[0].Name)
+	assert.Equal(t, "path", list.Parameters[0].In)
+
+	item := openAPI.Paths["/orders/{orderCode}/items/{itemId}"]
+	require.Contains(t, item, "get")
+	assert.Contains(t, item, "put")
+	assert.Contains(t, item, "patch")
+	assert.Contains(t, item, "delete")
+	assert.Equal(t, []string{"orderCode", "itemId"}, []string{item["get"].Parameters[0].Name, item["get"].Parameters[1].Name})
+	assert.NotContains(t, openAPI.Paths, "/orders/{orderCode}/items/count")
+
+	// Relation routes; the related ID parameter keeps its default name
+	assert.Contains(t, openAPI.Paths["/orders/{orderCode}/tags"], "post")
+	assert.Contains(t, openAPI.Paths["/orders/{orderCode}/tags"], "put")
+	detach, ok := openAPI.Paths["/orders/{orderCode}/tags/{relatedId}"]["delete"]
+	require.True(t, ok)
+	assert.Equal(t, handler.RelatedParam, detach.Parameters[1].Name)
+}
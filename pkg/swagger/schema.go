@@ -0,0 +1,360 @@
+package swagger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// schemaRefPrefix prefixes references to component schemas
+const schemaRefPrefix = "#/components/schemas/"
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	nullTimeType  = reflect.TypeOf(sql.NullTime{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// TypeSchema returns the schema of a Go type, derived from its JSON encoding. Structs
+// are added to components under their type name and referenced, so nested and
+// recursive types are described once. Constraints of validate and binding struct
+// tags (required, min, max, len, oneof, email, url, uuid, ...) become schema keywords.
+func TypeSchema(t reflect.Type, components map[string]Schema) Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType || t.ConvertibleTo(nullTimeType):
+		return Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(marshalerType):
+		// Custom JSON encodings (datatypes.JSON, ...) can hold any value
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{Type: "string"}
+	case reflect.Bool:
+		return Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{Type: "integer", Format: "uint32"}
+	case reflect.Uint64:
+		return Schema{Type: "integer", Format: "uint64"}
+	case reflect.Float32:
+		return Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if t.Kind() == reflect.Slice && t.Implements(marshalerType) {
+				// json.RawMessage and similar types hold encoded JSON
+				return Schema{}
+			}
+			return Schema{Type: "string", Format: "byte"}
+		}
+		items := TypeSchema(t.Elem(), components)
+		return Schema{Type: "array", Items: &items}
+	case reflect.Map:
+		values := TypeSchema(t.Elem(), components)
+		return Schema{Type: "object", AdditionalProperties: values}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, components)
+		}
+		if _, exists := components[t.Name()]; !exists {
+			// Registered before the fields are described to stop recursion
+			components[t.Name()] = Schema{Type: "object"}
+			components[t.Name()] = structSchema(t, components)
+		}
+		return Schema{Ref: schemaRefPrefix + t.Name()}
+	}
+	return Schema{}
+}
+
+// structSchema describes the JSON object of a struct. Embedded structs without a JSON
+// name are flattened, as encoding/json does.
+func structSchema(t reflect.Type, components map[string]Schema) Schema {
+	schema := Schema{Type: "object", Properties: make(map[string]Schema)}
+	for _, field := range jsonFields(t) {
+		property := TypeSchema(field.Type, components)
+		if applyValidationTags(&property, field) {
+			schema.Required = append(schema.Required, jsonName(field))
+		}
+		schema.Properties[jsonName(field)] = property
+	}
+	return schema
+}
+
+// jsonFields returns the struct fields encoded by encoding/json, flattening embedded
+// structs without a JSON name
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && strings.Split(tag, ",")[0] == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// jsonName returns the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// modelField returns the field of a model struct named like a resource field, by JSON
+// or Go name
+func modelField(model interface{}, name string) (reflect.StructField, bool) {
+	if model == nil {
+		return reflect.StructField{}, false
+	}
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for _, field := range jsonFields(t) {
+		if jsonName(field) == name || field.Name == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// applyValidationTags adds the constraints of the validate and binding tags of a
+// struct field to its schema and reports whether the field is required
+func applyValidationTags(schema *Schema, field reflect.StructField) bool {
+	required := false
+	for _, tag := range []string{field.Tag.Get("validate"), field.Tag.Get("binding")} {
+		for _, rule := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			switch name {
+			case "required":
+				required = true
+			case "min", "gte":
+				setLowerBound(schema, param, false)
+			case "max", "lte":
+				setUpperBound(schema, param, false)
+			case "gt":
+				setLowerBound(schema, param, true)
+			case "lt":
+				setUpperBound(schema, param, true)
+			case "len":
+				setLowerBound(schema, param, false)
+				setUpperBound(schema, param, false)
+			case "oneof":
+				for _, value := range strings.Fields(param) {
+					schema.Enum = append(schema.Enum, enumValue(schema.Type, value))
+				}
+			case "email":
+				schema.Format = "email"
+			case "url", "uri":
+				schema.Format = "uri"
+			case "uuid", "uuid4":
+				schema.Format = "uuid"
+			}
+		}
+	}
+	return required
+}
+
+// setLowerBound sets the minimum length, item count or value of a schema
+func setLowerBound(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		n := int(value)
+		schema.MinLength = &n
+	case "array":
+		n := int(value)
+		schema.MinItems = &n
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMinimum = &value
+		} else {
+			schema.Minimum = &value
+		}
+	}
+}
+
+// setUpperBound sets the maximum length, item count or value of a schema
+func setUpperBound(schema *Schema, param string, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		n := int(value)
+		schema.MaxLength = &n
+	case "array":
+		n := int(value)
+		schema.MaxItems = &n
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMaximum = &value
+		} else {
+			schema.Maximum = &value
+		}
+	}
+}
+
+// enumValue converts a oneof value to the type of the schema
+func enumValue(schemaType, value string) interface{} {
+	switch schemaType {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// resourceFieldSchema returns the schema of a resource field: the schema of the model
+// field it maps to (or of its declared type) with the options, validation and JSON
+// configuration of the resource field
+func resourceFieldSchema(res resource.Resource, field resource.Field, components map[string]Schema) Schema {
+	var schema Schema
+	if structField, ok := modelField(res.GetModel(), field.Name); ok {
+		schema = TypeSchema(structField.Type, components)
+		applyValidationTags(&schema, structField)
+	} else {
+		schema = fieldToSchema(field)
+	}
+
+	if field.Json != nil {
+		if nested, ok := jsonConfigSchema(field.Json); ok {
+			schema = nested
+		}
+	}
+
+	if field.Flags != nil {
+		// Bit flags are exposed as arrays of flag names
+		names := &Schema{Type: "string"}
+		for _, flag := range field.Flags.Flags {
+			names.Enum = append(names.Enum, flag.Name)
+		}
+		schema = Schema{Type: "array", Items: names}
+	}
+
+	if len(field.Options) > 0 {
+		schema.Enum = nil
+		for _, option := range field.Options {
+			schema.Enum = append(schema.Enum, option.Value)
+		}
+	}
+
+	if v := field.Validation; v != nil {
+		applyValidation(&schema, v.Min, v.Max, v.MinLength, v.MaxLength, v.Pattern)
+	}
+	if field.Label != "" && schema.Ref == "" {
+		schema.Title = field.Label
+	}
+	schema.ReadOnly = schema.ReadOnly || field.ReadOnly
+	return schema
+}
+
+// applyValidation adds the non-zero constraints of a resource validation to a schema
+func applyValidation(schema *Schema, min, max float64, minLength, maxLength int, pattern string) {
+	if min != 0 {
+		schema.Minimum = &min
+	}
+	if max != 0 {
+		schema.Maximum = &max
+	}
+	if minLength != 0 {
+		schema.MinLength = &minLength
+	}
+	if maxLength != 0 {
+		schema.MaxLength = &maxLength
+	}
+	if pattern != "" {
+		schema.Pattern = pattern
+	}
+}
+
+// jsonConfigSchema describes a JSON field by its JSON Schema, or by its nested
+// properties
+func jsonConfigSchema(config *resource.JsonConfig) (Schema, bool) {
+	if config.JSONSchema != nil {
+		encoded, err := json.Marshal(config.JSONSchema)
+		if err == nil {
+			var schema Schema
+			if json.Unmarshal(encoded, &schema) == nil {
+				return schema, true
+			}
+		}
+	}
+	if len(config.Properties) > 0 {
+		return jsonPropertiesSchema(config.Properties), true
+	}
+	return Schema{}, false
+}
+
+// jsonPropertiesSchema describes an object with nested JSON properties, named by the
+// last segment of their path
+func jsonPropertiesSchema(properties []resource.JsonProperty) Schema {
+	schema := Schema{Type: "object", Properties: make(map[string]Schema)}
+	for _, property := range properties {
+		name := property.Path[strings.LastIndex(property.Path, ".")+1:]
+
+		var propertySchema Schema
+		switch {
+		case len(property.Properties) > 0:
+			propertySchema = jsonPropertiesSchema(property.Properties)
+		case property.Type == "array":
+			propertySchema = Schema{Type: "array", Items: &Schema{}}
+		default:
+			propertySchema = Schema{Type: property.Type}
+		}
+		propertySchema.Title = property.Label
+		propertySchema.ReadOnly = property.ReadOnly
+
+		if v := property.Validation; v != nil {
+			applyValidation(&propertySchema, v.Min, v.Max, v.MinLength, v.MaxLength, v.Pattern)
+			if v.Required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		schema.Properties[name] = propertySchema
+	}
+	return schema
+}
@@ -0,0 +1,212 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/handler"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+type SchemaAddress struct {
+	Street string `json:"street" validate:"required,max=100"`
+	City   string `json:"city"`
+}
+
+type SchemaCategory struct {
+	Name   string          `json:"name"`
+	Parent *SchemaCategory `json:"parent,omitempty"`
+}
+
+type SchemaProduct struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name" validate:"required,min=3,max=50"`
+	Price     float64         `json:"price" binding:"gt=0"`
+	Email     string          `json:"email" validate:"email"`
+	Status    string          `json:"status"`
+	Tags      []string        `json:"tags" validate:"max=5"`
+	Address   SchemaAddress   `json:"address"`
+	Category  *SchemaCategory `json:"category"`
+	Settings  json.RawMessage `json:"settings"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Secret    string          `json:"-"`
+}
+
+func TestTypeSchema(t *testing.T) {
+	components := make(map[string]Schema)
+	schema := TypeSchema(reflect.TypeOf(SchemaProduct{}), components)
+
+	assert.Equal(t, schemaRefPrefix+"SchemaProduct", schema.Ref)
+	product := components["SchemaProduct"]
+	assert.Equal(t, "object", product.Type)
+	assert.ElementsMatch(t, []string{"name"}, product.Required)
+	assert.NotContains(t, product.Properties, "Secret")
+
+	name := product.Properties["name"]
+	assert.Equal(t, 3, *name.MinLength)
+	assert.Equal(t, 50, *name.MaxLength)
+	assert.Equal(t, 0.0, *product.Properties["price"].ExclusiveMinimum)
+	assert.Equal(t, "email", product.Properties["email"].Format)
+	assert.Equal(t, 5, *product.Properties["tags"].MaxItems)
+	assert.Equal(t, "string", product.Properties["tags"].Items.Type)
+	assert.Equal(t, Schema{Type: "string", Format: "date-time"}, product.Properties["createdAt"])
+	assert.Equal(t, Schema{}, product.Properties["settings"])
+
+	// Nested structs are components
+	assert.Equal(t, schemaRefPrefix+"SchemaAddress", product.Properties["address"].Ref)
+	assert.Equal(t, []string{"street"}, components["SchemaAddress"].Required)
+	assert.Equal(t, 100, *components["SchemaAddress"].Properties["street"].MaxLength)
+
+	// Recursive structs reference themselves
+	assert.Equal(t, schemaRefPrefix+"SchemaCategory", components["SchemaCategory"].Properties["parent"].Ref)
+}
+
+func TestGenerateOpenAPIModelSchema(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: SchemaProduct{},
+		Fields: []resource.Field{
+			{Name: "id", Type: "int", ReadOnly: true},
+			{Name: "name", Type: "string", Label: "Product name"},
+			{
+				Name: "status",
+				Type: "string",
+				Options: []resource.Option{
+					{Value: "draft", Label: "Draft"},
+					{Value: "published", Label: "Published"},
+				},
+			},
+			{Name: "price", Type: "float", Validation: &resource.Validation{Max: 1000}},
+			{Name: "address", Type: "struct"},
+			{
+				Name: "settings",
+				Type: "json",
+				Json: &resource.JsonConfig{
+					Properties: []resource.JsonProperty{
+						{Path: "theme", Type: "string"},
+						{Path: "limits", Type: "object", Properties: []resource.JsonProperty{
+							{Path: "limits.daily", Type: "number", Validation: &resource.JsonValidation{Required: true, Min: 1}},
+						}},
+					},
+				},
+			},
+		},
+		Operations: []resource.Operation{resource.OperationList, resource.OperationCreate},
+	})
+
+	openAPI := GenerateOpenAPI([]resource.Resource{res}, DefaultSwaggerInfo())
+	schema := openAPI.Components.Schemas["products"]
+
+	assert.True(t, schema.Properties["id"].ReadOnly)
+	assert.Equal(t, "Product name", schema.Properties["name"].Title)
+	assert.Equal(t, 3, *schema.Properties["name"].MinLength)
+	assert.Equal(t, []interface{}{"draft", "published"}, schema.Properties["status"].Enum)
+	assert.Equal(t, 1000.0, *schema.Properties["price"].Maximum)
+	assert.Equal(t, schemaRefPrefix+"SchemaAddress", schema.Properties["address"].Ref)
+	assert.Contains(t, openAPI.Components.Schemas, "SchemaAddress")
+
+	settings := schema.Properties["settings"]
+	assert.Equal(t, "object", settings.Type)
+	assert.Equal(t, "string", settings.Properties["theme"].Type)
+	limits := settings.Properties["limits"]
+	assert.Equal(t, []string{"daily"}, limits.Required)
+	assert.Equal(t, 1.0, *limits.Properties["daily"].Minimum)
+}
+
+func TestResourceFieldSchemaFlags(t *testing.T) {
+	type account struct {
+		ID           uint `json:"id"`
+		Capabilities int  `json:"capabilities" refine:"flags=read|write"`
+	}
+	res := resource.NewResource(resource.ResourceConfig{Name: "accounts", Model: account{}})
+
+	schema := generateModelSchema(res, make(map[string]Schema))
+	capabilities := schema.Properties["capabilities"]
+	assert.Equal(t, "array", capabilities.Type)
+	require.NotNil(t, capabilities.Items)
+	assert.Equal(t, []interface{}{"read", "write"}, capabilities.Items.Enum)
+}
+
+func TestGenerateOpenAPIBulkPaths(t *testing.T) {
+	res := resource.NewResource(resource.ResourceConfig{
+		Name:  "products",
+		Model: SchemaProduct{},
+		Operations: []resource.Operation{
+			resource.OperationCreateMany,
+			resource.OperationUpdateMany,
+			resource.OperationDeleteMany,
+		},
+	})
+
+	openAPI := GenerateOpenAPI([]resource.Resource{res}, DefaultSwaggerInfo())
+	batch, exists := openAPI.Paths["/products/batch"]
+	require.True(t, exists)
+
+	create := batch["post"].RequestBody.Content["application/json"].Schema
+	assert.Equal(t, schemaRefPrefix+"products", create.Properties["values"].Items.Ref)
+	assert.Contains(t, batch["post"].Responses, "207")
+
+	update := batch["put"].RequestBody.Content["application/json"].Schema
+	assert.ElementsMatch(t, []string{"ids", "values"}, update.Required)
+	updated := batch["put"].Responses["200"].Content["application/json"].Schema
+	require.Len(t, updated.OneOf, 2)
+	assert.Equal(t, "integer", updated.OneOf[0].Properties["data"].Properties["count"].Type)
+	assert.Contains(t, updated.OneOf[1].Properties["meta"].Properties, "updated")
+	assert.Contains(t, batch["put"].Responses, "207")
+
+	remove := batch["delete"].RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"ids"}, remove.Required)
+	deleted := batch["delete"].Responses["207"].Content["application/json"].Schema
+	assert.Contains(t, deleted.Properties["meta"].Properties, "deleted")
+	assert.NotContains(t, deleted.Properties["data"].Items.Properties, "data")
+}
+
+type publishRequest struct {
+	PublishAt time.Time `json:"publishAt" validate:"required"`
+}
+
+type PublishResult struct {
+	Published bool `json:"published"`
+}
+
+func TestRegisterCustomActions(t *testing.T) {
+	ResetCustomEndpoints()
+	defer ResetCustomEndpoints()
+
+	res := resource.NewResource(resource.ResourceConfig{Name: "products", Model: SchemaProduct{}})
+	noop := func(*gin.Context, resource.Resource, repository.Repository) (interface{}, error) { return nil, nil }
+	RegisterCustomActions(res, []handler.CustomAction{
+		{
+			Name:       "publish",
+			Method:     "POST",
+			RequiresID: true,
+			Summary:    "Publish a product",
+			Handler:    noop,
+			Request:    publishRequest{},
+			Response:   PublishResult{},
+		},
+		{Name: "stats", Method: "GET", Handler: noop},
+	})
+
+	openAPI := GenerateOpenAPI([]resource.Resource{res}, DefaultSwaggerInfo())
+
+	publish := openAPI.Paths["/products/{id}/actions/publish"]["post"]
+	assert.Equal(t, "Publish a product", publish.Summary)
+	assert.Equal(t, "id", publish.Parameters[0].Name)
+	request := publish.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, schemaRefPrefix+"publishRequest", request.Ref)
+	assert.Equal(t, []string{"publishAt"}, openAPI.Components.Schemas["publishRequest"].Required)
+	data := publish.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	assert.Equal(t, schemaRefPrefix+"PublishResult", data.Ref)
+	assert.Contains(t, openAPI.Components.Schemas, "PublishResult")
+
+	stats := openAPI.Paths["/products/actions/stats"]["get"]
+	assert.Nil(t, stats.RequestBody)
+	assert.Contains(t, stats.Responses, "200")
+}
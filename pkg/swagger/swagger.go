@@ -23,7 +23,7 @@ func DefaultSwaggerInfo() SwaggerInfo {
 // GenerateOpenAPI generates OpenAPI documentation from registered resources
 func GenerateOpenAPI(resources []resource.Resource, info SwaggerInfo) *OpenAPI {
 	openAPI := &OpenAPI{
-		OpenAPI: "3.0.0",
+		OpenAPI: "3.1.0",
 		Info: Info{
 			Title:       info.Title,
 			Description: info.Description,
@@ -60,7 +60,7 @@ func GenerateOpenAPI(resources []resource.Resource, info SwaggerInfo) *OpenAPI {
 		})
 
 		// Add schema for the resource model
-		modelSchema := generateModelSchema(res)
+		modelSchema := generateModelSchema(res, openAPI.Components.Schemas)
 		openAPI.Components.Schemas[res.GetName()] = modelSchema
 
 		// Generate paths for the resource
@@ -69,6 +69,11 @@ func GenerateOpenAPI(resources []resource.Resource, info SwaggerInfo) *OpenAPI {
 
 	// Merge custom endpoints registered via RegisterCustomEndpoint
 	for _, ce := range GetCustomEndpoints() {
+		for name, schema := range ce.Schemas {
+			if _, exists := openAPI.Components.Schemas[name]; !exists {
+				openAPI.Components.Schemas[name] = schema
+			}
+		}
 		if existing, exists := openAPI.Paths[ce.Path]; exists {
 			existing[ce.Method] = ce.Operation
 			openAPI.Paths[ce.Path] = existing
@@ -77,6 +82,10 @@ func GenerateOpenAPI(resources []resource.Resource, info SwaggerInfo) *OpenAPI {
 		}
 	}
 
+	if info.Examples != nil {
+		AddExamples(openAPI, resources, *info.Examples)
+	}
+
 	return openAPI
 }
 
@@ -129,8 +138,15 @@ func RegisterSwaggerWithOwnerResources(router *gin.RouterGroup, resources []reso
 
 // Helper functions
 
-// generateModelSchema creates a schema for a resource model
-func generateModelSchema(res resource.Resource) Schema {
+// ResourceSchema returns the schema of the records of a resource, with its field
+// aliases, options and validation. Nested structs of the model are added to components.
+func ResourceSchema(res resource.Resource, components map[string]Schema) Schema {
+	return generateModelSchema(res, components)
+}
+
+// generateModelSchema creates a schema for a resource model. Nested structs of the
+// model are added to components.
+func generateModelSchema(res resource.Resource, components map[string]Schema) Schema {
 	schema := Schema{
 		Type:       "object",
 		Properties: make(map[string]Schema),
@@ -138,8 +154,17 @@ func generateModelSchema(res resource.Resource) Schema {
 	}
 
 	for _, field := range res.GetFields() {
-		fieldSchema := fieldToSchema(field)
-		schema.Properties[field.Name] = fieldSchema
+		if field.ValueObject != nil {
+			// Value objects are nested objects of the records
+			object, ok := schema.Properties[field.ValueObject.Parent]
+			if !ok {
+				object = Schema{Type: "object", Properties: make(map[string]Schema)}
+			}
+			object.Properties[field.ValueObject.Key] = resourceFieldSchema(res, field, components)
+			schema.Properties[field.ValueObject.Parent] = object
+			continue
+		}
+		schema.Properties[field.APIName()] = resourceFieldSchema(res, field, components)
 	}
 
 	// Pobierz wymagane pola
@@ -206,28 +231,12 @@ func generateResourcePaths(openAPI *OpenAPI, res resource.Resource) {
 				Responses: map[string]Response{
 					"200": {
 						Description: "Successful operation",
-						Content: map[string]MediaType{
-							"application/json": {
-								Schema: Schema{
-									Type: "object",
-									Properties: map[string]Schema{
-										"data": {
-											Type: "array",
-											Items: &Schema{
-												Ref: "#/components/schemas/" + res.GetName(),
-											},
-										},
-										"total": {
-											Type: "integer",
-										},
-									},
-								},
-							},
-						},
+						Content:     jsonContent(listResponseSchema(res)),
 					},
 				},
 			},
 		}
+		generateListQueryPath(openAPI, res)
 	}
 
 	// Generate OPTIONS endpoint for resource metadata (always available)
@@ -443,6 +452,44 @@ func generateResourcePaths(openAPI *OpenAPI, res resource.Resource) {
 				},
 			},
 		}
+
+		patchOperation := openAPI.Paths[updatePath]["put"]
+		patchOperation.Summary = fmt.Sprintf("Patch %s", res.GetName())
+		patchOperation.Description = fmt.Sprintf("Apply a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) to an existing %s", res.GetName())
+		patchOperation.OperationID = fmt.Sprintf("patch%s", capitalize(res.GetName()))
+		patchOperation.RequestBody = &RequestBody{
+			Description: fmt.Sprintf("Changes to the %s", res.GetName()),
+			Required:    true,
+			Content: map[string]MediaType{
+				"application/merge-patch+json": {
+					Schema: Schema{Type: "object"},
+				},
+				"application/json-patch+json": {
+					Schema: Schema{
+						Type: "array",
+						Items: &Schema{
+							Type: "object",
+							Properties: map[string]Schema{
+								"op":    {Type: "string", Enum: []interface{}{"add", "remove", "replace", "move", "copy", "test"}},
+								"path":  {Type: "string"},
+								"from":  {Type: "string"},
+								"value": {},
+							},
+							Required: []string{"op", "path"},
+						},
+					},
+				},
+			},
+		}
+		patchOperation.Responses = map[string]Response{
+			"200": patchOperation.Responses["200"],
+			"400": {Description: "Invalid patch"},
+			"404": {Description: "Resource not found"},
+			"409": {Description: "JSON Patch test failed"},
+			"415": {Description: "Unsupported patch media type"},
+			"422": {Description: "Patch cannot be applied"},
+		}
+		openAPI.Paths[updatePath]["patch"] = patchOperation
 	}
 
 	// Generate delete endpoint
@@ -475,60 +522,7 @@ func generateResourcePaths(openAPI *OpenAPI, res resource.Resource) {
 		}
 	}
 
-	// Generate bulk endpoints if supported
-	if res.HasOperation(resource.OperationCreateMany) {
-		bulkCreatePath := fmt.Sprintf("/%s/batch", res.GetName())
-		openAPI.Paths[bulkCreatePath] = PathItem{
-			"post": Operation{
-				Summary:     fmt.Sprintf("Bulk create %s", res.GetName()),
-				Description: fmt.Sprintf("Create multiple %s at once", res.GetName()),
-				OperationID: fmt.Sprintf("bulkCreate%s", capitalize(res.GetName())),
-				Tags:        []string{res.GetName()},
-				RequestBody: &RequestBody{
-					Description: fmt.Sprintf("Array of %s objects to be created", res.GetName()),
-					Required:    true,
-					Content: map[string]MediaType{
-						"application/json": {
-							Schema: Schema{
-								Type: "object",
-								Properties: map[string]Schema{
-									"data": {
-										Type: "array",
-										Items: &Schema{
-											Ref: "#/components/schemas/" + res.GetName(),
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-				Responses: map[string]Response{
-					"201": {
-						Description: "Resources created",
-						Content: map[string]MediaType{
-							"application/json": {
-								Schema: Schema{
-									Type: "object",
-									Properties: map[string]Schema{
-										"data": {
-											Type: "array",
-											Items: &Schema{
-												Ref: "#/components/schemas/" + res.GetName(),
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-					"400": {
-						Description: "Invalid input",
-					},
-				},
-			},
-		}
-	}
+	generateBulkPaths(openAPI, res)
 }
 
 // generateListParameters generates standard parameters for list endpoints
@@ -191,7 +191,7 @@ func TestGenerateOpenAPI(t *testing.T) {
 	openAPI := GenerateOpenAPI([]resource.Resource{mockResource}, info)
 
 	// Verify basic structure
-	assert.Equal(t, "3.0.0", openAPI.OpenAPI)
+	assert.Equal(t, "3.1.0", openAPI.OpenAPI)
 	assert.Equal(t, "Test API", openAPI.Info.Title)
 	assert.Equal(t, "API for testing", openAPI.Info.Description)
 	assert.Equal(t, "1.0.0", openAPI.Info.Version)
@@ -207,6 +207,12 @@ func TestGenerateOpenAPI(t *testing.T) {
 	assert.NotNil(t, openAPI.Paths["/users"], "List endpoint should exist")
 	assert.NotNil(t, openAPI.Paths["/users/{id}"], "Get endpoint should exist")
 
+	// Verify the POST list endpoint, answering like the list endpoint
+	queryOperation := openAPI.Paths["/users/query"]["post"]
+	assert.Equal(t, "queryUsers", queryOperation.OperationID)
+	assert.Contains(t, queryOperation.RequestBody.Content["application/json"].Schema.Properties, "filters")
+	assert.Equal(t, openAPI.Paths["/users"]["get"].Responses["200"], queryOperation.Responses["200"])
+
 	// Verify OPTIONS endpoint
 	assert.NotNil(t, openAPI.Paths["/users"]["options"], "OPTIONS endpoint should exist")
 	optionsOperation := openAPI.Paths["/users"]["options"]
@@ -242,6 +248,22 @@ func TestGenerateOpenAPI(t *testing.T) {
 	assert.Equal(t, customOp, openAPI.Paths["/custom/path"]["post"])
 }
 
+func TestGenerateModelSchemaValueObjects(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type customer struct {
+		ID      uint    `json:"id"`
+		Address address `json:"address" gorm:"embedded;embeddedPrefix:address_"`
+	}
+	res := resource.NewResource(resource.ResourceConfig{Name: "customers", Model: customer{}})
+
+	schema := generateModelSchema(res, make(map[string]Schema))
+	assert.NotContains(t, schema.Properties, "address.city")
+	assert.Equal(t, "object", schema.Properties["address"].Type)
+	assert.Equal(t, "string", schema.Properties["address"].Properties["city"].Type)
+}
+
 func TestCapitalize(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -21,15 +21,33 @@ type Parameter struct {
 	Schema      Schema `json:"schema"`
 }
 
-// Schema represents the OpenAPI Schema Object
+// Schema represents the OpenAPI Schema Object (a JSON Schema draft 2020-12 schema in
+// OpenAPI 3.1)
 type Schema struct {
-	Type       string            `json:"type"`
-	Properties map[string]Schema `json:"properties,omitempty"`
-	Required   []string          `json:"required,omitempty"`
-	Items      *Schema           `json:"items,omitempty"`
-	Format     string            `json:"format,omitempty"`
-	Enum       []interface{}     `json:"enum,omitempty"`
-	Ref        string            `json:"$ref,omitempty"`
+	Type                 string            `json:"type,omitempty"`
+	Title                string            `json:"title,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Properties           map[string]Schema `json:"properties,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+	Items                *Schema           `json:"items,omitempty"`
+	AdditionalProperties interface{}       `json:"additionalProperties,omitempty"`
+	Format               string            `json:"format,omitempty"`
+	Enum                 []interface{}     `json:"enum,omitempty"`
+	Ref                  string            `json:"$ref,omitempty"`
+	OneOf                []Schema          `json:"oneOf,omitempty"`
+
+	// Validation keywords
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MinLength        *int     `json:"minLength,omitempty"`
+	MaxLength        *int     `json:"maxLength,omitempty"`
+	Pattern          string   `json:"pattern,omitempty"`
+	MinItems         *int     `json:"minItems,omitempty"`
+	MaxItems         *int     `json:"maxItems,omitempty"`
+
+	ReadOnly bool `json:"readOnly,omitempty"`
 }
 
 // RequestBody represents the OpenAPI Request Body Object
@@ -47,7 +65,8 @@ type Response struct {
 
 // MediaType represents the OpenAPI Media Type Object
 type MediaType struct {
-	Schema Schema `json:"schema"`
+	Schema  Schema      `json:"schema"`
+	Example interface{} `json:"example,omitempty"`
 }
 
 // SwaggerInfo contains metadata for the Swagger documentation
@@ -60,6 +79,10 @@ type SwaggerInfo struct {
 	Schemes     []string
 	License     *License
 	Contact     *Contact
+
+	// Examples adds request and response examples to the operations of resources
+	// when set (see AddExamples)
+	Examples *ExampleConfig
 }
 
 // License information
@@ -75,7 +98,7 @@ type Contact struct {
 	Email string
 }
 
-// OpenAPI represents an OpenAPI 3.0 document
+// OpenAPI represents an OpenAPI 3.1 document
 type OpenAPI struct {
 	OpenAPI    string                `json:"openapi"`
 	Info       Info                  `json:"info"`
@@ -0,0 +1,204 @@
+package takeout
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+)
+
+// batchSize is the number of records fetched per query while building an archive
+const batchSize = 500
+
+// build writes the archive of the owner in ctx to key and returns the number of
+// exported records. Every owner resource with a repository gets one file.
+func (e *Exporter) build(ctx context.Context, key string, roles []string) (int, error) {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+
+	total := 0
+	for _, res := range e.ownerResources() {
+		entry, err := zipWriter.Create(res.GetName() + "." + e.config.Format)
+		if err != nil {
+			return total, err
+		}
+		count, err := e.writeResource(ctx, entry, res, e.config.Repositories[res.GetName()], roles)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", res.GetName(), err)
+		}
+		total += count
+	}
+	if err := zipWriter.Close(); err != nil {
+		return total, err
+	}
+
+	_, err := e.config.Storage.Put(ctx, key, &archive, "application/zip")
+	return total, err
+}
+
+// ownerResources returns the owner resources with a repository, sorted by name
+func (e *Exporter) ownerResources() []resource.Resource {
+	var resources []resource.Resource
+	for _, res := range e.config.Registry.GetAll() {
+		if _, ok := e.config.Repositories[res.GetName()]; ok && resource.IsOwnerResource(res) {
+			resources = append(resources, res)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].GetName() < resources[j].GetName()
+	})
+	return resources
+}
+
+// columns returns the fields of a resource written to the archive: visible fields the
+// roles may read that are not redacted
+func (e *Exporter) columns(res resource.Resource, roles []string) []resource.Field {
+	redacted := make(map[string]bool)
+	for _, name := range e.config.Redact[res.GetName()] {
+		redacted[name] = true
+	}
+
+	var columns []resource.Field
+	for _, field := range res.GetFields() {
+		if field.Hidden || redacted[field.Name] || redacted[field.APIName()] {
+			continue
+		}
+		if len(roles) > 0 && !auth.CanAccessField(field, "read", roles) {
+			continue
+		}
+		columns = append(columns, field)
+	}
+	return columns
+}
+
+// writeResource writes all records of the owner of a resource as JSON or CSV
+func (e *Exporter) writeResource(ctx context.Context, w io.Writer, res resource.Resource, repo repository.Repository, roles []string) (int, error) {
+	options := query.QueryOptions{Resource: res, PerPage: batchSize}
+	if err := options.Validate(); err != nil {
+		return 0, err
+	}
+
+	columns := e.columns(res, roles)
+	names := make([]string, len(columns))
+	for i, field := range columns {
+		names[i] = field.APIName()
+	}
+
+	var csvWriter *csv.Writer
+	if e.config.Format == FormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(names); err != nil {
+			return 0, err
+		}
+	} else if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for page := 1; ; page++ {
+		options.Page = page
+		records, err := fetchBatch(ctx, repo, options)
+		if err != nil {
+			return count, err
+		}
+		for _, record := range records {
+			if err := writeRecord(w, csvWriter, record, columns, names, count); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(records) < batchSize {
+			break
+		}
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return count, csvWriter.Error()
+	}
+	_, err := io.WriteString(w, "]")
+	return count, err
+}
+
+// writeRecord writes one record as a CSV row or a JSON object
+func writeRecord(w io.Writer, csvWriter *csv.Writer, record map[string]interface{}, columns []resource.Field, names []string, index int) error {
+	if csvWriter != nil {
+		row := make([]string, len(columns))
+		for i, field := range columns {
+			row[i] = csvText(record[field.Name])
+		}
+		return csvWriter.Write(row)
+	}
+
+	object := make(map[string]interface{}, len(columns))
+	for i, field := range columns {
+		object[names[i]] = record[field.Name]
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if index > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(encoded)
+	return err
+}
+
+// fetchBatch fetches one page of records as JSON objects keyed by field name
+func fetchBatch(ctx context.Context, repo repository.Repository, options query.QueryOptions) ([]map[string]interface{}, error) {
+	data, _, err := repo.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, nil
+	}
+
+	records := make([]map[string]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		encoded, err := json.Marshal(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// csvText formats a value as CSV text; objects and arrays are written as JSON
+func csvText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
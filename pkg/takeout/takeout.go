@@ -0,0 +1,462 @@
+// Package takeout exports all records of an owner across owner resources as a ZIP
+// archive, for GDPR data portability requests.
+package takeout
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/storage"
+	"github.com/suranig/refine-gin/pkg/worker"
+)
+
+// Takeout errors
+var (
+	ErrJobNotFound      = errors.New("takeout not found")
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrLinkExpired      = errors.New("download link expired")
+	ErrDraining         = errors.New("takeouts are shutting down")
+)
+
+// Default settings
+const (
+	DefaultLinkTTL   = 24 * time.Hour
+	DefaultKeyPrefix = "takeout/"
+)
+
+// Archive formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Status of a takeout job
+type Status string
+
+// Takeout job statuses
+const (
+	StatusPending Status = "pending"
+	StatusReady   Status = "ready"
+	StatusFailed  Status = "failed"
+)
+
+// Config contains configuration for takeout exports
+type Config struct {
+	// Registry the owner resources are taken from (the global registry if nil)
+	Registry *resource.ResourceRegistry
+
+	// Repositories of the owner resources by resource name (see
+	// repository.NewOwnerRepository). Owner resources without a repository are
+	// not exported.
+	Repositories map[string]repository.Repository
+
+	// Storage the archives are written to
+	Storage storage.Provider
+
+	// Secret signs the download links
+	Secret []byte
+
+	// Pool the archives are built on (optional, a goroutine per takeout without it)
+	Pool *worker.Pool
+
+	// Format of the files in the archive, FormatJSON (default) or FormatCSV
+	Format string
+
+	// Redact lists fields left out of the archive by resource name, in addition to
+	// hidden fields and fields the roles of the requester may not read
+	Redact map[string][]string
+
+	// LinkTTL is how long takeouts are kept once completed (DefaultLinkTTL if zero).
+	// Download links are valid until then; expired takeouts are forgotten and their
+	// archives deleted.
+	LinkTTL time.Duration
+
+	// KeyPrefix prefixes the storage keys of the archives (DefaultKeyPrefix if empty)
+	KeyPrefix string
+}
+
+// Job is a takeout requested by an owner
+type Job struct {
+	ID          string     `json:"id"`
+	Status      Status     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Records     int        `json:"records"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	ownerID string
+	roles   []string
+
+	// scope adds the values of the starting request to the context of the build
+	scope func(ctx context.Context) context.Context
+}
+
+// Exporter builds takeout archives in the background and serves them through
+// signed download links. It implements refinegin.Drainer: register it in
+// RunOptions.Jobs so shutdown waits for the archives being built.
+type Exporter struct {
+	config Config
+
+	mu       sync.RWMutex
+	jobs     map[string]*Job
+	draining bool
+
+	// running tracks the archives built without a pool, which are canceled through
+	// ctx when draining times out
+	running sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewExporter creates an exporter
+func NewExporter(config Config) *Exporter {
+	if config.Registry == nil {
+		config.Registry = resource.GlobalResourceRegistry
+	}
+	if config.Format == "" {
+		config.Format = FormatJSON
+	}
+	if config.LinkTTL == 0 {
+		config.LinkTTL = DefaultLinkTTL
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = DefaultKeyPrefix
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Exporter{config: config, jobs: make(map[string]*Job), ctx: ctx, cancel: cancel}
+}
+
+// RegisterTakeoutRoutes registers the takeout endpoints of owners below path (e.g.
+// "/takeout"):
+//
+//	POST <path>        start a takeout of the records of the requesting owner
+//	GET  <path>/:id    status of a takeout, with a signed download URL once ready
+//
+// The owner is taken from the context (see middleware.OwnerContext).
+func RegisterTakeoutRoutes(router *gin.RouterGroup, path string, exporter *Exporter) {
+	router.POST(path, middleware.NoCacheMiddleware(), GenerateStartHandler(exporter))
+	router.GET(path+"/:id", middleware.NoCacheMiddleware(), GenerateStatusHandler(exporter))
+}
+
+// RegisterTakeoutDownloadRoute registers GET <path>/:id/download serving archives
+// through signed links (?expires=&signature=). Downloads are authorized by the
+// signature only, so links can be opened outside the application: register the route
+// on a group with the base path of RegisterTakeoutRoutes but without the owner
+// middleware.
+func RegisterTakeoutDownloadRoute(router *gin.RouterGroup, path string, exporter *Exporter) {
+	router.GET(path+"/:id/download", GenerateDownloadHandler(exporter))
+}
+
+// GenerateStartHandler generates a handler starting a takeout of the requesting owner
+func GenerateStartHandler(exporter *Exporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := middleware.GetOwnerID(c.Request.Context()); err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		job, err := exporter.Start(c.Request.Context())
+		if err != nil {
+			apierror.Respond(c, http.StatusServiceUnavailable, err)
+			return
+		}
+		c.Header("Location", c.Request.URL.Path+"/"+job.ID)
+		c.JSON(http.StatusAccepted, gin.H{"data": job})
+	}
+}
+
+// GenerateStatusHandler generates a handler returning the status of a takeout of the
+// requesting owner, with the download URL once the archive is ready
+func GenerateStatusHandler(exporter *Exporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := middleware.GetOwnerID(c.Request.Context())
+		if err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+		job, ok := exporter.Job(c.Param("id"))
+		if !ok || job.ownerID != fmt.Sprint(ownerID) {
+			apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+
+		response := gin.H{"data": job}
+		if job.Status == StatusReady {
+			expires := job.CompletedAt.Add(exporter.config.LinkTTL)
+			response["url"] = c.Request.URL.Path + "/download?" + exporter.signedQuery(job.ID, expires)
+			response["expiresAt"] = expires.UTC().Format(time.RFC3339)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GenerateDownloadHandler generates a handler serving the archive of a signed link
+func GenerateDownloadHandler(exporter *Exporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if err := exporter.verify(id, c.Query("expires"), c.Query("signature")); err != nil {
+			apierror.Respond(c, http.StatusForbidden, err)
+			return
+		}
+		job, ok := exporter.Job(id)
+		if !ok || job.Status != StatusReady {
+			apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+
+		object, err := exporter.config.Storage.Stat(c.Request.Context(), exporter.key(id))
+		if err != nil {
+			respondStorageError(c, err)
+			return
+		}
+		reader, err := exporter.config.Storage.Open(c.Request.Context(), object.Key)
+		if err != nil {
+			respondStorageError(c, err)
+			return
+		}
+		defer reader.Close()
+
+		c.DataFromReader(http.StatusOK, object.Size, "application/zip", reader, map[string]string{
+			"Content-Disposition": fmt.Sprintf(`attachment; filename="takeout-%s.zip"`, id),
+		})
+	}
+}
+
+// respondStorageError writes a 404 for missing archives and a 500 otherwise
+func respondStorageError(c *gin.Context, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		apierror.Respond(c, http.StatusNotFound, ErrJobNotFound)
+		return
+	}
+	apierror.Respond(c, http.StatusInternalServerError, err)
+}
+
+// Start queues a takeout of the records of the owner of a request context. The
+// records are read in the background as the request would read them: with its owner,
+// owner groups, tenant, roles and repository scopes (see repository.WithScope).
+func (e *Exporter) Start(ctx context.Context) (*Job, error) {
+	ownerID, err := middleware.GetOwnerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	roles, _ := requestctx.Roles.Get(ctx)
+	job := &Job{
+		ID:        id,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		ownerID:   fmt.Sprint(ownerID),
+		roles:     roles,
+		scope:     requestScope(ctx, ownerID),
+	}
+
+	e.mu.Lock()
+	if e.draining {
+		e.mu.Unlock()
+		return nil, ErrDraining
+	}
+	expired := e.evict(time.Now())
+	e.jobs[id] = job
+	if e.config.Pool == nil {
+		e.running.Add(1)
+	}
+	e.mu.Unlock()
+	e.deleteArchives(expired)
+
+	task := func(ctx context.Context) error {
+		return e.run(ctx, id)
+	}
+	if e.config.Pool == nil {
+		go func() {
+			defer e.running.Done()
+			_ = task(e.ctx)
+		}()
+		return e.snapshot(job), nil
+	}
+	if err := e.config.Pool.Submit("takeout", task); err != nil {
+		e.mu.Lock()
+		delete(e.jobs, id)
+		e.mu.Unlock()
+		return nil, err
+	}
+	return e.snapshot(job), nil
+}
+
+// Job returns a copy of a takeout job; expired takeouts are not found
+func (e *Exporter) Job(id string) (*Job, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	job, ok := e.jobs[id]
+	if !ok || e.expired(job, time.Now()) {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// Drain stops accepting takeouts and waits until the archives being built without a
+// pool are done or ctx is done; they are then canceled. Archives built on a pool are
+// drained with the pool.
+func (e *Exporter) Drain(ctx context.Context) error {
+	e.mu.Lock()
+	e.draining = true
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		e.cancel()
+		return nil
+	case <-ctx.Done():
+		e.cancel()
+		return ctx.Err()
+	}
+}
+
+// expired reports whether a completed takeout is older than LinkTTL
+func (e *Exporter) expired(job *Job, now time.Time) bool {
+	return job.CompletedAt != nil && now.After(job.CompletedAt.Add(e.config.LinkTTL))
+}
+
+// evict forgets the expired takeouts, returning the keys of their archives. The
+// caller holds the lock.
+func (e *Exporter) evict(now time.Time) []string {
+	var keys []string
+	for id, job := range e.jobs {
+		if e.expired(job, now) {
+			delete(e.jobs, id)
+			if job.Status == StatusReady {
+				keys = append(keys, e.key(id))
+			}
+		}
+	}
+	return keys
+}
+
+// deleteArchives deletes the archives of expired takeouts. Errors are ignored: the
+// archives cannot be downloaded anymore.
+func (e *Exporter) deleteArchives(keys []string) {
+	for _, key := range keys {
+		_ = e.config.Storage.Delete(context.Background(), key)
+	}
+}
+
+// requestScope returns a function adding the values of a request context that scope
+// repositories to a context, so background work reads the records the request could.
+// The values are copied: the request context itself ends with the request.
+func requestScope(request context.Context, ownerID interface{}) func(ctx context.Context) context.Context {
+	values := []requestctx.Value{requestctx.OwnerID.Value(ownerID)}
+	if groupIDs, ok := requestctx.OwnerGroupIDs.Get(request); ok {
+		values = append(values, requestctx.OwnerGroupIDs.Value(groupIDs))
+	}
+	if tenantID, ok := requestctx.TenantID.Get(request); ok {
+		values = append(values, requestctx.TenantID.Value(tenantID))
+	}
+	if roles, ok := requestctx.Roles.Get(request); ok {
+		values = append(values, requestctx.Roles.Value(roles))
+	}
+	scopes := repository.ScopesFromContext(request)
+
+	return func(ctx context.Context) context.Context {
+		ctx = requestctx.With(ctx, values...)
+		for _, scope := range scopes {
+			ctx = repository.WithScope(ctx, scope.Field, scope.Value)
+		}
+		return ctx
+	}
+}
+
+// run builds the archive of a job and records the outcome
+func (e *Exporter) run(ctx context.Context, id string) error {
+	job, _ := e.Job(id)
+	ctx = job.scope(ctx)
+
+	records, err := e.build(ctx, e.key(id), job.roles)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	stored := e.jobs[id]
+	stored.CompletedAt = &now
+	stored.Records = records
+	if err != nil {
+		stored.Status = StatusFailed
+		stored.Error = err.Error()
+		return err
+	}
+	stored.Status = StatusReady
+	return nil
+}
+
+// snapshot returns a copy of a job
+func (e *Exporter) snapshot(job *Job) *Job {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	copied := *job
+	return &copied
+}
+
+// key returns the storage key of the archive of a job
+func (e *Exporter) key(id string) string {
+	return e.config.KeyPrefix + id + ".zip"
+}
+
+// signedQuery returns the query string of a download link valid until expires
+func (e *Exporter) signedQuery(id string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return "expires=" + unix + "&signature=" + e.sign(id, unix)
+}
+
+// sign computes the signature of a download link
+func (e *Exporter) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, e.config.Secret)
+	mac.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and expiry of a download link
+func (e *Exporter) verify(id, expires, signature string) error {
+	if len(e.config.Secret) == 0 || !hmac.Equal([]byte(e.sign(id, expires)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
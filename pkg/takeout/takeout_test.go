@@ -0,0 +1,292 @@
+package takeout
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/query"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"github.com/suranig/refine-gin/pkg/storage"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type TakeoutNote struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	OwnerID string `json:"ownerId"`
+	Title   string `json:"title"`
+	Secret  string `json:"secret"`
+}
+
+func setupTakeout(t *testing.T, format string) (*gin.Engine, *Exporter) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TakeoutNote{}))
+	require.NoError(t, db.Create(&[]TakeoutNote{
+		{OwnerID: "alice", Title: "first", Secret: "s1"},
+		{OwnerID: "bob", Title: "other", Secret: "s2"},
+		{OwnerID: "alice", Title: "second", Secret: "s3"},
+	}).Error)
+
+	registry := resource.NewResourceRegistry()
+	notes := resource.NewOwnerResource(resource.NewResource(resource.ResourceConfig{
+		Name:  "notes",
+		Model: TakeoutNote{},
+	}), resource.DefaultOwnerConfig())
+	registry.Register(notes)
+	registry.Register(resource.NewResource(resource.ResourceConfig{Name: "plain", Model: TakeoutNote{}}))
+
+	repo, err := repository.NewOwnerRepository(db, notes)
+	require.NoError(t, err)
+
+	exporter := NewExporter(Config{
+		Registry:     registry,
+		Repositories: map[string]repository.Repository{"notes": repo},
+		Storage:      storage.NewLocalProvider(t.TempDir()),
+		Secret:       []byte("secret"),
+		Format:       format,
+		Redact:       map[string][]string{"notes": {"secret"}},
+	})
+
+	r := gin.New()
+	api := r.Group("/api", middleware.OwnerContext(middleware.ExtractOwnerIDFromHeader("X-Owner-ID")))
+	RegisterTakeoutRoutes(api, "/takeout", exporter)
+	RegisterTakeoutDownloadRoute(r.Group("/api"), "/takeout", exporter)
+	return r, exporter
+}
+
+func request(r *gin.Engine, method, path, owner string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Owner-ID", owner)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// waitForDownload starts a takeout and returns the download URL once it is ready
+func waitForDownload(t *testing.T, r *gin.Engine, owner string) string {
+	w := request(r, http.MethodPost, "/api/takeout", owner)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var started struct {
+		Data Job `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+	assert.Equal(t, StatusPending, started.Data.Status)
+	assert.Equal(t, "/api/takeout/"+started.Data.ID, w.Header().Get("Location"))
+
+	var status struct {
+		Data Job    `json:"data"`
+		URL  string `json:"url"`
+	}
+	require.Eventually(t, func() bool {
+		w := request(r, http.MethodGet, "/api/takeout/"+started.Data.ID, owner)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status.Data.Status != StatusPending
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, StatusReady, status.Data.Status, status.Data.Error)
+	assert.Equal(t, 2, status.Data.Records)
+
+	// Takeouts of other owners are not visible
+	w = request(r, http.MethodGet, "/api/takeout/"+started.Data.ID, "bob")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	return status.URL
+}
+
+// readArchive downloads an archive and returns its files
+func readArchive(t *testing.T, r *gin.Engine, url string) map[string]string {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestTakeoutJSON(t *testing.T) {
+	r, _ := setupTakeout(t, "")
+	url := waitForDownload(t, r, "alice")
+
+	files := readArchive(t, r, url)
+	require.Contains(t, files, "notes.json")
+	assert.NotContains(t, files, "plain.json")
+
+	var notes []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(files["notes.json"]), &notes))
+	require.Len(t, notes, 2)
+	assert.Equal(t, "first", notes[0]["title"])
+	assert.Equal(t, "second", notes[1]["title"])
+	for _, note := range notes {
+		assert.Equal(t, "alice", note["ownerId"])
+		assert.NotContains(t, note, "secret")
+	}
+}
+
+func TestTakeoutCSV(t *testing.T) {
+	r, _ := setupTakeout(t, FormatCSV)
+	url := waitForDownload(t, r, "alice")
+
+	files := readArchive(t, r, url)
+	lines := strings.Split(strings.TrimSpace(files["notes.csv"]), "\n")
+	require.Len(t, lines, 3)
+	assert.NotContains(t, lines[0], "secret")
+	assert.Contains(t, lines[1], "first")
+	assert.Contains(t, lines[2], "second")
+}
+
+func TestTakeoutDownloadLinks(t *testing.T) {
+	r, exporter := setupTakeout(t, "")
+	url := waitForDownload(t, r, "alice")
+	id := strings.Split(strings.TrimPrefix(url, "/api/takeout/"), "/")[0]
+
+	// Tampered signatures are rejected
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"0", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Expired links are rejected
+	expired := "/api/takeout/" + id + "/download?" + exporter.signedQuery(id, time.Now().Add(-time.Minute))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, expired, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrLinkExpired.Error())
+
+	// Starting a takeout requires an owner
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/takeout", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTakeoutExpiry(t *testing.T) {
+	r, exporter := setupTakeout(t, "")
+	url := waitForDownload(t, r, "alice")
+	id := strings.Split(strings.TrimPrefix(url, "/api/takeout/"), "/")[0]
+
+	// Completed takeouts expire after LinkTTL with their links
+	exporter.mu.Lock()
+	completed := time.Now().Add(-DefaultLinkTTL - time.Minute)
+	exporter.jobs[id].CompletedAt = &completed
+	exporter.mu.Unlock()
+
+	assert.Equal(t, http.StatusNotFound, request(r, http.MethodGet, "/api/takeout/"+id, "alice").Code)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/takeout/"+id+"/download?"+exporter.signedQuery(id, time.Now().Add(time.Minute)), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Expired takeouts are evicted with their archives
+	waitForDownload(t, r, "alice")
+	exporter.mu.RLock()
+	assert.Len(t, exporter.jobs, 1)
+	assert.NotContains(t, exporter.jobs, id)
+	exporter.mu.RUnlock()
+	_, err := exporter.config.Storage.Stat(context.Background(), exporter.key(id))
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// blockingRepository blocks lists until release is closed
+type blockingRepository struct {
+	repository.Repository
+	release chan struct{}
+}
+
+func (r *blockingRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	<-r.release
+	return r.Repository.List(ctx, options)
+}
+
+func TestTakeoutDrain(t *testing.T) {
+	_, exporter := setupTakeout(t, "")
+	blocking := &blockingRepository{Repository: exporter.config.Repositories["notes"], release: make(chan struct{})}
+	exporter.config.Repositories["notes"] = blocking
+
+	job, err := exporter.Start(requestctx.OwnerID.With(context.Background(), "alice"))
+	require.NoError(t, err)
+
+	drained := make(chan error, 1)
+	go func() { drained <- exporter.Drain(context.Background()) }()
+
+	// Shutdown waits for the archives being built
+	select {
+	case <-drained:
+		t.Fatal("drain returned before the takeout was built")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = exporter.Start(requestctx.OwnerID.With(context.Background(), "alice"))
+	assert.ErrorIs(t, err, ErrDraining)
+
+	close(blocking.release)
+	require.NoError(t, <-drained)
+	built, ok := exporter.Job(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, StatusReady, built.Status, built.Error)
+}
+
+// recordingRepository keeps the context of the last list
+type recordingRepository struct {
+	repository.Repository
+	ctx context.Context
+}
+
+func (r *recordingRepository) List(ctx context.Context, options query.QueryOptions) (interface{}, int64, error) {
+	r.ctx = ctx
+	return r.Repository.List(ctx, options)
+}
+
+func TestTakeoutKeepsRequestScope(t *testing.T) {
+	_, exporter := setupTakeout(t, "")
+	recording := &recordingRepository{Repository: exporter.config.Repositories["notes"]}
+	exporter.config.Repositories["notes"] = recording
+
+	ctx, cancel := context.WithCancel(requestctx.With(context.Background(),
+		requestctx.OwnerID.Value("alice"),
+		requestctx.OwnerGroupIDs.Value([]interface{}{"team-1"}),
+		requestctx.TenantID.Value("acme"),
+		requestctx.Roles.Value([]string{"editor"}),
+	))
+	job, err := exporter.Start(repository.WithScope(ctx, "title", "first"))
+	require.NoError(t, err)
+	// The request ends before the archive is built
+	cancel()
+
+	require.Eventually(t, func() bool {
+		built, _ := exporter.Job(job.ID)
+		return built.Status != StatusPending
+	}, time.Second, 10*time.Millisecond)
+	built, _ := exporter.Job(job.ID)
+	require.Equal(t, StatusReady, built.Status, built.Error)
+	assert.Equal(t, 1, built.Records)
+
+	owner, _ := middleware.GetOwnerID(recording.ctx)
+	assert.Equal(t, "alice", owner)
+	assert.Equal(t, []interface{}{"team-1"}, middleware.GetOwnerGroupIDs(recording.ctx))
+	tenant, _ := middleware.GetTenantID(recording.ctx)
+	assert.Equal(t, "acme", tenant)
+	roles, _ := requestctx.Roles.Get(recording.ctx)
+	assert.Equal(t, []string{"editor"}, roles)
+	assert.Equal(t, []repository.Scope{{Field: "title", Value: "first"}}, repository.ScopesFromContext(recording.ctx))
+}
@@ -0,0 +1,135 @@
+// Package uow runs units of work: functions writing to several resources in a single
+// database transaction, through repositories bound to it.
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// ErrNotConfigured is returned by Run before Configure is called
+var ErrNotConfigured = errors.New("uow: no database configured, call uow.Configure")
+
+// RepositoryFactory creates the repository of a resource bound to a transaction
+type RepositoryFactory func(tx *gorm.DB, res resource.Resource) (repository.Repository, error)
+
+// Config contains configuration for units of work
+type Config struct {
+	// DB the transactions are started on
+	DB *gorm.DB
+
+	// Registry the resources are looked up in (the global registry if nil)
+	Registry *resource.ResourceRegistry
+
+	// Factory creates the repositories of the resources (DefaultRepositoryFactory if
+	// nil)
+	Factory RepositoryFactory
+}
+
+// UnitOfWork yields the repositories of a transaction
+type UnitOfWork interface {
+	// Repo returns the repository of a registered resource bound to the transaction.
+	// It panics for unknown resources, which rolls the transaction back.
+	Repo(name string) repository.Repository
+
+	// DB returns the transaction, for queries without a resource
+	DB() *gorm.DB
+}
+
+// Runner runs units of work on a database
+type Runner struct {
+	config Config
+}
+
+// Default is the runner of Run, set by Configure
+var Default *Runner
+
+// New creates a runner of units of work
+func New(config Config) *Runner {
+	if config.Registry == nil {
+		config.Registry = resource.GlobalResourceRegistry
+	}
+	if config.Factory == nil {
+		config.Factory = DefaultRepositoryFactory
+	}
+	return &Runner{config: config}
+}
+
+// Configure sets the database of Run
+func Configure(db *gorm.DB) {
+	Default = New(Config{DB: db})
+}
+
+// Run runs fn in a transaction of the database set by Configure, e.g. in a custom
+// action cancelling an order and restocking its items:
+//
+//	ctx := c.Request.Context()
+//	err := uow.Run(ctx, func(tx uow.UnitOfWork) error {
+//		if _, err := tx.Repo("orders").Update(ctx, id, map[string]interface{}{"status": "cancelled"}); err != nil {
+//			return err
+//		}
+//		return tx.Repo("items").BulkUpdate(ctx, map[string]interface{}{"order_id": id},
+//			map[string]interface{}{"stock": gorm.Expr("stock + quantity")})
+//	})
+//
+// The transaction commits when fn returns nil and rolls back when it returns an error
+// or panics.
+func Run(ctx context.Context, fn func(tx UnitOfWork) error) error {
+	if Default == nil {
+		return ErrNotConfigured
+	}
+	return Default.Run(ctx, fn)
+}
+
+// Run runs fn in a transaction, committed when fn returns nil
+func (r *Runner) Run(ctx context.Context, fn func(tx UnitOfWork) error) error {
+	if r.config.DB == nil {
+		return ErrNotConfigured
+	}
+	return r.config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&unitOfWork{config: r.config, tx: tx, repos: make(map[string]repository.Repository)})
+	})
+}
+
+// DefaultRepositoryFactory creates owner repositories for owner resources, so the
+// records of other owners stay out of reach, and generic repositories otherwise
+func DefaultRepositoryFactory(tx *gorm.DB, res resource.Resource) (repository.Repository, error) {
+	if _, ok := res.(resource.OwnerResource); ok {
+		return repository.NewOwnerRepository(tx, res)
+	}
+	return repository.NewGenericRepositoryWithResource(tx, res), nil
+}
+
+// unitOfWork is the UnitOfWork of a transaction, creating each repository once
+type unitOfWork struct {
+	config Config
+	tx     *gorm.DB
+	repos  map[string]repository.Repository
+}
+
+// Repo returns the repository of a resource bound to the transaction
+func (u *unitOfWork) Repo(name string) repository.Repository {
+	if repo, ok := u.repos[name]; ok {
+		return repo
+	}
+	res, ok := u.config.Registry.GetByName(name)
+	if !ok {
+		panic(fmt.Sprintf("uow: unknown resource %q", name))
+	}
+	repo, err := u.config.Factory(u.tx, res)
+	if err != nil {
+		panic(fmt.Sprintf("uow: repository of %q: %v", name, err))
+	}
+	u.repos[name] = repo
+	return repo
+}
+
+// DB returns the transaction
+func (u *unitOfWork) DB() *gorm.DB {
+	return u.tx
+}
@@ -0,0 +1,93 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Order struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Status string `json:"status"`
+}
+
+type Item struct {
+	ID      uint `json:"id" gorm:"primaryKey"`
+	OrderID uint `json:"orderId"`
+	Stock   int  `json:"stock"`
+}
+
+func setupUnitOfWork(t *testing.T) (*gorm.DB, *Runner) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Order{}, &Item{}))
+	require.NoError(t, db.Create(&Order{Status: "open"}).Error)
+	require.NoError(t, db.Create([]*Item{{OrderID: 1, Stock: 1}, {OrderID: 1, Stock: 2}}).Error)
+
+	registry := resource.NewResourceRegistry()
+	registry.Register(resource.NewResource(resource.ResourceConfig{Name: "orders", Model: Order{}}))
+	registry.Register(resource.NewResource(resource.ResourceConfig{Name: "items", Model: Item{}}))
+	return db, New(Config{DB: db, Registry: registry})
+}
+
+func TestRunCommits(t *testing.T) {
+	db, runner := setupUnitOfWork(t)
+	ctx := context.Background()
+
+	err := runner.Run(ctx, func(tx UnitOfWork) error {
+		if _, err := tx.Repo("orders").Update(ctx, 1, map[string]interface{}{"status": "cancelled"}); err != nil {
+			return err
+		}
+		assert.Same(t, tx.Repo("orders"), tx.Repo("orders"))
+		return tx.Repo("items").BulkUpdate(ctx, map[string]interface{}{"order_id": 1},
+			map[string]interface{}{"stock": gorm.Expr("stock + 1")})
+	})
+	require.NoError(t, err)
+
+	var order Order
+	require.NoError(t, db.First(&order, 1).Error)
+	assert.Equal(t, "cancelled", order.Status)
+	var stock int
+	require.NoError(t, db.Model(&Item{}).Select("SUM(stock)").Scan(&stock).Error)
+	assert.Equal(t, 5, stock)
+}
+
+func TestRunRollsBack(t *testing.T) {
+	db, runner := setupUnitOfWork(t)
+	ctx := context.Background()
+	failed := errors.New("restock failed")
+
+	err := runner.Run(ctx, func(tx UnitOfWork) error {
+		if _, err := tx.Repo("orders").Update(ctx, 1, map[string]interface{}{"status": "cancelled"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+
+	var order Order
+	require.NoError(t, db.First(&order, 1).Error)
+	assert.Equal(t, "open", order.Status)
+
+	// Unknown resources panic, rolling back too
+	assert.PanicsWithValue(t, `uow: unknown resource "missing"`, func() {
+		_ = runner.Run(ctx, func(tx UnitOfWork) error {
+			_, _ = tx.Repo("orders").Update(ctx, 1, map[string]interface{}{"status": "cancelled"})
+			tx.Repo("missing")
+			return nil
+		})
+	})
+	require.NoError(t, db.First(&order, 1).Error)
+	assert.Equal(t, "open", order.Status)
+}
+
+func TestRunNotConfigured(t *testing.T) {
+	Default = nil
+	assert.ErrorIs(t, Run(context.Background(), func(UnitOfWork) error { return nil }), ErrNotConfigured)
+}
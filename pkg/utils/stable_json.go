@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// MarshalStable encodes v as JSON with a deterministic key order. Keys of records
+// (the top-level object and the objects in a top-level "data" value) follow fieldOrder,
+// remaining keys and keys of nested objects are sorted. Field names are matched
+// ignoring case, underscores and dashes, so the order also applies after naming
+// convention conversion.
+func MarshalStable(v interface{}, fieldOrder []string) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ReorderJSON(raw, fieldOrder)
+}
+
+// ReorderJSON rewrites an encoded JSON document with the key order of MarshalStable
+func ReorderJSON(data []byte, fieldOrder []string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	rank := make(map[string]int, len(fieldOrder))
+	for i, name := range fieldOrder {
+		if _, exists := rank[normalizeFieldKey(name)]; !exists {
+			rank[normalizeFieldKey(name)] = i
+		}
+	}
+
+	var buf bytes.Buffer
+	if object, ok := value.(map[string]interface{}); ok {
+		err := writeStableObject(&buf, object, rank, func(key string, child interface{}) error {
+			if key != "data" {
+				return writeStableJSON(&buf, child, nil)
+			}
+			if items, ok := child.([]interface{}); ok {
+				return writeStableArray(&buf, items, rank)
+			}
+			return writeStableJSON(&buf, child, rank)
+		})
+		return buf.Bytes(), err
+	}
+	if items, ok := value.([]interface{}); ok {
+		err := writeStableArray(&buf, items, rank)
+		return buf.Bytes(), err
+	}
+	err := writeStableJSON(&buf, value, nil)
+	return buf.Bytes(), err
+}
+
+// normalizeFieldKey makes field names comparable across naming conventions
+func normalizeFieldKey(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// writeStableArray writes an array whose objects are records
+func writeStableArray(buf *bytes.Buffer, items []interface{}, rank map[string]int) error {
+	buf.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeStableJSON(buf, item, rank); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// writeStableJSON writes a value; objects are ordered by rank when given, nested
+// values use sorted keys
+func writeStableJSON(buf *bytes.Buffer, value interface{}, rank map[string]int) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return writeStableObject(buf, v, rank, func(_ string, child interface{}) error {
+			return writeStableJSON(buf, child, nil)
+		})
+	case []interface{}:
+		return writeStableArray(buf, v, nil)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+		return nil
+	}
+}
+
+// writeStableObject writes the keys of an object ranked first, then sorted
+func writeStableObject(buf *bytes.Buffer, object map[string]interface{}, rank map[string]int, writeValue func(key string, value interface{}) error) error {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ri, iRanked := rank[normalizeFieldKey(keys[i])]
+		rj, jRanked := rank[normalizeFieldKey(keys[j])]
+		switch {
+		case iRanked && jRanked && ri != rj:
+			return ri < rj
+		case iRanked != jRanked:
+			return iRanked
+		}
+		return keys[i] < keys[j]
+	})
+
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		if err := writeValue(key, object[key]); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
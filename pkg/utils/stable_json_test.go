@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalStable(t *testing.T) {
+	type record struct {
+		Zeta  string                 `json:"zeta"`
+		Name  string                 `json:"name"`
+		Extra map[string]interface{} `json:"extra"`
+		ID    int64                  `json:"id"`
+	}
+	order := []string{"id", "name", "zeta"}
+
+	data, err := MarshalStable(record{Zeta: "z", Name: "n", ID: 9007199254740993, Extra: map[string]interface{}{"b": 1, "a": []interface{}{map[string]interface{}{"y": 1, "x": 2}}}}, order)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":9007199254740993,"name":"n","zeta":"z","extra":{"a":[{"x":2,"y":1}],"b":1}}`, string(data))
+
+	// Records inside "data" follow the field order, the envelope is sorted
+	data, err = ReorderJSON([]byte(`{"total":2,"data":[{"zeta":"1","Name":"a","id":1},{"id":2,"zeta":"2"}],"meta":{"pageSize":10,"page":1}}`), []string{"id", "name", "zeta"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"data":[{"id":1,"Name":"a","zeta":"1"},{"id":2,"zeta":"2"}],"meta":{"page":1,"pageSize":10},"total":2}`, string(data))
+
+	// Names match across naming conventions
+	data, err = ReorderJSON([]byte(`{"data":{"updatedAt":"u","created_at":"c"}}`), []string{"created_at", "updated_at"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"data":{"created_at":"c","updatedAt":"u"}}`, string(data))
+
+	_, err = ReorderJSON([]byte(`{`), nil)
+	assert.Error(t, err)
+}
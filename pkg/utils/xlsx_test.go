@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumn(0))
+	assert.Equal(t, "Z", xlsxColumn(25))
+	assert.Equal(t, "AA", xlsxColumn(26))
+	assert.Equal(t, "AZ", xlsxColumn(51))
+	assert.Equal(t, "BA", xlsxColumn(52))
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf, "Orders & Co")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRow([]interface{}{"name", "total", "paid", "note"}))
+	require.NoError(t, w.WriteRow([]interface{}{"<b>", 12.5, true, nil}))
+	require.NoError(t, w.WriteRow([]interface{}{"x", json.Number("7"), false, "y"}))
+	require.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		f, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+
+	require.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="Orders &amp; Co"`)
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;b&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>12.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="b"><v>1</v></c>`)
+	assert.NotContains(t, sheet, `r="D2"`)
+	assert.Contains(t, sheet, `<c r="B3"><v>7</v></c>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+}
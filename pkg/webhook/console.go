@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/auth"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Default and maximum number of deliveries listed by the console
+const (
+	DefaultConsoleLimit = 50
+	MaxConsoleLimit     = 500
+)
+
+// TestDeliveryPrefix prefixes the delivery IDs of test events sent from the console
+const TestDeliveryPrefix = "test-"
+
+// ConsoleConfig contains configuration for the webhook console endpoints
+type ConsoleConfig struct {
+	// Endpoints are the inbound webhooks managed by the console, by InboundConfig.Name
+	Endpoints []InboundConfig
+
+	// Roles allowed to use the console (see auth.UserRoles)
+	Roles []string
+
+	// Authorize grants access to the console for an endpoint, e.g. to the owner of an
+	// integration. Requests are allowed when they have one of the Roles or Authorize
+	// returns true; without either the console is closed.
+	Authorize func(c *gin.Context, endpoint string) bool
+
+	// Default number of deliveries listed (DefaultConsoleLimit if zero)
+	Limit int
+}
+
+// DeliveryView is a logged delivery as listed by the console
+type DeliveryView struct {
+	InboundDelivery
+
+	// LatencyMs is the processing time of the delivery, once processed
+	LatencyMs *int64 `json:"latencyMs,omitempty"`
+}
+
+// NewDeliveryView returns the console view of a delivery
+func NewDeliveryView(delivery InboundDelivery) DeliveryView {
+	view := DeliveryView{InboundDelivery: delivery}
+	if delivery.ProcessedAt != nil {
+		latency := delivery.ProcessedAt.Sub(delivery.ReceivedAt).Milliseconds()
+		view.LatencyMs = &latency
+	}
+	return view
+}
+
+// RegisterWebhookConsole registers endpoints for debugging inbound webhooks without
+// redeploying:
+//
+//	GET  <path>/:endpoint/deliveries             recent deliveries (?status=failed&limit=20)
+//	POST <path>/:endpoint/test                   run the handler with a test event (the body)
+//	POST <path>/:endpoint/deliveries/:id/replay  run the handler again for a failed delivery
+//
+// Test events and replays skip signature verification and are recorded in the log.
+func RegisterWebhookConsole(router *gin.RouterGroup, path string, log *DeliveryLog, cfg ConsoleConfig) {
+	if cfg.Limit <= 0 {
+		cfg.Limit = DefaultConsoleLimit
+	}
+	endpoints := make(map[string]InboundConfig, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		endpoints[endpoint.Name] = endpoint.withDefaults()
+	}
+
+	group := router.Group(path+"/:endpoint", consoleMiddleware(endpoints, cfg))
+	group.GET("/deliveries", generateDeliveriesHandler(log, cfg))
+	group.POST("/test", generateTestEventHandler(log, endpoints))
+	group.POST("/deliveries/:id/replay", generateReplayHandler(log, endpoints))
+}
+
+// consoleMiddleware rejects unknown endpoints and unauthorized requests
+func consoleMiddleware(endpoints map[string]InboundConfig, cfg ConsoleConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		name := c.Param("endpoint")
+		allowed := len(cfg.Roles) > 0 && hasAnyRole(auth.UserRoles(c), cfg.Roles)
+		if !allowed && cfg.Authorize != nil {
+			allowed = cfg.Authorize(c, name)
+		}
+		if !allowed {
+			apierror.AbortMessage(c, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if _, ok := endpoints[name]; !ok {
+			apierror.AbortMessage(c, http.StatusNotFound, "Webhook endpoint not found")
+			return
+		}
+		c.Next()
+	}
+}
+
+// generateDeliveriesHandler lists the most recent deliveries of an endpoint
+func generateDeliveriesHandler(log *DeliveryLog, cfg ConsoleConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := cfg.Limit
+		if value := c.Query("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				apierror.RespondMessage(c, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			limit = parsed
+		}
+		if limit > MaxConsoleLimit {
+			limit = MaxConsoleLimit
+		}
+
+		db := log.DB.WithContext(c.Request.Context()).Model(&InboundDelivery{}).Where("endpoint = ?", c.Param("endpoint"))
+		if status := c.Query("status"); status != "" {
+			db = db.Where("status IN ?", strings.Split(status, ","))
+		}
+
+		var total int64
+		if err := db.Count(&total).Error; err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		var deliveries []InboundDelivery
+		if err := db.Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		views := make([]DeliveryView, len(deliveries))
+		for i, delivery := range deliveries {
+			views[i] = NewDeliveryView(delivery)
+		}
+		c.JSON(http.StatusOK, gin.H{"data": views, "total": total})
+	}
+}
+
+// generateTestEventHandler runs the endpoint handler with the request body as a new
+// delivery
+func generateTestEventHandler(log *DeliveryLog, endpoints map[string]InboundConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := endpoints[c.Param("endpoint")]
+
+		body, err := c.GetRawData()
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if len(body) == 0 {
+			body = []byte(`{}`)
+		}
+
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+		delivery := &InboundDelivery{Endpoint: cfg.Name, DeliveryID: TestDeliveryPrefix + hex.EncodeToString(id)}
+
+		respondWithOutcome(c, log, cfg, delivery, body)
+	}
+}
+
+// generateReplayHandler runs the endpoint handler again with the payload of a failed
+// or rejected delivery
+func generateReplayHandler(log *DeliveryLog, endpoints map[string]InboundConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := endpoints[c.Param("endpoint")]
+
+		var delivery InboundDelivery
+		err := log.DB.WithContext(c.Request.Context()).
+			Where("id = ? AND endpoint = ?", c.Param("id"), cfg.Name).
+			First(&delivery).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.RespondMessage(c, http.StatusNotFound, "Delivery not found")
+			return
+		}
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if delivery.Status != DeliveryStatusFailed && delivery.Status != DeliveryStatusRejected {
+			apierror.RespondMessage(c, http.StatusConflict, "only failed or rejected deliveries can be replayed")
+			return
+		}
+
+		respondWithOutcome(c, log, cfg, &delivery, []byte(delivery.Payload))
+	}
+}
+
+// respondWithOutcome processes a delivery and responds with the logged delivery and the
+// response the sender would have received
+func respondWithOutcome(c *gin.Context, log *DeliveryLog, cfg InboundConfig, delivery *InboundDelivery, body []byte) {
+	deliveryID := delivery.DeliveryID
+	status, response := processDelivery(c, log, cfg, delivery, body)
+
+	// Pooled deliveries are still updated in the background, so the log is read again
+	logged, err := log.Find(c.Request.Context(), cfg.Name, deliveryID)
+	if err != nil || logged == nil {
+		c.JSON(status, response)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"delivery": NewDeliveryView(*logged),
+		"response": gin.H{"status": status, "body": response},
+	}})
+}
+
+// hasAnyRole reports whether any of the roles is allowed
+func hasAnyRole(roles, allowedRoles []string) bool {
+	for _, role := range roles {
+		for _, allowedRole := range allowedRoles {
+			if role == allowedRole {
+				return true
+			}
+		}
+	}
+	return false
+}
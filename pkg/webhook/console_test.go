@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/requestctx"
+)
+
+func TestWebhookConsole(t *testing.T) {
+	fail := true
+	cfg := InboundConfig{
+		Name:    "orders",
+		Secret:  testSecret,
+		Payload: &orderPayload{},
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			if fail {
+				return nil, errors.New("receiver down")
+			}
+			return gin.H{"orderId": data.(*orderPayload).OrderID}, nil
+		},
+	}
+	r, log := setupInboundTest(t, cfg)
+	console := r.Group("/admin", func(c *gin.Context) {
+		if role := c.GetHeader("X-Role"); role != "" {
+			requestctx.Roles.Set(c, []string{role})
+		}
+	})
+	RegisterWebhookConsole(console, "/webhooks", log, ConsoleConfig{
+		Endpoints: []InboundConfig{cfg},
+		Roles:     []string{"admin"},
+		Authorize: func(c *gin.Context, endpoint string) bool {
+			return c.GetHeader("X-Integration") == endpoint
+		},
+	})
+
+	request := func(method, path, role, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		r.ServeHTTP(w, req)
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	body := `{"orderId":"A-1","amount":10}`
+	require.Equal(t, http.StatusInternalServerError, sendWebhook(r, body, signedHeaders(body, "d-1")).Code)
+
+	// Access requires a role or the Authorize hook
+	w, _ := request(http.MethodGet, "/admin/webhooks/orders/deliveries", "", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = request(http.MethodGet, "/admin/webhooks/orders/deliveries", "viewer", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, _ = request(http.MethodGet, "/admin/webhooks/missing/deliveries", "admin", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/orders/deliveries", nil)
+	req.Header.Set("X-Integration", "orders")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, response := request(http.MethodGet, "/admin/webhooks/orders/deliveries?status=failed", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), response["total"])
+	failed := response["data"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "d-1", failed["deliveryId"])
+	assert.Equal(t, "receiver down", failed["error"])
+	assert.Contains(t, failed, "latencyMs")
+
+	// Test events run the handler without a signature
+	fail = false
+	w, response = request(http.MethodPost, "/admin/webhooks/orders/test", "admin", `{"orderId":"T-1"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, DeliveryStatusProcessed, data["delivery"].(map[string]interface{})["status"])
+	assert.Regexp(t, `^test-[0-9a-f]{16}$`, data["delivery"].(map[string]interface{})["deliveryId"])
+	assert.Equal(t, map[string]interface{}{"status": float64(200), "body": map[string]interface{}{"data": map[string]interface{}{"orderId": "T-1"}}}, data["response"])
+
+	w, response = request(http.MethodPost, "/admin/webhooks/orders/test", "admin", `{"amount":1}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	data = response["data"].(map[string]interface{})
+	assert.Equal(t, DeliveryStatusRejected, data["delivery"].(map[string]interface{})["status"])
+	assert.Equal(t, float64(http.StatusUnprocessableEntity), data["response"].(map[string]interface{})["status"])
+
+	// Failed deliveries can be replayed once the receiver is fixed
+	id := strconv.Itoa(int(failed["id"].(float64)))
+	w, response = request(http.MethodPost, "/admin/webhooks/orders/deliveries/"+id+"/replay", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	replayed := response["data"].(map[string]interface{})["delivery"].(map[string]interface{})
+	assert.Equal(t, "d-1", replayed["deliveryId"])
+	assert.Equal(t, DeliveryStatusProcessed, replayed["status"])
+	assert.NotContains(t, replayed, "error")
+
+	w, _ = request(http.MethodPost, "/admin/webhooks/orders/deliveries/"+id+"/replay", "admin", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = request(http.MethodPost, "/admin/webhooks/orders/deliveries/999/replay", "admin", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, response = request(http.MethodGet, "/admin/webhooks/orders/deliveries?limit=2", "admin", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(3), response["total"])
+	assert.Len(t, response["data"], 2)
+
+	// The sender's retry is now acknowledged as a duplicate
+	w = sendWebhook(r, body, signedHeaders(body, "d-1"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"duplicate":true`)
+}
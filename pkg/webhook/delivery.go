@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/suranig/refine-gin/pkg/resource"
+	"gorm.io/gorm"
+)
+
+// Delivery statuses
+const (
+	DeliveryStatusReceived  = "received"
+	DeliveryStatusProcessed = "processed"
+	DeliveryStatusFailed    = "failed"
+	DeliveryStatusRejected  = "rejected"
+)
+
+// InboundDelivery records a single inbound webhook call
+type InboundDelivery struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Endpoint    string     `json:"endpoint" gorm:"size:191;index:idx_inbound_deliveries_endpoint_delivery,unique"`
+	DeliveryID  string     `json:"deliveryId" gorm:"size:191;index:idx_inbound_deliveries_endpoint_delivery,unique"`
+	Status      string     `json:"status" gorm:"size:32;index"`
+	Error       string     `json:"error,omitempty"`
+	Payload     string     `json:"payload,omitempty" gorm:"type:text"`
+	RequestID   string     `json:"requestId,omitempty" gorm:"size:128"`
+	ReceivedAt  time.Time  `json:"receivedAt"`
+	ProcessedAt *time.Time `json:"processedAt,omitempty"`
+}
+
+// TableName returns the table used to store inbound deliveries
+func (InboundDelivery) TableName() string {
+	return "refine_inbound_deliveries"
+}
+
+// DeliveryLog stores inbound deliveries and provides replay protection
+type DeliveryLog struct {
+	DB *gorm.DB
+}
+
+// NewDeliveryLog creates a new delivery log
+func NewDeliveryLog(db *gorm.DB) *DeliveryLog {
+	return &DeliveryLog{DB: db}
+}
+
+// AutoMigrate creates or updates the delivery log table
+func (l *DeliveryLog) AutoMigrate() error {
+	return l.DB.AutoMigrate(&InboundDelivery{})
+}
+
+// Find returns a logged delivery by endpoint and delivery ID
+func (l *DeliveryLog) Find(ctx context.Context, endpoint, deliveryID string) (*InboundDelivery, error) {
+	var delivery InboundDelivery
+	err := l.DB.WithContext(ctx).
+		Where("endpoint = ? AND delivery_id = ?", endpoint, deliveryID).
+		First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Save creates or updates a delivery entry
+func (l *DeliveryLog) Save(ctx context.Context, delivery *InboundDelivery) error {
+	return l.DB.WithContext(ctx).Save(delivery).Error
+}
+
+// DeliveryResource returns a read-only resource exposing the delivery log,
+// to be registered with handler.RegisterResource and a GenericRepository
+func DeliveryResource() resource.Resource {
+	return resource.NewResource(resource.ResourceConfig{
+		Name:  "inbound-deliveries",
+		Label: "Inbound Deliveries",
+		Model: InboundDelivery{},
+		Operations: []resource.Operation{
+			resource.OperationList,
+			resource.OperationRead,
+			resource.OperationCount,
+		},
+		DefaultSort: &resource.Sort{
+			Field: "id",
+			Order: "desc",
+		},
+	})
+}
@@ -0,0 +1,397 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/suranig/refine-gin/pkg/apierror"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/repository"
+	"github.com/suranig/refine-gin/pkg/utils"
+	"github.com/suranig/refine-gin/pkg/worker"
+)
+
+var validate = validator.New()
+
+// Error constants for inbound webhooks
+var (
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrMissingTimestamp = errors.New("missing webhook timestamp")
+	ErrExpiredTimestamp = errors.New("webhook timestamp outside of tolerance")
+	ErrMissingDelivery  = errors.New("missing webhook delivery ID")
+	ErrInvalidDelivery  = errors.New("webhook delivery ID must not contain \".\"")
+)
+
+// Default header names, tolerance and body size for inbound webhooks
+const (
+	DefaultSignatureHeader  = "X-Webhook-Signature"
+	DefaultTimestampHeader  = "X-Webhook-Timestamp"
+	DefaultDeliveryIDHeader = "X-Webhook-Delivery"
+	DefaultTolerance        = 5 * time.Minute
+	DefaultMaxBodySize      = 1 << 20
+)
+
+// PayloadMapper converts a validated payload into the value passed to the handler
+type PayloadMapper func(payload interface{}) (interface{}, error)
+
+// InboundHandler processes a verified webhook payload
+type InboundHandler func(c *gin.Context, data interface{}) (interface{}, error)
+
+// InboundConfig contains configuration for an inbound webhook endpoint
+type InboundConfig struct {
+	// Name identifies the endpoint in the delivery log (e.g. "stripe")
+	Name string
+
+	// Shared secret used to compute the HMAC-SHA256 signature
+	Secret string
+
+	// Header carrying the hex encoded signature (optionally prefixed with "sha256=")
+	SignatureHeader string
+
+	// Header carrying the unix timestamp. When set, the signed content is "<timestamp>.<body>"
+	// and requests outside of Tolerance are rejected. Use "-" to sign the body only.
+	TimestampHeader string
+
+	// Header carrying a unique delivery ID used for replay protection. The ID is signed
+	// with the body ("<timestamp>.<id>.<body>") and must not contain ".". Use "-" to
+	// send no IDs; the signature itself then identifies the delivery.
+	DeliveryIDHeader string
+
+	// Maximum allowed clock difference for timestamped requests
+	Tolerance time.Duration
+
+	// Maximum size of the body in bytes. The body is read before its signature is
+	// checked, so larger requests are rejected with 413 without being read further.
+	MaxBodySize int64
+
+	// Payload is a pointer to a struct the body is decoded into and validated with
+	// `validate` tags. When nil the body is decoded into map[string]interface{}.
+	Payload interface{}
+
+	// Mapper converts the payload before it is passed to Handler (optional)
+	Mapper PayloadMapper
+
+	// Handler processes the payload
+	Handler InboundHandler
+
+	// Pool processes deliveries in the background when set. The endpoint then responds
+	// 202 Accepted once the delivery is queued, and 503 when the queue is full.
+	Pool *worker.Pool
+}
+
+// withDefaults fills in default header names, tolerance and body size
+func (cfg InboundConfig) withDefaults() InboundConfig {
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = DefaultSignatureHeader
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = DefaultTimestampHeader
+	}
+	if cfg.DeliveryIDHeader == "" {
+		cfg.DeliveryIDHeader = DefaultDeliveryIDHeader
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = DefaultTolerance
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	return cfg
+}
+
+// Sign computes the hex encoded HMAC-SHA256 signature of a payload
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignWithTimestamp computes the signature for a timestamped request of an endpoint
+// without delivery IDs
+func SignWithTimestamp(secret string, timestamp int64, payload []byte) string {
+	return Sign(secret, signedContent(strconv.FormatInt(timestamp, 10), "", payload))
+}
+
+// SignedHeaders returns the headers of a delivery signed for the endpoint configuration,
+// as a sender would set them
+func SignedHeaders(cfg InboundConfig, deliveryID string, body []byte, now time.Time) http.Header {
+	cfg = cfg.withDefaults()
+
+	header := http.Header{}
+	var timestamp string
+	if cfg.TimestampHeader != "-" {
+		timestamp = strconv.FormatInt(now.Unix(), 10)
+		header.Set(cfg.TimestampHeader, timestamp)
+	}
+	if cfg.DeliveryIDHeader != "-" {
+		header.Set(cfg.DeliveryIDHeader, deliveryID)
+	} else {
+		deliveryID = ""
+	}
+	header.Set(cfg.SignatureHeader, Sign(cfg.Secret, signedContent(timestamp, deliveryID, body)))
+	return header
+}
+
+// signedContent builds the signed content of a request: the timestamp and the delivery
+// ID, when the endpoint uses them, and the body, separated by "."
+func signedContent(timestamp, deliveryID string, body []byte) []byte {
+	var content []byte
+	for _, part := range []string{timestamp, deliveryID} {
+		if part != "" {
+			content = append(content, part+"."...)
+		}
+	}
+	return append(content, body...)
+}
+
+// VerifySignature checks the signature (and timestamp, if enabled) of an inbound request
+func VerifySignature(cfg InboundConfig, header http.Header, body []byte, now time.Time) error {
+	cfg = cfg.withDefaults()
+
+	signature := strings.TrimPrefix(header.Get(cfg.SignatureHeader), "sha256=")
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	var timestamp, deliveryID string
+	if cfg.TimestampHeader != "-" {
+		timestamp = header.Get(cfg.TimestampHeader)
+		if timestamp == "" {
+			return ErrMissingTimestamp
+		}
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrMissingTimestamp
+		}
+		diff := now.Sub(time.Unix(unix, 0))
+		if diff > cfg.Tolerance || diff < -cfg.Tolerance {
+			return ErrExpiredTimestamp
+		}
+	}
+	// IDs with "." could move the bounds of the signed parts
+	if cfg.DeliveryIDHeader != "-" {
+		deliveryID = header.Get(cfg.DeliveryIDHeader)
+		if deliveryID == "" {
+			return ErrMissingDelivery
+		}
+		if strings.Contains(deliveryID, ".") {
+			return ErrInvalidDelivery
+		}
+	}
+
+	expected := Sign(cfg.Secret, signedContent(timestamp, deliveryID, body))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// RegisterInboundWebhook registers a POST endpoint receiving signed webhooks
+func RegisterInboundWebhook(router *gin.RouterGroup, path string, log *DeliveryLog, cfg InboundConfig) {
+	router.POST(path, GenerateInboundWebhookHandler(log, cfg))
+}
+
+// GenerateInboundWebhookHandler creates a handler that verifies, deduplicates, validates
+// and processes inbound webhooks, recording every accepted delivery in the log. It
+// panics when the configuration has no secret.
+func GenerateInboundWebhookHandler(log *DeliveryLog, cfg InboundConfig) gin.HandlerFunc {
+	cfg = cfg.withDefaults()
+	if cfg.Secret == "" {
+		panic(fmt.Sprintf("inbound webhook %q has no secret", cfg.Name))
+	}
+
+	return func(c *gin.Context) {
+		utils.DisableCaching(c.Writer)
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodySize)
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.RespondMessage(c, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("Webhook body exceeds the limit of %d bytes", cfg.MaxBodySize))
+				return
+			}
+			apierror.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := VerifySignature(cfg, c.Request.Header, body, time.Now()); err != nil {
+			apierror.Respond(c, http.StatusUnauthorized, err)
+			return
+		}
+
+		// Identify the delivery for replay protection by what the signature covers
+		var deliveryID string
+		if cfg.DeliveryIDHeader != "-" {
+			deliveryID = c.GetHeader(cfg.DeliveryIDHeader)
+		} else {
+			deliveryID = strings.TrimPrefix(c.GetHeader(cfg.SignatureHeader), "sha256=")
+		}
+
+		ctx := c.Request.Context()
+		delivery, err := log.Find(ctx, cfg.Name, deliveryID)
+		if err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		if delivery != nil {
+			switch delivery.Status {
+			case DeliveryStatusProcessed:
+				c.JSON(http.StatusOK, gin.H{"data": gin.H{"duplicate": true, "deliveryId": deliveryID}})
+				return
+			case DeliveryStatusReceived:
+				apierror.RespondMessage(c, http.StatusConflict, "delivery is already being processed")
+				return
+			}
+			// Failed or rejected deliveries may be retried
+		} else {
+			delivery = &InboundDelivery{Endpoint: cfg.Name, DeliveryID: deliveryID}
+		}
+
+		status, response := processDelivery(c, log, cfg, delivery, body)
+		c.JSON(status, response)
+	}
+}
+
+// processDelivery runs the handler of a logged delivery, recording the outcome in the log, and
+// returns the response status and body
+func processDelivery(c *gin.Context, log *DeliveryLog, cfg InboundConfig, delivery *InboundDelivery, body []byte) (int, interface{}) {
+	ctx := c.Request.Context()
+
+	delivery.Status = DeliveryStatusReceived
+	delivery.Error = ""
+	delivery.ProcessedAt = nil
+	delivery.Payload = string(body)
+	delivery.RequestID = middleware.GetRequestID(c)
+	delivery.ReceivedAt = time.Now()
+	if err := log.Save(ctx, delivery); err != nil {
+		// A concurrent request has logged the same delivery
+		return failedDelivery(c, http.StatusConflict, errors.New("delivery is already being processed"))
+	}
+
+	finishWith := func(ctx context.Context, status string, err error) {
+		now := time.Now()
+		delivery.Status = status
+		delivery.ProcessedAt = &now
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		_ = log.Save(ctx, delivery)
+	}
+	finish := func(status string, err error) {
+		finishWith(ctx, status, err)
+	}
+
+	payload, err := decodePayload(cfg.Payload, body)
+	if err != nil {
+		finish(DeliveryStatusRejected, err)
+		return failedDelivery(c, http.StatusUnprocessableEntity, err)
+	}
+
+	data := payload
+	if cfg.Mapper != nil {
+		data, err = cfg.Mapper(payload)
+		if err != nil {
+			finish(DeliveryStatusRejected, err)
+			return failedDelivery(c, http.StatusUnprocessableEntity, err)
+		}
+	}
+
+	if cfg.Pool != nil {
+		background := c.Copy()
+		err := cfg.Pool.Submit(cfg.Name, func(taskCtx context.Context) error {
+			var err error
+			if cfg.Handler != nil {
+				background.Request = background.Request.WithContext(taskCtx)
+				_, err = cfg.Handler(background, data)
+			}
+			// Record the outcome even when the task was canceled
+			if err != nil {
+				finishWith(context.WithoutCancel(taskCtx), DeliveryStatusFailed, err)
+			} else {
+				finishWith(context.WithoutCancel(taskCtx), DeliveryStatusProcessed, nil)
+			}
+			return err
+		})
+		if err != nil {
+			finish(DeliveryStatusFailed, err)
+			return failedDelivery(c, http.StatusServiceUnavailable, err)
+		}
+		return http.StatusAccepted, gin.H{"data": gin.H{"deliveryId": delivery.DeliveryID, "status": DeliveryStatusReceived}}
+	}
+
+	var result interface{}
+	if cfg.Handler != nil {
+		result, err = cfg.Handler(c, data)
+		if err != nil {
+			finish(DeliveryStatusFailed, err)
+			return failedDelivery(c, http.StatusInternalServerError, err)
+		}
+	}
+
+	finish(DeliveryStatusProcessed, nil)
+	return http.StatusOK, gin.H{"data": result}
+}
+
+// failedDelivery returns the status and error response of a delivery that was not
+// processed
+func failedDelivery(c *gin.Context, status int, err error) (int, interface{}) {
+	return status, apierror.Prepare(c, apierror.New(status, err.Error()), err)
+}
+
+// decodePayload decodes the body into a new instance of the payload type and validates it
+func decodePayload(payloadType interface{}, body []byte) (interface{}, error) {
+	if payloadType == nil {
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	t := reflect.TypeOf(payloadType)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	payload := reflect.New(t).Interface()
+
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+	if t.Kind() == reflect.Struct {
+		if err := validate.Struct(payload); err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// CreateInRepository returns a handler creating the mapped payload in a repository
+func CreateInRepository(repo repository.Repository) InboundHandler {
+	return func(c *gin.Context, data interface{}) (interface{}, error) {
+		return repo.Create(c.Request.Context(), data)
+	}
+}
+
+// UpdateInRepository returns a handler updating the record identified by idFunc with the mapped payload
+func UpdateInRepository(repo repository.Repository, idFunc func(data interface{}) interface{}) InboundHandler {
+	return func(c *gin.Context, data interface{}) (interface{}, error) {
+		return repo.Update(c.Request.Context(), idFunc(data), data)
+	}
+}
@@ -0,0 +1,317 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/suranig/refine-gin/pkg/middleware"
+	"github.com/suranig/refine-gin/pkg/worker"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testSecret = "top-secret"
+
+type orderPayload struct {
+	OrderID string  `json:"orderId" validate:"required"`
+	Amount  float64 `json:"amount" validate:"gte=0"`
+}
+
+func setupInboundTest(t *testing.T, cfg InboundConfig) (*gin.Engine, *DeliveryLog) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	log := NewDeliveryLog(db)
+	require.NoError(t, log.AutoMigrate())
+
+	r := gin.New()
+	r.Use(middleware.RequestID(middleware.RequestIDConfig{}))
+	RegisterInboundWebhook(r.Group("/hooks"), "/orders", log, cfg)
+	return r, log
+}
+
+func sendWebhook(r *gin.Engine, body string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/hooks/orders", bytes.NewBufferString(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func signedHeaders(body, deliveryID string) map[string]string {
+	header := SignedHeaders(InboundConfig{Secret: testSecret}, deliveryID, []byte(body), time.Now())
+	headers := map[string]string{}
+	for key := range header {
+		headers[key] = header.Get(key)
+	}
+	return headers
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"orderId":"1"}`)
+	now := time.Now()
+	cfg := InboundConfig{Secret: testSecret}
+
+	t.Run("valid", func(t *testing.T) {
+		header := SignedHeaders(cfg, "d-1", body, now)
+		assert.Equal(t, Sign(testSecret, []byte(strconv.FormatInt(now.Unix(), 10)+".d-1."+string(body))), header.Get(DefaultSignatureHeader))
+		assert.NoError(t, VerifySignature(cfg, header, body, now))
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		assert.ErrorIs(t, VerifySignature(cfg, http.Header{}, body, now), ErrMissingSignature)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		header := SignedHeaders(InboundConfig{Secret: "other"}, "d-1", body, now)
+		assert.ErrorIs(t, VerifySignature(cfg, header, body, now), ErrInvalidSignature)
+	})
+
+	t.Run("expired timestamp", func(t *testing.T) {
+		header := SignedHeaders(cfg, "d-1", body, now.Add(-time.Hour))
+		assert.ErrorIs(t, VerifySignature(cfg, header, body, now), ErrExpiredTimestamp)
+	})
+
+	t.Run("delivery ID", func(t *testing.T) {
+		// The delivery ID is signed, so replays cannot pass for new deliveries
+		header := SignedHeaders(cfg, "d-1", body, now)
+		header.Set(DefaultDeliveryIDHeader, "d-2")
+		assert.ErrorIs(t, VerifySignature(cfg, header, body, now), ErrInvalidSignature)
+
+		header.Del(DefaultDeliveryIDHeader)
+		assert.ErrorIs(t, VerifySignature(cfg, header, body, now), ErrMissingDelivery)
+
+		header = SignedHeaders(cfg, "d.1", body, now)
+		assert.ErrorIs(t, VerifySignature(cfg, header, body, now), ErrInvalidDelivery)
+	})
+
+	t.Run("body only", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(DefaultSignatureHeader, Sign(testSecret, body))
+		assert.NoError(t, VerifySignature(InboundConfig{Secret: testSecret, TimestampHeader: "-", DeliveryIDHeader: "-"}, header, body, now))
+	})
+
+	t.Run("timestamp without delivery ID", func(t *testing.T) {
+		header := http.Header{}
+		header.Set(DefaultSignatureHeader, SignWithTimestamp(testSecret, now.Unix(), body))
+		header.Set(DefaultTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		assert.NoError(t, VerifySignature(InboundConfig{Secret: testSecret, DeliveryIDHeader: "-"}, header, body, now))
+	})
+}
+
+func TestInboundWebhook(t *testing.T) {
+	calls := 0
+	r, log := setupInboundTest(t, InboundConfig{
+		Name:    "orders",
+		Secret:  testSecret,
+		Payload: &orderPayload{},
+		Mapper: func(payload interface{}) (interface{}, error) {
+			return map[string]interface{}{"ref": payload.(*orderPayload).OrderID}, nil
+		},
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			calls++
+			return data, nil
+		},
+	})
+
+	body := `{"orderId":"A-1","amount":10}`
+
+	t.Run("rejects invalid signature", func(t *testing.T) {
+		headers := signedHeaders(body, "d-0")
+		headers[DefaultSignatureHeader] = "sha256=deadbeef"
+		w := sendWebhook(r, body, headers)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("processes delivery", func(t *testing.T) {
+		headers := signedHeaders(body, "d-1")
+		headers[middleware.RequestIDHeader] = "req-d-1"
+		w := sendWebhook(r, body, headers)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"ref":"A-1"`)
+		assert.Equal(t, 1, calls)
+
+		delivery, err := log.Find(context.Background(), "orders", "d-1")
+		require.NoError(t, err)
+		require.NotNil(t, delivery)
+		assert.Equal(t, DeliveryStatusProcessed, delivery.Status)
+		assert.NotNil(t, delivery.ProcessedAt)
+		assert.Equal(t, "req-d-1", delivery.RequestID)
+	})
+
+	t.Run("ignores replayed delivery", func(t *testing.T) {
+		w := sendWebhook(r, body, signedHeaders(body, "d-1"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"duplicate":true`)
+		assert.Equal(t, 1, calls)
+
+		headers := signedHeaders(body, "d-1")
+		headers[DefaultDeliveryIDHeader] = "d-1-replay"
+		assert.Equal(t, http.StatusUnauthorized, sendWebhook(r, body, headers).Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("rejects invalid payload", func(t *testing.T) {
+		invalid := `{"amount":-1}`
+		w := sendWebhook(r, invalid, signedHeaders(invalid, "d-2"))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		delivery, err := log.Find(context.Background(), "orders", "d-2")
+		require.NoError(t, err)
+		require.NotNil(t, delivery)
+		assert.Equal(t, DeliveryStatusRejected, delivery.Status)
+		assert.NotEmpty(t, delivery.Error)
+	})
+}
+
+func TestInboundWebhookBodyLimit(t *testing.T) {
+	calls := 0
+	r, log := setupInboundTest(t, InboundConfig{
+		Name:        "orders",
+		Secret:      testSecret,
+		MaxBodySize: 32,
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			calls++
+			return data, nil
+		},
+	})
+
+	body := `{"orderId":"A-3","note":"` + strings.Repeat("x", 64) + `"}`
+	w := sendWebhook(r, body, signedHeaders(body, "d-4"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"payload_too_large"`)
+	assert.Equal(t, 0, calls)
+
+	delivery, err := log.Find(context.Background(), "orders", "d-4")
+	require.NoError(t, err)
+	assert.Nil(t, delivery)
+
+	body = `{"orderId":"A-3"}`
+	w = sendWebhook(r, body, signedHeaders(body, "d-5"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestInboundWebhookWithoutDeliveryIDs(t *testing.T) {
+	calls := 0
+	r, _ := setupInboundTest(t, InboundConfig{
+		Name:             "orders",
+		Secret:           testSecret,
+		TimestampHeader:  "-",
+		DeliveryIDHeader: "-",
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			calls++
+			return data, nil
+		},
+	})
+
+	// The signature identifies the delivery, whatever other headers say
+	body := `{"orderId":"A-4"}`
+	headers := map[string]string{DefaultSignatureHeader: Sign(testSecret, []byte(body))}
+	assert.Equal(t, http.StatusOK, sendWebhook(r, body, headers).Code)
+	headers[DefaultDeliveryIDHeader] = "other"
+	w := sendWebhook(r, body, headers)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"duplicate":true`)
+	assert.Equal(t, 1, calls)
+}
+
+func TestInboundWebhookNeedsSecret(t *testing.T) {
+	assert.Panics(t, func() {
+		GenerateInboundWebhookHandler(nil, InboundConfig{Name: "orders"})
+	})
+}
+
+func TestInboundWebhookRetriesFailedDelivery(t *testing.T) {
+	fail := true
+	r, log := setupInboundTest(t, InboundConfig{
+		Name:   "orders",
+		Secret: testSecret,
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			if fail {
+				return nil, errors.New("downstream unavailable")
+			}
+			return data, nil
+		},
+	})
+
+	body := `{"orderId":"A-2"}`
+	w := sendWebhook(r, body, signedHeaders(body, "d-3"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	delivery, err := log.Find(context.Background(), "orders", "d-3")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryStatusFailed, delivery.Status)
+
+	fail = false
+	w = sendWebhook(r, body, signedHeaders(body, "d-3"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	delivery, err = log.Find(context.Background(), "orders", "d-3")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryStatusProcessed, delivery.Status)
+	assert.Empty(t, delivery.Error)
+}
+
+func TestInboundWebhookWithPool(t *testing.T) {
+	pool := worker.NewPool(worker.Config{Name: "webhooks", Workers: 1, QueueSize: 1})
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var received []interface{}
+	r, log := setupInboundTest(t, InboundConfig{
+		Name:    "orders",
+		Secret:  testSecret,
+		Payload: &orderPayload{},
+		Pool:    pool,
+		Handler: func(c *gin.Context, data interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			received = append(received, data)
+			if data.(*orderPayload).OrderID == "A-2" {
+				return nil, errors.New("downstream unavailable")
+			}
+			return data, nil
+		},
+	})
+
+	// The first delivery occupies the worker and the second one the queue
+	for i, id := range []string{"A-1", "A-2"} {
+		body := `{"orderId":"` + id + `"}`
+		w := sendWebhook(r, body, signedHeaders(body, "p-"+strconv.Itoa(i)))
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"received"`)
+		if i == 0 {
+			<-started
+		}
+	}
+
+	body := `{"orderId":"A-3"}`
+	w := sendWebhook(r, body, signedHeaders(body, "p-2"))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), worker.ErrQueueFull.Error())
+
+	close(release)
+	require.NoError(t, pool.Drain(context.Background()))
+	assert.Len(t, received, 2)
+
+	for id, status := range map[string]string{"p-0": DeliveryStatusProcessed, "p-1": DeliveryStatusFailed, "p-2": DeliveryStatusFailed} {
+		delivery, err := log.Find(context.Background(), "orders", id)
+		require.NoError(t, err)
+		assert.Equal(t, status, delivery.Status, id)
+	}
+	assert.Equal(t, int64(1), pool.Stats().Dropped)
+}
@@ -0,0 +1,277 @@
+// Package worker provides a bounded worker pool for dispatching events (hooks, webhooks,
+// notifications) off the request path without spawning a goroutine per event.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Error constants for the worker pool
+var (
+	ErrQueueFull  = errors.New("worker queue is full")
+	ErrPoolClosed = errors.New("worker pool is closed")
+)
+
+// Default pool settings
+const (
+	DefaultQueueSize = 100
+	DefaultBackoff   = 500 * time.Millisecond
+)
+
+// Task is a unit of work. The context is canceled when the task times out or when
+// draining the pool times out.
+type Task func(ctx context.Context) error
+
+// RetryPolicy controls how failed tasks are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one (default 1)
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry (default 500ms). It doubles with
+	// every further retry.
+	Backoff time.Duration
+
+	// MaxBackoff caps the retry delay (optional)
+	MaxBackoff time.Duration
+}
+
+// Delay returns the delay before the given retry (1 for the first retry)
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	if delay <= 0 {
+		delay = DefaultBackoff
+	}
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// Config contains configuration for a worker pool
+type Config struct {
+	// Name identifies the pool in errors (e.g. "webhooks")
+	Name string
+
+	// Workers is the number of tasks run concurrently (default runtime.NumCPU())
+	Workers int
+
+	// QueueSize is the number of tasks waiting for a worker before Submit drops
+	// new ones (default 100)
+	QueueSize int
+
+	// Timeout limits a single attempt of a task (optional)
+	Timeout time.Duration
+
+	// Retry controls retries of failed attempts
+	Retry RetryPolicy
+
+	// OnError is called when a task fails its last attempt (optional)
+	OnError func(name string, err error)
+
+	// OnDrop is called when a task is dropped because the queue is full (optional)
+	OnDrop func(name string)
+}
+
+// Stats are counters of a worker pool
+type Stats struct {
+	Submitted int64 `json:"submitted"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	Retried   int64 `json:"retried"`
+	TimedOut  int64 `json:"timedOut"`
+	Dropped   int64 `json:"dropped"`
+	Queued    int   `json:"queued"`
+	Running   int64 `json:"running"`
+}
+
+// job is a queued task
+type job struct {
+	name string
+	task Task
+}
+
+// Pool runs tasks on a fixed number of workers reading from a bounded queue. It
+// implements refinegin.Drainer, so it can be drained on shutdown.
+type Pool struct {
+	config Config
+	queue  chan job
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+
+	submitted, completed, failed, retried, timedOut, dropped, running atomic.Int64
+}
+
+// NewPool creates a pool and starts its workers
+func NewPool(config Config) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		config: config,
+		queue:  make(chan job, config.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	p.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues a task without blocking. It returns ErrQueueFull when the queue is
+// full and ErrPoolClosed once draining has started.
+func (p *Pool) Submit(name string, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.queue <- job{name: name, task: task}:
+		p.submitted.Add(1)
+		return nil
+	default:
+		p.dropped.Add(1)
+		if p.config.OnDrop != nil {
+			p.config.OnDrop(name)
+		}
+		return ErrQueueFull
+	}
+}
+
+// Stats returns the current counters
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Submitted: p.submitted.Load(),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+		Retried:   p.retried.Load(),
+		TimedOut:  p.timedOut.Load(),
+		Dropped:   p.dropped.Load(),
+		Queued:    len(p.queue),
+		Running:   p.running.Load(),
+	}
+}
+
+// Drain stops accepting tasks and waits until queued and running tasks are done or
+// ctx is done. Remaining tasks are then canceled.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		// Ask remaining tasks to stop
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// work runs queued tasks until the queue is closed
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for j := range p.queue {
+		p.running.Add(1)
+		err := p.run(j)
+		p.running.Add(-1)
+
+		if err != nil {
+			p.failed.Add(1)
+			if p.config.OnError != nil {
+				p.config.OnError(j.name, err)
+			}
+			continue
+		}
+		p.completed.Add(1)
+	}
+}
+
+// run runs a task with retries, returning the error of the last attempt
+func (p *Pool) run(j job) error {
+	var err error
+	for attempt := 1; attempt <= p.config.Retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			p.retried.Add(1)
+			select {
+			case <-time.After(p.config.Retry.Delay(attempt - 1)):
+			case <-p.ctx.Done():
+				return err
+			}
+		}
+
+		if err = p.attempt(j); err == nil {
+			return nil
+		}
+		if p.ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// attempt runs a task once, converting panics into errors
+func (p *Pool) attempt(j job) (err error) {
+	ctx := p.ctx
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: task %q panicked: %v", p.name(), j.name, r)
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			p.timedOut.Add(1)
+		}
+	}()
+	return j.task(ctx)
+}
+
+// name returns the pool name used in errors
+func (p *Pool) name() string {
+	if p.config.Name == "" {
+		return "worker"
+	}
+	return p.config.Name
+}
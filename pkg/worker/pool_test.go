@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolRunsTasks(t *testing.T) {
+	pool := NewPool(Config{Workers: 2})
+
+	var count atomic.Int64
+	for i := 0; i < 10; i++ {
+		require.NoError(t, pool.Submit("count", func(ctx context.Context) error {
+			count.Add(1)
+			return nil
+		}))
+	}
+
+	require.NoError(t, pool.Drain(context.Background()))
+	assert.Equal(t, int64(10), count.Load())
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(10), stats.Submitted)
+	assert.Equal(t, int64(10), stats.Completed)
+	assert.Equal(t, int64(0), stats.Failed)
+}
+
+func TestPoolDropsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var dropped []string
+	pool := NewPool(Config{Workers: 1, QueueSize: 1, OnDrop: func(name string) {
+		dropped = append(dropped, name)
+	}})
+
+	block := func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}
+	require.NoError(t, pool.Submit("running", block))
+	<-started
+	require.NoError(t, pool.Submit("queued", func(ctx context.Context) error { return nil }))
+
+	assert.ErrorIs(t, pool.Submit("dropped", func(ctx context.Context) error { return nil }), ErrQueueFull)
+	assert.Equal(t, []string{"dropped"}, dropped)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Dropped)
+	assert.Equal(t, int64(1), stats.Running)
+	assert.Equal(t, 1, stats.Queued)
+
+	close(release)
+	require.NoError(t, pool.Drain(context.Background()))
+	assert.Equal(t, int64(2), pool.Stats().Completed)
+}
+
+func TestPoolRetries(t *testing.T) {
+	var mu sync.Mutex
+	var failures []string
+	pool := NewPool(Config{
+		Workers: 1,
+		Retry:   RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+		OnError: func(name string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failures = append(failures, name+": "+err.Error())
+		},
+	})
+
+	var attempts atomic.Int64
+	require.NoError(t, pool.Submit("flaky", func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}))
+	require.NoError(t, pool.Submit("broken", func(ctx context.Context) error {
+		return errors.New("unavailable")
+	}))
+
+	require.NoError(t, pool.Drain(context.Background()))
+	assert.Equal(t, int64(3), attempts.Load())
+	assert.Equal(t, []string{"broken: unavailable"}, failures)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Completed)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(4), stats.Retried)
+}
+
+func TestPoolTimeoutAndPanic(t *testing.T) {
+	var errs []error
+	pool := NewPool(Config{Name: "hooks", Workers: 1, Timeout: 10 * time.Millisecond, OnError: func(name string, err error) {
+		errs = append(errs, err)
+	}})
+
+	require.NoError(t, pool.Submit("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	require.NoError(t, pool.Submit("panics", func(ctx context.Context) error {
+		panic("boom")
+	}))
+
+	require.NoError(t, pool.Drain(context.Background()))
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], context.DeadlineExceeded)
+	assert.EqualError(t, errs[1], `hooks: task "panics" panicked: boom`)
+	assert.Equal(t, int64(1), pool.Stats().TimedOut)
+}
+
+func TestPoolDrain(t *testing.T) {
+	pool := NewPool(Config{Workers: 1})
+
+	require.NoError(t, pool.Submit("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Drain(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, pool.Submit("late", func(ctx context.Context) error { return nil }), ErrPoolClosed)
+
+	// The canceled task finishes
+	assert.Eventually(t, func() bool { return pool.Stats().Failed == 1 }, time.Second, time.Millisecond)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 2*time.Second, policy.Delay(2))
+	assert.Equal(t, 4*time.Second, policy.Delay(3))
+	assert.Equal(t, 5*time.Second, policy.Delay(4))
+	assert.Equal(t, DefaultBackoff, RetryPolicy{}.Delay(1))
+}